@@ -0,0 +1,147 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AdaptiveRetryOptions configures contention-aware retry backoff. The controller is AIMD:
+// while the share of BUSY attempts stays above TargetBusyRate, the backoff multiplier grows
+// multiplicatively; once contention subsides it shrinks additively back to 1.
+type AdaptiveRetryOptions struct {
+	Interval          time.Duration // How often the busy rate is evaluated (default: 1s)
+	TargetBusyRate    float64       // Busy share of attempts above which backoff increases (default: 0.2)
+	IncreaseFactor    float64       // Multiplier growth per contended interval (default: 2)
+	DecreaseStep      float64       // Multiplier reduction per calm interval (default: 0.5)
+	MaxMultiplier     float64       // Upper bound on the multiplier (default: 16)
+	MinBudgetFraction float64       // Smallest fraction of MaxRetryDuration kept under contention (default: 0.25)
+	Clock             Clock         // Time source for intervals (default: real time)
+}
+
+// AdaptiveRetryStats reports the adaptive retry controller's state
+type AdaptiveRetryStats struct {
+	Multiplier   float64 // Current scale applied to retry delays
+	LastBusyRate float64 // Busy share of attempts in the last evaluated interval
+	Attempts     int64   // Attempts observed in the current interval
+	Busy         int64   // BUSY attempts observed in the current interval
+}
+
+// adaptiveRetry tracks recent BUSY rates and scales retry schedules
+type adaptiveRetry struct {
+	opts        AdaptiveRetryOptions
+	mu          sync.Mutex
+	windowStart time.Time
+	attempts    int64
+	busy        int64
+	multiplier  float64
+	lastRate    float64
+}
+
+// activeAdaptiveRetry is the running controller, if any
+var activeAdaptiveRetry atomic.Pointer[adaptiveRetry]
+
+// EnableAdaptiveRetry makes every retry loop scale its backoff by observed contention:
+// under sustained BUSY errors delays grow and retry budgets shrink, so thousands of
+// waiting goroutines back off harder instead of hammering the lock on the same schedule
+func EnableAdaptiveRetry(opts AdaptiveRetryOptions) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.TargetBusyRate <= 0 {
+		opts.TargetBusyRate = 0.2
+	}
+	if opts.IncreaseFactor <= 1 {
+		opts.IncreaseFactor = 2
+	}
+	if opts.DecreaseStep <= 0 {
+		opts.DecreaseStep = 0.5
+	}
+	if opts.MaxMultiplier < 1 {
+		opts.MaxMultiplier = 16
+	}
+	if opts.MinBudgetFraction <= 0 || opts.MinBudgetFraction > 1 {
+		opts.MinBudgetFraction = 0.25
+	}
+	if opts.Clock == nil {
+		opts.Clock = realClock{}
+	}
+
+	activeAdaptiveRetry.Store(&adaptiveRetry{opts: opts, windowStart: opts.Clock.Now(), multiplier: 1})
+	logf("📈 Adaptive retry enabled (target busy rate %.0f%%)", opts.TargetBusyRate*100)
+}
+
+// DisableAdaptiveRetry restores fixed retry schedules
+func DisableAdaptiveRetry() {
+	activeAdaptiveRetry.Store(nil)
+}
+
+// GetAdaptiveRetryStats returns the controller state, or a multiplier of 1 when disabled
+func GetAdaptiveRetryStats() AdaptiveRetryStats {
+	a := activeAdaptiveRetry.Load()
+	if a == nil {
+		return AdaptiveRetryStats{Multiplier: 1}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return AdaptiveRetryStats{Multiplier: a.multiplier, LastBusyRate: a.lastRate, Attempts: a.attempts, Busy: a.busy}
+}
+
+// adaptRetryConfig scales config by the current contention multiplier
+func adaptRetryConfig(config RetryConfig) RetryConfig {
+	a := activeAdaptiveRetry.Load()
+	if a == nil {
+		return config
+	}
+	a.mu.Lock()
+	m := a.multiplier
+	a.mu.Unlock()
+	if m <= 1 {
+		return config
+	}
+
+	config.BaseDelay = time.Duration(float64(config.BaseDelay) * m)
+	config.MaxDelay = time.Duration(float64(config.MaxDelay) * m)
+	budget := time.Duration(float64(config.MaxRetryDuration) / m)
+	if floor := time.Duration(float64(config.MaxRetryDuration) * a.opts.MinBudgetFraction); budget < floor {
+		budget = floor
+	}
+	config.MaxRetryDuration = budget
+	return config
+}
+
+// observeRetryAttempt records whether an attempt hit BUSY and re-evaluates the
+// multiplier once per interval
+func observeRetryAttempt(busy bool) {
+	a := activeAdaptiveRetry.Load()
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.attempts++
+	if busy {
+		a.busy++
+	}
+
+	now := a.opts.Clock.Now()
+	if now.Sub(a.windowStart) < a.opts.Interval {
+		return
+	}
+
+	a.lastRate = float64(a.busy) / float64(a.attempts)
+	previous := a.multiplier
+	if a.lastRate > a.opts.TargetBusyRate {
+		a.multiplier = min(a.multiplier*a.opts.IncreaseFactor, a.opts.MaxMultiplier)
+	} else {
+		a.multiplier = max(a.multiplier-a.opts.DecreaseStep, 1)
+	}
+	if a.multiplier != previous {
+		logf("📈 Adaptive retry: busy rate %.0f%% - backoff multiplier %.2g → %.2g", a.lastRate*100, previous, a.multiplier)
+	}
+
+	a.windowStart = now
+	a.attempts = 0
+	a.busy = 0
+}
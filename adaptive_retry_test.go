@@ -0,0 +1,57 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestAdaptiveRetry verifies that sustained contention multiplies backoff and shrinks the
+// retry budget, and that calm intervals step the multiplier back down
+func TestAdaptiveRetry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	EnableAdaptiveRetry(AdaptiveRetryOptions{Interval: time.Second, Clock: clock})
+	defer DisableAdaptiveRetry()
+
+	// Two contended intervals: 1 → 2 → 4
+	for i := 0; i < 2; i++ {
+		observeRetryAttempt(true)
+		clock.now = clock.now.Add(time.Second)
+		observeRetryAttempt(true)
+	}
+	if m := GetAdaptiveRetryStats().Multiplier; m != 4 {
+		t.Fatalf("Expected multiplier 4, got %v", m)
+	}
+
+	config := adaptRetryConfig(RetryConfig{MaxRetryDuration: 8 * time.Second, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second})
+	if config.BaseDelay != 40*time.Millisecond || config.MaxDelay != 4*time.Second || config.MaxRetryDuration != 2*time.Second {
+		t.Errorf("Unexpected adapted config: %+v", config)
+	}
+
+	// A calm interval steps down additively
+	for i := 0; i < 9; i++ {
+		observeRetryAttempt(false)
+	}
+	clock.now = clock.now.Add(time.Second)
+	observeRetryAttempt(false)
+	if stats := GetAdaptiveRetryStats(); stats.Multiplier != 3.5 || stats.LastBusyRate != 0 {
+		t.Errorf("Expected multiplier 3.5 after a calm interval, got %+v", stats)
+	}
+
+	// The retry loop feeds the controller
+	busy := errors.New("database is locked")
+	calls := 0
+	err := retryDatabaseOperation(func() error {
+		calls++
+		if calls < 3 {
+			return busy
+		}
+		return nil
+	}, RetryConfig{MaxRetryDuration: time.Second, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Clock: &fakeClock{now: time.Unix(0, 0)}})
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if stats := GetAdaptiveRetryStats(); stats.Attempts != 3 || stats.Busy != 2 {
+		t.Errorf("Expected 3 attempts with 2 busy observed, got %+v", stats)
+	}
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// AdminOptions configures the admin HTTP API
+type AdminOptions struct {
+	// Authorize is called for every request; returning an error rejects it with 401
+	Authorize func(r *http.Request) error
+}
+
+// NewAdminHandler returns an http.Handler exposing operational endpoints:
+//
+//	GET  /statements?by=total_time|count|p99&limit=n  top statements (see TopStatements)
+//	POST /statements/reset                            discard collected statement statistics
+//	GET  /migrations/progress                         running migrations (see RunningMigrations)
+//	GET  /health                                      database and subsystem health (see Health)
+//	GET  /subsystems                                  background subsystem status (see Subsystems)
+func NewAdminHandler(opts AdminOptions) http.Handler {
+	mux := http.NewServeMux()
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if opts.Authorize != nil {
+			if err := opts.Authorize(r); err != nil {
+				writeJSONError(w, http.StatusUnauthorized, err.Error())
+				return false
+			}
+		}
+		return true
+	}
+
+	mux.HandleFunc("GET /statements", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		order, err := parseStatementOrder(r.URL.Query().Get("by"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit := 20
+		if value := r.URL.Query().Get("limit"); value != "" {
+			if limit, err = strconv.Atoi(value); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %v", err))
+				return
+			}
+		}
+		if activeStatementStats.Load() == nil {
+			writeJSONError(w, http.StatusServiceUnavailable, "statement statistics are not enabled")
+			return
+		}
+		statements := TopStatements(order, limit)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"by": order, "statements": statements})
+	})
+
+	mux.HandleFunc("POST /statements/reset", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		ResetStatementStats()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /cache/rows", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		if activeRowCache.Load() == nil {
+			writeJSONError(w, http.StatusServiceUnavailable, "row cache is not enabled")
+			return
+		}
+		writeJSON(w, http.StatusOK, GetRowCacheStats())
+	})
+
+	mux.HandleFunc("GET /migrations/progress", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"migrations": RunningMigrations()})
+	})
+
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		report := Health(r.Context())
+		status := http.StatusOK
+		if report.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+
+	mux.HandleFunc("GET /subsystems", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"subsystems": Subsystems()})
+	})
+
+	return mux
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Analytics connection defaults
+const (
+	DefaultAnalyticsCacheSize = 256 << 20 // Page cache per connection
+	DefaultAnalyticsMmapSize  = 1 << 30   // Memory-mapped reads
+	DefaultAnalyticsMaxConns  = 2
+	DefaultAnalyticsTimeout   = 30 * time.Minute // Limit on one Snapshot
+)
+
+// AnalyticsConfig configures OpenAnalytics
+type AnalyticsConfig struct {
+	File      string        // Database file (default: DATABASE_FILE)
+	CacheSize int64         // Page cache per connection in bytes (default: DefaultAnalyticsCacheSize)
+	MmapSize  int64         // Memory-mapped I/O in bytes (default: DefaultAnalyticsMmapSize)
+	MaxConns  int           // Connections in the pool (default: DefaultAnalyticsMaxConns)
+	Timeout   time.Duration // Limit on one Snapshot (default: DefaultAnalyticsTimeout)
+}
+
+// AnalyticsDB is a read-only handle for long analytical reads, with its own small pool so
+// reports cannot take connections from interactive traffic on the same file
+type AnalyticsDB struct {
+	*sql.DB
+	timeout time.Duration
+}
+
+// OpenAnalytics opens the database read-only (the file is opened with mode=ro and
+// query_only is set) with a large page cache and memory-mapped reads. It uses the plain
+// driver, so the pipeline driver's result limits do not apply.
+func OpenAnalytics(cfg AnalyticsConfig) (*AnalyticsDB, error) {
+	if cfg.File == "" {
+		cfg.File = configValue("DATABASE_FILE")
+	}
+	if cfg.File == "" {
+		return nil, fmt.Errorf("DATABASE_FILE environment variable is required but not set")
+	}
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = DefaultAnalyticsCacheSize
+	}
+	if cfg.MmapSize <= 0 {
+		cfg.MmapSize = DefaultAnalyticsMmapSize
+	}
+	if cfg.MaxConns <= 0 {
+		cfg.MaxConns = DefaultAnalyticsMaxConns
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultAnalyticsTimeout
+	}
+
+	dsn := appendPragmas("file:"+cfg.File+"?mode=ro", []string{
+		"_pragma=query_only(1)",
+		fmt.Sprintf("_pragma=cache_size(%d)", -cfg.CacheSize/1024), // Negative: KiB instead of pages
+		fmt.Sprintf("_pragma=mmap_size(%d)", cfg.MmapSize),
+	})
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxConns)
+	db.SetMaxIdleConns(cfg.MaxConns)
+	if err := retryDatabaseOperation(func() error {
+		return db.Ping()
+	}, DefaultRetryConfig()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open analytics connection: %w", err)
+	}
+	return &AnalyticsDB{DB: db, timeout: cfg.Timeout}, nil
+}
+
+// Snapshot runs fn in a read transaction pinned to one snapshot, like ReadTransaction,
+// limited to the configured Timeout
+func (a *AnalyticsDB) Snapshot(ctx context.Context, fn func(*sql.Tx) error) error {
+	return readTransactionOn(ctx, a.DB, ReadTransactionOptions{Timeout: a.timeout, WarnAfter: DefaultReadTransactionWarnAfter}, fn)
+}
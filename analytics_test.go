@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestOpenAnalyticsIsReadOnlyAndUnlimited verifies the analytics handle reads snapshots,
+// rejects writes, including after a snapshot, and ignores result limits
+func TestOpenAnalyticsIsReadOnlyAndUnlimited(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "analytics.db"))
+	t.Setenv("DATABASE_MAX_RESULT_ROWS", "1")
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY); INSERT INTO events VALUES (1), (2), (3)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	analytics, err := OpenAnalytics(AnalyticsConfig{MaxConns: 1})
+	if err != nil {
+		t.Fatalf("OpenAnalytics failed: %v", err)
+	}
+	defer analytics.Close()
+
+	var mmap int64
+	if err := analytics.QueryRow("PRAGMA mmap_size").Scan(&mmap); err != nil || mmap == 0 {
+		t.Errorf("Expected memory-mapped reads, got %d: %v", mmap, err)
+	}
+	if _, err := analytics.Exec("INSERT INTO events VALUES (4)"); err == nil {
+		t.Error("Expected writes through the analytics handle to fail")
+	}
+
+	err = analytics.Snapshot(context.Background(), func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id FROM events")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		result, _, err := scanRowMaps(rows, 0)
+		if len(result) != 3 {
+			t.Errorf("Expected all 3 rows despite the result limit, got %d", len(result))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	var queryOnly bool
+	if err := analytics.QueryRow("PRAGMA query_only").Scan(&queryOnly); err != nil || !queryOnly {
+		t.Errorf("Expected the snapshot to leave the analytics connection read-only, got %v (%v)", queryOnly, err)
+	}
+}
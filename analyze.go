@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Auto-analyze defaults
+const (
+	DefaultAutoAnalyzeInterval  = 5 * time.Minute
+	DefaultAutoAnalyzeThreshold = 1000 // Rows changed before a table is considered
+	DefaultAutoAnalyzeFraction  = 0.1  // Share of the analyzed row count that must change
+	DefaultAutoAnalyzeLimit     = 1000 // PRAGMA analysis_limit for each ANALYZE
+)
+
+// AutoAnalyzeOptions configures EnableAutoAnalyze
+type AutoAnalyzeOptions struct {
+	Interval  time.Duration // How often write counts are checked (default: DefaultAutoAnalyzeInterval)
+	Threshold int64         // Rows changed since the last ANALYZE before a table is stale (default: DefaultAutoAnalyzeThreshold)
+	// Fraction is the share of the row count recorded by the last ANALYZE that must also
+	// have changed, so large tables are not re-analyzed for small relative churn
+	// (default: DefaultAutoAnalyzeFraction)
+	Fraction      float64
+	AnalysisLimit int  // Rows examined per index by each ANALYZE, 0 for the default, -1 for all (default: DefaultAutoAnalyzeLimit)
+	Optimize      bool // Also run PRAGMA optimize after a pass that analyzed tables
+}
+
+// AutoAnalyzeTable reports one table's write count since it was last analyzed
+type AutoAnalyzeTable struct {
+	Table        string    `json:"table"`
+	Changes      int64     `json:"changes"`
+	LastAnalyzed time.Time `json:"last_analyzed,omitempty"`
+}
+
+// AutoAnalyzeStats reports what auto-analyze has tracked and done
+type AutoAnalyzeStats struct {
+	Tables   []AutoAnalyzeTable `json:"tables"`
+	Analyses int64              `json:"analyses"` // ANALYZE statements run
+}
+
+// autoAnalyzer counts rows changed per table and analyzes stale tables
+type autoAnalyzer struct {
+	opts AutoAnalyzeOptions
+	db   *sql.DB
+
+	mu       sync.Mutex
+	changes  map[string]int64
+	analyzed map[string]time.Time
+
+	analyses atomic.Int64
+}
+
+// activeAutoAnalyzer is the running auto-analyzer, if any
+var activeAutoAnalyzer atomic.Pointer[autoAnalyzer]
+
+// EnableAutoAnalyze counts rows changed per table by writes through the retry helpers,
+// DBTX and the pipeline driver, and until ctx is cancelled runs a targeted ANALYZE on
+// tables whose statistics have likely gone stale, so the planner keeps choosing good
+// indexes as data grows. Counts are per process; writes by other processes are not seen.
+func EnableAutoAnalyze(ctx context.Context, opts AutoAnalyzeOptions) error {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultAutoAnalyzeInterval
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultAutoAnalyzeThreshold
+	}
+	if opts.Fraction <= 0 {
+		opts.Fraction = DefaultAutoAnalyzeFraction
+	}
+	if opts.AnalysisLimit == 0 {
+		opts.AnalysisLimit = DefaultAutoAnalyzeLimit
+	}
+
+	databaseFile := configValue("DATABASE_FILE")
+	if databaseFile == "" {
+		return fmt.Errorf("DATABASE_FILE environment variable is required but not set")
+	}
+	dsn := buildDSN(databaseFile)
+	if opts.AnalysisLimit > 0 {
+		dsn = appendPragmas(dsn, []string{fmt.Sprintf("_pragma=analysis_limit(%d)", opts.AnalysisLimit)})
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+	db.SetMaxOpenConns(1)
+	analyzer := &autoAnalyzer{opts: opts, db: db, changes: make(map[string]int64), analyzed: make(map[string]time.Time)}
+	activeAutoAnalyzer.Store(analyzer)
+	logf("📊 Auto-analyze enabled (every %v, %d+ changed rows)", opts.Interval, opts.Threshold)
+
+	tracker := startSubsystem("auto_analyze", opts.Interval)
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		defer tracker.stop()
+		for {
+			select {
+			case <-ctx.Done():
+				activeAutoAnalyzer.CompareAndSwap(analyzer, nil)
+				db.Close()
+				return
+			case <-ticker.C:
+				tracker.run(func() error {
+					_, err := analyzer.pass(ctx)
+					if err != nil && ctx.Err() == nil {
+						logErrorf("❌ Auto-analyze failed: %v", err)
+					}
+					return err
+				})
+			}
+		}
+	}()
+	return nil
+}
+
+// GetAutoAnalyzeStats returns the tracked write counts (empty when disabled)
+func GetAutoAnalyzeStats() AutoAnalyzeStats {
+	analyzer := activeAutoAnalyzer.Load()
+	if analyzer == nil {
+		return AutoAnalyzeStats{}
+	}
+	analyzer.mu.Lock()
+	stats := AutoAnalyzeStats{Analyses: analyzer.analyses.Load()}
+	for table, changes := range analyzer.changes {
+		stats.Tables = append(stats.Tables, AutoAnalyzeTable{Table: table, Changes: changes, LastAnalyzed: analyzer.analyzed[table]})
+	}
+	analyzer.mu.Unlock()
+	sort.Slice(stats.Tables, func(i, j int) bool { return stats.Tables[i].Table < stats.Tables[j].Table })
+	return stats
+}
+
+// countTableWrite adds a successful write's changed rows to its table's count
+func countTableWrite(query string, rows int64) {
+	analyzer := activeAutoAnalyzer.Load()
+	if analyzer == nil || rows == 0 || ClassifyStatement(query) != StatementWrite {
+		return
+	}
+	table := writeTargetTable(query)
+	if table == "" {
+		return
+	}
+	if rows < 0 {
+		rows = 1 // Rows affected unknown: count the statement
+	}
+	analyzer.mu.Lock()
+	analyzer.changes[table] += rows
+	analyzer.mu.Unlock()
+}
+
+// pass analyzes every stale table and returns their names
+func (a *autoAnalyzer) pass(ctx context.Context) ([]string, error) {
+	a.mu.Lock()
+	counted := make(map[string]int64, len(a.changes))
+	for table, changes := range a.changes {
+		if changes >= a.opts.Threshold {
+			counted[table] = changes
+		}
+	}
+	a.mu.Unlock()
+
+	var analyzed []string
+	for table, changes := range counted {
+		rows, err := a.analyzedRows(ctx, table)
+		if err != nil {
+			return analyzed, err
+		}
+		if float64(changes) < a.opts.Fraction*float64(rows) {
+			continue
+		}
+		if err := retryDatabaseOperationContext(ctx, func() error {
+			_, err := a.db.ExecContext(ctx, "ANALYZE "+quoteIdentifier(table))
+			return err
+		}, DefaultRetryConfig()); err != nil {
+			if isMissingTable(err) {
+				a.forget(table)
+				continue
+			}
+			return analyzed, fmt.Errorf("failed to analyze %s: %w", table, err)
+		}
+		a.analyses.Add(1)
+
+		// Writes counted while ANALYZE ran stay pending for the next pass
+		a.mu.Lock()
+		a.changes[table] -= changes
+		if a.changes[table] <= 0 {
+			delete(a.changes, table)
+		}
+		a.analyzed[table] = time.Now()
+		a.mu.Unlock()
+		analyzed = append(analyzed, table)
+	}
+	sort.Strings(analyzed)
+
+	if len(analyzed) > 0 {
+		logf("📊 Analyzed %d table(s) with stale statistics: %s", len(analyzed), strings.Join(analyzed, ", "))
+		if a.opts.Optimize {
+			if _, err := a.db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+				return analyzed, fmt.Errorf("failed to run PRAGMA optimize: %w", err)
+			}
+		}
+	}
+	return analyzed, nil
+}
+
+// analyzedRows returns the row count recorded for table by its last ANALYZE (0 if never analyzed)
+func (a *autoAnalyzer) analyzedRows(ctx context.Context, table string) (int64, error) {
+	var stat string
+	err := a.db.QueryRowContext(ctx, "SELECT stat FROM sqlite_stat1 WHERE tbl = ? LIMIT 1", table).Scan(&stat)
+	if errors.Is(err, sql.ErrNoRows) || isMissingTable(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read statistics for %s: %w", table, err)
+	}
+	// The first field of every stat row is the table's row count
+	fields := strings.Fields(stat)
+	if len(fields) == 0 {
+		return 0, nil
+	}
+	rows, _ := strconv.ParseInt(fields[0], 10, 64)
+	return rows, nil
+}
+
+// forget drops a table that no longer exists
+func (a *autoAnalyzer) forget(table string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.changes, table)
+	delete(a.analyzed, table)
+}
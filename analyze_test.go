@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAutoAnalyzeAnalyzesTablesPastTheThreshold verifies writes are counted per table
+// and only tables with enough changed rows are analyzed
+func TestAutoAnalyzeAnalyzesTablesPastTheThreshold(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "analyze.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY, status INTEGER); CREATE INDEX idx_events_status ON events (status); CREATE TABLE users (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := EnableAutoAnalyze(ctx, AutoAnalyzeOptions{Interval: time.Hour, Threshold: 50}); err != nil {
+		t.Fatalf("EnableAutoAnalyze failed: %v", err)
+	}
+	defer activeAutoAnalyzer.Store(nil)
+
+	if _, err := ExecWithRetry(db, "INSERT INTO events (status) WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 60) SELECT i % 3 FROM n"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if _, err := ExecWithRetry(db, "INSERT INTO users (id) VALUES (1)"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if _, err := ExecWithRetry(db, "DELETE FROM users WHERE id = 2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	stats := GetAutoAnalyzeStats()
+	if len(stats.Tables) != 2 || stats.Tables[0].Changes != 60 || stats.Tables[1].Changes != 1 {
+		t.Fatalf("Unexpected write counts: %+v", stats.Tables)
+	}
+
+	analyzed, err := activeAutoAnalyzer.Load().pass(ctx)
+	if err != nil || len(analyzed) != 1 || analyzed[0] != "events" {
+		t.Fatalf("Expected only events to be analyzed, got %v: %v", analyzed, err)
+	}
+	var stat string
+	if err := db.QueryRow("SELECT stat FROM sqlite_stat1 WHERE idx = 'idx_events_status'").Scan(&stat); err != nil || stat[:3] != "60 " {
+		t.Errorf("Expected statistics for 60 rows, got %q: %v", stat, err)
+	}
+	stats = GetAutoAnalyzeStats()
+	if stats.Analyses != 1 || len(stats.Tables) != 1 || stats.Tables[0].Table != "users" {
+		t.Errorf("Expected the events count to be reset, got %+v", stats)
+	}
+
+	if _, err := ExecWithRetry(db, "UPDATE events SET status = 0 WHERE id <= 5"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if analyzed, err := activeAutoAnalyzer.Load().pass(ctx); err != nil || len(analyzed) != 0 {
+		t.Errorf("Expected 5 changed rows to stay under the threshold, got %v: %v", analyzed, err)
+	}
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Anonymization strategies
+const (
+	AnonymizeHash    = "hash"    // Replace with a salted SHA-256 hash (keeps joins and uniqueness)
+	AnonymizeFake    = "fake"    // Replace with a generated placeholder value
+	AnonymizeNullify = "nullify" // Replace with NULL
+)
+
+// AnonymizationRule describes how a single column is anonymized in a snapshot
+type AnonymizationRule struct {
+	Table    string
+	Column   string
+	Strategy string
+	// Fake generates a replacement value for AnonymizeFake; when nil a placeholder
+	// is derived from the column name (emails, names, phones) and the rowid
+	Fake func(rowID int64, value interface{}) interface{}
+}
+
+// AnonymizationRules is the set of rules applied by SnapshotAnonymized
+type AnonymizationRules struct {
+	Salt  string // Salt mixed into hashed values so hashes can't be reversed by lookup
+	Rules []AnonymizationRule
+}
+
+// SnapshotAnonymized copies the database to dest and applies per-column anonymization
+// rules to the copy. The copy is vacuumed afterwards so original values don't survive
+// in free pages. dest must not already exist.
+func SnapshotAnonymized(ctx context.Context, rules AnonymizationRules, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("snapshot destination already exists: %s", dest)
+	}
+
+	for _, rule := range rules.Rules {
+		switch rule.Strategy {
+		case AnonymizeHash, AnonymizeFake, AnonymizeNullify:
+		default:
+			return fmt.Errorf("unknown anonymization strategy %q for %s.%s", rule.Strategy, rule.Table, rule.Column)
+		}
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	logf("📸 Creating snapshot: %s", dest)
+	err = retryDatabaseOperationContext(ctx, func() error {
+		_, err := db.ExecContext(ctx, "VACUUM INTO ?", dest)
+		return err
+	}, DefaultRetryConfig())
+	if err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+
+	snapshot, err := sql.Open("sqlite", dest)
+	if err != nil {
+		os.Remove(dest)
+		return err
+	}
+	defer snapshot.Close()
+
+	if err := anonymizeSnapshot(ctx, snapshot, rules); err != nil {
+		snapshot.Close()
+		os.Remove(dest)
+		return err
+	}
+
+	logf("✅ Anonymized snapshot created: %s (%d rules)", dest, len(rules.Rules))
+	return nil
+}
+
+// anonymizeSnapshot applies all rules to the snapshot and compacts it. Triggers are
+// suspended while rules run, so the updates do not copy original values into history or
+// sync change logs, and the trigger-maintained copies are scrubbed as well: rules also
+// apply to <table>_history, and _sync_changes entries for anonymized tables are removed.
+func anonymizeSnapshot(ctx context.Context, snapshot *sql.DB, rules AnonymizationRules) error {
+	tx, err := snapshot.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	triggers, err := suspendTriggers(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	scrubbed := make(map[string]bool)
+	for _, rule := range rules.Rules {
+		if err := anonymizeColumn(ctx, tx, rule, rules.Salt); err != nil {
+			return err
+		}
+
+		history := rule
+		history.Table = HistoryTable(rule.Table)
+		columns, err := tableColumns(ctx, tx, history.Table)
+		if err != nil {
+			return err
+		}
+		for _, column := range columns {
+			if column == rule.Column {
+				if err := anonymizeColumn(ctx, tx, history, rules.Salt); err != nil {
+					return err
+				}
+				break
+			}
+		}
+
+		if !scrubbed[rule.Table] {
+			scrubbed[rule.Table] = true
+			if _, err := tx.ExecContext(ctx, "DELETE FROM _sync_changes WHERE table_name = ?", rule.Table); err != nil && !isMissingTable(err) {
+				return fmt.Errorf("failed to scrub sync changes for %s: %w", rule.Table, err)
+			}
+		}
+	}
+
+	for _, trigger := range triggers {
+		if _, err := tx.ExecContext(ctx, trigger); err != nil {
+			return fmt.Errorf("failed to restore trigger: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Rebuild the file so original values don't remain in free pages
+	if _, err := snapshot.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum snapshot: %w", err)
+	}
+	return nil
+}
+
+// suspendTriggers drops every trigger in the transaction and returns the statements that
+// recreate them
+func suspendTriggers(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name, sql FROM sqlite_master WHERE type = 'trigger' AND sql IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read triggers: %w", err)
+	}
+	var names, triggers []string
+	for rows.Next() {
+		var name, statement string
+		if err := rows.Scan(&name, &statement); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+		triggers = append(triggers, statement)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, "DROP TRIGGER "+quoteIdentifier(name)); err != nil {
+			return nil, fmt.Errorf("failed to suspend trigger %s: %w", name, err)
+		}
+	}
+	return triggers, nil
+}
+
+// anonymizeColumn applies one rule to every row of its table
+func anonymizeColumn(ctx context.Context, tx *sql.Tx, rule AnonymizationRule, salt string) error {
+	table, column := quoteIdentifier(rule.Table), quoteIdentifier(rule.Column)
+
+	if rule.Strategy == AnonymizeNullify {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = NULL", table, column)); err != nil {
+			return fmt.Errorf("failed to nullify %s.%s: %w", rule.Table, rule.Column, err)
+		}
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %s IS NOT NULL", column, table, column))
+	if err != nil {
+		return fmt.Errorf("failed to read %s.%s: %w", rule.Table, rule.Column, err)
+	}
+	type replacement struct {
+		rowID int64
+		value interface{}
+	}
+	var replacements []replacement
+	for rows.Next() {
+		var rowID int64
+		var value interface{}
+		if err := rows.Scan(&rowID, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		replacements = append(replacements, replacement{rowID, anonymizeValue(rule, salt, rowID, value)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column)
+	for _, r := range replacements {
+		if _, err := tx.ExecContext(ctx, update, r.value, r.rowID); err != nil {
+			return fmt.Errorf("failed to anonymize %s.%s: %w", rule.Table, rule.Column, err)
+		}
+	}
+	return nil
+}
+
+// anonymizeValue computes the replacement for a single value
+func anonymizeValue(rule AnonymizationRule, salt string, rowID int64, value interface{}) interface{} {
+	if rule.Strategy == AnonymizeHash {
+		sum := sha256.Sum256([]byte(salt + "\x00" + stringifyValue(value)))
+		return hex.EncodeToString(sum[:])
+	}
+
+	if rule.Fake != nil {
+		return rule.Fake(rowID, value)
+	}
+
+	column := strings.ToLower(rule.Column)
+	switch {
+	case strings.Contains(column, "email"):
+		return fmt.Sprintf("user%d@example.com", rowID)
+	case strings.Contains(column, "phone"):
+		return fmt.Sprintf("+1555%07d", rowID%10000000)
+	case strings.Contains(column, "name"):
+		return fmt.Sprintf("Person %d", rowID)
+	default:
+		return fmt.Sprintf("%s-%d", rule.Column, rowID)
+	}
+}
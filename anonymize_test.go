@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSnapshotAnonymizedScrubsCopies verifies that anonymization reaches history and
+// sync change logs, does not write original values back into them through triggers, and
+// leaves the triggers in place in the snapshot
+func TestSnapshotAnonymizedScrubsCopies(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(dir, "source.db"))
+
+	if _, err := ExecBatch(ctx, []Statement{
+		{Query: "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT, phone TEXT)"},
+	}); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	if err := TrackHistory(ctx, "users", "id"); err != nil {
+		t.Fatalf("TrackHistory failed: %v", err)
+	}
+	if err := TrackTableChanges(ctx, "users", "id"); err != nil {
+		t.Fatalf("TrackTableChanges failed: %v", err)
+	}
+	if _, err := ExecBatch(ctx, []Statement{
+		{Query: "INSERT INTO users (id, email, name, phone) VALUES (1, 'ada@corp.example', 'Ada Lovelace', '+44 20 7946 0000')"},
+		{Query: "UPDATE users SET email = 'ada.l@corp.example' WHERE id = 1"},
+	}); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+
+	dest := filepath.Join(dir, "snapshot.db")
+	err := SnapshotAnonymized(ctx, AnonymizationRules{Salt: "pepper", Rules: []AnonymizationRule{
+		{Table: "users", Column: "email", Strategy: AnonymizeHash},
+		{Table: "users", Column: "name", Strategy: AnonymizeFake},
+		{Table: "users", Column: "phone", Strategy: AnonymizeNullify},
+	}}, dest)
+	if err != nil {
+		t.Fatalf("SnapshotAnonymized failed: %v", err)
+	}
+
+	snapshot, err := sql.Open("sqlite", dest)
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer snapshot.Close()
+
+	var email, name string
+	var phone sql.NullString
+	if err := snapshot.QueryRow("SELECT email, name, phone FROM users WHERE id = 1").Scan(&email, &name, &phone); err != nil {
+		t.Fatalf("Failed to read snapshot row: %v", err)
+	}
+	if len(email) != 64 || name != "Person 1" || phone.Valid {
+		t.Errorf("Unexpected anonymized row: %q, %q, %v", email, name, phone)
+	}
+
+	var dump string
+	if err := snapshot.QueryRow(`SELECT
+		(SELECT COALESCE(group_concat(COALESCE(email, '') || name || COALESCE(phone, ''), ' '), '') FROM users_history) || ' ' ||
+		(SELECT COALESCE(group_concat(row_key || COALESCE(data, ''), ' '), '') FROM _sync_changes)`).Scan(&dump); err != nil {
+		t.Fatalf("Failed to read trigger-maintained copies: %v", err)
+	}
+	for _, original := range []string{"ada@corp.example", "ada.l@corp.example", "Ada Lovelace", "+44 20 7946 0000"} {
+		if strings.Contains(dump, original) {
+			t.Errorf("Original value %q survived in history or sync changes: %s", original, dump)
+		}
+	}
+
+	var versions int
+	if err := snapshot.QueryRow("SELECT COUNT(*) FROM users_history").Scan(&versions); err != nil || versions != 2 {
+		t.Errorf("Expected the update not to add history versions, got %d (%v)", versions, err)
+	}
+	if _, err := snapshot.Exec("INSERT INTO users (id, email, name) VALUES (2, 'x@example.com', 'X')"); err != nil {
+		t.Fatalf("Failed to write to snapshot: %v", err)
+	}
+	if err := snapshot.QueryRow("SELECT COUNT(*) FROM users_history").Scan(&versions); err != nil || versions != 3 {
+		t.Errorf("Expected history triggers to be restored in the snapshot, got %d versions (%v)", versions, err)
+	}
+}
+
+// TestSnapshotAnonymizedRejectsUnknownStrategy verifies rules are checked before copying
+func TestSnapshotAnonymizedRejectsUnknownStrategy(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(dir, "source.db"))
+	dest := filepath.Join(dir, "snapshot.db")
+	err := SnapshotAnonymized(context.Background(), AnonymizationRules{Rules: []AnonymizationRule{
+		{Table: "users", Column: "email", Strategy: "scramble"},
+	}}, dest)
+	if err == nil || !strings.Contains(err.Error(), "unknown anonymization strategy") {
+		t.Errorf("Expected an unknown strategy error, got %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveSchema records the archive files written by Archiver, one per table and period
+const archiveSchema = `CREATE TABLE IF NOT EXISTS _archives (
+    table_name TEXT NOT NULL,
+    period     TEXT NOT NULL,
+    path       TEXT NOT NULL,
+    row_count  INTEGER NOT NULL,
+    min_at     INTEGER NOT NULL,
+    max_at     INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (table_name, period)
+)`
+
+// Archiver moves rows older than a threshold out of a table into per-period SQLite
+// archive files (events_2024_05.db, ...) and records them in the _archives catalog.
+// Each period is copied and deleted in one transaction on a connection with the archive
+// attached; a crash between the two files' commits can leave a row in both.
+type Archiver struct {
+	Table      string
+	TimeColumn string          // Column holding Unix milliseconds, e.g. "created_at"
+	Period     PartitionPeriod // Span of one archive file (default: PartitionMonthly)
+	After      time.Duration   // Rows older than this are archived
+	Directory  string          // Default: DATABASE_ARCHIVE_DIR, or "archive" next to DATABASE_FILE
+}
+
+// ArchiveFile is one archive file in the catalog
+type ArchiveFile struct {
+	Table  string
+	Period string // Period suffix, e.g. "2024_05"
+	Path   string
+	Rows   int64
+	From   time.Time // Oldest archived row
+	To     time.Time // Newest archived row
+}
+
+// ArchiveResult reports what a run archived
+type ArchiveResult struct {
+	Files []string // Archive files written to
+	Rows  int64
+}
+
+// archiveDirectory returns where archive files are written
+func (a *Archiver) archiveDirectory() string {
+	if a.Directory != "" {
+		return a.Directory
+	}
+	if dir := configValue("DATABASE_ARCHIVE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(configValue("DATABASE_FILE")), "archive")
+}
+
+// Run archives the rows older than now minus After, one period at a time, oldest first
+func (a *Archiver) Run(ctx context.Context, now time.Time) (*ArchiveResult, error) {
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+	dir := a.archiveDirectory()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, archiveSchema)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create archive catalog: %w", err)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result := &ArchiveResult{}
+	cutoff := now.Add(-a.After).UnixMilli()
+	var oldest sql.NullInt64
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT MIN(%s) FROM %s WHERE %s < ?",
+		quoteIdentifier(a.TimeColumn), quoteIdentifier(a.Table), quoteIdentifier(a.TimeColumn)), cutoff).Scan(&oldest); err != nil {
+		return nil, fmt.Errorf("failed to find rows to archive in %s: %w", a.Table, err)
+	}
+	if !oldest.Valid {
+		return result, nil
+	}
+
+	for start := a.Period.start(time.UnixMilli(oldest.Int64)); start.UnixMilli() < cutoff; start = a.Period.add(start, 1) {
+		end := min(a.Period.add(start, 1).UnixMilli(), cutoff)
+		path := filepath.Join(dir, a.Table+"_"+start.Format(a.Period.layout())+".db")
+		n, err := a.archivePeriod(ctx, conn, start, end, path)
+		if err != nil {
+			return result, err
+		}
+		if n > 0 {
+			result.Files = append(result.Files, path)
+			result.Rows += n
+		}
+	}
+	if result.Rows > 0 {
+		logfContext(ctx, LogLevelInfo, "🧊 Archived %d rows of %s into %d files", result.Rows, a.Table, len(result.Files))
+	}
+	return result, nil
+}
+
+// archivePeriod moves the rows in [start, end) to the archive file at path and updates
+// the catalog, returning how many rows moved
+func (a *Archiver) archivePeriod(ctx context.Context, conn *sql.Conn, start time.Time, end int64, path string) (int64, error) {
+	table, column := quoteIdentifier(a.Table), quoteIdentifier(a.TimeColumn)
+	var exists bool
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s >= ? AND %s < ?)", table, column, column),
+		start.UnixMilli(), end).Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS archive", path); err != nil {
+		return 0, fmt.Errorf("failed to attach archive %s: %w", path, err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "DETACH DATABASE archive"); err != nil {
+			logfContext(ctx, LogLevelWarn, "⚠️  Failed to detach archive %s: %v", path, err)
+		}
+	}()
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS archive.%s AS SELECT * FROM main.%s WHERE 0", table, table)); err != nil {
+		return 0, fmt.Errorf("failed to create archive table in %s: %w", path, err)
+	}
+
+	var moved int64
+	err := retryDatabaseOperationContext(ctx, func() error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		result, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO archive.%s SELECT * FROM main.%s WHERE %s >= ? AND %s < ?", table, table, column, column),
+			start.UnixMilli(), end)
+		if err != nil {
+			return err
+		}
+		if moved, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM main.%s WHERE %s >= ? AND %s < ?", table, column, column), start.UnixMilli(), end); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO main._archives (table_name, period, path, row_count, min_at, max_at, updated_at)
+SELECT ?, ?, ?, COUNT(*), MIN(%s), MAX(%s), `+nowMillisSQL+` FROM archive.%s WHERE true
+ON CONFLICT (table_name, period) DO UPDATE SET path = excluded.path, row_count = excluded.row_count,
+    min_at = excluded.min_at, max_at = excluded.max_at, updated_at = excluded.updated_at`, column, column, table),
+			a.Table, start.Format(a.Period.layout()), path); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}, DefaultRetryConfig())
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive %s into %s: %w", a.Table, path, err)
+	}
+	return moved, nil
+}
+
+// Archives returns the catalogued archive files of table, oldest first
+func Archives(ctx context.Context, table string) ([]ArchiveFile, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT period, path, row_count, min_at, max_at FROM _archives WHERE table_name = ? ORDER BY min_at", table)
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	var files []ArchiveFile
+	for rows.Next() {
+		file := ArchiveFile{Table: table}
+		var from, to int64
+		if err := rows.Scan(&file.Period, &file.Path, &file.Rows, &from, &to); err != nil {
+			return nil, err
+		}
+		file.From, file.To = time.UnixMilli(from).UTC(), time.UnixMilli(to).UTC()
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// WithArchives runs fn with table's archive files attached to conn, passing a subquery
+// that reads the live rows and every archived row:
+//
+//	WithArchives(ctx, "events", func(conn *sql.Conn, source string) error {
+//	    return conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+source+" WHERE user_id = ?", id).Scan(&n)
+//	})
+//
+// Archive tables keep the columns the table had when they were written. SQLite attaches
+// at most 10 databases per connection.
+func WithArchives(ctx context.Context, table string, fn func(conn *sql.Conn, source string) error) error {
+	files, err := Archives(ctx, table)
+	if err != nil {
+		return err
+	}
+	return withArchiveFiles(ctx, table, files, fn)
+}
+
+// withArchiveFiles attaches files to a connection, runs fn with a UNION ALL over the live
+// table and the archives, and detaches them
+func withArchiveFiles(ctx context.Context, table string, files []ArchiveFile, fn func(conn *sql.Conn, source string) error) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	selects := []string{"SELECT * FROM main." + quoteIdentifier(table)}
+	for i, file := range files {
+		schema := fmt.Sprintf("archive_%d", i)
+		if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS "+schema, file.Path); err != nil {
+			return fmt.Errorf("failed to attach archive %s: %w", file.Path, err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), "DETACH DATABASE "+schema); err != nil {
+				logfContext(ctx, LogLevelWarn, "⚠️  Failed to detach archive %s: %v", file.Path, err)
+			}
+		}()
+		selects = append(selects, "SELECT * FROM "+schema+"."+quoteIdentifier(table))
+	}
+	return fn(conn, "("+strings.Join(selects, " UNION ALL ")+")")
+}
+
+// QueryAcrossPlaceholder marks where QueryAcross puts the table's live and archived rows
+const QueryAcrossPlaceholder = "{table}"
+
+// QueryAcross runs query with QueryAcrossPlaceholder replaced by a UNION ALL over table
+// and the archive files holding rows between from and to, attaching only those files and
+// detaching them afterwards:
+//
+//	QueryAcross(ctx, "events", from, to,
+//	    "SELECT user_id, COUNT(*) FROM {table} WHERE created_at >= ? AND created_at < ? GROUP BY user_id",
+//	    from.UnixMilli(), to.UnixMilli())
+//
+// The range only selects archive files; the query still filters the rows it wants.
+func QueryAcross(ctx context.Context, table string, from, to time.Time, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	if !strings.Contains(query, QueryAcrossPlaceholder) {
+		return nil, fmt.Errorf("query must contain %s", QueryAcrossPlaceholder)
+	}
+	files, err := Archives(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	var overlapping []ArchiveFile
+	for _, file := range files {
+		if file.From.Before(to) && !file.To.Before(from) {
+			overlapping = append(overlapping, file)
+		}
+	}
+
+	var result []map[string]interface{}
+	err = withArchiveFiles(ctx, table, overlapping, func(conn *sql.Conn, source string) error {
+		return retryDatabaseOperationContext(ctx, func() error {
+			rows, err := conn.QueryContext(ctx, strings.ReplaceAll(query, QueryAcrossPlaceholder, source), args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			result, _, err = scanRowMaps(rows, 0)
+			return err
+		}, retryConfigFor(ctx, query))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s across %d archives: %w", table, len(overlapping), err)
+	}
+	return result, nil
+}
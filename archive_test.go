@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestArchiverMovesOldRowsAndFederates verifies rows move into per-month archive files,
+// repeated runs append to them, and WithArchives reads live and archived rows together
+func TestArchiverMovesOldRowsAndFederates(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(dir, "archive.db"))
+	ctx := context.Background()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY, created_at INTEGER NOT NULL)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i, day := range []int{1, 5, 32, 45, 55, 70} { // March 2024 onwards
+		at := time.Date(2024, 3, day, 12, 0, 0, 0, time.UTC)
+		if _, err := db.Exec("INSERT INTO events (id, created_at) VALUES (?, ?)", i+1, at.UnixMilli()); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	archiver := &Archiver{Table: "events", TimeColumn: "created_at", After: 30 * 24 * time.Hour}
+	result, err := archiver.Run(ctx, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)) // Cutoff April 20
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Rows != 4 || len(result.Files) != 2 || filepath.Base(result.Files[1]) != "events_2024_04.db" {
+		t.Errorf("Expected 4 rows in March and April archives, got %+v", result)
+	}
+	if result, err := archiver.Run(ctx, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)); err != nil || result.Rows != 0 {
+		t.Errorf("Expected a repeated run to do nothing, got %+v: %v", result, err)
+	}
+	if _, err := archiver.Run(ctx, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)); err != nil { // Cutoff May 2
+		t.Fatalf("Second run failed: %v", err)
+	}
+
+	files, err := Archives(ctx, "events")
+	if err != nil || len(files) != 2 || files[1].Rows != 3 || files[1].To.Day() != 24 {
+		t.Errorf("Expected the April archive to hold 3 rows, got %+v: %v", files, err)
+	}
+	var live int
+	if err := db.QueryRow("SELECT COUNT(*) FROM events").Scan(&live); err != nil || live != 1 {
+		t.Errorf("Expected 1 live row, got %d: %v", live, err)
+	}
+	var total int
+	err = WithArchives(ctx, "events", func(conn *sql.Conn, source string) error {
+		return conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+source).Scan(&total)
+	})
+	if err != nil || total != 6 {
+		t.Errorf("Expected all 6 rows across archives, got %d: %v", total, err)
+	}
+}
+
+// TestQueryAcrossAttachesOverlappingArchives verifies only archives in the range are read
+func TestQueryAcrossAttachesOverlappingArchives(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "across.db"))
+	ctx := context.Background()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY, created_at INTEGER NOT NULL)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i, month := range []time.Month{1, 2, 3, 6} {
+		at := time.Date(2024, month, 15, 0, 0, 0, 0, time.UTC)
+		if _, err := db.Exec("INSERT INTO events (id, created_at) VALUES (?, ?)", i+1, at.UnixMilli()); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+	archiver := &Archiver{Table: "events", TimeColumn: "created_at"}
+	if _, err := archiver.Run(ctx, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	from, to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := QueryAcross(ctx, "events", from, to, "SELECT id FROM {table} ORDER BY id")
+	if err != nil {
+		t.Fatalf("QueryAcross failed: %v", err)
+	}
+	var ids []int64
+	for _, row := range rows {
+		ids = append(ids, row["id"].(int64))
+	}
+	if len(ids) != 3 || ids[0] != 2 || ids[2] != 4 {
+		t.Errorf("Expected February and March archives plus live rows, got ids %v", ids)
+	}
+	if _, err := QueryAcross(ctx, "events", from, to, "SELECT 1"); err == nil {
+		t.Error("Expected an error for a query without the placeholder")
+	}
+}
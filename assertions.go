@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Expectation checks the single integer value returned by an assertion query
+type Expectation func(value int64) error
+
+// ExpectZero expects the assertion query to return 0 (e.g., a count of offending rows)
+func ExpectZero() Expectation {
+	return func(value int64) error {
+		if value != 0 {
+			return fmt.Errorf("expected 0, got %d", value)
+		}
+		return nil
+	}
+}
+
+// ExpectAtLeast expects the assertion query to return at least min
+func ExpectAtLeast(min int64) Expectation {
+	return func(value int64) error {
+		if value < min {
+			return fmt.Errorf("expected at least %d, got %d", min, value)
+		}
+		return nil
+	}
+}
+
+// ExpectAtMost expects the assertion query to return at most max
+func ExpectAtMost(max int64) Expectation {
+	return func(value int64) error {
+		if value > max {
+			return fmt.Errorf("expected at most %d, got %d", max, value)
+		}
+		return nil
+	}
+}
+
+// Assertion is a named data quality check
+type Assertion struct {
+	Name        string
+	Query       string // Query returning a single integer
+	Expectation Expectation
+}
+
+// AssertionResult is the outcome of running a single assertion
+type AssertionResult struct {
+	Name     string
+	Value    int64
+	Passed   bool
+	Err      error // Expectation failure or query error
+	Duration time.Duration
+}
+
+// AssertionReport summarizes a run of all registered assertions
+type AssertionReport struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Results   []AssertionResult
+	Passed    int
+	Failed    int
+}
+
+// assertionRegistry manages registered assertions and failure hooks
+type assertionRegistry struct {
+	mu         sync.RWMutex
+	assertions []Assertion
+	hooks      []func(AssertionResult)
+}
+
+// Global assertion registry instance
+var globalAssertions = &assertionRegistry{
+	assertions: make([]Assertion, 0),
+}
+
+// RegisterAssertion registers a data quality assertion, for example:
+//
+//	RegisterAssertion("no NULL emails", "SELECT COUNT(*) FROM users WHERE email IS NULL", ExpectZero())
+func RegisterAssertion(name, query string, expectation Expectation) {
+	globalAssertions.mu.Lock()
+	defer globalAssertions.mu.Unlock()
+
+	logf("🧪 Registering assertion: %s", name)
+	globalAssertions.assertions = append(globalAssertions.assertions, Assertion{
+		Name:        name,
+		Query:       query,
+		Expectation: expectation,
+	})
+}
+
+// OnAssertionFailure registers a hook called for every failed assertion,
+// used to forward failures to health checks or alerting
+func OnAssertionFailure(hook func(AssertionResult)) {
+	globalAssertions.mu.Lock()
+	defer globalAssertions.mu.Unlock()
+
+	globalAssertions.hooks = append(globalAssertions.hooks, hook)
+}
+
+// RunAssertions runs all registered assertions and returns a report.
+// Call it after RunAllMigrations to validate data post-migration.
+func RunAssertions(ctx context.Context) (*AssertionReport, error) {
+	globalAssertions.mu.RLock()
+	assertions := make([]Assertion, len(globalAssertions.assertions))
+	copy(assertions, globalAssertions.assertions)
+	hooks := make([]func(AssertionResult), len(globalAssertions.hooks))
+	copy(hooks, globalAssertions.hooks)
+	globalAssertions.mu.RUnlock()
+
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	report := &AssertionReport{StartedAt: time.Now()}
+	for _, a := range assertions {
+		result := AssertionResult{Name: a.Name}
+		start := time.Now()
+
+		result.Err = retryDatabaseOperationContext(ctx, func() error {
+			return db.QueryRowContext(ctx, a.Query).Scan(&result.Value)
+		}, DefaultRetryConfig())
+		if result.Err == nil && a.Expectation != nil {
+			result.Err = a.Expectation(result.Value)
+		}
+		result.Duration = time.Since(start)
+		result.Passed = result.Err == nil
+
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+			logErrorf("❌ Assertion failed: %s: %v", a.Name, result.Err)
+			for _, hook := range hooks {
+				hook(result)
+			}
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = time.Since(report.StartedAt)
+
+	logf("🧪 Assertions completed: %d passed, %d failed in %v", report.Passed, report.Failed, report.Duration)
+	return report, nil
+}
+
+// StartAssertionSchedule runs all registered assertions every interval until ctx is cancelled.
+// A run with failed assertions is recorded as a failure of the "assertions" subsystem, so
+// Health reports degraded until a later run passes.
+func StartAssertionSchedule(ctx context.Context, interval time.Duration) {
+	tracker := startSubsystem("assertions", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer tracker.stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				tracker.run(func() error {
+					report, err := RunAssertions(ctx)
+					if err != nil {
+						logErrorf("❌ Scheduled assertions failed to run: %v", err)
+						return err
+					}
+					return report.failure()
+				})
+			}
+		}
+	}()
+}
+
+// failure summarizes the failed assertions of a report, or returns nil when all passed
+func (r *AssertionReport) failure() error {
+	if r.Failed == 0 {
+		return nil
+	}
+	var failed []string
+	for _, result := range r.Results {
+		if !result.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %v", result.Name, result.Err))
+		}
+	}
+	return fmt.Errorf("%d of %d assertions failed: %s", r.Failed, len(r.Results), strings.Join(failed, "; "))
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useAssertions replaces the registered assertions and hooks for the rest of the test
+func useAssertions(t *testing.T) {
+	globalAssertions.mu.Lock()
+	saved, savedHooks := globalAssertions.assertions, globalAssertions.hooks
+	globalAssertions.assertions, globalAssertions.hooks = nil, nil
+	globalAssertions.mu.Unlock()
+	t.Cleanup(func() {
+		globalAssertions.mu.Lock()
+		globalAssertions.assertions, globalAssertions.hooks = saved, savedHooks
+		globalAssertions.mu.Unlock()
+	})
+}
+
+// TestRunAssertionsReportsFailures verifies the report counts and failure hooks
+func TestRunAssertionsReportsFailures(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "assertions.db"))
+	useAssertions(t)
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT); INSERT INTO users (email) VALUES ('a@example.com'), (NULL)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+
+	RegisterAssertion("no NULL emails", "SELECT COUNT(*) FROM users WHERE email IS NULL", ExpectZero())
+	RegisterAssertion("has users", "SELECT COUNT(*) FROM users", ExpectAtLeast(1))
+	var hooked []string
+	OnAssertionFailure(func(result AssertionResult) { hooked = append(hooked, result.Name) })
+
+	report, err := RunAssertions(context.Background())
+	if err != nil {
+		t.Fatalf("RunAssertions failed: %v", err)
+	}
+	if report.Passed != 1 || report.Failed != 1 || report.Results[0].Value != 1 || report.Results[0].Passed {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(hooked) != 1 || hooked[0] != "no NULL emails" {
+		t.Errorf("Expected the failure hook to see the failed assertion, got %v", hooked)
+	}
+}
+
+// TestAssertionScheduleDegradesHealth verifies that failed scheduled assertions make Health
+// report degraded until a run passes
+func TestAssertionScheduleDegradesHealth(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "assertions.db"))
+	useAssertions(t)
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT); INSERT INTO users (email) VALUES (NULL)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+	RegisterAssertion("no NULL emails", "SELECT COUNT(*) FROM users WHERE email IS NULL", ExpectZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartAssertionSchedule(ctx, 10*time.Millisecond)
+
+	// waitForHealth polls until the assertions subsystem has run and Health reports want
+	waitForHealth := func(want string) (HealthReport, SubsystemStatus) {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			report := Health(ctx)
+			for _, s := range report.Subsystems {
+				if s.Name == "assertions" && s.Runs > 0 && (report.Status == want || time.Now().After(deadline)) {
+					return report, s
+				}
+			}
+			if time.Now().After(deadline) {
+				return report, SubsystemStatus{}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	report, status := waitForHealth("degraded")
+	if report.Status != "degraded" || !strings.Contains(status.LastError, "no NULL emails") {
+		t.Fatalf("Expected failed assertions to degrade health, got %s with %+v", report.Status, status)
+	}
+
+	if _, err := db.Exec("UPDATE users SET email = 'a@example.com'"); err != nil {
+		t.Fatalf("Failed to fix data: %v", err)
+	}
+	if report, status := waitForHealth("ok"); report.Status != "ok" || status.LastError != "" {
+		t.Errorf("Expected health to recover once assertions pass, got %s with %+v", report.Status, status)
+	}
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrBatchAborted is reported for statements that were not executed because
+// an earlier statement in the same batch failed
+var ErrBatchAborted = errors.New("batch aborted by earlier statement failure")
+
+// Statement is a single parameterized statement in a batch
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
+
+// BatchResult holds the outcome of a single statement in a batch
+type BatchResult struct {
+	Result sql.Result
+	Err    error
+}
+
+// BatchOptions configures ExecBatchWithOptions
+type BatchOptions struct {
+	// SkipFailures rolls back only the statements that fail, via savepoints, and commits
+	// the rest; failures are reported in their results instead of aborting the batch
+	SkipFailures bool
+	// ChunkSize is how many statements share a savepoint when SkipFailures is set (default:
+	// 1). A failing chunk is rolled back and replayed one statement at a time to isolate
+	// the bad ones, so larger chunks are faster when failures are rare.
+	ChunkSize int
+}
+
+// ExecBatch executes a list of statements in a single transaction with one retry envelope.
+// If any statement fails the transaction is rolled back, the failing statement's error is
+// recorded in its result, later statements report ErrBatchAborted, and an error is returned.
+func ExecBatch(ctx context.Context, statements []Statement) ([]BatchResult, error) {
+	return ExecBatchWithOptions(ctx, statements, BatchOptions{})
+}
+
+// ExecBatchWithOptions executes statements like ExecBatch. With SkipFailures, a bad row in
+// an import is rolled back and reported in its result while the rest of the batch commits;
+// the error is nil unless the transaction itself fails. BUSY errors still retry the whole
+// batch rather than counting as statement failures.
+func ExecBatchWithOptions(ctx context.Context, statements []Statement, opts BatchOptions) ([]BatchResult, error) {
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(statements))
+
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		// Reset results so a retried attempt doesn't leak state from the previous one
+		for i := range results {
+			results[i] = BatchResult{}
+		}
+		if opts.SkipFailures {
+			return execBatchSavepoints(ctx, tx, statements, results, max(opts.ChunkSize, 1))
+		}
+
+		for i, stmt := range statements {
+			result, err := tx.ExecContext(ctx, stmt.Query, stmt.Args...)
+			if err != nil {
+				results[i].Err = err
+				for j := i + 1; j < len(results); j++ {
+					results[j].Err = ErrBatchAborted
+				}
+				return fmt.Errorf("batch statement %d failed: %w", i, err)
+			}
+			results[i].Result = result
+		}
+		return nil
+	})
+
+	return results, err
+}
+
+// execBatchSavepoints runs statements in savepoint-wrapped chunks, replaying a failed chunk
+// one statement at a time so only the failing statements are rolled back
+func execBatchSavepoints(ctx context.Context, tx *sql.Tx, statements []Statement, results []BatchResult, chunkSize int) error {
+	for start := 0; start < len(statements); start += chunkSize {
+		end := min(start+chunkSize, len(statements))
+		err := execSavepoint(ctx, tx, func() error {
+			for i := start; i < end; i++ {
+				result, err := tx.ExecContext(ctx, statements[i].Query, statements[i].Args...)
+				if err != nil {
+					return err
+				}
+				results[i].Result = result
+			}
+			return nil
+		})
+		if err == nil {
+			continue
+		}
+		if IsBusy(err) {
+			return err
+		}
+		if end-start == 1 {
+			results[start] = BatchResult{Err: err}
+			continue
+		}
+
+		for i := start; i < end; i++ {
+			results[i] = BatchResult{}
+			err := execSavepoint(ctx, tx, func() error {
+				result, err := tx.ExecContext(ctx, statements[i].Query, statements[i].Args...)
+				results[i].Result = result
+				return err
+			})
+			if IsBusy(err) {
+				return err
+			}
+			results[i].Err = err
+		}
+	}
+	return nil
+}
+
+// execSavepoint runs fn inside a savepoint, rolling back to it when fn fails
+func execSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO batch_item"); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rollbackErr)
+		}
+		if _, releaseErr := tx.ExecContext(ctx, "RELEASE batch_item"); releaseErr != nil {
+			return fmt.Errorf("%w (release of savepoint failed: %v)", err, releaseErr)
+		}
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE batch_item")
+	return err
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestExecBatchRollsBackOnFailure verifies that a failing statement rolls back
+// the whole batch and that per-statement errors are reported
+func TestExecBatchRollsBackOnFailure(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "batch.db"))
+	ctx := context.Background()
+
+	results, err := ExecBatch(ctx, []Statement{
+		{Query: "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)"},
+		{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{1, "one"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected first batch to succeed, got: %v", err)
+	}
+	if n, _ := results[1].Result.RowsAffected(); n != 1 {
+		t.Errorf("Expected 1 row affected, got %d", n)
+	}
+
+	results, err = ExecBatch(ctx, []Statement{
+		{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{2, "two"}},
+		{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{3, nil}},
+		{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{4, "four"}},
+	})
+	if err == nil {
+		t.Fatal("Expected second batch to fail on NOT NULL constraint")
+	}
+	if results[0].Err != nil || results[1].Err == nil || !errors.Is(results[2].Err, ErrBatchAborted) {
+		t.Errorf("Unexpected per-statement errors: %v, %v, %v", results[0].Err, results[1].Err, results[2].Err)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil {
+		t.Fatalf("Failed to count items: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected failed batch to be rolled back leaving 1 row, got %d", count)
+	}
+}
+
+// TestExecBatchSkipFailuresCommitsGoodStatements verifies savepoint isolation of failing
+// statements, alone and within chunks
+func TestExecBatchSkipFailuresCommitsGoodStatements(t *testing.T) {
+	for _, chunkSize := range []int{1, 3} {
+		t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "batch.db"))
+
+		db, err := GetDB()
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)"); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+
+		statements := []Statement{
+			{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{1, "a"}},
+			{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{2, nil}},
+			{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{3, "c"}},
+			{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{1, "dup"}},
+			{Query: "INSERT INTO items (id, name) VALUES (?, ?)", Args: []interface{}{5, "e"}},
+		}
+		results, err := ExecBatchWithOptions(context.Background(), statements, BatchOptions{SkipFailures: true, ChunkSize: chunkSize})
+		if err != nil {
+			t.Fatalf("Chunk size %d: expected the batch to commit, got %v", chunkSize, err)
+		}
+		for i, failed := range []bool{false, true, false, true, false} {
+			if (results[i].Err != nil) != failed {
+				t.Errorf("Chunk size %d: statement %d error = %v, want failure %v", chunkSize, i, results[i].Err, failed)
+			}
+		}
+
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil || count != 3 {
+			t.Errorf("Chunk size %d: expected 3 committed rows, got %d: %v", chunkSize, count, err)
+		}
+		db.Close()
+	}
+}
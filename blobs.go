@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// DefaultBlobChunkSize is how many bytes of a blob are stored per chunk row
+const DefaultBlobChunkSize = 256 << 10
+
+// ErrBlobNotFound is returned when opening a blob that does not exist
+var ErrBlobNotFound = errors.New("blob not found")
+
+// blobSchema creates the blob catalog and its chunks. Each write goes to a new generation
+// of chunks, and the catalog switches to it only when the write completes.
+const blobSchema = `CREATE TABLE IF NOT EXISTS _blobs (
+    id         TEXT PRIMARY KEY,
+    generation TEXT NOT NULL,
+    size       INTEGER NOT NULL,
+    sha256     TEXT NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS _blob_chunks (
+    blob_id    TEXT NOT NULL,
+    generation TEXT NOT NULL,
+    seq        INTEGER NOT NULL,
+    data       BLOB NOT NULL,
+    PRIMARY KEY (blob_id, generation, seq)
+) WITHOUT ROWID`
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	ID     string
+	Size   int64
+	SHA256 string
+}
+
+// BlobWriter streams a blob into the database in DefaultBlobChunkSize chunks, so
+// multi-megabyte payloads are never held in memory whole. Readers keep seeing the
+// previous content until Close commits the new one.
+type BlobWriter struct {
+	ctx        context.Context
+	db         *sql.DB
+	id         string
+	generation string
+	buf        []byte
+	seq        int64
+	size       int64
+	hash       hash.Hash
+	done       bool
+}
+
+// NewBlobWriter starts writing the blob id, replacing any existing content on Close
+func NewBlobWriter(ctx context.Context, id string) (*BlobWriter, error) {
+	gen := make([]byte, 8)
+	if _, err := rand.Read(gen); err != nil {
+		return nil, err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	if err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, blobSchema)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create blob tables: %w", err)
+	}
+	return &BlobWriter{ctx: ctx, db: db, id: id, generation: hex.EncodeToString(gen),
+		buf: make([]byte, 0, DefaultBlobChunkSize), hash: sha256.New()}, nil
+}
+
+// Write buffers p, storing every full chunk
+func (w *BlobWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, errors.New("blob writer is closed")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), DefaultBlobChunkSize-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p, written = p[n:], written+n
+		if len(w.buf) == DefaultBlobChunkSize {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush stores the buffered chunk
+func (w *BlobWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if _, err := ExecContextWithRetry(w.ctx, w.db, "INSERT INTO _blob_chunks (blob_id, generation, seq, data) VALUES (?, ?, ?, ?)",
+		w.id, w.generation, w.seq, w.buf); err != nil {
+		return fmt.Errorf("failed to write chunk %d of blob %s: %w", w.seq, w.id, err)
+	}
+	w.hash.Write(w.buf)
+	w.size += int64(len(w.buf))
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close stores the last chunk and makes the new content current, deleting the previous
+// generation's chunks in the same transaction
+func (w *BlobWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	if err := w.flush(); err != nil {
+		w.Abort()
+		return err
+	}
+	w.done = true
+	defer w.db.Close()
+
+	sum := hex.EncodeToString(w.hash.Sum(nil))
+	err := WithTransactionRetryContext(w.ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(w.ctx, "DELETE FROM _blob_chunks WHERE blob_id = ? AND generation <> ? AND generation = (SELECT generation FROM _blobs WHERE id = ?)",
+			w.id, w.generation, w.id); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(w.ctx, `INSERT INTO _blobs (id, generation, size, sha256, updated_at) VALUES (?, ?, ?, ?, `+nowMillisSQL+`)
+ON CONFLICT (id) DO UPDATE SET generation = excluded.generation, size = excluded.size, sha256 = excluded.sha256, updated_at = excluded.updated_at`,
+			w.id, w.generation, w.size, sum)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit blob %s: %w", w.id, err)
+	}
+	return nil
+}
+
+// Abort discards the chunks written so far, leaving the previous content current
+func (w *BlobWriter) Abort() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	defer w.db.Close()
+	_, err := ExecContextWithRetry(context.WithoutCancel(w.ctx), w.db, "DELETE FROM _blob_chunks WHERE blob_id = ? AND generation = ?", w.id, w.generation)
+	return err
+}
+
+// WriteBlob stores everything read from r as the blob id
+func WriteBlob(ctx context.Context, id string, r io.Reader) (*BlobInfo, error) {
+	w, err := NewBlobWriter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return &BlobInfo{ID: id, Size: w.size, SHA256: hex.EncodeToString(w.hash.Sum(nil))}, nil
+}
+
+// BlobReader streams a stored blob one chunk at a time
+type BlobReader struct {
+	ctx        context.Context
+	db         *sql.DB
+	info       BlobInfo
+	generation string
+	chunk      []byte
+	seq        int64
+	read       int64
+}
+
+// OpenBlob opens the blob id for reading, or returns ErrBlobNotFound
+func OpenBlob(ctx context.Context, id string) (*BlobReader, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	r := &BlobReader{ctx: ctx, db: db, info: BlobInfo{ID: id}}
+	err = db.QueryRowContext(ctx, "SELECT generation, size, sha256 FROM _blobs WHERE id = ?", id).Scan(&r.generation, &r.info.Size, &r.info.SHA256)
+	if errors.Is(err, sql.ErrNoRows) || isMissingTable(err) {
+		db.Close()
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, id)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Info returns the blob's size and checksum
+func (r *BlobReader) Info() BlobInfo {
+	return r.info
+}
+
+// Read reads the next bytes of the blob, loading chunks as needed
+func (r *BlobReader) Read(p []byte) (int, error) {
+	if len(r.chunk) == 0 {
+		if r.read >= r.info.Size {
+			return 0, io.EOF
+		}
+		err := retryDatabaseOperationContext(r.ctx, func() error {
+			return r.db.QueryRowContext(r.ctx, "SELECT data FROM _blob_chunks WHERE blob_id = ? AND generation = ? AND seq = ?",
+				r.info.ID, r.generation, r.seq).Scan(&r.chunk)
+		}, DefaultRetryConfig())
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("blob %s was replaced while reading", r.info.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.seq++
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	r.read += int64(n)
+	return n, nil
+}
+
+// Close releases the reader's connection
+func (r *BlobReader) Close() error {
+	return r.db.Close()
+}
+
+// DeleteBlob removes the blob id and its chunks
+func DeleteBlob(ctx context.Context, id string) error {
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM _blob_chunks WHERE blob_id = ?", id); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM _blobs WHERE id = ?", id)
+		return err
+	})
+	if isMissingTable(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// TestBlobsStreamInChunks verifies chunked writes and reads, replacement, aborted writes
+// and deletion
+func TestBlobsStreamInChunks(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "blobs.db"))
+	ctx := context.Background()
+
+	payload := bytes.Repeat([]byte("0123456789abcdef"), DefaultBlobChunkSize/16*2+1000) // Two full chunks and a partial one
+	info, err := WriteBlob(ctx, "report.pdf", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("WriteBlob failed: %v", err)
+	}
+	sum := sha256.Sum256(payload)
+	if info.Size != int64(len(payload)) || info.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected blob info: %+v", info)
+	}
+
+	read := func() []byte {
+		r, err := OpenBlob(ctx, "report.pdf")
+		if err != nil {
+			t.Fatalf("OpenBlob failed: %v", err)
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		return data
+	}
+	if !bytes.Equal(read(), payload) {
+		t.Error("Expected the blob to read back unchanged")
+	}
+
+	// An aborted write leaves the previous content in place
+	w, err := NewBlobWriter(ctx, "report.pdf")
+	if err != nil {
+		t.Fatalf("NewBlobWriter failed: %v", err)
+	}
+	w.Write(bytes.Repeat([]byte("x"), DefaultBlobChunkSize+1))
+	if err := w.Abort(); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+	if !bytes.Equal(read(), payload) {
+		t.Error("Expected an aborted write to keep the previous content")
+	}
+
+	if _, err := WriteBlob(ctx, "report.pdf", bytes.NewReader([]byte("v2"))); err != nil {
+		t.Fatalf("Overwrite failed: %v", err)
+	}
+	if got := read(); string(got) != "v2" {
+		t.Errorf("Expected the new content, got %d bytes", len(got))
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	var chunks int
+	if err := db.QueryRow("SELECT COUNT(*) FROM _blob_chunks").Scan(&chunks); err != nil || chunks != 1 {
+		t.Errorf("Expected old and aborted chunks to be deleted, %d remain: %v", chunks, err)
+	}
+
+	if err := DeleteBlob(ctx, "report.pdf"); err != nil {
+		t.Fatalf("DeleteBlob failed: %v", err)
+	}
+	if _, err := OpenBlob(ctx, "report.pdf"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound, got %v", err)
+	}
+}
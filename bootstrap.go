@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DevSeed populates a development database with sample data
+type DevSeed struct {
+	Name string
+	Seed func(ctx context.Context, tx *sql.Tx) error
+}
+
+// BootstrapOptions configures BootstrapDev
+type BootstrapOptions struct {
+	Snapshot string // Optional anonymized snapshot to start from (see SnapshotAnonymized)
+	Force    bool   // Replace an existing database file
+	SkipSeed bool   // Skip registered dev seeds
+}
+
+// devSeedRegistry manages all registered dev seeds
+type devSeedRegistry struct {
+	mu    sync.RWMutex
+	seeds []DevSeed
+}
+
+// Global dev seed registry instance
+var globalDevSeeds = &devSeedRegistry{
+	seeds: make([]DevSeed, 0),
+}
+
+// RegisterDevSeed registers a seed function run by BootstrapDev after migrations
+func RegisterDevSeed(name string, seed func(ctx context.Context, tx *sql.Tx) error) {
+	globalDevSeeds.mu.Lock()
+	defer globalDevSeeds.mu.Unlock()
+
+	logf("🌱 Registering dev seed: %s", name)
+	globalDevSeeds.seeds = append(globalDevSeeds.seeds, DevSeed{Name: name, Seed: seed})
+}
+
+// GetRegisteredDevSeeds returns all registered dev seeds
+func GetRegisteredDevSeeds() []DevSeed {
+	globalDevSeeds.mu.RLock()
+	defer globalDevSeeds.mu.RUnlock()
+
+	// Return a copy to prevent external modification
+	seeds := make([]DevSeed, len(globalDevSeeds.seeds))
+	copy(seeds, globalDevSeeds.seeds)
+	return seeds
+}
+
+// BootstrapDev builds a fresh local database at DATABASE_FILE: it optionally starts
+// from an anonymized snapshot, runs all registered migrations and views, then runs
+// registered dev seeds in order
+func BootstrapDev(ctx context.Context, opts BootstrapOptions) error {
+	databaseFile := configValue("DATABASE_FILE")
+	if databaseFile == "" {
+		return fmt.Errorf("DATABASE_FILE environment variable is required but not set")
+	}
+
+	if _, err := os.Stat(databaseFile); err == nil {
+		if !opts.Force {
+			return fmt.Errorf("database already exists: %s (use Force to replace it)", databaseFile)
+		}
+		logf("🗑️  Removing existing database: %s", databaseFile)
+		closeSharedDBs(databaseFile)
+		for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+			if err := os.Remove(databaseFile + suffix); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", databaseFile+suffix, err)
+			}
+		}
+	}
+
+	if opts.Snapshot != "" {
+		logf("📸 Restoring snapshot: %s", opts.Snapshot)
+		if err := copyFile(opts.Snapshot, databaseFile); err != nil {
+			return fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+	}
+
+	if err := RunAllMigrations(); err != nil {
+		return err
+	}
+
+	if opts.SkipSeed {
+		logf("🎉 Development database ready (seeds skipped): %s", databaseFile)
+		return nil
+	}
+
+	for _, seed := range GetRegisteredDevSeeds() {
+		logf("🌱 Running dev seed: %s", seed.Name)
+		err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+			return seed.Seed(ctx, tx)
+		})
+		if err != nil {
+			return fmt.Errorf("dev seed %s failed: %w", seed.Name, err)
+		}
+	}
+
+	logf("🎉 Development database ready: %s", databaseFile)
+	return nil
+}
+
+// copyFile copies src to a new file at dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useBootstrapRegistries swaps in empty migration and dev seed registries, with one
+// source creating a notes table, for the duration of a test
+func useBootstrapRegistries(t *testing.T) {
+	globalRegistry.mu.Lock()
+	savedSources := globalRegistry.sources
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	globalDevSeeds.mu.Lock()
+	savedSeeds := globalDevSeeds.seeds
+	globalDevSeeds.seeds = nil
+	globalDevSeeds.mu.Unlock()
+	t.Cleanup(func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = savedSources
+		globalRegistry.mu.Unlock()
+		globalDevSeeds.mu.Lock()
+		globalDevSeeds.seeds = savedSeeds
+		globalDevSeeds.mu.Unlock()
+	})
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "001_create_notes.up.sql"), []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "001_create_notes.down.sql"), []byte("DROP TABLE notes;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	RegisterMigrations(MigrationSource{Name: "bootstrap-test", Directory: dir})
+}
+
+// countNotes returns the rows in the notes table
+func countNotes(t *testing.T) int {
+	t.Helper()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notes").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// TestBootstrapDevMigratesAndSeeds verifies a fresh database is migrated and seeded, an
+// existing one is only replaced with Force, and SkipSeed leaves it empty
+func TestBootstrapDevMigratesAndSeeds(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "dev.db"))
+	useBootstrapRegistries(t)
+	ctx := context.Background()
+
+	RegisterDevSeed("notes", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO notes (body) VALUES ('hello'), ('world')")
+		return err
+	})
+
+	if err := BootstrapDev(ctx, BootstrapOptions{}); err != nil {
+		t.Fatalf("BootstrapDev failed: %v", err)
+	}
+	if got := countNotes(t); got != 2 {
+		t.Fatalf("Expected 2 seeded notes, got %d", got)
+	}
+
+	if err := BootstrapDev(ctx, BootstrapOptions{}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("Expected an existing database to be kept without Force, got %v", err)
+	}
+
+	if err := BootstrapDev(ctx, BootstrapOptions{Force: true, SkipSeed: true}); err != nil {
+		t.Fatalf("BootstrapDev with Force failed: %v", err)
+	}
+	if got := countNotes(t); got != 0 {
+		t.Fatalf("Expected a rebuilt, unseeded database, got %d notes", got)
+	}
+}
+
+// TestBootstrapDevFromSnapshot verifies the database starts from a snapshot's contents
+func TestBootstrapDevFromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "snapshot.db")
+	t.Setenv("DATABASE_FILE", snapshot)
+	useBootstrapRegistries(t)
+	ctx := context.Background()
+
+	if err := BootstrapDev(ctx, BootstrapOptions{SkipSeed: true}); err != nil {
+		t.Fatalf("BootstrapDev failed: %v", err)
+	}
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO notes (body) VALUES ('from snapshot')"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	CloseAll()
+
+	t.Setenv("DATABASE_FILE", filepath.Join(dir, "dev.db"))
+	if err := BootstrapDev(ctx, BootstrapOptions{Snapshot: snapshot}); err != nil {
+		t.Fatalf("BootstrapDev from snapshot failed: %v", err)
+	}
+	if got := countNotes(t); got != 1 {
+		t.Fatalf("Expected the snapshot's note, got %d notes", got)
+	}
+}
+
+// TestBootstrapDevReportsSeedFailures verifies a failing seed is named and rolled back
+func TestBootstrapDevReportsSeedFailures(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "dev.db"))
+	useBootstrapRegistries(t)
+
+	RegisterDevSeed("broken", func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO notes (body) VALUES ('partial')"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO missing_table VALUES (1)")
+		return err
+	})
+
+	err := BootstrapDev(context.Background(), BootstrapOptions{})
+	if err == nil || !strings.Contains(err.Error(), "dev seed broken failed") {
+		t.Fatalf("Expected the failing seed to be reported, got %v", err)
+	}
+	if got := countNotes(t); got != 0 {
+		t.Fatalf("Expected the failed seed to roll back, got %d notes", got)
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// sqliteModule is the Go module providing the "sqlite" driver
+const sqliteModule = "modernc.org/sqlite"
+
+// BuildDetails reports the package, SQLite library and driver in use, for comparing
+// environments when behavior differs between machines
+type BuildDetails struct {
+	Version        string          `json:"version"` // Package version
+	GoVersion      string          `json:"go_version"`
+	SQLiteVersion  string          `json:"sqlite_version"`  // SQLite library version
+	Driver         string          `json:"driver"`          // Underlying driver module and version
+	PipelineDriver string          `json:"pipeline_driver"` // See DriverName
+	Features       map[string]bool `json:"features"`        // FTS5, RTREE, JSON1, RETURNING, MATH
+	CompileOptions []string        `json:"compile_options"` // PRAGMA compile_options
+}
+
+// featureProbes detect optional SQLite features by using them
+var featureProbes = []struct {
+	name       string
+	statements []string
+}{
+	{"FTS5", []string{"CREATE VIRTUAL TABLE temp.probe_fts5 USING fts5(a)"}},
+	{"RTREE", []string{"CREATE VIRTUAL TABLE temp.probe_rtree USING rtree(id, x0, x1)"}},
+	{"JSON1", []string{"SELECT json('{}')"}},
+	{"RETURNING", []string{"CREATE TEMP TABLE probe_returning (a)", "INSERT INTO probe_returning VALUES (1) RETURNING a"}},
+	{"MATH", []string{"SELECT sqrt(4)"}},
+}
+
+// buildDetails is probed once, since the library cannot change while the process runs
+var buildDetails = sync.OnceValues(probeBuildDetails)
+
+// BuildInfo returns the package version, the SQLite library version, its compiled-in
+// features and the active driver. It probes an in-memory database, so it does not need
+// DATABASE_FILE. The result is also included in Health.
+func BuildInfo() (*BuildDetails, error) {
+	details, err := buildDetails()
+	if err != nil {
+		return nil, err
+	}
+	copied := *details
+	copied.Features = make(map[string]bool, len(details.Features))
+	for name, ok := range details.Features {
+		copied.Features[name] = ok
+	}
+	copied.CompileOptions = append([]string(nil), details.CompileOptions...)
+	return &copied, nil
+}
+
+// probeBuildDetails queries an in-memory database for the library's version and features
+func probeBuildDetails() (*BuildDetails, error) {
+	details := &BuildDetails{
+		Version:        Version,
+		GoVersion:      runtime.Version(),
+		Driver:         sqliteModule,
+		PipelineDriver: DriverName,
+		Features:       make(map[string]bool),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == sqliteModule {
+				details.Driver = sqliteModule + " " + dep.Version
+			}
+		}
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// Temp tables of an in-memory database live on a single connection
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&details.SQLiteVersion); err != nil {
+		return nil, fmt.Errorf("failed to read SQLite version: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, "PRAGMA compile_options")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compile options: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var option string
+		if err := rows.Scan(&option); err != nil {
+			return nil, err
+		}
+		details.CompileOptions = append(details.CompileOptions, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, probe := range featureProbes {
+		supported := true
+		for _, statement := range probe.statements {
+			rows, err := db.QueryContext(ctx, statement)
+			if err != nil {
+				supported = false
+				break
+			}
+			rows.Close()
+		}
+		details.Features[probe.name] = supported
+	}
+	return details, nil
+}
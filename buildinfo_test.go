@@ -0,0 +1,31 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBuildInfoReportsLibraryAndFeatures verifies version, driver and feature probing
+func TestBuildInfoReportsLibraryAndFeatures(t *testing.T) {
+	info, err := BuildInfo()
+	if err != nil {
+		t.Fatalf("BuildInfo failed: %v", err)
+	}
+	if info.Version != Version || info.PipelineDriver != DriverName || !strings.HasPrefix(info.SQLiteVersion, "3.") {
+		t.Errorf("Unexpected build info: %+v", info)
+	}
+	if !strings.HasPrefix(info.Driver, sqliteModule) || len(info.CompileOptions) == 0 {
+		t.Errorf("Expected the driver module and compile options, got %+v", info)
+	}
+	for _, feature := range []string{"JSON1", "RETURNING", "FTS5"} {
+		if !info.Features[feature] {
+			t.Errorf("Expected %s to be available, got %v", feature, info.Features)
+		}
+	}
+
+	// Callers get their own copy of the cached result
+	info.Features["JSON1"] = false
+	if again, _ := BuildInfo(); !again.Features["JSON1"] {
+		t.Error("Expected BuildInfo to return an independent copy")
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy is what a bulk load does with rows that hit a uniqueness conflict
+type ConflictPolicy string
+
+// Conflict policies
+const (
+	ConflictAbort   ConflictPolicy = "abort"   // Fail the whole load (default)
+	ConflictIgnore  ConflictPolicy = "ignore"  // Keep the existing row and skip the new one
+	ConflictReplace ConflictPolicy = "replace" // Delete the existing row and insert the new one
+	ConflictUpsert  ConflictPolicy = "upsert"  // Update MergeColumns of the existing row
+)
+
+// BulkLoadOptions configures BulkLoad
+type BulkLoadOptions struct {
+	OnConflict      ConflictPolicy
+	ConflictColumns []string // Unique key rows conflict on; required for replace and upsert
+	MergeColumns    []string // Columns upsert overwrites (default: every loaded column outside ConflictColumns)
+}
+
+// BulkLoadResult counts what a bulk load did with its rows
+type BulkLoadResult struct {
+	Inserted int
+	Updated  int // Rows replaced or merged into existing rows
+	Skipped  int // Rows ignored because of a conflict
+}
+
+// BulkLoad inserts rows (values in columns order) into table in one transaction,
+// resolving uniqueness conflicts per opts.OnConflict:
+//
+//	BulkLoad(ctx, "products", []string{"sku", "name", "price"}, rows, BulkLoadOptions{
+//	    OnConflict: ConflictUpsert, ConflictColumns: []string{"sku"}, MergeColumns: []string{"price"},
+//	})
+//
+// The policy is compiled into the INSERT statement (INSERT OR IGNORE, INSERT OR REPLACE,
+// or ON CONFLICT DO UPDATE); with ConflictColumns, each row's key is looked up first so
+// inserts and updates are counted apart.
+func BulkLoad(ctx context.Context, table string, columns []string, rows [][]interface{}, opts BulkLoadOptions) (*BulkLoadResult, error) {
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+	insert, err := bulkLoadStatement(table, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+	keyIndexes := make([]int, len(opts.ConflictColumns))
+	for i, key := range opts.ConflictColumns {
+		keyIndexes[i] = -1
+		for j, column := range columns {
+			if column == key {
+				keyIndexes[i] = j
+			}
+		}
+		if keyIndexes[i] < 0 {
+			return nil, fmt.Errorf("conflict column %s is not loaded", key)
+		}
+	}
+	exists := ""
+	if len(opts.ConflictColumns) > 0 {
+		where := make([]string, len(opts.ConflictColumns))
+		for i, column := range opts.ConflictColumns {
+			where[i] = quoteIdentifier(column) + " IS ?"
+		}
+		exists = fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE %s)", quoteIdentifier(table), strings.Join(where, " AND "))
+	}
+
+	result := &BulkLoadResult{}
+	err = WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		*result = BulkLoadResult{}
+		stmt, err := tx.PrepareContext(ctx, insert)
+		if err != nil {
+			return fmt.Errorf("failed to prepare bulk load into %s: %w", table, err)
+		}
+		defer stmt.Close()
+		var check *sql.Stmt
+		if exists != "" {
+			if check, err = tx.PrepareContext(ctx, exists); err != nil {
+				return fmt.Errorf("failed to prepare conflict check on %s: %w", table, err)
+			}
+			defer check.Close()
+		}
+
+		key := make([]interface{}, len(keyIndexes))
+		for i, row := range rows {
+			if len(row) != len(columns) {
+				return fmt.Errorf("bulk load row %d has %d values, want %d", i, len(row), len(columns))
+			}
+			existed := false
+			if check != nil {
+				for k, index := range keyIndexes {
+					key[k] = row[index]
+				}
+				if err := check.QueryRowContext(ctx, key...).Scan(&existed); err != nil {
+					return fmt.Errorf("failed to check row %d against %s: %w", i, table, err)
+				}
+			}
+			res, err := stmt.ExecContext(ctx, row...)
+			if err != nil {
+				return fmt.Errorf("bulk load row %d into %s failed: %w", i, table, err)
+			}
+			affected, _ := res.RowsAffected()
+			switch {
+			case affected == 0:
+				result.Skipped++
+			case existed && opts.OnConflict != ConflictIgnore:
+				result.Updated++
+			default:
+				result.Inserted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logf("📥 Bulk loaded %s: %d inserted, %d updated, %d skipped", table, result.Inserted, result.Updated, result.Skipped)
+	return result, nil
+}
+
+// bulkLoadStatement compiles the INSERT statement for a conflict policy
+func bulkLoadStatement(table string, columns []string, opts BulkLoadOptions) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("bulk load into %s needs columns", table)
+	}
+	values := fmt.Sprintf("%s (%s) VALUES (%s)", quoteIdentifier(table), quoteColumns(columns),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+
+	switch opts.OnConflict {
+	case "", ConflictAbort:
+		return "INSERT INTO " + values, nil
+	case ConflictIgnore:
+		return "INSERT OR IGNORE INTO " + values, nil
+	case ConflictReplace:
+		if len(opts.ConflictColumns) == 0 {
+			return "", fmt.Errorf("conflict policy %s needs ConflictColumns", opts.OnConflict)
+		}
+		return "INSERT OR REPLACE INTO " + values, nil
+	case ConflictUpsert:
+		if len(opts.ConflictColumns) == 0 {
+			return "", fmt.Errorf("conflict policy %s needs ConflictColumns", opts.OnConflict)
+		}
+		merge := opts.MergeColumns
+		if len(merge) == 0 {
+			keys := make(map[string]bool, len(opts.ConflictColumns))
+			for _, column := range opts.ConflictColumns {
+				keys[column] = true
+			}
+			for _, column := range columns {
+				if !keys[column] {
+					merge = append(merge, column)
+				}
+			}
+		}
+		if len(merge) == 0 {
+			return "", fmt.Errorf("conflict policy %s has no columns to merge", opts.OnConflict)
+		}
+		sets := make([]string, len(merge))
+		for i, column := range merge {
+			sets[i] = fmt.Sprintf("%s = excluded.%s", quoteIdentifier(column), quoteIdentifier(column))
+		}
+		return fmt.Sprintf("INSERT INTO %s ON CONFLICT (%s) DO UPDATE SET %s",
+			values, quoteColumns(opts.ConflictColumns), strings.Join(sets, ", ")), nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q", opts.OnConflict)
+	}
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestBulkLoadConflictPolicies verifies each conflict policy and its counts
+func TestBulkLoadConflictPolicies(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "bulk.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE products (sku TEXT PRIMARY KEY, name TEXT, price INTEGER);
+		INSERT INTO products VALUES ('a', 'Apple', 1), ('b', 'Banana', 2)`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	ctx := context.Background()
+	columns := []string{"sku", "name", "price"}
+	rows := [][]interface{}{{"a", "Apricot", 10}, {"c", "Cherry", 30}}
+	product := func(sku string) (name string, price int) {
+		db.QueryRow("SELECT name, price FROM products WHERE sku = ?", sku).Scan(&name, &price)
+		return name, price
+	}
+
+	if _, err := BulkLoad(ctx, "products", columns, rows, BulkLoadOptions{}); err == nil {
+		t.Error("Expected the abort policy to fail on a conflict")
+	}
+	if name, _ := product("c"); name != "" {
+		t.Error("Expected an aborted load to be rolled back")
+	}
+
+	result, err := BulkLoad(ctx, "products", columns, rows, BulkLoadOptions{OnConflict: ConflictIgnore})
+	if err != nil || *result != (BulkLoadResult{Inserted: 1, Skipped: 1}) {
+		t.Errorf("Unexpected ignore result %+v: %v", result, err)
+	}
+	if name, _ := product("a"); name != "Apple" {
+		t.Errorf("Expected ignore to keep the existing row, got %s", name)
+	}
+
+	rows = [][]interface{}{{"a", "Apricot", 10}, {"d", "Date", 40}}
+	result, err = BulkLoad(ctx, "products", columns, rows, BulkLoadOptions{OnConflict: ConflictUpsert, ConflictColumns: []string{"sku"}, MergeColumns: []string{"price"}})
+	if err != nil || *result != (BulkLoadResult{Inserted: 1, Updated: 1}) {
+		t.Errorf("Unexpected upsert result %+v: %v", result, err)
+	}
+	if name, price := product("a"); name != "Apple" || price != 10 {
+		t.Errorf("Expected upsert to merge only the price, got %s %d", name, price)
+	}
+
+	result, err = BulkLoad(ctx, "products", columns, [][]interface{}{{"b", "Blueberry", 5}}, BulkLoadOptions{OnConflict: ConflictReplace, ConflictColumns: []string{"sku"}})
+	if err != nil || *result != (BulkLoadResult{Updated: 1}) {
+		t.Errorf("Unexpected replace result %+v: %v", result, err)
+	}
+	if name, price := product("b"); name != "Blueberry" || price != 5 {
+		t.Errorf("Expected replace to overwrite the row, got %s %d", name, price)
+	}
+
+	if _, err := BulkLoad(ctx, "products", columns, rows, BulkLoadOptions{OnConflict: ConflictUpsert}); err == nil {
+		t.Error("Expected upsert without ConflictColumns to be rejected")
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCircuitBreakerCooldown is how long an open circuit rejects statements before
+// letting one operation through to probe the database
+const DefaultCircuitBreakerCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned by the package's helpers and the pipeline driver while the
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// SQLite primary result codes that indicate the database itself is unavailable, as
+// opposed to a problem with the statement
+const (
+	sqliteIOErr    = 10
+	sqliteCorrupt  = 11
+	sqliteCantOpen = 14
+)
+
+// circuitBreaker fails operations fast after repeated outage errors, so callers stop
+// queueing behind a database that cannot serve them
+type circuitBreaker struct {
+	mu        sync.Mutex
+	clock     Clock     // Time source for the cooldown (default: real time)
+	failures  int64     // Consecutive outage errors
+	openUntil time.Time // Zero while closed
+	probing   bool      // A probe operation is in flight after the cooldown
+}
+
+// breaker is the process-wide circuit breaker, configured by
+// DATABASE_CIRCUIT_BREAKER_THRESHOLD and DATABASE_CIRCUIT_BREAKER_COOLDOWN
+var breaker = &circuitBreaker{}
+
+// circuitBreakerSettings returns the failure threshold (0 = disabled) and cooldown
+func circuitBreakerSettings() (int64, time.Duration) {
+	cooldown := DefaultCircuitBreakerCooldown
+	if value := configValue("DATABASE_CIRCUIT_BREAKER_COOLDOWN"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			cooldown = d
+		}
+	}
+	return int64EnvOrZero("DATABASE_CIRCUIT_BREAKER_THRESHOLD"), cooldown
+}
+
+// now returns the current time on the breaker's clock; callers hold b.mu
+func (b *circuitBreaker) now() time.Time {
+	if b.clock == nil {
+		return time.Now()
+	}
+	return b.clock.Now()
+}
+
+// allow returns ErrCircuitOpen while the circuit is open. Once the cooldown has passed
+// the circuit is half-open: one operation is let through as the probe, reported by the
+// returned flag, and the rest are rejected until its outcome closes or reopens the circuit.
+func (b *circuitBreaker) allow() (bool, error) {
+	threshold, _ := circuitBreakerSettings()
+	if threshold <= 0 {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return false, nil
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return false, fmt.Errorf("%w after %d consecutive failures", ErrCircuitOpen, b.failures)
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record counts the outcome of an operation, opening the circuit once threshold outage
+// errors happen in a row and closing it on the first success. probe is the flag allow
+// returned, so a probe that ends without saying anything about the database (cancelled,
+// or rejected by a nested operation) lets the next operation probe instead.
+func (b *circuitBreaker) record(err error, probe bool) {
+	threshold, cooldown := circuitBreakerSettings()
+	if threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		// Says nothing about the database
+		return
+	}
+	if !isOutageError(err) {
+		if !b.openUntil.IsZero() {
+			logf("✅ Database circuit breaker closed")
+		}
+		b.failures, b.openUntil = 0, time.Time{}
+		return
+	}
+
+	b.failures++
+	if b.failures >= threshold {
+		if b.openUntil.IsZero() {
+			logErrorf("🚫 Database circuit breaker opened after %d consecutive failures: %v", b.failures, err)
+		}
+		b.openUntil = b.now().Add(cooldown)
+	}
+}
+
+// open reports whether the circuit is currently rejecting statements
+func (b *circuitBreaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openUntil.IsZero()
+}
+
+// reset closes the circuit
+func (b *circuitBreaker) reset() {
+	b.mu.Lock()
+	b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+	b.mu.Unlock()
+}
+
+// isOutageError reports whether err means the database could not serve a statement at
+// all (busy past the retry budget, locked, I/O errors, corruption or a full disk)
+func isOutageError(err error) bool {
+	if err == nil {
+		return false
+	}
+	code, ok := sqliteErrorCode(err)
+	if !ok {
+		return false
+	}
+	switch code & sqliteResultCodeBaseMask {
+	case sqliteBusy, sqliteLocked, sqliteIOErr, sqliteCorrupt, sqliteFull, sqliteCantOpen:
+		return true
+	}
+	return false
+}
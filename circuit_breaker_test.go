@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreakerOpensAndRecovers verifies the breaker opens after consecutive outage
+// errors, ignores statement errors, and lets a single probe through after the cooldown
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	t.Setenv("DATABASE_CIRCUIT_BREAKER_THRESHOLD", "2")
+	t.Setenv("DATABASE_CIRCUIT_BREAKER_COOLDOWN", "50ms")
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	breaker.reset()
+	breaker.clock = clock
+	defer func() {
+		breaker.reset()
+		breaker.clock = nil
+	}()
+
+	breaker.record(codedError(sqliteBusy), false)
+	breaker.record(codedError(sqliteConstraintUnique), false)
+	breaker.record(codedError(sqliteBusy), false)
+	if _, err := breaker.allow(); err != nil {
+		t.Fatalf("Expected a constraint error to reset the failure count, got %v", err)
+	}
+
+	breaker.record(codedError(sqliteIOErr), false)
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to open, got %v", err)
+	}
+
+	clock.Sleep(60 * time.Millisecond)
+	probe, err := breaker.allow()
+	if err != nil || !probe {
+		t.Fatalf("Expected a probe after the cooldown, got %v (probe %v)", err, probe)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected other operations to wait for the probe, got %v", err)
+	}
+	breaker.record(codedError(sqliteBusy), probe)
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected a failed probe to reopen the circuit, got %v", err)
+	}
+
+	clock.Sleep(60 * time.Millisecond)
+	probe, _ = breaker.allow()
+	breaker.record(context.Canceled, probe)
+	if probe, err = breaker.allow(); err != nil || !probe {
+		t.Fatalf("Expected a cancelled probe to let the next operation probe, got %v (probe %v)", err, probe)
+	}
+	breaker.record(nil, probe)
+	if breaker.open() {
+		t.Fatal("Expected a successful probe to close the circuit")
+	}
+}
+
+// TestCircuitBreakerRejectsStatements verifies the retry helpers and the pipeline driver
+// fail fast while the circuit is open and Health reports it
+func TestCircuitBreakerRejectsStatements(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "breaker.db"))
+	t.Setenv("DATABASE_CIRCUIT_BREAKER_THRESHOLD", "1")
+	breaker.reset()
+	defer breaker.reset()
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	breaker.record(codedError(sqliteFull), false)
+	if _, err := ExecWithRetry(db, "INSERT INTO items (id) VALUES (1)"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected helper writes to be rejected, got %v", err)
+	}
+	pipeline, err := sql.Open(DriverName, os.Getenv("DATABASE_FILE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipeline.Close()
+	if _, err := pipeline.Query("SELECT id FROM items"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected pipeline reads to be rejected, got %v", err)
+	}
+	if report := Health(context.Background()); report.Status != "degraded" {
+		t.Errorf("Expected Health to report the open circuit, got %+v", report)
+	}
+
+	t.Setenv("DATABASE_CIRCUIT_BREAKER_THRESHOLD", "0")
+	if _, err := ExecWithRetry(db, "INSERT INTO items (id) VALUES (1)"); err != nil {
+		t.Errorf("Expected a disabled breaker to allow statements, got %v", err)
+	}
+}
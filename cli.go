@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// RunCLI runs a database management subcommand. Applications call it from their own
+// main package (after importing the packages that register migrations and seeds):
+//
+//	func main() {
+//	    if err := database.RunCLI(context.Background(), os.Args[1:]); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//
+// Subcommands:
+//
+//	migrate                                run all registered migrations
+//	bootstrap-dev [-snapshot f] [-force]   build a fresh development database
+//	status                                 print registered migration sources
+//	generate-migration -dir d -name n      write a migration for the model/schema delta
+//	top-statements -url u [-by o] [-limit n]  show top statements from a running admin API
+//	config [-file f]                       print the effective configuration and its sources
+func RunCLI(ctx context.Context, args []string) error {
+	return runCLI(ctx, args, os.Stdout)
+}
+
+// runCLI implements RunCLI with a configurable output writer
+func runCLI(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		printCLIUsage(out)
+		return fmt.Errorf("missing subcommand")
+	}
+
+	switch args[0] {
+	case "migrate":
+		return RunAllMigrations()
+
+	case "bootstrap-dev":
+		fs := flag.NewFlagSet("bootstrap-dev", flag.ContinueOnError)
+		fs.SetOutput(out)
+		snapshot := fs.String("snapshot", "", "anonymized snapshot to start from")
+		force := fs.Bool("force", false, "replace an existing database file")
+		skipSeed := fs.Bool("skip-seed", false, "skip registered dev seeds")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return BootstrapDev(ctx, BootstrapOptions{Snapshot: *snapshot, Force: *force, SkipSeed: *skipSeed})
+
+	case "status":
+		stats, err := GetMigrationStats()
+		if err != nil {
+			return err
+		}
+		for _, source := range GetRegisteredSources() {
+			fmt.Fprintf(out, "%s\t%d files\n", source.Name, stats[source.Name])
+		}
+		return nil
+
+	case "generate-migration":
+		fs := flag.NewFlagSet("generate-migration", flag.ContinueOnError)
+		fs.SetOutput(out)
+		dir := fs.String("dir", "migrations", "directory to write the migration into")
+		name := fs.String("name", "model_changes", "migration name")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		path, err := WriteModelMigration(ctx, *dir, *name)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			fmt.Fprintln(out, "Schema matches models; no migration generated")
+			return nil
+		}
+		fmt.Fprintf(out, "Wrote %s\n", path)
+		return nil
+
+	case "top-statements":
+		fs := flag.NewFlagSet("top-statements", flag.ContinueOnError)
+		fs.SetOutput(out)
+		adminURL := fs.String("url", "", "base URL of the admin API (see NewAdminHandler)")
+		by := fs.String("by", string(ByTotalTime), "ordering: total_time, count or p99")
+		limit := fs.Int("limit", 20, "number of statements to show")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *adminURL == "" {
+			return fmt.Errorf("top-statements requires -url")
+		}
+		return printTopStatements(ctx, out, *adminURL, *by, *limit)
+
+	case "impact":
+		plan, err := PlanMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		report, err := EstimateImpact(ctx, plan)
+		if err != nil {
+			return err
+		}
+		printImpactReport(out, report)
+		return nil
+
+	case "config":
+		fs := flag.NewFlagSet("config", flag.ContinueOnError)
+		fs.SetOutput(out)
+		file := fs.String("file", "", "YAML config file to load (default: DATABASE_CONFIG_FILE)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		var cfg *Config
+		var err error
+		if *file != "" {
+			cfg, err = LoadConfig(*file)
+		} else {
+			cfg, err = EffectiveConfig()
+		}
+		if err != nil {
+			return err
+		}
+		return cfg.Dump(out)
+
+	case "help", "-h", "--help":
+		printCLIUsage(out)
+		return nil
+	}
+
+	printCLIUsage(out)
+	return fmt.Errorf("unknown subcommand: %s", args[0])
+}
+
+// printCLIUsage writes the subcommand summary
+func printCLIUsage(out io.Writer) {
+	fmt.Fprintln(out, "Usage: <app> <subcommand> [flags]")
+	fmt.Fprintln(out, "")
+	fmt.Fprintln(out, "Subcommands:")
+	fmt.Fprintln(out, "  migrate         Run all registered migrations")
+	fmt.Fprintln(out, "  bootstrap-dev   Build a fresh development database (-snapshot, -force, -skip-seed)")
+	fmt.Fprintln(out, "  status          Show registered migration sources")
+	fmt.Fprintln(out, "  generate-migration  Write a migration for model/schema differences (-dir, -name)")
+	fmt.Fprintln(out, "  impact          Estimate lock time and disk usage of pending migrations")
+	fmt.Fprintln(out, "  top-statements  Show the slowest or most frequent statements of a running app (-url, -by, -limit)")
+	fmt.Fprintln(out, "  config          Print the effective configuration and where each value came from (-file)")
+}
+
+// printTopStatements fetches top statements from an admin API and prints them as a table
+func printTopStatements(ctx context.Context, out io.Writer, baseURL, by string, limit int) error {
+	order, err := parseStatementOrder(by)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/statements?by=%s&limit=%d", strings.TrimRight(baseURL, "/"), url.QueryEscape(string(order)), limit)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Statements []StatementStats `json:"statements"`
+		Error      string           `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("invalid admin API response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("admin API returned %s: %s", resp.Status, body.Error)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CALLS\tTOTAL\tMEAN\tP99\tERRORS\tRETRIES\tSTATEMENT")
+	for _, s := range body.Statements {
+		fmt.Fprintf(w, "%d\t%v\t%v\t%v\t%d\t%d\t%s\n", s.Calls, s.TotalTime.Round(time.Microsecond),
+			s.MeanTime.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Errors, s.Retries, s.Statement)
+	}
+	return w.Flush()
+}
+
+// printImpactReport prints an impact report as a table followed by its warnings
+func printImpactReport(out io.Writer, report *ImpactReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tKIND\tTABLE\tROWS\tLOCK\tDISK\tRISK")
+	for _, s := range report.Statements {
+		fmt.Fprintf(w, "%s/%d_%s\t%s\t%s\t%d\t%v\t%d\t%s\n", s.Source, s.Version, s.Migration, s.Kind, s.Table,
+			s.Rows, s.EstimatedLockTime.Round(time.Millisecond), s.EstimatedDiskBytes, s.Risk)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\nTotal: lock %v, disk %d bytes, temp %d bytes, risk %s\n",
+		report.EstimatedLockTime.Round(time.Millisecond), report.EstimatedDiskBytes, report.EstimatedTempBytes, report.Risk)
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(out, "⚠️  %s\n", warning)
+	}
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunCLIUsage verifies usage is printed for help and for missing or unknown subcommands
+func TestRunCLIUsage(t *testing.T) {
+	ctx := context.Background()
+	for _, args := range [][]string{nil, {"nope"}} {
+		var out bytes.Buffer
+		if err := runCLI(ctx, args, &out); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+		if !strings.Contains(out.String(), "Subcommands:") {
+			t.Errorf("Expected usage for %v, got %q", args, out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runCLI(ctx, []string{"help"}, &out); err != nil || !strings.Contains(out.String(), "bootstrap-dev") {
+		t.Errorf("Expected help to print usage, got %v: %q", err, out.String())
+	}
+}
+
+// TestRunCLIMigrationCommands verifies bootstrap-dev, migrate and status against a
+// registered source
+func TestRunCLIMigrationCommands(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "cli.db"))
+	useBootstrapRegistries(t)
+	ctx := context.Background()
+
+	RegisterDevSeed("notes", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO notes (body) VALUES ('seeded')")
+		return err
+	})
+
+	var out bytes.Buffer
+	if err := runCLI(ctx, []string{"bootstrap-dev", "-skip-seed"}, &out); err != nil {
+		t.Fatalf("bootstrap-dev failed: %v", err)
+	}
+	if got := countNotes(t); got != 0 {
+		t.Fatalf("Expected -skip-seed to leave notes empty, got %d", got)
+	}
+	if err := runCLI(ctx, []string{"bootstrap-dev", "-force"}, &out); err != nil {
+		t.Fatalf("bootstrap-dev -force failed: %v", err)
+	}
+	if got := countNotes(t); got != 1 {
+		t.Fatalf("Expected -force to rebuild and seed, got %d notes", got)
+	}
+	if err := runCLI(ctx, []string{"bootstrap-dev", "-unknown"}, &out); err == nil {
+		t.Error("Expected an unknown flag to fail")
+	}
+
+	if err := runCLI(ctx, []string{"migrate"}, &out); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	out.Reset()
+	if err := runCLI(ctx, []string{"status"}, &out); err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if got := out.String(); got != "bootstrap-test\t2 files\n" {
+		t.Errorf("Unexpected status output %q", got)
+	}
+}
+
+// TestRunCLIConfig verifies config prints the loaded file's values with their source
+func TestRunCLIConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(dir, "cli.db"))
+	file := filepath.Join(dir, "database.yaml")
+	if err := os.WriteFile(file, []byte("log_format: json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runCLI(context.Background(), []string{"config", "-file", file}, &out); err != nil {
+		t.Fatalf("config failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "DATABASE_LOG_FORMAT=json\t# file") {
+		t.Errorf("Expected the file's log format in the dump, got %q", out.String())
+	}
+}
+
+// TestRunCLITopStatements verifies top-statements reads a running admin API
+func TestRunCLITopStatements(t *testing.T) {
+	EnableStatementStats(10)
+	defer DisableStatementStats()
+
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "cli.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(NewAdminHandler(AdminOptions{}))
+	defer server.Close()
+
+	ctx := context.Background()
+	var out bytes.Buffer
+	if err := runCLI(ctx, []string{"top-statements", "-url", server.URL, "-by", "count"}, &out); err != nil {
+		t.Fatalf("top-statements failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "CALLS") || !strings.Contains(out.String(), NormalizeStatement("CREATE TABLE items (id INTEGER PRIMARY KEY)")) {
+		t.Errorf("Expected a table with the statement, got %q", out.String())
+	}
+
+	if err := runCLI(ctx, []string{"top-statements"}, &out); err == nil {
+		t.Error("Expected top-statements without -url to fail")
+	}
+	if err := runCLI(ctx, []string{"top-statements", "-url", server.URL, "-by", "bogus"}, &out); err == nil {
+		t.Error("Expected an unknown ordering to fail")
+	}
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"modernc.org/sqlite"
+)
+
+// Collations registered on every connection, for use in COLLATE clauses and the
+// `collate=` model tag option
+const (
+	// CollationUnicodeNoCase compares case-insensitively across Unicode ("Ärger" = "ärger"),
+	// unlike SQLite's NOCASE which only folds ASCII letters
+	CollationUnicodeNoCase = "UNICODE_NOCASE"
+	// CollationNatural is UNICODE_NOCASE with runs of digits compared by numeric value,
+	// so "file2" sorts before "file10"
+	CollationNatural = "NATURAL_NOCASE"
+)
+
+// collationRegistry tracks collations registered with the SQLite driver
+var collationRegistry = struct {
+	mu    sync.Mutex
+	names map[string]bool
+}{names: make(map[string]bool)}
+
+func init() {
+	if err := RegisterCollation(CollationUnicodeNoCase, compareUnicodeNoCase); err != nil {
+		panic(err)
+	}
+	if err := RegisterCollation(CollationNatural, compareNatural); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterCollation makes a collation available to every connection opened afterwards,
+// through both the "sqlite" and pipeline drivers. compare returns a negative number,
+// zero or a positive number when a sorts before, equal to or after b, and must be a
+// consistent total order or indexes using it will be corrupt. Register collations
+// before opening connections, typically in an init function.
+func RegisterCollation(name string, compare func(a, b string) int) error {
+	collationRegistry.mu.Lock()
+	defer collationRegistry.mu.Unlock()
+
+	key := strings.ToUpper(name)
+	if collationRegistry.names[key] {
+		return fmt.Errorf("collation %s is already registered", name)
+	}
+	if err := sqlite.RegisterCollationUtf8(name, compare); err != nil {
+		return fmt.Errorf("failed to register collation %s: %w", name, err)
+	}
+	collationRegistry.names[key] = true
+	return nil
+}
+
+// Collate returns column with a COLLATE clause, for ORDER BY and comparisons:
+//
+//	query := "SELECT * FROM users ORDER BY " + database.Collate("name", database.CollationNatural)
+func Collate(column, collation string) string {
+	return quoteIdentifier(column) + " COLLATE " + collation
+}
+
+// compareUnicodeNoCase orders strings by their case-folded runes
+func compareUnicodeNoCase(a, b string) int {
+	for a != "" && b != "" {
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if c := compareFolded(ra, rb); c != 0 {
+			return c
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return len(a) - len(b)
+}
+
+// compareNatural orders strings like compareUnicodeNoCase but compares digit runs by
+// value; strings equal except for leading zeros fall back to compareUnicodeNoCase
+func compareNatural(a, b string) int {
+	origA, origB := a, b
+	for a != "" && b != "" {
+		if isASCIIDigit(a[0]) && isASCIIDigit(b[0]) {
+			digitsA, digitsB := leadingDigits(a), leadingDigits(b)
+			a, b = a[len(digitsA):], b[len(digitsB):]
+			digitsA, digitsB = strings.TrimLeft(digitsA, "0"), strings.TrimLeft(digitsB, "0")
+			if len(digitsA) != len(digitsB) {
+				return len(digitsA) - len(digitsB)
+			}
+			if c := strings.Compare(digitsA, digitsB); c != 0 {
+				return c
+			}
+			continue
+		}
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if c := compareFolded(ra, rb); c != 0 {
+			return c
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return compareUnicodeNoCase(origA, origB)
+}
+
+// compareFolded compares two runes after Unicode simple case folding
+func compareFolded(a, b rune) int {
+	return int(foldRune(a)) - int(foldRune(b))
+}
+
+// foldRune maps r to a canonical case: the lower case of its upper case, so every
+// member of a case-folding orbit (such as σ, ς and Σ) maps to the same rune
+func foldRune(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+// leadingDigits returns the run of ASCII digits at the start of s
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && isASCIIDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+// isASCIIDigit reports whether c is 0-9
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
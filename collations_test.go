@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestCollationOrdering verifies Unicode case folding and numeric-aware ordering
+func TestCollationOrdering(t *testing.T) {
+	for _, pair := range [][2]string{{"Ärger", "ärger"}, {"ΣΊΣΥΦΟΣ", "σίσυφος"}, {"Straße", "STRASSE"}} {
+		got := compareUnicodeNoCase(pair[0], pair[1])
+		if want := pair[0] != "Straße"; (got == 0) != want {
+			t.Errorf("compareUnicodeNoCase(%q, %q) = %d", pair[0], pair[1], got)
+		}
+	}
+
+	names := []string{"file10", "File2", "file1", "file02", "file2b"}
+	sort.Slice(names, func(i, j int) bool { return compareNatural(names[i], names[j]) < 0 })
+	if got := strings.Join(names, " "); got != "file1 file02 File2 file2b file10" {
+		t.Errorf("Unexpected natural order: %s", got)
+	}
+	if compareNatural("a01", "a1") == 0 || compareNatural("ABC", "abc") != 0 {
+		t.Error("Expected leading zeros to be distinct and case to be ignored")
+	}
+}
+
+type collatedUser struct {
+	ID   int64  `db:"id,pk"`
+	Name string `db:"name,unique,collate=UNICODE_NOCASE"`
+}
+
+// TestCollationsInSchema verifies a model column declared with a collation enforces it
+// through its unique index, and that Collate orders query results
+func TestCollationsInSchema(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "collations.db"))
+	ctx := context.Background()
+
+	globalRepositories.mu.Lock()
+	saved := globalRepositories.repositories
+	globalRepositories.repositories = nil
+	globalRepositories.mu.Unlock()
+	defer func() {
+		globalRepositories.mu.Lock()
+		globalRepositories.repositories = saved
+		globalRepositories.mu.Unlock()
+	}()
+	RegisterRepository(Repository{Table: "users", Model: collatedUser{}})
+
+	up, _, err := GenerateModelMigration(ctx)
+	if err != nil || !strings.Contains(up, `"name" TEXT COLLATE UNICODE_NOCASE NOT NULL`) {
+		t.Fatalf("Expected a collated column, got %q: %v", up, err)
+	}
+	db, err := sql.Open(DriverName, os.Getenv("DATABASE_FILE"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(up); err != nil {
+		t.Fatalf("Failed to apply migration: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users (name) VALUES ('Émile'), ('item 10'), ('item 9')"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users (name) VALUES ('émile')"); err == nil {
+		t.Error("Expected the unique index to treat Émile and émile as equal")
+	}
+
+	rows, err := db.Query("SELECT name FROM users WHERE name LIKE 'item%' ORDER BY " + Collate("name", CollationNatural))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+	var ordered []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		ordered = append(ordered, name)
+	}
+	if strings.Join(ordered, ",") != "item 9,item 10" {
+		t.Errorf("Expected natural order, got %v", ordered)
+	}
+
+	if err := RegisterCollation("unicode_nocase", compareUnicodeNoCase); err == nil {
+		t.Error("Expected registering a collation twice to fail")
+	}
+}
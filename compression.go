@@ -0,0 +1,229 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a compression format for column values
+type Codec string
+
+// Codecs
+const (
+	CodecZstd Codec = "zstd" // Fast, best ratio (default)
+	CodecGzip Codec = "gzip" // Readable by any tool
+)
+
+// DefaultCompressBatchSize is how many rows CompressColumn rewrites per transaction
+const DefaultCompressBatchSize = 500
+
+// Magic bytes that identify compressed values
+var (
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// zstd encoders and decoders are safe for concurrent EncodeAll/DecodeAll calls
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		encoder, _ := zstd.NewWriter(nil)
+		return encoder
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		decoder, _ := zstd.NewReader(nil)
+		return decoder
+	})
+)
+
+// Compressed is a column value stored compressed and decompressed transparently when
+// scanned. Scan recognizes zstd and gzip by their magic bytes and passes any other
+// value through, so a column can mix compressed rows with legacy uncompressed ones:
+//
+//	db.Exec("INSERT INTO events (payload) VALUES (?)", database.Compressed{Data: payload})
+//	var payload database.Compressed
+//	db.QueryRow("SELECT payload FROM events WHERE id = ?", id).Scan(&payload)
+type Compressed struct {
+	Data  []byte
+	Codec Codec // Codec used when writing (default: CodecZstd)
+}
+
+// Value compresses Data for storage; nil Data is stored as NULL
+func (c Compressed) Value() (driver.Value, error) {
+	if c.Data == nil {
+		return nil, nil
+	}
+	return compressBytes(c.Data, c.Codec)
+}
+
+// Scan decompresses a stored value, detecting its codec
+func (c *Compressed) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		c.Data, c.Codec = nil, ""
+		return nil
+	case string:
+		return c.decode([]byte(v))
+	case []byte:
+		return c.decode(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Compressed", src)
+	}
+}
+
+// String returns the decompressed value as text
+func (c Compressed) String() string {
+	return string(c.Data)
+}
+
+// decode decompresses data according to its magic bytes
+func (c *Compressed) decode(data []byte) error {
+	decoded, codec, err := decompressBytes(data)
+	if err != nil {
+		return err
+	}
+	c.Data, c.Codec = decoded, codec
+	return nil
+}
+
+// compressBytes compresses data with codec
+func compressBytes(data []byte, codec Codec) ([]byte, error) {
+	switch codec {
+	case "", CodecZstd:
+		return zstdEncoder().EncodeAll(data, make([]byte, 0, len(data)/4)), nil
+	case CodecGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", codec)
+	}
+}
+
+// decompressBytes decompresses zstd or gzip data, returning anything else as is (with an
+// empty codec) and copying it so callers may keep it
+func decompressBytes(data []byte) ([]byte, Codec, error) {
+	switch {
+	case bytes.HasPrefix(data, zstdMagic):
+		decoded, err := zstdDecoder().DecodeAll(data, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress zstd value: %w", err)
+		}
+		return decoded, CodecZstd, nil
+	case bytes.HasPrefix(data, gzipMagic):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress gzip value: %w", err)
+		}
+		decoded, err := io.ReadAll(r)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress gzip value: %w", err)
+		}
+		return decoded, CodecGzip, nil
+	default:
+		return bytes.Clone(data), "", nil
+	}
+}
+
+// CompressColumn compresses the values of column in table that are not compressed yet,
+// in batches of DefaultCompressBatchSize rows (one transaction each) walked in rowid
+// order, and returns how many it rewrote. It is idempotent, so an interrupted run can
+// simply be repeated. Values are stored as BLOBs; read them with Compressed.
+func CompressColumn(ctx context.Context, table, column string, codec Codec) (int64, error) {
+	if _, err := compressBytes(nil, codec); err != nil {
+		return 0, err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	selectBatch := fmt.Sprintf("SELECT rowid, %s FROM %s WHERE rowid > ? AND %s IS NOT NULL ORDER BY rowid LIMIT ?",
+		quoteIdentifier(column), quoteIdentifier(table), quoteIdentifier(column))
+	update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", quoteIdentifier(table), quoteIdentifier(column))
+
+	var total int64
+	after := int64(-1 << 63)
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		type pending struct {
+			rowid int64
+			value []byte
+		}
+		var batch []pending
+		last, read := after, 0
+		err := retryDatabaseOperationContext(ctx, func() error {
+			batch, last, read = batch[:0], after, 0
+			rows, err := db.QueryContext(ctx, selectBatch, after, DefaultCompressBatchSize)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var rowid int64
+				var value []byte
+				if err := rows.Scan(&rowid, &value); err != nil {
+					return err
+				}
+				last, read = rowid, read+1
+				if bytes.HasPrefix(value, zstdMagic) || bytes.HasPrefix(value, gzipMagic) {
+					continue
+				}
+				compressed, err := compressBytes(value, codec)
+				if err != nil {
+					return err
+				}
+				batch = append(batch, pending{rowid: rowid, value: compressed})
+			}
+			return rows.Err()
+		}, DefaultRetryConfig())
+		if err != nil {
+			return total, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+		}
+		if read == 0 {
+			break
+		}
+
+		if len(batch) > 0 {
+			err = WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+				for _, row := range batch {
+					if _, err := tx.ExecContext(ctx, update, row.value, row.rowid); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return total, fmt.Errorf("failed to compress %s.%s: %w", table, column, err)
+			}
+			total += int64(len(batch))
+		}
+		after = last
+	}
+	logf("🗜️  Compressed %d values of %s.%s with %s", total, table, column, codecName(codec))
+	return total, nil
+}
+
+// codecName returns the name of a codec, resolving the default
+func codecName(codec Codec) Codec {
+	if codec == "" {
+		return CodecZstd
+	}
+	return codec
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCompressedColumnsMixCodecsAndLegacyRows verifies round trips with both codecs,
+// reading legacy rows and compressing existing rows in batches
+func TestCompressedColumnsMixCodecsAndLegacyRows(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "compress.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY, payload BLOB)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	payload := []byte(strings.Repeat(`{"type":"click","target":"button"}`, 100))
+	if _, err := db.Exec("INSERT INTO events (id, payload) VALUES (1, ?), (2, ?), (3, ?), (4, NULL)",
+		Compressed{Data: payload}, Compressed{Data: payload, Codec: CodecGzip}, string(payload)); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	var stored int
+	if err := db.QueryRow("SELECT length(payload) FROM events WHERE id = 1").Scan(&stored); err != nil || stored >= len(payload)/10 {
+		t.Errorf("Expected the zstd value to be compressed, stored %d of %d bytes: %v", stored, len(payload), err)
+	}
+	for id, codec := range map[int]Codec{1: CodecZstd, 2: CodecGzip, 3: ""} {
+		var value Compressed
+		if err := db.QueryRow("SELECT payload FROM events WHERE id = ?", id).Scan(&value); err != nil || !bytes.Equal(value.Data, payload) || value.Codec != codec {
+			t.Errorf("Row %d: unexpected value (codec %q): %v", id, value.Codec, err)
+		}
+	}
+
+	compressed, err := CompressColumn(context.Background(), "events", "payload", CodecZstd)
+	if err != nil || compressed != 1 {
+		t.Errorf("Expected only the legacy row to be compressed, got %d: %v", compressed, err)
+	}
+	var value Compressed
+	if err := db.QueryRow("SELECT payload FROM events WHERE id = 3").Scan(&value); err != nil || value.Codec != CodecZstd || value.String() != string(payload) {
+		t.Errorf("Unexpected migrated value (codec %q): %v", value.Codec, err)
+	}
+	if compressed, err := CompressColumn(context.Background(), "events", "payload", CodecZstd); err != nil || compressed != 0 {
+		t.Errorf("Expected a second run to do nothing, got %d: %v", compressed, err)
+	}
+}
@@ -0,0 +1,401 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the package's environment configuration as one typed value. Each field
+// mirrors the environment variable in its env tag and the YAML key in its yaml tag.
+// Zero values mean "not set", so the package default applies.
+type Config struct {
+	File                      string        `yaml:"file" env:"DATABASE_FILE"`
+	ForeignKeys               bool          `yaml:"foreign_keys" env:"DATABASE_FOREIGN_KEYS"`
+	JournalMode               string        `yaml:"journal_mode" env:"DATABASE_JOURNAL_MODE"`
+	MaxSize                   int64         `yaml:"max_size" env:"DATABASE_MAX_SIZE"`
+	MaxResultRows             int64         `yaml:"max_result_rows" env:"DATABASE_MAX_RESULT_ROWS"`
+	MaxResultBytes            int64         `yaml:"max_result_bytes" env:"DATABASE_MAX_RESULT_BYTES"`
+	ResultLimitMode           string        `yaml:"result_limit_mode" env:"DATABASE_RESULT_LIMIT_MODE"`
+	LogLevel                  string        `yaml:"log_level" env:"DATABASE_LOG_LEVEL"`
+	LogFormat                 string        `yaml:"log_format" env:"DATABASE_LOG_FORMAT"`
+	QueryLog                  string        `yaml:"query_log" env:"DATABASE_QUERY_LOG"`
+	QueryLogSample            float64       `yaml:"query_log_sample" env:"DATABASE_QUERY_LOG_SAMPLE"`
+	MigrationsSerial          bool          `yaml:"migrations_serial" env:"DATABASE_MIGRATIONS_SERIAL"`
+	MigrationPreflight        *bool         `yaml:"migration_preflight" env:"DATABASE_MIGRATION_PREFLIGHT"`
+	MigrationProgressInterval time.Duration `yaml:"migration_progress_interval" env:"DATABASE_MIGRATION_PROGRESS_INTERVAL"`
+	MigrationCache            string        `yaml:"migration_cache" env:"DATABASE_MIGRATION_CACHE"`
+	MigrationPublicKey        string        `yaml:"migration_public_key" env:"DATABASE_MIGRATION_PUBLIC_KEY"`
+	RequireSignedMigrations   bool          `yaml:"require_signed_migrations" env:"DATABASE_REQUIRE_SIGNED_MIGRATIONS"`
+	CircuitBreakerThreshold   int64         `yaml:"circuit_breaker_threshold" env:"DATABASE_CIRCUIT_BREAKER_THRESHOLD"`
+	CircuitBreakerCooldown    time.Duration `yaml:"circuit_breaker_cooldown" env:"DATABASE_CIRCUIT_BREAKER_COOLDOWN"`
+	ArchiveDir                string        `yaml:"archive_dir" env:"DATABASE_ARCHIVE_DIR"`
+	TempDir                   string        `yaml:"temp_dir" env:"SQLITE_TMPDIR"`
+	Service                   string        `yaml:"service" env:"DD_SERVICE"`
+	TracingAPIKeySecretARN    string        `yaml:"tracing_api_key_secret_arn" env:"DD_API_KEY_SECRET_ARN"`
+	Profile                   string        `yaml:"-" env:"DATABASE_PROFILE"`
+
+	Retry          *RetryPolicy `yaml:"-"`               // Installed with SetRetryPolicy by UseConfig when set
+	StatementStats int          `yaml:"statement_stats"` // Statements tracked by EnableStatementStats when UseConfig installs the config (0 = leave off)
+
+	sources map[string]string // Where each set field came from, by env name
+}
+
+// Config sources, lowest precedence first
+const (
+	ConfigSourceDefault  = "default"
+	ConfigSourceProfile  = "profile"
+	ConfigSourceFile     = "file"
+	ConfigSourceEnv      = "env"
+	ConfigSourceOverride = "override"
+)
+
+// DefaultConfig returns the package defaults
+func DefaultConfig() Config {
+	return Config{
+		ResultLimitMode:           "error",
+		LogLevel:                  "info",
+		LogFormat:                 string(LogFormatEmoji),
+		QueryLog:                  "stdout",
+		QueryLogSample:            1,
+		MigrationProgressInterval: DefaultMigrationProgressInterval,
+	}
+}
+
+// LoadConfig merges, from lowest to highest precedence: DefaultConfig, the profile named
+// by DATABASE_PROFILE, the YAML file at path (DATABASE_CONFIG_FILE when path is empty; no
+// file when both are), environment variables that are set, and overrides. The result is
+// validated; pass it to UseConfig to make the package read it instead of the environment.
+//
+//	cfg, err := database.LoadConfig("config/database.yaml", func(c *database.Config) {
+//	    c.LogLevel = "warn"
+//	})
+func LoadConfig(path string, overrides ...func(*Config)) (*Config, error) {
+	return loadConfig(os.Getenv("DATABASE_PROFILE"), path, overrides)
+}
+
+// loadConfig implements LoadConfig with an explicit profile
+func loadConfig(profile, path string, overrides []func(*Config)) (*Config, error) {
+	cfg := DefaultConfig()
+	cfg.sources = map[string]string{}
+	if profile != "" {
+		preset, ok := profiles[profile]
+		if !ok {
+			return nil, invalidConfig("unknown profile %q; use one of %s", profile, strings.Join(ProfileNames(), ", "))
+		}
+		before := cfg.Values()
+		preset(&cfg)
+		cfg.Profile = profile
+		cfg.markSources(before, ConfigSourceProfile)
+	}
+
+	if path == "" {
+		path = os.Getenv("DATABASE_CONFIG_FILE")
+	}
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		before := cfg.Values()
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		cfg.markSources(before, ConfigSourceFile)
+	}
+
+	before := cfg.Values()
+	if err := cfg.forEachField(func(name string, field reflect.Value) error {
+		value, ok := os.LookupEnv(name)
+		if !ok || name == "DATABASE_PROFILE" {
+			return nil
+		}
+		if err := setConfigField(field, value); err != nil {
+			return fmt.Errorf("%w: %s=%q: %v", ErrInvalidConfig, name, value, err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	cfg.markSources(before, ConfigSourceEnv)
+
+	for _, override := range overrides {
+		before := cfg.Values()
+		override(&cfg)
+		cfg.markSources(before, ConfigSourceOverride)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// forEachField calls fn with the env name and value of every configuration field
+func (c *Config) forEachField(fn func(name string, field reflect.Value) error) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		if err := fn(name, v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Values returns every field as its environment variable's value; unset fields are empty
+func (c *Config) Values() map[string]string {
+	values := map[string]string{}
+	c.forEachField(func(name string, field reflect.Value) error {
+		values[name] = formatConfigField(field)
+		return nil
+	})
+	return values
+}
+
+// markSources records source for every field that differs from before, or for every set
+// field when before is nil
+func (c *Config) markSources(before map[string]string, source string) {
+	for name, value := range c.Values() {
+		if (before == nil && value != "") || (before != nil && before[name] != value) {
+			c.sources[name] = source
+		}
+	}
+}
+
+// Source returns where the value of an environment variable's field came from
+func (c *Config) Source(name string) string {
+	if source, ok := c.sources[name]; ok {
+		return source
+	}
+	return ConfigSourceDefault
+}
+
+// Dump writes the effective configuration as NAME=value lines annotated with their source
+func (c *Config) Dump(w io.Writer) error {
+	var lines []string
+	c.forEachField(func(name string, field reflect.Value) error {
+		lines = append(lines, fmt.Sprintf("%s=%s\t# %s", name, formatConfigField(field), c.Source(name)))
+		return nil
+	})
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// Validate rejects values the package would misread or silently ignore
+func (c *Config) Validate() error {
+	var errs []error
+	if c.File == "" {
+		errs = append(errs, invalidConfig("DATABASE_FILE (file) is required; set it to the SQLite database path"))
+	}
+	if c.MaxSize < 0 || c.MaxResultRows < 0 || c.MaxResultBytes < 0 {
+		errs = append(errs, invalidConfig("max_size, max_result_rows and max_result_bytes must not be negative; use 0 for unlimited"))
+	}
+	checks := []struct {
+		name, value string
+		allowed     []string
+	}{
+		{"DATABASE_RESULT_LIMIT_MODE", c.ResultLimitMode, []string{"error", "truncate"}},
+		{"DATABASE_JOURNAL_MODE", strings.ToLower(c.JournalMode), []string{"delete", "truncate", "persist", "memory", "wal", "off"}},
+		{"DATABASE_LOG_LEVEL", strings.ToLower(c.LogLevel), []string{"info", "warn", "warning", "quiet", "error", "off", "none"}},
+		{"DATABASE_LOG_FORMAT", strings.ToLower(c.LogFormat), []string{"emoji", "text", "json"}},
+		{"DATABASE_QUERY_LOG", strings.ToLower(c.QueryLog), []string{"stdout", "otlp"}},
+	}
+	for _, check := range checks {
+		if check.value != "" && !containsString(check.allowed, check.value) {
+			errs = append(errs, invalidConfig("%s %q is unknown; use one of %s", check.name, check.value, strings.Join(check.allowed, ", ")))
+		}
+	}
+	if c.QueryLogSample < 0 || c.QueryLogSample > 1 {
+		errs = append(errs, invalidConfig("DATABASE_QUERY_LOG_SAMPLE (%v) is a fraction and must be between 0 and 1", c.QueryLogSample))
+	}
+	if c.StatementStats < 0 {
+		errs = append(errs, invalidConfig("statement_stats (%d) must not be negative; use 0 to leave statement stats off", c.StatementStats))
+	}
+	if c.Retry != nil {
+		if err := c.Retry.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.CircuitBreakerThreshold < 0 || c.CircuitBreakerCooldown < 0 {
+		errs = append(errs, invalidConfig("circuit_breaker_threshold and circuit_breaker_cooldown must not be negative; use 0 to disable the breaker"))
+	}
+	if c.MigrationProgressInterval < 0 {
+		errs = append(errs, invalidConfig("DATABASE_MIGRATION_PROGRESS_INTERVAL (%v) must not be negative", c.MigrationProgressInterval))
+	}
+	if strings.EqualFold(c.JournalMode, "wal") && c.File != "" {
+		if err := validateWALLocation(c.File); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// walFileSystemInfo inspects the filesystem holding a WAL database (replaced in tests)
+var walFileSystemInfo = fileSystemInfo
+
+// validateWALLocation rejects WAL journaling for a database on a read-only mount, where
+// the -wal and -shm files cannot be created, or on a network filesystem, where WAL's
+// shared-memory index does not work across hosts
+func validateWALLocation(file string) error {
+	path := strings.TrimPrefix(file, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" || path == ":memory:" {
+		return nil
+	}
+	// The database may not exist yet; its directory decides where the WAL goes
+	dir := filepath.Dir(path)
+	readOnly, network, err := walFileSystemInfo(dir)
+	if err != nil {
+		return nil
+	}
+	if readOnly {
+		return invalidConfig("DATABASE_JOURNAL_MODE is wal but %s is on a read-only mount, where the -wal and -shm files cannot be created; set journal_mode to delete, or move the database to a writable volume", dir)
+	}
+	if network != "" {
+		return invalidConfig("DATABASE_JOURNAL_MODE is wal but %s is on %s (e.g. EFS), where WAL's shared-memory index does not work across hosts; set journal_mode to delete and coordinate writers with StartEFSCoordination, or move the database to local storage", dir, network)
+	}
+	return nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// formatConfigField returns a field as an environment variable value; zero is empty
+func formatConfigField(field reflect.Value) string {
+	if field.IsZero() {
+		return ""
+	}
+	switch value := field.Interface().(type) {
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case *bool:
+		return strconv.FormatBool(*value)
+	case time.Duration:
+		return value.String()
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// setConfigField parses an environment variable value into a field
+func setConfigField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case bool, *bool:
+		var b bool
+		switch strings.ToLower(value) {
+		case "1", "true", "on", "yes":
+			b = true
+		case "", "0", "false", "off", "no":
+		default:
+			return fmt.Errorf("not a boolean")
+		}
+		if field.Kind() == reflect.Ptr {
+			field.Set(reflect.ValueOf(&b))
+		} else {
+			field.SetBool(b)
+		}
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	}
+	return nil
+}
+
+// installedConfig is a config installed by UseConfig with its values precomputed
+type installedConfig struct {
+	config Config
+	values map[string]string
+}
+
+// activeConfig is the config installed by UseConfig, if any
+var activeConfig atomic.Pointer[installedConfig]
+
+// UseConfig makes the package read cfg instead of the environment; nil goes back to the
+// environment. It also installs cfg's retry policy and enables statement stats when
+// they are set. Logging settings are re-read on the next log line unless SetLogConfig
+// is called afterwards.
+func UseConfig(cfg *Config) {
+	if cfg == nil {
+		activeConfig.Store(nil)
+	} else {
+		activeConfig.Store(&installedConfig{config: *cfg, values: cfg.Values()})
+		if cfg.Retry != nil {
+			SetRetryPolicy(*cfg.Retry)
+		}
+		if cfg.StatementStats > 0 {
+			EnableStatementStats(cfg.StatementStats)
+		}
+	}
+	logMu.Lock()
+	logConfig = nil
+	logMu.Unlock()
+}
+
+// EffectiveConfig returns the config installed by UseConfig, or loads one from the
+// environment and DATABASE_CONFIG_FILE
+func EffectiveConfig() (*Config, error) {
+	if installed := activeConfig.Load(); installed != nil {
+		cfg := installed.config
+		return &cfg, nil
+	}
+	return LoadConfig("")
+}
+
+// configValue returns the setting for an environment variable: from the config installed
+// by UseConfig, or the environment
+func configValue(name string) string {
+	if installed := activeConfig.Load(); installed != nil {
+		if value, ok := installed.values[name]; ok {
+			return value
+		}
+	}
+	return os.Getenv(name)
+}
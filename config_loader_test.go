@@ -0,0 +1,102 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadConfigPrecedence verifies defaults, file, environment and overrides are merged
+// in that order and that an installed config replaces the environment
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "database.yaml")
+	content := "file: " + filepath.Join(dir, "from-file.db") + "\nlog_level: warn\nmax_size: 1048576\nmigration_progress_interval: 30s\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("DATABASE_LOG_LEVEL", "error")
+	t.Setenv("DATABASE_FOREIGN_KEYS", "on")
+
+	cfg, err := LoadConfig(path, func(c *Config) { c.MaxSize = 2 << 20 })
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.LogLevel != "error" || !cfg.ForeignKeys || cfg.MaxSize != 2<<20 || cfg.MigrationProgressInterval != 30*time.Second || cfg.LogFormat != "emoji" {
+		t.Errorf("Unexpected merged config: %+v", cfg)
+	}
+	for name, want := range map[string]string{
+		"DATABASE_FILE":       ConfigSourceFile,
+		"DATABASE_LOG_LEVEL":  ConfigSourceEnv,
+		"DATABASE_MAX_SIZE":   ConfigSourceOverride,
+		"DATABASE_LOG_FORMAT": ConfigSourceDefault,
+	} {
+		if got := cfg.Source(name); got != want {
+			t.Errorf("Expected %s from %s, got %s", name, want, got)
+		}
+	}
+
+	var dump bytes.Buffer
+	if err := runCLI(context.Background(), []string{"config", "-file", path}, &dump); err != nil {
+		t.Fatalf("config subcommand failed: %v", err)
+	}
+	if !strings.Contains(dump.String(), "DATABASE_LOG_LEVEL=error\t# env") {
+		t.Errorf("Expected the dump to show values with their sources, got:\n%s", dump.String())
+	}
+
+	UseConfig(cfg)
+	defer UseConfig(nil)
+	t.Setenv("DATABASE_FILE", filepath.Join(dir, "from-env.db"))
+	if got := configValue("DATABASE_FILE"); got != cfg.File {
+		t.Errorf("Expected the installed config to win over later environment changes, got %s", got)
+	}
+	if !isForeignKeysEnabled() {
+		t.Error("Expected settings to be read from the installed config")
+	}
+}
+
+// TestLoadConfigRejectsInvalidValues verifies unknown keys and bad values fail loading
+func TestLoadConfigRejectsInvalidValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database.yaml")
+	if err := os.WriteFile(path, []byte("file: app.db\nlog_levle: warn\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "log_levle") {
+		t.Errorf("Expected the misspelled key to be reported, got %v", err)
+	}
+
+	t.Setenv("DATABASE_FILE", "app.db")
+	t.Setenv("DATABASE_RESULT_LIMIT_MODE", "clip")
+	if _, err := LoadConfig(""); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "DATABASE_RESULT_LIMIT_MODE") {
+		t.Errorf("Expected an unknown result limit mode to be rejected, got %v", err)
+	}
+}
+
+// TestValidateRejectsWALOnUnsuitableMounts verifies WAL journaling is rejected for a
+// database on a read-only mount or a network filesystem
+func TestValidateRejectsWALOnUnsuitableMounts(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{File: filepath.Join(dir, "app.db"), JournalMode: "wal"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected WAL on a local writable directory to pass, got %v", err)
+	}
+
+	defer func(original func(string) (bool, string, error)) { walFileSystemInfo = original }(walFileSystemInfo)
+	walFileSystemInfo = func(string) (bool, string, error) { return true, "", nil }
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("Expected WAL on a read-only mount to be rejected, got %v", err)
+	}
+	walFileSystemInfo = func(string) (bool, string, error) { return false, "NFS", nil }
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "NFS") {
+		t.Errorf("Expected WAL on NFS to be rejected, got %v", err)
+	}
+	cfg.JournalMode = "delete"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected rollback journaling on NFS to pass, got %v", err)
+	}
+}
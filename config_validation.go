@@ -0,0 +1,85 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidConfig is returned by Validate for configurations that would misbehave at runtime
+var ErrInvalidConfig = errors.New("invalid database configuration")
+
+// invalidConfig returns an ErrInvalidConfig describing one problem
+func invalidConfig(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, args...))
+}
+
+// Validate reports every setting that would make the retry loop misbehave, so callers
+// can fail at startup. The zero RetryConfig is valid and disables retries.
+func (c RetryConfig) Validate() error {
+	var errs []error
+	if c.MaxRetryDuration < 0 || c.BaseDelay < 0 || c.MaxDelay < 0 {
+		errs = append(errs, invalidConfig("RetryConfig durations must not be negative (MaxRetryDuration %v, BaseDelay %v, MaxDelay %v)",
+			c.MaxRetryDuration, c.BaseDelay, c.MaxDelay))
+	}
+	if c.MaxRetryDuration == 0 && c.BaseDelay > 0 {
+		errs = append(errs, invalidConfig("RetryConfig.MaxRetryDuration is 0, so BUSY errors are never retried despite BaseDelay %v; set a budget such as %v, or zero the whole config to disable retries",
+			c.BaseDelay, DefaultMaxRetryDuration))
+	}
+	if c.MaxRetryDuration > 0 && c.BaseDelay == 0 {
+		errs = append(errs, invalidConfig("RetryConfig.BaseDelay is 0, so the first BUSY error gives up immediately; set it to e.g. %v", DefaultBaseDelay))
+	}
+	if c.BaseDelay > c.MaxDelay {
+		errs = append(errs, invalidConfig("RetryConfig.BaseDelay (%v) is greater than MaxDelay (%v); lower BaseDelay or raise MaxDelay", c.BaseDelay, c.MaxDelay))
+	}
+	if c.JitterPercent < 0 || c.JitterPercent > 1 {
+		errs = append(errs, invalidConfig("RetryConfig.JitterPercent (%v) must be between 0 and 1, e.g. %v for ±25%%", c.JitterPercent, DefaultJitterPercent))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the retry config of every statement kind
+func (p RetryPolicy) Validate() error {
+	var errs []error
+	for kind, config := range map[StatementKind]RetryConfig{StatementRead: p.Read, StatementWrite: p.Write, StatementDDL: p.DDL} {
+		if err := config.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("retry policy for %s statements: %w", kind, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate rejects unknown log levels and formats
+func (c LogConfig) Validate() error {
+	var errs []error
+	if c.Level < LogLevelInfo || c.Level > LogLevelOff {
+		errs = append(errs, invalidConfig("LogConfig.Level %d is unknown; use LogLevelInfo, LogLevelWarn, LogLevelError or LogLevelOff", c.Level))
+	}
+	switch c.Format {
+	case "", LogFormatEmoji, LogFormatText, LogFormatJSON:
+	default:
+		errs = append(errs, invalidConfig("LogConfig.Format %q is unknown; use %q, %q or %q", c.Format, LogFormatEmoji, LogFormatText, LogFormatJSON))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate rejects adaptive retry settings outside their meaningful ranges; zero values
+// select the defaults and are valid
+func (o AdaptiveRetryOptions) Validate() error {
+	var errs []error
+	if o.Interval < 0 {
+		errs = append(errs, invalidConfig("AdaptiveRetryOptions.Interval (%v) must not be negative", o.Interval))
+	}
+	if o.TargetBusyRate < 0 || o.TargetBusyRate > 1 {
+		errs = append(errs, invalidConfig("AdaptiveRetryOptions.TargetBusyRate (%v) is a share of attempts and must be between 0 and 1", o.TargetBusyRate))
+	}
+	if o.IncreaseFactor != 0 && o.IncreaseFactor <= 1 {
+		errs = append(errs, invalidConfig("AdaptiveRetryOptions.IncreaseFactor (%v) must be greater than 1, or the multiplier never grows", o.IncreaseFactor))
+	}
+	if o.MaxMultiplier != 0 && o.MaxMultiplier < 1 {
+		errs = append(errs, invalidConfig("AdaptiveRetryOptions.MaxMultiplier (%v) must be at least 1", o.MaxMultiplier))
+	}
+	if o.MinBudgetFraction < 0 || o.MinBudgetFraction > 1 {
+		errs = append(errs, invalidConfig("AdaptiveRetryOptions.MinBudgetFraction (%v) must be between 0 and 1", o.MinBudgetFraction))
+	}
+	return errors.Join(errs...)
+}
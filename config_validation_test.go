@@ -0,0 +1,59 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRetryConfigValidate verifies nonsensical retry settings are rejected with a reason
+func TestRetryConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*RetryConfig)
+		want   string
+	}{
+		{"default", func(*RetryConfig) {}, ""},
+		{"zero disables retries", func(c *RetryConfig) { *c = RetryConfig{} }, ""},
+		{"base above max", func(c *RetryConfig) { c.BaseDelay = 2 * time.Second }, "greater than MaxDelay"},
+		{"jitter above one", func(c *RetryConfig) { c.JitterPercent = 1.5 }, "between 0 and 1"},
+		{"zero budget", func(c *RetryConfig) { c.MaxRetryDuration = 0 }, "never retried"},
+		{"zero base delay", func(c *RetryConfig) { c.BaseDelay = 0 }, "gives up immediately"},
+		{"negative", func(c *RetryConfig) { c.MaxDelay = -time.Second }, "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultRetryConfig()
+			tt.modify(&config)
+			err := config.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Expected a valid config, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected ErrInvalidConfig mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestValidateOtherConfigs verifies policies, log configs and adaptive options are checked
+func TestValidateOtherConfigs(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.DDL.JitterPercent = -1
+	if err := policy.Validate(); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "ddl statements") {
+		t.Errorf("Expected the DDL entry to be rejected, got %v", err)
+	}
+	if err := (LogConfig{Format: "yaml"}).Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected an unknown log format to be rejected, got %v", err)
+	}
+	if err := (AdaptiveRetryOptions{}).Validate(); err != nil {
+		t.Errorf("Expected default adaptive options to be valid, got %v", err)
+	}
+	if err := (AdaptiveRetryOptions{TargetBusyRate: 20}).Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected a busy rate above 1 to be rejected, got %v", err)
+	}
+}
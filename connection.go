@@ -1,27 +1,50 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"log"
-	"os"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 )
 
 // Database connection management with retry support
 
-// GetDB returns a database connection
+// maxSharedIdleConns bounds the idle connections kept per database file
+const maxSharedIdleConns = 8
+
+// sharedPool keeps idle SQLite connections for one data source name. Every handle GetDB
+// returns for that name borrows from it, so connections outlive the handles.
+type sharedPool struct {
+	dsn    string
+	mu     sync.Mutex
+	idle   []*sharedConn
+	closed bool // Released connections are closed instead of kept
+}
+
+// sharedPools holds the pools behind GetDB, keyed by data source name
+var sharedPools = struct {
+	mu    sync.Mutex
+	pools map[string]*sharedPool
+}{pools: make(map[string]*sharedPool)}
+
+// GetDB returns a database handle for DATABASE_FILE. Handles borrow connections from one
+// pool per database file, so repeated calls reuse open connections; closing a handle
+// returns its connections to that pool, reset to the state they were opened in. Close
+// each handle when done, and call CloseAll at shutdown to release the idle connections.
 func GetDB() (*sql.DB, error) {
-	databaseFile := os.Getenv("DATABASE_FILE")
+	databaseFile := configValue("DATABASE_FILE")
 	if databaseFile == "" {
 		panic("DATABASE_FILE environment variable is required but not set")
 	}
 
-	db, err := sql.Open("sqlite", databaseFile)
-	if err != nil {
-		return nil, err
-	}
+	db := sql.OpenDB(sharedConnector{pool: sharedPoolFor(buildDSN(databaseFile))})
+	db.SetMaxIdleConns(0) // Released connections go back to the shared pool
 
 	// Test the connection with retry logic for SQLITE_BUSY errors
-	err = retryDatabaseOperation(func() error {
+	err := retryDatabaseOperation(func() error {
 		return db.Ping()
 	}, DefaultRetryConfig())
 
@@ -33,39 +56,392 @@ func GetDB() (*sql.DB, error) {
 	return db, nil
 }
 
-// WithTransaction executes a function within a database transaction
-func WithTransaction(fn func(*sql.Tx) error) error {
-	db, err := GetDB()
+// CloseAll closes the idle connections of every shared pool. Handles still open keep
+// working; the connections they hold are closed when released, and the next GetDB
+// starts a new pool.
+func CloseAll() error {
+	sharedPools.mu.Lock()
+	pools := sharedPools.pools
+	sharedPools.pools = make(map[string]*sharedPool)
+	sharedPools.mu.Unlock()
+
+	var errs []error
+	for _, pool := range pools {
+		errs = append(errs, pool.close())
+	}
+	return errors.Join(errs...)
+}
+
+// sharedPoolFor returns the pool for dsn, creating it on first use
+func sharedPoolFor(dsn string) *sharedPool {
+	sharedPools.mu.Lock()
+	defer sharedPools.mu.Unlock()
+	pool, ok := sharedPools.pools[dsn]
+	if !ok {
+		pool = &sharedPool{dsn: dsn}
+		sharedPools.pools[dsn] = pool
+	}
+	return pool
+}
+
+// closeSharedDBs closes the pools for databaseFile, before the file is replaced
+func closeSharedDBs(databaseFile string) {
+	sharedPools.mu.Lock()
+	var pools []*sharedPool
+	for dsn, pool := range sharedPools.pools {
+		if dsn == databaseFile || strings.HasPrefix(dsn, databaseFile+"?") {
+			pools = append(pools, pool)
+			delete(sharedPools.pools, dsn)
+		}
+	}
+	sharedPools.mu.Unlock()
+
+	for _, pool := range pools {
+		pool.close()
+	}
+}
+
+// get returns an idle connection or opens a new one
+func (p *sharedPool) get() (*sharedConn, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	conn, err := sqliteDriver().Open(p.dsn)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer db.Close()
+	c := &sharedConn{Conn: conn, pool: p}
+	if c.opened, err = c.sessionState(context.Background()); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read connection state: %w", err)
+	}
+	return c, nil
+}
 
-	// Begin transaction with retry logic
-	var tx *sql.Tx
-	err = retryDatabaseOperation(func() error {
-		tx, err = db.Begin()
-		return err
-	}, DefaultRetryConfig())
+// put keeps a released connection for reuse once ResetSession has returned it to the
+// state it was opened in; connections that cannot be cleaned up are closed
+func (p *sharedPool) put(c *sharedConn) error {
+	if !c.broken && c.ResetSession(context.Background()) != nil {
+		c.broken = true
+	}
+	if validator, ok := c.Conn.(driver.Validator); ok && !validator.IsValid() {
+		c.broken = true
+	}
+
+	p.mu.Lock()
+	if c.broken || p.closed || len(p.idle) >= maxSharedIdleConns {
+		p.mu.Unlock()
+		return c.Conn.Close()
+	}
+	p.idle = append(p.idle, c)
+	p.mu.Unlock()
+	return nil
+}
+
+// close closes the idle connections and stops keeping released ones
+func (p *sharedPool) close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle, p.closed = nil, true
+	p.mu.Unlock()
+
+	var errs []error
+	for _, c := range idle {
+		if err := c.Conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sharedConnector opens handles over a shared pool
+type sharedConnector struct {
+	pool *sharedPool
+}
+
+func (c sharedConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.pool.get()
+}
+
+func (c sharedConnector) Driver() driver.Driver {
+	return sqliteDriver()
+}
+
+// sharedConn is a pooled SQLite connection; closing it returns it to its pool
+type sharedConn struct {
+	driver.Conn
+	pool   *sharedPool
+	opened sessionState // State when opened, restored before the connection is reused
+	inTx   bool         // A transaction is open and has not been committed or rolled back
+	broken bool         // Close for real instead of pooling
+}
+
+// sessionPragmas are the per-connection pragmas callers may change, such as query_only
+// in ReadTransaction, foreign_keys in OnlineAlter or cache_size in hints
+var sessionPragmas = []string{
+	"query_only", "foreign_keys", "legacy_alter_table", "recursive_triggers", "defer_foreign_keys",
+	"cache_size", "temp_store", "analysis_limit", "busy_timeout", "synchronous", "automatic_index",
+}
+
+// sessionStateQuery reads the TEMP objects, attached schemas and session pragmas of a
+// connection in one statement
+var sessionStateQuery = func() string {
+	columns := []string{
+		"(SELECT COUNT(*) FROM temp.sqlite_master)",
+		"(SELECT COUNT(*) FROM pragma_database_list WHERE name NOT IN ('main', 'temp'))",
+	}
+	for _, pragma := range sessionPragmas {
+		columns = append(columns, "(SELECT * FROM pragma_"+pragma+")")
+	}
+	return "SELECT " + strings.Join(columns, ", ")
+}()
+
+// sessionState is the connection state that leaks between callers sharing a connection
+type sessionState struct {
+	tempObjects int64
+	attached    int64
+	pragmas     []int64 // Values of sessionPragmas
+}
+
+// sessionState reads the connection's current session state
+func (c *sharedConn) sessionState(ctx context.Context) (sessionState, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return sessionState{}, errors.New("driver connection cannot run queries")
+	}
+	rows, err := queryer.QueryContext(ctx, sessionStateQuery, nil)
 	if err != nil {
-		return err
+		return sessionState{}, err
+	}
+	defer rows.Close()
+
+	values := make([]driver.Value, 2+len(sessionPragmas))
+	if err := rows.Next(values); err != nil {
+		return sessionState{}, err
+	}
+	state := sessionState{pragmas: make([]int64, len(sessionPragmas))}
+	for i, value := range values {
+		n, ok := value.(int64)
+		if !ok {
+			return sessionState{}, fmt.Errorf("unexpected connection state value %v", value)
+		}
+		switch i {
+		case 0:
+			state.tempObjects = n
+		case 1:
+			state.attached = n
+		default:
+			state.pragmas[i-2] = n
+		}
+	}
+	return state, nil
+}
+
+// restoreSession drops TEMP objects, detaches attached schemas and resets session pragmas
+// that differ from when the connection was opened
+func (c *sharedConn) restoreSession(ctx context.Context, state sessionState) error {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return errors.New("driver connection cannot run statements")
+	}
+	queryer := c.Conn.(driver.QueryerContext) // Checked by sessionState
+
+	var statements []string
+	if state.tempObjects > 0 {
+		// Triggers and views first; indexes go with their tables
+		rows, err := queryer.QueryContext(ctx,
+			"SELECT type, name FROM temp.sqlite_master WHERE type IN ('trigger', 'view', 'table') ORDER BY CASE type WHEN 'trigger' THEN 0 WHEN 'view' THEN 1 ELSE 2 END", nil)
+		if err != nil {
+			return err
+		}
+		values := make([]driver.Value, 2)
+		for rows.Next(values) == nil {
+			statements = append(statements, fmt.Sprintf("DROP %s IF EXISTS temp.%s", strings.ToUpper(fmt.Sprint(values[0])), quoteIdentifier(fmt.Sprint(values[1]))))
+		}
+		rows.Close()
+	}
+	if state.attached > 0 {
+		rows, err := queryer.QueryContext(ctx, "SELECT name FROM pragma_database_list WHERE name NOT IN ('main', 'temp')", nil)
+		if err != nil {
+			return err
+		}
+		values := make([]driver.Value, 1)
+		for rows.Next(values) == nil {
+			statements = append(statements, "DETACH DATABASE "+quoteIdentifier(fmt.Sprint(values[0])))
+		}
+		rows.Close()
+	}
+	for i, pragma := range sessionPragmas {
+		if state.pragmas[i] != c.opened.pragmas[i] {
+			statements = append(statements, fmt.Sprintf("PRAGMA %s = %d", pragma, c.opened.pragmas[i]))
+		}
+	}
+
+	for _, stmt := range statements {
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// clean reports whether state matches the state the connection was opened in
+func (c *sharedConn) clean(state sessionState) bool {
+	if state.tempObjects != c.opened.tempObjects || state.attached != c.opened.attached {
+		return false
+	}
+	for i := range state.pragmas {
+		if state.pragmas[i] != c.opened.pragmas[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *sharedConn) Close() error {
+	return c.pool.put(c)
+}
+
+func (c *sharedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	result, err := execer.ExecContext(ctx, query, args)
+	if err == nil && strings.EqualFold(strings.TrimSpace(query), "ROLLBACK") {
+		c.inTx = false // Cleanup after a failed COMMIT
+	}
+	return result, err
+}
+
+func (c *sharedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *sharedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *sharedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin() // Fallback for drivers without BeginTx
+	}
+	if err != nil {
+		return nil, err
 	}
+	c.inTx = true
+	return &sharedTx{Tx: tx, conn: c}, nil
+}
+
+func (c *sharedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
 
-	// Execute the function
-	if err := fn(tx); err != nil {
-		// Rollback with retry logic
-		rollbackErr := retryDatabaseOperation(func() error {
-			return tx.Rollback()
-		}, DefaultRetryConfig())
-		if rollbackErr != nil {
-			// Log rollback error but return original error
-			log.Printf("❌ Failed to rollback transaction: %v", rollbackErr)
+// ResetSession returns the connection to the state it was opened in before another
+// caller gets it: an open transaction is rolled back, TEMP objects are dropped, attached
+// schemas are detached and session pragmas are restored. It returns driver.ErrBadConn,
+// so the connection is discarded, when any of that fails.
+func (c *sharedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		if err := resetter.ResetSession(ctx); err != nil {
+			return driver.ErrBadConn
 		}
+	}
+	if c.inTx {
+		execer, ok := c.Conn.(driver.ExecerContext)
+		if !ok {
+			return driver.ErrBadConn
+		}
+		if _, err := execer.ExecContext(ctx, "ROLLBACK", nil); err != nil {
+			return driver.ErrBadConn
+		}
+		c.inTx = false
+	}
+
+	state, err := c.sessionState(ctx)
+	if err == nil && !c.clean(state) {
+		if err = c.restoreSession(ctx, state); err == nil {
+			if state, err = c.sessionState(ctx); err == nil && !c.clean(state) {
+				err = errors.New("connection state differs after cleanup")
+			}
+		}
+	}
+	if err != nil {
+		logWarnf("⚠️  Discarding pooled connection that could not be reset: %v", err)
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *sharedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// sharedTx clears its connection's open transaction once it really ends; a COMMIT that
+// fails leaves SQLite's transaction open
+type sharedTx struct {
+	driver.Tx
+	conn *sharedConn
+}
+
+func (t *sharedTx) Commit() error {
+	err := t.Tx.Commit()
+	if err == nil {
+		t.conn.inTx = false
+	}
+	return err
+}
+
+func (t *sharedTx) Rollback() error {
+	err := t.Tx.Rollback()
+	if err == nil {
+		t.conn.inTx = false
+	}
+	return err
+}
+
+// discardConn closes conn's underlying connection instead of returning it to the pool,
+// for connections left in a state that cannot be cleaned up
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(driverConn interface{}) error {
+		if c, ok := driverConn.(*sharedConn); ok {
+			c.broken = true
+		}
+		return driver.ErrBadConn
+	})
+}
+
+// WithTransaction executes a function within a database transaction. fn runs once; use
+// WithTransactionRetry to restart transactions that lose a write conflict.
+func WithTransaction(fn func(*sql.Tx) error) error {
+	ctx := context.Background()
+	leave, err := enterLane(ctx)
+	if err != nil {
 		return err
 	}
+	defer leave()
 
-	// Commit with retry logic
-	return retryDatabaseOperation(func() error {
-		return tx.Commit()
-	}, DefaultRetryConfig())
+	return resultError(runTransactionOnce(ctx, adaptRetryConfig(DefaultRetryConfig()), fn))
 }
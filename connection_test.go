@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestGetDBSharesPool verifies that handles borrow connections from one pool per database
+// file, that closing a handle leaves the pool and other handles working, and that CloseAll
+// does not break handles in use
+func TestGetDBSharesPool(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "shared.db"))
+	defer CloseAll()
+
+	first, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if _, err := first.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+	second, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer second.Close()
+
+	pool := sharedPoolFor(buildDSN(configValue("DATABASE_FILE")))
+	first.Close()
+	pool.mu.Lock()
+	idle := len(pool.idle)
+	pool.mu.Unlock()
+	if idle == 0 {
+		t.Error("Expected closing a handle to return its connections to the shared pool")
+	}
+	if _, err := second.Exec("INSERT INTO notes (id) VALUES (1)"); err != nil {
+		t.Errorf("Expected other handles to keep working after a Close, got %v", err)
+	}
+
+	if err := CloseAll(); err != nil {
+		t.Fatalf("CloseAll failed: %v", err)
+	}
+	if _, err := second.Exec("INSERT INTO notes (id) VALUES (2)"); err != nil {
+		t.Errorf("Expected handles in use to keep working after CloseAll, got %v", err)
+	}
+	if sharedPoolFor(buildDSN(configValue("DATABASE_FILE"))) == pool {
+		t.Error("Expected GetDB to start a new pool after CloseAll")
+	}
+}
+
+// TestBusyCommitDoesNotPoisonPool verifies that a connection whose COMMIT failed with
+// BUSY goes back to the shared pool with its transaction rolled back
+func TestBusyCommitDoesNotPoisonPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.db")
+	t.Setenv("DATABASE_FILE", path)
+	defer CloseAll()
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+
+	// A reader on another handle holds a SHARED lock, so COMMIT cannot take EXCLUSIVE
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open second handle: %v", err)
+	}
+	defer other.Close()
+	reader, err := other.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin reader: %v", err)
+	}
+	var count int
+	if err := reader.QueryRow("SELECT COUNT(*) FROM notes").Scan(&count); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO notes (id) VALUES (1)"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := tx.Commit(); !IsBusy(err) {
+		t.Fatalf("Expected a BUSY commit, got %v", err)
+	}
+
+	err = WithTransactionRetryOptions(context.Background(), TransactionOptions{MaxAttempts: 1}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO notes (id) VALUES (2)")
+		return err
+	})
+	if !errors.Is(err, ErrTransactionConflict) {
+		t.Fatalf("Expected a transaction conflict, got %v", err)
+	}
+	reader.Rollback()
+
+	// Both failed commits released their connections; later writes must really commit
+	if _, err := db.Exec("INSERT INTO notes (id) VALUES (3)"); err != nil {
+		t.Fatalf("Failed to insert after BUSY commit: %v", err)
+	}
+	if err := other.QueryRow("SELECT COUNT(*) FROM notes").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected the later write to be visible to another handle, got %d rows (%v)", count, err)
+	}
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatalf("Expected to begin a new transaction on the pooled connection, got %v", err)
+	}
+	tx.Rollback()
+	if err := WithTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO notes (id) VALUES (4)")
+		return err
+	}); err != nil {
+		t.Errorf("WithTransaction failed after BUSY commit: %v", err)
+	}
+}
+
+// TestReadTransactionReleasesQueryOnly verifies that a read transaction does not leave its
+// pooled connection read-only
+func TestReadTransactionReleasesQueryOnly(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "readonly.db"))
+	defer CloseAll()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+
+	if err := ReadTransaction(context.Background(), func(tx *sql.Tx) error { return nil }); err != nil {
+		t.Fatalf("Read transaction failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO notes (id) VALUES (1)"); err != nil {
+		t.Errorf("Expected the pooled connection to accept writes, got %v", err)
+	}
+}
+
+// TestPooledConnectionsAreReset verifies TEMP objects, attached schemas and session
+// pragmas set through one handle do not reach the next caller of the pooled connection
+func TestPooledConnectionsAreReset(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(dir, "reset.db"))
+	defer CloseAll()
+	ctx := context.Background()
+
+	first, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE TEMP TABLE scratch (id INTEGER)",
+		"CREATE TEMP VIEW scratch_view AS SELECT id FROM scratch",
+		"ATTACH DATABASE '" + filepath.Join(dir, "other.db") + "' AS other",
+		"PRAGMA query_only = ON",
+		"PRAGMA cache_size = -4096",
+	} {
+		if _, err := first.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	first.Close()
+
+	pool := sharedPoolFor(buildDSN(configValue("DATABASE_FILE")))
+	pool.mu.Lock()
+	idle := len(pool.idle)
+	pool.mu.Unlock()
+	if idle != 1 {
+		t.Fatalf("Expected the cleaned connection back in the pool, got %d idle", idle)
+	}
+
+	second, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	var temps, attached, queryOnly, cacheSize int
+	err = second.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM temp.sqlite_master),
+		(SELECT COUNT(*) FROM pragma_database_list WHERE name = 'other'),
+		(SELECT * FROM pragma_query_only), (SELECT * FROM pragma_cache_size)`).Scan(&temps, &attached, &queryOnly, &cacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if temps != 0 || attached != 0 || queryOnly != 0 || cacheSize == -4096 {
+		t.Errorf("Expected a reset connection, got %d temp objects, %d attached, query_only %d, cache_size %d",
+			temps, attached, queryOnly, cacheSize)
+	}
+	if _, err := second.ExecContext(ctx, "CREATE TABLE notes (id INTEGER)"); err != nil {
+		t.Errorf("Expected writes on the reset connection, got %v", err)
+	}
+}
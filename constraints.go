@@ -0,0 +1,92 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ConstraintViolation describes a failed constraint, parsed from the driver error
+type ConstraintViolation struct {
+	Kind    error    // ErrConstraintUnique, ErrConstraintPrimaryKey, ErrConstraintNotNull, ErrConstraintCheck or ErrConstraintForeignKey
+	Table   string   // Table of the offending columns (empty when SQLite reports an index or no detail)
+	Columns []string // Offending columns, in constraint order
+	Index   string   // Unique index on expressions, reported by name instead of columns
+	Check   string   // CHECK constraint name or expression
+	Err     error
+}
+
+// constraintDetail captures what SQLite reports after "<KIND> constraint failed: ",
+// stripping the " (code)" suffix added by some drivers
+var constraintDetail = regexp.MustCompile(`(UNIQUE|NOT NULL|CHECK|FOREIGN KEY) constraint failed(?::\s*(.*?))?(?:\s+\(\d+\))?$`)
+
+// ParseConstraintViolation extracts the table and columns of a constraint failure, so
+// handlers can report the offending field (e.g. a 409 naming "email") instead of a 500
+func ParseConstraintViolation(err error) (*ConstraintViolation, bool) {
+	classified := ClassifyError(err)
+	if !errors.Is(classified, ErrConstraint) {
+		return nil, false
+	}
+
+	violation := &ConstraintViolation{Kind: ErrConstraint, Err: classified}
+	for _, kind := range []error{ErrConstraintPrimaryKey, ErrConstraintUnique, ErrConstraintNotNull, ErrConstraintCheck, ErrConstraintForeignKey} {
+		if errors.Is(classified, kind) {
+			violation.Kind = kind
+			break
+		}
+	}
+
+	match := constraintDetail.FindStringSubmatch(err.Error())
+	if match == nil || match[2] == "" {
+		return violation, true
+	}
+	detail := match[2]
+
+	switch {
+	case match[1] == "CHECK":
+		violation.Check = detail
+	case strings.HasPrefix(detail, "index '"):
+		violation.Index = strings.TrimSuffix(strings.TrimPrefix(detail, "index '"), "'")
+	default:
+		for _, qualified := range strings.Split(detail, ",") {
+			table, column, ok := strings.Cut(strings.TrimSpace(qualified), ".")
+			if !ok {
+				continue
+			}
+			violation.Table = table
+			violation.Columns = append(violation.Columns, column)
+		}
+	}
+	return violation, true
+}
+
+// IsUniqueViolation reports whether err is a UNIQUE or PRIMARY KEY violation on table's
+// columns (in any order). With no columns, any uniqueness violation on table matches.
+func IsUniqueViolation(err error, table string, columns ...string) bool {
+	violation, ok := ParseConstraintViolation(err)
+	if !ok || (violation.Kind != ErrConstraintUnique && violation.Kind != ErrConstraintPrimaryKey) {
+		return false
+	}
+	if !strings.EqualFold(violation.Table, table) {
+		return false
+	}
+	if len(columns) == 0 {
+		return true
+	}
+	if len(columns) != len(violation.Columns) {
+		return false
+	}
+	for _, column := range columns {
+		found := false
+		for _, violated := range violation.Columns {
+			if strings.EqualFold(column, violated) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestConstraintViolationDetails verifies that the offending table, columns, index and
+// check are parsed from constraint failures
+func TestConstraintViolationDetails(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "constraints.db"))
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, org TEXT, handle TEXT, slug TEXT, CONSTRAINT handle_format CHECK (handle <> ''), UNIQUE (org, handle))",
+		"CREATE UNIQUE INDEX ux_users_lower_slug ON users (lower(slug))",
+		"INSERT INTO users (id, email, org, handle, slug) VALUES (1, 'a@example.com', 'acme', 'ann', 'Ann')",
+	} {
+		if _, err := ExecWithRetry(db, stmt); err != nil {
+			t.Fatalf("Failed to set up: %v", err)
+		}
+	}
+
+	_, err = ExecWithRetry(db, "INSERT INTO users (id, email) VALUES (2, 'a@example.com')")
+	if !IsUniqueViolation(err, "users", "email") || !IsUniqueViolation(err, "users") || IsUniqueViolation(err, "users", "org") {
+		t.Errorf("Expected a unique violation on users.email, got %v", err)
+	}
+
+	_, err = ExecWithRetry(db, "INSERT INTO users (id, email) VALUES (1, 'b@example.com')")
+	if !IsUniqueViolation(err, "users", "id") {
+		t.Errorf("Expected a primary key violation on users.id, got %v", err)
+	}
+
+	_, err = ExecWithRetry(db, "INSERT INTO users (id, org, handle) VALUES (3, 'acme', 'ann')")
+	if !IsUniqueViolation(err, "users", "handle", "org") || IsUniqueViolation(err, "users", "org") {
+		t.Errorf("Expected a composite unique violation, got %v", err)
+	}
+
+	_, err = ExecWithRetry(db, "INSERT INTO users (id, slug) VALUES (4, 'ANN')")
+	if v, ok := ParseConstraintViolation(err); !ok || v.Index != "ux_users_lower_slug" || v.Kind != ErrConstraintUnique {
+		t.Errorf("Expected an expression index violation, got %+v", v)
+	}
+
+	_, err = ExecWithRetry(db, "INSERT INTO users (id, handle) VALUES (5, '')")
+	v, ok := ParseConstraintViolation(err)
+	if !ok || v.Kind != ErrConstraintCheck || v.Check != "handle_format" || v.Columns != nil {
+		t.Errorf("Expected a named check violation, got %+v", v)
+	}
+
+	v, ok = ParseConstraintViolation(errors.New("NOT NULL constraint failed: users.org, users.handle"))
+	if !ok || v.Kind != ErrConstraintNotNull || v.Table != "users" || !reflect.DeepEqual(v.Columns, []string{"org", "handle"}) {
+		t.Errorf("Expected NOT NULL columns from a plain message, got %+v", v)
+	}
+	if _, ok := ParseConstraintViolation(errors.New("no such table: users")); ok {
+		t.Error("Expected non-constraint errors to be rejected")
+	}
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCoordinatedPending is returned when a coordinated transaction was committed on some
+// databases but not all; the remaining writes are applied by Recover
+var ErrCoordinatedPending = errors.New("coordinated transaction partially committed; recovery pending")
+
+// coordinatedSchema creates the marker table recording applied coordinated transactions
+const coordinatedSchema = `CREATE TABLE IF NOT EXISTS _coordinated_txs (
+    txid       TEXT PRIMARY KEY,
+    applied_at INTEGER NOT NULL
+)`
+
+// CoordinatedWrite is the set of statements a coordinated transaction runs on one database.
+// Statement arguments must survive a JSON round trip, since they are replayed from the intent log.
+type CoordinatedWrite struct {
+	Database   string      `json:"database"` // Database file path
+	Statements []Statement `json:"statements"`
+}
+
+// intentRecord is the JSON content of an intent log entry
+type intentRecord struct {
+	ID        string             `json:"id"`
+	CreatedAt int64              `json:"created_at"` // Unix milliseconds
+	Writes    []CoordinatedWrite `json:"writes"`
+}
+
+// Coordinator runs best-effort atomic writes across multiple SQLite databases
+// (e.g., a tenant database and the core database). Each database's statements run
+// in its own transaction; once all of them have succeeded the decision to commit is
+// persisted in an intent log before any transaction commits, so writes that were
+// lost to a crash between commits can be replayed by Recover.
+type Coordinator struct {
+	intentDir string
+}
+
+// NewCoordinator creates a coordinator that keeps its intent log in intentDir
+func NewCoordinator(intentDir string) (*Coordinator, error) {
+	if err := os.MkdirAll(intentDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create intent log directory: %w", err)
+	}
+	return &Coordinator{intentDir: intentDir}, nil
+}
+
+// Exec runs the writes as one coordinated transaction. If any statement fails, all
+// transactions are rolled back. If a commit fails after the decision was logged,
+// ErrCoordinatedPending is returned and the remaining writes are applied by Recover.
+func (c *Coordinator) Exec(ctx context.Context, writes []CoordinatedWrite) error {
+	if err := checkWriteGuards(ctx); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, w := range writes {
+		if seen[w.Database] {
+			return fmt.Errorf("coordinated transaction has more than one write for %s", w.Database)
+		}
+		seen[w.Database] = true
+	}
+
+	id, err := newCoordinatedID()
+	if err != nil {
+		return err
+	}
+
+	var dbs []*sql.DB
+	var txs []*sql.Tx
+	defer func() {
+		for _, tx := range txs {
+			tx.Rollback() // No-op for committed transactions
+		}
+		for _, db := range dbs {
+			db.Close()
+		}
+	}()
+
+	for _, w := range writes {
+		db, err := sql.Open("sqlite", buildDSN(w.Database))
+		if err != nil {
+			return err
+		}
+		dbs = append(dbs, db)
+
+		var tx *sql.Tx
+		err = retryDatabaseOperationContext(ctx, func() error {
+			tx, err = db.BeginTx(ctx, nil)
+			return err
+		}, DefaultRetryConfig())
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction on %s: %w", w.Database, err)
+		}
+		txs = append(txs, tx)
+
+		if err := applyCoordinatedWrite(ctx, tx, id, w); err != nil {
+			return resultError(err)
+		}
+	}
+
+	// Decision point: once the intent is durable, the transaction must complete
+	intentFile, err := c.writeIntent(&intentRecord{ID: id, CreatedAt: time.Now().UnixMilli(), Writes: writes})
+	if err != nil {
+		return fmt.Errorf("failed to write intent log: %w", err)
+	}
+
+	for i, tx := range txs {
+		if err := tx.Commit(); err != nil {
+			logErrorf("❌ Coordinated transaction %s failed to commit on %s: %v", id, writes[i].Database, err)
+			return fmt.Errorf("%w: %v", ErrCoordinatedPending, err)
+		}
+	}
+
+	if err := os.Remove(intentFile); err != nil {
+		logWarnf("⚠️  Failed to remove intent log entry %s: %v", intentFile, err)
+	}
+	return nil
+}
+
+// Recover completes coordinated transactions left in the intent log by a crash,
+// applying each write on databases where it was not committed. Call it on startup.
+func (c *Coordinator) Recover(ctx context.Context) error {
+	entries, err := filepath.Glob(filepath.Join(c.intentDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range entries {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read intent log entry %s: %w", path, err)
+		}
+		var record intentRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("failed to parse intent log entry %s: %w", path, err)
+		}
+
+		for _, w := range record.Writes {
+			if err := recoverCoordinatedWrite(ctx, record.ID, w); err != nil {
+				return fmt.Errorf("failed to recover coordinated transaction %s on %s: %w", record.ID, w.Database, err)
+			}
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove intent log entry %s: %w", path, err)
+		}
+		logf("♻️  Recovered coordinated transaction %s", record.ID)
+	}
+	return nil
+}
+
+// recoverCoordinatedWrite replays a write unless its marker shows it was already committed
+func recoverCoordinatedWrite(ctx context.Context, id string, w CoordinatedWrite) error {
+	db, err := sql.Open("sqlite", buildDSN(w.Database))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return retryDatabaseOperationContext(ctx, func() error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, coordinatedSchema); err != nil {
+			return err
+		}
+		var applied int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM _coordinated_txs WHERE txid = ?", id).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > 0 {
+			return nil
+		}
+
+		if err := applyCoordinatedWrite(ctx, tx, id, w); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}, DefaultRetryConfig())
+}
+
+// applyCoordinatedWrite runs a write's statements and records its marker in tx
+func applyCoordinatedWrite(ctx context.Context, tx *sql.Tx, id string, w CoordinatedWrite) error {
+	if _, err := tx.ExecContext(ctx, coordinatedSchema); err != nil {
+		return fmt.Errorf("failed to create coordinated transaction table: %w", err)
+	}
+	for i, stmt := range w.Statements {
+		if _, err := tx.ExecContext(ctx, stmt.Query, stmt.Args...); err != nil {
+			return fmt.Errorf("statement %d on %s failed: %w", i, w.Database, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO _coordinated_txs (txid, applied_at) VALUES (?, ?)",
+		id, time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("failed to record coordinated transaction: %w", err)
+	}
+	return nil
+}
+
+// writeIntent durably writes an intent log entry via write-and-rename and returns its path
+func (c *Coordinator) writeIntent(record *intentRecord) (string, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(c.intentDir, record.ID+".json")
+	tmp, err := os.CreateTemp(c.intentDir, record.ID+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// newCoordinatedID returns a random coordinated transaction ID
+func newCoordinatedID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s", time.Now().UnixMilli(), hex.EncodeToString(buf)), nil
+}
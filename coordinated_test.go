@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCoordinatorRecoversPartialCommit verifies that writes in the intent log are replayed
+// only on databases where the coordinated transaction was not committed
+func TestCoordinatorRecoversPartialCommit(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	core := filepath.Join(dir, "core.db")
+	tenant := filepath.Join(dir, "tenant.db")
+
+	for _, path := range []string{core, tenant} {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", path, err)
+		}
+		if _, err := db.Exec("CREATE TABLE ledger (entry TEXT)"); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+		db.Close()
+	}
+
+	coordinator, err := NewCoordinator(filepath.Join(dir, "intents"))
+	if err != nil {
+		t.Fatalf("Failed to create coordinator: %v", err)
+	}
+	insert := []Statement{{Query: "INSERT INTO ledger (entry) VALUES (?)", Args: []interface{}{"transfer"}}}
+	if err := coordinator.Exec(ctx, []CoordinatedWrite{
+		{Database: core, Statements: insert},
+		{Database: tenant, Statements: insert},
+	}); err != nil {
+		t.Fatalf("Coordinated Exec failed: %v", err)
+	}
+
+	// Simulate a crash after the decision was logged and only the core database committed
+	writes := []CoordinatedWrite{{Database: core, Statements: insert}, {Database: tenant, Statements: insert}}
+	if _, err := coordinator.writeIntent(&intentRecord{ID: "crashed", CreatedAt: time.Now().UnixMilli(), Writes: writes}); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	if err := recoverCoordinatedWrite(ctx, "crashed", writes[0]); err != nil {
+		t.Fatalf("Failed to apply core write: %v", err)
+	}
+
+	if err := coordinator.Recover(ctx); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if err := coordinator.Recover(ctx); err != nil {
+		t.Fatalf("Second Recover failed: %v", err)
+	}
+
+	for _, path := range []string{core, tenant} {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", path, err)
+		}
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM ledger").Scan(&count); err != nil {
+			t.Fatalf("Failed to count ledger entries: %v", err)
+		}
+		db.Close()
+		if count != 2 {
+			t.Errorf("Expected 2 ledger entries in %s, got %d", filepath.Base(path), count)
+		}
+	}
+}
@@ -4,12 +4,18 @@
 // - SQLite retry logic with exponential backoff for SQLITE_BUSY errors
 // - Migration registry system for packages to register their migrations
 // - Simple database connection management
+// - Managed views and table-backed materialized views
 package database
 
+import "context"
+
 // Version of the go-database package
 const Version = "1.0.0"
 
-// RunAllMigrations runs all migrations from registered sources
+// RunAllMigrations runs all migrations from registered sources and then applies registered views
 func RunAllMigrations() error {
-	return UpAll()
+	if err := UpAll(); err != nil {
+		return err
+	}
+	return ApplyViews(context.Background())
 }
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// sqlPreparer is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type sqlPreparer interface {
+	sqlConn
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// DBTX runs statements through this package's pipeline: write guards, write limits,
+// priority lanes, retry policies and tracing. It implements the DBTX interface generated
+// by sqlc, so generated code inherits retry and tracing with queries.New(database.NewDBTX(db)),
+// and the ExecQuerier interface of ent's dialect/sql package, for use with
+// entsql.Conn{ExecQuerier: database.NewDBTX(db)}. ent transactions need a *sql.DB and
+// bypass the adapter.
+type DBTX struct {
+	conn sqlPreparer
+	db   *sql.DB // Handle used for per-handle write limits (nil for transactions)
+}
+
+// NewDBTX wraps a database handle
+func NewDBTX(db *sql.DB) *DBTX {
+	return &DBTX{conn: db, db: db}
+}
+
+// NewTxDBTX wraps a transaction, e.g. for sqlc's Queries.WithTx
+func NewTxDBTX(tx *sql.Tx) *DBTX {
+	return &DBTX{conn: tx}
+}
+
+// ExecContext executes a statement with write guards, retry and tracing
+func (d *DBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+	if d.db != nil {
+		if err := checkHandleWriteLimit(d.db); err != nil {
+			return nil, err
+		}
+		// Transactions already hold their write lock; only handle writes take a queue turn
+		var leave func()
+		var err error
+		if ctx, leave, err = enterWriteQueue(ctx); err != nil {
+			return nil, err
+		}
+		defer leave()
+	}
+
+	defer trackWrite(query)()
+
+	var result sql.Result
+	recorder, execCtx := startStatement(ctx, "exec", query)
+	err := retryInLane(ctx, func() error {
+		var err error
+		recorder.attempt()
+		result, err = tracedExecContext(execCtx, d.conn, query, args...)
+		return err
+	}, retryConfigFor(ctx, query))
+	recorder.finish(rowsAffected(result), err)
+	if err == nil {
+		invalidateRowCacheForWrite(ctx, query)
+		countTableWrite(query, rowsAffected(result))
+	}
+	return result, resultError(err)
+}
+
+// QueryContext executes a query with retry and tracing
+func (d *DBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	recorder, queryCtx := startStatement(ctx, "query", query)
+	err := retryDatabaseOperationContext(ctx, func() error {
+		var err error
+		recorder.attempt()
+		rows, err = tracedQueryContext(queryCtx, d.conn, query, args...)
+		return err
+	}, retryConfigFor(ctx, query))
+	recorder.finish(-1, err)
+	return rows, resultError(err)
+}
+
+// QueryRowContext executes a single-row query with tracing. *sql.Row defers its error
+// to Scan, so it is not retried; use QueryRowWithRetry where retry matters.
+func (d *DBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tracedQueryRowContext(ctx, d.conn, query, args...)
+}
+
+// PrepareContext prepares a statement on the wrapped handle
+func (d *DBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	var stmt *sql.Stmt
+	err := retryDatabaseOperationContext(ctx, func() error {
+		var err error
+		stmt, err = d.conn.PrepareContext(ctx, query)
+		return err
+	}, retryConfigFor(ctx, query))
+	return stmt, resultError(err)
+}
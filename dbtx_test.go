@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// sqlcDBTX mirrors the interface sqlc generates in db.go
+type sqlcDBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+var _ sqlcDBTX = (*DBTX)(nil)
+
+// TestDBTXRunsThroughPipeline verifies that writes through the adapter honor write guards
+// and that handle and transaction adapters execute statements
+func TestDBTXRunsThroughPipeline(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "dbtx.db"))
+	ctx := context.Background()
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+
+	adapter := NewDBTX(db)
+	if _, err := adapter.ExecContext(ctx, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	blocked := errors.New("writes blocked")
+	setWriteGuard("test-dbtx", func(context.Context) error { return blocked })
+	_, err = adapter.ExecContext(ctx, "INSERT INTO notes (body) VALUES ('blocked')")
+	removeWriteGuard("test-dbtx")
+	if !errors.Is(err, blocked) {
+		t.Fatalf("Expected write guard error, got %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if _, err := NewTxDBTX(tx).ExecContext(ctx, "INSERT INTO notes (body) VALUES (?)", "hello"); err != nil {
+		t.Fatalf("Failed to insert through transaction adapter: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	var body string
+	if err := adapter.QueryRowContext(ctx, "SELECT body FROM notes").Scan(&body); err != nil || body != "hello" {
+		t.Errorf("Expected hello, got %q (%v)", body, err)
+	}
+}
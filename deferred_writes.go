@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidDeferredWrites is returned by Flush when a validator rejects the buffered writes
+var ErrInvalidDeferredWrites = errors.New("deferred writes rejected by validation")
+
+// WriteValidator checks buffered writes before they are flushed, e.g. against
+// application policy; a non-nil error stops the flush
+type WriteValidator func(ctx context.Context, statements []Statement) error
+
+// DeferredWrites buffers the writes of a logical unit of work so they can be validated
+// and flushed in one short transaction at the end, instead of holding the write lock
+// while interleaving reads and writes
+type DeferredWrites struct {
+	mu         sync.Mutex
+	statements []Statement
+	validators []WriteValidator
+}
+
+// deferredWritesKey carries a unit of work's DeferredWrites in a context
+type deferredWritesKey struct{}
+
+// NewDeferredWrites returns an empty buffer checked by validators on Flush
+func NewDeferredWrites(validators ...WriteValidator) *DeferredWrites {
+	return &DeferredWrites{validators: validators}
+}
+
+// WithDeferredWrites returns a context carrying a new buffer, so code deep in a request
+// can add writes with DeferredWritesFrom and the request flushes them once
+func WithDeferredWrites(ctx context.Context, validators ...WriteValidator) (context.Context, *DeferredWrites) {
+	writes := NewDeferredWrites(validators...)
+	return context.WithValue(ctx, deferredWritesKey{}, writes), writes
+}
+
+// DeferredWritesFrom returns the buffer set with WithDeferredWrites, or nil
+func DeferredWritesFrom(ctx context.Context) *DeferredWrites {
+	writes, _ := ctx.Value(deferredWritesKey{}).(*DeferredWrites)
+	return writes
+}
+
+// Add buffers a write statement; reads are refused since they would see no buffered writes
+func (d *DeferredWrites) Add(query string, args ...interface{}) error {
+	if ClassifyStatement(query) == StatementRead {
+		return fmt.Errorf("deferred writes only buffer write statements: %s", NormalizeStatement(query))
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, Statement{Query: query, Args: args})
+	return nil
+}
+
+// Len returns the number of buffered writes
+func (d *DeferredWrites) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.statements)
+}
+
+// Discard drops the buffered writes, e.g. when the unit of work fails
+func (d *DeferredWrites) Discard() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = nil
+}
+
+// Flush validates the buffered writes and executes them in a single transaction with
+// ExecBatch. Constraint failures roll the whole unit back; see ParseConstraintViolation.
+// The buffer is emptied on success and kept on failure.
+func (d *DeferredWrites) Flush(ctx context.Context) ([]BatchResult, error) {
+	d.mu.Lock()
+	statements := append([]Statement(nil), d.statements...)
+	validators := d.validators
+	d.mu.Unlock()
+	if len(statements) == 0 {
+		return nil, nil
+	}
+
+	for _, validate := range validators {
+		if err := validate(ctx, statements); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDeferredWrites, err)
+		}
+	}
+
+	results, err := ExecBatch(ctx, statements)
+	if err != nil {
+		return results, err
+	}
+
+	// Keep writes added while flushing, unless the buffer was discarded meanwhile
+	d.mu.Lock()
+	if len(d.statements) >= len(statements) {
+		d.statements = d.statements[len(statements):]
+	}
+	d.mu.Unlock()
+	return results, nil
+}
+
+// DenyUnboundedWrites is a WriteValidator rejecting UPDATE and DELETE statements without
+// a WHERE clause
+func DenyUnboundedWrites() WriteValidator {
+	return func(ctx context.Context, statements []Statement) error {
+		for i, stmt := range statements {
+			words := strings.Fields(strings.ToUpper(stripLeadingComments(stmt.Query)))
+			if len(words) == 0 || (words[0] != "UPDATE" && words[0] != "DELETE") {
+				continue
+			}
+			bounded := false
+			for _, word := range words {
+				if word == "WHERE" {
+					bounded = true
+				}
+			}
+			if !bounded {
+				return fmt.Errorf("statement %d has no WHERE clause: %s", i, NormalizeStatement(stmt.Query))
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestDeferredWritesFlushAtomically verifies that buffered writes are validated, applied
+// in one transaction, and rolled back together when one violates a constraint
+func TestDeferredWritesFlushAtomically(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "deferred.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := ExecWithRetry(db, "CREATE TABLE orders (id INTEGER PRIMARY KEY, status TEXT NOT NULL)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+
+	ctx, writes := WithDeferredWrites(context.Background(), DenyUnboundedWrites())
+	if err := DeferredWritesFrom(ctx).Add("INSERT INTO orders VALUES (?, ?)", 1, "new"); err != nil {
+		t.Fatalf("Failed to buffer write: %v", err)
+	}
+	if err := writes.Add("SELECT * FROM orders"); err == nil {
+		t.Error("Expected reads to be refused")
+	}
+	if _, err := writes.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if writes.Len() != 0 {
+		t.Errorf("Expected an empty buffer after flushing, got %d", writes.Len())
+	}
+
+	writes.Add("UPDATE orders SET status = 'cancelled'")
+	if _, err := writes.Flush(ctx); !errors.Is(err, ErrInvalidDeferredWrites) {
+		t.Errorf("Expected the unbounded update to be rejected, got %v", err)
+	}
+	writes.Discard()
+
+	writes.Add("INSERT INTO orders VALUES (?, ?)", 2, "new")
+	writes.Add("INSERT INTO orders VALUES (?, ?)", 1, "duplicate")
+	if _, err := writes.Flush(ctx); !IsUniqueViolation(err, "orders") {
+		t.Errorf("Expected a unique violation, got %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count); err != nil {
+		t.Fatalf("Failed to count orders: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the failed unit to roll back entirely, found %d orders", count)
+	}
+}
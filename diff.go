@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ClientState describes the rows an offline client currently holds
+type ClientState struct {
+	KeyColumn     string            // Column uniquely identifying a row (default: "id")
+	VersionColumn string            // Optional column holding a row version; rows are hashed when empty
+	Rows          map[string]string // Row key -> version (or RowHash) the client holds
+}
+
+// RowDiff holds the changes a client must apply to match the server
+type RowDiff struct {
+	Inserted []map[string]interface{} // Rows the client does not have
+	Updated  []map[string]interface{} // Rows whose version or hash differs from the client's
+	Deleted  []string                 // Keys the client holds that no longer match the query
+}
+
+// DiffRows runs query and compares its result set with the client's state,
+// returning the rows to insert, update and delete on the client
+func DiffRows(ctx context.Context, query string, state ClientState, args ...interface{}) (*RowDiff, error) {
+	keyColumn := state.KeyColumn
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var serverRows []map[string]interface{}
+	err = retryDatabaseOperationContext(ctx, func() error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		serverRows, _, err = scanRowMaps(rows, 0)
+		return err
+	}, DefaultRetryConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rows for diff: %w", err)
+	}
+
+	diff := &RowDiff{}
+	seen := make(map[string]bool, len(serverRows))
+
+	for _, row := range serverRows {
+		keyValue, ok := row[keyColumn]
+		if !ok {
+			return nil, fmt.Errorf("diff query does not return key column: %s", keyColumn)
+		}
+		key := stringifyValue(keyValue)
+		seen[key] = true
+
+		var version string
+		if state.VersionColumn != "" {
+			versionValue, ok := row[state.VersionColumn]
+			if !ok {
+				return nil, fmt.Errorf("diff query does not return version column: %s", state.VersionColumn)
+			}
+			version = stringifyValue(versionValue)
+		} else {
+			version = RowHash(row)
+		}
+
+		clientVersion, known := state.Rows[key]
+		switch {
+		case !known:
+			diff.Inserted = append(diff.Inserted, row)
+		case clientVersion != version:
+			diff.Updated = append(diff.Updated, row)
+		}
+	}
+
+	for key := range state.Rows {
+		if !seen[key] {
+			diff.Deleted = append(diff.Deleted, key)
+		}
+	}
+	sort.Strings(diff.Deleted)
+
+	return diff, nil
+}
+
+// RowHash returns a deterministic SHA-256 hash of a row, independent of column order.
+// Clients hash rows with the same algorithm to populate ClientState.Rows: columns are
+// sorted and each written as "column=value\x00" ("column\x00" for NULL), with integers
+// in base 10, floats in shortest 'g' form, times as RFC3339Nano in UTC, and text and
+// blobs as their raw bytes.
+func RowHash(row map[string]interface{}) string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	h := sha256.New()
+	for _, column := range columns {
+		// NULL hashes differently from an empty string
+		if row[column] == nil {
+			fmt.Fprintf(h, "%s\x00", column)
+			continue
+		}
+		fmt.Fprintf(h, "%s=%s\x00", column, stringifyValue(row[column]))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// stringifyValue converts a scanned SQLite value into its canonical string form
+func stringifyValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestStringifyValueCanonical verifies values use encodings other languages can reproduce
+func TestStringifyValueCanonical(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"text", "text"},
+		{[]byte("blob"), "blob"},
+		{int64(-42), "-42"},
+		{float64(0.1), "0.1"},
+		{float64(3), "3"},
+		{float64(1e21), "1e+21"},
+		{time.Date(2026, 3, 4, 7, 8, 9, 120000000, zone), "2026-03-04T05:08:09.12Z"},
+		{time.Date(2026, 3, 4, 5, 8, 9, 0, time.UTC), "2026-03-04T05:08:09Z"},
+	}
+	for _, c := range cases {
+		if got := stringifyValue(c.value); got != c.want {
+			t.Errorf("stringifyValue(%#v) = %q, want %q", c.value, got, c.want)
+		}
+	}
+}
+
+// TestRowHashFormat verifies RowHash matches the documented encoding
+func TestRowHashFormat(t *testing.T) {
+	row := map[string]interface{}{
+		"price":   float64(9.5),
+		"id":      int64(7),
+		"note":    nil,
+		"updated": time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60)),
+	}
+	sum := sha256.Sum256([]byte("id=7\x00note\x00price=9.5\x00updated=2026-01-02T08:04:05Z\x00"))
+	if got, want := RowHash(row), hex.EncodeToString(sum[:]); got != want {
+		t.Fatalf("RowHash = %s, want %s", got, want)
+	}
+
+	// The same instant in another zone hashes identically
+	row["updated"] = time.Date(2026, 1, 2, 8, 4, 5, 0, time.UTC)
+	if got, want := RowHash(row), hex.EncodeToString(sum[:]); got != want {
+		t.Fatalf("RowHash in UTC = %s, want %s", got, want)
+	}
+}
+
+// TestDiffRows verifies inserts, updates and deletes against a client's hashes
+func TestDiffRows(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "diff.db"))
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT, score REAL)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO notes (id, body, score) VALUES (1, 'a', 1.5), (2, 'b', 2.25)"); err != nil {
+		t.Fatal(err)
+	}
+
+	state := ClientState{Rows: map[string]string{
+		"1": RowHash(map[string]interface{}{"id": int64(1), "body": "a", "score": 1.5}),
+		"2": "stale",
+		"3": "gone",
+	}}
+	diff, err := DiffRows(context.Background(), "SELECT id, body, score FROM notes", state)
+	if err != nil {
+		t.Fatalf("DiffRows: %v", err)
+	}
+	if len(diff.Inserted) != 0 || len(diff.Updated) != 1 || len(diff.Deleted) != 1 {
+		t.Fatalf("diff = %+v, want 1 update and 1 delete", diff)
+	}
+	if diff.Deleted[0] != "3" || diff.Updated[0]["id"] != int64(2) {
+		t.Fatalf("diff = %+v, want row 2 updated and 3 deleted", diff)
+	}
+}
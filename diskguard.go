@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrDiskSpaceLow is returned for writes while free disk space is below the configured minimum
+var ErrDiskSpaceLow = errors.New("database volume is low on free space; writes are disabled")
+
+// DiskStatus reports free space on the database volume
+type DiskStatus struct {
+	Path        string
+	TotalBytes  uint64
+	FreeBytes   uint64
+	FreePercent float64
+	Degraded    bool // Writes are disabled until space recovers
+	CheckedAt   time.Time
+	LastError   string
+}
+
+// DiskGuardOptions configures the free-space monitor
+type DiskGuardOptions struct {
+	Path           string        // Directory to monitor (default: directory of DATABASE_FILE)
+	MinFreeBytes   uint64        // Degrade when free space drops below this many bytes
+	MinFreePercent float64       // Degrade when free space drops below this percentage (0-100)
+	ResumeMargin   float64       // Extra headroom (fraction of the minimum) required to resume writes (default: 0.1)
+	Interval       time.Duration // Check interval (default: 30s)
+
+	// OnLowSpace is called once each time the guard enters degraded mode (for alerting)
+	OnLowSpace func(status DiskStatus)
+	// Prune is called while degraded to free space (e.g., apply retention policies)
+	Prune func(ctx context.Context) error
+}
+
+// DiskGuard monitors free space and disables writes before the volume fills up
+type DiskGuard struct {
+	opts   DiskGuardOptions
+	mu     sync.RWMutex
+	status DiskStatus
+}
+
+// StartDiskGuard starts monitoring free space on the database volume until ctx is cancelled.
+// While degraded, package write helpers return ErrDiskSpaceLow.
+func StartDiskGuard(ctx context.Context, opts DiskGuardOptions) (*DiskGuard, error) {
+	if opts.Path == "" {
+		databaseFile := configValue("DATABASE_FILE")
+		if databaseFile == "" {
+			return nil, fmt.Errorf("disk guard requires Path or DATABASE_FILE")
+		}
+		opts.Path = filepath.Dir(databaseFile)
+	}
+	if opts.MinFreeBytes == 0 && opts.MinFreePercent == 0 {
+		return nil, fmt.Errorf("disk guard requires MinFreeBytes or MinFreePercent")
+	}
+	if opts.ResumeMargin <= 0 {
+		opts.ResumeMargin = 0.1
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+
+	guard := &DiskGuard{opts: opts, status: DiskStatus{Path: opts.Path}}
+	guard.check(ctx)
+	setWriteGuard("disk", guard.guard)
+
+	tracker := startSubsystem("disk_guard", opts.Interval)
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		defer tracker.stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				removeWriteGuard("disk")
+				return
+			case <-ticker.C:
+				tracker.run(func() error { return guard.check(ctx) })
+			}
+		}
+	}()
+
+	logf("💽 Disk guard started: %s", opts.Path)
+	return guard, nil
+}
+
+// Status returns the most recent disk status
+func (g *DiskGuard) Status() DiskStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.status
+}
+
+// guard is the write guard installed while the monitor is running
+func (g *DiskGuard) guard(ctx context.Context) error {
+	if g.Status().Degraded {
+		return ErrDiskSpaceLow
+	}
+	return nil
+}
+
+// check measures free space and updates the degraded state with hysteresis
+func (g *DiskGuard) check(ctx context.Context) error {
+	total, free, err := diskUsage(g.opts.Path)
+
+	g.mu.Lock()
+	g.status.CheckedAt = time.Now()
+	if err != nil {
+		g.status.LastError = err.Error()
+		g.mu.Unlock()
+		logWarnf("⚠️  Disk guard failed to read free space: %v", err)
+		return err
+	}
+	g.status.LastError = ""
+	g.status.TotalBytes = total
+	g.status.FreeBytes = free
+	if total > 0 {
+		g.status.FreePercent = float64(free) / float64(total) * 100
+	}
+
+	wasDegraded := g.status.Degraded
+	margin := 1.0
+	if wasDegraded {
+		margin += g.opts.ResumeMargin
+	}
+	low := (g.opts.MinFreeBytes > 0 && float64(free) < float64(g.opts.MinFreeBytes)*margin) ||
+		(g.opts.MinFreePercent > 0 && g.status.FreePercent < g.opts.MinFreePercent*margin)
+	g.status.Degraded = low
+	status := g.status
+	g.mu.Unlock()
+
+	switch {
+	case low && !wasDegraded:
+		logErrorf("❌ Low disk space on %s (%d bytes, %.1f%% free) - disabling writes", status.Path, status.FreeBytes, status.FreePercent)
+		if g.opts.OnLowSpace != nil {
+			g.opts.OnLowSpace(status)
+		}
+	case !low && wasDegraded:
+		logf("✅ Disk space recovered on %s (%d bytes, %.1f%% free) - writes enabled", status.Path, status.FreeBytes, status.FreePercent)
+	}
+
+	if low && g.opts.Prune != nil {
+		logf("🧹 Running emergency pruning")
+		if err := g.opts.Prune(ctx); err != nil {
+			logErrorf("❌ Emergency pruning failed: %v", err)
+			return fmt.Errorf("emergency pruning failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestDiskGuardDegradesAndRecovers verifies that writes are refused below the free-space
+// minimum, that the alert fires once, and that writes resume once space recovers
+func TestDiskGuardDegradesAndRecovers(t *testing.T) {
+	alerts := 0
+	guard := &DiskGuard{opts: DiskGuardOptions{
+		Path:         t.TempDir(),
+		MinFreeBytes: 1 << 62,
+		ResumeMargin: 0.1,
+		OnLowSpace:   func(DiskStatus) { alerts++ },
+	}}
+
+	guard.check(context.Background())
+	guard.check(context.Background())
+	if status := guard.Status(); !status.Degraded || status.LastError != "" {
+		t.Fatalf("Expected degraded status without error, got %+v", status)
+	}
+	if err := guard.guard(context.Background()); !errors.Is(err, ErrDiskSpaceLow) {
+		t.Errorf("Expected ErrDiskSpaceLow while degraded, got %v", err)
+	}
+	if alerts != 1 {
+		t.Errorf("Expected exactly one low-space alert, got %d", alerts)
+	}
+
+	guard.opts.MinFreeBytes = 1
+	guard.check(context.Background())
+	if err := guard.guard(context.Background()); err != nil {
+		t.Errorf("Expected writes to resume after recovery, got %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package database
+
+import "syscall"
+
+// diskUsage returns total and available bytes on the filesystem containing path
+func diskUsage(path string) (uint64, uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	return uint64(stat.Blocks) * uint64(stat.Bsize), uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,304 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// DriverName is the database/sql driver name that runs every statement through this
+// package's pipeline: write guards, priority lanes, retry policies, quota errors, result
+// limits, query hints and tracing. Code that only holds a plain *sql.DB gets the same behavior by
+// opening it with sql.Open(database.DriverName, path) instead of "sqlite".
+const DriverName = "sqlite-rs"
+
+func init() {
+	sql.Register(DriverName, &pipelineDriver{})
+}
+
+// sqliteDriver returns the underlying SQLite driver registered as "sqlite"
+var sqliteDriver = sync.OnceValue(func() driver.Driver {
+	db, err := sql.Open("sqlite", "")
+	if err != nil {
+		panic("sqlite driver is not registered: " + err.Error())
+	}
+	defer db.Close()
+	return db.Driver()
+})
+
+// pipelineDriver wraps the SQLite driver's connections with the execution pipeline
+type pipelineDriver struct{}
+
+func (d *pipelineDriver) Open(name string) (driver.Conn, error) {
+	conn, err := sqliteDriver().Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &pipelineConn{Conn: conn}, nil
+}
+
+// pipelineConn runs statements through the execution pipeline before reaching SQLite
+type pipelineConn struct {
+	driver.Conn
+	inTx        bool               // A transaction is open; its statements skip the write queue
+	txStatement func(query string) // Records the open transaction's first statement for lock diagnostics
+	broken      bool               // A transaction could not be ended; discard instead of reusing
+}
+
+func (c *pipelineConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return c.exec(ctx, query, args, func(ctx context.Context, query string) (driver.Result, error) {
+		return execer.ExecContext(ctx, query, args)
+	})
+}
+
+func (c *pipelineConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return c.query(ctx, query, args, func(ctx context.Context, query string) (driver.Rows, error) {
+		return queryer.QueryContext(ctx, query, args)
+	})
+}
+
+// exec runs a statement through the pipeline; run executes the (hinted) query on SQLite
+func (c *pipelineConn) exec(ctx context.Context, query string, args []driver.NamedValue, run func(ctx context.Context, query string) (driver.Result, error)) (driver.Result, error) {
+	if ClassifyStatement(query) != StatementRead {
+		if err := checkWriteGuards(ctx); err != nil {
+			return nil, err
+		}
+		if c.inTx {
+			c.txStatement(query)
+		} else {
+			var leave func()
+			var err error
+			if ctx, leave, err = enterWriteQueue(ctx); err != nil {
+				return nil, err
+			}
+			defer leave()
+			defer trackWrite(query)()
+		}
+	}
+
+	query, restore, err := c.applyHints(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	var result driver.Result
+	recorder, ctx := startStatement(ctx, "exec", query)
+	err = c.traced(ctx, "sqlite.exec", query, args, func(ctx context.Context) error {
+		return retryInLane(ctx, func() error {
+			var err error
+			recorder.attempt()
+			result, err = run(ctx, query)
+			return err
+		}, retryConfigFor(ctx, query))
+	})
+	recorder.finish(rowsAffected(result), err)
+	if err == nil {
+		invalidateRowCacheForWrite(ctx, query)
+		countTableWrite(query, rowsAffected(result))
+	}
+	return result, resultError(err)
+}
+
+// query runs a query through the pipeline; run executes the (hinted) query on SQLite
+func (c *pipelineConn) query(ctx context.Context, query string, args []driver.NamedValue, run func(ctx context.Context, query string) (driver.Rows, error)) (driver.Rows, error) {
+	query, restore, err := c.applyHints(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows driver.Rows
+	recorder, ctx := startStatement(ctx, "query", query)
+	err = c.traced(ctx, "sqlite.query", query, args, func(ctx context.Context) error {
+		return retryInLane(ctx, func() error {
+			var err error
+			recorder.attempt()
+			rows, err = run(ctx, query)
+			return err
+		}, retryConfigFor(ctx, query))
+	})
+	recorder.finish(-1, err)
+	if err != nil {
+		restore()
+		return nil, resultError(err)
+	}
+	return limitRows(ctx, &hintedRows{Rows: rows, restore: restore}, query), nil
+}
+
+// PrepareContext prepares a statement whose executions run through the pipeline. NOT
+// INDEXED hints on ctx are applied when the statement is prepared; PRAGMA hints apply to
+// each execution.
+func (c *pipelineConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if hints, ok := hintsFromContext(ctx); ok {
+		query = applyNotIndexed(query, hints.NotIndexed)
+	}
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, resultError(err)
+	}
+	return &pipelineStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+// pipelineStmt is a prepared statement whose executions run through its connection's
+// pipeline, like statements executed directly on the connection
+type pipelineStmt struct {
+	driver.Stmt
+	conn  *pipelineConn
+	query string
+}
+
+func (s *pipelineStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.exec(ctx, s.query, args, func(ctx context.Context, _ string) (driver.Result, error) {
+		if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+			return execer.ExecContext(ctx, args)
+		}
+		values, err := stmtValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Stmt.Exec(values) // Fallback for drivers without StmtExecContext
+	})
+}
+
+func (s *pipelineStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.query(ctx, s.query, args, func(ctx context.Context, _ string) (driver.Rows, error) {
+		if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			return queryer.QueryContext(ctx, args)
+		}
+		values, err := stmtValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Stmt.Query(values) // Fallback for drivers without StmtQueryContext
+	})
+}
+
+// stmtValues converts positional arguments for drivers without context-aware statements
+func stmtValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("driver does not support named parameters (%s)", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+func (c *pipelineConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin() // Fallback for drivers without BeginTx
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	done, setStatement := trackLockHolder("transaction", "")
+	c.txStatement = setStatement
+	return &pipelineTx{Tx: tx, conn: c, done: done}, nil
+}
+
+// pipelineTx tracks when a connection's transaction ends
+type pipelineTx struct {
+	driver.Tx
+	conn *pipelineConn
+	done func()
+}
+
+// Commit commits the transaction. A COMMIT that fails, e.g. with SQLITE_BUSY, leaves
+// SQLite's transaction open, so it is rolled back; if that fails too, the connection is
+// discarded rather than returned to the pool holding a write transaction.
+func (t *pipelineTx) Commit() error {
+	err := t.Tx.Commit()
+	if err != nil {
+		execer, ok := t.conn.Conn.(driver.ExecerContext)
+		if !ok {
+			t.conn.broken = true
+		} else if _, rollbackErr := execer.ExecContext(context.Background(), "ROLLBACK", nil); rollbackErr != nil {
+			t.conn.broken = true
+		}
+	}
+	t.conn.inTx = false
+	t.done()
+	return err
+}
+
+func (t *pipelineTx) Rollback() error {
+	err := t.Tx.Rollback()
+	if err != nil {
+		t.conn.broken = true
+	}
+	t.conn.inTx = false
+	t.done()
+	return err
+}
+
+func (c *pipelineConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *pipelineConn) ResetSession(ctx context.Context) error {
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *pipelineConn) IsValid() bool {
+	if c.broken {
+		return false
+	}
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// traced runs fn inside a Datadog span when tracing is enabled
+func (c *pipelineConn) traced(ctx context.Context, operation, query string, args []driver.NamedValue, fn func(ctx context.Context) error) error {
+	if !isTracingEnabled() {
+		return fn(ctx)
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	span, ctx := startSQLSpan(ctx, operation, query, values)
+	defer span.Finish()
+
+	err := fn(ctx)
+	if err != nil {
+		span.SetTag(ext.Error, err)
+		span.SetTag("error.message", err.Error())
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPipelineDriverAppliesWriteGuards verifies that a plain *sql.DB opened with DriverName
+// runs writes through the write guards while reads pass
+func TestPipelineDriverAppliesWriteGuards(t *testing.T) {
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "driver.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO items (id) VALUES (1)"); err != nil {
+		t.Fatalf("Failed to insert in transaction: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	blocked := errors.New("writes blocked")
+	setWriteGuard("test-driver", func(context.Context) error { return blocked })
+	defer removeWriteGuard("test-driver")
+
+	if _, err := db.Exec("INSERT INTO items (id) VALUES (2)"); !errors.Is(err, blocked) {
+		t.Errorf("Expected write guard error, got %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected 1 item readable while writes are blocked, got %d (%v)", count, err)
+	}
+}
+
+// TestPipelineDriverPreparedStatements verifies that prepared statements, on the handle and
+// in transactions, run through the write guards and statement stats
+func TestPipelineDriverPreparedStatements(t *testing.T) {
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "prepared.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	EnableStatementStats(0)
+	defer DisableStatementStats()
+	insert, err := db.Prepare("INSERT INTO items (id) VALUES (?)")
+	if err != nil {
+		t.Fatalf("Failed to prepare: %v", err)
+	}
+	defer insert.Close()
+	for id := 1; id <= 3; id++ {
+		if _, err := insert.Exec(id); err != nil {
+			t.Fatalf("Prepared insert failed: %v", err)
+		}
+	}
+	var calls int64
+	for _, stat := range TopStatements(ByCount, 0) {
+		if stat.Statement == NormalizeStatement("INSERT INTO items (id) VALUES (?)") {
+			calls = stat.Calls
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 recorded executions of the prepared insert, got %d", calls)
+	}
+
+	blocked := errors.New("writes blocked")
+	setWriteGuard("test-prepared", func(context.Context) error { return blocked })
+	defer removeWriteGuard("test-prepared")
+
+	if _, err := insert.Exec(4); !errors.Is(err, blocked) {
+		t.Errorf("Expected the write guard to block a prepared insert, got %v", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare("INSERT INTO items (id) VALUES (?)")
+	if err != nil {
+		t.Fatalf("Failed to prepare in transaction: %v", err)
+	}
+	if _, err := stmt.Exec(5); !errors.Is(err, blocked) {
+		t.Errorf("Expected the write guard to block a prepared insert in a transaction, got %v", err)
+	}
+	count, err := tx.Prepare("SELECT COUNT(*) FROM items")
+	if err != nil {
+		t.Fatalf("Failed to prepare query: %v", err)
+	}
+	var n int
+	if err := count.QueryRow().Scan(&n); err != nil || n != 3 {
+		t.Errorf("Expected prepared reads to pass with 3 rows, got %d (%v)", n, err)
+	}
+}
+
+// TestPipelineDriverBusyCommitReleasesConnection verifies that a transaction whose COMMIT
+// failed with BUSY does not leave its write transaction open on the pooled connection
+func TestPipelineDriverBusyCommitReleasesConnection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.db")
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// A reader on another handle holds a SHARED lock, so COMMIT cannot take EXCLUSIVE
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open second handle: %v", err)
+	}
+	defer other.Close()
+	reader, err := other.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin reader: %v", err)
+	}
+	var count int
+	if err := reader.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO items (id) VALUES (1)"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := tx.Commit(); !IsBusy(err) {
+		t.Fatalf("Expected a BUSY commit, got %v", err)
+	}
+	reader.Rollback()
+
+	if _, err := db.Exec("INSERT INTO items (id) VALUES (2)"); err != nil {
+		t.Fatalf("Failed to insert after BUSY commit: %v", err)
+	}
+	var id int
+	if err := other.QueryRow("SELECT COUNT(*), MAX(id) FROM items").Scan(&count, &id); err != nil || count != 1 || id != 2 {
+		t.Errorf("Expected only the later write to be visible to another handle, got %d rows (%v)", count, err)
+	}
+}
+
+// TestPipelineDriverQueriesUseLanes verifies that pipeline queries in the batch lane yield
+// to interactive operations like pipeline statements do
+func TestPipelineDriverQueriesUseLanes(t *testing.T) {
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "lanes.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	leaveInteractive, err := enterLane(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to enter interactive lane: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		var n int
+		db.QueryRowContext(WithQoS(context.Background(), QoSBatch), "SELECT 1").Scan(&n)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Batch query ran while an interactive operation was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	leaveInteractive()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Batch query did not run after the interactive operation finished")
+	}
+}
@@ -0,0 +1,307 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// EFS coordination modes
+const (
+	EFSModeWriter   = "writer"   // This process holds the writer lease
+	EFSModeReader   = "reader"   // Another process holds the writer lease
+	EFSModeDegraded = "degraded" // The lease file is misbehaving; all writes are refused
+)
+
+var (
+	// ErrNotWriter is returned for writes while another process holds the writer lease
+	ErrNotWriter = errors.New("database writer lease is held by another process")
+	// ErrDegradedReadOnly is returned for writes while lease coordination is unreliable
+	ErrDegradedReadOnly = errors.New("database is in degraded read-only mode")
+)
+
+// EFSOptions configures lease-based single-writer coordination on network filesystems
+type EFSOptions struct {
+	LeaseFile     string        // Sidecar lease file (default: DATABASE_FILE + ".lease")
+	Owner         string        // Unique owner ID (default: hostname:pid)
+	LeaseTTL      time.Duration // Lease lifetime (default: 15s)
+	RenewInterval time.Duration // How often the lease is checked and renewed (default: LeaseTTL/3)
+	MaxClockSkew  time.Duration // Tolerated clock skew between hosts (default: 5s)
+	MaxAnomalies  int           // Consecutive anomalies before degrading to read-only (default: 3)
+}
+
+// EFSHealth reports the state of lease coordination
+type EFSHealth struct {
+	Mode                 string
+	Owner                string
+	LeaseOwner           string
+	LeaseExpiresAt       time.Time
+	LastCheck            time.Time
+	ConsecutiveAnomalies int
+	TotalAnomalies       int
+	LastAnomaly          string
+}
+
+// leaseRecord is the JSON content of the lease file
+type leaseRecord struct {
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expires_at"` // Unix milliseconds
+}
+
+// WriterLease coordinates a single writer across processes sharing a database on EFS/NFS,
+// where SQLite's own file locking cannot be trusted
+type WriterLease struct {
+	opts   EFSOptions
+	mu     sync.RWMutex
+	health EFSHealth
+	done   chan struct{}
+}
+
+// StartEFSCoordination acquires (or waits on) the writer lease and keeps renewing it until
+// ctx is cancelled. While running, package write helpers refuse writes unless this process
+// is the lease holder, and refuse all writes when the lease file behaves inconsistently.
+func StartEFSCoordination(ctx context.Context, opts EFSOptions) (*WriterLease, error) {
+	if opts.LeaseFile == "" {
+		databaseFile := configValue("DATABASE_FILE")
+		if databaseFile == "" {
+			return nil, fmt.Errorf("EFS coordination requires LeaseFile or DATABASE_FILE")
+		}
+		opts.LeaseFile = databaseFile + ".lease"
+	}
+	if opts.Owner == "" {
+		hostname, _ := os.Hostname()
+		opts.Owner = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = 15 * time.Second
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = opts.LeaseTTL / 3
+	}
+	if opts.MaxClockSkew <= 0 {
+		opts.MaxClockSkew = 5 * time.Second
+	}
+	if opts.MaxAnomalies <= 0 {
+		opts.MaxAnomalies = 3
+	}
+
+	lease := &WriterLease{
+		opts:   opts,
+		health: EFSHealth{Mode: EFSModeReader, Owner: opts.Owner},
+		done:   make(chan struct{}),
+	}
+	lease.check()
+	setWriteGuard("efs", lease.guard)
+
+	tracker := startSubsystem("efs", opts.RenewInterval)
+	go func() {
+		defer close(lease.done)
+		ticker := time.NewTicker(opts.RenewInterval)
+		defer ticker.Stop()
+		defer tracker.stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				lease.release()
+				removeWriteGuard("efs")
+				return
+			case <-ticker.C:
+				tracker.run(func() error {
+					lease.check()
+					if health := lease.Health(); health.ConsecutiveAnomalies > 0 {
+						return errors.New(health.LastAnomaly)
+					}
+					return nil
+				})
+			}
+		}
+	}()
+
+	logf("🔐 EFS coordination started: %s (mode: %s)", opts.LeaseFile, lease.Health().Mode)
+	return lease, nil
+}
+
+// Health returns a snapshot of the lease state
+func (l *WriterLease) Health() EFSHealth {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.health
+}
+
+// IsWriter reports whether this process currently holds the writer lease
+func (l *WriterLease) IsWriter() bool {
+	return l.Health().Mode == EFSModeWriter
+}
+
+// Done is closed once coordination has stopped and the lease has been released
+func (l *WriterLease) Done() <-chan struct{} {
+	return l.done
+}
+
+// guard is the write guard installed while coordination is running
+func (l *WriterLease) guard(ctx context.Context) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	switch l.health.Mode {
+	case EFSModeWriter:
+		// A lease we failed to renew in time is no longer safe to write under
+		if time.Now().After(l.health.LeaseExpiresAt) {
+			return ErrNotWriter
+		}
+		return nil
+	case EFSModeDegraded:
+		return ErrDegradedReadOnly
+	default:
+		return ErrNotWriter
+	}
+}
+
+// check reads the lease file, acquires or renews the lease when possible,
+// and records anomalies that indicate unreliable filesystem semantics
+func (l *WriterLease) check() {
+	now := time.Now()
+	wasWriter := l.IsWriter()
+
+	current, err := l.readLease()
+	if err != nil && !os.IsNotExist(err) {
+		l.recordAnomaly(fmt.Sprintf("failed to read lease: %v", err))
+		return
+	}
+
+	if current != nil {
+		expiresAt := time.UnixMilli(current.ExpiresAt)
+
+		// A lease expiring further out than any owner could have set indicates clock skew
+		if expiresAt.After(now.Add(l.opts.LeaseTTL + l.opts.MaxClockSkew)) {
+			l.recordAnomaly(fmt.Sprintf("lease held by %s expires too far in the future (%v)", current.Owner, expiresAt))
+			return
+		}
+
+		if current.Owner != l.opts.Owner && expiresAt.Add(l.opts.MaxClockSkew).After(now) {
+			if wasWriter {
+				l.recordAnomaly(fmt.Sprintf("lease taken over by %s while held", current.Owner))
+				return
+			}
+			l.update(EFSModeReader, current)
+			return
+		}
+	}
+
+	// The lease is free, expired, or ours: write it and read it back to confirm
+	record := &leaseRecord{Owner: l.opts.Owner, ExpiresAt: now.Add(l.opts.LeaseTTL).UnixMilli()}
+	if err := l.writeLease(record); err != nil {
+		l.recordAnomaly(fmt.Sprintf("failed to write lease: %v", err))
+		return
+	}
+
+	confirmed, err := l.readLease()
+	if err != nil {
+		l.recordAnomaly(fmt.Sprintf("failed to confirm lease: %v", err))
+		return
+	}
+	if confirmed.Owner != l.opts.Owner {
+		// Another process raced us; defer to it
+		l.update(EFSModeReader, confirmed)
+		return
+	}
+
+	if !wasWriter {
+		logf("👑 Acquired database writer lease: %s", l.opts.Owner)
+	}
+	l.update(EFSModeWriter, confirmed)
+}
+
+// update records a successful check, clearing consecutive anomalies
+func (l *WriterLease) update(mode string, record *leaseRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.health.Mode = mode
+	l.health.LeaseOwner = record.Owner
+	l.health.LeaseExpiresAt = time.UnixMilli(record.ExpiresAt)
+	l.health.LastCheck = time.Now()
+	l.health.ConsecutiveAnomalies = 0
+}
+
+// recordAnomaly counts a lease inconsistency and degrades to read-only when they persist
+func (l *WriterLease) recordAnomaly(message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.health.LastCheck = time.Now()
+	l.health.ConsecutiveAnomalies++
+	l.health.TotalAnomalies++
+	l.health.LastAnomaly = message
+	logWarnf("⚠️  EFS lease anomaly (%d consecutive): %s", l.health.ConsecutiveAnomalies, message)
+
+	if l.health.ConsecutiveAnomalies >= l.opts.MaxAnomalies && l.health.Mode != EFSModeDegraded {
+		l.health.Mode = EFSModeDegraded
+		logErrorf("❌ EFS lease coordination unreliable - switching to degraded read-only mode")
+	} else if l.health.Mode == EFSModeWriter {
+		// Never keep writing through an anomaly
+		l.health.Mode = EFSModeReader
+	}
+}
+
+// release gives up the lease if this process holds it
+func (l *WriterLease) release() {
+	current, err := l.readLease()
+	if err == nil && current.Owner == l.opts.Owner {
+		if err := os.Remove(l.opts.LeaseFile); err != nil {
+			logWarnf("⚠️  Failed to release EFS lease: %v", err)
+		}
+	}
+
+	l.mu.Lock()
+	l.health.Mode = EFSModeReader
+	l.mu.Unlock()
+	logf("🔓 EFS coordination stopped: %s", l.opts.LeaseFile)
+}
+
+// readLease reads and decodes the lease file
+func (l *WriterLease) readLease() (*leaseRecord, error) {
+	data, err := os.ReadFile(l.opts.LeaseFile)
+	if err != nil {
+		return nil, err
+	}
+	var record leaseRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("corrupt lease file: %w", err)
+	}
+	return &record, nil
+}
+
+// writeLease atomically replaces the lease file via write-and-rename
+func (l *WriterLease) writeLease(record *leaseRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.opts.LeaseFile), filepath.Base(l.opts.LeaseFile)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), l.opts.LeaseFile)
+}
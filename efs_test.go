@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWriterLeaseSingleWriter verifies that only one process holds the writer lease,
+// that writes are refused for non-holders, and that the lease is handed over on release
+func TestWriterLeaseSingleWriter(t *testing.T) {
+	leaseFile := filepath.Join(t.TempDir(), "app.db.lease")
+	opts := EFSOptions{LeaseFile: leaseFile, LeaseTTL: time.Second, RenewInterval: time.Hour}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	optsA := opts
+	optsA.Owner = "host-a"
+	leaseA, err := StartEFSCoordination(ctxA, optsA)
+	if err != nil {
+		t.Fatalf("Failed to start coordination A: %v", err)
+	}
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	optsB := opts
+	optsB.Owner = "host-b"
+	leaseB, err := StartEFSCoordination(ctxB, optsB)
+	if err != nil {
+		t.Fatalf("Failed to start coordination B: %v", err)
+	}
+
+	if !leaseA.IsWriter() || leaseB.IsWriter() {
+		t.Fatalf("Expected A to be writer and B reader, got A=%s B=%s", leaseA.Health().Mode, leaseB.Health().Mode)
+	}
+	if err := leaseB.guard(context.Background()); !errors.Is(err, ErrNotWriter) {
+		t.Errorf("Expected ErrNotWriter for reader, got %v", err)
+	}
+
+	cancelA()
+	<-leaseA.Done()
+
+	leaseB.check()
+	if !leaseB.IsWriter() {
+		t.Errorf("Expected B to acquire the lease after A released it, got %s", leaseB.Health().Mode)
+	}
+
+	cancelB()
+	<-leaseB.Done()
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultExistsCacheEntries bounds the negative results kept by CachedExists
+const DefaultExistsCacheEntries = 100000
+
+// existsCache holds negative existence results until they expire
+var existsCache = struct {
+	mu      sync.Mutex
+	missing map[string]time.Time // Key -> expiry
+}{missing: make(map[string]time.Time)}
+
+// existsFlights collapses concurrent checks of the same key
+var existsFlights flightGroup
+
+// CachedExists reports whether query returns a row, remembering a "no" under key for ttl.
+// Concurrent checks of the same key share one query, so a burst of lookups for a missing
+// key (e.g. a bogus API key) costs one query per ttl. Positive results are never cached.
+// Call ForgetCachedExists after inserting the row key stands for.
+func CachedExists(ctx context.Context, key string, ttl time.Duration, query string, args ...interface{}) (bool, error) {
+	existsCache.mu.Lock()
+	expires, cached := existsCache.missing[key]
+	if cached && time.Now().After(expires) {
+		delete(existsCache.missing, key)
+		cached = false
+	}
+	existsCache.mu.Unlock()
+	if cached {
+		return false, nil
+	}
+
+	// The query is shared, so one caller's cancellation must not fail the others
+	queryCtx := context.WithoutCancel(ctx)
+	value, err, _ := existsFlights.do(key, func() (interface{}, error) {
+		db, err := GetDB()
+		if err != nil {
+			return false, err
+		}
+		defer db.Close()
+
+		exists := false
+		err = retryDatabaseOperationContext(queryCtx, func() error {
+			rows, err := db.QueryContext(queryCtx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			exists = rows.Next()
+			return rows.Err()
+		}, retryConfigFor(queryCtx, query))
+		if err != nil {
+			return false, err
+		}
+		if !exists && ttl > 0 {
+			rememberMissing(key, time.Now().Add(ttl))
+		}
+		return exists, nil
+	})
+	if err != nil {
+		return false, resultError(err)
+	}
+	return value.(bool), nil
+}
+
+// ForgetCachedExists drops a cached negative result, e.g. after creating the row
+func ForgetCachedExists(key string) {
+	existsCache.mu.Lock()
+	defer existsCache.mu.Unlock()
+	delete(existsCache.missing, key)
+}
+
+// rememberMissing caches a negative result, evicting expired (then arbitrary) entries
+// when the cache is full
+func rememberMissing(key string, expires time.Time) {
+	existsCache.mu.Lock()
+	defer existsCache.mu.Unlock()
+	if len(existsCache.missing) >= DefaultExistsCacheEntries {
+		now := time.Now()
+		for k, e := range existsCache.missing {
+			if now.After(e) {
+				delete(existsCache.missing, k)
+			}
+		}
+		for k := range existsCache.missing {
+			if len(existsCache.missing) < DefaultExistsCacheEntries {
+				break
+			}
+			delete(existsCache.missing, k)
+		}
+	}
+	existsCache.missing[key] = expires
+}
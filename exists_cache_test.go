@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCachedExistsRemembersMissingKeys verifies that a negative result is served from
+// the cache until it expires or is forgotten, and that positive results are not cached
+func TestCachedExistsRemembersMissingKeys(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "exists.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := ExecWithRetry(db, "CREATE TABLE api_keys (key TEXT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+
+	ctx := context.Background()
+	check := func() bool {
+		t.Helper()
+		exists, err := CachedExists(ctx, "api_key:abc", time.Minute, "SELECT 1 FROM api_keys WHERE key = ?", "abc")
+		if err != nil {
+			t.Fatalf("Failed to check key: %v", err)
+		}
+		return exists
+	}
+
+	if check() {
+		t.Fatal("Expected the key to be missing")
+	}
+	if _, err := ExecWithRetry(db, "INSERT INTO api_keys VALUES ('abc')"); err != nil {
+		t.Fatalf("Failed to insert key: %v", err)
+	}
+	if check() {
+		t.Error("Expected the cached negative result until it is forgotten")
+	}
+	ForgetCachedExists("api_key:abc")
+	if !check() {
+		t.Error("Expected the key to exist once forgotten")
+	}
+	if _, err := ExecWithRetry(db, "DELETE FROM api_keys"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if check() {
+		t.Error("Expected positive results not to be cached")
+	}
+	ForgetCachedExists("api_key:abc")
+}
+
+// TestFlightGroupSharesConcurrentCalls verifies that concurrent calls with one key run once
+func TestFlightGroupSharesConcurrentCalls(t *testing.T) {
+	var group flightGroup
+	var runs atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err, _ := group.do("key", func() (interface{}, error) {
+				runs.Add(1)
+				<-release
+				return 42, nil
+			})
+			if err != nil || value != 42 {
+				t.Errorf("Unexpected result %v, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // Let every caller join the flight
+	close(release)
+	wg.Wait()
+
+	if n := runs.Load(); n != 1 {
+		t.Errorf("Expected one execution, got %d", n)
+	}
+}
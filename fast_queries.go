@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// QueryInt64s returns the first column of every row as int64, e.g. for ID lists. Values
+// scan straight into typed destinations without maps or reflection; a NULL is an error, so
+// filter them in SQL.
+func QueryInt64s(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]int64, error) {
+	var result []int64
+	err := queryColumn(ctx, db, query, args, func(rows *sql.Rows) error {
+		var value int64
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+		result = append(result, value)
+		return nil
+	}, func() { result = result[:0] })
+	return result, err
+}
+
+// QueryStrings returns the first column of every row as a string. A NULL is an error, so
+// filter them in SQL.
+func QueryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	var result []string
+	err := queryColumn(ctx, db, query, args, func(rows *sql.Rows) error {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+		result = append(result, value)
+		return nil
+	}, func() { result = result[:0] })
+	return result, err
+}
+
+// ExistsQuery reports whether query returns at least one row. The query is wrapped in
+// SELECT EXISTS(...), so SQLite stops at the first match.
+func ExistsQuery(ctx context.Context, db *sql.DB, query string, args ...interface{}) (bool, error) {
+	wrapped := "SELECT EXISTS(" + query + ")"
+	var exists bool
+	err := retryDatabaseOperationContext(ctx, func() error {
+		return db.QueryRowContext(ctx, wrapped, args...).Scan(&exists)
+	}, retryConfigFor(ctx, query))
+	if err != nil {
+		return false, resultError(err)
+	}
+	return exists, nil
+}
+
+// queryColumn runs a single-column query with retries, calling scan for each row and
+// reset before every attempt
+func queryColumn(ctx context.Context, db *sql.DB, query string, args []interface{}, scan func(*sql.Rows) error, reset func()) error {
+	err := retryDatabaseOperationContext(ctx, func() error {
+		reset()
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		if len(columns) != 1 {
+			return fmt.Errorf("query returns %d columns, want 1", len(columns))
+		}
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}, retryConfigFor(ctx, query))
+	return resultError(err)
+}
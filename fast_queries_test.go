@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openFastQueryDB returns a database with 100 items
+func openFastQueryDB(t testing.TB) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "fast.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100)
+		INSERT INTO items SELECT i, 'item' || i FROM n`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	return db
+}
+
+// TestTypedColumnQueries verifies QueryInt64s, QueryStrings and ExistsQuery
+func TestTypedColumnQueries(t *testing.T) {
+	db := openFastQueryDB(t)
+	ctx := context.Background()
+
+	ids, err := QueryInt64s(ctx, db, "SELECT id FROM items WHERE id <= ? ORDER BY id", 3)
+	if err != nil || !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("Unexpected ids %v: %v", ids, err)
+	}
+	names, err := QueryStrings(ctx, db, "SELECT name FROM items WHERE id IN (1, 2) ORDER BY id")
+	if err != nil || !reflect.DeepEqual(names, []string{"item1", "item2"}) {
+		t.Errorf("Unexpected names %v: %v", names, err)
+	}
+	if _, err := QueryInt64s(ctx, db, "SELECT id, name FROM items"); err == nil {
+		t.Error("Expected multi-column queries to be rejected")
+	}
+	if _, err := QueryStrings(ctx, db, "SELECT NULL"); err == nil {
+		t.Error("Expected NULL values to be an error")
+	}
+
+	exists, err := ExistsQuery(ctx, db, "SELECT 1 FROM items WHERE name = ?", "item50")
+	if err != nil || !exists {
+		t.Errorf("Expected item50 to exist: %v", err)
+	}
+	if exists, err := ExistsQuery(ctx, db, "SELECT 1 FROM items WHERE name = ?", "nope"); err != nil || exists {
+		t.Errorf("Expected no match: %v", err)
+	}
+}
+
+// BenchmarkQueryInt64s measures the typed ID-list path
+func BenchmarkQueryInt64s(b *testing.B) {
+	db := openFastQueryDB(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := QueryInt64s(ctx, db, "SELECT id FROM items"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkQueryInt64sViaRowMaps is the map-based baseline for BenchmarkQueryInt64s
+func BenchmarkQueryInt64sViaRowMaps(b *testing.B) {
+	db := openFastQueryDB(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT id FROM items")
+		if err != nil {
+			b.Fatal(err)
+		}
+		result, _, err := scanRowMaps(rows, 0)
+		rows.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids := make([]int64, len(result))
+		for j, row := range result {
+			ids[j] = row["id"].(int64)
+		}
+	}
+}
+
+// BenchmarkExistsQuery measures existence checks
+func BenchmarkExistsQuery(b *testing.B) {
+	db := openFastQueryDB(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ExistsQuery(ctx, db, "SELECT 1 FROM items WHERE name = ?", "item50"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
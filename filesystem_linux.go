@@ -0,0 +1,30 @@
+//go:build linux
+
+package database
+
+import "syscall"
+
+// Filesystem magic numbers of network filesystems, from statfs(2)
+const (
+	nfsSuperMagic  = 0x6969
+	smbSuperMagic  = 0x517b
+	cifsSuperMagic = 0xff534d42
+	smb2SuperMagic = 0xfe534d42
+)
+
+// fileSystemInfo reports whether the filesystem containing path is mounted read-only,
+// and the name of its type when it is a network filesystem (NFS, which includes EFS, or SMB)
+func fileSystemInfo(path string) (bool, string, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false, "", err
+	}
+	readOnly := stat.Flags&syscall.MS_RDONLY != 0
+	switch uint32(stat.Type) {
+	case nfsSuperMagic:
+		return readOnly, "NFS", nil
+	case smbSuperMagic, cifsSuperMagic, smb2SuperMagic:
+		return readOnly, "SMB", nil
+	}
+	return readOnly, "", nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package database
+
+import "errors"
+
+// fileSystemInfo is not supported on this platform
+func fileSystemInfo(path string) (bool, string, error) {
+	return false, "", errors.New("filesystem detection is not supported on this platform")
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidFilter is returned for filters on fields outside the allowlist or with bad operands
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// FilterOp is a filter comparison
+type FilterOp string
+
+// Filter comparisons
+const (
+	OpEq    FilterOp = "eq"    // field = value
+	OpIn    FilterOp = "in"    // field IN (values...)
+	OpLike  FilterOp = "like"  // field LIKE pattern (\ escapes % and _)
+	OpRange FilterOp = "range" // min <= field <= max; a nil bound is open
+)
+
+// Filter is a condition on one field, or an AND/OR composition of filters. The zero
+// Filter matches everything.
+type Filter struct {
+	Field  string
+	Op     FilterOp
+	Values []interface{} // One value for eq and like, any number for in, [min, max] for range
+	And    []Filter
+	Or     []Filter
+}
+
+// FilterFields allowlists the fields a filter may use, mapping each API field name to its
+// column (e.g. "created": "created_at")
+type FilterFields map[string]string
+
+// Eq matches rows whose field equals value
+func Eq(field string, value interface{}) Filter {
+	return Filter{Field: field, Op: OpEq, Values: []interface{}{value}}
+}
+
+// In matches rows whose field is one of values; no values match no rows
+func In(field string, values ...interface{}) Filter {
+	return Filter{Field: field, Op: OpIn, Values: values}
+}
+
+// Like matches rows whose field matches a LIKE pattern
+func Like(field, pattern string) Filter {
+	return Filter{Field: field, Op: OpLike, Values: []interface{}{pattern}}
+}
+
+// Range matches rows whose field is between min and max inclusive; pass nil for an open end
+func Range(field string, min, max interface{}) Filter {
+	return Filter{Field: field, Op: OpRange, Values: []interface{}{min, max}}
+}
+
+// And matches rows matching every filter
+func And(filters ...Filter) Filter {
+	return Filter{And: filters}
+}
+
+// Or matches rows matching any filter
+func Or(filters ...Filter) Filter {
+	return Filter{Or: filters}
+}
+
+// IsEmpty reports whether the filter has no conditions and so matches everything
+func (f Filter) IsEmpty() bool {
+	if f.Field != "" {
+		return false
+	}
+	for _, sub := range append(append([]Filter(nil), f.And...), f.Or...) {
+		if !sub.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Compile renders the filter as a parameterized WHERE condition (without the WHERE
+// keyword), checking every field against fields. An empty filter compiles to "1".
+func (f Filter) Compile(fields FilterFields) (string, []interface{}, error) {
+	if f.IsEmpty() {
+		return "1", nil, nil
+	}
+	var args []interface{}
+	condition, err := f.compile(fields, &args)
+	if err != nil {
+		return "", nil, err
+	}
+	return condition, args, nil
+}
+
+// compile renders a non-empty filter, appending its arguments
+func (f Filter) compile(fields FilterFields, args *[]interface{}) (string, error) {
+	if f.Field == "" {
+		if len(f.And) > 0 && len(f.Or) > 0 {
+			return "", fmt.Errorf("%w: a filter cannot combine And and Or; nest them", ErrInvalidFilter)
+		}
+		joiner, subs := " AND ", f.And
+		if len(f.Or) > 0 {
+			joiner, subs = " OR ", f.Or
+		}
+		var parts []string
+		for _, sub := range subs {
+			if sub.IsEmpty() {
+				continue
+			}
+			part, err := sub.compile(fields, args)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		return "(" + strings.Join(parts, joiner) + ")", nil
+	}
+
+	column, ok := fields[f.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: field %q cannot be filtered on", ErrInvalidFilter, f.Field)
+	}
+	column = quoteIdentifier(column)
+
+	switch f.Op {
+	case OpEq, OpLike:
+		if len(f.Values) != 1 {
+			return "", fmt.Errorf("%w: %s on %q takes one value", ErrInvalidFilter, f.Op, f.Field)
+		}
+		if f.Op == OpEq && f.Values[0] == nil {
+			return column + " IS NULL", nil
+		}
+		*args = append(*args, f.Values[0])
+		if f.Op == OpLike {
+			return column + ` LIKE ? ESCAPE '\'`, nil
+		}
+		return column + " = ?", nil
+	case OpIn:
+		if len(f.Values) == 0 {
+			return "0", nil
+		}
+		*args = append(*args, f.Values...)
+		return column + " IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(f.Values)), ", ") + ")", nil
+	case OpRange:
+		if len(f.Values) != 2 || (f.Values[0] == nil && f.Values[1] == nil) {
+			return "", fmt.Errorf("%w: range on %q takes a min and/or max", ErrInvalidFilter, f.Field)
+		}
+		var parts []string
+		if f.Values[0] != nil {
+			parts = append(parts, column+" >= ?")
+			*args = append(*args, f.Values[0])
+		}
+		if f.Values[1] != nil {
+			parts = append(parts, column+" <= ?")
+			*args = append(*args, f.Values[1])
+		}
+		return "(" + strings.Join(parts, " AND ") + ")", nil
+	default:
+		return "", fmt.Errorf("%w: unknown operator %q on %q", ErrInvalidFilter, f.Op, f.Field)
+	}
+}
+
+// ParseFilterQuery builds an AND filter from URL query parameters on allowlisted fields:
+//
+//	status=open            eq
+//	status[in]=open,held   in (comma-separated)
+//	name[like]=ada%        like
+//	created[gte]=2024-01-01&created[lte]=2024-12-31   range
+//
+// Plain parameters that are not allowlisted fields (page, sort, ...) are ignored; an
+// operator on a field outside the allowlist is an error. Values are strings; SQLite's type
+// affinity compares them with numeric columns.
+func ParseFilterQuery(values url.Values, fields FilterFields) (Filter, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var filters []Filter
+	ranges := make(map[string]int) // Field -> index of its range in filters
+	for _, key := range keys {
+		field, op := key, ""
+		if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+			field, op = key[:open], key[open+1:len(key)-1]
+		}
+		if _, ok := fields[field]; !ok {
+			if op == "" {
+				continue
+			}
+			return Filter{}, fmt.Errorf("%w: field %q cannot be filtered on", ErrInvalidFilter, field)
+		}
+		value := values.Get(key)
+
+		switch op {
+		case "", "eq":
+			filters = append(filters, Eq(field, value))
+		case "in":
+			var items []interface{}
+			for _, item := range strings.Split(value, ",") {
+				items = append(items, item)
+			}
+			filters = append(filters, In(field, items...))
+		case "like":
+			filters = append(filters, Like(field, value))
+		case "gte", "lte":
+			i, ok := ranges[field]
+			if !ok {
+				i = len(filters)
+				ranges[field] = i
+				filters = append(filters, Range(field, nil, nil))
+			}
+			if op == "gte" {
+				filters[i].Values[0] = value
+			} else {
+				filters[i].Values[1] = value
+			}
+		default:
+			return Filter{}, fmt.Errorf("%w: unknown operator %q on %q", ErrInvalidFilter, op, field)
+		}
+	}
+	return And(filters...), nil
+}
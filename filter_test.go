@@ -0,0 +1,65 @@
+package database
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestFilterCompilesParameterizedConditions verifies compilation, allowlisting and
+// query string parsing of filters
+func TestFilterCompilesParameterizedConditions(t *testing.T) {
+	fields := FilterFields{"status": "status", "name": "name", "created": "created_at"}
+
+	where, args, err := And(
+		Eq("status", "open"),
+		Or(Like("name", "ada%"), In("name", "grace", "linus")),
+		Range("created", 10, nil),
+	).Compile(fields)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	expected := `("status" = ? AND ("name" LIKE ? ESCAPE '\' OR "name" IN (?, ?)) AND ("created_at" >= ?))`
+	if where != expected || !reflect.DeepEqual(args, []interface{}{"open", "ada%", "grace", "linus", 10}) {
+		t.Errorf("Unexpected compilation:\n%s\n%v", where, args)
+	}
+
+	if _, _, err := Eq("password", "x").Compile(fields); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expected fields outside the allowlist to be rejected, got %v", err)
+	}
+	if where, _, err := (Filter{}).Compile(fields); err != nil || where != "1" {
+		t.Errorf("Expected an empty filter to match everything, got %q: %v", where, err)
+	}
+
+	query, _ := url.ParseQuery("status[in]=open,held&created[gte]=5&created[lte]=20&page=2")
+	filter, err := ParseFilterQuery(query, fields)
+	if err != nil {
+		t.Fatalf("ParseFilterQuery failed: %v", err)
+	}
+	where, args, err = filter.Compile(fields)
+	if err != nil || where != `(("created_at" >= ? AND "created_at" <= ?) AND "status" IN (?, ?))` || len(args) != 4 {
+		t.Errorf("Unexpected parsed filter: %s %v (%v)", where, args, err)
+	}
+	if _, err := ParseFilterQuery(url.Values{"secret[like]": {"%"}}, fields); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expected an operator on an unknown field to be rejected, got %v", err)
+	}
+
+	// The compiled condition runs against SQLite
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "filter.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE tickets (status TEXT, name TEXT, created_at INTEGER);
+		INSERT INTO tickets VALUES ('open', 'a', 5), ('held', 'b', 25), ('closed', 'c', 10), ('open', 'd', 20)`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	where, args, _ = filter.Compile(fields)
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM tickets WHERE "+where, args...).Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected 2 matching tickets, got %d: %v", count, err)
+	}
+}
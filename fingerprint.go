@@ -0,0 +1,120 @@
+package database
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// NormalizeStatement strips literals, comments and formatting from a statement so that
+// executions differing only in values share the same text: literals and parameters become
+// "?", keywords and identifiers are upper-cased, and IN/VALUES lists collapse to one entry
+func NormalizeStatement(query string) string {
+	runes := []rune(query)
+	var tokens []string
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/'); i++ {
+			}
+			i++
+		case r == '\'':
+			// String literal with '' escapes
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			tokens = append(tokens, "?")
+		case r == '"' || r == '`' || r == '[':
+			// Quoted identifier, kept verbatim
+			end := r
+			if r == '[' {
+				end = ']'
+			}
+			start := i
+			for i++; i < len(runes) && runes[i] != end; i++ {
+			}
+			tokens = append(tokens, string(runes[start:min(i+1, len(runes))]))
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			for i+1 < len(runes) && (isIdentifierRune(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			tokens = append(tokens, "?")
+		case r == '?' || r == '$' || r == ':' || r == '@':
+			// Positional or named parameter
+			for i+1 < len(runes) && isIdentifierRune(runes[i+1]) {
+				i++
+			}
+			tokens = append(tokens, "?")
+		case isIdentifierRune(r):
+			start := i
+			for i+1 < len(runes) && isIdentifierRune(runes[i+1]) {
+				i++
+			}
+			tokens = append(tokens, strings.ToUpper(string(runes[start:i+1])))
+		default:
+			// Keep multi-character operators such as <=, <>, != and || together
+			if i+1 < len(runes) && strings.ContainsRune("<>=!|", runes[i+1]) && strings.ContainsRune("<>=!|", r) {
+				tokens = append(tokens, string(runes[i:i+2]))
+				i++
+				continue
+			}
+			tokens = append(tokens, string(r))
+		}
+	}
+
+	for len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token != "," && token != ")" && tokens[i-1] != "(" {
+			b.WriteByte(' ')
+		}
+		b.WriteString(token)
+	}
+	return collapseLists(b.String())
+}
+
+// collapseLists rewrites "(?, ?, ?)" and "(?), (?)" to a single entry so IN lists and
+// multi-row VALUES of any length share a fingerprint
+func collapseLists(s string) string {
+	for {
+		next := strings.ReplaceAll(s, "?, ?", "?")
+		next = strings.ReplaceAll(next, "(?), (?)", "(?)")
+		if next == s {
+			return s
+		}
+		s = next
+	}
+}
+
+// isIdentifierRune reports whether r can appear in an unquoted identifier
+func isIdentifierRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Fingerprint returns a short stable identifier for a statement's normalized form
+func Fingerprint(query string) string {
+	return normalizedFingerprint(NormalizeStatement(query))
+}
+
+// normalizedFingerprint fingerprints an already normalized statement
+func normalizedFingerprint(normalized string) string {
+	sum := sha1.Sum([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
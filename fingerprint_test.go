@@ -0,0 +1,26 @@
+package database
+
+import "testing"
+
+// TestNormalizeStatement verifies that literals, comments, formatting and list lengths
+// do not affect the normalized form
+func TestNormalizeStatement(t *testing.T) {
+	cases := map[string]string{
+		"select *  from users where id=42 and name = 'O''Brien' -- lookup\n;": "SELECT * FROM USERS WHERE ID = ? AND NAME = ?",
+		"SELECT x FROM t WHERE id IN (1, 2, 3) AND y = :name":                 "SELECT X FROM T WHERE ID IN (?) AND Y = ?",
+		"INSERT INTO t (a, b) VALUES (1, 2.5), (3, 'x')":                      "INSERT INTO T (A, B) VALUES (?)",
+		`SELECT "Mixed Case" FROM t /* hint */ WHERE a <= $1`:                 `SELECT "Mixed Case" FROM T WHERE A <= ?`,
+	}
+	for query, expected := range cases {
+		if got := NormalizeStatement(query); got != expected {
+			t.Errorf("NormalizeStatement(%q) = %q, expected %q", query, got, expected)
+		}
+	}
+
+	if Fingerprint("SELECT * FROM t WHERE id = 1") != Fingerprint("select * from t where id = ?") {
+		t.Error("Expected statements differing only in literals to share a fingerprint")
+	}
+	if Fingerprint("SELECT * FROM t WHERE id = 1") == Fingerprint("SELECT * FROM u WHERE id = 1") {
+		t.Error("Expected different tables to have different fingerprints")
+	}
+}
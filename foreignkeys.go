@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ForeignKeyViolation describes a single row reported by PRAGMA foreign_key_check
+type ForeignKeyViolation struct {
+	Table  string        // Table containing the offending row
+	RowID  sql.NullInt64 // Rowid of the offending row (NULL for WITHOUT ROWID tables)
+	Parent string        // Table the foreign key refers to
+	FKID   int           // Index of the foreign key constraint within the table
+}
+
+// isForeignKeysEnabled checks if foreign key enforcement should be enabled on new connections
+func isForeignKeysEnabled() bool {
+	value := strings.ToLower(configValue("DATABASE_FOREIGN_KEYS"))
+	return value == "1" || value == "true" || value == "on"
+}
+
+// journalMode returns the journal mode set by DATABASE_JOURNAL_MODE, or "" to keep
+// SQLite's default
+func journalMode() string {
+	mode := strings.ToLower(configValue("DATABASE_JOURNAL_MODE"))
+	switch mode {
+	case "delete", "truncate", "persist", "memory", "wal", "off":
+		return mode
+	}
+	return ""
+}
+
+// buildDSN returns the data source name for the given database file,
+// appending connection pragmas based on environment configuration
+func buildDSN(databaseFile string) string {
+	return buildDSNWithQuota(databaseFile, databaseQuotaFromEnv())
+}
+
+// buildDSNWithQuota returns the data source name for the given database file with
+// an explicit size quota in bytes (0 means unlimited)
+func buildDSNWithQuota(databaseFile string, maxBytes int64) string {
+	var pragmas []string
+	if isForeignKeysEnabled() {
+		pragmas = append(pragmas, "_pragma=foreign_keys(1)")
+	}
+	if mode := journalMode(); mode != "" {
+		pragmas = append(pragmas, "_pragma=journal_mode("+mode+")")
+	}
+	if maxBytes > 0 {
+		pragmas = append(pragmas, maxPageCountPragma(databaseFile, maxBytes))
+	}
+	return appendPragmas(databaseFile, pragmas)
+}
+
+// appendPragmas adds connection pragmas to a data source name
+func appendPragmas(dsn string, pragmas []string) string {
+	if len(pragmas) == 0 {
+		return dsn
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + strings.Join(pragmas, "&")
+}
+
+// CheckForeignKeys runs PRAGMA foreign_key_check across all tables and returns any violations
+func CheckForeignKeys(ctx context.Context) ([]ForeignKeyViolation, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var violations []ForeignKeyViolation
+	err = retryDatabaseOperationContext(ctx, func() error {
+		violations = nil
+
+		rows, err := db.QueryContext(ctx, "PRAGMA foreign_key_check")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var v ForeignKeyViolation
+			if err := rows.Scan(&v.Table, &v.RowID, &v.Parent, &v.FKID); err != nil {
+				return err
+			}
+			violations = append(violations, v)
+		}
+		return rows.Err()
+	}, DefaultRetryConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+
+	return violations, nil
+}
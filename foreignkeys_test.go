@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestCheckForeignKeysReportsViolations verifies that orphaned rows are reported
+// and that DATABASE_FOREIGN_KEYS enables enforcement on new connections
+func TestCheckForeignKeysReportsViolations(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "fk.db"))
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+
+	schema := []string{
+		"CREATE TABLE parents (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parents(id))",
+		"INSERT INTO children (id, parent_id) VALUES (1, 42)",
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", stmt, err)
+		}
+	}
+
+	violations, err := CheckForeignKeys(context.Background())
+	if err != nil {
+		t.Fatalf("CheckForeignKeys failed: %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d: %v", len(violations), violations)
+	}
+	v := violations[0]
+	if v.Table != "children" || v.Parent != "parents" || !v.RowID.Valid || v.RowID.Int64 != 1 {
+		t.Errorf("Unexpected violation: %+v", v)
+	}
+
+	// With enforcement enabled, inserting another orphan must fail
+	t.Setenv("DATABASE_FOREIGN_KEYS", "true")
+	enforced, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer enforced.Close()
+
+	if _, err := enforced.Exec("INSERT INTO children (id, parent_id) VALUES (2, 99)"); err == nil {
+		t.Error("Expected foreign key violation error with DATABASE_FOREIGN_KEYS enabled")
+	}
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"modernc.org/sqlite"
+)
+
+// Fuzzy matching defaults
+const (
+	DefaultFuzzyMaxDistance = 2
+	DefaultFuzzyLimit       = 20
+)
+
+func init() {
+	functions := map[string]struct {
+		args int32
+		fn   func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error)
+	}{
+		"levenshtein": {2, levenshteinFunction},
+		"similarity":  {2, similarityFunction},
+		"soundex":     {1, soundexFunction},
+	}
+	for name, f := range functions {
+		if err := sqlite.RegisterDeterministicScalarFunction(name, f.args, f.fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Levenshtein returns the number of single-character insertions, deletions and
+// substitutions that turn a into b, counted in runes. SQL: levenshtein(a, b).
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			next := min(row[j]+1, row[j-1]+1, diagonal+cost)
+			diagonal, row[j] = row[j], next
+		}
+	}
+	return row[len(rb)]
+}
+
+// Similarity returns 1 - Levenshtein(a, b) / the longer length, from 0 (nothing in common)
+// to 1 (equal). SQL: similarity(a, b).
+func Similarity(a, b string) float64 {
+	longest := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(a, b))/float64(longest)
+}
+
+// soundexCodes maps consonants to their Soundex digit; vowels, H, W and Y have none
+var soundexCodes = map[rune]byte{
+	'B': '1', 'F': '1', 'P': '1', 'V': '1',
+	'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+	'D': '3', 'T': '3',
+	'L': '4',
+	'M': '5', 'N': '5',
+	'R': '6',
+}
+
+// Soundex returns the American Soundex code of s ("Robert" and "Rupert" are R163), so
+// names that sound alike match. Letters outside A-Z are ignored; s without any gives "".
+// SQL: soundex(s), which is deterministic and can back an expression index.
+func Soundex(s string) string {
+	code := make([]byte, 0, 4)
+	var last byte
+	for _, r := range strings.ToUpper(s) {
+		if r < 'A' || r > 'Z' {
+			continue
+		}
+		digit := soundexCodes[r]
+		if len(code) == 0 {
+			code = append(code, byte(r))
+			last = digit
+			continue
+		}
+		switch {
+		case digit != 0 && digit != last:
+			code = append(code, digit)
+			if len(code) == 4 {
+				return string(code)
+			}
+			last = digit
+		case r != 'H' && r != 'W':
+			// Vowels separate repeated codes; H and W do not
+			last = digit
+		}
+	}
+	if len(code) == 0 {
+		return ""
+	}
+	return string(code) + strings.Repeat("0", 4-len(code))
+}
+
+// FuzzyQuery describes a typo-tolerant search over a short text column such as a name
+type FuzzyQuery struct {
+	Table       string
+	Column      string
+	Term        string
+	MaxDistance int  // Largest edit distance that matches (default: DefaultFuzzyMaxDistance)
+	Phonetic    bool // Also match values with the same Soundex code at any distance
+	Limit       int  // Rows returned (default: DefaultFuzzyLimit)
+}
+
+// FuzzyMatchResult is a row matched by FuzzyMatch
+type FuzzyMatchResult struct {
+	Row      map[string]interface{}
+	Distance int // Edit distance between the column and the term, ignoring case and surrounding space
+}
+
+// FuzzyMatch returns the rows of q.Table whose q.Column is within q.MaxDistance edits of
+// q.Term, or sounds like it when q.Phonetic is set, closest first. Case and surrounding
+// whitespace are ignored. Every row is compared, so keep it to short columns on tables
+// of modest size, or narrow candidates first with an expression index on soundex(column).
+func FuzzyMatch(ctx context.Context, db *sql.DB, q FuzzyQuery) ([]FuzzyMatchResult, error) {
+	if q.MaxDistance <= 0 {
+		q.MaxDistance = DefaultFuzzyMaxDistance
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultFuzzyLimit
+	}
+	column := "normalize_text(" + quoteIdentifier(q.Column) + ", 'trim,fold')"
+	condition := "_distance <= ?"
+	args := []interface{}{NormalizeText(q.Term, NormalizeTrim|NormalizeFold), q.MaxDistance}
+	if q.Phonetic {
+		condition += " OR soundex(" + quoteIdentifier(q.Column) + ") = soundex(?)"
+		args = append(args, q.Term)
+	}
+	query := fmt.Sprintf("SELECT * FROM (SELECT *, levenshtein(%s, ?) AS _distance FROM %s) WHERE %s ORDER BY _distance LIMIT %d",
+		column, quoteIdentifier(q.Table), condition, q.Limit)
+
+	var rows []map[string]interface{}
+	recorder, statementCtx := startStatement(ctx, "query", query)
+	err := retryInLane(ctx, func() error {
+		recorder.attempt()
+		result, err := db.QueryContext(statementCtx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+		rows, _, err = scanRowMaps(result, 0)
+		return err
+	}, retryConfigFor(ctx, query))
+	recorder.finish(int64(len(rows)), err)
+	if err != nil {
+		return nil, fmt.Errorf("fuzzy match on %s.%s failed: %w", q.Table, q.Column, resultError(err))
+	}
+
+	matches := make([]FuzzyMatchResult, len(rows))
+	for i, row := range rows {
+		distance, _ := row["_distance"].(int64)
+		delete(row, "_distance")
+		matches[i] = FuzzyMatchResult{Row: row, Distance: int(distance)}
+	}
+	return matches, nil
+}
+
+// levenshteinFunction implements levenshtein(a, b); NULL in gives NULL out
+func levenshteinFunction(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	a, okA := functionText(args[0])
+	b, okB := functionText(args[1])
+	if !okA || !okB {
+		return nil, nil
+	}
+	return int64(Levenshtein(a, b)), nil
+}
+
+// similarityFunction implements similarity(a, b); NULL in gives NULL out
+func similarityFunction(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	a, okA := functionText(args[0])
+	b, okB := functionText(args[1])
+	if !okA || !okB {
+		return nil, nil
+	}
+	return Similarity(a, b), nil
+}
+
+// soundexFunction implements soundex(s); NULL in gives NULL out
+func soundexFunction(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	s, ok := functionText(args[0])
+	if !ok {
+		return nil, nil
+	}
+	return Soundex(s), nil
+}
+
+// functionText returns a SQL function argument as text; false for NULL
+func functionText(value driver.Value) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
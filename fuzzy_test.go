@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestFuzzyFunctions verifies edit distance, similarity and Soundex codes
+func TestFuzzyFunctions(t *testing.T) {
+	if d := Levenshtein("kitten", "sitting"); d != 3 {
+		t.Errorf("Expected distance 3, got %d", d)
+	}
+	if d := Levenshtein("Zoë", "Zoe"); d != 1 {
+		t.Errorf("Expected runes to be compared, got %d", d)
+	}
+	if s := Similarity("abcd", "abcf"); s != 0.75 {
+		t.Errorf("Expected similarity 0.75, got %v", s)
+	}
+	for name, want := range map[string]string{"Robert": "R163", "Rupert": "R163", "Ashcraft": "A261", "Tymczak": "T522", "Pfister": "P236", "Lee": "L000", "": ""} {
+		if got := Soundex(name); got != want {
+			t.Errorf("Soundex(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestFuzzyMatch verifies typo-tolerant and phonetic matching through the SQL functions
+func TestFuzzyMatch(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "fuzzy.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE INDEX idx_people_soundex ON people (soundex(name));
+		INSERT INTO people (name) VALUES ('Catherine'), ('Katherine'), ('Kathryn'), ('Robert'), (NULL)`); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+
+	matches, err := FuzzyMatch(context.Background(), db, FuzzyQuery{Table: "people", Column: "name", Term: " catherin"})
+	if err != nil {
+		t.Fatalf("FuzzyMatch failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Row["name"] != "Catherine" || matches[0].Distance != 1 || matches[1].Row["name"] != "Katherine" {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+	if _, ok := matches[0].Row["_distance"]; ok {
+		t.Error("Expected the distance column to be removed from rows")
+	}
+
+	matches, err = FuzzyMatch(context.Background(), db, FuzzyQuery{Table: "people", Column: "name", Term: "Rupert", MaxDistance: 1, Phonetic: true})
+	if err != nil || len(matches) != 1 || matches[0].Row["name"] != "Robert" {
+		t.Errorf("Expected a phonetic match for Rupert, got %+v: %v", matches, err)
+	}
+}
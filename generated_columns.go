@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedSQLite is returned when the schema needs a feature the linked SQLite lacks
+var ErrUnsupportedSQLite = errors.New("feature not supported by this SQLite version")
+
+// Minimum SQLite versions for schema features
+const (
+	GeneratedColumnsMinVersion = "3.31.0"
+	ExpressionIndexMinVersion  = "3.9.0"
+	PartialIndexMinVersion     = "3.8.0"
+)
+
+// ExpressionIndex is an index on expressions rather than plain columns, typically over
+// JSON fields:
+//
+//	database.ExpressionIndex{Name: "idx_events_type", Expression: "json_extract(payload, '$.type')"}
+type ExpressionIndex struct {
+	Name       string // Index name
+	Expression string // Indexed expressions, comma separated; functions must be deterministic
+	Unique     bool
+	Where      string // Optional condition making the index partial
+}
+
+// createSQL renders the CREATE INDEX statement for the index on table
+func (i ExpressionIndex) createSQL(table string) string {
+	kind := "INDEX"
+	if i.Unique {
+		kind = "UNIQUE INDEX"
+	}
+	statement := fmt.Sprintf("CREATE %s IF NOT EXISTS %s ON %s (%s)", kind, quoteIdentifier(i.Name), quoteIdentifier(table), i.Expression)
+	if i.Where != "" {
+		statement += " WHERE " + i.Where
+	}
+	return statement + ";"
+}
+
+// generatedColumnDefinition renders a generated column; NOT NULL is left off since an
+// expression over a JSON payload may yield NULL
+func generatedColumnDefinition(f modelField, columnType string) string {
+	kind := "VIRTUAL"
+	if f.Stored {
+		kind = "STORED"
+	}
+	return fmt.Sprintf("%s GENERATED ALWAYS AS (%s) %s", columnDeclaration(f, columnType), f.Generated, kind)
+}
+
+// validateSchemaFeatures checks a repository's generated columns and expression indexes
+// against the SQLite version they will run on
+func validateSchemaFeatures(repo Repository, fields []modelField, version string) error {
+	for _, f := range fields {
+		if f.Generated == "" {
+			if f.Stored {
+				return fmt.Errorf("field %s: the stored option needs a generated tag", f.Name)
+			}
+			continue
+		}
+		if f.PrimaryKey {
+			return fmt.Errorf("field %s: a generated column cannot be the primary key", f.Name)
+		}
+		if !sqliteVersionAtLeast(version, GeneratedColumnsMinVersion) {
+			return fmt.Errorf("%w: generated column %s needs SQLite %s or later (have %s)", ErrUnsupportedSQLite, f.Column, GeneratedColumnsMinVersion, version)
+		}
+	}
+	for _, idx := range repo.Indexes {
+		if idx.Name == "" || strings.TrimSpace(idx.Expression) == "" {
+			return fmt.Errorf("expression index needs a name and an expression")
+		}
+		if !sqliteVersionAtLeast(version, ExpressionIndexMinVersion) {
+			return fmt.Errorf("%w: expression index %s needs SQLite %s or later (have %s)", ErrUnsupportedSQLite, idx.Name, ExpressionIndexMinVersion, version)
+		}
+		if idx.Where != "" && !sqliteVersionAtLeast(version, PartialIndexMinVersion) {
+			return fmt.Errorf("%w: partial index %s needs SQLite %s or later (have %s)", ErrUnsupportedSQLite, idx.Name, PartialIndexMinVersion, version)
+		}
+	}
+	return nil
+}
+
+// sqliteVersion returns the version of the linked SQLite library
+func sqliteVersion(ctx context.Context) (string, error) {
+	db, err := GetDB()
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var version string
+	err = retryDatabaseOperationContext(ctx, func() error {
+		return db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version)
+	}, DefaultRetryConfig())
+	if err != nil {
+		return "", fmt.Errorf("failed to read SQLite version: %w", err)
+	}
+	return version, nil
+}
+
+// sqliteVersionAtLeast reports whether dotted version have is at least want
+func sqliteVersionAtLeast(have, want string) bool {
+	haveParts, wantParts := strings.Split(have, "."), strings.Split(want, ".")
+	for i := range wantParts {
+		var h int
+		if i < len(haveParts) {
+			h, _ = strconv.Atoi(haveParts[i])
+		}
+		w, _ := strconv.Atoi(wantParts[i])
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type migrationEvent struct {
+	ID      int64   `db:"id,pk"`
+	Payload string  `db:"payload"`
+	UserID  *string `db:"user_id,index" generated:"json_extract(payload, '$.user.id')"`
+	Kind    *string `db:"kind" generated:"json_extract(payload, '$.type')"`
+}
+
+// TestGenerateModelMigrationGeneratedColumns verifies generated columns and expression
+// indexes are scaffolded, introspected after they apply, and checked against the SQLite version
+func TestGenerateModelMigrationGeneratedColumns(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "generated.db"))
+	ctx := context.Background()
+
+	globalRepositories.mu.Lock()
+	saved := globalRepositories.repositories
+	globalRepositories.repositories = nil
+	globalRepositories.mu.Unlock()
+	defer func() {
+		globalRepositories.mu.Lock()
+		globalRepositories.repositories = saved
+		globalRepositories.mu.Unlock()
+	}()
+	RegisterRepository(Repository{Table: "events", Model: migrationEvent{}, Indexes: []ExpressionIndex{
+		{Name: "idx_events_amount", Expression: "json_extract(payload, '$.amount')", Where: "json_extract(payload, '$.amount') IS NOT NULL"},
+	}})
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY, payload TEXT NOT NULL, kind TEXT GENERATED ALWAYS AS (json_extract(payload, '$.type')) VIRTUAL)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	up, down, err := GenerateModelMigration(ctx)
+	if err != nil {
+		t.Fatalf("GenerateModelMigration failed: %v", err)
+	}
+	for _, expected := range []string{
+		`ALTER TABLE "events" ADD COLUMN "user_id" TEXT GENERATED ALWAYS AS (json_extract(payload, '$.user.id')) VIRTUAL;`,
+		`CREATE INDEX IF NOT EXISTS "idx_events_user_id" ON "events" ("user_id");`,
+		`CREATE INDEX IF NOT EXISTS "idx_events_amount" ON "events" (json_extract(payload, '$.amount')) WHERE json_extract(payload, '$.amount') IS NOT NULL;`,
+	} {
+		if !strings.Contains(up, expected) {
+			t.Errorf("Expected up migration to contain %q, got:\n%s", expected, up)
+		}
+	}
+	if strings.Contains(up, `"kind"`) {
+		t.Errorf("Expected the existing generated column to be recognised, got:\n%s", up)
+	}
+
+	if _, err := db.Exec(up); err != nil {
+		t.Fatalf("Failed to apply up migration: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO events (payload) VALUES ('{"user": {"id": "u1"}, "type": "click"}')`); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	var userID string
+	if err := db.QueryRow("SELECT user_id FROM events").Scan(&userID); err != nil || userID != "u1" {
+		t.Errorf("Expected the generated column to extract u1, got %q: %v", userID, err)
+	}
+	if again, _, err := GenerateModelMigration(ctx); err != nil || again != "" {
+		t.Errorf("Expected no delta after applying the migration, got %q (%v)", again, err)
+	}
+	if _, err := db.Exec(down); err != nil {
+		t.Fatalf("Failed to apply down migration: %v", err)
+	}
+
+	fields, _ := modelFields(migrationEvent{})
+	if err := validateSchemaFeatures(Repository{}, fields, "3.30.1"); !errors.Is(err, ErrUnsupportedSQLite) {
+		t.Errorf("Expected generated columns to be rejected on SQLite 3.30.1, got %v", err)
+	}
+	if !sqliteVersionAtLeast("3.46.0", GeneratedColumnsMinVersion) || sqliteVersionAtLeast("3.8.11", ExpressionIndexMinVersion) {
+		t.Error("Unexpected version comparison")
+	}
+}
@@ -0,0 +1,62 @@
+// Package gormdb integrates GORM with the go-database execution pipeline.
+//
+// Wrap the SQLite dialector of your choice and point it at the pipeline driver so every
+// statement GORM issues gets write guards, retry and tracing:
+//
+//	db, err := gorm.Open(gormdb.New(sqlite.New(sqlite.Config{
+//	    DriverName: database.DriverName,
+//	    DSN:        os.Getenv("DATABASE_FILE"),
+//	})), &gorm.Config{})
+package gormdb
+
+import (
+	"errors"
+	"fmt"
+
+	database "github.com/realsensesolutions/go-database"
+	"gorm.io/gorm"
+)
+
+// Dialector wraps a GORM SQLite dialector and translates SQLite errors into GORM's typed
+// errors using the package's error classification
+type Dialector struct {
+	gorm.Dialector
+}
+
+// New wraps a GORM SQLite dialector
+func New(base gorm.Dialector) *Dialector {
+	return &Dialector{Dialector: base}
+}
+
+// Initialize initializes the wrapped dialector and enables GORM error translation,
+// so callers can match errors with errors.Is(err, gorm.ErrDuplicatedKey)
+func (d *Dialector) Initialize(db *gorm.DB) error {
+	if err := d.Dialector.Initialize(db); err != nil {
+		return err
+	}
+	db.Config.TranslateError = true
+	return nil
+}
+
+// Translate maps SQLite errors to GORM and go-database errors, falling back to the
+// wrapped dialector's translator
+func (d *Dialector) Translate(err error) error {
+	if err == nil || database.IsBusy(err) || errors.Is(err, database.ErrQuotaExceeded) {
+		return err
+	}
+
+	classified := database.ClassifyError(err)
+	switch {
+	case errors.Is(classified, database.ErrConstraintUnique), errors.Is(classified, database.ErrConstraintPrimaryKey):
+		return fmt.Errorf("%w: %w", gorm.ErrDuplicatedKey, classified)
+	case errors.Is(classified, database.ErrConstraintForeignKey):
+		return fmt.Errorf("%w: %w", gorm.ErrForeignKeyViolated, classified)
+	case errors.Is(classified, database.ErrConstraintCheck):
+		return fmt.Errorf("%w: %w", gorm.ErrCheckConstraintViolated, classified)
+	}
+
+	if translator, ok := d.Dialector.(gorm.ErrorTranslator); ok {
+		return translator.Translate(err)
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package gormdb
+
+import (
+	"errors"
+	"testing"
+
+	database "github.com/realsensesolutions/go-database"
+	"gorm.io/gorm"
+)
+
+// TestTranslateMapsSQLiteErrors verifies that constraint errors become GORM's typed errors
+// while retryable and quota errors keep their identity
+func TestTranslateMapsSQLiteErrors(t *testing.T) {
+	d := New(nil)
+
+	cases := map[string]error{
+		"UNIQUE constraint failed: users.email (2067)": gorm.ErrDuplicatedKey,
+		"FOREIGN KEY constraint failed (787)":          gorm.ErrForeignKeyViolated,
+		"CHECK constraint failed: age > 0 (275)":       gorm.ErrCheckConstraintViolated,
+	}
+	for message, expected := range cases {
+		if err := d.Translate(errors.New(message)); !errors.Is(err, expected) {
+			t.Errorf("Translate(%q) = %v, expected %v", message, err, expected)
+		}
+	}
+
+	busy := errors.New("database is locked (5) (SQLITE_BUSY)")
+	if err := d.Translate(busy); err != busy {
+		t.Errorf("Expected busy error to pass through, got %v", err)
+	}
+	if err := d.Translate(database.ErrQuotaExceeded); err != database.ErrQuotaExceeded {
+		t.Errorf("Expected quota error to pass through, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultGraphMaxDepth bounds traversals that do not set MaxDepth
+const DefaultGraphMaxDepth = 32
+
+// Edges describes an adjacency table: each row links the node in From to the node in To.
+// A tree kept in one table links a parent to its children:
+//
+//	// employees(id, manager_id): Descendants of a manager are their reports
+//	org := database.Edges{Table: "employees", From: "manager_id", To: "id"}
+type Edges struct {
+	Table      string
+	From       string
+	To         string
+	Undirected bool // Follow edges both ways
+	MaxDepth   int  // Longest path followed (default: DefaultGraphMaxDepth)
+}
+
+// GraphNode is a node reached by a traversal
+type GraphNode struct {
+	Node  interface{}
+	Depth int // Edges on the shortest path from the start node
+}
+
+// Ancestors returns the nodes with a path to node, nearest first: a tree node's parent,
+// grandparent and so on up to the root
+func Ancestors(ctx context.Context, db *sql.DB, e Edges, node interface{}) ([]GraphNode, error) {
+	return reachable(ctx, db, e, node, true)
+}
+
+// Descendants returns the nodes reachable from node, nearest first: a tree node's
+// children, grandchildren and so on
+func Descendants(ctx context.Context, db *sql.DB, e Edges, node interface{}) ([]GraphNode, error) {
+	return reachable(ctx, db, e, node, false)
+}
+
+// ShortestPath returns the nodes on a shortest path from one node to another, both
+// included, or nil when none is within MaxDepth edges
+func ShortestPath(ctx context.Context, db *sql.DB, e Edges, from, to interface{}) ([]interface{}, error) {
+	edges, err := e.edgesSQL(false)
+	if err != nil {
+		return nil, err
+	}
+	query := graphWalkSQL(edges) + " SELECT path FROM walk WHERE node = ? ORDER BY depth LIMIT 1"
+	rows, err := queryGraph(ctx, db, e, query, from, from, e.maxDepth(), to, to)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	path, _ := functionText(rows[0]["path"])
+	return decodeGraphPath(path)
+}
+
+// reachable walks e from node, against the edge direction when reverse is set
+func reachable(ctx context.Context, db *sql.DB, e Edges, node interface{}, reverse bool) ([]GraphNode, error) {
+	edges, err := e.edgesSQL(reverse)
+	if err != nil {
+		return nil, err
+	}
+	query := graphWalkSQL(edges) + " SELECT node, MIN(depth) AS depth FROM walk WHERE depth > 0 GROUP BY node ORDER BY depth, node"
+	// No target: the walk stops only at MaxDepth or a cycle
+	rows, err := queryGraph(ctx, db, e, query, node, node, e.maxDepth(), nil)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]GraphNode, len(rows))
+	for i, row := range rows {
+		depth, _ := row["depth"].(int64)
+		nodes[i] = GraphNode{Node: row["node"], Depth: int(depth)}
+	}
+	return nodes, nil
+}
+
+// graphWalkSQL is a recursive CTE enumerating paths from a start node over edges (with
+// src and dst columns). Each path is a JSON array of its nodes; a step to a node already
+// on the path is skipped, so cycles end the walk. Parameters: start node twice, max
+// depth, and a target node at which paths stop (NULL for none).
+func graphWalkSQL(edges string) string {
+	return "WITH RECURSIVE walk(node, depth, path) AS (" +
+		"SELECT ?, 0, json_array(?) " +
+		"UNION ALL SELECT e.dst, w.depth + 1, json_insert(w.path, '$[#]', e.dst) " +
+		"FROM walk w JOIN " + edges + " e ON e.src = w.node " +
+		"WHERE w.depth < ? AND w.node IS NOT ? " +
+		"AND NOT EXISTS (SELECT 1 FROM json_each(w.path) WHERE value = e.dst))"
+}
+
+// edgesSQL renders the adjacency table as a src/dst subquery, skipping NULL links such
+// as a root's parent
+func (e Edges) edgesSQL(reverse bool) (string, error) {
+	if e.Table == "" || e.From == "" || e.To == "" {
+		return "", fmt.Errorf("edges need a table and from and to columns")
+	}
+	from, to, table := quoteIdentifier(e.From), quoteIdentifier(e.To), quoteIdentifier(e.Table)
+	if reverse {
+		from, to = to, from
+	}
+	direction := func(src, dst string) string {
+		return fmt.Sprintf("SELECT %s AS src, %s AS dst FROM %s WHERE %s IS NOT NULL AND %s IS NOT NULL", src, dst, table, src, dst)
+	}
+	edges := direction(from, to)
+	if e.Undirected {
+		edges += " UNION ALL " + direction(to, from)
+	}
+	return "(" + edges + ")", nil
+}
+
+// maxDepth returns e.MaxDepth or the default
+func (e Edges) maxDepth() int {
+	if e.MaxDepth <= 0 {
+		return DefaultGraphMaxDepth
+	}
+	return e.MaxDepth
+}
+
+// queryGraph runs a traversal query with retries and returns its rows
+func queryGraph(ctx context.Context, db *sql.DB, e Edges, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	recorder, statementCtx := startStatement(ctx, "query", query)
+	err := retryInLane(ctx, func() error {
+		recorder.attempt()
+		result, err := db.QueryContext(statementCtx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+		rows, _, err = scanRowMaps(result, 0)
+		return err
+	}, retryConfigFor(ctx, query))
+	recorder.finish(int64(len(rows)), err)
+	if err != nil {
+		return nil, fmt.Errorf("graph traversal over %s failed: %w", e.Table, resultError(err))
+	}
+	return rows, nil
+}
+
+// decodeGraphPath decodes a JSON path, keeping integer node IDs as int64 like scanned columns
+func decodeGraphPath(path string) ([]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(path))
+	decoder.UseNumber()
+	var nodes []interface{}
+	if err := decoder.Decode(&nodes); err != nil {
+		return nil, fmt.Errorf("failed to decode graph path: %w", err)
+	}
+	for i, node := range nodes {
+		number, ok := node.(json.Number)
+		if !ok {
+			continue
+		}
+		if n, err := number.Int64(); err == nil {
+			nodes[i] = n
+		} else if f, err := number.Float64(); err == nil {
+			nodes[i] = f
+		}
+	}
+	return nodes, nil
+}
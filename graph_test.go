@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestTreeTraversal verifies ancestors and descendants of an org chart kept in one table
+func TestTreeTraversal(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "graph.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE employees (id INTEGER PRIMARY KEY, manager_id INTEGER);
+		INSERT INTO employees (id, manager_id) VALUES (1, NULL), (2, 1), (3, 1), (4, 2), (5, 4)`); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+	org := Edges{Table: "employees", From: "manager_id", To: "id"}
+	ctx := context.Background()
+
+	reports, err := Descendants(ctx, db, org, 2)
+	if err != nil {
+		t.Fatalf("Descendants failed: %v", err)
+	}
+	if want := []GraphNode{{Node: int64(4), Depth: 1}, {Node: int64(5), Depth: 2}}; !reflect.DeepEqual(reports, want) {
+		t.Errorf("Unexpected descendants: %+v", reports)
+	}
+
+	chain, err := Ancestors(ctx, db, org, 5)
+	if err != nil {
+		t.Fatalf("Ancestors failed: %v", err)
+	}
+	if want := []GraphNode{{Node: int64(4), Depth: 1}, {Node: int64(2), Depth: 2}, {Node: int64(1), Depth: 3}}; !reflect.DeepEqual(chain, want) {
+		t.Errorf("Unexpected ancestors: %+v", chain)
+	}
+
+	org.MaxDepth = 1
+	if chain, err := Ancestors(ctx, db, org, 5); err != nil || len(chain) != 1 {
+		t.Errorf("Expected the depth limit to stop at the manager, got %+v (%v)", chain, err)
+	}
+}
+
+// TestGraphCyclesAndShortestPath verifies that cycles end traversals and that the
+// shortest path is found in either direction
+func TestGraphCyclesAndShortestPath(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "graph.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE links (src TEXT, dst TEXT);
+		INSERT INTO links VALUES ('a', 'b'), ('b', 'c'), ('c', 'a'), ('c', 'd'), ('a', 'd'), ('e', 'd')`); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+	links := Edges{Table: "links", From: "src", To: "dst"}
+	ctx := context.Background()
+
+	reached, err := Descendants(ctx, db, links, "b")
+	if err != nil {
+		t.Fatalf("Descendants failed: %v", err)
+	}
+	if want := []GraphNode{{Node: "c", Depth: 1}, {Node: "a", Depth: 2}, {Node: "d", Depth: 2}}; !reflect.DeepEqual(reached, want) {
+		t.Errorf("Unexpected nodes in cyclic graph: %+v", reached)
+	}
+
+	path, err := ShortestPath(ctx, db, links, "b", "d")
+	if err != nil {
+		t.Fatalf("ShortestPath failed: %v", err)
+	}
+	if want := []interface{}{"b", "c", "d"}; !reflect.DeepEqual(path, want) {
+		t.Errorf("Unexpected path: %v", path)
+	}
+	if path, err := ShortestPath(ctx, db, links, "b", "e"); err != nil || path != nil {
+		t.Errorf("Expected no directed path to e, got %v (%v)", path, err)
+	}
+
+	links.Undirected = true
+	if path, err := ShortestPath(ctx, db, links, "b", "e"); err != nil || !reflect.DeepEqual(path, []interface{}{"b", "c", "d", "e"}) && !reflect.DeepEqual(path, []interface{}{"b", "a", "d", "e"}) {
+		t.Errorf("Expected an undirected path of three edges, got %v (%v)", path, err)
+	}
+
+	if _, err := Descendants(ctx, db, Edges{Table: "links"}, "a"); err == nil {
+		t.Error("Expected an error for edges without columns")
+	}
+}
@@ -0,0 +1,642 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GraphQLOptions configures the read-only GraphQL endpoint
+type GraphQLOptions struct {
+	// Allowlist maps table names to the columns that may be queried ("*" allows all columns).
+	// Tables not present in the allowlist are not exposed.
+	Allowlist map[string][]string
+	// MaxDepth limits selection nesting (default: 3)
+	MaxDepth int
+	// MaxComplexity limits the estimated number of values a query may return (default: 10000)
+	MaxComplexity int
+	// DefaultLimit is the row limit applied when a query does not specify one (default: 100)
+	DefaultLimit int
+	// MaxLimit caps the limit argument (default: 1000)
+	MaxLimit int
+	// Authorize is called for every request; returning an error rejects it with 401.
+	// It is required: without it every request is rejected.
+	Authorize func(r *http.Request) error
+}
+
+// gqlField is a parsed GraphQL field selection
+type gqlField struct {
+	Alias     string
+	Name      string
+	Args      map[string]interface{}
+	Selection []*gqlField
+}
+
+// responseKey returns the key the field is rendered under
+func (f *gqlField) responseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// NewGraphQLHandler returns an http.Handler serving a read-only GraphQL subset over the
+// introspected schema. Top-level fields are tables and accept limit, offset, order_by and
+// column equality arguments; nested fields are columns or many-to-one relations named after
+// the referenced table. Fragments, variables and mutations are not supported.
+func NewGraphQLHandler(opts GraphQLOptions) http.Handler {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 3
+	}
+	if opts.MaxComplexity <= 0 {
+		opts.MaxComplexity = 10000
+	}
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = 100
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 1000
+	}
+	if opts.Authorize == nil {
+		logWarnf("⚠️  GraphQL handler has no Authorize hook; every request will be rejected")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "GraphQL endpoint only accepts POST")
+			return
+		}
+		if opts.Authorize == nil {
+			writeJSONError(w, http.StatusUnauthorized, errNoAuthorizer.Error())
+			return
+		}
+		if err := opts.Authorize(r); err != nil {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		var body struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+
+		data, status, err := executeGraphQL(r.Context(), body.Query, opts)
+		if err != nil {
+			writeGraphQLError(w, status, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+	})
+}
+
+// writeGraphQLError writes a GraphQL-style error response
+func writeGraphQLError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+}
+
+// executeGraphQL parses, validates and executes a query, returning the data object
+func executeGraphQL(ctx context.Context, query string, opts GraphQLOptions) (map[string]interface{}, int, error) {
+	fields, err := parseGraphQL(query)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	schema, err := IntrospectSchema(ctx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	complexity := 0
+	for _, f := range fields {
+		cost, err := validateGraphQLField(schema, opts, f.Name, f, 1, true)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		complexity += cost
+	}
+	if complexity > opts.MaxComplexity {
+		return nil, http.StatusBadRequest, fmt.Errorf("query complexity %d exceeds limit %d", complexity, opts.MaxComplexity)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	defer db.Close()
+
+	data := make(map[string]interface{})
+	for _, f := range fields {
+		var rows []map[string]interface{}
+		err := retryDatabaseOperationContext(ctx, func() error {
+			var err error
+			rows, err = resolveGraphQLTable(ctx, db, schema, opts, f)
+			return err
+		}, DefaultRetryConfig())
+		if err != nil {
+			logErrorf("❌ GraphQL query failed: %v", err)
+			return nil, http.StatusInternalServerError, fmt.Errorf("query failed")
+		}
+		data[f.responseKey()] = rows
+	}
+	return data, http.StatusOK, nil
+}
+
+// allowedColumn reports whether the allowlist exposes a table column
+func allowedColumn(opts GraphQLOptions, table, column string) bool {
+	columns, ok := opts.Allowlist[table]
+	if !ok {
+		return false
+	}
+	for _, c := range columns {
+		if c == "*" || c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// relationFor finds the many-to-one relation of a table named after the referenced table
+func relationFor(table TableInfo, name string) (ForeignKeyInfo, bool) {
+	for _, fk := range table.ForeignKeys {
+		if fk.RefTable == name {
+			return fk, true
+		}
+	}
+	return ForeignKeyInfo{}, false
+}
+
+// validateGraphQLField checks a table selection against the schema, allowlist and depth
+// limit and returns its estimated complexity
+func validateGraphQLField(schema *Schema, opts GraphQLOptions, tableName string, f *gqlField, depth int, list bool) (int, error) {
+	if depth > opts.MaxDepth {
+		return 0, fmt.Errorf("query depth exceeds limit %d", opts.MaxDepth)
+	}
+
+	table, ok := schema.Table(tableName)
+	if !ok || opts.Allowlist[tableName] == nil {
+		return 0, fmt.Errorf("unknown table: %s", tableName)
+	}
+	if len(f.Selection) == 0 {
+		return 0, fmt.Errorf("table %s requires a selection set", tableName)
+	}
+
+	rows := 1
+	if list {
+		limit, err := graphQLLimit(f, opts)
+		if err != nil {
+			return 0, err
+		}
+		rows = limit
+		for name, value := range f.Args {
+			switch name {
+			case "limit":
+			case "offset":
+				if offset, ok := value.(int64); !ok || offset < 0 {
+					return 0, fmt.Errorf("offset must be a non-negative integer")
+				}
+			case "order_by":
+				column, ok := value.(string)
+				if !ok || !allowedColumn(opts, tableName, strings.TrimPrefix(column, "-")) {
+					return 0, fmt.Errorf("invalid order_by for %s", tableName)
+				}
+			default:
+				if _, ok := table.Column(name); !ok || !allowedColumn(opts, tableName, name) {
+					return 0, fmt.Errorf("unknown filter %s on %s", name, tableName)
+				}
+			}
+		}
+	} else if len(f.Args) > 0 {
+		return 0, fmt.Errorf("relation %s does not accept arguments", f.Name)
+	}
+
+	cost := 0
+	for _, child := range f.Selection {
+		if len(child.Selection) > 0 {
+			if _, ok := relationFor(table, child.Name); !ok {
+				return 0, fmt.Errorf("unknown relation %s on %s", child.Name, tableName)
+			}
+			childCost, err := validateGraphQLField(schema, opts, child.Name, child, depth+1, false)
+			if err != nil {
+				return 0, err
+			}
+			cost += childCost
+			continue
+		}
+		if _, ok := table.Column(child.Name); !ok || !allowedColumn(opts, tableName, child.Name) {
+			return 0, fmt.Errorf("unknown field %s on %s", child.Name, tableName)
+		}
+		cost++
+	}
+	return rows * cost, nil
+}
+
+// graphQLLimit returns the effective row limit of a table field
+func graphQLLimit(f *gqlField, opts GraphQLOptions) (int, error) {
+	value, ok := f.Args["limit"]
+	if !ok {
+		return opts.DefaultLimit, nil
+	}
+	limit, ok := value.(int64)
+	if !ok || limit <= 0 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	if int(limit) > opts.MaxLimit {
+		return 0, fmt.Errorf("limit %d exceeds maximum %d", limit, opts.MaxLimit)
+	}
+	return int(limit), nil
+}
+
+// resolveGraphQLTable executes a validated top-level table selection
+func resolveGraphQLTable(ctx context.Context, db *sql.DB, schema *Schema, opts GraphQLOptions, f *gqlField) ([]map[string]interface{}, error) {
+	table, _ := schema.Table(f.Name)
+	limit, _ := graphQLLimit(f, opts)
+
+	var where []string
+	var args []interface{}
+	for name, value := range f.Args {
+		if name == "limit" || name == "offset" || name == "order_by" {
+			continue
+		}
+		where = append(where, fmt.Sprintf("%s = ?", quoteIdentifier(name)))
+		args = append(args, value)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", graphQLColumnList(table, f), quoteIdentifier(table.Name))
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if orderBy, ok := f.Args["order_by"].(string); ok {
+		direction := "ASC"
+		if strings.HasPrefix(orderBy, "-") {
+			direction = "DESC"
+		}
+		query += fmt.Sprintf(" ORDER BY %s %s", quoteIdentifier(strings.TrimPrefix(orderBy, "-")), direction)
+	}
+	query += " LIMIT ?"
+	args = append(args, limit)
+	if offset, ok := f.Args["offset"].(int64); ok {
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := scanRowMaps(rows, 0)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return shapeGraphQLRows(ctx, db, schema, table, f, result)
+}
+
+// graphQLColumnList returns the columns to select for a table field, including
+// foreign key columns needed to resolve nested relations and any extra columns
+func graphQLColumnList(table TableInfo, f *gqlField, extra ...string) string {
+	seen := make(map[string]bool)
+	var columns []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			columns = append(columns, quoteIdentifier(name))
+		}
+	}
+	for _, name := range extra {
+		add(name)
+	}
+	for _, child := range f.Selection {
+		if len(child.Selection) > 0 {
+			fk, _ := relationFor(table, child.Name)
+			add(fk.Column)
+			continue
+		}
+		add(child.Name)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// graphQLBatchSize bounds the keys looked up by one relation query
+const graphQLBatchSize = 500
+
+// shapeGraphQLRows renders rows according to the selection set. Each nested relation is
+// resolved for all rows at once with one IN query per batch of keys, so the number of
+// queries depends on the shape of the query, not on the number of rows returned.
+func shapeGraphQLRows(ctx context.Context, db *sql.DB, schema *Schema, table TableInfo, f *gqlField, rows []map[string]interface{}) ([]map[string]interface{}, error) {
+	out := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		out[i] = make(map[string]interface{}, len(f.Selection))
+		for _, child := range f.Selection {
+			if len(child.Selection) == 0 {
+				out[i][child.responseKey()] = row[child.Name]
+			}
+		}
+	}
+
+	for _, child := range f.Selection {
+		if len(child.Selection) == 0 {
+			continue
+		}
+		fk, _ := relationFor(table, child.Name)
+		refTable, _ := schema.Table(fk.RefTable)
+		refColumn := fk.RefColumn
+		if refColumn == "" {
+			for _, c := range refTable.Columns {
+				if c.PrimaryKey {
+					refColumn = c.Name
+					break
+				}
+			}
+		}
+
+		var keys []interface{}
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			if value := row[fk.Column]; value != nil && !seen[graphQLKey(value)] {
+				seen[graphQLKey(value)] = true
+				keys = append(keys, value)
+			}
+		}
+
+		related := make(map[string]map[string]interface{}, len(keys))
+		for start := 0; start < len(keys); start += graphQLBatchSize {
+			batch := keys[start:min(start+graphQLBatchSize, len(keys))]
+			query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)",
+				graphQLColumnList(refTable, child, refColumn), quoteIdentifier(refTable.Name), quoteIdentifier(refColumn),
+				strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", "))
+			recorder, statementCtx := startStatement(ctx, "query", query)
+			recorder.attempt()
+			found, err := queryRowMaps(statementCtx, db, query, batch)
+			recorder.finish(int64(len(found)), err)
+			if err != nil {
+				return nil, err
+			}
+			shaped, err := shapeGraphQLRows(ctx, db, schema, refTable, child, found)
+			if err != nil {
+				return nil, err
+			}
+			for i, row := range found {
+				related[graphQLKey(row[refColumn])] = shaped[i]
+			}
+		}
+
+		for i, row := range rows {
+			var nested interface{}
+			if value := row[fk.Column]; value != nil {
+				if match, ok := related[graphQLKey(value)]; ok {
+					nested = match
+				}
+			}
+			out[i][child.responseKey()] = nested
+		}
+	}
+	return out, nil
+}
+
+// queryRowMaps runs a query and scans every row into a map
+func queryRowMaps(ctx context.Context, db *sql.DB, query string, args []interface{}) ([]map[string]interface{}, error) {
+	result, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	rows, _, err := scanRowMaps(result, 0)
+	return rows, err
+}
+
+// graphQLKey matches a foreign key value to the referenced row regardless of the Go type
+// each side was scanned as
+func graphQLKey(value interface{}) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// gqlParser is a minimal recursive-descent parser for GraphQL query documents
+type gqlParser struct {
+	src string
+	pos int
+}
+
+// parseGraphQL parses a query document into its top-level field selections
+func parseGraphQL(query string) ([]*gqlField, error) {
+	p := &gqlParser{src: query}
+	p.skipIgnored()
+
+	if p.peekName() == "query" {
+		p.readName()
+		p.skipIgnored()
+		if p.pos < len(p.src) && p.src[p.pos] != '{' {
+			p.readName() // operation name
+		}
+	} else if name := p.peekName(); name == "mutation" || name == "subscription" {
+		return nil, fmt.Errorf("only read queries are supported")
+	}
+
+	fields, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	p.skipIgnored()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected input at offset %d", p.pos)
+	}
+	return fields, nil
+}
+
+// skipIgnored skips whitespace, commas and comments
+func (p *gqlParser) skipIgnored() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == ',' || unicode.IsSpace(rune(c)):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// expect consumes the given punctuation character
+func (p *gqlParser) expect(c byte) error {
+	p.skipIgnored()
+	if p.pos >= len(p.src) || p.src[p.pos] != c {
+		return fmt.Errorf("expected %q at offset %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// peek reports whether the next significant character is c
+func (p *gqlParser) peek(c byte) bool {
+	p.skipIgnored()
+	return p.pos < len(p.src) && p.src[p.pos] == c
+}
+
+// peekName returns the next name without consuming it
+func (p *gqlParser) peekName() string {
+	start := p.pos
+	name := p.readName()
+	p.pos = start
+	return name
+}
+
+// readName consumes a GraphQL name
+func (p *gqlParser) readName() string {
+	p.skipIgnored()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (p.pos > start && c >= '0' && c <= '9') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// parseSelectionSet parses a braced list of fields
+func (p *gqlParser) parseSelectionSet() ([]*gqlField, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	var fields []*gqlField
+	for !p.peek('}') {
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	p.pos++
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return fields, nil
+}
+
+// parseField parses a field with optional alias, arguments and selection set
+func (p *gqlParser) parseField() (*gqlField, error) {
+	name := p.readName()
+	if name == "" {
+		return nil, fmt.Errorf("expected field name at offset %d", p.pos)
+	}
+	f := &gqlField{Name: name, Args: map[string]interface{}{}}
+
+	if p.peek(':') {
+		p.pos++
+		f.Alias = name
+		f.Name = p.readName()
+		if f.Name == "" {
+			return nil, fmt.Errorf("expected field name after alias %s", name)
+		}
+	}
+
+	if p.peek('(') {
+		p.pos++
+		for !p.peek(')') {
+			argName := p.readName()
+			if argName == "" {
+				return nil, fmt.Errorf("expected argument name at offset %d", p.pos)
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			f.Args[argName] = value
+		}
+		p.pos++
+	}
+
+	if p.peek('{') {
+		selection, err := p.parseSelectionSet()
+		if err != nil {
+			return nil, err
+		}
+		f.Selection = selection
+	}
+	return f, nil
+}
+
+// parseValue parses a scalar argument value
+func (p *gqlParser) parseValue() (interface{}, error) {
+	p.skipIgnored()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("expected value")
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c == '"':
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		value, err := strconv.Unquote(p.src[p.pos : end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid string: %w", err)
+		}
+		p.pos = end + 1
+		return value, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.ContainsRune("0123456789.eE+-", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		literal := p.src[start:p.pos]
+		if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", literal)
+		}
+		return f, nil
+	}
+
+	switch name := p.readName(); name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, fmt.Errorf("expected value at offset %d", p.pos)
+	default:
+		// Enum values are treated as strings (e.g., order_by: name)
+		return name, nil
+	}
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGraphQLHandlerResolvesRelations verifies nested relation resolution,
+// the field allowlist and the depth limit
+func TestGraphQLHandlerResolvesRelations(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "graphql.db"))
+
+	if _, err := ExecBatch(context.Background(), []Statement{
+		{Query: "CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT, api_key TEXT)"},
+		{Query: "CREATE TABLE boards (id TEXT PRIMARY KEY, title TEXT, owner_id TEXT REFERENCES users(id))"},
+		{Query: "INSERT INTO users VALUES ('u1', 'Ada', 'secret')"},
+		{Query: "INSERT INTO boards VALUES ('b1', 'Roadmap', 'u1'), ('b2', 'Ideas', 'u1')"},
+	}); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	handler := NewGraphQLHandler(GraphQLOptions{
+		Allowlist: map[string][]string{
+			"boards": {"*"},
+			"users":  {"id", "name"},
+		},
+		MaxDepth:  2,
+		Authorize: func(r *http.Request) error { return nil },
+	})
+
+	run := func(query string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	code, response := run(`query Boards { items: boards(limit: 10, order_by: "-title") { id title users { name } } }`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", code, response)
+	}
+	items := response["data"].(map[string]interface{})["items"].([]interface{})
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 boards, got %d", len(items))
+	}
+	first := items[0].(map[string]interface{})
+	if first["title"] != "Roadmap" || first["users"].(map[string]interface{})["name"] != "Ada" {
+		t.Errorf("Unexpected first board: %v", first)
+	}
+
+	if code, _ := run(`{ users { api_key } }`); code != http.StatusBadRequest {
+		t.Errorf("Expected non-allowlisted field to be rejected, got %d", code)
+	}
+	if code, _ := run(`{ boards { users { id } } }`); code != http.StatusOK {
+		t.Errorf("Expected depth 2 query to succeed, got %d", code)
+	}
+	if code, _ := run(`mutation { boards { id } }`); code != http.StatusBadRequest {
+		t.Errorf("Expected mutation to be rejected, got %d", code)
+	}
+}
+
+// TestGraphQLHandlerRequiresAuthorize verifies that a handler built without an Authorize
+// hook rejects every request
+func TestGraphQLHandlerRequiresAuthorize(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "graphql.db"))
+	handler := NewGraphQLHandler(GraphQLOptions{Allowlist: map[string][]string{"users": {"*"}}})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ users { id } }"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without Authorize, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestGraphQLBatchesRelationsAndValidatesOffset verifies that nested relations cost one
+// query per level rather than one per row, and that offset must be a non-negative integer
+func TestGraphQLBatchesRelationsAndValidatesOffset(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "graphql.db"))
+	if _, err := ExecBatch(context.Background(), []Statement{
+		{Query: "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"},
+		{Query: "CREATE TABLE boards (id INTEGER PRIMARY KEY, owner_id INTEGER REFERENCES users(id))"},
+		{Query: "CREATE TABLE cards (id INTEGER PRIMARY KEY, board_id INTEGER REFERENCES boards(id))"},
+		{Query: "INSERT INTO users (id, name) VALUES (1, 'Ada'), (2, 'Grace')"},
+		{Query: "INSERT INTO boards (id, owner_id) VALUES (1, 1), (2, 2), (3, NULL)"},
+		{Query: "INSERT INTO cards (id, board_id) WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 60) SELECT i, i % 3 + 1 FROM n"},
+	}); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	handler := NewGraphQLHandler(GraphQLOptions{
+		Allowlist: map[string][]string{"users": {"*"}, "boards": {"*"}, "cards": {"*"}},
+		Authorize: func(r *http.Request) error { return nil },
+	})
+	run := func(query string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	EnableStatementStats(0)
+	defer DisableStatementStats()
+	code, response := run(`{ cards(order_by: "id") { id boards { id users { name } } } }`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", code, response)
+	}
+	cards := response["data"].(map[string]interface{})["cards"].([]interface{})
+	if len(cards) != 60 {
+		t.Fatalf("Expected 60 cards, got %d", len(cards))
+	}
+	first := cards[0].(map[string]interface{})["boards"].(map[string]interface{})
+	if first["id"] != float64(2) || first["users"].(map[string]interface{})["name"] != "Grace" {
+		t.Errorf("Unexpected relation for first card: %v", first)
+	}
+	if board := cards[1].(map[string]interface{})["boards"].(map[string]interface{}); board["users"] != nil {
+		t.Errorf("Expected a board without owner to have a null relation, got %v", board)
+	}
+	var relationQueries int64
+	for _, s := range TopStatements(ByCount, 0) {
+		if strings.Contains(s.Statement, " IN (") {
+			relationQueries += s.Calls
+		}
+	}
+	if relationQueries != 2 {
+		t.Errorf("Expected one relation query per level, got %d", relationQueries)
+	}
+
+	for _, offset := range []string{`-1`, `"2"`} {
+		if code, _ := run(`{ cards(offset: ` + offset + `) { id } }`); code != http.StatusBadRequest {
+			t.Errorf("Expected offset %s to be rejected, got %d", offset, code)
+		}
+	}
+	if code, response := run(`{ cards(offset: 59) { id } }`); code != http.StatusOK || len(response["data"].(map[string]interface{})["cards"].([]interface{})) != 1 {
+		t.Errorf("Expected a valid offset to apply, got %d: %v", code, response)
+	}
+}
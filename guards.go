@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"sync"
+)
+
+// writeGuard rejects write operations while a subsystem requires it (e.g., the
+// EFS lease is held by another process)
+type writeGuard struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// guardRegistry manages all active write guards
+type guardRegistry struct {
+	mu     sync.RWMutex
+	guards []writeGuard
+}
+
+// Global write guard registry instance
+var globalGuards = &guardRegistry{}
+
+// setWriteGuard installs or replaces the named write guard
+func setWriteGuard(name string, check func(ctx context.Context) error) {
+	globalGuards.mu.Lock()
+	defer globalGuards.mu.Unlock()
+
+	for i, g := range globalGuards.guards {
+		if g.name == name {
+			globalGuards.guards[i].check = check
+			return
+		}
+	}
+	globalGuards.guards = append(globalGuards.guards, writeGuard{name: name, check: check})
+}
+
+// removeWriteGuard uninstalls the named write guard
+func removeWriteGuard(name string) {
+	globalGuards.mu.Lock()
+	defer globalGuards.mu.Unlock()
+
+	for i, g := range globalGuards.guards {
+		if g.name == name {
+			globalGuards.guards = append(globalGuards.guards[:i], globalGuards.guards[i+1:]...)
+			return
+		}
+	}
+}
+
+// checkWriteGuards returns the first error reported by an active write guard
+func checkWriteGuards(ctx context.Context) error {
+	globalGuards.mu.RLock()
+	guards := make([]writeGuard, len(globalGuards.guards))
+	copy(guards, globalGuards.guards)
+	globalGuards.mu.RUnlock()
+
+	for _, g := range guards {
+		if err := g.check(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
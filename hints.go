@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Hints tune one statement run through the pipeline driver without global changes.
+// Pragmas are set on the executing connection before the statement and restored after
+// it (for queries, when the rows are closed).
+type Hints struct {
+	CacheSize     int64    // Page cache in bytes while the statement runs
+	TempStore     string   // "memory" or "file" for sorts and temp b-trees
+	AnalysisLimit int      // Rows ANALYZE and PRAGMA optimize examine per index
+	NotIndexed    []string // Tables to scan without indexes (FROM t NOT INDEXED)
+}
+
+// hintsContextKey is the context key for statement hints
+type hintsContextKey struct{}
+
+// WithHints returns a context whose statements through the pipeline driver use hints:
+//
+//	ctx := database.WithHints(ctx, database.Hints{CacheSize: 64 << 20, NotIndexed: []string{"events"}})
+//	rows, err := db.QueryContext(ctx, "SELECT ... FROM events WHERE status = ?", status)
+func WithHints(ctx context.Context, hints Hints) context.Context {
+	return context.WithValue(ctx, hintsContextKey{}, hints)
+}
+
+// hintsFromContext returns the hints set with WithHints
+func hintsFromContext(ctx context.Context) (Hints, bool) {
+	hints, ok := ctx.Value(hintsContextKey{}).(Hints)
+	return hints, ok
+}
+
+// pragmas returns the PRAGMA name/value pairs the hints set
+func (h Hints) pragmas() ([][2]string, error) {
+	var pragmas [][2]string
+	if h.CacheSize > 0 {
+		pragmas = append(pragmas, [2]string{"cache_size", fmt.Sprint(-h.CacheSize / 1024)})
+	}
+	switch strings.ToLower(h.TempStore) {
+	case "":
+	case "memory", "file":
+		pragmas = append(pragmas, [2]string{"temp_store", strings.ToLower(h.TempStore)})
+	default:
+		return nil, fmt.Errorf("unknown temp_store hint %q (use memory or file)", h.TempStore)
+	}
+	if h.AnalysisLimit > 0 {
+		pragmas = append(pragmas, [2]string{"analysis_limit", fmt.Sprint(h.AnalysisLimit)})
+	}
+	return pragmas, nil
+}
+
+// applyHints rewrites query for ctx's hints and sets their pragmas on the connection,
+// returning a func that restores the previous pragma values
+func (c *pipelineConn) applyHints(ctx context.Context, query string) (string, func(), error) {
+	hints, ok := hintsFromContext(ctx)
+	if !ok {
+		return query, func() {}, nil
+	}
+	query = applyNotIndexed(query, hints.NotIndexed)
+	pragmas, err := hints.pragmas()
+	if err != nil || len(pragmas) == 0 {
+		return query, func() {}, err
+	}
+	execer, ok1 := c.Conn.(driver.ExecerContext)
+	queryer, ok2 := c.Conn.(driver.QueryerContext)
+	if !ok1 || !ok2 {
+		return query, func() {}, fmt.Errorf("PRAGMA hints need a driver connection with ExecerContext and QueryerContext, got %T", c.Conn)
+	}
+
+	var previous [][2]string
+	restore := func() {
+		for i := len(previous) - 1; i >= 0; i-- {
+			if _, err := execer.ExecContext(context.WithoutCancel(ctx), "PRAGMA "+previous[i][0]+" = "+previous[i][1], nil); err != nil {
+				logfContext(ctx, LogLevelWarn, "⚠️  Failed to restore PRAGMA %s after a hinted statement: %v", previous[i][0], err)
+			}
+		}
+	}
+	for _, pragma := range pragmas {
+		value, err := readPragma(ctx, queryer, pragma[0])
+		if err != nil {
+			restore()
+			return query, func() {}, fmt.Errorf("failed to read PRAGMA %s for hints: %w", pragma[0], err)
+		}
+		if _, err := execer.ExecContext(ctx, "PRAGMA "+pragma[0]+" = "+pragma[1], nil); err != nil {
+			restore()
+			return query, func() {}, fmt.Errorf("failed to apply PRAGMA %s hint: %w", pragma[0], err)
+		}
+		previous = append(previous, [2]string{pragma[0], value})
+	}
+	return query, restore, nil
+}
+
+// readPragma returns the current value of a pragma on a driver connection
+func readPragma(ctx context.Context, queryer driver.QueryerContext, name string) (string, error) {
+	rows, err := queryer.QueryContext(ctx, "PRAGMA "+name, nil)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	values := make([]driver.Value, len(rows.Columns()))
+	if err := rows.Next(values); err != nil {
+		if err == io.EOF {
+			return "", fmt.Errorf("PRAGMA %s returned no value", name)
+		}
+		return "", err
+	}
+	return fmt.Sprint(values[0]), nil
+}
+
+// hintedRows restores hint pragmas when the rows are closed
+type hintedRows struct {
+	driver.Rows
+	restore func()
+}
+
+func (r *hintedRows) Close() error {
+	err := r.Rows.Close()
+	r.restore()
+	return err
+}
+
+// applyNotIndexed adds NOT INDEXED after every FROM or JOIN reference to one of tables
+// (after its alias, if any), leaving references that already have an index clause alone
+func applyNotIndexed(query string, tables []string) string {
+	if len(tables) == 0 {
+		return query
+	}
+	hinted := map[string]bool{}
+	for _, table := range tables {
+		hinted[strings.ToLower(table)] = true
+	}
+
+	var out strings.Builder
+	rest := query
+	for {
+		i := nextFromOrJoin(rest)
+		if i < 0 {
+			out.WriteString(rest)
+			return out.String()
+		}
+		out.WriteString(rest[:i])
+		rest = rest[i:]
+
+		keyword, after := nextToken(rest)
+		table, afterTable := nextToken(after)
+		if !hinted[strings.ToLower(strings.Trim(strings.TrimSpace(table), "\"`[]"))] {
+			out.WriteString(keyword)
+			rest = after
+			continue
+		}
+		end := len(rest) - len(afterTable)
+		if alias, afterAlias := nextToken(afterTable); strings.EqualFold(strings.TrimSpace(alias), "AS") {
+			_, afterName := nextToken(afterAlias)
+			end = len(rest) - len(afterName)
+		} else if isAliasToken(alias) {
+			end = len(rest) - len(afterAlias)
+		}
+		out.WriteString(rest[:end])
+		if !hasIndexClause(rest[end:]) {
+			out.WriteString(" NOT INDEXED")
+		}
+		rest = rest[end:]
+	}
+}
+
+// hasIndexClause reports whether s starts with NOT INDEXED or INDEXED BY
+func hasIndexClause(s string) bool {
+	first, rest := nextToken(s)
+	second, _ := nextToken(rest)
+	first, second = strings.TrimSpace(first), strings.TrimSpace(second)
+	return strings.EqualFold(first, "INDEXED") || (strings.EqualFold(first, "NOT") && strings.EqualFold(second, "INDEXED"))
+}
+
+// nextFromOrJoin returns the index of the next FROM or JOIN keyword in query, or -1.
+// String literals, quoted identifiers and comments are skipped.
+func nextFromOrJoin(query string) int {
+	for i := 0; i < len(query); {
+		switch {
+		case query[i] == '\'' || query[i] == '"' || query[i] == '`':
+			i = skipPast(query, i+1, query[i:i+1])
+		case query[i] == '[':
+			i = skipPast(query, i+1, "]")
+		case strings.HasPrefix(query[i:], "--"):
+			i = skipPast(query, i+2, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipPast(query, i+2, "*/")
+		case isIdentifierRune(rune(query[i])):
+			end := i
+			for end < len(query) && isIdentifierRune(rune(query[end])) {
+				end++
+			}
+			if word := query[i:end]; strings.EqualFold(word, "FROM") || strings.EqualFold(word, "JOIN") {
+				return i
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// skipPast returns the index just after the first closing delimiter at or after i, or
+// len(s) when it is missing. An escaped quote (two in a row) inside a literal needs no
+// special case: the scan resumes at the second quote and reopens the literal.
+func skipPast(s string, i int, closing string) int {
+	j := strings.Index(s[i:], closing)
+	if j < 0 {
+		return len(s)
+	}
+	return i + j + len(closing)
+}
+
+// nextToken splits the next whitespace-separated token (including leading whitespace)
+// off s, stopping at commas and parentheses
+func nextToken(s string) (string, string) {
+	i := 0
+	for i < len(s) && unicode.IsSpace(rune(s[i])) {
+		i++
+	}
+	j := i
+	for j < len(s) && !unicode.IsSpace(rune(s[j])) && !strings.ContainsRune(",();", rune(s[j])) {
+		j++
+	}
+	return s[:j], s[j:]
+}
+
+// isAliasToken reports whether token is a bare table alias rather than a keyword
+func isAliasToken(token string) bool {
+	token = strings.TrimSpace(token)
+	if token == "" || !isIdentifierRune(rune(token[0])) {
+		return false
+	}
+	switch strings.ToUpper(token) {
+	case "WHERE", "JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "NATURAL", "ON", "USING", "GROUP",
+		"ORDER", "LIMIT", "HAVING", "WINDOW", "UNION", "EXCEPT", "INTERSECT", "INDEXED", "NOT", "SET", "RETURNING":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestApplyNotIndexed verifies NOT INDEXED lands after hinted tables and their aliases, but
+// not inside literals or comments, nor after a reference that already has an index clause
+func TestApplyNotIndexed(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"SELECT * FROM events WHERE status = ?", "SELECT * FROM events NOT INDEXED WHERE status = ?"},
+		{"SELECT * FROM events e JOIN users u ON u.id = e.user_id", "SELECT * FROM events e NOT INDEXED JOIN users u ON u.id = e.user_id"},
+		{"select * from users join \"events\" as ev on ev.user_id = users.id", "select * from users join \"events\" as ev NOT INDEXED on ev.user_id = users.id"},
+		{"SELECT from_date FROM events_archive", "SELECT from_date FROM events_archive"},
+		{"SELECT COUNT(*) FROM events", "SELECT COUNT(*) FROM events NOT INDEXED"},
+		{"SELECT 'from events' AS note FROM events", "SELECT 'from events' AS note FROM events NOT INDEXED"},
+		{"SELECT 'it''s from events', \"join events\" FROM users", "SELECT 'it''s from events', \"join events\" FROM users"},
+		{"SELECT id -- from events\nFROM users /* join events */", "SELECT id -- from events\nFROM users /* join events */"},
+		{"SELECT * FROM events NOT INDEXED WHERE status = ?", "SELECT * FROM events NOT INDEXED WHERE status = ?"},
+		{"SELECT * FROM events e INDEXED BY idx_events_status", "SELECT * FROM events e INDEXED BY idx_events_status"},
+	}
+	for _, tt := range tests {
+		if got := applyNotIndexed(tt.query, []string{"events"}); got != tt.want {
+			t.Errorf("applyNotIndexed(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestHintsAreScopedToTheStatement verifies hinted pragmas apply while a query runs and are
+// restored afterwards, and that NOT INDEXED changes the plan
+func TestHintsAreScopedToTheStatement(t *testing.T) {
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "hints.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY, status INTEGER); CREATE INDEX idx_events_status ON events (status)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	ctx := WithHints(context.Background(), Hints{CacheSize: 64 << 20, NotIndexed: []string{"events"}})
+	var hinted, restored int64
+	if err := db.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&hinted); err != nil || hinted != -65536 {
+		t.Errorf("Expected the hinted cache size while the statement runs, got %d: %v", hinted, err)
+	}
+	if err := db.QueryRow("PRAGMA cache_size").Scan(&restored); err != nil || restored == hinted {
+		t.Errorf("Expected the cache size to be restored, got %d: %v", restored, err)
+	}
+
+	plan := func(ctx context.Context) string {
+		var id, parent, unused int
+		var detail string
+		if err := db.QueryRowContext(ctx, "EXPLAIN QUERY PLAN SELECT * FROM events WHERE status = 1").Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatalf("EXPLAIN failed: %v", err)
+		}
+		return detail
+	}
+	if detail := plan(context.Background()); !strings.Contains(detail, "idx_events_status") {
+		t.Errorf("Expected the index to be used without hints, got %q", detail)
+	}
+	if detail := plan(ctx); !strings.HasPrefix(detail, "SCAN") {
+		t.Errorf("Expected a table scan with NOT INDEXED, got %q", detail)
+	}
+
+	if _, err := db.ExecContext(WithHints(context.Background(), Hints{TempStore: "disk"}), "DELETE FROM events"); err == nil {
+		t.Error("Expected an unknown temp_store hint to be rejected")
+	}
+}
+
+// bareConn is a driver connection without ExecerContext or QueryerContext
+type bareConn struct{}
+
+func (bareConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (bareConn) Close() error                        { return nil }
+func (bareConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+// TestPragmaHintsNeedExecer verifies PRAGMA hints fail loudly on a connection that cannot
+// run them instead of being skipped
+func TestPragmaHintsNeedExecer(t *testing.T) {
+	conn := &pipelineConn{Conn: bareConn{}}
+	ctx := WithHints(context.Background(), Hints{CacheSize: 64 << 20})
+	if _, _, err := conn.applyHints(ctx, "SELECT 1"); err == nil {
+		t.Error("Expected PRAGMA hints on an unsupported connection to fail")
+	}
+	ctx = WithHints(context.Background(), Hints{NotIndexed: []string{"events"}})
+	if query, _, err := conn.applyHints(ctx, "SELECT * FROM events"); err != nil || query != "SELECT * FROM events NOT INDEXED" {
+		t.Errorf("Expected NOT INDEXED alone to need no PRAGMAs, got %q (%v)", query, err)
+	}
+}
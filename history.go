@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HistoryTable returns the name of the table holding a tracked table's row versions
+func HistoryTable(table string) string {
+	return table + "_history"
+}
+
+// TrackHistory keeps every version of table's rows in <table>_history, maintained by
+// triggers: each insert or update adds a version valid from the change (Unix milliseconds
+// in valid_from) and closes the previous one (valid_to); a delete closes the last version.
+// Existing rows get an open version on first tracking. Running it again after adding
+// columns extends the history table and regenerates the triggers.
+func TrackHistory(ctx context.Context, table, keyColumn string) error {
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	history := HistoryTable(table)
+
+	return WithTransactionRetry(func(tx *sql.Tx) error {
+		columns, err := tableColumnTypes(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			return fmt.Errorf("table does not exist: %s", table)
+		}
+		names := make([]string, len(columns))
+		for i, column := range columns {
+			names[i] = column[0]
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    valid_from INTEGER NOT NULL,
+    valid_to   INTEGER
+)`, quoteIdentifier(history))); err != nil {
+			return fmt.Errorf("failed to create history table %s: %w", history, err)
+		}
+		existing, err := tableColumns(ctx, tx, history)
+		if err != nil {
+			return err
+		}
+		have := make(map[string]bool, len(existing))
+		for _, name := range existing {
+			have[name] = true
+		}
+		for _, column := range columns {
+			if have[column[0]] {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+				quoteIdentifier(history), quoteIdentifier(column[0]), column[1])); err != nil {
+				return fmt.Errorf("failed to add column %s to %s: %w", column[0], history, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s, valid_from)",
+			quoteIdentifier("idx_"+history+"_key"), quoteIdentifier(history), quoteIdentifier(keyColumn))); err != nil {
+			return fmt.Errorf("failed to index %s: %w", history, err)
+		}
+
+		// Rows that existed before tracking started get an open version from now
+		quoted := quoteColumns(names)
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"INSERT INTO %s (%s, valid_from) SELECT %s, %s FROM %s t WHERE NOT EXISTS (SELECT 1 FROM %s h WHERE h.%s = t.%s AND h.valid_to IS NULL)",
+			quoteIdentifier(history), quoted, quoted, nowMillisSQL, quoteIdentifier(table),
+			quoteIdentifier(history), quoteIdentifier(keyColumn), quoteIdentifier(keyColumn))); err != nil {
+			return fmt.Errorf("failed to seed %s: %w", history, err)
+		}
+
+		for _, t := range historyTriggers(table, keyColumn, names) {
+			if _, err := tx.ExecContext(ctx, t.DropSQL()); err != nil {
+				return fmt.Errorf("failed to drop trigger %s: %w", t.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, t.SQL); err != nil {
+				return fmt.Errorf("failed to create trigger %s: %w", t.Name, err)
+			}
+		}
+
+		logf("🕰️  Tracking history for %s in %s", table, history)
+		return nil
+	})
+}
+
+// AsOf returns the version of the row with the given key that was current at time at,
+// or sql.ErrNoRows when it did not exist then. The row carries the history columns
+// valid_from and valid_to (nil while current) alongside the table's columns.
+func AsOf(ctx context.Context, table, keyColumn string, key interface{}, at time.Time) (map[string]interface{}, error) {
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	rows, err := queryHistory(ctx, fmt.Sprintf(
+		"SELECT * FROM %s WHERE %s = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?) ORDER BY history_id DESC LIMIT 1",
+		quoteIdentifier(HistoryTable(table)), quoteIdentifier(keyColumn)), key, at.UnixMilli(), at.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return rows[0], nil
+}
+
+// TableAsOf returns every row of table as it was at time at, e.g. to reconstruct the
+// state a past invoice was computed from
+func TableAsOf(ctx context.Context, table string, at time.Time) ([]map[string]interface{}, error) {
+	return queryHistory(ctx, fmt.Sprintf(
+		"SELECT * FROM %s WHERE valid_from <= ? AND (valid_to IS NULL OR valid_to > ?) ORDER BY history_id",
+		quoteIdentifier(HistoryTable(table))), at.UnixMilli(), at.UnixMilli())
+}
+
+// History returns every version of the row with the given key, oldest first
+func History(ctx context.Context, table, keyColumn string, key interface{}) ([]map[string]interface{}, error) {
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	return queryHistory(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s = ? ORDER BY history_id",
+		quoteIdentifier(HistoryTable(table)), quoteIdentifier(keyColumn)), key)
+}
+
+// queryHistory runs a read against a history table
+func queryHistory(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result []map[string]interface{}
+	err = retryDatabaseOperationContext(ctx, func() error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		result, _, err = scanRowMaps(rows, 0)
+		return err
+	}, retryConfigFor(ctx, query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return result, nil
+}
+
+// historyTriggers builds the triggers that version a table's rows into its history table
+func historyTriggers(table, keyColumn string, columns []string) []Trigger {
+	history, key := quoteIdentifier(HistoryTable(table)), quoteIdentifier(keyColumn)
+	values := func(ref string) string {
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			parts[i] = ref + "." + quoteIdentifier(column)
+		}
+		return strings.Join(parts, ", ")
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s, valid_from) VALUES (%s, %s);", history, quoteColumns(columns), values("NEW"), nowMillisSQL)
+	closeVersion := fmt.Sprintf("UPDATE %s SET valid_to = %s WHERE %s = OLD.%s AND valid_to IS NULL;", history, nowMillisSQL, key, key)
+
+	build := func(op, event string, statements ...string) Trigger {
+		name := fmt.Sprintf("%s_history_%s", table, op)
+		return Trigger{
+			Name:  name,
+			Table: table,
+			SQL: fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER %s ON %s\nFOR EACH ROW\nBEGIN\n    %s\nEND;",
+				quoteIdentifier(name), event, quoteIdentifier(table), strings.Join(statements, "\n    ")),
+		}
+	}
+
+	return []Trigger{
+		build("insert", "INSERT", insert),
+		build("update", "UPDATE", closeVersion, insert),
+		build("delete", "DELETE", closeVersion),
+	}
+}
+
+// tableColumnTypes returns the name and declared type of a table's columns
+func tableColumnTypes(ctx context.Context, tx *sql.Tx, table string) ([][2]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name, type FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns [][2]string
+	for rows.Next() {
+		var column [2]string
+		if err := rows.Scan(&column[0], &column[1]); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// quoteColumns quotes and joins column names
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	return strings.Join(quoted, ", ")
+}
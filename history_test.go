@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestTrackHistoryAsOf verifies that tracked rows are versioned and can be read as of a
+// point in time
+func TestTrackHistoryAsOf(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "history.db"))
+	ctx := context.Background()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	exec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("Failed to run %q: %v", query, err)
+		}
+	}
+	pause := func() time.Time {
+		time.Sleep(5 * time.Millisecond)
+		at := time.Now()
+		time.Sleep(5 * time.Millisecond)
+		return at
+	}
+
+	exec("CREATE TABLE plans (id INTEGER PRIMARY KEY, name TEXT, price INTEGER)")
+	exec("INSERT INTO plans (id, name, price) VALUES (1, 'pro', 10)")
+	beforeTracking := pause()
+	if err := TrackHistory(ctx, "plans", ""); err != nil {
+		t.Fatalf("Failed to track history: %v", err)
+	}
+	atTen := pause()
+	exec("UPDATE plans SET price = 12 WHERE id = 1")
+	exec("INSERT INTO plans (id, name, price) VALUES (2, 'team', 30)")
+	atTwelve := pause()
+	exec("DELETE FROM plans WHERE id = 1")
+	afterDelete := pause()
+
+	if _, err := AsOf(ctx, "plans", "id", 1, beforeTracking); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected no version before tracking started, got %v", err)
+	}
+	if row, err := AsOf(ctx, "plans", "id", 1, atTen); err != nil || row["price"] != int64(10) {
+		t.Errorf("Expected price 10, got %v: %v", row, err)
+	}
+	if row, err := AsOf(ctx, "plans", "id", 1, atTwelve); err != nil || row["price"] != int64(12) {
+		t.Errorf("Expected price 12, got %v: %v", row, err)
+	}
+	if _, err := AsOf(ctx, "plans", "id", 1, afterDelete); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected the deleted row to be gone, got %v", err)
+	}
+	if rows, err := TableAsOf(ctx, "plans", atTwelve); err != nil || len(rows) != 2 {
+		t.Errorf("Expected 2 plans at that time, got %v: %v", rows, err)
+	}
+	if versions, err := History(ctx, "plans", "id", 1); err != nil || len(versions) != 2 || versions[1]["valid_to"] == nil {
+		t.Errorf("Expected 2 closed versions, got %v: %v", versions, err)
+	}
+
+	// New columns extend the history table
+	exec("ALTER TABLE plans ADD COLUMN currency TEXT")
+	if err := TrackHistory(ctx, "plans", "id"); err != nil {
+		t.Fatalf("Failed to re-track history: %v", err)
+	}
+	exec("UPDATE plans SET currency = 'EUR' WHERE id = 2")
+	if row, err := AsOf(ctx, "plans", "id", 2, time.Now().Add(time.Second)); err != nil || row["currency"] != "EUR" {
+		t.Errorf("Expected the new column to be versioned, got %v: %v", row, err)
+	}
+}
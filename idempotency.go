@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// idempotencySchema creates the table recording completed idempotent operations
+const idempotencySchema = `CREATE TABLE IF NOT EXISTS _idempotency_keys (
+    key            TEXT PRIMARY KEY,
+    rows_affected  INTEGER NOT NULL,
+    last_insert_id INTEGER NOT NULL,
+    completed_at   INTEGER NOT NULL
+)`
+
+// idempotentResult is the sql.Result recorded for a completed idempotent operation
+type idempotentResult struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+func (r idempotentResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r idempotentResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// ExecIdempotent executes a write at most once per key. The statement and the key record
+// are committed in the same transaction; later calls with the same key skip the statement
+// and return the recorded result, so retried inserts and other side effects are not repeated.
+func ExecIdempotent(ctx context.Context, key, query string, args ...interface{}) (sql.Result, error) {
+	if key == "" {
+		return nil, fmt.Errorf("idempotency key is required")
+	}
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+
+	var result idempotentResult
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, idempotencySchema); err != nil {
+			return fmt.Errorf("failed to create idempotency table: %w", err)
+		}
+
+		err := tx.QueryRowContext(ctx,
+			"SELECT rows_affected, last_insert_id FROM _idempotency_keys WHERE key = ?", key,
+		).Scan(&result.rowsAffected, &result.lastInsertID)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		// Drivers that can't report these leave them at zero
+		result.rowsAffected, _ = res.RowsAffected()
+		result.lastInsertID, _ = res.LastInsertId()
+
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO _idempotency_keys (key, rows_affected, last_insert_id, completed_at) VALUES (?, ?, ?, ?)",
+			key, result.rowsAffected, result.lastInsertID, time.Now().UnixMilli()); err != nil {
+			return fmt.Errorf("failed to record idempotency key: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PruneIdempotencyKeys deletes idempotency keys recorded more than olderThan ago
+// and returns the number removed
+func PruneIdempotencyKeys(ctx context.Context, olderThan time.Duration) (int64, error) {
+	var removed int64
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, idempotencySchema); err != nil {
+			return fmt.Errorf("failed to create idempotency table: %w", err)
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM _idempotency_keys WHERE completed_at < ?",
+			time.Now().Add(-olderThan).UnixMilli())
+		if err != nil {
+			return err
+		}
+		removed, _ = res.RowsAffected()
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestExecIdempotentSkipsDuplicates verifies that a repeated key does not re-run the
+// statement and returns the originally recorded result
+func TestExecIdempotentSkipsDuplicates(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "idempotent.db"))
+	ctx := context.Background()
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE payments (id INTEGER PRIMARY KEY, amount INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		result, err := ExecIdempotent(ctx, "payment-123", "INSERT INTO payments (amount) VALUES (?)", 100)
+		if err != nil {
+			t.Fatalf("ExecIdempotent failed: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		ids = append(ids, id)
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("Expected the duplicate to return the recorded insert ID %d, got %d", ids[0], ids[1])
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM payments").Scan(&count); err != nil {
+		t.Fatalf("Failed to count payments: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 payment, got %d", count)
+	}
+}
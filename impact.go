@@ -0,0 +1,507 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ImpactThroughput is the assumed bytes per second SQLite reads or writes while holding
+// the write lock during a rebuild, index build or backfill
+const ImpactThroughput = 50 << 20
+
+// Risk thresholds for estimated lock time
+const (
+	ImpactMediumLockTime = time.Second
+	ImpactHighLockTime   = 30 * time.Second
+)
+
+// ImpactRisk grades how disruptive applying a migration is expected to be
+type ImpactRisk string
+
+const (
+	RiskLow    ImpactRisk = "low"
+	RiskMedium ImpactRisk = "medium"
+	RiskHigh   ImpactRisk = "high"
+)
+
+// ImpactKind classifies what a migration statement does to existing data
+type ImpactKind string
+
+const (
+	ImpactSchemaOnly   ImpactKind = "schema_only"   // Catalog change only (CREATE TABLE, ADD COLUMN, RENAME)
+	ImpactTableRewrite ImpactKind = "table_rewrite" // Copies a table (DROP COLUMN, INSERT ... SELECT, CREATE TABLE AS, VACUUM)
+	ImpactIndexBuild   ImpactKind = "index_build"   // Builds an index over existing rows
+	ImpactBackfill     ImpactKind = "backfill"      // UPDATE or DELETE over existing rows
+	ImpactDrop         ImpactKind = "drop"          // Drops a table and its rows
+)
+
+// TableStats describes the size of a table
+type TableStats struct {
+	Name       string
+	Rows       int64
+	Bytes      int64 // Pages used by the table (0 when the dbstat table is unavailable)
+	IndexBytes int64 // Pages used by the table's indexes
+	Columns    int
+}
+
+// PendingMigration is an up migration not yet applied
+type PendingMigration struct {
+	Source   string
+	Database string
+	Version  uint64
+	Name     string
+	SQL      string
+}
+
+// MigrationPlan lists the pending migrations of all registered sources in apply order
+type MigrationPlan struct {
+	Migrations []PendingMigration
+}
+
+// StatementImpact is the estimated impact of one statement of a pending migration
+type StatementImpact struct {
+	Source             string
+	Version            uint64
+	Migration          string
+	Statement          string // Normalized statement
+	Kind               ImpactKind
+	Table              string
+	Rows               int64         // Existing rows the statement touches
+	EstimatedLockTime  time.Duration // Time the write lock is held
+	EstimatedDiskBytes int64         // Bytes written to the database (and WAL)
+	EstimatedTempBytes int64         // Temporary space for sorting and copies
+	Risk               ImpactRisk
+	Warnings           []string
+}
+
+// ImpactReport is the estimated impact of a migration plan
+type ImpactReport struct {
+	Statements         []StatementImpact
+	EstimatedLockTime  time.Duration
+	EstimatedDiskBytes int64
+	EstimatedTempBytes int64
+	Risk               ImpactRisk
+	Warnings           []string
+}
+
+// GetTableStats returns row counts and sizes of the user tables in DATABASE_FILE
+func GetTableStats(ctx context.Context) ([]TableStats, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return tableStats(ctx, db)
+}
+
+// tableStats returns row counts and sizes of the user tables of db
+func tableStats(ctx context.Context, db *sql.DB) ([]TableStats, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.name, (SELECT COUNT(*) FROM pragma_table_info(m.name))
+		FROM sqlite_master m
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+		ORDER BY m.name`)
+	if err != nil {
+		return nil, err
+	}
+	var stats []TableStats
+	for rows.Next() {
+		var s TableStats
+		if err := rows.Scan(&s.Name, &s.Columns); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdentifier(stats[i].Name))).Scan(&stats[i].Rows); err != nil {
+			return nil, err
+		}
+	}
+
+	// Page usage per table and index, attributed to the owning table
+	sizeRows, err := db.QueryContext(ctx, `
+		SELECT m.tbl_name, m.type, SUM(d.pgsize)
+		FROM dbstat d JOIN sqlite_master m ON m.name = d.name
+		GROUP BY m.tbl_name, m.type`)
+	if err != nil {
+		logWarnf("⚠️  Table sizes unavailable (dbstat): %v", err)
+		return stats, nil
+	}
+	defer sizeRows.Close()
+	byName := make(map[string]*TableStats, len(stats))
+	for i := range stats {
+		byName[stats[i].Name] = &stats[i]
+	}
+	for sizeRows.Next() {
+		var table, kind string
+		var bytes int64
+		if err := sizeRows.Scan(&table, &kind, &bytes); err != nil {
+			return nil, err
+		}
+		if s, ok := byName[table]; ok {
+			if kind == "index" {
+				s.IndexBytes += bytes
+			} else {
+				s.Bytes += bytes
+			}
+		}
+	}
+	return stats, sizeRows.Err()
+}
+
+// PlanMigrations lists the migrations UpAll would apply, without applying them
+func PlanMigrations(ctx context.Context) (*MigrationPlan, error) {
+	plan := &MigrationPlan{}
+	for _, source := range GetRegisteredSources() {
+		source, err := resolveRemoteSource(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		pending, err := pendingMigrations(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan migrations for %s: %w", source.Name, err)
+		}
+		plan.Migrations = append(plan.Migrations, pending...)
+	}
+	return plan, nil
+}
+
+// pendingMigrations returns a source's up migrations newer than its applied version,
+// or for groups, the members' migrations not recorded in the group table
+func pendingMigrations(ctx context.Context, source MigrationSource) ([]PendingMigration, error) {
+	databaseFile := sourceDatabaseFile(source)
+	db, err := sql.Open("sqlite", buildDSN(databaseFile))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var pending []PendingMigration
+	add := func(m groupMigration) {
+		pending = append(pending, PendingMigration{Source: m.source, Database: databaseFile, Version: m.version, Name: m.name, SQL: m.sql})
+	}
+
+	if source.group != nil {
+		migrations, err := loadGroupMigrations(source.group)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		applied, err := appliedGroupVersions(ctx, conn, source.group)
+		if err != nil && !isMissingTable(err) {
+			return nil, err
+		}
+		for _, m := range migrations {
+			if !applied[m.source][m.version] {
+				add(m)
+			}
+		}
+		return pending, nil
+	}
+
+	if source.EmbedFS == nil && source.Directory == "" {
+		return nil, nil
+	}
+	migrations, err := loadUpMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var current uint64
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT version FROM %s LIMIT 1", quoteIdentifier(source.Prefix+"schema_migrations"))).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) && !isMissingTable(err) {
+		return nil, err
+	}
+	for _, m := range migrations {
+		if m.version > current {
+			add(m)
+		}
+	}
+	return pending, nil
+}
+
+// EstimateImpact inspects the plan's statements for table rewrites, index builds on
+// large tables and backfills without a WHERE clause, and estimates lock time and disk
+// usage from the current table sizes
+func EstimateImpact(ctx context.Context, plan *MigrationPlan) (*ImpactReport, error) {
+	report := &ImpactReport{Risk: RiskLow}
+	statsByDatabase := make(map[string]map[string]TableStats)
+
+	for _, m := range plan.Migrations {
+		stats, ok := statsByDatabase[m.Database]
+		if !ok {
+			db, err := sql.Open("sqlite", buildDSN(m.Database))
+			if err != nil {
+				return nil, err
+			}
+			list, err := tableStats(ctx, db)
+			db.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read table stats for %s: %w", m.Database, err)
+			}
+			stats = make(map[string]TableStats, len(list))
+			for _, s := range list {
+				stats[strings.ToUpper(s.Name)] = s
+			}
+			statsByDatabase[m.Database] = stats
+		}
+
+		for _, statement := range splitStatements(m.SQL) {
+			impact := estimateStatement(statement, stats)
+			impact.Source, impact.Version, impact.Migration = m.Source, m.Version, m.Name
+			report.Statements = append(report.Statements, impact)
+			report.EstimatedLockTime += impact.EstimatedLockTime
+			report.EstimatedDiskBytes += impact.EstimatedDiskBytes
+			report.EstimatedTempBytes = max(report.EstimatedTempBytes, impact.EstimatedTempBytes)
+			report.Risk = maxRisk(report.Risk, impact.Risk)
+			for _, warning := range impact.Warnings {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s/%d_%s: %s", m.Source, m.Version, m.Name, warning))
+			}
+		}
+	}
+
+	report.Risk = maxRisk(report.Risk, riskForLockTime(report.EstimatedLockTime))
+	return report, nil
+}
+
+// estimateStatement classifies a single statement and estimates its cost
+func estimateStatement(statement string, stats map[string]TableStats) StatementImpact {
+	normalized := NormalizeStatement(statement)
+	tokens := strings.Fields(normalized)
+	impact := StatementImpact{Statement: normalized, Kind: ImpactSchemaOnly, Risk: RiskLow}
+	lookup := func(table string) TableStats {
+		impact.Table = table
+		s := stats[strings.ToUpper(table)]
+		impact.Rows = s.Rows
+		return s
+	}
+	has := func(keyword string) bool { return tokenIndex(tokens, keyword, 0) >= 0 }
+
+	switch {
+	case len(tokens) == 0:
+	case tokens[0] == "VACUUM":
+		impact.Kind = ImpactTableRewrite
+		var total int64
+		for _, s := range stats {
+			total += s.Bytes + s.IndexBytes
+			impact.Rows += s.Rows
+		}
+		impact.EstimatedDiskBytes = total
+		impact.EstimatedTempBytes = total
+		impact.EstimatedLockTime = throughputTime(2 * total)
+		impact.Warnings = append(impact.Warnings, "VACUUM rewrites the whole database")
+
+	case creates(tokens, "INDEX"):
+		s := lookup(tableAfter(tokens, "ON"))
+		impact.Kind = ImpactIndexBuild
+		columns := int64(1)
+		if on := strings.Index(normalized, " ON "); on >= 0 {
+			columns += int64(strings.Count(normalized[on:], ","))
+		}
+		indexBytes := s.Rows * (16*columns + 16)
+		if s.Bytes > 0 && s.Columns > 0 {
+			indexBytes = min(indexBytes, s.Bytes*columns/int64(s.Columns)+s.Rows*8)
+		}
+		impact.EstimatedDiskBytes = indexBytes
+		impact.EstimatedTempBytes = indexBytes
+		impact.EstimatedLockTime = throughputTime(s.Bytes + 2*indexBytes)
+		if has("UNIQUE") && s.Rows > 0 {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("UNIQUE index on %s fails if existing rows are duplicated", impact.Table))
+		}
+
+	case creates(tokens, "TABLE") && has("SELECT"):
+		s := lookup(tableAfter(tokens, "FROM"))
+		impact.Kind = ImpactTableRewrite
+		impact.EstimatedDiskBytes = s.Bytes
+		impact.EstimatedLockTime = throughputTime(2 * s.Bytes)
+
+	case tokens[0] == "INSERT" && has("SELECT"):
+		s := lookup(tableAfter(tokens, "FROM"))
+		impact.Kind = ImpactTableRewrite
+		impact.EstimatedDiskBytes = s.Bytes
+		impact.EstimatedLockTime = throughputTime(2 * s.Bytes)
+
+	case tokens[0] == "ALTER" && has("DROP"):
+		s := lookup(tableAfter(tokens, "TABLE"))
+		impact.Kind = ImpactTableRewrite
+		impact.EstimatedDiskBytes = s.Bytes
+		impact.EstimatedTempBytes = s.Bytes
+		impact.EstimatedLockTime = throughputTime(2 * s.Bytes)
+
+	case tokens[0] == "UPDATE" || tokens[0] == "DELETE":
+		table := tableAfter(tokens, "UPDATE")
+		if tokens[0] == "DELETE" {
+			table = tableAfter(tokens, "FROM")
+		}
+		s := lookup(table)
+		impact.Kind = ImpactBackfill
+		if has("WHERE") {
+			// Matching rows are unknown; the scan is the lower bound
+			impact.EstimatedLockTime = throughputTime(s.Bytes)
+		} else {
+			impact.EstimatedLockTime = throughputTime(2 * s.Bytes)
+			if tokens[0] == "UPDATE" {
+				impact.EstimatedDiskBytes = s.Bytes
+			}
+			if s.Rows > 0 {
+				impact.Warnings = append(impact.Warnings, fmt.Sprintf("%s without WHERE touches all %d rows of %s", tokens[0], s.Rows, impact.Table))
+			}
+		}
+
+	case tokens[0] == "DROP" && has("TABLE"):
+		s := lookup(tableAfter(tokens, "TABLE"))
+		impact.Kind = ImpactDrop
+		impact.EstimatedLockTime = throughputTime((s.Bytes + s.IndexBytes) / 10)
+		if s.Rows > 0 {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("drops %s with %d rows", impact.Table, s.Rows))
+		}
+	}
+
+	impact.Risk = riskForLockTime(impact.EstimatedLockTime)
+	if len(impact.Warnings) > 0 {
+		impact.Risk = maxRisk(impact.Risk, RiskMedium)
+	}
+	return impact
+}
+
+// creates reports whether tokens form a CREATE statement for the given object type,
+// e.g. CREATE UNIQUE INDEX or CREATE TEMP TABLE
+func creates(tokens []string, object string) bool {
+	if len(tokens) == 0 || tokens[0] != "CREATE" {
+		return false
+	}
+	for _, token := range tokens[1:min(len(tokens), 4)] {
+		if token == object {
+			return true
+		}
+	}
+	return false
+}
+
+// tableAfter returns the unquoted name following keyword, skipping IF [NOT] EXISTS
+func tableAfter(tokens []string, keyword string) string {
+	i := tokenIndex(tokens, keyword, 0)
+	if i < 0 {
+		return ""
+	}
+	for i++; i < len(tokens) && (tokens[i] == "IF" || tokens[i] == "NOT" || tokens[i] == "EXISTS" || tokens[i] == "ONLY"); i++ {
+	}
+	if i >= len(tokens) {
+		return ""
+	}
+	name := strings.TrimRight(tokens[i], "(;")
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return strings.Trim(name, "\"`[]")
+}
+
+// tokenIndex returns the index of the first token equal to keyword at or after start
+func tokenIndex(tokens []string, keyword string, start int) int {
+	for i := start; i < len(tokens); i++ {
+		if tokens[i] == keyword {
+			return i
+		}
+	}
+	return -1
+}
+
+// throughputTime converts bytes processed into time at ImpactThroughput
+func throughputTime(bytes int64) time.Duration {
+	return time.Duration(float64(bytes) / ImpactThroughput * float64(time.Second))
+}
+
+// riskForLockTime grades an estimated lock time
+func riskForLockTime(lockTime time.Duration) ImpactRisk {
+	switch {
+	case lockTime >= ImpactHighLockTime:
+		return RiskHigh
+	case lockTime >= ImpactMediumLockTime:
+		return RiskMedium
+	}
+	return RiskLow
+}
+
+// maxRisk returns the higher of two risks
+func maxRisk(a, b ImpactRisk) ImpactRisk {
+	rank := map[ImpactRisk]int{RiskLow: 0, RiskMedium: 1, RiskHigh: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// splitStatements splits a migration file into statements on semicolons outside
+// literals, comments and trigger bodies
+func splitStatements(script string) []string {
+	var statements []string
+	runes := []rune(script)
+	start, depth := 0, 0
+	word := func(i int) string {
+		j := i
+		for j < len(runes) && isIdentifierRune(runes[j]) {
+			j++
+		}
+		return strings.ToUpper(string(runes[i:j]))
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/'); i++ {
+			}
+			i++
+		case r == '\'' || r == '"' || r == '`' || r == '[':
+			end := r
+			if r == '[' {
+				end = ']'
+			}
+			for i++; i < len(runes) && runes[i] != end; i++ {
+			}
+		case isIdentifierRune(r) && (i == 0 || !isIdentifierRune(runes[i-1])):
+			// Trigger bodies contain semicolons between BEGIN and END
+			switch word(i) {
+			case "BEGIN":
+				if strings.Contains(strings.ToUpper(string(runes[start:i])), "TRIGGER") {
+					depth++
+				}
+			case "END":
+				if depth > 0 {
+					depth--
+				}
+			}
+			for i+1 < len(runes) && isIdentifierRune(runes[i+1]) {
+				i++
+			}
+		case r == ';' && depth == 0:
+			if statement := strings.TrimSpace(string(runes[start:i])); statement != "" {
+				statements = append(statements, statement)
+			}
+			start = i + 1
+		}
+	}
+	// A trailing statement without semicolon, ignoring trailing comments
+	if statement := strings.TrimSpace(string(runes[start:])); NormalizeStatement(statement) != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
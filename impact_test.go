@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEstimateImpactFlagsRiskyStatements verifies that pending migrations are planned
+// and that index builds, rewrites and unbounded backfills are sized from table stats
+func TestEstimateImpactFlagsRiskyStatements(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "impact.db"))
+	dir := writeMigrations(t, filepath.Join(tempDir, "events"), map[string]string{
+		"001_create_events.up.sql": "CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT, payload TEXT);",
+	})
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+	RegisterMigrations(MigrationSource{Name: "events", Directory: dir})
+	if err := UpAll(); err != nil {
+		t.Fatalf("Failed to apply first migration: %v", err)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if _, err := db.Exec(`WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 5000)
+		INSERT INTO events (kind, payload) SELECT 'click', printf('%0200d', x) FROM n`); err != nil {
+		t.Fatalf("Failed to seed events: %v", err)
+	}
+	db.Close()
+
+	writeMigrations(t, dir, map[string]string{
+		"002_index_kind.up.sql": `-- speed up lookups
+CREATE INDEX idx_events_kind ON events (kind, id);
+CREATE TRIGGER events_touch AFTER UPDATE ON events BEGIN
+	UPDATE events SET kind = NEW.kind WHERE id = NEW.id;
+END;
+ALTER TABLE events ADD COLUMN source TEXT;`,
+		"003_backfill.up.sql": "UPDATE events SET source = 'web'; DELETE FROM events WHERE id < 10;",
+	})
+
+	plan, err := PlanMigrations(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to plan migrations: %v", err)
+	}
+	if len(plan.Migrations) != 2 || plan.Migrations[0].Version != 2 {
+		t.Fatalf("Expected migrations 2 and 3 pending, got %+v", plan.Migrations)
+	}
+
+	report, err := EstimateImpact(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("Failed to estimate impact: %v", err)
+	}
+	kinds := make([]ImpactKind, 0, len(report.Statements))
+	for _, statement := range report.Statements {
+		kinds = append(kinds, statement.Kind)
+	}
+	want := []ImpactKind{ImpactIndexBuild, ImpactSchemaOnly, ImpactSchemaOnly, ImpactBackfill, ImpactBackfill}
+	if len(kinds) != len(want) {
+		t.Fatalf("Expected statements %v, got %v", want, kinds)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("Statement %d: expected %s, got %s", i, want[i], kinds[i])
+		}
+	}
+
+	index := report.Statements[0]
+	if index.Table != "EVENTS" || index.Rows != 5000 || index.EstimatedDiskBytes == 0 || index.EstimatedTempBytes == 0 {
+		t.Errorf("Expected index build on 5000 events rows with disk estimates, got %+v", index)
+	}
+	backfill := report.Statements[3]
+	if len(backfill.Warnings) != 1 || backfill.Risk == RiskLow {
+		t.Errorf("Expected UPDATE without WHERE to be flagged, got %+v", backfill)
+	}
+	if len(report.Statements[4].Warnings) != 0 {
+		t.Errorf("Expected DELETE with WHERE not to be flagged, got %v", report.Statements[4].Warnings)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "events/3_backfill") {
+		t.Errorf("Expected one report warning naming the migration, got %v", report.Warnings)
+	}
+	if report.Risk == RiskLow || report.EstimatedLockTime <= 0 {
+		t.Errorf("Expected elevated risk with lock time, got %s / %v", report.Risk, report.EstimatedLockTime)
+	}
+}
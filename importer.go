@@ -0,0 +1,365 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultImportBatchSize is how many records an Importer commits per transaction
+const DefaultImportBatchSize = 500
+
+// ImportFormat is the encoding of an import source
+type ImportFormat string
+
+// Import formats
+const (
+	ImportCSV       ImportFormat = "csv"   // Header row, then one record per row
+	ImportJSONLines ImportFormat = "jsonl" // One JSON object per line
+)
+
+// ImportType is the coercion applied to a source value
+type ImportType string
+
+// Import types
+const (
+	ImportText    ImportType = "text"    // As is (default)
+	ImportInteger ImportType = "integer" // Base-10 integer
+	ImportReal    ImportType = "real"    // Floating point
+	ImportBool    ImportType = "bool"    // true/false, 1/0, yes/no; stored as 1/0
+	ImportTime    ImportType = "time"    // RFC 3339 or YYYY-MM-DD; stored as RFC 3339 UTC
+)
+
+// importProgressSchema records how many records of each resumable import are done
+const importProgressSchema = `CREATE TABLE IF NOT EXISTS _import_progress (
+    job        TEXT PRIMARY KEY,
+    records    INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+)`
+
+// ImportColumn maps a source field to a table column
+type ImportColumn struct {
+	Source   string      // CSV header or JSON key
+	Column   string      // Table column (default: Source)
+	Type     ImportType  // Coercion (default: ImportText)
+	Required bool        // Reject records where the field is missing or empty
+	Default  interface{} // Value for a missing or empty field that is not required
+}
+
+// Importer loads CSV or JSON Lines records into a table, coercing mapped fields,
+// validating each record and writing rejected ones to ErrorOutput instead of failing
+type Importer struct {
+	Table     string
+	Format    ImportFormat
+	Columns   []ImportColumn
+	Validate  func(row map[string]interface{}) error // Per-record check on the coerced row; an error rejects it
+	Conflict  BulkLoadOptions                        // Conflict handling, as for BulkLoad
+	BatchSize int                                    // Records per transaction (default: DefaultImportBatchSize)
+
+	// ErrorOutput receives one JSON line per rejected record: its number, the reason and
+	// its source fields, so the file can be fixed and re-imported
+	ErrorOutput io.Writer
+
+	// Job names a resumable import: progress is committed with each batch in
+	// _import_progress, and running the same job again skips the records already done
+	Job string
+}
+
+// ImportResult counts what an import did with its records
+type ImportResult struct {
+	Records  int // Records read, including resumed ones
+	Resumed  int // Records skipped because an earlier run of the job processed them
+	Imported int
+	Rejected int
+}
+
+// ImportRejection is a record the importer did not load
+type ImportRejection struct {
+	Record int               `json:"record"` // 1-based record number, excluding the CSV header
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Run imports every record from r
+func (im *Importer) Run(ctx context.Context, r io.Reader) (*ImportResult, error) {
+	if len(im.Columns) == 0 {
+		return nil, fmt.Errorf("importer for %s has no columns", im.Table)
+	}
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+	batchSize := im.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+	columns := make([]string, len(im.Columns))
+	for i, column := range im.Columns {
+		columns[i] = column.Column
+		if columns[i] == "" {
+			columns[i] = column.Source
+		}
+	}
+	insert, err := bulkLoadStatement(im.Table, columns, im.Conflict)
+	if err != nil {
+		return nil, err
+	}
+	next, err := importRecords(im.Format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	done, err := im.progress(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type pending struct {
+		number int
+		fields map[string]string
+		values []interface{}
+	}
+	var batch []pending
+	flush := func() error {
+		if len(batch) == 0 && im.Job == "" {
+			return nil
+		}
+		var rejected []ImportRejection
+		imported := 0
+		err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+			rejected, imported = nil, 0
+			stmt, err := tx.PrepareContext(ctx, insert)
+			if err != nil {
+				return fmt.Errorf("failed to prepare import into %s: %w", im.Table, err)
+			}
+			defer stmt.Close()
+			for _, record := range batch {
+				err := execSavepoint(ctx, tx, func() error {
+					_, err := stmt.ExecContext(ctx, record.values...)
+					return err
+				})
+				if IsBusy(err) {
+					return err
+				}
+				if err != nil {
+					rejected = append(rejected, ImportRejection{Record: record.number, Error: err.Error(), Fields: record.fields})
+					continue
+				}
+				imported++
+			}
+			if im.Job != "" {
+				if _, err := tx.ExecContext(ctx, importProgressSchema); err != nil {
+					return err
+				}
+				if _, err := tx.ExecContext(ctx, "INSERT INTO _import_progress (job, records, updated_at) VALUES (?, ?, "+nowMillisSQL+") ON CONFLICT (job) DO UPDATE SET records = excluded.records, updated_at = excluded.updated_at",
+					im.Job, result.Records); err != nil {
+					return fmt.Errorf("failed to record import progress: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		result.Imported += imported
+		batch = batch[:0]
+		return im.reject(result, rejected...)
+	}
+
+	for {
+		fields, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read import record %d: %w", result.Records+1, err)
+		}
+		result.Records++
+		if result.Records <= done {
+			result.Resumed++
+			continue
+		}
+
+		values, err := im.coerce(fields)
+		if err != nil {
+			if err := im.reject(result, ImportRejection{Record: result.Records, Error: err.Error(), Fields: fields}); err != nil {
+				return result, err
+			}
+			continue
+		}
+		batch = append(batch, pending{number: result.Records, fields: fields, values: values})
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+	logf("📥 Imported %d records into %s (%d rejected, %d resumed)", result.Imported, im.Table, result.Rejected, result.Resumed)
+	return result, nil
+}
+
+// progress returns how many records of the job earlier runs processed
+func (im *Importer) progress(ctx context.Context) (int, error) {
+	if im.Job == "" {
+		return 0, nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var records int
+	err = db.QueryRowContext(ctx, "SELECT records FROM _import_progress WHERE job = ?", im.Job).Scan(&records)
+	if errors.Is(err, sql.ErrNoRows) || isMissingTable(err) {
+		return 0, nil
+	}
+	return records, err
+}
+
+// reject counts rejected records and writes them to ErrorOutput
+func (im *Importer) reject(result *ImportResult, rejections ...ImportRejection) error {
+	result.Rejected += len(rejections)
+	if im.ErrorOutput == nil {
+		return nil
+	}
+	for _, rejection := range rejections {
+		line, err := json.Marshal(rejection)
+		if err != nil {
+			return err
+		}
+		if _, err := im.ErrorOutput.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write import error file: %w", err)
+		}
+	}
+	return nil
+}
+
+// coerce maps a record's fields to column values and validates the row
+func (im *Importer) coerce(fields map[string]string) ([]interface{}, error) {
+	values := make([]interface{}, len(im.Columns))
+	row := make(map[string]interface{}, len(im.Columns))
+	for i, column := range im.Columns {
+		raw, ok := fields[column.Source]
+		raw = strings.TrimSpace(raw)
+		var value interface{}
+		switch {
+		case !ok || raw == "":
+			if column.Required {
+				return nil, fmt.Errorf("%s is required", column.Source)
+			}
+			value = column.Default
+		default:
+			var err error
+			if value, err = coerceImportValue(raw, column.Type); err != nil {
+				return nil, fmt.Errorf("%s: %w", column.Source, err)
+			}
+		}
+		values[i] = value
+		name := column.Column
+		if name == "" {
+			name = column.Source
+		}
+		row[name] = value
+	}
+	if im.Validate != nil {
+		if err := im.Validate(row); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// coerceImportValue converts a source value to its column type
+func coerceImportValue(raw string, typ ImportType) (interface{}, error) {
+	switch typ {
+	case "", ImportText:
+		return raw, nil
+	case ImportInteger:
+		return strconv.ParseInt(raw, 10, 64)
+	case ImportReal:
+		return strconv.ParseFloat(raw, 64)
+	case ImportBool:
+		switch strings.ToLower(raw) {
+		case "true", "1", "yes", "y":
+			return 1, nil
+		case "false", "0", "no", "n":
+			return 0, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q", raw)
+	case ImportTime:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t.UTC().Format(time.RFC3339Nano), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", raw)
+	default:
+		return nil, fmt.Errorf("unknown import type %q", typ)
+	}
+}
+
+// importRecords returns a reader of source records as field maps, ending with io.EOF
+func importRecords(format ImportFormat, r io.Reader) (func() (map[string]string, error), error) {
+	switch format {
+	case "", ImportCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err == io.EOF {
+			return func() (map[string]string, error) { return nil, io.EOF }, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		return func() (map[string]string, error) {
+			record, err := reader.Read()
+			if err != nil {
+				return nil, err
+			}
+			fields := make(map[string]string, len(header))
+			for i, name := range header {
+				if i < len(record) {
+					fields[name] = record[i]
+				}
+			}
+			return fields, nil
+		}, nil
+	case ImportJSONLines:
+		decoder := json.NewDecoder(r)
+		decoder.UseNumber()
+		return func() (map[string]string, error) {
+			var object map[string]interface{}
+			if err := decoder.Decode(&object); err != nil {
+				return nil, err
+			}
+			fields := make(map[string]string, len(object))
+			for key, value := range object {
+				switch v := value.(type) {
+				case nil:
+				case string:
+					fields[key] = v
+				case json.Number:
+					fields[key] = v.String()
+				case bool:
+					fields[key] = strconv.FormatBool(v)
+				default:
+					encoded, _ := json.Marshal(v)
+					fields[key] = string(encoded)
+				}
+			}
+			return fields, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestImporterCoercesValidatesAndResumes verifies CSV and JSON Lines imports, rejected
+// record output and resuming a job
+func TestImporterCoercesValidatesAndResumes(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "import.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE customers (email TEXT PRIMARY KEY, age INTEGER, active INTEGER, joined TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	ctx := context.Background()
+
+	var rejected bytes.Buffer
+	importer := &Importer{
+		Table:  "customers",
+		Format: ImportCSV,
+		Columns: []ImportColumn{
+			{Source: "Email", Column: "email", Required: true},
+			{Source: "Age", Column: "age", Type: ImportInteger},
+			{Source: "Active", Column: "active", Type: ImportBool, Default: 1},
+			{Source: "Joined", Column: "joined", Type: ImportTime},
+		},
+		Validate: func(row map[string]interface{}) error {
+			if !strings.Contains(row["email"].(string), "@") {
+				return errors.New("invalid email")
+			}
+			return nil
+		},
+		ErrorOutput: &rejected,
+		BatchSize:   2,
+		Job:         "customers-2024",
+	}
+	source := `Email,Age,Active,Joined
+ada@x.io,36,yes,2024-01-02
+grace@x.io,abc,no,2024-01-03
+linus,50,,2024-01-04
+,20,1,2024-01-05
+ada@x.io,37,1,2024-01-06
+tim@x.io,,,2024-01-07T10:00:00Z
+`
+	result, err := importer.Run(ctx, strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if *result != (ImportResult{Records: 6, Imported: 2, Rejected: 4}) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	lines := strings.Split(strings.TrimSpace(rejected.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], `"record":2`) || !strings.Contains(rejected.String(), "UNIQUE") {
+		t.Errorf("Unexpected rejected records:\n%s", rejected.String())
+	}
+	var active int
+	var joined string
+	if err := db.QueryRow("SELECT active, joined FROM customers WHERE email = 'tim@x.io'").Scan(&active, &joined); err != nil || active != 1 || joined != "2024-01-07T10:00:00Z" {
+		t.Errorf("Unexpected coerced row: %d %s (%v)", active, joined, err)
+	}
+
+	// Running the job again skips what it already processed
+	rejected.Reset()
+	result, err = importer.Run(ctx, strings.NewReader(source+"new@x.io,1,1,2024-02-01\n"))
+	if err != nil || *result != (ImportResult{Records: 7, Resumed: 6, Imported: 1}) || rejected.Len() != 0 {
+		t.Errorf("Unexpected resumed result %+v: %v", result, err)
+	}
+
+	importer = &Importer{Table: "customers", Format: ImportJSONLines, Columns: []ImportColumn{{Source: "email"}, {Source: "age", Type: ImportInteger}}}
+	result, err = importer.Run(ctx, strings.NewReader(`{"email": "json@x.io", "age": 41}
+{"email": "null@x.io", "age": null}
+`))
+	if err != nil || result.Imported != 2 {
+		t.Errorf("Unexpected JSON Lines result %+v: %v", result, err)
+	}
+}
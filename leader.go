@@ -0,0 +1,274 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNotLeader is returned by CheckFencingToken when a token's term has ended
+var ErrNotLeader = errors.New("leadership was lost")
+
+// leaderSchema creates the leader leases; token counts terms and is never reset, so a
+// stale leader's token is always lower than the current one
+const leaderSchema = `CREATE TABLE IF NOT EXISTS _leader_leases (
+    name       TEXT PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    token      INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL
+)`
+
+// ElectionOptions configures leader election among processes sharing the database file
+type ElectionOptions struct {
+	Name          string        // Election name, e.g. "cron" or "outbox" (required)
+	Owner         string        // Unique owner ID (default: hostname:pid)
+	LeaseTTL      time.Duration // Lease lifetime (default: 15s)
+	RenewInterval time.Duration // How often the lease is renewed or contested (default: LeaseTTL/3)
+
+	// OnElected runs in its own goroutine when this process becomes leader. Its context is
+	// cancelled when leadership ends; token is the term's fencing token.
+	OnElected func(ctx context.Context, token int64)
+	// OnResigned is called when leadership ends, after OnElected's context is cancelled
+	OnResigned func()
+}
+
+// LeaderStatus reports the state of an election as seen by this process
+type LeaderStatus struct {
+	Name      string
+	Owner     string
+	Leader    string    // Current lease holder, if any
+	Token     int64     // Current term's fencing token
+	ExpiresAt time.Time // Lease expiry
+	IsLeader  bool
+	LastCheck time.Time
+	LastError string
+}
+
+// LeaderElection elects one leader per name among processes sharing the database file,
+// generalizing the EFS writer lease to any singleton work (cron tasks, relays, maintenance)
+type LeaderElection struct {
+	opts   ElectionOptions
+	mu     sync.RWMutex
+	status LeaderStatus
+	cancel context.CancelFunc // Cancels OnElected's context while leading
+	done   chan struct{}
+}
+
+// StartLeaderElection contests the named lease and keeps renewing or contesting it until
+// ctx is cancelled, when the lease is released so another process can take over at once
+func StartLeaderElection(ctx context.Context, opts ElectionOptions) (*LeaderElection, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("leader election requires a name")
+	}
+	if opts.Owner == "" {
+		hostname, _ := os.Hostname()
+		opts.Owner = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = 15 * time.Second
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = opts.LeaseTTL / 3
+	}
+
+	election := &LeaderElection{
+		opts:   opts,
+		status: LeaderStatus{Name: opts.Name, Owner: opts.Owner},
+		done:   make(chan struct{}),
+	}
+	election.check(ctx)
+
+	tracker := startSubsystem("leader:"+opts.Name, opts.RenewInterval)
+	go func() {
+		defer close(election.done)
+		ticker := time.NewTicker(opts.RenewInterval)
+		defer ticker.Stop()
+		defer tracker.stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				election.release()
+				return
+			case <-ticker.C:
+				tracker.run(func() error {
+					election.check(ctx)
+					if status := election.Status(); status.LastError != "" {
+						return errors.New(status.LastError)
+					}
+					return nil
+				})
+			}
+		}
+	}()
+
+	logf("🗳️  Leader election started: %s (owner: %s, leader: %t)", opts.Name, opts.Owner, election.IsLeader())
+	return election, nil
+}
+
+// Status returns a snapshot of the election state
+func (e *LeaderElection) Status() LeaderStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.status
+}
+
+// IsLeader reports whether this process currently holds an unexpired lease
+func (e *LeaderElection) IsLeader() bool {
+	status := e.Status()
+	return status.IsLeader && time.Now().Before(status.ExpiresAt)
+}
+
+// Token returns the current term's fencing token while leading, or 0
+func (e *LeaderElection) Token() int64 {
+	if !e.IsLeader() {
+		return 0
+	}
+	return e.Status().Token
+}
+
+// Done is closed once the election has stopped and the lease has been released
+func (e *LeaderElection) Done() <-chan struct{} {
+	return e.done
+}
+
+// CheckFencingToken returns ErrNotLeader unless token is still the named lease's current,
+// unexpired term. Call it inside the transaction of a leader-only write, so a leader that
+// stalled past its lease cannot write after another process took over.
+func CheckFencingToken(ctx context.Context, tx *sql.Tx, name string, token int64) error {
+	var current, expiresAt int64
+	err := tx.QueryRowContext(ctx, "SELECT token, expires_at FROM _leader_leases WHERE name = ?", name).Scan(&current, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s has no lease", ErrNotLeader, name)
+	}
+	if err != nil {
+		return err
+	}
+	if current != token || time.Now().UnixMilli() >= expiresAt {
+		return fmt.Errorf("%w: %s token %d is not the current term %d", ErrNotLeader, name, token, current)
+	}
+	return nil
+}
+
+// check acquires, renews or observes the lease in one transaction
+func (e *LeaderElection) check(ctx context.Context) {
+	var owner string
+	var token, expiresAt int64
+	now := time.Now()
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, leaderSchema); err != nil {
+			return fmt.Errorf("failed to create leader leases: %w", err)
+		}
+		err := tx.QueryRowContext(ctx, "SELECT owner, token, expires_at FROM _leader_leases WHERE name = ?", e.opts.Name).
+			Scan(&owner, &token, &expiresAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		held := err == nil && expiresAt > now.UnixMilli()
+		if held && owner != e.opts.Owner {
+			return nil
+		}
+		// A free or expired lease starts a new term; our own live lease is renewed
+		if !held {
+			token++
+		}
+		owner, expiresAt = e.opts.Owner, now.Add(e.opts.LeaseTTL).UnixMilli()
+		_, err = tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO _leader_leases (name, owner, token, expires_at) VALUES (?, ?, ?, ?)",
+			e.opts.Name, owner, token, expiresAt)
+		return err
+	})
+	if err != nil {
+		e.failed(err)
+		return
+	}
+	e.observe(owner, token, time.UnixMilli(expiresAt))
+}
+
+// observe records the lease holder and fires callbacks when leadership changes
+func (e *LeaderElection) observe(owner string, token int64, expiresAt time.Time) {
+	leading := owner == e.opts.Owner
+
+	e.mu.Lock()
+	was, wasToken := e.status.IsLeader, e.status.Token
+	e.status.Leader, e.status.Token, e.status.ExpiresAt = owner, token, expiresAt
+	e.status.IsLeader = leading
+	e.status.LastCheck, e.status.LastError = time.Now(), ""
+	e.mu.Unlock()
+
+	if was && (!leading || token != wasToken) {
+		e.resign()
+	}
+	if leading && (!was || token != wasToken) {
+		e.elect(token)
+	}
+}
+
+// failed records a check error, resigning before the lease could lapse unrenewed
+func (e *LeaderElection) failed(err error) {
+	e.mu.Lock()
+	e.status.LastCheck, e.status.LastError = time.Now(), err.Error()
+	lapsing := e.status.IsLeader && time.Until(e.status.ExpiresAt) < e.opts.RenewInterval
+	if lapsing {
+		e.status.IsLeader = false
+	}
+	e.mu.Unlock()
+
+	logWarnf("⚠️  Leader election %s check failed: %v", e.opts.Name, err)
+	if lapsing {
+		e.resign()
+	}
+}
+
+// elect starts a term, running OnElected with a context that ends with the term
+func (e *LeaderElection) elect(token int64) {
+	logf("👑 Elected leader of %s (token %d): %s", e.opts.Name, token, e.opts.Owner)
+	termCtx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	e.cancel = cancel
+	e.mu.Unlock()
+	if e.opts.OnElected != nil {
+		go e.opts.OnElected(termCtx, token)
+	}
+}
+
+// resign ends the current term
+func (e *LeaderElection) resign() {
+	e.mu.Lock()
+	cancel := e.cancel
+	e.cancel = nil
+	e.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	logf("🔓 Resigned leadership of %s: %s", e.opts.Name, e.opts.Owner)
+	if e.opts.OnResigned != nil {
+		e.opts.OnResigned()
+	}
+}
+
+// release expires the lease if this process holds it, keeping the token for the next term
+func (e *LeaderElection) release() {
+	if e.Status().IsLeader {
+		db, err := GetDB()
+		if err == nil {
+			_, err = ExecContextWithRetry(context.Background(), db,
+				"UPDATE _leader_leases SET expires_at = 0 WHERE name = ? AND owner = ?", e.opts.Name, e.opts.Owner)
+			db.Close()
+		}
+		if err != nil {
+			logWarnf("⚠️  Failed to release leader lease %s: %v", e.opts.Name, err)
+		}
+	}
+
+	e.mu.Lock()
+	e.status.IsLeader = false
+	e.mu.Unlock()
+	e.resign()
+	logf("🗳️  Leader election stopped: %s", e.opts.Name)
+}
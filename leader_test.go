@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLeaderElectionFailover verifies a single leader, callbacks, handover on release and
+// fencing of the previous term's token
+func TestLeaderElectionFailover(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "leader.db"))
+
+	elected := make(chan int64, 2)
+	resigned := make(chan string, 2)
+	start := func(ctx context.Context, owner string) *LeaderElection {
+		election, err := StartLeaderElection(ctx, ElectionOptions{
+			Name:          "cron",
+			Owner:         owner,
+			LeaseTTL:      time.Second,
+			RenewInterval: 20 * time.Millisecond,
+			OnElected:     func(ctx context.Context, token int64) { elected <- token },
+			OnResigned:    func() { resigned <- owner },
+		})
+		if err != nil {
+			t.Fatalf("Failed to start election for %s: %v", owner, err)
+		}
+		return election
+	}
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	first := start(firstCtx, "a")
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	second := start(secondCtx, "b")
+	defer func() {
+		stopSecond()
+		<-second.Done()
+	}()
+
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("Expected a to lead alone, got a=%t b=%t", first.IsLeader(), second.IsLeader())
+	}
+	if token := <-elected; token != 1 {
+		t.Errorf("Expected the first term's token to be 1, got %d", token)
+	}
+
+	stopFirst()
+	<-first.Done()
+	if owner := <-resigned; owner != "a" {
+		t.Errorf("Expected a to resign, got %s", owner)
+	}
+	select {
+	case token := <-elected:
+		if token != 2 || second.Token() != 2 {
+			t.Errorf("Expected b's term to have token 2, got %d", token)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected b to take over after a released the lease")
+	}
+
+	err := WithTransactionRetry(func(tx *sql.Tx) error {
+		if err := CheckFencingToken(context.Background(), tx, "cron", 2); err != nil {
+			t.Errorf("Expected the current token to pass: %v", err)
+		}
+		return CheckFencingToken(context.Background(), tx, "cron", 1)
+	})
+	if !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Expected the stale token to be fenced off, got %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidSort is returned for sorts on fields outside the sort allowlist
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrInvalidProjection is returned for projections of fields outside the allowlist
+	ErrInvalidProjection = errors.New("invalid projection")
+)
+
+// SortField orders by one field
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Sort orders by fields in priority order
+type Sort []SortField
+
+// Projection selects the fields a list returns
+type Projection []string
+
+// ParseSort parses a sort parameter such as "-created,name" (a leading "-" sorts
+// descending), checking every field against fields
+func ParseSort(value string, fields FilterFields) (Sort, error) {
+	var result Sort
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := fields[field.Field]; !ok {
+			return nil, fmt.Errorf("%w: field %q cannot be sorted on", ErrInvalidSort, field.Field)
+		}
+		result = append(result, field)
+	}
+	return result, nil
+}
+
+// Compile renders the sort as an ORDER BY list (without the keywords), ending with
+// tiebreaker ascending unless the sort already includes that column, so pages never
+// overlap or skip rows that tie on the sorted fields
+func (s Sort) Compile(fields FilterFields, tiebreaker string) (string, error) {
+	var parts []string
+	tied := false
+	for _, field := range s {
+		column, ok := fields[field.Field]
+		if !ok {
+			return "", fmt.Errorf("%w: field %q cannot be sorted on", ErrInvalidSort, field.Field)
+		}
+		direction := "ASC"
+		if field.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, quoteIdentifier(column)+" "+direction)
+		tied = tied || column == tiebreaker
+	}
+	if tiebreaker != "" && !tied {
+		parts = append(parts, quoteIdentifier(tiebreaker)+" ASC")
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// ParseProjection parses a fields parameter such as "id,name", checking every field
+// against fields
+func ParseProjection(value string, fields FilterFields) (Projection, error) {
+	var projection Projection
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if _, ok := fields[part]; !ok {
+			return nil, fmt.Errorf("%w: field %q cannot be selected", ErrInvalidProjection, part)
+		}
+		projection = append(projection, part)
+	}
+	return projection, nil
+}
+
+// Compile renders the projection as a select list with each column aliased to its field
+// name. An empty projection selects every allowlisted field (never "*", so columns added
+// later are not exposed by accident).
+func (p Projection) Compile(fields FilterFields) (string, error) {
+	selected := p
+	if len(selected) == 0 {
+		selected = sortedFieldNames(fields)
+	}
+	parts := make([]string, len(selected))
+	for i, field := range selected {
+		column, ok := fields[field]
+		if !ok {
+			return "", fmt.Errorf("%w: field %q cannot be selected", ErrInvalidProjection, field)
+		}
+		parts[i] = quoteIdentifier(column)
+		if column != field {
+			parts[i] += " AS " + quoteIdentifier(field)
+		}
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// ListQuery builds the SELECT of a list endpoint from validated filter, sort and
+// projection inputs
+type ListQuery struct {
+	Table      string
+	Fields     FilterFields // Fields that may be selected and filtered on
+	SortFields FilterFields // Fields that may be sorted on, e.g. only indexed ones (default: Fields)
+	Select     Projection
+	Filter     Filter
+	Sort       Sort
+	Tiebreaker string // Unique column ending every ORDER BY (default: "id")
+	Limit      int    // 0 for no limit
+	Offset     int
+	Count      CountStrategy // How List computes the total (default: no total)
+	CountCap   int           // Rows CountCapped and CountApproximate count up to (default: DefaultCountCap)
+}
+
+// Build renders the query and its arguments
+func (q ListQuery) Build() (string, []interface{}, error) {
+	columns, err := q.Select.Compile(q.Fields)
+	if err != nil {
+		return "", nil, err
+	}
+	where, args, err := q.Filter.Compile(q.Fields)
+	if err != nil {
+		return "", nil, err
+	}
+	sortFields := q.SortFields
+	if sortFields == nil {
+		sortFields = q.Fields
+	}
+	tiebreaker := q.Tiebreaker
+	if tiebreaker == "" {
+		tiebreaker = "id"
+	}
+	order, err := q.Sort.Compile(sortFields, tiebreaker)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s", columns, quoteIdentifier(q.Table), where, order)
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+		if q.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, q.Offset)
+		}
+	}
+	return query, args, nil
+}
+
+// sortedFieldNames returns the allowlisted field names in name order
+func sortedFieldNames(fields FilterFields) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CountStrategy is how a list's total row count is computed
+type CountStrategy string
+
+// Count strategies
+const (
+	CountNone        CountStrategy = ""            // No total; HasMore still tells whether a next page exists
+	CountExact       CountStrategy = "exact"       // COUNT(*) of every matching row
+	CountCapped      CountStrategy = "capped"      // Count up to CountCap rows, reporting "more than CountCap" beyond
+	CountApproximate CountStrategy = "approximate" // Table estimate when unfiltered, capped count otherwise
+)
+
+// DefaultCountCap is how many rows CountCapped counts before reporting "more than"
+const DefaultCountCap = 1000
+
+// ListPage is one page of a list and its total
+type ListPage struct {
+	Rows       []map[string]interface{} `json:"rows"`
+	HasMore    bool                     `json:"has_more"`
+	Total      int64                    `json:"total,omitempty"`
+	TotalExact bool                     `json:"total_exact"` // False when Total is an estimate or a cap ("more than Total")
+}
+
+// BuildCount renders the query counting the matching rows: a plain COUNT(*) for
+// CountExact, or a COUNT(*) over at most CountCap+1 rows otherwise
+func (q ListQuery) BuildCount() (string, []interface{}, error) {
+	where, args, err := q.Filter.Compile(q.Fields)
+	if err != nil {
+		return "", nil, err
+	}
+	if q.Count == CountExact {
+		return fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", quoteIdentifier(q.Table), where), args, nil
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s WHERE %s LIMIT ?)", quoteIdentifier(q.Table), where), append(args, q.countCap()+1), nil
+}
+
+// List runs the query and, per its Count strategy, counts the matching rows in the same
+// read snapshot. One extra row is fetched to set HasMore, and the count query is skipped
+// whenever the page itself shows where the list ends.
+func (q ListQuery) List(ctx context.Context) (*ListPage, error) {
+	limit := q.Limit
+	if limit > 0 {
+		q.Limit = limit + 1
+	}
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ListPage{}
+	err = ReadTransaction(ctx, func(tx *sql.Tx) error {
+		*page = ListPage{}
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		page.Rows, _, err = scanRowMaps(rows, 0)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if limit > 0 && len(page.Rows) > limit {
+			page.Rows, page.HasMore = page.Rows[:limit], true
+		}
+		if page.Rows == nil {
+			page.Rows = []map[string]interface{}{}
+		}
+
+		if q.Count == CountNone {
+			return nil
+		}
+		if !page.HasMore && (len(page.Rows) > 0 || q.Offset == 0) {
+			// The last page: the total is known without counting
+			page.Total, page.TotalExact = int64(q.Offset+len(page.Rows)), true
+			return nil
+		}
+		if q.Count == CountApproximate && q.Filter.IsEmpty() {
+			estimate, err := EstimateRowCount(ctx, q.Table)
+			if err != nil {
+				return err
+			}
+			page.Total = estimate.Count
+			return nil
+		}
+
+		countQuery, countArgs, err := q.BuildCount()
+		if err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, countQuery, countArgs...).Scan(&page.Total); err != nil {
+			return fmt.Errorf("failed to count %s: %w", q.Table, err)
+		}
+		page.TotalExact = true
+		if q.Count != CountExact && page.Total > int64(q.countCap()) {
+			page.Total, page.TotalExact = int64(q.countCap()), false
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", q.Table, err)
+	}
+	return page, nil
+}
+
+// countCap returns the capped count limit
+func (q ListQuery) countCap() int {
+	if q.CountCap <= 0 {
+		return DefaultCountCap
+	}
+	return q.CountCap
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestListQueryBuildsAllowlistedSortAndProjection verifies sort and projection parsing,
+// the stable tiebreaker and the composed list query
+func TestListQueryBuildsAllowlistedSortAndProjection(t *testing.T) {
+	fields := FilterFields{"id": "id", "status": "status", "created": "created_at"}
+
+	sort, err := ParseSort("-created, status", fields)
+	if err != nil || !reflect.DeepEqual(sort, Sort{{Field: "created", Desc: true}, {Field: "status"}}) {
+		t.Fatalf("Unexpected sort %v: %v", sort, err)
+	}
+	order, _ := sort.Compile(fields, "id")
+	if order != `"created_at" DESC, "status" ASC, "id" ASC` {
+		t.Errorf("Expected the tiebreaker to end the order, got %s", order)
+	}
+	order, _ = Sort{{Field: "id", Desc: true}}.Compile(fields, "id")
+	if order != `"id" DESC` {
+		t.Errorf("Expected no second tiebreaker when sorting on it, got %s", order)
+	}
+	if _, err := ParseSort("password", fields); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("Expected sorts outside the allowlist to be rejected, got %v", err)
+	}
+	if _, err := ParseProjection("id,secret", fields); !errors.Is(err, ErrInvalidProjection) {
+		t.Errorf("Expected projections outside the allowlist to be rejected, got %v", err)
+	}
+	columns, _ := Projection(nil).Compile(fields)
+	if columns != `"created_at" AS "created", "id", "status"` {
+		t.Errorf("Expected an empty projection to select the allowlist, got %s", columns)
+	}
+
+	// Sorting can be restricted to fewer (e.g. indexed) fields than filtering
+	q := ListQuery{Table: "tickets", Fields: fields, SortFields: FilterFields{"created": "created_at"}, Sort: Sort{{Field: "status"}}}
+	if _, _, err := q.Build(); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("Expected a sort outside SortFields to be rejected, got %v", err)
+	}
+
+	projection, _ := ParseProjection("id,status", fields)
+	q = ListQuery{Table: "tickets", Fields: fields, Select: projection, Filter: Eq("status", "open"), Sort: Sort{{Field: "created", Desc: true}}, Limit: 2, Offset: 1}
+	query, args, err := q.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	expected := `SELECT "id", "status" FROM "tickets" WHERE "status" = ? ORDER BY "created_at" DESC, "id" ASC LIMIT ? OFFSET ?`
+	if query != expected || !reflect.DeepEqual(args, []interface{}{"open", 2, 1}) {
+		t.Errorf("Unexpected query:\n%s\n%v", query, args)
+	}
+
+	// Rows tying on the sort field come back in tiebreaker order
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "list.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE tickets (id INTEGER PRIMARY KEY, status TEXT, created_at INTEGER, secret TEXT);
+		INSERT INTO tickets VALUES (4, 'open', 10, 'x'), (2, 'open', 10, 'x'), (3, 'open', 10, 'x'), (1, 'held', 10, 'x')`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	result, _, err := scanRowMaps(rows, 0)
+	rows.Close()
+	if err != nil || len(result) != 2 || result[0]["id"] != int64(3) || result[1]["id"] != int64(4) {
+		t.Errorf("Expected ids 3 and 4, got %v: %v", result, err)
+	}
+	if _, ok := result[0]["secret"]; ok {
+		t.Error("Expected columns outside the projection to be left out")
+	}
+}
+
+// TestListQueryCountStrategies verifies exact, capped and approximate totals and HasMore
+func TestListQueryCountStrategies(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "count.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, kind TEXT);
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 50)
+		INSERT INTO items SELECT i, CASE WHEN i % 2 = 0 THEN 'even' ELSE 'odd' END FROM n`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	ctx := context.Background()
+	base := ListQuery{Table: "items", Fields: FilterFields{"id": "id", "kind": "kind"}, Limit: 10}
+
+	q := base
+	q.Count = CountExact
+	q.Filter = Eq("kind", "even")
+	page, err := q.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(page.Rows) != 10 || !page.HasMore || page.Total != 25 || !page.TotalExact {
+		t.Errorf("Unexpected exact page: %d rows, more=%v, total=%d exact=%v", len(page.Rows), page.HasMore, page.Total, page.TotalExact)
+	}
+
+	q.Count, q.CountCap = CountCapped, 20
+	if page, err = q.List(ctx); err != nil || page.Total != 20 || page.TotalExact {
+		t.Errorf("Expected \"more than 20\", got %d exact=%v: %v", page.Total, page.TotalExact, err)
+	}
+	q.CountCap = 100
+	if page, err = q.List(ctx); err != nil || page.Total != 25 || !page.TotalExact {
+		t.Errorf("Expected an exact total under the cap, got %d exact=%v: %v", page.Total, page.TotalExact, err)
+	}
+
+	// The last page knows its total without counting
+	q.Offset = 20
+	if page, err = q.List(ctx); err != nil || len(page.Rows) != 5 || page.HasMore || page.Total != 25 || !page.TotalExact {
+		t.Errorf("Unexpected last page: %+v: %v", page, err)
+	}
+
+	q = base
+	q.Count = CountApproximate
+	if page, err = q.List(ctx); err != nil || page.Total != 50 || page.TotalExact {
+		t.Errorf("Expected an estimated total of 50, got %d exact=%v: %v", page.Total, page.TotalExact, err)
+	}
+
+	q.Count = CountNone
+	if page, err = q.List(ctx); err != nil || page.Total != 0 || !page.HasMore {
+		t.Errorf("Expected no total but a next page, got %+v: %v", page, err)
+	}
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockHolder is a write statement or transaction in progress in this process
+type LockHolder struct {
+	Kind      string // "statement" or "transaction"
+	Statement string // Normalized statement, or the first statement of a transaction
+	Caller    string // Where a transaction was opened
+	Started   time.Time
+	Age       time.Duration // How long it had been running when diagnostics were captured
+}
+
+// LockDiagnostics describes lock contention at the moment a retry budget ran out
+type LockDiagnostics struct {
+	CapturedAt        time.Time
+	Elapsed           time.Duration // Time spent retrying
+	Attempts          int
+	Waiters           int64        // Operations in this process currently retrying BUSY errors
+	WriteQueueWaiting int          // Writers waiting in the write queue (see EnableWriteQueue)
+	WALBytes          int64        // Size of DATABASE_FILE's WAL, -1 when unknown
+	Holders           []LockHolder // Writes and transactions in progress, oldest first
+	LongestHolder     *LockHolder  // The oldest entry in Holders, the most likely lock holder
+}
+
+// LockWaitError is returned when a retry budget is exhausted on BUSY errors. It wraps the
+// last SQLite error, so IsBusy and errors.Is keep working.
+type LockWaitError struct {
+	Err         error
+	Diagnostics LockDiagnostics
+}
+
+func (e *LockWaitError) Error() string {
+	d := e.Diagnostics
+	message := fmt.Sprintf("%v (gave up after %d attempts in %v; %d waiters, %d in write queue, WAL %d bytes",
+		e.Err, d.Attempts, d.Elapsed.Round(time.Millisecond), d.Waiters, d.WriteQueueWaiting, d.WALBytes)
+	if d.LongestHolder != nil {
+		message += fmt.Sprintf("; longest holder: %s %q running %v", d.LongestHolder.Kind, d.LongestHolder.Statement, d.LongestHolder.Age.Round(time.Millisecond))
+	}
+	return message + ")"
+}
+
+func (e *LockWaitError) Unwrap() error {
+	return e.Err
+}
+
+// lockHolderRegistry tracks in-progress writes and transactions
+type lockHolderRegistry struct {
+	mu      sync.Mutex
+	nextID  int64
+	holders map[int64]*LockHolder
+	hooks   []func(LockDiagnostics)
+}
+
+// Global lock holder registry instance
+var globalLockHolders = &lockHolderRegistry{holders: make(map[int64]*LockHolder)}
+
+// retryWaiters counts retry loops currently backing off on BUSY errors
+var retryWaiters atomic.Int64
+
+// OnLockTimeout registers a hook called with diagnostics whenever a retry budget is
+// exhausted on BUSY errors, used to forward lock contention to logging or alerting
+func OnLockTimeout(hook func(LockDiagnostics)) {
+	globalLockHolders.mu.Lock()
+	defer globalLockHolders.mu.Unlock()
+
+	globalLockHolders.hooks = append(globalLockHolders.hooks, hook)
+}
+
+// trackLockHolder records a write or transaction as in progress until the returned
+// function is called. The returned setter updates the recorded statement for
+// transactions, whose first statement is not known when they begin.
+func trackLockHolder(kind, statement string) (func(), func(statement string)) {
+	holder := &LockHolder{Kind: kind, Statement: statement, Started: time.Now()}
+	if kind == "transaction" {
+		holder.Caller = statementCaller()
+	}
+
+	r := globalLockHolders
+	r.mu.Lock()
+	r.nextID++
+	id := r.nextID
+	r.holders[id] = holder
+	r.mu.Unlock()
+
+	done := func() {
+		r.mu.Lock()
+		delete(r.holders, id)
+		r.mu.Unlock()
+	}
+	setStatement := func(statement string) {
+		r.mu.Lock()
+		if holder.Statement == "" {
+			holder.Statement = NormalizeStatement(statement)
+		}
+		r.mu.Unlock()
+	}
+	return done, setStatement
+}
+
+// trackWrite records an autocommit write statement as in progress
+func trackWrite(query string) func() {
+	done, _ := trackLockHolder("statement", NormalizeStatement(query))
+	return done
+}
+
+// lockWaitError captures diagnostics for an exhausted retry budget, notifies hooks,
+// and wraps err with them
+func lockWaitError(ctx context.Context, err error, elapsed time.Duration, attempts int) error {
+	diagnostics := LockDiagnostics{
+		CapturedAt:        time.Now(),
+		Elapsed:           elapsed,
+		Attempts:          attempts,
+		Waiters:           retryWaiters.Load(),
+		WriteQueueWaiting: GetWriteQueueStats().Waiting,
+		WALBytes:          -1,
+	}
+	if path := getDatabasePath(); path != "" {
+		if info, statErr := os.Stat(path + "-wal"); statErr == nil {
+			diagnostics.WALBytes = info.Size()
+		} else if os.IsNotExist(statErr) {
+			diagnostics.WALBytes = 0
+		}
+	}
+
+	r := globalLockHolders
+	r.mu.Lock()
+	for _, holder := range r.holders {
+		snapshot := *holder
+		snapshot.Age = diagnostics.CapturedAt.Sub(holder.Started)
+		diagnostics.Holders = append(diagnostics.Holders, snapshot)
+	}
+	hooks := make([]func(LockDiagnostics), len(r.hooks))
+	copy(hooks, r.hooks)
+	r.mu.Unlock()
+
+	sort.Slice(diagnostics.Holders, func(i, j int) bool {
+		return diagnostics.Holders[i].Started.Before(diagnostics.Holders[j].Started)
+	})
+	if len(diagnostics.Holders) > 0 {
+		diagnostics.LongestHolder = &diagnostics.Holders[0]
+	}
+
+	lockErr := &LockWaitError{Err: err, Diagnostics: diagnostics}
+	logfContext(ctx, LogLevelInfo, "🔒 Lock wait diagnostics: %v", lockErr)
+	for _, hook := range hooks {
+		hook(diagnostics)
+	}
+	return lockErr
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLockWaitDiagnostics verifies that an exhausted retry budget returns diagnostics
+// naming the transaction holding the lock, and notifies OnLockTimeout hooks
+func TestLockWaitDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks.db")
+	t.Setenv("DATABASE_FILE", path)
+
+	holder, err := sql.Open(DriverName, path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer holder.Close()
+	if _, err := holder.Exec("CREATE TABLE counters (n INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	tx, err := holder.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO counters (n) VALUES (1)"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	var hooked []LockDiagnostics
+	OnLockTimeout(func(d LockDiagnostics) { hooked = append(hooked, d) })
+
+	waiter, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer waiter.Close()
+
+	config := RetryConfig{MaxRetryDuration: 50 * time.Millisecond, BaseDelay: 5 * time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	_, err = ExecWithRetryConfig(waiter, config, "INSERT INTO counters (n) VALUES (2)")
+
+	var lockErr *LockWaitError
+	if !errors.As(err, &lockErr) || !IsBusy(err) {
+		t.Fatalf("Expected a busy LockWaitError, got %v", err)
+	}
+	d := lockErr.Diagnostics
+	if d.Attempts < 2 || d.LongestHolder == nil {
+		t.Fatalf("Unexpected diagnostics: %+v", d)
+	}
+	if d.LongestHolder.Kind != "transaction" || d.LongestHolder.Statement != "INSERT INTO COUNTERS (N) VALUES (?)" ||
+		!strings.Contains(d.LongestHolder.Caller, "lockdiag_test.go") {
+		t.Errorf("Expected the open transaction as longest holder, got %+v", d.LongestHolder)
+	}
+	if len(hooked) != 1 || hooked[0].Attempts != d.Attempts {
+		t.Errorf("Expected one hook call, got %+v", hooked)
+	}
+}
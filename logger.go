@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// LogLevel is the minimum severity of messages the package logs
+type LogLevel int
+
+const (
+	LogLevelInfo  LogLevel = iota // Everything (default)
+	LogLevelWarn                  // Warnings and errors only (quiet mode)
+	LogLevelError                 // Errors only
+	LogLevelOff                   // Nothing
+)
+
+// LogFormat selects how the package's log lines are written
+type LogFormat string
+
+const (
+	LogFormatEmoji LogFormat = "emoji" // Human-friendly lines with emoji through the standard log package (default)
+	LogFormatText  LogFormat = "text"  // Emoji-free key=value lines, e.g. time=... level=warn msg="..."
+	LogFormatJSON  LogFormat = "json"  // One JSON object per line with time, level and msg
+)
+
+// LogConfig configures the package's logging
+type LogConfig struct {
+	Level  LogLevel
+	Format LogFormat
+	Output io.Writer // Destination for text and JSON lines (default: the standard logger's writer)
+	// Logger receives every message instead of Output, leaving format and destination to
+	// its handler
+	Logger *slog.Logger
+}
+
+var (
+	logMu     sync.RWMutex
+	logConfig *LogConfig
+)
+
+// SetLogConfig replaces the logging configuration, overriding DATABASE_LOG_LEVEL and
+// DATABASE_LOG_FORMAT
+func SetLogConfig(config LogConfig) {
+	if config.Format == "" {
+		config.Format = LogFormatEmoji
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+	logConfig = &config
+}
+
+// GetLogConfig returns the logging configuration, read from DATABASE_LOG_LEVEL
+// (info, warn, error or off) and DATABASE_LOG_FORMAT (emoji, text or json) unless
+// SetLogConfig was called
+func GetLogConfig() LogConfig {
+	logMu.RLock()
+	config := logConfig
+	logMu.RUnlock()
+	if config != nil {
+		return *config
+	}
+
+	loaded := LogConfig{Level: LogLevelInfo, Format: LogFormatEmoji}
+	switch strings.ToLower(configValue("DATABASE_LOG_LEVEL")) {
+	case "warn", "warning", "quiet":
+		loaded.Level = LogLevelWarn
+	case "error":
+		loaded.Level = LogLevelError
+	case "off", "none":
+		loaded.Level = LogLevelOff
+	}
+	switch format := LogFormat(strings.ToLower(configValue("DATABASE_LOG_FORMAT"))); format {
+	case LogFormatText, LogFormatJSON:
+		loaded.Format = format
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	if logConfig == nil {
+		logConfig = &loaded
+	}
+	return *logConfig
+}
+
+// String returns the level's name as written in text and JSON lines
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	case LogLevelOff:
+		return "off"
+	}
+	return "info"
+}
+
+// logf logs an informational message of the package without request context
+func logf(format string, args ...any) {
+	logEvent(context.Background(), LogLevelInfo, nil, format, args...)
+}
+
+// logWarnf logs a warning of the package without request context
+func logWarnf(format string, args ...any) {
+	logEvent(context.Background(), LogLevelWarn, nil, format, args...)
+}
+
+// logErrorf logs an error of the package without request context
+func logErrorf(format string, args ...any) {
+	logEvent(context.Background(), LogLevelError, nil, format, args...)
+}
+
+// logfContext logs a message of the package at level for the request on ctx. Messages
+// start with an emoji for the emoji format, which the text and JSON formats strip. A
+// logger set with WithLogger, or else LogConfig.Logger, receives the message instead.
+// The request ID from WithRequestID and the trace ID of the active span are added.
+func logfContext(ctx context.Context, level LogLevel, format string, args ...any) {
+	logEvent(ctx, level, nil, format, args...)
+}
+
+// logEvent is logfContext with structured attributes: alternating keys and values, as
+// for slog. They become slog attributes and fields of text and JSON lines; the emoji
+// format already carries the values in its message and leaves them out.
+func logEvent(ctx context.Context, level LogLevel, attrs []any, format string, args ...any) {
+	config := GetLogConfig()
+	if level < config.Level || config.Level == LogLevelOff {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	text := stripLogEmoji(message)
+
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	traceID := traceIDFromContext(ctx)
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	if !ok && config.Logger != nil {
+		logger, ok = config.Logger, true
+	}
+	if ok {
+		attrs := append([]any{}, attrs...)
+		if requestID != "" {
+			attrs = append(attrs, "request_id", requestID)
+		}
+		if traceID != "" {
+			attrs = append(attrs, "trace_id", traceID)
+		}
+		logger.Log(ctx, level.slogLevel(), text, attrs...)
+		return
+	}
+
+	switch config.Format {
+	case LogFormatText, LogFormatJSON:
+		out := config.Output
+		if out == nil {
+			out = log.Writer()
+		}
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		var line []byte
+		if config.Format == LogFormatJSON {
+			line, _ = json.Marshal(struct {
+				Time      string `json:"time"`
+				Level     string `json:"level"`
+				Msg       string `json:"msg"`
+				RequestID string `json:"request_id,omitempty"`
+				TraceID   string `json:"trace_id,omitempty"`
+			}{now, level.String(), text, requestID, traceID})
+			line = appendJSONAttrs(line, attrs)
+		} else {
+			line = []byte(fmt.Sprintf("time=%s level=%s msg=%q", now, level, text) + logAttrFields(attrs) + logContextFields(requestID, traceID))
+		}
+		out.Write(append(line, '\n'))
+	default:
+		message += logContextFields(requestID, traceID)
+		if config.Output != nil {
+			fmt.Fprintln(config.Output, message)
+			return
+		}
+		log.Print(message)
+	}
+}
+
+// logAttrValue returns an attribute value as written in text and JSON lines
+func logAttrValue(value any) any {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+	return value
+}
+
+// logAttrFields formats attributes as key=value pairs, quoting text with spaces
+func logAttrFields(attrs []any) string {
+	var fields strings.Builder
+	for i := 0; i+1 < len(attrs); i += 2 {
+		value := fmt.Sprint(logAttrValue(attrs[i+1]))
+		if strings.ContainsAny(value, " \t\"=") || value == "" {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&fields, " %v=%s", attrs[i], value)
+	}
+	return fields.String()
+}
+
+// appendJSONAttrs adds attributes to the end of a JSON object
+func appendJSONAttrs(line []byte, attrs []any) []byte {
+	if len(attrs) < 2 {
+		return line
+	}
+	line = line[:len(line)-1]
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key, _ := json.Marshal(fmt.Sprint(attrs[i]))
+		value, err := json.Marshal(logAttrValue(attrs[i+1]))
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(attrs[i+1]))
+		}
+		line = append(append(append(append(line, ','), key...), ':'), value...)
+	}
+	return append(line, '}')
+}
+
+// logContextFields formats the request and trace IDs as key=value pairs
+func logContextFields(requestID, traceID string) string {
+	fields := ""
+	if requestID != "" {
+		fields += " request_id=" + requestID
+	}
+	if traceID != "" {
+		fields += " trace_id=" + traceID
+	}
+	return fields
+}
+
+// loggerKey and requestIDKey carry request-scoped logging in a context
+type (
+	loggerKey    struct{}
+	requestIDKey struct{}
+)
+
+// WithLogger returns a context whose package logs (retry warnings, lock diagnostics,
+// transaction restarts) go to logger, e.g. one carrying the request's attributes
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithRequestID returns a context whose package logs and query log entries carry id as
+// request_id, for correlation in aggregated logs
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set with WithRequestID, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// traceIDFromContext returns the Datadog trace ID of the span on ctx, or ""
+func traceIDFromContext(ctx context.Context) string {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return strconv.FormatUint(span.Context().TraceID(), 10)
+}
+
+// slogLevel maps the level to its log/slog equivalent
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// stripLogEmoji returns a message without its emoji prefix
+func stripLogEmoji(message string) string {
+	prefix, rest, found := strings.Cut(message, " ")
+	if !found || strings.IndexFunc(prefix, func(r rune) bool { return r < unicode.MaxASCII }) >= 0 {
+		return message
+	}
+	return strings.TrimSpace(rest)
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLogFormatsAndLevels verifies that the text and JSON formats strip emoji and that
+// the level filters lower-severity messages
+func TestLogFormatsAndLevels(t *testing.T) {
+	previous := GetLogConfig()
+	defer SetLogConfig(previous)
+
+	var out bytes.Buffer
+	SetLogConfig(LogConfig{Level: LogLevelInfo, Format: LogFormatText, Output: &out})
+	logErrorf("❌ Failed to migrate %s", "billing")
+	if line := out.String(); !strings.Contains(line, `level=error msg="Failed to migrate billing"`) || strings.Contains(line, "❌") {
+		t.Errorf("Unexpected text line: %q", line)
+	}
+
+	out.Reset()
+	SetLogConfig(LogConfig{Level: LogLevelWarn, Format: LogFormatJSON, Output: &out})
+	logf("✅ Completed migrations for: %s", "billing")
+	logWarnf("⚠️  No migration sources registered")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the warning in quiet mode, got %q", out.String())
+	}
+	var entry struct{ Level, Msg string }
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON line: %v", err)
+	}
+	if entry.Level != "warn" || entry.Msg != "No migration sources registered" {
+		t.Errorf("Unexpected JSON entry: %+v", entry)
+	}
+
+	// The level is the caller's, whatever the message's emoji
+	out.Reset()
+	SetLogConfig(LogConfig{Level: LogLevelInfo, Format: LogFormatText, Output: &out})
+	logWarnf("Disk space is low")
+	logfContext(context.Background(), LogLevelInfo, "❌ Validation rule reported")
+	if text := out.String(); !strings.Contains(text, `level=warn msg="Disk space is low"`) || !strings.Contains(text, `level=info msg="Validation rule reported"`) {
+		t.Errorf("Expected explicit levels, got %q", text)
+	}
+
+	out.Reset()
+	SetLogConfig(LogConfig{Level: LogLevelOff, Output: &out})
+	logErrorf("❌ Failed")
+	if out.Len() != 0 {
+		t.Errorf("Expected no output when logging is off, got %q", out.String())
+	}
+}
+
+// TestLogsCarryRequestContext verifies that logs for a request carry its ID and go to
+// the logger set on the context
+func TestLogsCarryRequestContext(t *testing.T) {
+	previous := GetLogConfig()
+	defer SetLogConfig(previous)
+
+	var out bytes.Buffer
+	SetLogConfig(LogConfig{Format: LogFormatText, Output: &out})
+	ctx := WithRequestID(context.Background(), "req-42")
+	logfContext(ctx, LogLevelInfo, "🔄 SQLite BUSY - retrying in %v", "10ms")
+	if line := out.String(); !strings.Contains(line, `level=info msg="SQLite BUSY - retrying in 10ms" request_id=req-42`) {
+		t.Errorf("Expected request ID in text line, got %q", line)
+	}
+
+	var handled bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&handled, nil))
+	out.Reset()
+	logfContext(WithLogger(ctx, logger), LogLevelWarn, "⚠️  Lock wait")
+	if out.Len() != 0 {
+		t.Errorf("Expected the context logger to replace the package output, got %q", out.String())
+	}
+	var entry struct {
+		Level     string
+		Msg       string
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(handled.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse slog line %q: %v", handled.String(), err)
+	}
+	if entry.Level != "WARN" || entry.Msg != "Lock wait" || entry.RequestID != "req-42" {
+		t.Errorf("Unexpected slog entry: %+v", entry)
+	}
+}
+
+// TestInjectedLoggers verifies LogConfig.Logger receives package logs and that a
+// RetryConfig's logger takes the retry loop's messages
+func TestInjectedLoggers(t *testing.T) {
+	previous := GetLogConfig()
+	defer SetLogConfig(previous)
+
+	var global, retries bytes.Buffer
+	SetLogConfig(LogConfig{Level: LogLevelWarn, Logger: slog.New(slog.NewTextHandler(&global, nil))})
+	logf("✅ Completed migrations")
+	logErrorf("❌ Failed to migrate %s", "billing")
+	if line := global.String(); strings.Count(line, "\n") != 1 || !strings.Contains(line, `level=ERROR msg="Failed to migrate billing"`) {
+		t.Errorf("Expected only the error through the global logger, got %q", line)
+	}
+
+	global.Reset()
+	config := RetryConfig{MaxRetryDuration: time.Second, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Clock: &fakeClock{},
+		Logger: slog.New(slog.NewTextHandler(&retries, nil))}
+	_ = retryDatabaseOperation(func() error { return errors.New("database is locked") }, config)
+	if global.Len() != 0 || !strings.Contains(retries.String(), "SQLite operation failed after") {
+		t.Errorf("Expected retry messages on the RetryConfig logger only, got %q and %q", global.String(), retries.String())
+	}
+}
+
+// TestLogEventAttributes verifies that structured event fields reach the text, JSON and
+// slog outputs while the emoji format keeps its message
+func TestLogEventAttributes(t *testing.T) {
+	previous := GetLogConfig()
+	defer SetLogConfig(previous)
+
+	attrs := []any{"operation", "retry", "attempt", 2, "delay", 15 * time.Millisecond, "error", errors.New("database is locked")}
+
+	var out bytes.Buffer
+	SetLogConfig(LogConfig{Format: LogFormatText, Output: &out})
+	logEvent(context.Background(), LogLevelInfo, attrs, "🔄 SQLite BUSY - retrying")
+	if line := out.String(); !strings.Contains(line, `operation=retry attempt=2 delay=15ms error="database is locked"`) {
+		t.Errorf("Expected operation fields in text line, got %q", line)
+	}
+
+	out.Reset()
+	SetLogConfig(LogConfig{Format: LogFormatJSON, Output: &out})
+	logEvent(context.Background(), LogLevelInfo, attrs, "🔄 SQLite BUSY - retrying")
+	var entry map[string]any
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON line %q: %v", out.String(), err)
+	}
+	if entry["operation"] != "retry" || entry["attempt"] != float64(2) || entry["delay"] != "15ms" || entry["error"] != "database is locked" {
+		t.Errorf("Unexpected JSON entry: %v", entry)
+	}
+
+	var handled bytes.Buffer
+	SetLogConfig(LogConfig{Logger: slog.New(slog.NewJSONHandler(&handled, nil))})
+	logEvent(context.Background(), LogLevelInfo, []any{"operation", "migration_completed", "source", "billing"}, "✅ Completed migrations for: %s", "billing")
+	entry = nil
+	if err := json.Unmarshal(handled.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse slog line %q: %v", handled.String(), err)
+	}
+	if entry["operation"] != "migration_completed" || entry["source"] != "billing" || entry["msg"] != "Completed migrations for: billing" {
+		t.Errorf("Unexpected slog entry: %v", entry)
+	}
+
+	out.Reset()
+	SetLogConfig(LogConfig{Output: &out})
+	logEvent(context.Background(), LogLevelInfo, attrs, "🔄 SQLite BUSY - retrying")
+	if line := out.String(); !strings.Contains(line, "🔄 SQLite BUSY - retrying") || strings.Contains(line, "operation=") {
+		t.Errorf("Expected the emoji format unchanged, got %q", line)
+	}
+}
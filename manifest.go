@@ -0,0 +1,82 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSourceManifestMismatch is returned when the registered migration sources differ
+// from the manifest declared with ListExpectedSources
+var ErrSourceManifestMismatch = errors.New("registered migration sources do not match manifest")
+
+// SourceManifestError lists the sources that differ from the manifest
+type SourceManifestError struct {
+	Missing    []string // Expected but never registered, e.g. a module whose init() wasn't imported
+	Unexpected []string // Registered but not in the manifest
+}
+
+func (e *SourceManifestError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unexpected) > 0 {
+		parts = append(parts, "unexpected: "+strings.Join(e.Unexpected, ", "))
+	}
+	return fmt.Sprintf("%v (%s)", ErrSourceManifestMismatch, strings.Join(parts, "; "))
+}
+
+func (e *SourceManifestError) Unwrap() error {
+	return ErrSourceManifestMismatch
+}
+
+// ListExpectedSources declares the migration sources the binary must register. Once set,
+// UpAll and UpAllContext fail fast with a *SourceManifestError before migrating anything
+// if a source is missing or unexpected. Passing nil clears the manifest.
+func ListExpectedSources(names []string) {
+	globalRegistry.mu.Lock()
+	defer globalRegistry.mu.Unlock()
+
+	if names == nil {
+		globalRegistry.expected = nil
+		return
+	}
+	globalRegistry.expected = append([]string{}, names...)
+	logf("📋 Expecting %d migration sources: %s", len(names), strings.Join(names, ", "))
+}
+
+// CheckRegisteredSources compares the registered sources with the manifest declared with
+// ListExpectedSources. It returns nil when no manifest is declared.
+func CheckRegisteredSources() error {
+	globalRegistry.mu.RLock()
+	defer globalRegistry.mu.RUnlock()
+
+	if globalRegistry.expected == nil {
+		return nil
+	}
+
+	registered := make(map[string]bool, len(globalRegistry.sources))
+	for _, source := range globalRegistry.sources {
+		registered[source.Name] = true
+	}
+	expected := make(map[string]bool, len(globalRegistry.expected))
+	manifestErr := &SourceManifestError{}
+	for _, name := range globalRegistry.expected {
+		expected[name] = true
+		if !registered[name] {
+			manifestErr.Missing = append(manifestErr.Missing, name)
+		}
+	}
+	for _, source := range globalRegistry.sources {
+		if !expected[source.Name] {
+			manifestErr.Unexpected = append(manifestErr.Unexpected, source.Name)
+		}
+	}
+
+	if len(manifestErr.Missing) > 0 || len(manifestErr.Unexpected) > 0 {
+		logErrorf("❌ %v", manifestErr)
+		return manifestErr
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestUpAllFailsFastOnManifestMismatch verifies that a forgotten or unexpected
+// registration is reported before any migration runs
+func TestUpAllFailsFastOnManifestMismatch(t *testing.T) {
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+		ListExpectedSources(nil)
+	}()
+
+	ListExpectedSources([]string{"core", "billing"})
+	RegisterMigrations(MigrationSource{Name: "core", Directory: "/nonexistent"})
+	RegisterMigrations(MigrationSource{Name: "reports", Directory: "/nonexistent"})
+
+	err := UpAll()
+	if !errors.Is(err, ErrSourceManifestMismatch) {
+		t.Fatalf("Expected ErrSourceManifestMismatch, got %v", err)
+	}
+	var manifestErr *SourceManifestError
+	if !errors.As(err, &manifestErr) {
+		t.Fatalf("Expected *SourceManifestError, got %T", err)
+	}
+	if !reflect.DeepEqual(manifestErr.Missing, []string{"billing"}) || !reflect.DeepEqual(manifestErr.Unexpected, []string{"reports"}) {
+		t.Errorf("Unexpected mismatch: missing %v, unexpected %v", manifestErr.Missing, manifestErr.Unexpected)
+	}
+
+	ListExpectedSources([]string{"core", "reports"})
+	if err := CheckRegisteredSources(); err != nil {
+		t.Errorf("Expected manifest to match, got %v", err)
+	}
+
+	ListExpectedSources(nil)
+	RegisterMigrations(MigrationSource{Name: "extra", Directory: "/nonexistent"})
+	if err := CheckRegisteredSources(); err != nil {
+		t.Errorf("Expected no check without a manifest, got %v", err)
+	}
+}
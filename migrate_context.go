@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// DefaultMigrationTimeout is the per-migration timeout used by UpAllContext
+// when a source does not set one
+const DefaultMigrationTimeout = 5 * time.Minute
+
+// UpAllContext runs all migrations from all registered sources one migration at a time.
+// Each migration runs under ctx and the source's per-migration timeout; when either
+// expires, the running statement is interrupted and an error is returned.
+func UpAllContext(ctx context.Context) error {
+	logf("🚀 Running all migrations from registered sources...")
+
+	if err := CheckRegisteredSources(); err != nil {
+		return err
+	}
+	if err := preflightDiskSpace(ctx); err != nil {
+		return err
+	}
+
+	sources := GetRegisteredSources()
+	if len(sources) == 0 {
+		logWarnf("⚠️  No migration sources registered")
+		return nil
+	}
+
+	err := runSources(sources, withReport(ctx, withValidations(ctx, func(source MigrationSource) error {
+		if source.group != nil {
+			timeout := source.Timeout
+			if timeout <= 0 {
+				timeout = DefaultMigrationTimeout
+			}
+			groupCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			if err := runMigrationGroup(groupCtx, source.group, sourceDatabaseFile(source)); err != nil {
+				return fmt.Errorf("failed to run migration group %s: %w", source.Name, err)
+			}
+			return nil
+		}
+		source, err := resolveRemoteSource(ctx, source)
+		if err != nil {
+			return err
+		}
+		if err := verifyMigrationSignatures(source); err != nil {
+			return err
+		}
+		if source.EmbedFS == nil && source.Directory == "" {
+			logEvent(ctx, LogLevelWarn, []any{"operation", "migration_skipped", "source", source.Name}, "⚠️  No migration source (directory or embed) specified for: %s", source.Name)
+			recordSkipped(ctx, source.Name, "no migration source (directory or embed) specified")
+			return nil
+		}
+
+		logEvent(ctx, LogLevelInfo, []any{"operation", "migration_started", "source", source.Name}, "📦 Processing migrations from: %s", source.Name)
+		if err := runSourceContext(ctx, source); err != nil {
+			return fmt.Errorf("failed to run migrations for %s: %w", source.Name, err)
+		}
+		logEvent(ctx, LogLevelInfo, []any{"operation", "migration_completed", "source", source.Name}, "✅ Completed migrations for: %s", source.Name)
+		return nil
+	})))
+	if err != nil {
+		return err
+	}
+
+	logf("🎉 All migrations completed successfully!")
+	return nil
+}
+
+// runSourceContext applies a source's pending migrations one step at a time
+func runSourceContext(ctx context.Context, source MigrationSource) error {
+	databaseFile := sourceDatabaseFile(source)
+	timeout := source.Timeout
+	if timeout <= 0 {
+		timeout = DefaultMigrationTimeout
+	}
+
+	connector, err := newInterruptibleConnector(databaseFile)
+	if err != nil {
+		return err
+	}
+	connector.setContext(ctx)
+
+	config := &sqlite.Config{}
+	if source.Prefix != "" {
+		config.MigrationsTable = source.Prefix + "schema_migrations"
+		logf("🏷️  Using prefixed schema table: %s", config.MigrationsTable)
+	}
+	dbDriver, err := sqlite.WithInstance(sql.OpenDB(connector), config)
+	if err != nil {
+		return fmt.Errorf("failed to open migration database: %w", err)
+	}
+
+	var m *migrate.Migrate
+	if source.EmbedFS != nil {
+		subPath := source.SubPath
+		if subPath == "" {
+			subPath = "."
+		}
+		srcDriver, err := iofs.New(*source.EmbedFS, subPath)
+		if err != nil {
+			dbDriver.Close()
+			return fmt.Errorf("failed to create iofs driver: %w", err)
+		}
+		m, err = migrate.NewWithInstance("iofs", srcDriver, "sqlite", dbDriver)
+		if err != nil {
+			dbDriver.Close()
+			return fmt.Errorf("failed to initialize migrate with embedded FS: %w", err)
+		}
+	} else {
+		m, err = migrate.NewWithDatabaseInstance("file://"+source.Directory, "sqlite", dbDriver)
+		if err != nil {
+			dbDriver.Close()
+			return fmt.Errorf("failed to initialize migrate: %w", err)
+		}
+	}
+	defer m.Close()
+
+	// Versions applied by this run, reverted when RollbackOnFailure is set
+	var applied []uint
+	startVersion, _, startErr := m.Version()
+	hasStart := startErr == nil
+
+	// Migration names by version, for progress reporting
+	names := make(map[uint64]string)
+	if migrations, err := loadUpMigrations(source); err == nil {
+		for _, migration := range migrations {
+			names[migration.version] = migration.name
+		}
+	}
+	next := func() (uint64, string) {
+		current, _, err := m.Version()
+		var version uint64
+		for v := range names {
+			if (err != nil || v > uint64(current)) && (version == 0 || v < version) {
+				version = v
+			}
+		}
+		return version, names[version]
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		version, name := next()
+		_, doneProgress := startMigrationProgress(ctx, source.Name, version, name, databaseFile)
+		started := time.Now()
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		connector.setContext(stepCtx)
+		err := m.Steps(1)
+		stepErr := stepCtx.Err()
+		cancel()
+		doneProgress()
+		connector.setContext(ctx)
+
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			if stepErr != nil {
+				version, dirty, _ := m.Version()
+				err = fmt.Errorf("migration after version %d interrupted (dirty: %t): %w", version, dirty, stepErr)
+			}
+			if source.RollbackOnFailure {
+				return rollbackBatch(ctx, m, connector, source.Name, applied, startVersion, hasStart, err)
+			}
+			return err
+		}
+		if version, _, err := m.Version(); err == nil {
+			applied = append(applied, version)
+			recordApplied(ctx, source.Name, AppliedMigration{Version: uint64(version), Name: names[uint64(version)], Duration: time.Since(started)})
+		}
+	}
+}
+
+// rollbackBatch restores the schema from before a failed run: it clears the dirty flag
+// left by the failed migration (whose transaction was rolled back) and runs the down
+// files of the migrations applied earlier in the run, newest first
+func rollbackBatch(ctx context.Context, m *migrate.Migrate, connector *interruptibleConnector, name string, applied []uint, startVersion uint, hasStart bool, cause error) error {
+	// The rollback must run even when ctx was canceled or timed out
+	connector.setContext(context.WithoutCancel(ctx))
+	logEvent(ctx, LogLevelWarn, []any{"operation", "migration_rollback", "source", name, "migrations", len(applied), "error", cause},
+		"⏪ Rolling back %d migrations of %s after failure: %v", len(applied), name, cause)
+
+	forceVersion := -1
+	if len(applied) > 0 {
+		forceVersion = int(applied[len(applied)-1])
+	} else if hasStart {
+		forceVersion = int(startVersion)
+	}
+	if err := m.Force(forceVersion); err != nil {
+		return fmt.Errorf("%w (rollback failed: %v)", cause, err)
+	}
+	if len(applied) > 0 {
+		if err := m.Steps(-len(applied)); err != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", cause, err)
+		}
+	}
+
+	logEvent(ctx, LogLevelInfo, []any{"operation", "migration_rolled_back", "source", name, "migrations", len(applied)}, "✅ Rolled back %s to its pre-deploy schema", name)
+	return fmt.Errorf("%w (rolled back %d migrations)", cause, len(applied))
+}
+
+// interruptibleConnector opens SQLite connections whose statements run under a
+// swappable context, so golang-migrate's context-free calls can be interrupted
+type interruptibleConnector struct {
+	driver driver.Driver
+	dsn    string
+
+	mu  sync.RWMutex
+	ctx context.Context
+}
+
+// newInterruptibleConnector creates a connector for the given database file
+func newInterruptibleConnector(databaseFile string) (*interruptibleConnector, error) {
+	db, err := sql.Open("sqlite", buildDSN(databaseFile))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return &interruptibleConnector{driver: db.Driver(), dsn: buildDSN(databaseFile), ctx: context.Background()}, nil
+}
+
+// setContext sets the context used by statements on this connector's connections
+func (c *interruptibleConnector) setContext(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = ctx
+}
+
+// context returns the context statements currently run under
+func (c *interruptibleConnector) context() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ctx
+}
+
+func (c *interruptibleConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &interruptibleConn{Conn: conn, connector: c}, nil
+}
+
+func (c *interruptibleConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// interruptibleConn replaces the caller's context with the connector's context
+type interruptibleConn struct {
+	driver.Conn
+	connector *interruptibleConnector
+}
+
+func (c *interruptibleConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return execer.ExecContext(c.connector.context(), query, args)
+}
+
+func (c *interruptibleConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return queryer.QueryContext(c.connector.context(), query, args)
+}
+
+func (c *interruptibleConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(c.connector.context(), opts)
+	}
+	return c.Conn.Begin() // Fallback for drivers without BeginTx
+}
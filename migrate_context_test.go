@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestUpAllContextInterruptsHungMigration verifies that a migration exceeding its
+// timeout is interrupted instead of blocking forever, and earlier migrations stay applied
+func TestUpAllContextInterruptsHungMigration(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "hung.db"))
+
+	migrations := map[string]string{
+		"001_create_items.up.sql":   "CREATE TABLE items (id INTEGER PRIMARY KEY);",
+		"001_create_items.down.sql": "DROP TABLE items;",
+		"002_hang.up.sql":           "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n) SELECT count(*) FROM n;",
+		"002_hang.down.sql":         "SELECT 1;",
+	}
+	for name, content := range migrations {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write migration %s: %v", name, err)
+		}
+	}
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+
+	RegisterMigrations(MigrationSource{
+		Name:      "test-hung",
+		Directory: tempDir,
+		Timeout:   200 * time.Millisecond,
+	})
+
+	started := time.Now()
+	if err := UpAllContext(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the hung migration to time out, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Hung migration was not interrupted promptly (took %v)", elapsed)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'items'").Scan(&count); err != nil {
+		t.Fatalf("Failed to check items table: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected migration 001 to remain applied")
+	}
+}
@@ -0,0 +1,315 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MigrationGroup migrates several sources as one unit: all pending migrations of all
+// member sources are applied in a single transaction under one write lock, so a product
+// composed of many plugins either fully migrates or not at all. Members keep their own
+// migration files; versions are tracked per member in <Prefix>group_migrations.
+// Migration files in a group must not contain their own BEGIN/COMMIT.
+type MigrationGroup struct {
+	Name      string
+	Prefix    string            // Prefix of the group's tracking table (e.g., "product_")
+	Database  string            // Database file to migrate (default: DATABASE_FILE)
+	DependsOn []string          // Names of sources or groups that must be migrated first
+	Timeout   time.Duration     // Timeout for the whole group under UpAllContext (default: DefaultMigrationTimeout)
+	Sources   []MigrationSource // Members in apply order; their Prefix and Database are ignored
+
+	Validations []MigrationValidation // Smoke tests that must pass after the group migrates
+}
+
+// MigrationGroupStatus is the combined migration state of a group
+type MigrationGroupStatus struct {
+	Name    string
+	Table   string
+	Applied int
+	Pending int
+	Sources []MigrationGroupSourceStatus
+}
+
+// MigrationGroupSourceStatus is the migration state of one member of a group
+type MigrationGroupSourceStatus struct {
+	Name           string
+	Applied        int
+	Pending        int
+	CurrentVersion uint64 // Highest applied version (0 when none)
+}
+
+// groupMigration is one up migration of a source or group member
+type groupMigration struct {
+	source  string
+	version uint64
+	name    string
+	sql     string
+}
+
+// upMigrationFile matches golang-migrate style up files, e.g. 001_create_users.up.sql
+var upMigrationFile = regexp.MustCompile(`^(\d+)_(.+)\.up\.sql$`)
+
+// RegisterMigrationGroup registers a group with the global registry. It is scheduled by
+// UpAll and UpAllContext like a single source named after the group.
+func RegisterMigrationGroup(group MigrationGroup) {
+	logf("📦 Registering migration group: %s (%d sources)", group.Name, len(group.Sources))
+	RegisterMigrations(MigrationSource{
+		Name:        group.Name,
+		Database:    group.Database,
+		DependsOn:   group.DependsOn,
+		Timeout:     group.Timeout,
+		Validations: group.Validations,
+		group:       &group,
+	})
+}
+
+// groupTable returns the group's tracking table name
+func (g *MigrationGroup) groupTable() string {
+	return g.Prefix + "group_migrations"
+}
+
+// memberFS returns the filesystem holding a member's migration files
+func memberFS(source MigrationSource) (fs.FS, error) {
+	if source.EmbedFS != nil {
+		subPath := source.SubPath
+		if subPath == "" {
+			subPath = "."
+		}
+		return fs.Sub(source.EmbedFS, subPath)
+	}
+	if source.Directory != "" {
+		return os.DirFS(source.Directory), nil
+	}
+	return nil, fmt.Errorf("migration source %s has neither Directory nor EmbedFS specified", source.Name)
+}
+
+// loadGroupMigrations reads all members' up migrations, ordered by member then version
+func loadGroupMigrations(group *MigrationGroup) ([]groupMigration, error) {
+	var migrations []groupMigration
+	for _, source := range group.Sources {
+		if err := verifyMigrationSignatures(source); err != nil {
+			return nil, err
+		}
+		members, err := loadUpMigrations(source)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, members...)
+	}
+	return migrations, nil
+}
+
+// loadUpMigrations reads a source's up migrations, ordered by version
+func loadUpMigrations(source MigrationSource) ([]groupMigration, error) {
+	fsys, err := memberFS(source)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations for %s: %w", source.Name, err)
+	}
+
+	var migrations []groupMigration
+	seen := make(map[uint64]string)
+	for _, entry := range entries {
+		match := upMigrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migration source %s has duplicate version %d (%s, %s)", source.Name, version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, groupMigration{source: source.Name, version: version, name: match[2], sql: string(content)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// ensureGroupTable creates the group's tracking table
+func ensureGroupTable(ctx context.Context, conn *sql.Conn, group *MigrationGroup) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %q (
+		source TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (source, version)
+	)`, group.groupTable()))
+	return err
+}
+
+// appliedGroupVersions returns the applied versions of each member
+func appliedGroupVersions(ctx context.Context, conn *sql.Conn, group *MigrationGroup) (map[string]map[uint64]bool, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT source, version FROM %q", group.groupTable()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]map[uint64]bool)
+	for rows.Next() {
+		var source string
+		var version uint64
+		if err := rows.Scan(&source, &version); err != nil {
+			return nil, err
+		}
+		if applied[source] == nil {
+			applied[source] = make(map[uint64]bool)
+		}
+		applied[source][version] = true
+	}
+	return applied, rows.Err()
+}
+
+// runMigrationGroup applies all pending migrations of a group in one transaction
+func runMigrationGroup(ctx context.Context, group *MigrationGroup, databaseFile string) error {
+	migrations, err := loadGroupMigrations(group)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", buildDSN(databaseFile))
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	if err := ensureGroupTable(ctx, conn, group); err != nil {
+		return fmt.Errorf("failed to create %s: %w", group.groupTable(), err)
+	}
+
+	// BEGIN IMMEDIATE takes the write lock up front, so the group runs under one lock
+	err = retryDatabaseOperationContext(ctx, func() error {
+		_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+		return err
+	}, DefaultRetryConfig())
+	if err != nil {
+		return fmt.Errorf("failed to lock database for group %s: %w", group.Name, err)
+	}
+	rollback := func() {
+		if _, err := conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+			logErrorf("❌ Failed to rollback migration group %s: %v", group.Name, err)
+		}
+	}
+
+	applied, err := appliedGroupVersions(ctx, conn, group)
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to read %s: %w", group.groupTable(), err)
+	}
+
+	var done []AppliedMigration
+	for _, m := range migrations {
+		if applied[m.source][m.version] {
+			continue
+		}
+		logf("⬆️  Applying %s/%d_%s", m.source, m.version, m.name)
+		_, doneProgress := startMigrationProgress(ctx, m.source, m.version, m.name, databaseFile)
+		started := time.Now()
+		_, err := conn.ExecContext(ctx, m.sql)
+		doneProgress()
+		if err != nil {
+			rollback()
+			return fmt.Errorf("migration %s/%d_%s failed, group %s rolled back: %w", m.source, m.version, m.name, group.Name, err)
+		}
+		insert := fmt.Sprintf("INSERT INTO %q (source, version, name) VALUES (?, ?, ?)", group.groupTable())
+		if _, err := conn.ExecContext(ctx, insert, m.source, m.version, m.name); err != nil {
+			rollback()
+			return fmt.Errorf("failed to record migration %s/%d_%s: %w", m.source, m.version, m.name, err)
+		}
+		done = append(done, AppliedMigration{Version: m.version, Name: m.name, Member: m.source, Duration: time.Since(started)})
+	}
+
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		rollback()
+		return fmt.Errorf("failed to commit migration group %s: %w", group.Name, err)
+	}
+	// Migrations only count as applied once the group commits
+	for _, m := range done {
+		recordApplied(ctx, group.Name, m)
+	}
+	logf("✅ Migration group %s applied %d migrations", group.Name, len(done))
+	return nil
+}
+
+// GetMigrationGroupStatus returns the combined status of a registered group
+func GetMigrationGroupStatus(ctx context.Context, name string) (*MigrationGroupStatus, error) {
+	var group *MigrationGroup
+	var databaseFile string
+	for _, source := range GetRegisteredSources() {
+		if source.group != nil && source.Name == name {
+			group = source.group
+			databaseFile = sourceDatabaseFile(source)
+		}
+	}
+	if group == nil {
+		return nil, fmt.Errorf("unknown migration group: %s", name)
+	}
+
+	migrations, err := loadGroupMigrations(group)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", buildDSN(databaseFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	if err := ensureGroupTable(ctx, conn, group); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", group.groupTable(), err)
+	}
+	applied, err := appliedGroupVersions(ctx, conn, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", group.groupTable(), err)
+	}
+
+	status := &MigrationGroupStatus{Name: group.Name, Table: group.groupTable()}
+	for _, source := range group.Sources {
+		member := MigrationGroupSourceStatus{Name: source.Name}
+		for _, m := range migrations {
+			if m.source != source.Name {
+				continue
+			}
+			if applied[m.source][m.version] {
+				member.Applied++
+				member.CurrentVersion = max(member.CurrentVersion, m.version)
+			} else {
+				member.Pending++
+			}
+		}
+		status.Applied += member.Applied
+		status.Pending += member.Pending
+		status.Sources = append(status.Sources, member)
+	}
+	return status, nil
+}
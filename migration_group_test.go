@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeMigrations writes migration files into a new directory under dir
+func writeMigrations(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", dir, err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write migration %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestMigrationGroupIsAtomic verifies that a failing plugin migration rolls back every
+// member's migrations, and that the group applies once fixed
+func TestMigrationGroupIsAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "group.db"))
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+
+	core := writeMigrations(t, filepath.Join(tempDir, "core"), map[string]string{
+		"001_create_accounts.up.sql": "CREATE TABLE accounts (id INTEGER PRIMARY KEY);",
+		"002_add_name.up.sql":        "ALTER TABLE accounts ADD COLUMN name TEXT;",
+	})
+	billing := writeMigrations(t, filepath.Join(tempDir, "billing"), map[string]string{
+		"001_create_invoices.up.sql": "CREATE TABLE invoices (id INTEGER PRIMARY KEY, account_id INTEGER);",
+		"002_broken.up.sql":          "ALTER TABLE missing ADD COLUMN x TEXT;",
+	})
+
+	RegisterMigrationGroup(MigrationGroup{
+		Name:   "product",
+		Prefix: "product_",
+		Sources: []MigrationSource{
+			{Name: "core", Directory: core},
+			{Name: "billing", Directory: billing},
+		},
+	})
+
+	if err := UpAllContext(context.Background()); err == nil {
+		t.Fatal("Expected the broken billing migration to fail the group")
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('accounts', 'invoices')").Scan(&count); err != nil {
+		t.Fatalf("Failed to check tables: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the whole group to roll back, found %d tables", count)
+	}
+
+	status, err := GetMigrationGroupStatus(context.Background(), "product")
+	if err != nil {
+		t.Fatalf("Failed to get group status: %v", err)
+	}
+	if status.Applied != 0 || status.Pending != 4 {
+		t.Errorf("Expected 0 applied and 4 pending, got %d and %d", status.Applied, status.Pending)
+	}
+
+	writeMigrations(t, billing, map[string]string{
+		"002_broken.up.sql": "ALTER TABLE invoices ADD COLUMN total INTEGER;",
+	})
+	if err := UpAll(); err != nil {
+		t.Fatalf("Failed to migrate fixed group: %v", err)
+	}
+
+	status, err = GetMigrationGroupStatus(context.Background(), "product")
+	if err != nil {
+		t.Fatalf("Failed to get group status: %v", err)
+	}
+	if status.Table != "product_group_migrations" || status.Applied != 4 || status.Pending != 0 {
+		t.Errorf("Unexpected group status: %+v", status)
+	}
+	for _, member := range status.Sources {
+		if member.CurrentVersion != 2 {
+			t.Errorf("Expected %s at version 2, got %d", member.Name, member.CurrentVersion)
+		}
+	}
+
+	// Re-running is a no-op
+	if err := UpAll(); err != nil {
+		t.Fatalf("Failed to re-run group: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrInsufficientDiskSpace is returned by UpAll and UpAllContext when pending migrations
+// that rebuild tables or build indexes are estimated to need more space than is free
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space for pending migrations")
+
+// preflightMargin is the extra fraction of the estimate required to be free
+const preflightMargin = 0.2
+
+// preflightDiskUsage reports volume space for the preflight (replaced in tests)
+var preflightDiskUsage = diskUsage
+
+// DiskPreflight is the estimated space pending migrations need on one database's volume
+type DiskPreflight struct {
+	Database       string
+	Volume         string
+	RequiredBytes  uint64 // Pages written to the WAL and checkpointed into the database, plus margin
+	AvailableBytes uint64
+	TempDir        string
+	TempBytes      uint64 // Sorter and rebuild temp files, plus margin
+	TempAvailable  uint64
+}
+
+// sqliteTempDir returns the directory SQLite writes temporary files to
+func sqliteTempDir() string {
+	if dir := configValue("SQLITE_TMPDIR"); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// PreflightMigrations estimates the disk space pending migrations need and returns
+// ErrInsufficientDiskSpace when a database or temp volume cannot hold it, so a deploy is
+// refused up front instead of failing midway and leaving a dirty migration on a full volume.
+// Migrations that only change the schema skip the (table-scanning) estimate.
+func PreflightMigrations(ctx context.Context) ([]DiskPreflight, error) {
+	plan, err := PlanMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	heavy := false
+	for _, m := range plan.Migrations {
+		for _, statement := range splitStatements(m.SQL) {
+			if estimateStatement(statement, nil).Kind != ImpactSchemaOnly {
+				heavy = true
+			}
+		}
+	}
+	if !heavy {
+		return nil, nil
+	}
+
+	var checks []DiskPreflight
+	byDatabase := make(map[string]*MigrationPlan)
+	var order []string
+	for _, m := range plan.Migrations {
+		if byDatabase[m.Database] == nil {
+			byDatabase[m.Database] = &MigrationPlan{}
+			order = append(order, m.Database)
+		}
+		byDatabase[m.Database].Migrations = append(byDatabase[m.Database].Migrations, m)
+	}
+
+	for _, database := range order {
+		report, err := EstimateImpact(ctx, byDatabase[database])
+		if err != nil {
+			return nil, err
+		}
+		for _, statement := range report.Statements {
+			for _, warning := range statement.Warnings {
+				recordWarning(ctx, statement.Source, fmt.Sprintf("%d_%s: %s", statement.Version, statement.Migration, warning))
+			}
+		}
+		check := DiskPreflight{
+			Database:      database,
+			Volume:        filepath.Dir(database),
+			RequiredBytes: uint64(float64(2*report.EstimatedDiskBytes) * (1 + preflightMargin)),
+			TempDir:       sqliteTempDir(),
+			TempBytes:     uint64(float64(report.EstimatedTempBytes) * (1 + preflightMargin)),
+		}
+
+		volumeTotal, volumeFree, err := preflightDiskUsage(check.Volume)
+		if err != nil {
+			logWarnf("⚠️  Skipping disk preflight for %s: %v", database, err)
+			continue
+		}
+		tempTotal, tempFree, err := preflightDiskUsage(check.TempDir)
+		if err != nil {
+			logWarnf("⚠️  Skipping temp space preflight for %s: %v", check.TempDir, err)
+			tempTotal, tempFree, check.TempBytes = volumeTotal, volumeFree, 0
+		}
+		check.AvailableBytes, check.TempAvailable = volumeFree, tempFree
+		checks = append(checks, check)
+
+		// Identical totals and free space mean both directories share a volume
+		sameVolume := volumeTotal == tempTotal && volumeFree == tempFree
+		switch {
+		case sameVolume && check.RequiredBytes+check.TempBytes > volumeFree:
+			return checks, fmt.Errorf("%w: %s needs ~%d bytes (including %d temp) but %s has %d free",
+				ErrInsufficientDiskSpace, database, check.RequiredBytes+check.TempBytes, check.TempBytes, check.Volume, volumeFree)
+		case check.RequiredBytes > volumeFree:
+			return checks, fmt.Errorf("%w: %s needs ~%d bytes but %s has %d free",
+				ErrInsufficientDiskSpace, database, check.RequiredBytes, check.Volume, volumeFree)
+		case !sameVolume && check.TempBytes > tempFree:
+			return checks, fmt.Errorf("%w: %s needs ~%d temp bytes but %s has %d free (set SQLITE_TMPDIR to a larger volume)",
+				ErrInsufficientDiskSpace, database, check.TempBytes, check.TempDir, tempFree)
+		}
+		logf("💽 Disk preflight passed for %s: ~%d bytes needed, %d free", database, check.RequiredBytes+check.TempBytes, volumeFree)
+	}
+	return checks, nil
+}
+
+// preflightDiskSpace runs PreflightMigrations before UpAll and UpAllContext unless
+// DATABASE_MIGRATION_PREFLIGHT is "false"
+func preflightDiskSpace(ctx context.Context) error {
+	if configValue("DATABASE_MIGRATION_PREFLIGHT") == "false" {
+		return nil
+	}
+	_, err := PreflightMigrations(ctx)
+	return err
+}
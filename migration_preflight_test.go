@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestPreflightRefusesIndexBuildOnFullVolume verifies that an index build is refused
+// before any migration runs when the volume cannot hold it
+func TestPreflightRefusesIndexBuildOnFullVolume(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "preflight.db"))
+	dir := writeMigrations(t, filepath.Join(tempDir, "logs"), map[string]string{
+		"001_create_logs.up.sql": "CREATE TABLE logs (id INTEGER PRIMARY KEY, line TEXT);",
+	})
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+	RegisterMigrations(MigrationSource{Name: "logs", Directory: dir})
+	if err := UpAll(); err != nil {
+		t.Fatalf("Failed to apply first migration: %v", err)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 2000)
+		INSERT INTO logs (line) SELECT printf('%0100d', x) FROM n`); err != nil {
+		t.Fatalf("Failed to seed logs: %v", err)
+	}
+
+	writeMigrations(t, dir, map[string]string{
+		"002_index_lines.up.sql": "CREATE INDEX idx_logs_line ON logs (line);",
+	})
+
+	defer func(original func(string) (uint64, uint64, error)) { preflightDiskUsage = original }(preflightDiskUsage)
+	preflightDiskUsage = func(string) (uint64, uint64, error) { return 1 << 30, 4096, nil }
+
+	if err := UpAllContext(context.Background()); !errors.Is(err, ErrInsufficientDiskSpace) {
+		t.Fatalf("Expected ErrInsufficientDiskSpace, got %v", err)
+	}
+	var version int
+	if err := db.QueryRow("SELECT version FROM schema_migrations").Scan(&version); err != nil || version != 1 {
+		t.Errorf("Expected version 1 to remain clean, got %d (%v)", version, err)
+	}
+
+	preflightDiskUsage = func(string) (uint64, uint64, error) { return 1 << 40, 1 << 39, nil }
+	if err := UpAll(); err != nil {
+		t.Fatalf("Expected migration to apply with enough space, got %v", err)
+	}
+}
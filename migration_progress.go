@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMigrationProgressInterval is how often running migrations log a heartbeat
+const DefaultMigrationProgressInterval = 10 * time.Second
+
+// MigrationProgress is the state of a running migration or data migration
+type MigrationProgress struct {
+	Source    string        `json:"source"`
+	Version   uint64        `json:"version,omitempty"`
+	Migration string        `json:"migration"`
+	Done      int64         `json:"done"`
+	Total     int64         `json:"total"` // 0 when unknown
+	Unit      string        `json:"unit"`  // "rows" when reported, "wal_bytes" when observed from the WAL
+	StartedAt time.Time     `json:"started_at"`
+	UpdatedAt time.Time     `json:"updated_at"` // Last time progress advanced
+	Elapsed   time.Duration `json:"elapsed_ns"`
+	Stalled   bool          `json:"stalled"` // No progress for three heartbeat intervals
+}
+
+// migrationTask tracks one running migration
+type migrationTask struct {
+	mu       sync.Mutex
+	progress MigrationProgress
+	walPath  string
+	walStart int64
+	reported bool // Progress comes from ReportMigrationProgress rather than the WAL
+}
+
+// migrationTaskKey carries the running migration in a context
+type migrationTaskKey struct{}
+
+var (
+	progressMu    sync.RWMutex
+	runningTasks  = make(map[*migrationTask]struct{})
+	progressHooks []func(MigrationProgress)
+)
+
+// OnMigrationProgress registers a hook called on every heartbeat and progress report,
+// e.g. to forward deploy events to deploy tooling
+func OnMigrationProgress(hook func(MigrationProgress)) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	progressHooks = append(progressHooks, hook)
+}
+
+// RunningMigrations returns the progress of migrations currently running in this process
+func RunningMigrations() []MigrationProgress {
+	progressMu.RLock()
+	defer progressMu.RUnlock()
+
+	running := make([]MigrationProgress, 0, len(runningTasks))
+	for task := range runningTasks {
+		running = append(running, task.snapshot())
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].StartedAt.Before(running[j].StartedAt) })
+	return running
+}
+
+// TrackMigrationProgress marks a long-running Go data migration as running, so it is
+// logged, listed by RunningMigrations and the admin API, and can report progress with
+// ReportMigrationProgress on the returned context. Call done when it finishes.
+func TrackMigrationProgress(ctx context.Context, source, name string) (context.Context, func()) {
+	return startMigrationProgress(ctx, source, 0, name, "")
+}
+
+// ReportMigrationProgress records that done of total units (0 when unknown) have been
+// processed by the migration tracked on ctx. It does nothing outside a tracked migration.
+func ReportMigrationProgress(ctx context.Context, done, total int64, unit string) {
+	task, ok := ctx.Value(migrationTaskKey{}).(*migrationTask)
+	if !ok {
+		return
+	}
+	task.mu.Lock()
+	task.reported = true
+	task.progress.Done, task.progress.Total, task.progress.Unit = done, total, unit
+	task.progress.UpdatedAt = time.Now()
+	task.mu.Unlock()
+	notifyMigrationProgress(task.snapshot())
+}
+
+// migrationProgressInterval returns the heartbeat interval (DATABASE_MIGRATION_PROGRESS_INTERVAL)
+func migrationProgressInterval() time.Duration {
+	if value := configValue("DATABASE_MIGRATION_PROGRESS_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+			return interval
+		}
+	}
+	return DefaultMigrationProgressInterval
+}
+
+// startMigrationProgress tracks a migration until the returned func is called. With a
+// database file, progress of SQL migrations is observed from the growth of its WAL.
+func startMigrationProgress(ctx context.Context, source string, version uint64, name, databaseFile string) (context.Context, func()) {
+	now := time.Now()
+	task := &migrationTask{progress: MigrationProgress{Source: source, Version: version, Migration: name, StartedAt: now, UpdatedAt: now}}
+	if databaseFile != "" {
+		task.walPath = databaseFile + "-wal"
+		task.walStart = fileSize(task.walPath)
+		task.progress.Unit = "wal_bytes"
+	}
+
+	progressMu.Lock()
+	runningTasks[task] = struct{}{}
+	progressMu.Unlock()
+
+	interval := migrationProgressInterval()
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				task.heartbeat(interval)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return context.WithValue(ctx, migrationTaskKey{}, task), func() {
+		once.Do(func() {
+			close(stop)
+			progressMu.Lock()
+			delete(runningTasks, task)
+			progressMu.Unlock()
+		})
+	}
+}
+
+// heartbeat samples WAL growth, logs the migration's state and notifies hooks
+func (t *migrationTask) heartbeat(interval time.Duration) {
+	t.mu.Lock()
+	if !t.reported && t.walPath != "" {
+		// The WAL is reset by checkpoints, so only growth counts as progress
+		if written := fileSize(t.walPath) - t.walStart; written > t.progress.Done {
+			t.progress.Done = written
+			t.progress.UpdatedAt = time.Now()
+		}
+	}
+	t.mu.Unlock()
+
+	p := t.snapshot()
+	label := p.Source + "/" + p.Migration
+	switch {
+	case p.Stalled:
+		logWarnf("⚠️  Migration %s running for %v with no progress for %v", label, p.Elapsed.Round(time.Second), time.Since(p.UpdatedAt).Round(time.Second))
+	case p.Total > 0:
+		logf("⏳ Migration %s running for %v: %d/%d %s", label, p.Elapsed.Round(time.Second), p.Done, p.Total, p.Unit)
+	default:
+		logf("⏳ Migration %s running for %v: %d %s", label, p.Elapsed.Round(time.Second), p.Done, p.Unit)
+	}
+	notifyMigrationProgress(p)
+}
+
+// snapshot returns a copy of the task's progress with derived fields filled in
+func (t *migrationTask) snapshot() MigrationProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.progress
+	p.Elapsed = time.Since(p.StartedAt)
+	p.Stalled = time.Since(p.UpdatedAt) >= 3*migrationProgressInterval()
+	return p
+}
+
+// notifyMigrationProgress calls the registered progress hooks
+func notifyMigrationProgress(p MigrationProgress) {
+	progressMu.RLock()
+	hooks := append([]func(MigrationProgress){}, progressHooks...)
+	progressMu.RUnlock()
+	for _, hook := range hooks {
+		hook(p)
+	}
+}
+
+// fileSize returns the size of a file, or 0 when it does not exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
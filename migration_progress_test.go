@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestMigrationProgressHeartbeats verifies that a long SQL migration emits heartbeats
+// naming it, with progress observed from WAL growth
+func TestMigrationProgressHeartbeats(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "progress.db"))
+	t.Setenv("DATABASE_MIGRATION_PROGRESS_INTERVAL", "10ms")
+	dir := writeMigrations(t, filepath.Join(tempDir, "bulk"), map[string]string{
+		"001_create_samples.up.sql": "CREATE TABLE samples (id INTEGER PRIMARY KEY, value TEXT);",
+		"002_load_samples.up.sql": `WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 300000)
+			INSERT INTO samples (value) SELECT printf('%0100d', x) FROM n;`,
+	})
+
+	var mu sync.Mutex
+	var events []MigrationProgress
+	OnMigrationProgress(func(p MigrationProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, p)
+	})
+	defer func() {
+		progressMu.Lock()
+		progressHooks = nil
+		progressMu.Unlock()
+	}()
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+	RegisterMigrations(MigrationSource{Name: "bulk", Directory: dir})
+	if err := UpAllContext(context.Background()); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var load *MigrationProgress
+	for i := range events {
+		if events[i].Migration == "load_samples" {
+			load = &events[i]
+		}
+	}
+	if load == nil {
+		t.Fatalf("Expected heartbeats for load_samples, got %+v", events)
+	}
+	if load.Source != "bulk" || load.Version != 2 || load.Unit != "wal_bytes" {
+		t.Errorf("Unexpected heartbeat: %+v", load)
+	}
+	if len(RunningMigrations()) != 0 {
+		t.Errorf("Expected no running migrations after completion, got %+v", RunningMigrations())
+	}
+}
+
+// TestReportMigrationProgressViaAdminAPI verifies that data migrations report progress
+// that is listed by the admin API
+func TestReportMigrationProgressViaAdminAPI(t *testing.T) {
+	ctx, done := TrackMigrationProgress(context.Background(), "billing", "backfill_totals")
+	defer done()
+	ReportMigrationProgress(ctx, 250, 1000, "rows")
+
+	server := httptest.NewServer(NewAdminHandler(AdminOptions{}))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/migrations/progress")
+	if err != nil {
+		t.Fatalf("Failed to query admin API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Migrations []MigrationProgress `json:"migrations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(body.Migrations) != 1 {
+		t.Fatalf("Expected one running migration, got %+v", body.Migrations)
+	}
+	if p := body.Migrations[0]; p.Migration != "backfill_totals" || p.Done != 250 || p.Total != 1000 || p.Unit != "rows" {
+		t.Errorf("Unexpected progress: %+v", p)
+	}
+
+	// Reporting outside a tracked migration is a no-op
+	ReportMigrationProgress(context.Background(), 1, 1, "rows")
+}
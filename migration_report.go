@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Source statuses in a MigrationReport
+const (
+	SourceApplied  = "applied"    // At least one migration was applied
+	SourceUpToDate = "up_to_date" // Nothing was pending
+	SourceSkipped  = "skipped"    // The source was not run (see Warnings)
+	SourceFailed   = "failed"     // See Error
+)
+
+// MigrationReport describes what UpAllWithReport did, for deploy tooling to emit as
+// structured deploy events
+type MigrationReport struct {
+	StartedAt time.Time      `json:"started_at"`
+	Duration  time.Duration  `json:"duration_ns"`
+	Sources   []SourceReport `json:"sources"`
+	Warnings  []string       `json:"warnings,omitempty"` // Warnings not tied to a registered source
+}
+
+// SourceReport describes the migrations of one registered source or group
+type SourceReport struct {
+	Name     string             `json:"name"`
+	Database string             `json:"database"`
+	Status   string             `json:"status"`
+	Applied  []AppliedMigration `json:"applied,omitempty"`
+	Duration time.Duration      `json:"duration_ns"`
+	Warnings []string           `json:"warnings,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// AppliedMigration is a migration applied during the run
+type AppliedMigration struct {
+	Version  uint64        `json:"version"`
+	Name     string        `json:"name"`
+	Member   string        `json:"member,omitempty"` // Member source, for groups
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// migrationReportKey carries the report being collected in a context
+type migrationReportKey struct{}
+
+// reportRecorder collects a MigrationReport from concurrently migrating sources
+type reportRecorder struct {
+	mu     sync.Mutex
+	report *MigrationReport
+}
+
+// UpAllWithReport runs all migrations like UpAllContext and returns a report of each
+// source's applied versions, durations, skips and warnings. The report is returned
+// even when migrating fails, with the failing source marked SourceFailed.
+func UpAllWithReport(ctx context.Context) (*MigrationReport, error) {
+	recorder := &reportRecorder{report: &MigrationReport{StartedAt: time.Now()}}
+	for _, source := range GetRegisteredSources() {
+		recorder.report.Sources = append(recorder.report.Sources, SourceReport{
+			Name:     source.Name,
+			Database: sourceDatabaseFile(source),
+			Status:   SourceSkipped,
+		})
+	}
+
+	err := UpAllContext(context.WithValue(ctx, migrationReportKey{}, recorder))
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.report.Duration = time.Since(recorder.report.StartedAt)
+	return recorder.report, err
+}
+
+// reportFrom returns the recorder collecting a report on ctx, or nil
+func reportFrom(ctx context.Context) *reportRecorder {
+	recorder, _ := ctx.Value(migrationReportKey{}).(*reportRecorder)
+	return recorder
+}
+
+// update applies fn to the named source's report; it does nothing without a recorder
+func (r *reportRecorder) update(name string, fn func(*SourceReport)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.report.Sources {
+		if r.report.Sources[i].Name == name {
+			fn(&r.report.Sources[i])
+			return
+		}
+	}
+}
+
+// recordApplied adds an applied migration to a source's report
+func recordApplied(ctx context.Context, source string, migration AppliedMigration) {
+	reportFrom(ctx).update(source, func(s *SourceReport) {
+		s.Applied = append(s.Applied, migration)
+	})
+}
+
+// recordWarning adds a warning to a source's report, or to the report itself when the
+// source is not registered (e.g. a group member)
+func recordWarning(ctx context.Context, source, warning string) {
+	recorder := reportFrom(ctx)
+	if recorder == nil {
+		return
+	}
+	found := false
+	recorder.update(source, func(s *SourceReport) {
+		found = true
+		s.Warnings = append(s.Warnings, warning)
+	})
+	if !found {
+		recorder.mu.Lock()
+		recorder.report.Warnings = append(recorder.report.Warnings, source+": "+warning)
+		recorder.mu.Unlock()
+	}
+}
+
+// withReport records each source's status, duration and error around run
+func withReport(ctx context.Context, run func(MigrationSource) error) func(MigrationSource) error {
+	recorder := reportFrom(ctx)
+	if recorder == nil {
+		return run
+	}
+	return func(source MigrationSource) error {
+		started := time.Now()
+		recorder.update(source.Name, func(s *SourceReport) { s.Status = SourceUpToDate })
+		err := run(source)
+		recorder.update(source.Name, func(s *SourceReport) {
+			s.Duration = time.Since(started)
+			switch {
+			case err != nil:
+				s.Status = SourceFailed
+				s.Error = err.Error()
+			case s.Status == SourceSkipped:
+			case len(s.Applied) > 0:
+				s.Status = SourceApplied
+			}
+		})
+		return err
+	}
+}
+
+// recordSkipped marks a source as not run
+func recordSkipped(ctx context.Context, source, reason string) {
+	reportFrom(ctx).update(source, func(s *SourceReport) {
+		s.Status = SourceSkipped
+		s.Warnings = append(s.Warnings, reason)
+	})
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestUpAllWithReportDescribesEachSource verifies that the report lists applied
+// versions per source and marks up-to-date, skipped and failed sources
+func TestUpAllWithReportDescribesEachSource(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "report.db"))
+	shop := writeMigrations(t, filepath.Join(tempDir, "shop"), map[string]string{
+		"001_create_products.up.sql": "CREATE TABLE products (id INTEGER PRIMARY KEY);",
+		"002_create_orders.up.sql":   "CREATE TABLE orders (id INTEGER PRIMARY KEY);",
+	})
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+
+	RegisterMigrations(MigrationSource{Name: "shop", Directory: shop})
+	RegisterMigrations(MigrationSource{Name: "empty"})
+
+	report, err := UpAllWithReport(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if len(report.Sources) != 2 {
+		t.Fatalf("Expected 2 sources in report, got %d", len(report.Sources))
+	}
+	applied := report.Sources[0]
+	if applied.Status != SourceApplied || len(applied.Applied) != 2 {
+		t.Fatalf("Expected shop to apply 2 migrations, got %+v", applied)
+	}
+	if applied.Applied[1].Version != 2 || applied.Applied[1].Name != "create_orders" {
+		t.Errorf("Expected 2_create_orders, got %+v", applied.Applied[1])
+	}
+	if skipped := report.Sources[1]; skipped.Status != SourceSkipped || len(skipped.Warnings) == 0 {
+		t.Errorf("Expected empty source to be skipped with a warning, got %+v", skipped)
+	}
+
+	report, err = UpAllWithReport(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to rerun migrations: %v", err)
+	}
+	if status := report.Sources[0].Status; status != SourceUpToDate {
+		t.Errorf("Expected shop to be up to date, got %s", status)
+	}
+
+	writeMigrations(t, shop, map[string]string{
+		"003_broken.up.sql": "CREATE INDEX idx_missing ON orders (missing_column);",
+	})
+	report, err = UpAllWithReport(context.Background())
+	if err == nil {
+		t.Fatal("Expected the broken migration to fail")
+	}
+	if failed := report.Sources[0]; failed.Status != SourceFailed || failed.Error == "" {
+		t.Errorf("Expected shop to be reported as failed, got %+v", failed)
+	}
+}
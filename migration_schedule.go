@@ -0,0 +1,148 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultMigrationParallelism bounds how many sources migrate concurrently
+const DefaultMigrationParallelism = 4
+
+// errDependencyFailed marks sources skipped because a dependency failed
+var errDependencyFailed = errors.New("dependency failed")
+
+// isSerialMigrations checks if DATABASE_MIGRATIONS_SERIAL forces sources to run one at a time
+func isSerialMigrations() bool {
+	value := strings.ToLower(configValue("DATABASE_MIGRATIONS_SERIAL"))
+	return value == "1" || value == "true" || value == "on"
+}
+
+// runSources runs each source after the sources it depends on. Sources that target the
+// same database always run one at a time in dependency then registration order; sources
+// on different databases run concurrently up to DefaultMigrationParallelism. The first
+// error in registration order is returned.
+func runSources(sources []MigrationSource, run func(MigrationSource) error) error {
+	order, err := orderSources(sources)
+	if err != nil {
+		return err
+	}
+
+	if isSerialMigrations() {
+		for _, i := range order {
+			if err := run(sources[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	deps := sourceDependencies(sources)
+	// Chain sources on the same database so they never migrate the same file concurrently
+	lastOnDatabase := make(map[string]int)
+	for _, i := range order {
+		databaseFile := sourceDatabaseFile(sources[i])
+		if prev, ok := lastOnDatabase[databaseFile]; ok {
+			deps[i] = append(deps[i], prev)
+		}
+		lastOnDatabase[databaseFile] = i
+	}
+
+	done := make([]chan struct{}, len(sources))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	errs := make([]error, len(sources))
+	sem := make(chan struct{}, DefaultMigrationParallelism)
+
+	var wg sync.WaitGroup
+	for i := range sources {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, dep := range deps[i] {
+				<-done[dep]
+				if errs[dep] != nil {
+					errs[i] = fmt.Errorf("skipped %s: %w", sources[i].Name, errDependencyFailed)
+					return
+				}
+			}
+
+			sem <- struct{}{}
+			errs[i] = run(sources[i])
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, errDependencyFailed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceDependencies resolves each source's DependsOn names to source indexes
+func sourceDependencies(sources []MigrationSource) [][]int {
+	byName := make(map[string][]int)
+	for i, source := range sources {
+		byName[source.Name] = append(byName[source.Name], i)
+	}
+
+	deps := make([][]int, len(sources))
+	for i, source := range sources {
+		for _, name := range source.DependsOn {
+			deps[i] = append(deps[i], byName[name]...)
+		}
+	}
+	return deps
+}
+
+// orderSources returns source indexes in dependency order, keeping registration
+// order among sources that don't depend on each other
+func orderSources(sources []MigrationSource) ([]int, error) {
+	names := make(map[string]bool)
+	for _, source := range sources {
+		names[source.Name] = true
+	}
+	for _, source := range sources {
+		for _, name := range source.DependsOn {
+			if !names[name] {
+				return nil, fmt.Errorf("migration source %s depends on unregistered source %s", source.Name, name)
+			}
+		}
+	}
+
+	deps := sourceDependencies(sources)
+	placed := make([]bool, len(sources))
+	order := make([]int, 0, len(sources))
+	for len(order) < len(sources) {
+		next := -1
+		for i := range sources {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for _, dep := range deps[i] {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("migration sources have a dependency cycle")
+		}
+		placed[next] = true
+		order = append(order, next)
+	}
+	return order, nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestRunSourcesRespectsDependencies verifies that sources run after their dependencies and
+// after earlier sources on the same database, and that dependents of a failed source are skipped
+func TestRunSourcesRespectsDependencies(t *testing.T) {
+	sources := []MigrationSource{
+		{Name: "core", Database: "core.db"},
+		{Name: "tenant", Database: "tenant.db"},
+		{Name: "billing", Database: "core.db", DependsOn: []string{"tenant"}},
+		{Name: "reports", Database: "reports.db", DependsOn: []string{"billing"}},
+	}
+
+	var mu sync.Mutex
+	var ran []string
+	failure := errors.New("billing failed")
+	err := runSources(sources, func(source MigrationSource) error {
+		mu.Lock()
+		ran = append(ran, source.Name)
+		mu.Unlock()
+		if source.Name == "billing" {
+			return failure
+		}
+		return nil
+	})
+
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected billing failure, got %v", err)
+	}
+	position := make(map[string]int)
+	for i, name := range ran {
+		position[name] = i
+	}
+	if _, ok := position["reports"]; ok {
+		t.Errorf("Expected reports to be skipped after its dependency failed, ran %v", ran)
+	}
+	if position["billing"] < position["core"] || position["billing"] < position["tenant"] {
+		t.Errorf("Expected billing to run after core and tenant, ran %v", ran)
+	}
+
+	cyclic := []MigrationSource{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"a"}},
+	}
+	if err := runSources(cyclic, func(MigrationSource) error { return nil }); err == nil {
+		t.Error("Expected an error for a dependency cycle")
+	}
+}
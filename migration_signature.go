@@ -0,0 +1,235 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// MigrationManifestFile lists the SHA-256 of every migration file of a signed source, in
+// sha256sum format. It is signed with minisign (migrations.sum.minisig) or cosign
+// sign-blob (migrations.sum.sig).
+const MigrationManifestFile = "migrations.sum"
+
+var (
+	// ErrUnsignedMigration is returned when signatures are required and a migration file
+	// is not covered by a signed manifest
+	ErrUnsignedMigration = errors.New("migration is not signed")
+
+	// ErrInvalidMigrationSignature is returned when a manifest signature does not verify
+	// or a migration file does not match its signed checksum
+	ErrInvalidMigrationSignature = errors.New("migration signature is invalid")
+)
+
+// migrationVerifier checks a manifest signature with a public key
+type migrationVerifier interface {
+	signatureFile() string
+	verify(manifest, signature []byte) error
+}
+
+// migrationPublicKey returns the public key for a source: its PublicKey, or
+// DATABASE_MIGRATION_PUBLIC_KEY. Either may hold the key itself or a path to it.
+func migrationPublicKey(source MigrationSource) string {
+	key := source.PublicKey
+	if key == "" {
+		key = configValue("DATABASE_MIGRATION_PUBLIC_KEY")
+	}
+	if key != "" && !strings.Contains(key, "\n") {
+		if content, err := os.ReadFile(key); err == nil {
+			return string(content)
+		}
+	}
+	return key
+}
+
+// requireSignedMigrations reports whether unsigned migrations must be refused
+func requireSignedMigrations() bool {
+	return configValue("DATABASE_REQUIRE_SIGNED_MIGRATIONS") == "true"
+}
+
+// parseMigrationPublicKey parses a minisign public key or a PEM public key (cosign)
+func parseMigrationPublicKey(key string) (migrationVerifier, error) {
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PEM public key: %w", err)
+		}
+		switch pub := pub.(type) {
+		case *ecdsa.PublicKey:
+			return pemVerifier{ecdsa: pub}, nil
+		case ed25519.PublicKey:
+			return pemVerifier{ed25519: pub}, nil
+		}
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	// minisign: optional "untrusted comment:" line, then base64("Ed" || key ID || key)
+	var encoded string
+	for _, line := range strings.Split(key, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			encoded = line
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != 42 || string(raw[:2]) != "Ed" {
+		return nil, fmt.Errorf("invalid minisign public key")
+	}
+	verifier := minisignVerifier{key: ed25519.PublicKey(raw[10:])}
+	copy(verifier.keyID[:], raw[2:10])
+	return verifier, nil
+}
+
+// minisignVerifier verifies minisign signatures (legacy "Ed" and prehashed "ED")
+type minisignVerifier struct {
+	keyID [8]byte
+	key   ed25519.PublicKey
+}
+
+func (v minisignVerifier) signatureFile() string {
+	return MigrationManifestFile + ".minisig"
+}
+
+func (v minisignVerifier) verify(manifest, signature []byte) error {
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 74 {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	if !bytes.Equal(sig[2:10], v.keyID[:]) {
+		return fmt.Errorf("signed with a different key")
+	}
+
+	message := manifest
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		digest := blake2b.Sum512(manifest)
+		message = digest[:]
+	default:
+		return fmt.Errorf("unsupported minisign algorithm %q", sig[:2])
+	}
+	if !ed25519.Verify(v.key, message, sig[10:]) {
+		return fmt.Errorf("signature does not match")
+	}
+
+	// The global signature covers the signature and trusted comment
+	trustedComment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || !ed25519.Verify(v.key, append(sig[10:], trustedComment...), globalSig) {
+		return fmt.Errorf("trusted comment signature does not match")
+	}
+	return nil
+}
+
+// pemVerifier verifies base64 signatures from cosign sign-blob --key
+type pemVerifier struct {
+	ecdsa   *ecdsa.PublicKey
+	ed25519 ed25519.PublicKey
+}
+
+func (v pemVerifier) signatureFile() string {
+	return MigrationManifestFile + ".sig"
+}
+
+func (v pemVerifier) verify(manifest, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if v.ed25519 != nil {
+		if !ed25519.Verify(v.ed25519, manifest, sig) {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+	}
+	digest := sha256.Sum256(manifest)
+	if !ecdsa.VerifyASN1(v.ecdsa, digest[:], sig) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+// verifyMigrationSignatures checks a directory source against its signed manifest. Without
+// a public key it passes, unless DATABASE_REQUIRE_SIGNED_MIGRATIONS is set. Embedded
+// sources are compiled into the binary and are not checked.
+func verifyMigrationSignatures(source MigrationSource) error {
+	if source.Directory == "" {
+		return nil
+	}
+	key := migrationPublicKey(source)
+	if key == "" {
+		if requireSignedMigrations() {
+			return fmt.Errorf("%w: no public key configured for %s", ErrUnsignedMigration, source.Name)
+		}
+		return nil
+	}
+	verifier, err := parseMigrationPublicKey(key)
+	if err != nil {
+		return fmt.Errorf("migration public key for %s: %w", source.Name, err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(source.Directory, MigrationManifestFile))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s has no %s", ErrUnsignedMigration, source.Name, MigrationManifestFile)
+	}
+	if err != nil {
+		return err
+	}
+	signature, err := os.ReadFile(filepath.Join(source.Directory, verifier.signatureFile()))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s has no %s", ErrUnsignedMigration, source.Name, verifier.signatureFile())
+	}
+	if err != nil {
+		return err
+	}
+	if err := verifier.verify(manifest, signature); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidMigrationSignature, source.Name, err)
+	}
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(source.Directory, "*.sql"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		expected, ok := sums[name]
+		if !ok {
+			return fmt.Errorf("%w: %s/%s is not in %s", ErrUnsignedMigration, source.Name, name, MigrationManifestFile)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != strings.ToLower(expected) {
+			return fmt.Errorf("%w: %s/%s does not match its signed checksum", ErrInvalidMigrationSignature, source.Name, name)
+		}
+	}
+
+	logf("🔏 Verified signed migrations for: %s (%d files)", source.Name, len(files))
+	return nil
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// writeMigrationManifest writes migrations.sum for the .sql files in dir
+func writeMigrationManifest(t *testing.T, dir string) []byte {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.sql"))
+	var manifest strings.Builder
+	for _, file := range files {
+		content, _ := os.ReadFile(file)
+		sum := sha256.Sum256(content)
+		fmt.Fprintf(&manifest, "%s  %s\n", hex.EncodeToString(sum[:]), filepath.Base(file))
+	}
+	if err := os.WriteFile(filepath.Join(dir, MigrationManifestFile), []byte(manifest.String()), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	return []byte(manifest.String())
+}
+
+// minisignSign returns a minisign public key and a prehashed signature of message
+func minisignSign(t *testing.T, message []byte) (publicKey, signature string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyID := []byte("testkey1")
+	publicKey = "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...)) + "\n"
+
+	digest := blake2b.Sum512(message)
+	sig := append(append([]byte("ED"), keyID...), ed25519.Sign(priv, digest[:])...)
+	trustedComment := "timestamp:1700000000"
+	globalSig := ed25519.Sign(priv, append(append([]byte{}, sig[10:]...), trustedComment...))
+	signature = fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(sig), trustedComment, base64.StdEncoding.EncodeToString(globalSig))
+	return publicKey, signature
+}
+
+// TestSignedMigrationsAreVerified verifies that minisign-signed migrations apply, and
+// that tampered or unsigned migrations are refused
+func TestSignedMigrationsAreVerified(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "signed.db"))
+	dir := writeMigrations(t, filepath.Join(tempDir, "signed"), map[string]string{
+		"001_create_audit.up.sql":   "CREATE TABLE audit (id INTEGER PRIMARY KEY);",
+		"001_create_audit.down.sql": "DROP TABLE audit;",
+	})
+	publicKey, signature := minisignSign(t, writeMigrationManifest(t, dir))
+	os.WriteFile(filepath.Join(dir, MigrationManifestFile+".minisig"), []byte(signature), 0644)
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+
+	source := MigrationSource{Name: "signed", Directory: dir, PublicKey: publicKey}
+	RegisterMigrations(source)
+	if err := UpAllContext(context.Background()); err != nil {
+		t.Fatalf("Failed to apply signed migrations: %v", err)
+	}
+
+	// A file changed after signing is refused
+	os.WriteFile(filepath.Join(dir, "001_create_audit.down.sql"), []byte("DROP TABLE users;"), 0644)
+	if err := verifyMigrationSignatures(source); !errors.Is(err, ErrInvalidMigrationSignature) {
+		t.Errorf("Expected ErrInvalidMigrationSignature for a tampered file, got %v", err)
+	}
+
+	// A file added after signing is refused
+	writeMigrationManifest(t, dir)
+	os.WriteFile(filepath.Join(dir, "002_extra.up.sql"), []byte("SELECT 1;"), 0644)
+	if err := verifyMigrationSignatures(source); !errors.Is(err, ErrInvalidMigrationSignature) {
+		t.Errorf("Expected ErrInvalidMigrationSignature for a re-written manifest, got %v", err)
+	}
+
+	// Without a key, unsigned sources are refused only when signatures are required
+	unsigned := MigrationSource{Name: "unsigned", Directory: t.TempDir()}
+	if err := verifyMigrationSignatures(unsigned); err != nil {
+		t.Errorf("Expected unsigned source to pass by default, got %v", err)
+	}
+	t.Setenv("DATABASE_REQUIRE_SIGNED_MIGRATIONS", "true")
+	if err := verifyMigrationSignatures(unsigned); !errors.Is(err, ErrUnsignedMigration) {
+		t.Errorf("Expected ErrUnsignedMigration, got %v", err)
+	}
+}
+
+// TestCosignSignedMigrations verifies cosign sign-blob style ECDSA signatures
+func TestCosignSignedMigrations(t *testing.T) {
+	dir := writeMigrations(t, filepath.Join(t.TempDir(), "cosign"), map[string]string{
+		"001_create_jobs.up.sql": "CREATE TABLE jobs (id INTEGER PRIMARY KEY);",
+	})
+	manifest := writeMigrationManifest(t, dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyFile := filepath.Join(t.TempDir(), "cosign.pub")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+	t.Setenv("DATABASE_MIGRATION_PUBLIC_KEY", keyFile)
+
+	source := MigrationSource{Name: "cosign", Directory: dir}
+	if err := verifyMigrationSignatures(source); !errors.Is(err, ErrUnsignedMigration) {
+		t.Errorf("Expected ErrUnsignedMigration without a signature, got %v", err)
+	}
+
+	digest := sha256.Sum256(manifest)
+	sig, _ := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	os.WriteFile(filepath.Join(dir, MigrationManifestFile+".sig"), []byte(base64.StdEncoding.EncodeToString(sig)), 0644)
+	if err := verifyMigrationSignatures(source); err != nil {
+		t.Errorf("Expected cosign signature to verify, got %v", err)
+	}
+}
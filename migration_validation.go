@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrMigrationValidationFailed is returned by UpAll and UpAllContext when a source's
+// post-migration validation fails
+var ErrMigrationValidationFailed = errors.New("post-migration validation failed")
+
+// MigrationValidation is a smoke test run after a source's migrations are applied.
+// Query must succeed and return a row; when Expect is set, the first column of the first
+// row must equal it (compared as formatted values, e.g. Expect "ok" for PRAGMA
+// integrity_check or 0 for an orphan count). Check runs custom validation instead.
+type MigrationValidation struct {
+	Name   string
+	Query  string
+	Expect any
+	Check  func(ctx context.Context, db *sql.DB) error
+}
+
+// MigrationValidationError reports which validation broke a deploy
+type MigrationValidationError struct {
+	Source     string
+	Validation string
+	Err        error
+}
+
+func (e *MigrationValidationError) Error() string {
+	return fmt.Sprintf("%v: %s/%s: %v", ErrMigrationValidationFailed, e.Source, e.Validation, e.Err)
+}
+
+func (e *MigrationValidationError) Is(target error) bool {
+	return target == ErrMigrationValidationFailed
+}
+
+func (e *MigrationValidationError) Unwrap() error {
+	return e.Err
+}
+
+// withValidations runs a source's validations after run migrates it successfully
+func withValidations(ctx context.Context, run func(MigrationSource) error) func(MigrationSource) error {
+	return func(source MigrationSource) error {
+		if err := run(source); err != nil {
+			return err
+		}
+		return runMigrationValidations(ctx, source)
+	}
+}
+
+// runMigrationValidations runs a source's validations in order, stopping at the first failure
+func runMigrationValidations(ctx context.Context, source MigrationSource) error {
+	if len(source.Validations) == 0 {
+		return nil
+	}
+
+	db, err := sql.Open("sqlite", buildDSN(sourceDatabaseFile(source)))
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	for _, validation := range source.Validations {
+		if err := runMigrationValidation(ctx, db, validation); err != nil {
+			validationErr := &MigrationValidationError{Source: source.Name, Validation: validation.Name, Err: err}
+			logErrorf("❌ %v", validationErr)
+			return validationErr
+		}
+	}
+	logf("✅ %d validations passed for: %s", len(source.Validations), source.Name)
+	return nil
+}
+
+// runMigrationValidation runs a single validation
+func runMigrationValidation(ctx context.Context, db *sql.DB, validation MigrationValidation) error {
+	if validation.Check != nil {
+		return validation.Check(ctx, db)
+	}
+
+	var value any
+	err := db.QueryRowContext(ctx, validation.Query).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("query returned no rows")
+	}
+	if err != nil {
+		return err
+	}
+	if validation.Expect == nil {
+		return nil
+	}
+	if value, ok := value.([]byte); ok {
+		if string(value) != fmt.Sprint(validation.Expect) {
+			return fmt.Errorf("expected %v, got %s", validation.Expect, value)
+		}
+		return nil
+	}
+	if fmt.Sprint(value) != fmt.Sprint(validation.Expect) {
+		return fmt.Errorf("expected %v, got %v", validation.Expect, value)
+	}
+	return nil
+}
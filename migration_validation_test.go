@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestMigrationValidationsGateDeploy verifies that validations run after migrations and
+// that a failing one is reported by source and name
+func TestMigrationValidationsGateDeploy(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "validate.db"))
+	dir := writeMigrations(t, filepath.Join(tempDir, "roles"), map[string]string{
+		"001_create_roles.up.sql":   "CREATE TABLE roles (name TEXT PRIMARY KEY); INSERT INTO roles VALUES ('admin');",
+		"001_create_roles.down.sql": "DROP TABLE roles;",
+	})
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+
+	RegisterMigrations(MigrationSource{
+		Name:      "roles",
+		Directory: dir,
+		Validations: []MigrationValidation{
+			{Name: "integrity", Query: "PRAGMA integrity_check", Expect: "ok"},
+			{Name: "admin role present", Query: "SELECT 1 FROM roles WHERE name = 'admin'"},
+			{Name: "role count", Query: "SELECT COUNT(*) FROM roles", Expect: 1},
+		},
+	})
+	if err := UpAll(); err != nil {
+		t.Fatalf("Expected validations to pass, got %v", err)
+	}
+
+	RegisterMigrations(MigrationSource{
+		Name:      "roles-strict",
+		Directory: dir,
+		Validations: []MigrationValidation{
+			{Name: "auditor role present", Query: "SELECT 1 FROM roles WHERE name = 'auditor'"},
+		},
+	})
+	err := UpAllContext(context.Background())
+	if !errors.Is(err, ErrMigrationValidationFailed) {
+		t.Fatalf("Expected ErrMigrationValidationFailed, got %v", err)
+	}
+	var validationErr *MigrationValidationError
+	if !errors.As(err, &validationErr) || validationErr.Source != "roles-strict" || validationErr.Validation != "auditor role present" {
+		t.Errorf("Expected failure naming roles-strict/auditor role present, got %v", err)
+	}
+}
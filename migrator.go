@@ -1,10 +1,9 @@
 package database
 
 import (
+	"context"
 	"embed"
 	"fmt"
-	"log"
-	"os"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
@@ -12,65 +11,108 @@ import (
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-// UpAll runs all migrations from all registered sources
+// UpAll runs all migrations from all registered sources. Sources targeting different
+// databases run concurrently unless DATABASE_MIGRATIONS_SERIAL is set; see runSources.
 func UpAll() error {
-	log.Printf("🚀 Running all migrations from registered sources...")
+	logf("🚀 Running all migrations from registered sources...")
+
+	if err := CheckRegisteredSources(); err != nil {
+		return err
+	}
+	if err := preflightDiskSpace(context.Background()); err != nil {
+		return err
+	}
 
 	sources := GetRegisteredSources()
 	if len(sources) == 0 {
-		log.Printf("⚠️  No migration sources registered")
+		logWarnf("⚠️  No migration sources registered")
 		return nil
 	}
 
-	// For each registered source, run its migrations using golang-migrate
-	for _, source := range sources {
-		log.Printf("📦 Processing migrations from: %s", source.Name)
+	err := runSources(sources, withValidations(context.Background(), func(source MigrationSource) error {
+		logEvent(context.Background(), LogLevelInfo, []any{"operation", "migration_started", "source", source.Name}, "📦 Processing migrations from: %s", source.Name)
+		databaseFile := sourceDatabaseFile(source)
+
+		source, err := resolveRemoteSource(context.Background(), source)
+		if err != nil {
+			return err
+		}
+		if err := verifyMigrationSignatures(source); err != nil {
+			return err
+		}
+
+		// Handle migration groups, applied atomically as one unit
+		if source.group != nil {
+			if err := runMigrationGroup(context.Background(), source.group, databaseFile); err != nil {
+				return fmt.Errorf("failed to run migration group %s: %w", source.Name, err)
+			}
+			return nil
+		}
+
+		// Sources that roll back failed runs use the step-wise runner, which tracks each migration
+		if source.RollbackOnFailure && (source.EmbedFS != nil || source.Directory != "") {
+			if err := runSourceContext(context.Background(), source); err != nil {
+				return fmt.Errorf("failed to run migrations for %s: %w", source.Name, err)
+			}
+			logEvent(context.Background(), LogLevelInfo, []any{"operation", "migration_completed", "source", source.Name}, "✅ Completed migrations for: %s", source.Name)
+			return nil
+		}
 
 		// Handle embedded filesystem sources
 		if source.EmbedFS != nil {
-			log.Printf("📁 Using embedded filesystem for: %s", source.Name)
+			logf("📁 Using embedded filesystem for: %s", source.Name)
 			subPath := source.SubPath
 			if subPath == "" {
 				subPath = "." // Default to current directory if not specified
 			}
-			if err := runEmbeddedMigrations(source.EmbedFS, subPath, source.Prefix); err != nil {
+			if err := runEmbeddedMigrations(source.EmbedFS, subPath, source.Prefix, databaseFile); err != nil {
 				return fmt.Errorf("failed to run embedded migrations for %s: %w", source.Name, err)
 			}
-			log.Printf("✅ Completed embedded migrations for: %s", source.Name)
-			continue
+			logEvent(context.Background(), LogLevelInfo, []any{"operation", "migration_completed", "source", source.Name}, "✅ Completed embedded migrations for: %s", source.Name)
+			return nil
 		}
 
 		// Handle directory-based sources (legacy)
 		if source.Directory != "" {
-			log.Printf("📂 Using directory filesystem for: %s", source.Name)
+			logf("📂 Using directory filesystem for: %s", source.Name)
 			// Use the prefix-aware migration runner
-			if err := runMigrationsFromDirectoryWithPrefix(source.Directory, source.Prefix); err != nil {
+			if err := runMigrationsFromDirectoryWithPrefix(source.Directory, source.Prefix, databaseFile); err != nil {
 				return fmt.Errorf("failed to run directory migrations for %s: %w", source.Name, err)
 			}
-			log.Printf("✅ Completed directory migrations for: %s", source.Name)
-			continue
+			logEvent(context.Background(), LogLevelInfo, []any{"operation", "migration_completed", "source", source.Name}, "✅ Completed directory migrations for: %s", source.Name)
+			return nil
 		}
 
-		log.Printf("⚠️  No migration source (directory or embed) specified for: %s", source.Name)
+		logEvent(context.Background(), LogLevelWarn, []any{"operation", "migration_skipped", "source", source.Name}, "⚠️  No migration source (directory or embed) specified for: %s", source.Name)
+		return nil
+	}))
+	if err != nil {
+		return err
 	}
 
-	log.Printf("🎉 All migrations completed successfully!")
+	logf("🎉 All migrations completed successfully!")
 	return nil
 }
 
+// sourceDatabaseFile returns the database file a source migrates
+func sourceDatabaseFile(source MigrationSource) string {
+	if source.Database != "" {
+		return source.Database
+	}
+	databaseFile := configValue("DATABASE_FILE")
+	if databaseFile == "" {
+		databaseFile = "app.db"
+	}
+	return databaseFile
+}
+
 // runMigrationsFromDirectory runs migrations from a specific directory (legacy, no prefix)
 func runMigrationsFromDirectory(migrationsDir string) error {
-	return runMigrationsFromDirectoryWithPrefix(migrationsDir, "")
+	return runMigrationsFromDirectoryWithPrefix(migrationsDir, "", sourceDatabaseFile(MigrationSource{}))
 }
 
 // runMigrationsFromDirectoryWithPrefix runs migrations from a directory with prefix support
-func runMigrationsFromDirectoryWithPrefix(migrationsDir string, prefix string) error {
-	// Get database file path
-	databaseFile := os.Getenv("DATABASE_FILE")
-	if databaseFile == "" {
-		databaseFile = "app.db"
-	}
-
+func runMigrationsFromDirectoryWithPrefix(migrationsDir string, prefix string, databaseFile string) error {
 	// If no prefix, use the legacy approach
 	if prefix == "" {
 		return runLegacyMigrations(migrationsDir, databaseFile)
@@ -108,7 +150,7 @@ func runPrefixedMigrations(migrationsDir string, prefix string, databaseFile str
 	databaseURL := fmt.Sprintf("sqlite://%s?x-migrations-table=%sschema_migrations", databaseFile, prefix)
 	migrationsURL := fmt.Sprintf("file://%s", migrationsDir)
 
-	log.Printf("🏷️  Using prefixed schema table: %sschema_migrations", prefix)
+	logf("🏷️  Using prefixed schema table: %sschema_migrations", prefix)
 
 	// Initialize migrate instance with prefixed schema table
 	m, err := migrate.New(migrationsURL, databaseURL)
@@ -127,18 +169,12 @@ func runPrefixedMigrations(migrationsDir string, prefix string, databaseFile str
 }
 
 // runEmbeddedMigrations runs migrations from an embedded filesystem with prefix support
-func runEmbeddedMigrations(embedFS *embed.FS, subpath string, prefix string) error {
-	// Get database file path
-	databaseFile := os.Getenv("DATABASE_FILE")
-	if databaseFile == "" {
-		databaseFile = "app.db"
-	}
-
+func runEmbeddedMigrations(embedFS *embed.FS, subpath string, prefix string, databaseFile string) error {
 	// Create database URL with optional prefix for schema table
 	var databaseURL string
 	if prefix != "" {
 		databaseURL = fmt.Sprintf("sqlite://%s?x-migrations-table=%sschema_migrations", databaseFile, prefix)
-		log.Printf("🏷️  Using prefixed schema table: %sschema_migrations", prefix)
+		logf("🏷️  Using prefixed schema table: %sschema_migrations", prefix)
 	} else {
 		databaseURL = fmt.Sprintf("sqlite://%s", databaseFile)
 	}
@@ -178,6 +214,9 @@ func GetMigrationStatus() (map[string]interface{}, error) {
 		sourceStatus["name"] = source.Name
 		sourceStatus["has_directory"] = source.Directory != ""
 		sourceStatus["has_embed"] = source.EmbedFS != nil
+		if source.group != nil {
+			sourceStatus["group_sources"] = len(source.group.Sources)
+		}
 
 		// Try to get migration count
 		count, err := countMigrationFiles(source)
@@ -0,0 +1,278 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// migrationNumberPattern matches the numeric prefix of a migration file name
+var migrationNumberPattern = regexp.MustCompile(`^(\d+)_`)
+
+// GenerateModelMigration compares the models of registered repositories against the
+// introspected schema and renders up and down migrations for the delta: missing tables,
+// missing columns, missing `unique`/`index` indexes, generated columns and the
+// repositories' expression indexes. Columns present only in the
+// database are never dropped, and type changes are reported as comments since SQLite
+// cannot alter a column in place (see OnlineAlter). Both results are empty when the
+// schema already matches.
+func GenerateModelMigration(ctx context.Context) (up string, down string, err error) {
+	repositories := GetRegisteredRepositories()
+	if len(repositories) == 0 {
+		return "", "", fmt.Errorf("no repositories registered")
+	}
+
+	schema, err := IntrospectSchema(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	version, err := sqliteVersion(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	var upParts, downParts []string
+	for _, repo := range repositories {
+		fields, err := modelFields(repo.Model)
+		if err != nil {
+			return "", "", fmt.Errorf("repository %s: %w", repo.Name, err)
+		}
+		if err := validateSchemaFeatures(repo, fields, version); err != nil {
+			return "", "", fmt.Errorf("repository %s: %w", repo.Name, err)
+		}
+
+		table, exists := schema.Table(repo.Table)
+		if !exists {
+			create, err := createTableSQL(repo.Table, fields)
+			if err != nil {
+				return "", "", fmt.Errorf("repository %s: %w", repo.Name, err)
+			}
+			upParts = append(upParts, create)
+			downParts = append(downParts, fmt.Sprintf("DROP TABLE IF EXISTS %s;", quoteIdentifier(repo.Table)))
+		} else {
+			for _, f := range fields {
+				columnType, err := sqliteColumnType(f.Type)
+				if err != nil {
+					return "", "", fmt.Errorf("repository %s field %s: %w", repo.Name, f.Name, err)
+				}
+
+				existing, ok := table.Column(f.Column)
+				if !ok {
+					if f.Generated != "" && f.Stored {
+						// SQLite can only add VIRTUAL generated columns to an existing table
+						upParts = append(upParts, fmt.Sprintf("-- %s.%s is a STORED generated column, which cannot be added to an existing table; rewrite the table with OnlineAlter",
+							repo.Table, f.Column))
+						continue
+					}
+					upParts = append(upParts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", quoteIdentifier(repo.Table), addColumnDefinition(f, columnType)))
+					downParts = append(downParts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", quoteIdentifier(repo.Table), quoteIdentifier(f.Column)))
+					continue
+				}
+				if (existing.Generated != "") != (f.Generated != "") {
+					upParts = append(upParts, fmt.Sprintf("-- %s.%s is %s in the model but %s in the database; rewrite the table with OnlineAlter",
+						repo.Table, f.Column, describeGenerated(f.Generated != ""), describeGenerated(existing.Generated != "")))
+				}
+				if !strings.EqualFold(existing.Type, columnType) {
+					upParts = append(upParts, fmt.Sprintf("-- %s.%s is %s in the model but %s in the database; rewrite the table with OnlineAlter",
+						repo.Table, f.Column, columnType, existing.Type))
+				}
+			}
+		}
+
+		for _, f := range fields {
+			if !f.Unique && !f.Index {
+				continue
+			}
+			if hasSingleColumnIndex(table, f.Column, f.Unique) {
+				continue
+			}
+			prefix, kind := "idx", "INDEX"
+			if f.Unique {
+				prefix, kind = "ux", "UNIQUE INDEX"
+			}
+			name := fmt.Sprintf("%s_%s_%s", prefix, repo.Table, f.Column)
+			upParts = append(upParts, fmt.Sprintf("CREATE %s IF NOT EXISTS %s ON %s (%s);",
+				kind, quoteIdentifier(name), quoteIdentifier(repo.Table), quoteIdentifier(f.Column)))
+			if exists {
+				// Indexes on new tables are dropped with the table
+				downParts = append(downParts, fmt.Sprintf("DROP INDEX IF EXISTS %s;", quoteIdentifier(name)))
+			}
+		}
+
+		for _, idx := range repo.Indexes {
+			if hasIndexNamed(table, idx.Name) {
+				continue
+			}
+			upParts = append(upParts, idx.createSQL(repo.Table))
+			if exists {
+				downParts = append(downParts, fmt.Sprintf("DROP INDEX IF EXISTS %s;", quoteIdentifier(idx.Name)))
+			}
+		}
+	}
+
+	if len(upParts) == 0 {
+		return "", "", nil
+	}
+	// Undo in reverse order so down migrations mirror up migrations
+	for i, j := 0, len(downParts)-1; i < j; i, j = i+1, j-1 {
+		downParts[i], downParts[j] = downParts[j], downParts[i]
+	}
+	return strings.Join(upParts, "\n\n") + "\n", strings.Join(downParts, "\n") + "\n", nil
+}
+
+// WriteModelMigration generates a migration for the model delta and writes it into dir
+// as the next numbered .up.sql/.down.sql pair. It returns the path of the up file, or
+// an empty string when the schema already matches the models.
+func WriteModelMigration(ctx context.Context, dir, name string) (string, error) {
+	up, down, err := GenerateModelMigration(ctx)
+	if err != nil || up == "" {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create migration directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list migration directory: %w", err)
+	}
+	next := 1
+	for _, entry := range entries {
+		if match := migrationNumberPattern.FindStringSubmatch(entry.Name()); match != nil {
+			if n, err := strconv.Atoi(match[1]); err == nil && n >= next {
+				next = n + 1
+			}
+		}
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%03d_%s", next, toSnakeCase(name)))
+	if err := os.WriteFile(base+".up.sql", []byte(up), 0644); err != nil {
+		return "", fmt.Errorf("failed to write up migration: %w", err)
+	}
+	if err := os.WriteFile(base+".down.sql", []byte(down), 0644); err != nil {
+		return "", fmt.Errorf("failed to write down migration: %w", err)
+	}
+	return base + ".up.sql", nil
+}
+
+// createTableSQL renders a CREATE TABLE statement for a model
+func createTableSQL(table string, fields []modelField) (string, error) {
+	var pks []string
+	for _, f := range fields {
+		if f.PrimaryKey {
+			pks = append(pks, quoteIdentifier(f.Column))
+		}
+	}
+
+	var lines []string
+	for _, f := range fields {
+		columnType, err := sqliteColumnType(f.Type)
+		if err != nil {
+			return "", fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if f.Generated != "" {
+			lines = append(lines, "    "+generatedColumnDefinition(f, columnType))
+			continue
+		}
+		line := columnDeclaration(f, columnType)
+		switch {
+		case f.PrimaryKey && len(pks) == 1:
+			line += " PRIMARY KEY"
+		case f.Type.Kind() != reflect.Ptr:
+			line += " NOT NULL"
+		}
+		lines = append(lines, "    "+line)
+	}
+	if len(pks) > 1 {
+		lines = append(lines, fmt.Sprintf("    PRIMARY KEY (%s)", strings.Join(pks, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);", quoteIdentifier(table), strings.Join(lines, ",\n")), nil
+}
+
+// addColumnDefinition renders a column for ALTER TABLE ADD COLUMN; SQLite requires a
+// constant default for NOT NULL columns added to existing tables
+func addColumnDefinition(f modelField, columnType string) string {
+	if f.Generated != "" {
+		return generatedColumnDefinition(f, columnType)
+	}
+	definition := columnDeclaration(f, columnType)
+	if f.Type.Kind() == reflect.Ptr {
+		return definition
+	}
+	defaults := map[string]string{
+		"TEXT":     "''",
+		"INTEGER":  "0",
+		"REAL":     "0",
+		"BLOB":     "x''",
+		"DATETIME": "'0001-01-01T00:00:00Z'",
+	}
+	return definition + " NOT NULL DEFAULT " + defaults[columnType]
+}
+
+// columnDeclaration renders a column's name and type, with its collation if the model sets one
+func columnDeclaration(f modelField, columnType string) string {
+	declaration := quoteIdentifier(f.Column) + " " + columnType
+	if f.Collate != "" {
+		declaration += " COLLATE " + f.Collate
+	}
+	return declaration
+}
+
+// sqliteColumnType maps a Go field type to a SQLite column type
+func sqliteColumnType(t reflect.Type) (string, error) {
+	if t.Kind() == reflect.Ptr {
+		return sqliteColumnType(t.Elem())
+	}
+	if t == timeType {
+		return "DATETIME", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "TEXT", nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER", nil
+	case reflect.Float32, reflect.Float64:
+		return "REAL", nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+// hasSingleColumnIndex reports whether the table already indexes column alone;
+// a unique index also satisfies a plain index
+func hasSingleColumnIndex(table TableInfo, column string, unique bool) bool {
+	for _, idx := range table.Indexes {
+		if len(idx.Columns) == 1 && idx.Columns[0] == column && (idx.Unique || !unique) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIndexNamed reports whether the table already has an index called name
+func hasIndexNamed(table TableInfo, name string) bool {
+	for _, idx := range table.Indexes {
+		if idx.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// describeGenerated names a column kind for migration comments
+func describeGenerated(generated bool) string {
+	if generated {
+		return "a generated column"
+	}
+	return "a plain column"
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type migrationUser struct {
+	ID       string  `db:"id,pk"`
+	Email    string  `db:"email,unique"`
+	Age      int     `db:"age"`
+	Nickname *string `db:"nickname,index"`
+}
+
+type migrationTeam struct {
+	ID   int64  `db:"id,pk"`
+	Name string `db:"name"`
+}
+
+// TestGenerateModelMigrationDelta verifies that missing tables, columns and indexes are
+// generated, that the migration applies and reverts cleanly, and that no delta remains after it
+func TestGenerateModelMigrationDelta(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "models.db"))
+	ctx := context.Background()
+
+	globalRepositories.mu.Lock()
+	saved := globalRepositories.repositories
+	globalRepositories.repositories = nil
+	globalRepositories.mu.Unlock()
+	defer func() {
+		globalRepositories.mu.Lock()
+		globalRepositories.repositories = saved
+		globalRepositories.mu.Unlock()
+	}()
+	RegisterRepository(Repository{Table: "users", Model: migrationUser{}})
+	RegisterRepository(Repository{Table: "teams", Model: migrationTeam{}})
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT NOT NULL)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	dir := t.TempDir()
+	path, err := WriteModelMigration(ctx, dir, "AddTeams")
+	if err != nil {
+		t.Fatalf("WriteModelMigration failed: %v", err)
+	}
+	if filepath.Base(path) != "001_add_teams.up.sql" {
+		t.Errorf("Unexpected migration file: %s", path)
+	}
+	up, _ := os.ReadFile(path)
+	down, _ := os.ReadFile(strings.TrimSuffix(path, ".up.sql") + ".down.sql")
+	for _, expected := range []string{
+		`ALTER TABLE "users" ADD COLUMN "age" INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE "users" ADD COLUMN "nickname" TEXT;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "ux_users_email"`,
+		`CREATE INDEX IF NOT EXISTS "idx_users_nickname"`,
+		`CREATE TABLE IF NOT EXISTS "teams"`,
+	} {
+		if !strings.Contains(string(up), expected) {
+			t.Errorf("Expected up migration to contain %q, got:\n%s", expected, up)
+		}
+	}
+
+	if _, err := db.Exec(string(up)); err != nil {
+		t.Fatalf("Failed to apply up migration: %v", err)
+	}
+	if again, _, err := GenerateModelMigration(ctx); err != nil || again != "" {
+		t.Errorf("Expected no delta after applying the migration, got %q (%v)", again, err)
+	}
+	if _, err := db.Exec(string(down)); err != nil {
+		t.Fatalf("Failed to apply down migration: %v", err)
+	}
+}
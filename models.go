@@ -0,0 +1,138 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// modelField describes a struct field mapped to a table column via the `db` tag.
+// Tags take the form `db:"column_name"` followed by options: `pk` for the primary key,
+// `unique` for a unique index and `index` for a plain index; `db:"-"` skips the field.
+// A `generated:"expression"` tag makes the column a generated column computed from
+// expression (VIRTUAL, or STORED with the `stored` option), e.g.
+// `db:"user_id,index" generated:"json_extract(payload, '$.user.id')"`.
+// `collate=NAME` declares the column with a collation such as UNICODE_NOCASE; its
+// indexes then use it too. A `normalize:"trim,nfkc,fold"` tag declares how NormalizeModel
+// and NormalizeValue normalize the field's text. A `proto:"N"` tag gives the field's
+// number in the message GenerateProto renders for the model.
+type modelField struct {
+	Name       string       // Go field name
+	Column     string       // Column name
+	Type       reflect.Type // Go field type
+	PrimaryKey bool
+	Unique     bool
+	Index      bool
+	Generated  string            // Expression computing a generated column
+	Stored     bool              // Generated column is STORED rather than VIRTUAL
+	Collate    string            // Collation declared on the column
+	Normalize  TextNormalization // Steps from the `normalize` tag applied on write
+	Proto      int               // Proto field number from the `proto` tag (0 when untagged)
+}
+
+// modelFields returns the columns declared by a struct (or pointer to struct) model
+func modelFields(model interface{}) ([]modelField, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a struct, got %T", model)
+	}
+
+	var fields []modelField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		column := parts[0]
+		if column == "" {
+			column = toSnakeCase(f.Name)
+		}
+
+		field := modelField{Name: f.Name, Column: column, Type: f.Type, Generated: f.Tag.Get("generated")}
+		if spec, ok := f.Tag.Lookup("normalize"); ok {
+			normalize, err := ParseTextNormalization(spec)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			field.Normalize = normalize
+		}
+		if number, ok := f.Tag.Lookup("proto"); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("field %s: invalid proto field number %q", f.Name, number)
+			}
+			field.Proto = n
+		}
+		for _, option := range parts[1:] {
+			switch option {
+			case "pk":
+				field.PrimaryKey = true
+			case "unique":
+				field.Unique = true
+			case "index":
+				field.Index = true
+			case "stored":
+				field.Stored = true
+			default:
+				if name, ok := strings.CutPrefix(option, "collate="); ok {
+					field.Collate = name
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("model %s has no exported fields", t.Name())
+	}
+	return fields, nil
+}
+
+// modelName returns the struct type name of a model
+func modelName(model interface{}) string {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name()
+}
+
+// timeType is used to detect time.Time fields
+var timeType = reflect.TypeOf(time.Time{})
+
+// toSnakeCase converts a Go identifier such as "GivenName" or "APIKey" to snake_case
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		isUpper := r >= 'A' && r <= 'Z'
+		if isUpper && i > 0 {
+			prevLower := runes[i-1] >= 'a' && runes[i-1] <= 'z'
+			nextLower := i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z'
+			prevUpper := runes[i-1] >= 'A' && runes[i-1] <= 'Z'
+			if prevLower || (prevUpper && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		if isUpper {
+			r = r + ('a' - 'A')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
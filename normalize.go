@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"modernc.org/sqlite"
+)
+
+// TextNormalization is a set of steps applied to user-entered text so equal-looking
+// values compare equal: trimming, a Unicode normal form and case folding
+type TextNormalization uint8
+
+const (
+	NormalizeTrim TextNormalization = 1 << iota // Strip leading and trailing whitespace
+	NormalizeNFC                                // Compose characters (e + ◌́ = é)
+	NormalizeNFKC                               // Compose and fold compatibility forms (ﬁ = fi, ① = 1)
+	NormalizeFold                               // Unicode case folding (Straße = strasse)
+
+	// NormalizeIdentifier suits emails and usernames
+	NormalizeIdentifier = NormalizeTrim | NormalizeNFKC | NormalizeFold
+)
+
+// textNormalizationNames maps normalize tag and SQL function names to steps
+var textNormalizationNames = map[string]TextNormalization{
+	"trim":       NormalizeTrim,
+	"nfc":        NormalizeNFC,
+	"nfkc":       NormalizeNFKC,
+	"fold":       NormalizeFold,
+	"identifier": NormalizeIdentifier,
+}
+
+func init() {
+	if err := sqlite.RegisterDeterministicScalarFunction("normalize_text", 2, normalizeTextFunction); err != nil {
+		panic(err)
+	}
+}
+
+// ParseTextNormalization parses a comma-separated list of steps (trim, nfc, nfkc, fold,
+// or identifier for all of trim, nfkc and fold), as used by the `normalize` model tag
+// and the normalize_text SQL function
+func ParseTextNormalization(spec string) (TextNormalization, error) {
+	var n TextNormalization
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		step, ok := textNormalizationNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown text normalization %q (use trim, nfc, nfkc, fold or identifier)", name)
+		}
+		n |= step
+	}
+	return n, nil
+}
+
+// NormalizeText applies n to s: trim, then the normal form, then case folding (re-applying
+// the normal form, since folding can denormalize)
+func NormalizeText(s string, n TextNormalization) string {
+	if n&NormalizeTrim != 0 {
+		s = strings.TrimSpace(s)
+	}
+	form, normalize := norm.NFC, n&(NormalizeNFC|NormalizeNFKC) != 0
+	if n&NormalizeNFKC != 0 {
+		form = norm.NFKC
+	}
+	if normalize {
+		s = form.String(s)
+	}
+	if n&NormalizeFold != 0 {
+		s = cases.Fold().String(s)
+		if normalize {
+			s = form.String(s)
+		}
+	}
+	return s
+}
+
+// NormalizeModel applies the `normalize` tags of a struct's string fields in place, so
+// values are stored normalized. Call it on a model before writing it:
+//
+//	type User struct {
+//	    Email string `db:"email,unique" normalize:"identifier"`
+//	}
+//	err := database.NormalizeModel(&user)
+func NormalizeModel(model interface{}) error {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to a struct, got %T", model)
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		spec, ok := v.Type().Field(i).Tag.Lookup("normalize")
+		if !ok {
+			continue
+		}
+		n, err := ParseTextNormalization(spec)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", v.Type().Field(i).Name, err)
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if field.Kind() != reflect.String || !field.CanSet() {
+			return fmt.Errorf("field %s: normalize applies to exported string fields", v.Type().Field(i).Name)
+		}
+		field.SetString(NormalizeText(field.String(), n))
+	}
+	return nil
+}
+
+// NormalizeValue normalizes a lookup value the way the registered repository for table
+// normalizes column on write, so WHERE column = ? matches what was stored. Values of
+// columns without a `normalize` tag are returned unchanged.
+func NormalizeValue(table, column, value string) (string, error) {
+	for _, repo := range GetRegisteredRepositories() {
+		if repo.Table != table {
+			continue
+		}
+		fields, err := modelFields(repo.Model)
+		if err != nil {
+			return "", fmt.Errorf("repository %s: %w", repo.Name, err)
+		}
+		for _, f := range fields {
+			if f.Column == column {
+				return NormalizeText(value, f.Normalize), nil
+			}
+		}
+		return "", fmt.Errorf("repository %s has no column %s", repo.Name, column)
+	}
+	return "", fmt.Errorf("no repository registered for table %s", table)
+}
+
+// normalizeTextFunction implements normalize_text(value, steps) for backfills and
+// expression indexes, e.g. UPDATE users SET email = normalize_text(email, 'identifier')
+func normalizeTextFunction(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	var value string
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return v, nil
+	}
+	spec, _ := args[1].(string)
+	n, err := ParseTextNormalization(spec)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeText(value, n), nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type normalizedUser struct {
+	ID       int64   `db:"id,pk"`
+	Email    string  `db:"email,unique" normalize:"identifier"`
+	Username *string `db:"username" normalize:"trim,nfc"`
+	Bio      string  `db:"bio"`
+}
+
+// TestNormalizeText verifies each step and their combination for identifiers
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    TextNormalization
+		want string
+	}{
+		{"  Ann@Example.com ", NormalizeTrim, "Ann@Example.com"},
+		{"Cafe\u0301", NormalizeNFC, "Caf\u00e9"},
+		{"ﬁle①", NormalizeNFKC, "file1"},
+		{" STRAẞE@Exámple.de ", NormalizeIdentifier, "strasse@exámple.de"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeText(tt.in, tt.n); got != tt.want {
+			t.Errorf("NormalizeText(%q, %v) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+	if _, err := ParseTextNormalization("trim,upper"); err == nil {
+		t.Error("Expected an unknown step to be rejected")
+	}
+}
+
+// TestNormalizeOnWriteAndLookup verifies models are normalized before writing, lookups are
+// normalized the same way, and normalize_text matches for backfills in SQL
+func TestNormalizeOnWriteAndLookup(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "normalize.db"))
+
+	globalRepositories.mu.Lock()
+	saved := globalRepositories.repositories
+	globalRepositories.repositories = nil
+	globalRepositories.mu.Unlock()
+	defer func() {
+		globalRepositories.mu.Lock()
+		globalRepositories.repositories = saved
+		globalRepositories.mu.Unlock()
+	}()
+	RegisterRepository(Repository{Table: "users", Model: normalizedUser{}})
+
+	username := " Zoë "
+	user := normalizedUser{Email: " Ann@Example.COM", Username: &username, Bio: " Hi "}
+	if err := NormalizeModel(&user); err != nil {
+		t.Fatalf("NormalizeModel failed: %v", err)
+	}
+	if user.Email != "ann@example.com" || *user.Username != "Zoë" || user.Bio != " Hi " {
+		t.Errorf("Unexpected normalized model: %q %q %q", user.Email, *user.Username, user.Bio)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, username TEXT, bio TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := ExecWithRetry(db, "INSERT INTO users (email, username, bio) VALUES (?, ?, ?)", user.Email, *user.Username, user.Bio); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	lookup, err := NormalizeValue("users", "email", "ANN@example.com  ")
+	if err != nil {
+		t.Fatalf("NormalizeValue failed: %v", err)
+	}
+	var id int64
+	if err := db.QueryRow("SELECT id FROM users WHERE email = ?", lookup).Scan(&id); err != nil {
+		t.Errorf("Expected the normalized lookup to find the row: %v", err)
+	}
+	if err := db.QueryRow("SELECT id FROM users WHERE email = normalize_text(?, 'identifier')", " ANN@EXAMPLE.com").Scan(&id); err != nil {
+		t.Errorf("Expected normalize_text to match the stored value: %v", err)
+	}
+	if _, err := NormalizeValue("users", "missing", "x"); err == nil {
+		t.Error("Expected an unknown column to be rejected")
+	}
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultBackfillBatchSize is the number of rows copied per backfill transaction
+const DefaultBackfillBatchSize = 1000
+
+// createTablePattern matches the table name of a CREATE TABLE statement
+var createTablePattern = regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+(IF\s+NOT\s+EXISTS\s+)?("(?:[^"]|"")+"|[\w.]+)`)
+
+// OnlineBackfill describes how rows are copied from the old table into the new one
+type OnlineBackfill struct {
+	Columns   []string // Columns of the new table to fill
+	Select    []string // Expression over the old table for each column (default: the column name)
+	BatchSize int      // Rows copied per transaction (default: DefaultBackfillBatchSize)
+	Indexes   []string // Statements run after the swap (e.g., CREATE INDEX on the new table)
+}
+
+// OnlineAlter rewrites a large table without blocking writes for the duration of the copy.
+// newDDL is the CREATE TABLE statement for the new shape; its table name is replaced with a
+// shadow table name. Triggers keep the shadow table in sync while existing rows are copied in
+// short transactions, then the old table is dropped and the shadow table renamed in one
+// transaction. Rows are matched by rowid, so the old table must not be WITHOUT ROWID.
+func OnlineAlter(ctx context.Context, table, newDDL string, backfill OnlineBackfill) error {
+	if err := checkWriteGuards(ctx); err != nil {
+		return err
+	}
+	if len(backfill.Columns) == 0 {
+		return fmt.Errorf("online alter of %s requires backfill columns", table)
+	}
+	if len(backfill.Select) == 0 {
+		backfill.Select = make([]string, len(backfill.Columns))
+		for i, column := range backfill.Columns {
+			backfill.Select[i] = quoteIdentifier(column)
+		}
+	}
+	if len(backfill.Select) != len(backfill.Columns) {
+		return fmt.Errorf("online alter of %s has %d columns but %d select expressions", table, len(backfill.Columns), len(backfill.Select))
+	}
+	if backfill.BatchSize <= 0 {
+		backfill.BatchSize = DefaultBackfillBatchSize
+	}
+
+	shadow := "_" + table + "_shadow"
+	match := createTablePattern.FindStringSubmatchIndex(newDDL)
+	if match == nil {
+		return fmt.Errorf("online alter of %s requires a CREATE TABLE statement", table)
+	}
+	shadowDDL := newDDL[:match[4]] + quoteIdentifier(shadow) + newDDL[match[5]:]
+	triggers := shadowSyncTriggers(table, shadow, backfill)
+
+	if _, tracked := ctx.Value(migrationTaskKey{}).(*migrationTask); !tracked {
+		var done func()
+		ctx, done = TrackMigrationProgress(ctx, "online-alter", table)
+		defer done()
+	}
+
+	// Start from a clean slate in case a previous attempt was interrupted
+	if err := dropShadow(ctx, shadow, triggers); err != nil {
+		return err
+	}
+
+	logf("🔧 Online alter of %s: creating shadow table", table)
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, shadowDDL); err != nil {
+			return fmt.Errorf("failed to create shadow table: %w", err)
+		}
+		return nil
+	})
+	if err == nil {
+		err = ApplyTriggers(ctx, triggers...)
+	}
+	if err == nil {
+		err = backfillShadow(ctx, table, shadow, backfill)
+	}
+	if err == nil {
+		err = swapShadow(ctx, table, shadow, triggers, backfill.Indexes)
+	}
+	if err != nil {
+		if cleanupErr := dropShadow(ctx, shadow, triggers); cleanupErr != nil {
+			logErrorf("❌ Failed to clean up shadow table %s: %v", shadow, cleanupErr)
+		}
+		return fmt.Errorf("online alter of %s failed: %w", table, err)
+	}
+
+	logf("✅ Online alter of %s completed", table)
+	return nil
+}
+
+// shadowSyncTriggers returns triggers that mirror writes on the old table into the shadow table
+func shadowSyncTriggers(table, shadow string, backfill OnlineBackfill) []Trigger {
+	t, s := quoteIdentifier(table), quoteIdentifier(shadow)
+	columns := make([]string, len(backfill.Columns))
+	for i, column := range backfill.Columns {
+		columns[i] = quoteIdentifier(column)
+	}
+	upsert := fmt.Sprintf("INSERT OR REPLACE INTO %s (rowid, %s) SELECT rowid, %s FROM %s WHERE rowid = NEW.rowid;",
+		s, strings.Join(columns, ", "), strings.Join(backfill.Select, ", "), t)
+
+	trigger := func(suffix, event, body string) Trigger {
+		name := shadow + "_" + suffix
+		return Trigger{
+			Name:  name,
+			Table: table,
+			SQL: fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER %s ON %s\nFOR EACH ROW\nBEGIN\n    %s\nEND;",
+				quoteIdentifier(name), event, t, body),
+		}
+	}
+	return []Trigger{
+		trigger("insert", "INSERT", upsert),
+		trigger("update", "UPDATE", fmt.Sprintf("DELETE FROM %s WHERE rowid = OLD.rowid;\n    %s", s, upsert)),
+		trigger("delete", "DELETE", fmt.Sprintf("DELETE FROM %s WHERE rowid = OLD.rowid;", s)),
+	}
+}
+
+// backfillShadow copies existing rows into the shadow table in rowid-ordered chunks
+func backfillShadow(ctx context.Context, table, shadow string, backfill OnlineBackfill) error {
+	t, s := quoteIdentifier(table), quoteIdentifier(shadow)
+	columns := make([]string, len(backfill.Columns))
+	for i, column := range backfill.Columns {
+		columns[i] = quoteIdentifier(column)
+	}
+	copyChunk := fmt.Sprintf("INSERT OR IGNORE INTO %s (rowid, %s) SELECT rowid, %s FROM %s WHERE rowid > ? AND rowid <= ?",
+		s, strings.Join(columns, ", "), strings.Join(backfill.Select, ", "), t)
+	chunkEnd := fmt.Sprintf("SELECT MAX(rowid) FROM (SELECT rowid FROM %s WHERE rowid > ? ORDER BY rowid LIMIT ?)", t)
+
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var total int64
+	if err := QueryRowWithRetry(db, fmt.Sprintf("SELECT COUNT(*) FROM %s", t)).Scan(&total); err != nil {
+		return fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+
+	var last int64 = -1 << 63
+	var rows int64
+	copied := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var end sql.NullInt64
+		var chunkRows int64
+		err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+			if err := tx.QueryRowContext(ctx, chunkEnd, last, backfill.BatchSize).Scan(&end); err != nil {
+				return err
+			}
+			if !end.Valid {
+				return nil
+			}
+			result, err := tx.ExecContext(ctx, copyChunk, last, end.Int64)
+			if err == nil {
+				chunkRows, _ = result.RowsAffected()
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backfill shadow table: %w", err)
+		}
+		if !end.Valid {
+			logf("📋 Backfilled %d chunks into %s", copied, shadow)
+			return nil
+		}
+		last = end.Int64
+		copied++
+		rows += chunkRows
+		ReportMigrationProgress(ctx, rows, total, "rows")
+	}
+}
+
+// swapShadow replaces the old table with the shadow table in a single transaction
+func swapShadow(ctx context.Context, table, shadow string, triggers []Trigger, indexes []string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Dropping the old table must not cascade into referencing tables, and renaming must not
+	// fail on views that reference the table name while it is briefly missing; both pragmas
+	// are ignored inside a transaction, so they are set on the connection first
+	var foreignKeys int
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), fmt.Sprintf("PRAGMA foreign_keys = %d", foreignKeys))
+	if _, err := conn.ExecContext(ctx, "PRAGMA legacy_alter_table = ON"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA legacy_alter_table = OFF")
+
+	return retryDatabaseOperationContext(ctx, func() error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		statements := make([]string, 0, len(triggers)+2+len(indexes))
+		for _, t := range triggers {
+			statements = append(statements, t.DropSQL())
+		}
+		statements = append(statements,
+			fmt.Sprintf("DROP TABLE %s", quoteIdentifier(table)),
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdentifier(shadow), quoteIdentifier(table)),
+		)
+		statements = append(statements, indexes...)
+
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to swap shadow table (%s): %w", stmt, err)
+			}
+		}
+		return tx.Commit()
+	}, DefaultRetryConfig())
+}
+
+// dropShadow removes the shadow table and its sync triggers
+func dropShadow(ctx context.Context, shadow string, triggers []Trigger) error {
+	return WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		for _, t := range triggers {
+			if _, err := tx.ExecContext(ctx, t.DropSQL()); err != nil {
+				return fmt.Errorf("failed to drop trigger %s: %w", t.Name, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteIdentifier(shadow)); err != nil {
+			return fmt.Errorf("failed to drop shadow table: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// TestOnlineAlterRewritesTable verifies that rows are copied in chunks with the new shape,
+// that writes during the copy are mirrored, and that the swap leaves a single table with its indexes
+func TestOnlineAlterRewritesTable(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "alter.db"))
+	ctx := context.Background()
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i := 1; i <= 25; i++ {
+		if _, err := db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", i, fmt.Sprintf("user%d", i)); err != nil {
+			t.Fatalf("Failed to insert user: %v", err)
+		}
+	}
+
+	backfill := OnlineBackfill{
+		Columns:   []string{"id", "name", "name_upper"},
+		Select:    []string{"id", "name", "upper(name)"},
+		BatchSize: 10,
+		Indexes:   []string{"CREATE INDEX idx_users_name_upper ON users(name_upper)"},
+	}
+
+	// Writes to the old table while the shadow exists are mirrored by the sync triggers
+	triggers := shadowSyncTriggers("users", "_users_shadow", backfill)
+	if _, err := db.Exec("CREATE TABLE _users_shadow (id INTEGER PRIMARY KEY, name TEXT, name_upper TEXT)"); err != nil {
+		t.Fatalf("Failed to create shadow table: %v", err)
+	}
+	if err := ApplyTriggers(ctx, triggers...); err != nil {
+		t.Fatalf("Failed to apply sync triggers: %v", err)
+	}
+	statements := []string{
+		"INSERT INTO users (id, name) VALUES (26, 'late')",
+		"UPDATE users SET name = 'renamed' WHERE id = 2",
+		"DELETE FROM users WHERE id = 3",
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", stmt, err)
+		}
+	}
+	var mirrored string
+	if err := db.QueryRow("SELECT name_upper FROM _users_shadow WHERE id = 2").Scan(&mirrored); err != nil || mirrored != "RENAMED" {
+		t.Fatalf("Expected update to be mirrored into the shadow table, got %q (%v)", mirrored, err)
+	}
+	if err := dropShadow(ctx, "_users_shadow", triggers); err != nil {
+		t.Fatalf("Failed to drop shadow table: %v", err)
+	}
+
+	err = OnlineAlter(ctx, "users", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, name_upper TEXT)", backfill)
+	if err != nil {
+		t.Fatalf("OnlineAlter failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE name_upper = upper(name)").Scan(&count); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	if count != 25 {
+		t.Errorf("Expected 25 rewritten users, got %d", count)
+	}
+	var leftovers int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name LIKE '_users_shadow%'").Scan(&leftovers); err != nil {
+		t.Fatalf("Failed to check schema: %v", err)
+	}
+	if leftovers != 0 {
+		t.Errorf("Expected shadow table and triggers to be removed, found %d objects", leftovers)
+	}
+	var indexes int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_users_name_upper'").Scan(&indexes); err != nil || indexes != 1 {
+		t.Errorf("Expected index to be created after the swap, got %d (%v)", indexes, err)
+	}
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PartitionPeriod is the span of time one partition of a partitioned table holds
+type PartitionPeriod string
+
+// Partition periods
+const (
+	PartitionDaily   PartitionPeriod = "daily"   // events_2024_05_17
+	PartitionMonthly PartitionPeriod = "monthly" // events_2024_05
+	PartitionYearly  PartitionPeriod = "yearly"  // events_2024
+)
+
+// PartitionedTable manages per-period physical tables (events_2024_05, ...) behind a
+// logical name. Partitions are plain tables created from Columns; a view named after
+// the logical table unions all of them for unbounded reads. Times are bucketed in UTC.
+type PartitionedTable struct {
+	Name      string          // Logical table name, e.g. "events"
+	Columns   string          // Column definitions of every partition
+	Indexes   []string        // Column lists to index in every partition, e.g. "user_id, created_at"
+	Period    PartitionPeriod // Default: PartitionMonthly
+	Retention int             // Periods to keep, counting the current one; older partitions are dropped (0 = keep all)
+	Precreate int             // Upcoming partitions Maintain creates ahead of time (default: 1)
+}
+
+// layout returns the time layout of partition name suffixes
+func (period PartitionPeriod) layout() string {
+	switch period {
+	case PartitionDaily:
+		return "2006_01_02"
+	case PartitionYearly:
+		return "2006"
+	default:
+		return "2006_01"
+	}
+}
+
+// start truncates t to the start of its period, in UTC
+func (period PartitionPeriod) start(t time.Time) time.Time {
+	t = t.UTC()
+	switch period {
+	case PartitionDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case PartitionYearly:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// add moves a period start by n periods
+func (period PartitionPeriod) add(t time.Time, n int) time.Time {
+	switch period {
+	case PartitionDaily:
+		return t.AddDate(0, 0, n)
+	case PartitionYearly:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, n, 0)
+	}
+}
+
+// PartitionFor returns the name of the partition holding time t
+func (p *PartitionedTable) PartitionFor(t time.Time) string {
+	return p.Name + "_" + t.UTC().Format(p.Period.layout())
+}
+
+// Ensure creates the partition holding time t (and its indexes) if it does not exist
+// and returns its name
+func (p *PartitionedTable) Ensure(ctx context.Context, t time.Time) (string, error) {
+	name := p.PartitionFor(t)
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoteIdentifier(name), p.Columns)); err != nil {
+			return err
+		}
+		for i, columns := range p.Indexes {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)",
+				quoteIdentifier(fmt.Sprintf("idx_%s_%d", name, i)), quoteIdentifier(name), columns)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create partition %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// Insert routes a row to the partition for time at, creating the partition if needed
+func (p *PartitionedTable) Insert(ctx context.Context, at time.Time, columns []string, values ...interface{}) (sql.Result, error) {
+	name, err := p.Ensure(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return ExecContextWithRetry(ctx, db, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdentifier(name), quoteColumns(columns),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")), values...)
+}
+
+// Partitions returns the existing partitions, oldest first
+func (p *PartitionedTable) Partitions(ctx context.Context) ([]string, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND substr(name, 1, ?) = ?", len(p.Name)+1, p.Name+"_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", p.Name, err)
+	}
+	defer rows.Close()
+	var partitions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if _, ok := p.partitionTime(name); ok {
+			partitions = append(partitions, name)
+		}
+	}
+	sort.Strings(partitions)
+	return partitions, rows.Err()
+}
+
+// partitionTime parses the period start of a partition name
+func (p *PartitionedTable) partitionTime(name string) (time.Time, bool) {
+	t, err := time.Parse(p.Period.layout(), strings.TrimPrefix(name, p.Name+"_"))
+	return t, err == nil && p.PartitionFor(t) == name
+}
+
+// Range returns a subquery reading the partitions that overlap [from, to), for use as
+// "SELECT ... FROM " + subquery + " WHERE created_at >= ?": only those partitions are
+// scanned. When none exist, the partition for from is created so the query stays valid.
+func (p *PartitionedTable) Range(ctx context.Context, from, to time.Time) (string, error) {
+	partitions, err := p.Partitions(ctx)
+	if err != nil {
+		return "", err
+	}
+	first, last := p.Period.start(from), to.UTC()
+	var selects []string
+	for _, name := range partitions {
+		start, _ := p.partitionTime(name)
+		if !start.Before(first) && start.Before(last) {
+			selects = append(selects, "SELECT * FROM "+quoteIdentifier(name))
+		}
+	}
+	if len(selects) == 0 {
+		name, err := p.Ensure(ctx, from)
+		if err != nil {
+			return "", err
+		}
+		selects = append(selects, "SELECT * FROM "+quoteIdentifier(name))
+	}
+	return "(" + strings.Join(selects, " UNION ALL ") + ")", nil
+}
+
+// Maintain creates the current and upcoming partitions, drops partitions past the
+// retention and rebuilds the view named after the logical table over what remains.
+// It returns the names of dropped partitions.
+func (p *PartitionedTable) Maintain(ctx context.Context, now time.Time) ([]string, error) {
+	precreate := p.Precreate
+	if precreate <= 0 {
+		precreate = 1
+	}
+	current := p.Period.start(now)
+	for i := 0; i <= precreate; i++ {
+		if _, err := p.Ensure(ctx, p.Period.add(current, i)); err != nil {
+			return nil, err
+		}
+	}
+
+	partitions, err := p.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var dropped, kept []string
+	for _, name := range partitions {
+		start, _ := p.partitionTime(name)
+		if p.Retention > 0 && start.Before(p.Period.add(current, -(p.Retention-1))) {
+			dropped = append(dropped, name)
+		} else {
+			kept = append(kept, name)
+		}
+	}
+
+	err = WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DROP VIEW IF EXISTS "+quoteIdentifier(p.Name)); err != nil {
+			return err
+		}
+		for _, name := range dropped {
+			if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteIdentifier(name)); err != nil {
+				return err
+			}
+		}
+		selects := make([]string, len(kept))
+		for i, name := range kept {
+			selects[i] = "SELECT * FROM " + quoteIdentifier(name)
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE VIEW %s AS %s", quoteIdentifier(p.Name), strings.Join(selects, " UNION ALL ")))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to maintain partitions of %s: %w", p.Name, err)
+	}
+	if len(dropped) > 0 {
+		logf("🗂️  Dropped %d expired partitions of %s: %s", len(dropped), p.Name, strings.Join(dropped, ", "))
+	}
+	return dropped, nil
+}
+
+// StartMaintenance runs Maintain now and then every interval until ctx is cancelled
+func (p *PartitionedTable) StartMaintenance(ctx context.Context, interval time.Duration) {
+	tracker := startSubsystem("partitions:"+p.Name, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer tracker.stop()
+
+		for {
+			tracker.run(func() error {
+				_, err := p.Maintain(ctx, time.Now())
+				if err != nil && ctx.Err() == nil {
+					logErrorf("❌ Partition maintenance of %s failed: %v", p.Name, err)
+				}
+				return err
+			})
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestPartitionedTableRoutesReadsAndExpires verifies insert routing, range subqueries,
+// precreation, retention and the logical view
+func TestPartitionedTableRoutesReadsAndExpires(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "partitions.db"))
+	ctx := context.Background()
+	events := &PartitionedTable{Name: "events", Columns: "id INTEGER, created_at INTEGER", Indexes: []string{"created_at"}, Retention: 2}
+
+	months := []time.Time{
+		time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+	}
+	for i, at := range months {
+		if _, err := events.Insert(ctx, at, []string{"id", "created_at"}, i+1, at.Unix()); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if got := events.PartitionFor(months[2]); got != "events_2024_05" {
+		t.Errorf("Expected events_2024_05, got %s", got)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	subquery, err := events.Range(ctx, months[1], months[2].AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + subquery).Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected the April and May rows, got %d: %v", count, err)
+	}
+
+	dropped, err := events.Maintain(ctx, months[2])
+	if err != nil {
+		t.Fatalf("Maintain failed: %v", err)
+	}
+	if !reflect.DeepEqual(dropped, []string{"events_2024_03"}) {
+		t.Errorf("Expected March to expire, dropped %v", dropped)
+	}
+	partitions, err := events.Partitions(ctx)
+	if err != nil || !reflect.DeepEqual(partitions, []string{"events_2024_04", "events_2024_05", "events_2024_06"}) {
+		t.Errorf("Unexpected partitions %v: %v", partitions, err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM events").Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected the view to read the retained rows, got %d: %v", count, err)
+	}
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"sort"
+	"time"
+)
+
+// Built-in configuration profiles
+const (
+	ProfileDev     = "dev"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+)
+
+// profiles are the presets applied on top of DefaultConfig, by name
+var profiles = map[string]func(*Config){
+	// Verbose, human-readable logs, strict schema checks and a short retry budget so
+	// lock problems surface quickly instead of hanging a dev server
+	ProfileDev: func(c *Config) {
+		c.LogLevel = "info"
+		c.LogFormat = string(LogFormatEmoji)
+		c.ForeignKeys = true
+		c.TracingAPIKeySecretARN = ""
+		c.Retry = &RetryPolicy{
+			Read:  shortRetryConfig(2 * time.Second),
+			Write: shortRetryConfig(2 * time.Second),
+			DDL:   shortRetryConfig(5 * time.Second),
+		}
+	},
+	// Production settings with machine-readable logs, for rehearsing releases
+	ProfileStaging: func(c *Config) {
+		c.LogLevel = "info"
+		c.LogFormat = string(LogFormatJSON)
+		c.ForeignKeys = true
+		c.StatementStats = DefaultMaxStatements
+	},
+	// WAL journaling, quiet JSON logs, statement stats, a circuit breaker, bounded result
+	// sets and reads that give up before a typical API gateway timeout
+	ProfileProd: func(c *Config) {
+		c.LogLevel = "warn"
+		c.LogFormat = string(LogFormatJSON)
+		c.ForeignKeys = true
+		c.JournalMode = "wal"
+		c.CircuitBreakerThreshold = 5
+		c.CircuitBreakerCooldown = DefaultCircuitBreakerCooldown
+		c.MaxResultRows = 100000
+		c.StatementStats = DefaultMaxStatements
+		c.Retry = &RetryPolicy{
+			Read:  shortRetryConfig(5 * time.Second),
+			Write: DefaultRetryConfig(),
+			DDL:   DefaultRetryConfig(),
+		}
+	},
+}
+
+// shortRetryConfig returns DefaultRetryConfig with a smaller budget
+func shortRetryConfig(budget time.Duration) RetryConfig {
+	config := DefaultRetryConfig()
+	config.MaxRetryDuration = budget
+	return config
+}
+
+// ProfileNames returns the names of the built-in profiles
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile loads the configuration like LoadConfig, starting from the named preset instead
+// of the one in DATABASE_PROFILE. Overrides still win over the preset:
+//
+//	cfg, err := database.Profile(database.ProfileProd, func(c *database.Config) {
+//	    c.MaxResultRows = 0 // this service streams exports
+//	})
+func Profile(name string, overrides ...func(*Config)) (*Config, error) {
+	return loadConfig(name, "", overrides)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestProfilesApplyPresetsBelowEnvironmentAndOverrides verifies presets, their precedence
+// and that UseConfig installs a profile's retry policy
+func TestProfilesApplyPresetsBelowEnvironmentAndOverrides(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "profile.db"))
+	t.Setenv("DATABASE_LOG_LEVEL", "info")
+
+	cfg, err := Profile(ProfileProd, func(c *Config) { c.MaxResultRows = 0 })
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	if cfg.LogFormat != "json" || cfg.JournalMode != "wal" || cfg.CircuitBreakerThreshold <= 0 || cfg.RequireSignedMigrations || cfg.LogLevel != "info" || cfg.MaxResultRows != 0 {
+		t.Errorf("Unexpected prod config: %+v", cfg)
+	}
+	for name, want := range map[string]string{
+		"DATABASE_LOG_FORMAT":      ConfigSourceProfile,
+		"DATABASE_LOG_LEVEL":       ConfigSourceEnv,
+		"DATABASE_MAX_RESULT_ROWS": ConfigSourceOverride,
+	} {
+		if got := cfg.Source(name); got != want {
+			t.Errorf("Expected %s from %s, got %s", name, want, got)
+		}
+	}
+
+	if _, err := Profile("qa"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected an unknown profile to be rejected, got %v", err)
+	}
+
+	t.Setenv("DATABASE_PROFILE", ProfileDev)
+	dev, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	UseConfig(dev)
+	defer SetRetryPolicy(DefaultRetryPolicy())
+	defer UseConfig(nil)
+	if got := retryConfigFor(context.Background(), "UPDATE users SET name = ?").MaxRetryDuration; got != 2*time.Second {
+		t.Errorf("Expected the dev write budget to be installed, got %v", got)
+	}
+}
+
+// TestProdProfileEnablesWAL verifies connections opened under the prod profile use WAL
+func TestProdProfileEnablesWAL(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "wal.db"))
+
+	cfg, err := Profile(ProfileProd)
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	UseConfig(cfg)
+	defer SetRetryPolicy(DefaultRetryPolicy())
+	defer UseConfig(nil)
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Errorf("Expected journal_mode wal, got %s", mode)
+	}
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnsureOptions configures database file provisioning
+type EnsureOptions struct {
+	DirMode       os.FileMode // Mode for created parent directories (default: 0755)
+	FileMode      os.FileMode // Mode for the database file (default: 0644)
+	Chown         bool        // Apply UID/GID ownership to the database file
+	UID           int         // Owner user ID (-1 leaves unchanged)
+	GID           int         // Owner group ID (-1 leaves unchanged)
+	PageSize      int         // page_size applied on creation (0 keeps SQLite's default)
+	AutoVacuum    string      // auto_vacuum applied on creation: "NONE", "FULL" or "INCREMENTAL"
+	Preallocate   int64       // Bytes to pre-size a new file to (requires AutoVacuum NONE)
+	VerifyLocking bool        // Check that the filesystem enforces SQLite write locks
+}
+
+// DefaultEnsureOptions returns the default provisioning options
+func DefaultEnsureOptions() EnsureOptions {
+	return EnsureOptions{
+		DirMode:       0755,
+		FileMode:      0644,
+		UID:           -1,
+		GID:           -1,
+		VerifyLocking: true,
+	}
+}
+
+// EnsureDatabase makes sure the database file at path exists and is usable: it creates
+// parent directories, applies file mode and ownership, configures page_size and auto_vacuum
+// on creation, optionally pre-sizes the file, and verifies locking semantics
+func EnsureDatabase(path string, opts EnsureOptions) error {
+	if opts.DirMode == 0 {
+		opts.DirMode = 0755
+	}
+	if opts.FileMode == 0 {
+		opts.FileMode = 0644
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), opts.DirMode); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	_, statErr := os.Stat(path)
+	created := os.IsNotExist(statErr)
+	if statErr != nil && !created {
+		return fmt.Errorf("failed to stat database file: %w", statErr)
+	}
+
+	if created {
+		logf("🆕 Provisioning database file: %s", path)
+		if err := initializeDatabaseFile(path, opts); err != nil {
+			os.Remove(path)
+			return err
+		}
+	}
+
+	if err := os.Chmod(path, opts.FileMode); err != nil {
+		return fmt.Errorf("failed to set database file mode: %w", err)
+	}
+	if opts.Chown {
+		if err := os.Chown(path, opts.UID, opts.GID); err != nil {
+			return fmt.Errorf("failed to set database file ownership: %w", err)
+		}
+	}
+
+	if opts.VerifyLocking {
+		if err := verifyLocking(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initializeDatabaseFile creates a new database file with the requested layout
+func initializeDatabaseFile(path string, opts EnsureOptions) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// page_size and auto_vacuum only take effect before the first table is created
+	if opts.PageSize > 0 {
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA page_size = %d", opts.PageSize)); err != nil {
+			return fmt.Errorf("failed to set page_size: %w", err)
+		}
+	}
+	autoVacuum := strings.ToUpper(opts.AutoVacuum)
+	if autoVacuum != "" {
+		switch autoVacuum {
+		case "NONE", "FULL", "INCREMENTAL":
+		default:
+			return fmt.Errorf("invalid auto_vacuum mode: %s", opts.AutoVacuum)
+		}
+		if _, err := db.Exec("PRAGMA auto_vacuum = " + autoVacuum); err != nil {
+			return fmt.Errorf("failed to set auto_vacuum: %w", err)
+		}
+	}
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to initialize database file: %w", err)
+	}
+
+	if opts.Preallocate > 0 {
+		if autoVacuum != "" && autoVacuum != "NONE" {
+			return fmt.Errorf("preallocation requires auto_vacuum NONE")
+		}
+		// Grow the file with a throwaway blob; freed pages stay allocated without auto_vacuum
+		statements := []string{
+			"CREATE TABLE _preallocate (data BLOB)",
+			fmt.Sprintf("INSERT INTO _preallocate (data) VALUES (zeroblob(%d))", opts.Preallocate),
+			"DROP TABLE _preallocate",
+		}
+		for _, stmt := range statements {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("failed to preallocate database file: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyLocking checks that a second connection cannot take the write lock while
+// another connection holds it, which fails on filesystems with broken locking
+func verifyLocking(path string) error {
+	dsn := path + "?_pragma=busy_timeout(0)"
+
+	holder, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+	defer holder.Close()
+	contender, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+	defer contender.Close()
+
+	ctx := context.Background()
+	holderConn, err := holder.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open locking check connection: %w", err)
+	}
+	defer holderConn.Close()
+	contenderConn, err := contender.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open locking check connection: %w", err)
+	}
+	defer contenderConn.Close()
+
+	if _, err := holderConn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("failed to take write lock during locking check: %w", err)
+	}
+	defer holderConn.ExecContext(ctx, "ROLLBACK")
+
+	if _, err := contenderConn.ExecContext(ctx, "BEGIN IMMEDIATE"); err == nil {
+		contenderConn.ExecContext(ctx, "ROLLBACK")
+		return fmt.Errorf("filesystem does not enforce SQLite write locks for %s", path)
+	}
+	return nil
+}
+
+// OpenAndMigrate provisions the database file at DATABASE_FILE, runs all registered
+// migrations and returns a connection
+func OpenAndMigrate(opts EnsureOptions) (*sql.DB, error) {
+	databaseFile := configValue("DATABASE_FILE")
+	if databaseFile == "" {
+		return nil, fmt.Errorf("DATABASE_FILE environment variable is required but not set")
+	}
+
+	if err := EnsureDatabase(databaseFile, opts); err != nil {
+		return nil, err
+	}
+	if err := RunAllMigrations(); err != nil {
+		return nil, err
+	}
+	return GetDB()
+}
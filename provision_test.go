@@ -0,0 +1,129 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pragmaInt reads an integer pragma from the database file at path
+func pragmaInt(t *testing.T, path, pragma string) int64 {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var value int64
+	if err := db.QueryRow("PRAGMA " + pragma).Scan(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// TestEnsureDatabaseProvisionsNewFiles verifies directories, mode, page_size,
+// auto_vacuum and preallocation of a newly created database
+func TestEnsureDatabaseProvisionsNewFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "app.db")
+
+	opts := DefaultEnsureOptions()
+	opts.FileMode = 0600
+	opts.PageSize = 8192
+	opts.AutoVacuum = "none"
+	opts.Preallocate = 1 << 20
+	if err := EnsureDatabase(path, opts); err != nil {
+		t.Fatalf("EnsureDatabase failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected the database file to exist: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+	if info.Size() < opts.Preallocate {
+		t.Errorf("Expected the file to be preallocated to %d bytes, got %d", opts.Preallocate, info.Size())
+	}
+	if got := pragmaInt(t, path, "page_size"); got != 8192 {
+		t.Errorf("Expected page_size 8192, got %d", got)
+	}
+	if got := pragmaInt(t, path, "auto_vacuum"); got != 0 {
+		t.Errorf("Expected auto_vacuum NONE, got %d", got)
+	}
+	if got := pragmaInt(t, path, "freelist_count"); got == 0 {
+		t.Error("Expected preallocated pages to stay on the freelist")
+	}
+}
+
+// TestEnsureDatabaseKeepsExistingFiles verifies an existing database is not
+// reinitialized, while its mode is still applied
+func TestEnsureDatabaseKeepsExistingFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	if err := EnsureDatabase(path, EnsureOptions{PageSize: 4096}); err != nil {
+		t.Fatalf("EnsureDatabase failed: %v", err)
+	}
+
+	opts := DefaultEnsureOptions()
+	opts.FileMode = 0640
+	opts.PageSize = 16384
+	if err := EnsureDatabase(path, opts); err != nil {
+		t.Fatalf("EnsureDatabase on an existing file failed: %v", err)
+	}
+	if got := pragmaInt(t, path, "page_size"); got != 4096 {
+		t.Errorf("Expected the existing page_size to be kept, got %d", got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 on the existing file, got %v", info.Mode().Perm())
+	}
+}
+
+// TestEnsureDatabaseRejectsInvalidLayouts verifies invalid options fail without leaving
+// a half-initialized file behind
+func TestEnsureDatabaseRejectsInvalidLayouts(t *testing.T) {
+	dir := t.TempDir()
+	for name, opts := range map[string]EnsureOptions{
+		"bad_vacuum.db":    {AutoVacuum: "sometimes"},
+		"full_prealloc.db": {AutoVacuum: "FULL", Preallocate: 1 << 16},
+	} {
+		path := filepath.Join(dir, name)
+		if err := EnsureDatabase(path, opts); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed after a failed provision, got %v", name, err)
+		}
+	}
+}
+
+// TestVerifyLockingOnLocalFilesystem verifies the locking check passes where SQLite
+// locks work
+func TestVerifyLockingOnLocalFilesystem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	if err := EnsureDatabase(path, EnsureOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyLocking(path); err != nil {
+		t.Fatalf("Expected locking to be enforced on a local filesystem: %v", err)
+	}
+}
+
+// TestOpenAndMigrate verifies DATABASE_FILE is provisioned and migrated
+func TestOpenAndMigrate(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "data", "app.db"))
+	useBootstrapRegistries(t)
+
+	db, err := OpenAndMigrate(DefaultEnsureOptions())
+	if err != nil {
+		t.Fatalf("OpenAndMigrate failed: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("INSERT INTO notes (body) VALUES ('migrated')"); err != nil {
+		t.Fatalf("Expected the notes migration to have run: %v", err)
+	}
+}
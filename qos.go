@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// QoSClass is the priority lane of a database operation
+type QoSClass int
+
+// QoS classes
+const (
+	QoSInteractive QoSClass = iota // User-facing operations (default)
+	QoSBatch                       // Background jobs, backfills and bulk imports
+)
+
+// Batch lane tuning
+const (
+	BatchBackoffFactor = 4                    // Multiplier applied to retry delays for batch operations
+	BatchMaxYield      = 2 * time.Second      // Longest a batch operation waits for interactive work to drain
+	batchYieldPoll     = 5 * time.Millisecond // How often a yielding batch operation rechecks
+)
+
+// qosContextKey is the context key for the QoS class
+type qosContextKey struct{}
+
+// activeInteractive counts interactive operations currently executing
+var activeInteractive atomic.Int64
+
+// WithQoS returns a context that runs database operations in the given priority lane
+func WithQoS(ctx context.Context, class QoSClass) context.Context {
+	return context.WithValue(ctx, qosContextKey{}, class)
+}
+
+// QoSFromContext returns the priority lane set on ctx (QoSInteractive if unset)
+func QoSFromContext(ctx context.Context) QoSClass {
+	if class, ok := ctx.Value(qosContextKey{}).(QoSClass); ok {
+		return class
+	}
+	return QoSInteractive
+}
+
+// String returns the name of the QoS class
+func (c QoSClass) String() string {
+	if c == QoSBatch {
+		return "batch"
+	}
+	return "interactive"
+}
+
+// enterLane admits an operation into its priority lane. Batch operations yield while
+// interactive operations are executing, up to BatchMaxYield, unless they hold the write
+// queue's turn: those yielded before taking it. The returned function must be called
+// when the operation completes.
+func enterLane(ctx context.Context) (func(), error) {
+	if QoSFromContext(ctx) != QoSBatch {
+		activeInteractive.Add(1)
+		return func() { activeInteractive.Add(-1) }, nil
+	}
+	if ctx.Value(writeQueueHeldKey{}) != nil {
+		return func() {}, nil
+	}
+	if err := yieldToInteractive(ctx); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+// yieldToInteractive waits while interactive operations are executing, up to BatchMaxYield
+func yieldToInteractive(ctx context.Context) error {
+	deadline := time.Now().Add(BatchMaxYield)
+	for activeInteractive.Load() > 0 && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(batchYieldPoll):
+		}
+	}
+	return nil
+}
+
+// laneRetryConfig adjusts a retry config for the priority lane set on ctx,
+// so batch operations back off further than interactive ones under contention
+func laneRetryConfig(ctx context.Context, config RetryConfig) RetryConfig {
+	if QoSFromContext(ctx) == QoSBatch {
+		config.BaseDelay *= BatchBackoffFactor
+		config.MaxDelay *= BatchBackoffFactor
+	}
+	return config
+}
+
+// retryInLane runs a retried operation in the priority lane set on ctx
+func retryInLane(ctx context.Context, operation func() error, config RetryConfig) error {
+	leave, err := enterLane(ctx)
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	return retryDatabaseOperationContext(ctx, operation, laneRetryConfig(ctx, config))
+}
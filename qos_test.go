@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestBatchLaneYieldsToInteractive verifies that batch operations wait for in-flight
+// interactive operations and back off further on retry
+func TestBatchLaneYieldsToInteractive(t *testing.T) {
+	leaveInteractive, err := enterLane(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to enter interactive lane: %v", err)
+	}
+
+	batchCtx := WithQoS(context.Background(), QoSBatch)
+	admitted := make(chan struct{})
+	go func() {
+		leave, err := enterLane(batchCtx)
+		if err == nil {
+			leave()
+		}
+		close(admitted)
+	}()
+
+	select {
+	case <-admitted:
+		t.Fatal("Batch operation was admitted while an interactive operation was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	leaveInteractive()
+	select {
+	case <-admitted:
+	case <-time.After(time.Second):
+		t.Fatal("Batch operation was not admitted after the interactive operation finished")
+	}
+
+	config := laneRetryConfig(batchCtx, DefaultRetryConfig())
+	if config.BaseDelay != DefaultBaseDelay*BatchBackoffFactor {
+		t.Errorf("Expected batch base delay %v, got %v", DefaultBaseDelay*BatchBackoffFactor, config.BaseDelay)
+	}
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// QueryParam describes an input parameter of a named query
+type QueryParam struct {
+	Name     string                  // Parameter name, bound as a named SQL argument (e.g., :email)
+	Required bool                    // Reject requests that omit the parameter
+	Validate func(interface{}) error // Optional validation of the decoded JSON value
+}
+
+// NamedQuery is a parameterized, read-only query exposed by the HTTP query service
+type NamedQuery struct {
+	Name        string
+	Description string
+	SQL         string
+	Params      []QueryParam
+}
+
+// QueryServerOptions configures the HTTP query service
+type QueryServerOptions struct {
+	// Authorize is called for every request; returning an error rejects it with 401.
+	// It is required: without it every request is rejected.
+	Authorize func(r *http.Request, queryName string) error
+	// MaxRows caps the number of rows returned per request (default: 1000)
+	MaxRows int
+}
+
+// queryResponse is the JSON body returned for an executed query
+type queryResponse struct {
+	Columns   []string                 `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	Truncated bool                     `json:"truncated,omitempty"`
+}
+
+// queryRegistry manages all registered named queries
+type queryRegistry struct {
+	mu      sync.RWMutex
+	queries map[string]NamedQuery
+}
+
+// Global named query registry instance
+var globalQueries = &queryRegistry{
+	queries: make(map[string]NamedQuery),
+}
+
+// RegisterQuery registers a named query with the global query registry
+func RegisterQuery(query NamedQuery) {
+	globalQueries.mu.Lock()
+	defer globalQueries.mu.Unlock()
+
+	logf("🔎 Registering named query: %s", query.Name)
+	globalQueries.queries[query.Name] = query
+}
+
+// GetRegisteredQueries returns all registered named queries sorted by name
+func GetRegisteredQueries() []NamedQuery {
+	globalQueries.mu.RLock()
+	defer globalQueries.mu.RUnlock()
+
+	queries := make([]NamedQuery, 0, len(globalQueries.queries))
+	for _, q := range globalQueries.queries {
+		queries = append(queries, q)
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+	return queries
+}
+
+// NewQueryHandler returns an http.Handler exposing registered named queries:
+//
+//	GET  /queries         lists registered queries and their parameters
+//	POST /queries/{name}  executes a query with {"params": {...}} and returns rows as JSON
+//
+// Queries run inside a transaction that is always rolled back, so they cannot persist writes.
+func NewQueryHandler(opts QueryServerOptions) http.Handler {
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = 1000
+	}
+	if opts.Authorize == nil {
+		logWarnf("⚠️  Query handler has no Authorize hook; every request will be rejected")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /queries", func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeQueryRequest(w, r, opts, "") {
+			return
+		}
+
+		type paramInfo struct {
+			Name     string `json:"name"`
+			Required bool   `json:"required"`
+		}
+		type queryInfo struct {
+			Name        string      `json:"name"`
+			Description string      `json:"description,omitempty"`
+			Params      []paramInfo `json:"params"`
+		}
+
+		var list []queryInfo
+		for _, q := range GetRegisteredQueries() {
+			info := queryInfo{Name: q.Name, Description: q.Description, Params: []paramInfo{}}
+			for _, p := range q.Params {
+				info.Params = append(info.Params, paramInfo{Name: p.Name, Required: p.Required})
+			}
+			list = append(list, info)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"queries": list})
+	})
+
+	mux.HandleFunc("POST /queries/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if !authorizeQueryRequest(w, r, opts, name) {
+			return
+		}
+
+		globalQueries.mu.RLock()
+		query, ok := globalQueries.queries[name]
+		globalQueries.mu.RUnlock()
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown query: %s", name))
+			return
+		}
+
+		var body struct {
+			Params map[string]interface{} `json:"params"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+				return
+			}
+		}
+
+		args, err := bindQueryParams(query, body.Params)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		response, err := runReadOnlyQuery(r.Context(), query.SQL, args, opts.MaxRows)
+		if err != nil {
+			logErrorf("❌ Named query %s failed: %v", name, err)
+			writeJSONError(w, http.StatusInternalServerError, "query failed")
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	return mux
+}
+
+// errNoAuthorizer rejects requests to a query or GraphQL handler built without an
+// Authorize hook, so a missing hook never exposes the database
+var errNoAuthorizer = errors.New("endpoint has no Authorize hook configured")
+
+// authorizeQueryRequest runs the authorization hook and writes a 401 response on failure
+func authorizeQueryRequest(w http.ResponseWriter, r *http.Request, opts QueryServerOptions, name string) bool {
+	if opts.Authorize == nil {
+		writeJSONError(w, http.StatusUnauthorized, errNoAuthorizer.Error())
+		return false
+	}
+	if err := opts.Authorize(r, name); err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		return false
+	}
+	return true
+}
+
+// bindQueryParams validates request parameters against a query definition
+// and converts them into named SQL arguments
+func bindQueryParams(query NamedQuery, params map[string]interface{}) ([]interface{}, error) {
+	declared := make(map[string]bool, len(query.Params))
+	args := make([]interface{}, 0, len(query.Params))
+
+	for _, p := range query.Params {
+		declared[p.Name] = true
+		value, ok := params[p.Name]
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("missing required parameter: %s", p.Name)
+			}
+			value = nil
+		}
+		if ok && p.Validate != nil {
+			if err := p.Validate(value); err != nil {
+				return nil, fmt.Errorf("invalid parameter %s: %w", p.Name, err)
+			}
+		}
+		args = append(args, sql.Named(p.Name, value))
+	}
+
+	for name := range params {
+		if !declared[name] {
+			return nil, fmt.Errorf("unknown parameter: %s", name)
+		}
+	}
+	return args, nil
+}
+
+// runReadOnlyQuery executes a query inside a transaction that is always rolled back
+func runReadOnlyQuery(ctx context.Context, query string, args []interface{}, maxRows int) (*queryResponse, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	response := &queryResponse{}
+	err = retryDatabaseOperationContext(ctx, func() error {
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		result, columns, err := scanRowMaps(rows, maxRows+1)
+		if err != nil {
+			return err
+		}
+
+		response.Columns = columns
+		response.Rows = result
+		response.Truncated = false
+		if len(response.Rows) > maxRows {
+			response.Rows = response.Rows[:maxRows]
+			response.Truncated = true
+		}
+		if response.Rows == nil {
+			response.Rows = []map[string]interface{}{}
+		}
+		return nil
+	}, DefaultRetryConfig())
+
+	return response, err
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil && !errors.Is(err, http.ErrHandlerTimeout) {
+		logErrorf("❌ Failed to write JSON response: %v", err)
+	}
+}
+
+// writeJSONError writes a JSON error response
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestQueryHandlerExecutesNamedQuery verifies parameter validation, authorization
+// and that named queries cannot persist writes
+func TestQueryHandlerExecutesNamedQuery(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "queries.db"))
+
+	if _, err := ExecBatch(context.Background(), []Statement{
+		{Query: "CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT)"},
+		{Query: "INSERT INTO users (id, email) VALUES ('u1', 'a@example.com'), ('u2', 'b@example.com')"},
+	}); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	RegisterQuery(NamedQuery{
+		Name: "user-by-email",
+		SQL:  "SELECT id, email FROM users WHERE email = :email",
+		Params: []QueryParam{{
+			Name:     "email",
+			Required: true,
+			Validate: func(v interface{}) error {
+				if s, ok := v.(string); !ok || !strings.Contains(s, "@") {
+					return errors.New("must be an email address")
+				}
+				return nil
+			},
+		}},
+	})
+	RegisterQuery(NamedQuery{Name: "sneaky-delete", SQL: "DELETE FROM users"})
+
+	handler := NewQueryHandler(QueryServerOptions{
+		Authorize: func(r *http.Request, _ string) error {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				return errors.New("unauthorized")
+			}
+			return nil
+		},
+	})
+
+	post := func(name, body string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/queries/"+name, strings.NewReader(body))
+		if auth {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("user-by-email", `{"params":{"email":"a@example.com"}}`, false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", rec.Code)
+	}
+	if rec := post("user-by-email", `{"params":{"email":"nope"}}`, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid parameter, got %d", rec.Code)
+	}
+
+	rec := post("user-by-email", `{"params":{"email":"a@example.com"}}`, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response queryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Rows) != 1 || response.Rows[0]["id"] != "u1" {
+		t.Errorf("Unexpected rows: %v", response.Rows)
+	}
+
+	post("sneaky-delete", "", true)
+	var count int
+	if err := QueryRowWithRetry(mustGetDB(t), "SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected writes from named queries to be rolled back, got %d users", count)
+	}
+}
+
+// TestQueryHandlerRequiresAuthorize verifies that a handler built without an Authorize
+// hook rejects every request instead of serving queries unauthenticated
+func TestQueryHandlerRequiresAuthorize(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "queries.db"))
+	RegisterQuery(NamedQuery{Name: "open-count", SQL: "SELECT 1 AS one"})
+
+	handler := NewQueryHandler(QueryServerOptions{})
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/queries", nil),
+		httptest.NewRequest(http.MethodPost, "/queries/open-count", strings.NewReader(`{"params": {}}`)),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %s %s without Authorize, got %d: %s", req.Method, req.URL.Path, rec.Code, rec.Body.String())
+		}
+	}
+}
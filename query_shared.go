@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// QuerySharedStats reports how many QueryShared calls were served by another call's execution
+type QuerySharedStats struct {
+	Executions int64 `json:"executions"` // Queries actually run
+	Shared     int64 `json:"shared"`     // Calls that received another call's result
+}
+
+var (
+	sharedFlights    flightGroup
+	sharedExecutions atomic.Int64
+	sharedResults    atomic.Int64
+)
+
+// QueryShared runs a read query and returns its rows as column-name keyed maps. Identical
+// concurrent calls (same query text and arguments) are collapsed into one execution whose
+// rows are copied to every caller, shedding duplicate load during traffic spikes and cache
+// misses. Only reads may be shared.
+func QueryShared(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	if ClassifyStatement(query) != StatementRead {
+		return nil, fmt.Errorf("QueryShared only runs read statements: %s", NormalizeStatement(query))
+	}
+
+	var key strings.Builder
+	key.WriteString(query)
+	for _, arg := range args {
+		fmt.Fprintf(&key, "\x00%T:%v", arg, arg)
+	}
+
+	// The query is shared, so one caller's cancellation must not fail the others
+	queryCtx := context.WithoutCancel(ctx)
+	value, err, shared := sharedFlights.do(key.String(), func() (interface{}, error) {
+		sharedExecutions.Add(1)
+		var result []map[string]interface{}
+		recorder, statementCtx := startStatement(queryCtx, "query", query)
+		err := retryInLane(queryCtx, func() error {
+			recorder.attempt()
+			rows, err := db.QueryContext(statementCtx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			result, _, err = scanRowMaps(rows, 0)
+			return err
+		}, retryConfigFor(queryCtx, query))
+		recorder.finish(int64(len(result)), err)
+		return result, err
+	})
+	if shared {
+		sharedResults.Add(1)
+	}
+	if err != nil {
+		return nil, resultError(err)
+	}
+
+	rows := value.([]map[string]interface{})
+	copied := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		copied[i] = copyRow(row)
+	}
+	return copied, nil
+}
+
+// GetQuerySharedStats returns how often QueryShared collapsed identical queries
+func GetQuerySharedStats() QuerySharedStats {
+	return QuerySharedStats{Executions: sharedExecutions.Load(), Shared: sharedResults.Load()}
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestQuerySharedCollapsesConcurrentQueries verifies that identical concurrent reads run
+// once and every caller gets the rows, and that writes are refused
+func TestQuerySharedCollapsesConcurrentQueries(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "shared.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Slow enough that the callers overlap
+	query := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?) SELECT COUNT(*) AS total FROM n"
+	before := GetQuerySharedStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rows, err := QueryShared(context.Background(), db, query, 200000)
+			if err != nil {
+				t.Errorf("Shared query failed: %v", err)
+				return
+			}
+			if len(rows) != 1 || rows[0]["total"] != int64(200000) {
+				t.Errorf("Unexpected rows: %v", rows)
+			}
+		}()
+	}
+	wg.Wait()
+
+	after := GetQuerySharedStats()
+	if executions := after.Executions - before.Executions; executions >= 10 {
+		t.Errorf("Expected concurrent queries to share executions, got %d executions", executions)
+	}
+	if shared := after.Shared - before.Shared; shared == 0 {
+		t.Error("Expected some calls to receive a shared result")
+	}
+
+	if _, err := QueryShared(context.Background(), db, "DELETE FROM sqlite_sequence"); err == nil {
+		t.Error("Expected writes to be refused")
+	}
+}
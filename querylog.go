@@ -0,0 +1,318 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueryLogEntry records one statement executed through the package pipeline
+type QueryLogEntry struct {
+	Time        time.Time `json:"time"`
+	Operation   string    `json:"operation"` // "exec" or "query"
+	Fingerprint string    `json:"fingerprint"`
+	Statement   string    `json:"statement"` // Normalized text with literals stripped
+	DurationMs  float64   `json:"duration_ms"`
+	Rows        int64     `json:"rows"` // Rows affected by exec; -1 when unknown (queries stream rows)
+	Attempts    int       `json:"attempts"`
+	Caller      string    `json:"caller"` // First file:line outside this package and database/sql
+	Error       string    `json:"error,omitempty"`
+	RequestID   string    `json:"request_id,omitempty"` // From WithRequestID
+	TraceID     string    `json:"trace_id,omitempty"`   // Datadog trace of the statement's request
+}
+
+// QueryLogExporter ships batches of query log entries
+type QueryLogExporter interface {
+	Export(ctx context.Context, entries []QueryLogEntry) error
+}
+
+// QueryLogOptions configures the structured query log
+type QueryLogOptions struct {
+	// Exporter receives batches (default: from DATABASE_QUERY_LOG, "stdout" or "otlp")
+	Exporter QueryLogExporter
+	// SampleRate is the fraction of statements logged, 0-1 (default: DATABASE_QUERY_LOG_SAMPLE or 1)
+	SampleRate float64
+	// SlowThreshold logs statements at least this slow regardless of sampling (0: disabled)
+	SlowThreshold time.Duration
+	BatchSize     int           // Entries per export (default: 100)
+	FlushInterval time.Duration // Maximum time an entry waits before export (default: 1s)
+	BufferSize    int           // Entries queued before new ones are dropped (default: 10x BatchSize)
+}
+
+// QueryLog samples executed statements and exports them in the background
+type QueryLog struct {
+	opts    QueryLogOptions
+	entries chan QueryLogEntry
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+// activeQueryLog is the running query log, if any
+var activeQueryLog atomic.Pointer[QueryLog]
+
+// queryLogSkipKey marks a context whose statement is already being recorded, so the
+// pipeline driver does not log it a second time underneath a package helper
+type queryLogSkipKey struct{}
+
+// packagePath is this module's import path, used to find the caller outside the package
+const packagePath = "github.com/realsensesolutions/go-database"
+
+// StartQueryLog starts recording statements run through the retry helpers, DBTX and the
+// pipeline driver until ctx is cancelled. Entries still queued are flushed on shutdown.
+func StartQueryLog(ctx context.Context, opts QueryLogOptions) (*QueryLog, error) {
+	if opts.Exporter == nil {
+		exporter, err := queryLogExporterFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		opts.Exporter = exporter
+	}
+	if opts.SampleRate == 0 {
+		opts.SampleRate = 1
+		if value := configValue("DATABASE_QUERY_LOG_SAMPLE"); value != "" {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DATABASE_QUERY_LOG_SAMPLE %q: %w", value, err)
+			}
+			opts.SampleRate = rate
+		}
+	}
+	if opts.SampleRate < 0 || opts.SampleRate > 1 {
+		return nil, fmt.Errorf("query log sample rate must be between 0 and 1, got %v", opts.SampleRate)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = opts.BatchSize * 10
+	}
+
+	ql := &QueryLog{
+		opts:    opts,
+		entries: make(chan QueryLogEntry, opts.BufferSize),
+		done:    make(chan struct{}),
+	}
+	activeQueryLog.Store(ql)
+
+	go func() {
+		defer close(ql.done)
+		ticker := time.NewTicker(opts.FlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]QueryLogEntry, 0, opts.BatchSize)
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			exportCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := opts.Exporter.Export(exportCtx, batch); err != nil {
+				logWarnf("⚠️  Failed to export %d query log entries: %v", len(batch), err)
+			}
+			cancel()
+			batch = batch[:0]
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				activeQueryLog.CompareAndSwap(ql, nil)
+				for {
+					select {
+					case entry := <-ql.entries:
+						batch = append(batch, entry)
+						if len(batch) >= opts.BatchSize {
+							flush()
+						}
+					default:
+						flush()
+						logf("📝 Query log stopped (%d entries dropped)", ql.Dropped())
+						return
+					}
+				}
+			case entry := <-ql.entries:
+				batch = append(batch, entry)
+				if len(batch) >= opts.BatchSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+
+	logf("📝 Query log started (sample rate %v)", opts.SampleRate)
+	return ql, nil
+}
+
+// Dropped returns how many sampled entries were discarded because the buffer was full
+func (q *QueryLog) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Done is closed once the query log has stopped and flushed its remaining entries
+func (q *QueryLog) Done() <-chan struct{} {
+	return q.done
+}
+
+// queryLogExporterFromEnv builds the exporter selected by DATABASE_QUERY_LOG
+func queryLogExporterFromEnv() (QueryLogExporter, error) {
+	switch value := strings.ToLower(configValue("DATABASE_QUERY_LOG")); value {
+	case "", "stdout":
+		return NewJSONLExporter(os.Stdout), nil
+	case "otlp":
+		endpoint := os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+		if endpoint == "" {
+			base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+			if base == "" {
+				return nil, fmt.Errorf("DATABASE_QUERY_LOG=otlp requires OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+			}
+			endpoint = strings.TrimRight(base, "/") + "/v1/logs"
+		}
+		return NewOTLPLogExporter(endpoint, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown DATABASE_QUERY_LOG exporter %q (use stdout or otlp)", value)
+	}
+}
+
+// jsonlExporter writes one JSON object per line
+type jsonlExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLExporter writes query log entries to w as JSON lines
+func NewJSONLExporter(w io.Writer) QueryLogExporter {
+	return &jsonlExporter{w: w}
+}
+
+func (e *jsonlExporter) Export(ctx context.Context, entries []QueryLogEntry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	encoder := json.NewEncoder(e.w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statementRecorder times one statement for the query log and statement statistics.
+// A nil recorder records nothing, so callers need not check whether either is running.
+type statementRecorder struct {
+	log       *QueryLog
+	stats     *statementStats
+	operation string
+	query     string
+	start     time.Time
+	attempts  int
+	requestID string
+	traceID   string
+}
+
+// startStatement begins recording a statement if the query log or statement statistics
+// are running, returning a context that stops the pipeline driver from recording it again
+func startStatement(ctx context.Context, operation, query string) (*statementRecorder, context.Context) {
+	ql, stats := activeQueryLog.Load(), activeStatementStats.Load()
+	if (ql == nil && stats == nil) || ctx.Value(queryLogSkipKey{}) != nil {
+		return nil, ctx
+	}
+	recorder := &statementRecorder{log: ql, stats: stats, operation: operation, query: query, start: time.Now()}
+	if ql != nil {
+		recorder.requestID, recorder.traceID = RequestIDFromContext(ctx), traceIDFromContext(ctx)
+	}
+	return recorder, context.WithValue(ctx, queryLogSkipKey{}, true)
+}
+
+// attempt counts one execution attempt
+func (r *statementRecorder) attempt() {
+	if r != nil {
+		r.attempts++
+	}
+}
+
+// finish aggregates the statement and samples it into the query log; rows is -1 when unknown
+func (r *statementRecorder) finish(rows int64, err error) {
+	if r == nil {
+		return
+	}
+	duration := time.Since(r.start)
+	statement := NormalizeStatement(r.query)
+	fingerprint := normalizedFingerprint(statement)
+
+	if r.stats != nil {
+		r.stats.record(fingerprint, statement, duration, rows, r.attempts, err)
+	}
+	if r.log == nil {
+		return
+	}
+	slow := r.log.opts.SlowThreshold > 0 && duration >= r.log.opts.SlowThreshold
+	if !slow && rand.Float64() >= r.log.opts.SampleRate {
+		return
+	}
+
+	entry := QueryLogEntry{
+		Time:        r.start,
+		Operation:   r.operation,
+		Fingerprint: fingerprint,
+		Statement:   statement,
+		DurationMs:  float64(duration) / float64(time.Millisecond),
+		Rows:        rows,
+		Attempts:    r.attempts,
+		Caller:      statementCaller(),
+		RequestID:   r.requestID,
+		TraceID:     r.traceID,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	select {
+	case r.log.entries <- entry:
+	default:
+		r.log.dropped.Add(1)
+	}
+}
+
+// rowsAffected returns the affected row count of result, or -1 when unavailable
+func rowsAffected(result interface{ RowsAffected() (int64, error) }) int64 {
+	if result == nil {
+		return -1
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return rows
+}
+
+// statementCaller returns file:line of the first frame outside this package and database/sql
+// (the package's own tests count as callers)
+func statementCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, packagePath+".") && !strings.HasSuffix(frame.File, "_test.go")
+		if !internal && !strings.HasPrefix(frame.Function, "database/sql.") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
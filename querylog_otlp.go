@@ -0,0 +1,112 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// otlpExporter sends query log entries as OTLP log records over HTTP/JSON
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPLogExporter sends query log entries to an OTLP/HTTP logs endpoint
+// (e.g. http://localhost:4318/v1/logs) using the JSON encoding
+func NewOTLPLogExporter(endpoint string, headers map[string]string) QueryLogExporter {
+	return &otlpExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// otlpAttribute is an OTLP KeyValue with the value types used by query log records
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]any{"stringValue": value}}
+}
+
+func otlpInt(key string, value int64) otlpAttribute {
+	// OTLP JSON encodes 64-bit integers as strings
+	return otlpAttribute{Key: key, Value: map[string]any{"intValue": strconv.FormatInt(value, 10)}}
+}
+
+func otlpDouble(key string, value float64) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]any{"doubleValue": value}}
+}
+
+func (e *otlpExporter) Export(ctx context.Context, entries []QueryLogEntry) error {
+	records := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		attributes := []otlpAttribute{
+			otlpString("db.system", "sqlite"),
+			otlpString("db.operation", entry.Operation),
+			otlpString("db.statement.fingerprint", entry.Fingerprint),
+			otlpString("db.statement", entry.Statement),
+			otlpDouble("db.duration_ms", entry.DurationMs),
+			otlpInt("db.rows", entry.Rows),
+			otlpInt("db.attempts", int64(entry.Attempts)),
+			otlpString("code.caller", entry.Caller),
+		}
+		severity, severityNumber := "INFO", 9
+		if entry.Error != "" {
+			attributes = append(attributes, otlpString("error.message", entry.Error))
+			severity, severityNumber = "ERROR", 17
+		}
+		records = append(records, map[string]any{
+			"timeUnixNano":   strconv.FormatInt(entry.Time.UnixNano(), 10),
+			"severityText":   severity,
+			"severityNumber": severityNumber,
+			"body":           map[string]any{"stringValue": entry.Statement},
+			"attributes":     attributes,
+		})
+	}
+
+	payload := map[string]any{
+		"resourceLogs": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttribute{otlpString("service.name", getServiceName())},
+			},
+			"scopeLogs": []any{map[string]any{
+				"scope":      map[string]any{"name": packagePath},
+				"logRecords": records,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OTLP endpoint returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
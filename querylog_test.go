@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureExporter collects exported query log entries
+type captureExporter struct {
+	mu      sync.Mutex
+	entries []QueryLogEntry
+}
+
+func (e *captureExporter) Export(ctx context.Context, entries []QueryLogEntry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries = append(e.entries, entries...)
+	return nil
+}
+
+// TestQueryLogRecordsStatements verifies that statements are recorded once with a
+// fingerprint, row count, attempt count and the caller outside the package
+func TestQueryLogRecordsStatements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "querylog.db")
+	t.Setenv("DATABASE_FILE", path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter := &captureExporter{}
+	ql, err := StartQueryLog(ctx, QueryLogOptions{Exporter: exporter, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to start query log: %v", err)
+	}
+
+	// The pipeline driver must not log statements already recorded by a helper
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := ExecWithRetry(db, "CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := ExecWithRetry(db, "INSERT INTO events (name) VALUES ('"+name+"')"); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	cancel()
+	<-ql.Done()
+
+	if len(exporter.entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(exporter.entries), exporter.entries)
+	}
+	insertA, insertB := exporter.entries[1], exporter.entries[2]
+	if insertA.Fingerprint != insertB.Fingerprint || insertA.Statement != "INSERT INTO EVENTS (NAME) VALUES (?)" {
+		t.Errorf("Expected inserts to share a normalized fingerprint, got %+v and %+v", insertA, insertB)
+	}
+	if insertA.Operation != "exec" || insertA.Rows != 1 || insertA.Attempts != 1 {
+		t.Errorf("Unexpected entry: %+v", insertA)
+	}
+	if !strings.Contains(insertA.Caller, "querylog_test.go") {
+		t.Errorf("Expected caller in querylog_test.go, got %q", insertA.Caller)
+	}
+}
+
+// TestQueryLogSampling verifies that a zero-ish sample rate still logs slow statements
+func TestQueryLogSampling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter := &captureExporter{}
+	ql, err := StartQueryLog(ctx, QueryLogOptions{Exporter: exporter, SampleRate: 0.000001, SlowThreshold: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to start query log: %v", err)
+	}
+
+	fast, _ := startStatement(ctx, "query", "SELECT 1")
+	fast.finish(-1, nil)
+	slow, _ := startStatement(ctx, "query", "SELECT 2")
+	slow.start = slow.start.Add(-time.Second)
+	slow.finish(-1, nil)
+
+	cancel()
+	<-ql.Done()
+
+	if len(exporter.entries) != 1 || exporter.entries[0].Statement != "SELECT ?" || exporter.entries[0].DurationMs < 1000 {
+		t.Errorf("Expected only the slow statement, got %+v", exporter.entries)
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrQuotaExceeded is returned for writes that would grow the database past its size quota
+var ErrQuotaExceeded = errors.New("database size quota exceeded")
+
+// defaultPageSize is SQLite's page size for new database files
+const defaultPageSize = 4096
+
+// quotaOpened records whether any database has been opened with an explicit quota
+var quotaOpened atomic.Bool
+
+// QuotaStats reports size quota usage for a database connection
+type QuotaStats struct {
+	PageSize     int64   // Bytes per page
+	PageCount    int64   // Pages currently in use
+	MaxPageCount int64   // Page limit enforced by max_page_count
+	UsedBytes    int64   // PageCount * PageSize
+	MaxBytes     int64   // MaxPageCount * PageSize
+	UsedPercent  float64 // UsedBytes as a percentage of MaxBytes
+}
+
+// databaseQuotaFromEnv returns the size quota in bytes from DATABASE_MAX_SIZE (0 means unlimited)
+func databaseQuotaFromEnv() int64 {
+	value := configValue("DATABASE_MAX_SIZE")
+	if value == "" {
+		return 0
+	}
+	maxBytes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || maxBytes < 0 {
+		return 0
+	}
+	return maxBytes
+}
+
+// maxPageCountPragma converts a byte quota into a max_page_count connection pragma
+// using the page size recorded in the database file header
+func maxPageCountPragma(databaseFile string, maxBytes int64) string {
+	pages := maxBytes / readPageSize(databaseFile)
+	if pages < 1 {
+		pages = 1
+	}
+	return fmt.Sprintf("_pragma=max_page_count(%d)", pages)
+}
+
+// readPageSize returns the page size stored in the database file header,
+// falling back to SQLite's default for new or unreadable files
+func readPageSize(databaseFile string) int64 {
+	path, _, _ := strings.Cut(databaseFile, "?")
+	file, err := os.Open(path)
+	if err != nil {
+		return defaultPageSize
+	}
+	defer file.Close()
+
+	header := make([]byte, 2)
+	if _, err := file.ReadAt(header, 16); err != nil {
+		return defaultPageSize
+	}
+	switch size := binary.BigEndian.Uint16(header); size {
+	case 0:
+		return defaultPageSize
+	case 1:
+		return 65536 // Encoded as 1 in the header
+	default:
+		return int64(size)
+	}
+}
+
+// OpenWithQuota opens the database file at path with its own size quota in bytes,
+// for deployments that keep one database file per tenant on a shared volume
+func OpenWithQuota(path string, maxBytes int64) (*sql.DB, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("quota must be positive, got %d", maxBytes)
+	}
+	quotaOpened.Store(true)
+
+	db, err := sql.Open("sqlite", buildDSNWithQuota(path, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	err = retryDatabaseOperation(func() error {
+		return db.Ping()
+	}, DefaultRetryConfig())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// GetQuotaStats reports size quota usage for the given connection
+func GetQuotaStats(ctx context.Context, db *sql.DB) (*QuotaStats, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stats := &QuotaStats{}
+	pragmas := []struct {
+		name string
+		dest *int64
+	}{
+		{"page_size", &stats.PageSize},
+		{"page_count", &stats.PageCount},
+		{"max_page_count", &stats.MaxPageCount},
+	}
+	for _, p := range pragmas {
+		err := retryDatabaseOperationContext(ctx, func() error {
+			return conn.QueryRowContext(ctx, "PRAGMA "+p.name).Scan(p.dest)
+		}, DefaultRetryConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p.name, err)
+		}
+	}
+
+	stats.UsedBytes = stats.PageCount * stats.PageSize
+	stats.MaxBytes = stats.MaxPageCount * stats.PageSize
+	if stats.MaxPageCount > 0 {
+		stats.UsedPercent = float64(stats.PageCount) / float64(stats.MaxPageCount) * 100
+	}
+	return stats, nil
+}
+
+// quotaError translates SQLITE_FULL into ErrQuotaExceeded when a size quota is configured
+func quotaError(err error) error {
+	if err == nil || errors.Is(err, ErrQuotaExceeded) {
+		return err
+	}
+	if databaseQuotaFromEnv() == 0 && !quotaOpened.Load() {
+		return err
+	}
+	if !errors.Is(ClassifyError(err), ErrFull) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrQuotaExceeded, err)
+}
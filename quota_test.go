@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestQuotaExceededOnWrite verifies that writes past the quota return ErrQuotaExceeded
+// and that usage is reported against the configured limit
+func TestQuotaExceededOnWrite(t *testing.T) {
+	db, err := OpenWithQuota(filepath.Join(t.TempDir(), "tenant.db"), 64*1024)
+	if err != nil {
+		t.Fatalf("Failed to open database with quota: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := ExecWithRetry(db, "CREATE TABLE blobs (data BLOB)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	_, err = ExecWithRetry(db, "INSERT INTO blobs (data) VALUES (zeroblob(128 * 1024))")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	stats, err := GetQuotaStats(context.Background(), db)
+	if err != nil {
+		t.Fatalf("GetQuotaStats failed: %v", err)
+	}
+	if stats.MaxBytes != 64*1024 || stats.UsedBytes <= 0 || stats.UsedPercent <= 0 {
+		t.Errorf("Unexpected quota stats: %+v", stats)
+	}
+}
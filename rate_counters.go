@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRateLimitCleanupInterval is how often Allow also deletes expired counters
+const DefaultRateLimitCleanupInterval = time.Minute
+
+// rateLimitSchema creates the persisted rate-limit counters: one row per key holding the
+// current and previous fixed window's counts
+const rateLimitSchema = `CREATE TABLE IF NOT EXISTS _rate_limits (
+    key            TEXT PRIMARY KEY,
+    window_start   INTEGER NOT NULL,
+    count          INTEGER NOT NULL,
+    previous_count INTEGER NOT NULL,
+    expires_at     INTEGER NOT NULL
+)`
+
+// lastRateLimitCleanup is when Allow last deleted expired counters (Unix milliseconds)
+var lastRateLimitCleanup atomic.Int64
+
+// Allow reports whether one more event for key fits in limit events per sliding window,
+// counting it if so. The window is approximated from the current and previous fixed
+// windows (weighting the previous one by how much of it still overlaps), so each key
+// costs one row. Counters survive restarts and are shared by every process on the
+// database file; expired ones are deleted lazily. Use one limit and window per key.
+func Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	if limit <= 0 || window <= 0 {
+		return false, fmt.Errorf("invalid rate limit for %s: %d per %v", key, limit, window)
+	}
+
+	now := time.Now().UnixMilli()
+	size := window.Milliseconds()
+	if size == 0 {
+		size = 1
+	}
+	start := now - now%size
+
+	allowed := false
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		allowed = false
+		if _, err := tx.ExecContext(ctx, rateLimitSchema); err != nil {
+			return fmt.Errorf("failed to create rate limit table: %w", err)
+		}
+		last := lastRateLimitCleanup.Load()
+		if now-last >= DefaultRateLimitCleanupInterval.Milliseconds() && lastRateLimitCleanup.CompareAndSwap(last, now) {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM _rate_limits WHERE expires_at <= ?", now); err != nil {
+				return fmt.Errorf("failed to clean up rate limits: %w", err)
+			}
+		}
+
+		var windowStart, count, previous int64
+		err := tx.QueryRowContext(ctx, "SELECT window_start, count, previous_count FROM _rate_limits WHERE key = ?", key).
+			Scan(&windowStart, &count, &previous)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		switch {
+		case err != nil:
+			count, previous = 0, 0
+		case windowStart == start-size:
+			count, previous = 0, count
+		case windowStart != start:
+			count, previous = 0, 0
+		}
+
+		overlap := float64(size-(now-start)) / float64(size)
+		if float64(previous)*overlap+float64(count)+1 > float64(limit) {
+			return nil
+		}
+		allowed = true
+		_, err = tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO _rate_limits (key, window_start, count, previous_count, expires_at) VALUES (?, ?, ?, ?, ?)",
+			key, start, count+1, previous, start+2*size)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// ResetRateLimit forgets key's counters
+func ResetRateLimit(ctx context.Context, key string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := ExecContextWithRetry(ctx, db, rateLimitSchema); err != nil {
+		return fmt.Errorf("failed to create rate limit table: %w", err)
+	}
+	_, err = ExecContextWithRetry(ctx, db, "DELETE FROM _rate_limits WHERE key = ?", key)
+	return err
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAllowPersistsSlidingWindowCounts verifies per-key limits, the window rollover and
+// lazy cleanup of expired counters
+func TestAllowPersistsSlidingWindowCounts(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "ratelimit.db"))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if ok, err := Allow(ctx, "login:ada", 3, time.Hour); err != nil || !ok {
+			t.Fatalf("Expected event %d to be allowed: %v", i, err)
+		}
+	}
+	if ok, err := Allow(ctx, "login:ada", 3, time.Hour); err != nil || ok {
+		t.Fatalf("Expected the 4th event to be limited: %v", err)
+	}
+	if ok, err := Allow(ctx, "login:bob", 3, time.Hour); err != nil || !ok {
+		t.Fatalf("Expected another key to have its own limit: %v", err)
+	}
+	if err := ResetRateLimit(ctx, "login:ada"); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if ok, err := Allow(ctx, "login:ada", 3, time.Hour); err != nil || !ok {
+		t.Fatalf("Expected a reset key to be allowed: %v", err)
+	}
+
+	// Counters from windows long past are ignored, then deleted lazily
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("UPDATE _rate_limits SET window_start = 0, count = 100, expires_at = 1"); err != nil {
+		t.Fatalf("Failed to age counters: %v", err)
+	}
+	if ok, err := Allow(ctx, "login:ada", 3, time.Hour); err != nil || !ok {
+		t.Fatalf("Expected old windows not to count: %v", err)
+	}
+	lastRateLimitCleanup.Store(0)
+	if _, err := Allow(ctx, "login:carol", 3, time.Hour); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM _rate_limits").Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected bob's expired counter to be cleaned up leaving 2, got %d: %v", count, err)
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrThrottled is returned for writes rejected by a write rate limiter
+var ErrThrottled = errors.New("database write rate limit exceeded")
+
+// WriteLimiter is a token-bucket limiter for write operations
+type WriteLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens added per second
+	burst  float64 // Maximum tokens in the bucket
+	tokens float64
+	last   time.Time
+}
+
+// NewWriteLimiter creates a limiter allowing ratePerSecond writes on average with bursts of up to burst writes
+func NewWriteLimiter(ratePerSecond float64, burst int) *WriteLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &WriteLimiter{
+		rate:   ratePerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow takes a token if one is available
+func (l *WriteLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// guard is the write guard installed for the package-wide limiter
+func (l *WriteLimiter) guard(ctx context.Context) error {
+	if !l.Allow() {
+		return ErrThrottled
+	}
+	return nil
+}
+
+// handleLimiters holds per-handle write limiters (e.g., one per tenant database)
+var (
+	handleLimitersMu sync.RWMutex
+	handleLimiters   = make(map[*sql.DB]*WriteLimiter)
+)
+
+// SetWriteLimit limits all package write helpers to ratePerSecond writes with bursts of up to burst.
+// A rate of 0 or less removes the limit.
+func SetWriteLimit(ratePerSecond float64, burst int) {
+	if ratePerSecond <= 0 {
+		removeWriteGuard("ratelimit")
+		return
+	}
+	setWriteGuard("ratelimit", NewWriteLimiter(ratePerSecond, burst).guard)
+}
+
+// SetHandleWriteLimit limits writes made through the given handle, in addition to any package-wide limit.
+// A rate of 0 or less removes the limit.
+func SetHandleWriteLimit(db *sql.DB, ratePerSecond float64, burst int) {
+	handleLimitersMu.Lock()
+	defer handleLimitersMu.Unlock()
+
+	if ratePerSecond <= 0 {
+		delete(handleLimiters, db)
+		return
+	}
+	handleLimiters[db] = NewWriteLimiter(ratePerSecond, burst)
+}
+
+// checkHandleWriteLimit returns ErrThrottled if the handle's write limiter is exhausted
+func checkHandleWriteLimit(db *sql.DB) error {
+	handleLimitersMu.RLock()
+	limiter := handleLimiters[db]
+	handleLimitersMu.RUnlock()
+
+	if limiter != nil && !limiter.Allow() {
+		return ErrThrottled
+	}
+	return nil
+}
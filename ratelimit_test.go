@@ -0,0 +1,43 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestHandleWriteLimitThrottles verifies that writes beyond the burst return ErrThrottled
+// and that other handles are not affected
+func TestHandleWriteLimitThrottles(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "limit.db"))
+
+	limited, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer limited.Close()
+	other, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer other.Close()
+
+	SetHandleWriteLimit(limited, 0.001, 2)
+	defer SetHandleWriteLimit(limited, 0, 0)
+
+	statements := []string{
+		"CREATE TABLE events (id INTEGER PRIMARY KEY)",
+		"INSERT INTO events (id) VALUES (1)",
+	}
+	for _, stmt := range statements {
+		if _, err := ExecWithRetry(limited, stmt); err != nil {
+			t.Fatalf("Failed to execute %q within burst: %v", stmt, err)
+		}
+	}
+	if _, err := ExecWithRetry(limited, "INSERT INTO events (id) VALUES (2)"); !errors.Is(err, ErrThrottled) {
+		t.Errorf("Expected ErrThrottled after burst, got %v", err)
+	}
+	if _, err := ExecWithRetry(other, "INSERT INTO events (id) VALUES (3)"); err != nil {
+		t.Errorf("Expected unlimited handle to write, got %v", err)
+	}
+}
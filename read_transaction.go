@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultReadTransactionTimeout is how long a read transaction may stay open
+const DefaultReadTransactionTimeout = 5 * time.Minute
+
+// DefaultReadTransactionWarnAfter is how long a read transaction stays open before it is
+// logged for holding back WAL checkpoints
+const DefaultReadTransactionWarnAfter = 30 * time.Second
+
+// ErrReadTransactionTimeout is returned when a read transaction outlives its time limit
+var ErrReadTransactionTimeout = errors.New("read transaction exceeded its time limit")
+
+// ReadTransactionOptions configures ReadTransactionWithOptions
+type ReadTransactionOptions struct {
+	Timeout   time.Duration // Limit on the whole transaction (default: DefaultReadTransactionTimeout)
+	WarnAfter time.Duration // Log a warning every interval the transaction stays open (default: DefaultReadTransactionWarnAfter)
+}
+
+// ReadTransaction runs fn in a read-only transaction pinned to one consistent snapshot,
+// so multi-query reads (reports, exports) see the database as of a single point in time
+func ReadTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+	return ReadTransactionWithOptions(ctx, ReadTransactionOptions{}, fn)
+}
+
+// ReadTransactionWithOptions runs fn like ReadTransaction with a custom time limit and
+// warning interval. While a snapshot is open, checkpoints cannot reset the WAL past it,
+// so long transactions are logged and cut off at opts.Timeout with ErrReadTransactionTimeout.
+func ReadTransactionWithOptions(ctx context.Context, opts ReadTransactionOptions, fn func(*sql.Tx) error) error {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultReadTransactionTimeout
+	}
+	if opts.WarnAfter <= 0 {
+		opts.WarnAfter = DefaultReadTransactionWarnAfter
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return readTransactionOn(ctx, db, opts, fn)
+}
+
+// readTransactionOn runs fn in a read transaction on one of db's connections
+func readTransactionOn(ctx context.Context, db *sql.DB, opts ReadTransactionOptions, fn func(*sql.Tx) error) error {
+	txCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	conn, err := db.Conn(txCtx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The connection returns to its pool, so query_only is restored afterwards rather than
+	// cleared: handles such as the analytics pool open their connections read-only. A
+	// connection that cannot be restored is discarded rather than left read-only.
+	var queryOnly bool
+	var path string
+	if err := conn.QueryRowContext(txCtx, "SELECT (SELECT query_only FROM pragma_query_only), (SELECT file FROM pragma_database_list WHERE name = 'main')").Scan(&queryOnly, &path); err != nil {
+		return err
+	}
+	if !queryOnly {
+		if _, err := conn.ExecContext(txCtx, "PRAGMA query_only = ON"); err != nil {
+			return err
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), "PRAGMA query_only = OFF"); err != nil {
+				discardConn(conn)
+			}
+		}()
+	}
+
+	var tx *sql.Tx
+	err = retryDatabaseOperationContext(ctx, func() error {
+		var err error
+		tx, err = conn.BeginTx(txCtx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		// BEGIN is deferred: the snapshot is taken by the first read, so take it now
+		var tables int
+		if err := tx.QueryRowContext(txCtx, "SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return nil
+	}, DefaultRetryConfig())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	started := time.Now()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(opts.WarnAfter)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				logfContext(ctx, LogLevelWarn, "⚠️  Read transaction open for %v is holding back WAL checkpoints (WAL %d bytes)",
+					time.Since(started).Round(time.Second), fileSize(path+"-wal"))
+			}
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil && errors.Is(txCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("%w (%v): %v", ErrReadTransactionTimeout, opts.Timeout, err)
+	}
+	return err
+}
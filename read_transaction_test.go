@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestReadTransactionSeesOneSnapshot verifies that reads inside a read transaction do not
+// see writes committed after it started, that it refuses writes, and that it is time-limited
+func TestReadTransactionSeesOneSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	t.Setenv("DATABASE_FILE", path)
+
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer other.Close()
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "CREATE TABLE orders (id INTEGER PRIMARY KEY)", "INSERT INTO orders VALUES (1)"} {
+		if _, err := other.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up: %v", err)
+		}
+	}
+
+	err = ReadTransaction(context.Background(), func(tx *sql.Tx) error {
+		// Committed before the first query of fn, but after the snapshot was taken
+		if _, err := other.Exec("INSERT INTO orders VALUES (2)"); err != nil {
+			return err
+		}
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count); err != nil {
+			return err
+		}
+		if count != 1 {
+			t.Errorf("Expected the snapshot to hold 1 order, got %d", count)
+		}
+		if _, err := tx.Exec("INSERT INTO orders VALUES (3)"); err == nil {
+			t.Error("Expected writes to be refused in a read transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read transaction failed: %v", err)
+	}
+
+	opts := ReadTransactionOptions{Timeout: 50 * time.Millisecond, WarnAfter: 10 * time.Millisecond}
+	err = ReadTransactionWithOptions(context.Background(), opts, func(tx *sql.Tx) error {
+		time.Sleep(100 * time.Millisecond)
+		var count int
+		return tx.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count)
+	})
+	if !errors.Is(err, ErrReadTransactionTimeout) {
+		t.Errorf("Expected ErrReadTransactionTimeout, got %v", err)
+	}
+}
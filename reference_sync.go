@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReferenceSyncResult counts the changes SyncReferenceTable applied
+type ReferenceSyncResult struct {
+	Inserted int
+	Updated  int
+	Deleted  int
+}
+
+// SyncReferenceTable makes table hold exactly the given canonical rows, matched on
+// keyColumns: missing rows are inserted, rows whose provided columns differ are updated
+// and rows not in the set are deleted, all in one transaction. Rows already matching are
+// not written, so running it at every startup keeps lookup tables (countries, plans,
+// permissions) in sync idempotently. Columns left out of a row keep their stored values.
+func SyncReferenceTable(ctx context.Context, table string, rows []map[string]interface{}, keyColumns ...string) (*ReferenceSyncResult, error) {
+	if len(keyColumns) == 0 {
+		keyColumns = []string{"id"}
+	}
+	wanted := make(map[string]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		for _, column := range keyColumns {
+			if _, ok := row[column]; !ok {
+				return nil, fmt.Errorf("reference row %d of %s has no key column %s", i, table, column)
+			}
+		}
+		key := referenceKey(row, keyColumns)
+		if _, dup := wanted[key]; dup {
+			return nil, fmt.Errorf("reference rows of %s repeat key %s", table, strings.ReplaceAll(key, "\x00", ", "))
+		}
+		wanted[key] = row
+	}
+
+	result := &ReferenceSyncResult{}
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		*result = ReferenceSyncResult{}
+		current, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", quoteIdentifier(table)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		existing, _, err := scanRowMaps(current, 0)
+		current.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", table, err)
+		}
+
+		where := make([]string, len(keyColumns))
+		for i, column := range keyColumns {
+			where[i] = quoteIdentifier(column) + " = ?"
+		}
+		keyArgs := func(row map[string]interface{}) []interface{} {
+			args := make([]interface{}, len(keyColumns))
+			for i, column := range keyColumns {
+				args[i] = row[column]
+			}
+			return args
+		}
+
+		seen := make(map[string]bool, len(existing))
+		for _, row := range existing {
+			key := referenceKey(row, keyColumns)
+			want, ok := wanted[key]
+			if !ok {
+				if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", quoteIdentifier(table), strings.Join(where, " AND ")), keyArgs(row)...); err != nil {
+					return fmt.Errorf("failed to delete from %s: %w", table, err)
+				}
+				result.Deleted++
+				continue
+			}
+			seen[key] = true
+
+			var sets []string
+			var args []interface{}
+			for _, column := range sortedColumns(want) {
+				if referenceValue(row[column]) != referenceValue(want[column]) {
+					sets = append(sets, quoteIdentifier(column)+" = ?")
+					args = append(args, want[column])
+				}
+			}
+			if len(sets) == 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteIdentifier(table), strings.Join(sets, ", "), strings.Join(where, " AND ")), append(args, keyArgs(row)...)...); err != nil {
+				return fmt.Errorf("failed to update %s: %w", table, err)
+			}
+			result.Updated++
+		}
+
+		for _, row := range rows {
+			if seen[referenceKey(row, keyColumns)] {
+				continue
+			}
+			columns := sortedColumns(row)
+			args := make([]interface{}, len(columns))
+			for i, column := range columns {
+				args[i] = row[column]
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdentifier(table), quoteColumns(columns),
+				strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")), args...); err != nil {
+				return fmt.Errorf("failed to insert into %s: %w", table, err)
+			}
+			result.Inserted++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Inserted+result.Updated+result.Deleted > 0 {
+		logf("📚 Synced reference table %s: %d inserted, %d updated, %d deleted", table, result.Inserted, result.Updated, result.Deleted)
+	}
+	return result, nil
+}
+
+// referenceKey joins a row's key values into a map key
+func referenceKey(row map[string]interface{}, keyColumns []string) string {
+	parts := make([]string, len(keyColumns))
+	for i, column := range keyColumns {
+		parts[i] = referenceValue(row[column])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// referenceValue renders a provided or stored value canonically, so 1, int64(1), 1.0 and
+// true compare equal as SQLite stores them
+func referenceValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "\x01NULL"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return stringifyValue(v)
+	}
+}
+
+// sortedColumns returns a row's columns in name order
+func sortedColumns(row map[string]interface{}) []string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestSyncReferenceTableAppliesMinimalChanges verifies inserts, updates and deletes against
+// canonical rows and that an unchanged set writes nothing
+func TestSyncReferenceTableAppliesMinimalChanges(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "reference.db"))
+	ctx := context.Background()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE plans (code TEXT, region TEXT, price INTEGER, active BOOLEAN, notes TEXT, PRIMARY KEY (code, region));
+		INSERT INTO plans VALUES ('pro', 'eu', 10, 1, 'kept'), ('old', 'eu', 5, 1, NULL), ('team', 'eu', 30, 1, NULL)`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+
+	canonical := []map[string]interface{}{
+		{"code": "pro", "region": "eu", "price": 10, "active": true},
+		{"code": "team", "region": "eu", "price": 35.0, "active": true},
+		{"code": "pro", "region": "us", "price": 11, "active": false},
+	}
+	result, err := SyncReferenceTable(ctx, "plans", canonical, "code", "region")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if *result != (ReferenceSyncResult{Inserted: 1, Updated: 1, Deleted: 1}) {
+		t.Errorf("Expected 1 insert, 1 update and 1 delete, got %+v", result)
+	}
+
+	var price int
+	var notes string
+	if err := db.QueryRow("SELECT price FROM plans WHERE code = 'team'").Scan(&price); err != nil || price != 35 {
+		t.Errorf("Expected team to cost 35, got %d: %v", price, err)
+	}
+	if err := db.QueryRow("SELECT notes FROM plans WHERE code = 'pro' AND region = 'eu'").Scan(&notes); err != nil || notes != "kept" {
+		t.Errorf("Expected unlisted columns to be kept, got %q: %v", notes, err)
+	}
+
+	if result, err := SyncReferenceTable(ctx, "plans", canonical, "code", "region"); err != nil || *result != (ReferenceSyncResult{}) {
+		t.Errorf("Expected a second sync to change nothing, got %+v: %v", result, err)
+	}
+	if _, err := SyncReferenceTable(ctx, "plans", append(canonical, canonical[0]), "code", "region"); err == nil {
+		t.Error("Expected duplicate keys to be rejected")
+	}
+}
@@ -3,25 +3,36 @@ package database
 import (
 	"embed"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 // MigrationSource represents a source of database migrations
 type MigrationSource struct {
-	Name      string    // Human-readable name (e.g., "user-management", "sentipulse-core")
-	Directory string    // File system path to migrations (for file-based sources)
-	EmbedFS   *embed.FS // Embedded filesystem (for embedded migrations)
-	SubPath   string    // Subpath within embedded filesystem (e.g., "migrations", "." for root)
-	Prefix    string    // Optional prefix for migration files (e.g., "user_", "app_")
+	Name              string                // Human-readable name (e.g., "user-management", "sentipulse-core")
+	Directory         string                // File system path to migrations (for file-based sources)
+	EmbedFS           *embed.FS             // Embedded filesystem (for embedded migrations)
+	SubPath           string                // Subpath within embedded filesystem (e.g., "migrations", "." for root)
+	Prefix            string                // Optional prefix for migration files (e.g., "user_", "app_")
+	Timeout           time.Duration         // Per-migration timeout for UpAllContext (default: DefaultMigrationTimeout)
+	Database          string                // Database file to migrate (default: DATABASE_FILE)
+	DependsOn         []string              // Names of sources that must be migrated first
+	URL               string                // Remote tar.gz bundle of migration files (http(s):// or s3://bucket/key)
+	Checksum          string                // SHA-256 of the URL bundle ("sha256:<hex>"); fetched from URL+".sha256" when empty
+	PublicKey         string                // minisign or PEM (cosign) key, or a path to one, verifying migrations.sum (default: DATABASE_MIGRATION_PUBLIC_KEY)
+	Validations       []MigrationValidation // Smoke tests that must pass after the source migrates
+	RollbackOnFailure bool                  // Run the down files of the migrations applied in a failed run
+
+	group *MigrationGroup // Set for groups registered with RegisterMigrationGroup
 }
 
 // Registry manages all registered migration sources
 type Registry struct {
-	mu      sync.RWMutex
-	sources []MigrationSource
+	mu       sync.RWMutex
+	sources  []MigrationSource
+	expected []string // Manifest set by ListExpectedSources (nil when unchecked)
 }
 
 // Global registry instance
@@ -34,7 +45,7 @@ func RegisterMigrations(source MigrationSource) {
 	globalRegistry.mu.Lock()
 	defer globalRegistry.mu.Unlock()
 
-	log.Printf("📦 Registering migration source: %s", source.Name)
+	logf("📦 Registering migration source: %s", source.Name)
 	globalRegistry.sources = append(globalRegistry.sources, source)
 }
 
@@ -67,12 +78,29 @@ func GetMigrationStats() (map[string]int, error) {
 
 // countMigrationFiles counts the number of migration files in a source
 func countMigrationFiles(source MigrationSource) (int, error) {
+	if source.group != nil {
+		total := 0
+		for _, member := range source.group.Sources {
+			count, err := countMigrationFiles(member)
+			if err != nil {
+				return 0, err
+			}
+			total += count
+		}
+		return total, nil
+	}
+
 	if source.Directory != "" {
 		return countMigrationFilesInDirectory(source.Directory)
 	}
 
+	if source.URL != "" {
+		logf("📦 Remote migrations are counted after download for: %s", source.Name)
+		return 0, nil
+	}
+
 	if source.EmbedFS != nil {
-		log.Printf("📦 Embedded FS migrations not yet implemented for: %s", source.Name)
+		logf("📦 Embedded FS migrations not yet implemented for: %s", source.Name)
 		return 0, nil
 	}
 
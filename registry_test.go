@@ -71,6 +71,11 @@ CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);`
 	os.WriteFile(filepath.Join(migrationDir, "001_create_users_table.up.sql"), []byte(userMigrationSQL), 0644)
 	os.WriteFile(filepath.Join(migrationDir, "001_create_users_table.down.sql"), []byte("DROP TABLE users;"), 0644)
 
+	// Clear any existing registrations for clean test
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+
 	// Register test migration source
 	RegisterMigrations(MigrationSource{
 		Name:      "test-user-management",
@@ -0,0 +1,230 @@
+package database
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrChecksumMismatch is returned when a downloaded migration bundle does not match its checksum
+var ErrChecksumMismatch = errors.New("migration bundle checksum mismatch")
+
+// MigrationFetcher downloads the object at rawURL
+type MigrationFetcher func(ctx context.Context, rawURL string) (io.ReadCloser, error)
+
+// remoteClient downloads bundles for the built-in fetchers
+var remoteClient = &http.Client{Timeout: 5 * time.Minute}
+
+var (
+	fetchersMu sync.RWMutex
+	fetchers   = map[string]MigrationFetcher{
+		"http":  fetchHTTP,
+		"https": fetchHTTP,
+		"s3":    fetchS3,
+	}
+)
+
+// RegisterMigrationFetcher sets the fetcher for a URL scheme, e.g. an "s3" fetcher using
+// the AWS SDK for private buckets. It replaces any built-in fetcher for the scheme.
+func RegisterMigrationFetcher(scheme string, fetcher MigrationFetcher) {
+	fetchersMu.Lock()
+	defer fetchersMu.Unlock()
+	fetchers[strings.ToLower(scheme)] = fetcher
+}
+
+// fetchHTTP downloads rawURL with a GET request
+func fetchHTTP(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// fetchS3 downloads s3://bucket/key anonymously from the bucket's HTTPS endpoint
+// (region from AWS_REGION). Private buckets need a fetcher registered for "s3".
+func fetchS3(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host + ".s3.amazonaws.com"
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", u.Host, region)
+	}
+	return fetchHTTP(ctx, "https://"+host+u.Path)
+}
+
+// fetchRemote downloads rawURL with the fetcher registered for its scheme
+func fetchRemote(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration URL %s: %w", rawURL, err)
+	}
+	fetchersMu.RLock()
+	fetcher, ok := fetchers[strings.ToLower(u.Scheme)]
+	fetchersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no migration fetcher registered for scheme %q", u.Scheme)
+	}
+	return fetcher(ctx, rawURL)
+}
+
+// migrationCacheDir returns where downloaded bundles are extracted
+func migrationCacheDir() string {
+	if dir := configValue("DATABASE_MIGRATION_CACHE"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "go-database-migrations")
+}
+
+// parseChecksum returns the hex SHA-256 from "sha256:<hex>", "<hex>" or a sha256sum line
+func parseChecksum(checksum string) (string, error) {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum")
+	}
+	sum := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 checksum %q", fields[0])
+	}
+	return sum, nil
+}
+
+// resolveRemoteSource downloads, verifies and caches a source's URL bundle, returning the
+// source with Directory pointing at the extracted files. Sources without URL are unchanged.
+func resolveRemoteSource(ctx context.Context, source MigrationSource) (MigrationSource, error) {
+	if source.URL == "" {
+		return source, nil
+	}
+
+	checksum := source.Checksum
+	if checksum == "" {
+		body, err := fetchRemote(ctx, source.URL+".sha256")
+		if err != nil {
+			return source, fmt.Errorf("failed to fetch checksum for %s: %w", source.URL, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(body, 1024))
+		body.Close()
+		if err != nil {
+			return source, fmt.Errorf("failed to fetch checksum for %s: %w", source.URL, err)
+		}
+		checksum = string(content)
+	}
+	sum, err := parseChecksum(checksum)
+	if err != nil {
+		return source, fmt.Errorf("invalid checksum for %s: %w", source.URL, err)
+	}
+
+	// Bundles are cached by checksum, so a pinned Checksum works offline once downloaded
+	directory := filepath.Join(migrationCacheDir(), sum)
+	if _, err := os.Stat(directory); err == nil {
+		logf("📦 Using cached migration bundle for %s: %s", source.Name, directory)
+		source.Directory = directory
+		return source, nil
+	}
+
+	logf("⬇️  Downloading migration bundle for %s: %s", source.Name, source.URL)
+	if err := os.MkdirAll(migrationCacheDir(), 0755); err != nil {
+		return source, fmt.Errorf("failed to create migration cache: %w", err)
+	}
+	archive, err := os.CreateTemp(migrationCacheDir(), "bundle-*.tar.gz")
+	if err != nil {
+		return source, fmt.Errorf("failed to create migration cache: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	body, err := fetchRemote(ctx, source.URL)
+	if err != nil {
+		return source, fmt.Errorf("failed to download %s: %w", source.URL, err)
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(archive, hash), body)
+	body.Close()
+	if err != nil {
+		return source, fmt.Errorf("failed to download %s: %w", source.URL, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != sum {
+		return source, fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, source.URL, sum, got)
+	}
+
+	// Extract next to the cache entry and rename, so a partial extraction is never used
+	staging, err := os.MkdirTemp(migrationCacheDir(), "extract-*")
+	if err != nil {
+		return source, fmt.Errorf("failed to create migration cache: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return source, err
+	}
+	if err := extractMigrationBundle(archive, staging); err != nil {
+		return source, fmt.Errorf("failed to extract %s: %w", source.URL, err)
+	}
+	if err := os.Rename(staging, directory); err != nil {
+		// Another process may have cached the same bundle concurrently
+		if _, statErr := os.Stat(directory); statErr != nil {
+			return source, fmt.Errorf("failed to cache %s: %w", source.URL, err)
+		}
+	}
+
+	logf("✅ Verified and cached migration bundle for %s", source.Name)
+	source.Directory = directory
+	return source, nil
+}
+
+// extractMigrationBundle extracts the .sql files and signed manifest of a tar.gz bundle
+// into directory, flattening paths; migration files are expected at the bundle's top level
+func extractMigrationBundle(r io.Reader, directory string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Base(header.Name)
+		isManifest := strings.HasPrefix(name, MigrationManifestFile)
+		if header.Typeflag != tar.TypeReg || (!strings.HasSuffix(name, ".sql") && !isManifest) {
+			continue
+		}
+		file, err := os.OpenFile(filepath.Join(directory, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, tr)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// buildMigrationBundle returns a tar.gz holding files under a migrations/ directory
+func buildMigrationBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: "migrations/" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write bundle: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// TestRemoteSourceDownloadsVerifiesAndCaches verifies that a URL source is downloaded
+// once, checked against its published checksum, applied, and served from cache afterwards
+func TestRemoteSourceDownloadsVerifiesAndCaches(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "remote.db"))
+	t.Setenv("DATABASE_MIGRATION_CACHE", filepath.Join(tempDir, "cache"))
+
+	bundle := buildMigrationBundle(t, map[string]string{
+		"001_create_devices.up.sql":   "CREATE TABLE devices (id INTEGER PRIMARY KEY);",
+		"001_create_devices.down.sql": "DROP TABLE devices;",
+	})
+	sum := sha256.Sum256(bundle)
+
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bundle.tar.gz":
+			downloads.Add(1)
+			w.Write(bundle)
+		case "/bundle.tar.gz.sha256":
+			w.Write([]byte(hex.EncodeToString(sum[:]) + "  bundle.tar.gz\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+
+	RegisterMigrations(MigrationSource{Name: "appliance", URL: server.URL + "/bundle.tar.gz"})
+	if err := UpAllContext(context.Background()); err != nil {
+		t.Fatalf("Failed to run remote migrations: %v", err)
+	}
+	if err := UpAll(); err != nil {
+		t.Fatalf("Failed to re-run remote migrations: %v", err)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("Expected the bundle to be downloaded once, got %d", n)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'devices'").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected devices table from the remote bundle (count %d, err %v)", count, err)
+	}
+
+	// A tampered bundle is refused
+	tampered := MigrationSource{Name: "tampered", URL: server.URL + "/bundle.tar.gz", Checksum: "sha256:" + hex.EncodeToString(make([]byte, 32))}
+	if _, err := resolveRemoteSource(context.Background(), tampered); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
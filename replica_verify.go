@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultReplicaChunkRows is how many rows VerifyReplica checksums per chunk
+const DefaultReplicaChunkRows = 1000
+
+// Replica mismatch reasons
+const (
+	ReplicaMissingTable  = "missing_table" // The table does not exist on the replica
+	ReplicaSchemaDiffers = "schema"        // The table definitions differ; data is not compared
+	ReplicaDataDiffers   = "data"          // The rows in the range differ
+)
+
+// ReplicaCheckpoint is where an interrupted verification resumes: after AfterRowID of Table
+type ReplicaCheckpoint struct {
+	Table      string `json:"table"`
+	AfterRowID int64  `json:"after_rowid"`
+}
+
+// ReplicaVerifyOptions configures VerifyReplicaWithOptions
+type ReplicaVerifyOptions struct {
+	Tables    []string           // Tables to compare (default: every table of the primary)
+	ChunkRows int                // Rows per checksum chunk (default: DefaultReplicaChunkRows)
+	MaxChunks int                // Stop after this many chunks, returning a checkpoint (0 = no limit)
+	Resume    *ReplicaCheckpoint // Continue a previous verification
+}
+
+// ReplicaMismatch is a table, or a rowid range of it, that differs on the replica
+type ReplicaMismatch struct {
+	Table     string `json:"table"`
+	Reason    string `json:"reason"`
+	FromRowID int64  `json:"from_rowid"` // Exclusive; ranges cover the whole table for WITHOUT ROWID tables
+	ToRowID   int64  `json:"to_rowid"`   // Inclusive
+}
+
+// ReplicaReport is the outcome of a replica verification
+type ReplicaReport struct {
+	Tables     int                `json:"tables"`
+	Chunks     int                `json:"chunks"`
+	Mismatches []ReplicaMismatch  `json:"mismatches"`
+	Complete   bool               `json:"complete"`
+	Checkpoint *ReplicaCheckpoint `json:"checkpoint,omitempty"` // Set when incomplete; pass as Resume
+}
+
+// Consistent reports whether a complete verification found no differences
+func (r *ReplicaReport) Consistent() bool {
+	return r.Complete && len(r.Mismatches) == 0
+}
+
+// VerifyReplica compares every table of primary and replica chunk by chunk and reports the
+// rowid ranges whose checksums differ
+func VerifyReplica(ctx context.Context, primary, replica *sql.DB) (*ReplicaReport, error) {
+	return VerifyReplicaWithOptions(ctx, primary, replica, ReplicaVerifyOptions{})
+}
+
+// VerifyReplicaWithOptions is VerifyReplica with table selection and resumable, bounded
+// runs. Tables are compared in name order; chunks are rowid ranges of ChunkRows primary
+// rows, the last one open-ended so extra replica rows are caught. When MaxChunks is
+// reached or ctx is cancelled, the report carries a checkpoint to resume from.
+func VerifyReplicaWithOptions(ctx context.Context, primary, replica *sql.DB, opts ReplicaVerifyOptions) (*ReplicaReport, error) {
+	if opts.ChunkRows <= 0 {
+		opts.ChunkRows = DefaultReplicaChunkRows
+	}
+	tables := append([]string(nil), opts.Tables...)
+	if len(tables) == 0 {
+		var err error
+		if tables, err = replicaTables(ctx, primary); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(tables)
+
+	report := &ReplicaReport{Mismatches: []ReplicaMismatch{}}
+	for _, table := range tables {
+		after := int64(math.MinInt64)
+		if opts.Resume != nil {
+			if table < opts.Resume.Table {
+				continue
+			}
+			if table == opts.Resume.Table {
+				after = opts.Resume.AfterRowID
+			}
+		}
+		report.Tables++
+
+		primarySQL, err := tableDefinition(ctx, primary, table)
+		if err != nil {
+			return report, err
+		}
+		replicaSQL, err := tableDefinition(ctx, replica, table)
+		if err != nil {
+			return report, err
+		}
+		switch {
+		case replicaSQL == "":
+			report.Mismatches = append(report.Mismatches, ReplicaMismatch{Table: table, Reason: ReplicaMissingTable, FromRowID: math.MinInt64, ToRowID: math.MaxInt64})
+			continue
+		case normalizeSchemaSQL(primarySQL) != normalizeSchemaSQL(replicaSQL):
+			report.Mismatches = append(report.Mismatches, ReplicaMismatch{Table: table, Reason: ReplicaSchemaDiffers, FromRowID: math.MinInt64, ToRowID: math.MaxInt64})
+			continue
+		}
+
+		if !hasRowID(ctx, primary, table) {
+			// WITHOUT ROWID tables are compared whole
+			if err := compareReplicaRange(ctx, primary, replica, table, nil, report); err != nil {
+				return report, err
+			}
+			continue
+		}
+
+		for {
+			if ctx.Err() != nil || (opts.MaxChunks > 0 && report.Chunks >= opts.MaxChunks) {
+				report.Checkpoint = &ReplicaCheckpoint{Table: table, AfterRowID: after}
+				return report, ctx.Err()
+			}
+			end, last, err := chunkEnd(ctx, primary, table, after, opts.ChunkRows)
+			if err != nil {
+				return report, err
+			}
+			if err := compareReplicaRange(ctx, primary, replica, table, []int64{after, end}, report); err != nil {
+				return report, err
+			}
+			if last {
+				break
+			}
+			after = end
+		}
+	}
+	report.Complete = true
+	logf("🔍 Replica verification compared %d tables in %d chunks: %d mismatches", report.Tables, report.Chunks, len(report.Mismatches))
+	return report, nil
+}
+
+// compareReplicaRange checksums a rowid range (nil for the whole table) on both sides
+func compareReplicaRange(ctx context.Context, primary, replica *sql.DB, table string, bounds []int64, report *ReplicaReport) error {
+	query := fmt.Sprintf("SELECT * FROM %s", quoteIdentifier(table))
+	var args []interface{}
+	if bounds != nil {
+		query = fmt.Sprintf("SELECT rowid, * FROM %s WHERE rowid > ? AND rowid <= ? ORDER BY rowid", quoteIdentifier(table))
+		args = []interface{}{bounds[0], bounds[1]}
+	}
+	primarySum, err := rangeChecksum(ctx, primary, bounds == nil, table, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s on primary: %w", table, err)
+	}
+	replicaSum, err := rangeChecksum(ctx, replica, bounds == nil, table, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s on replica: %w", table, err)
+	}
+
+	report.Chunks++
+	if primarySum != replicaSum {
+		mismatch := ReplicaMismatch{Table: table, Reason: ReplicaDataDiffers, FromRowID: math.MinInt64, ToRowID: math.MaxInt64}
+		if bounds != nil {
+			mismatch.FromRowID, mismatch.ToRowID = bounds[0], bounds[1]
+		}
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+	return nil
+}
+
+// rangeChecksum hashes the rows of query, or the whole table in content order
+func rangeChecksum(ctx context.Context, db *sql.DB, whole bool, table, query string, args ...interface{}) (string, error) {
+	if whole {
+		return tableDataHash(ctx, db, table)
+	}
+	var sum string
+	err := retryDatabaseOperationContext(ctx, func() error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		result, _, err := scanRowMaps(rows, 0)
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		for _, row := range result {
+			fmt.Fprintf(h, "%s\n", RowHash(row))
+		}
+		sum = hex.EncodeToString(h.Sum(nil))
+		return nil
+	}, DefaultRetryConfig())
+	return sum, err
+}
+
+// chunkEnd returns the last rowid of the chunk after after, or MaxInt64 for the final chunk
+func chunkEnd(ctx context.Context, db *sql.DB, table string, after int64, rows int) (int64, bool, error) {
+	var end int64
+	err := QueryRowWithRetry(db,
+		fmt.Sprintf("SELECT rowid FROM %s WHERE rowid > ? ORDER BY rowid LIMIT 1 OFFSET ?", quoteIdentifier(table)),
+		after, rows-1).Scan(&end)
+	if errors.Is(err, sql.ErrNoRows) {
+		return math.MaxInt64, true, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return end, end == math.MaxInt64, nil
+}
+
+// hasRowID reports whether a table has a rowid (it is not a WITHOUT ROWID table)
+func hasRowID(ctx context.Context, db *sql.DB, table string) bool {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT rowid FROM %s LIMIT 0", quoteIdentifier(table)))
+	if err != nil {
+		return false
+	}
+	rows.Close()
+	return true
+}
+
+// replicaTables lists the tables of db
+func replicaTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// tableDefinition returns a table's CREATE statement, or "" when it does not exist
+func tableDefinition(ctx context.Context, db *sql.DB, table string) (string, error) {
+	var definition string
+	err := QueryRowWithRetry(db, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&definition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return definition, err
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// TestVerifyReplicaReportsMismatchedRanges verifies chunked comparison, mismatch ranges,
+// missing tables and resuming from a checkpoint
+func TestVerifyReplicaReportsMismatchedRanges(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open := func(name string) *sql.DB {
+		db, err := sql.Open("sqlite", buildDSN(filepath.Join(dir, name)))
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", name, err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	primary, replica := open("primary.db"), open("replica.db")
+	for _, db := range []*sql.DB{primary, replica} {
+		if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+		for i := 1; i <= 25; i++ {
+			if _, err := db.Exec("INSERT INTO items (id, name) VALUES (?, ?)", i, fmt.Sprintf("item %d", i)); err != nil {
+				t.Fatalf("Failed to insert: %v", err)
+			}
+		}
+	}
+	if _, err := primary.Exec("CREATE TABLE tags (name TEXT PRIMARY KEY) WITHOUT ROWID"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	opts := ReplicaVerifyOptions{ChunkRows: 10, Tables: []string{"items"}}
+	report, err := VerifyReplicaWithOptions(ctx, primary, replica, opts)
+	if err != nil || !report.Consistent() || report.Chunks != 3 {
+		t.Fatalf("Expected 3 consistent chunks, got %+v: %v", report, err)
+	}
+
+	if _, err := replica.Exec("UPDATE items SET name = 'drifted' WHERE id = 15"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if _, err := replica.Exec("INSERT INTO items (id, name) VALUES (100, 'extra')"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	report, err = VerifyReplicaWithOptions(ctx, primary, replica, ReplicaVerifyOptions{ChunkRows: 10})
+	if err != nil || !report.Complete {
+		t.Fatalf("Verification failed: %+v: %v", report, err)
+	}
+	if len(report.Mismatches) != 3 {
+		t.Fatalf("Expected 3 mismatches, got %+v", report.Mismatches)
+	}
+	if m := report.Mismatches[0]; m.Reason != ReplicaDataDiffers || m.FromRowID != 10 || m.ToRowID != 20 {
+		t.Errorf("Expected rows (10, 20] to differ, got %+v", m)
+	}
+	if m := report.Mismatches[1]; m.Reason != ReplicaDataDiffers || m.FromRowID != 20 {
+		t.Errorf("Expected the open-ended last chunk to catch the extra row, got %+v", m)
+	}
+	if m := report.Mismatches[2]; m.Table != "tags" || m.Reason != ReplicaMissingTable {
+		t.Errorf("Expected the missing tags table, got %+v", m)
+	}
+
+	opts.MaxChunks = 1
+	partial, err := VerifyReplicaWithOptions(ctx, primary, replica, opts)
+	if err != nil || partial.Complete || partial.Checkpoint == nil || partial.Checkpoint.AfterRowID != 10 {
+		t.Fatalf("Expected a checkpoint after the first chunk, got %+v: %v", partial, err)
+	}
+	opts.MaxChunks, opts.Resume = 0, partial.Checkpoint
+	rest, err := VerifyReplicaWithOptions(ctx, primary, replica, opts)
+	if err != nil || !rest.Complete || rest.Chunks != 2 || len(rest.Mismatches) != 2 {
+		t.Errorf("Expected the resumed run to check the remaining 2 chunks, got %+v: %v", rest, err)
+	}
+}
@@ -0,0 +1,357 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Repository describes a table exposed to sibling services through a generated data-access API
+type Repository struct {
+	Name  string      // Resource name used for RPC names (e.g., "User")
+	Table string      // Backing table (e.g., "users")
+	Model interface{} // Struct with `db` tags describing the columns
+	// Plural names the List RPC (default: Name pluralized with English rules, e.g. "Categories")
+	Plural string
+	// Indexes are indexes on expressions, such as JSON fields, created by GenerateModelMigration
+	Indexes []ExpressionIndex
+}
+
+// repositoryRegistry manages all registered repositories
+type repositoryRegistry struct {
+	mu           sync.RWMutex
+	repositories []Repository
+}
+
+// Global repository registry instance
+var globalRepositories = &repositoryRegistry{
+	repositories: make([]Repository, 0),
+}
+
+// RegisterRepository registers a repository with the global repository registry
+func RegisterRepository(repo Repository) {
+	globalRepositories.mu.Lock()
+	defer globalRepositories.mu.Unlock()
+
+	if repo.Name == "" {
+		repo.Name = modelName(repo.Model)
+	}
+	logf("🗂️  Registering repository: %s (%s)", repo.Name, repo.Table)
+	globalRepositories.repositories = append(globalRepositories.repositories, repo)
+}
+
+// GetRegisteredRepositories returns all registered repositories
+func GetRegisteredRepositories() []Repository {
+	globalRepositories.mu.RLock()
+	defer globalRepositories.mu.RUnlock()
+
+	// Return a copy to prevent external modification
+	repositories := make([]Repository, len(globalRepositories.repositories))
+	copy(repositories, globalRepositories.repositories)
+	return repositories
+}
+
+// GenerateProto renders a proto3 definition with CRUD and filtered list RPCs for all
+// registered repositories. Every model field needs a `proto:"N"` tag giving its field
+// number, so adding or reordering Go fields never renumbers fields on the wire. Serve the
+// service with RegisterRepositoryService, or feed the output to protoc for client stubs.
+func GenerateProto(protoPackage, serviceName string) (string, error) {
+	resources, err := repositoryResources()
+	if err != nil {
+		return "", err
+	}
+	file, err := repositoryProto(protoPackage, serviceName, resources)
+	if err != nil {
+		return "", err
+	}
+	return renderProto(file), nil
+}
+
+// Proto field numbers 19000-19999 are reserved for the protobuf implementation
+const (
+	maxProtoFieldNumber   = 1<<29 - 1
+	firstReservedProtoNum = 19000
+	lastReservedProtoNum  = 19999
+)
+
+// repositoryResource is a registered repository with the fields and names of its RPCs
+type repositoryResource struct {
+	Repository
+	fields []modelField
+	pk     modelField
+	plural string
+}
+
+// repositoryResources returns the registered repositories, checking that each has a
+// primary key and a unique, valid proto field number on every field
+func repositoryResources() ([]repositoryResource, error) {
+	repositories := GetRegisteredRepositories()
+	if len(repositories) == 0 {
+		return nil, fmt.Errorf("no repositories registered")
+	}
+
+	resources := make([]repositoryResource, 0, len(repositories))
+	for _, repo := range repositories {
+		fields, err := modelFields(repo.Model)
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %w", repo.Name, err)
+		}
+		res := repositoryResource{Repository: repo, fields: fields, plural: repo.Plural}
+		if res.plural == "" {
+			res.plural = pluralize(repo.Name)
+		}
+
+		numbers := make(map[int]string)
+		hasPK := false
+		for _, f := range fields {
+			switch {
+			case f.Proto == 0:
+				return nil, fmt.Errorf("repository %s field %s has no proto field number (tag it with `proto:\"N\"`)", repo.Name, f.Name)
+			case f.Proto > maxProtoFieldNumber || (f.Proto >= firstReservedProtoNum && f.Proto <= lastReservedProtoNum):
+				return nil, fmt.Errorf("repository %s field %s: proto field number %d is out of range or reserved", repo.Name, f.Name, f.Proto)
+			}
+			if other, ok := numbers[f.Proto]; ok {
+				return nil, fmt.Errorf("repository %s: fields %s and %s share proto field number %d", repo.Name, other, f.Name, f.Proto)
+			}
+			numbers[f.Proto] = f.Name
+			if f.PrimaryKey && !hasPK {
+				res.pk, hasPK = f, true
+			}
+		}
+		if !hasPK {
+			return nil, fmt.Errorf("repository %s has no primary key field (tag a field with `db:\"column,pk\"`)", repo.Name)
+		}
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+// pluralize returns the English plural of a resource name for its List RPC, e.g.
+// "Category" -> "Categories" and "Address" -> "Addresses". Irregular plurals are set
+// with Repository.Plural.
+func pluralize(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case len(lower) > 1 && strings.HasSuffix(lower, "y") && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
+
+// repositoryProto describes the messages and service of the repository API: GenerateProto
+// renders it and RegisterRepositoryService serves it
+func repositoryProto(protoPackage, serviceName string, resources []repositoryResource) (*descriptorpb.FileDescriptorProto, error) {
+	qualified := func(name string) string {
+		if protoPackage == "" {
+			return "." + name
+		}
+		return "." + protoPackage + "." + name
+	}
+	message := func(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+	}
+	method := func(name, input, output string) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{Name: proto.String(name), InputType: proto.String(input), OutputType: proto.String(output)}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("repositories.proto"),
+		Package: proto.String(protoPackage),
+		Syntax:  proto.String("proto3"),
+	}
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String(serviceName)}
+	imports := map[string]bool{"google/protobuf/empty.proto": true}
+
+	for _, res := range resources {
+		resource := message(res.Name)
+		for _, f := range res.fields {
+			field, imp, err := protoField(f.Column, f.Proto, f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("repository %s field %s: %w", res.Name, f.Name, err)
+			}
+			if imp != "" {
+				imports[imp] = true
+			}
+			if f.Type.Kind() == reflect.Ptr {
+				// Nullable columns are proto3 optional fields, so NULL is distinguishable from zero
+				field.Proto3Optional = proto.Bool(true)
+				field.OneofIndex = proto.Int32(int32(len(resource.OneofDecl)))
+				resource.OneofDecl = append(resource.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + f.Column)})
+			}
+			resource.Field = append(resource.Field, field)
+		}
+		key, _, err := protoField(res.pk.Column, 1, res.pk.Type)
+		if err != nil {
+			return nil, fmt.Errorf("repository %s field %s: %w", res.Name, res.pk.Name, err)
+		}
+
+		listRequest := message("List"+res.plural+"Request",
+			&descriptorpb.FieldDescriptorProto{
+				Name: proto.String("filters"), Number: proto.Int32(1),
+				Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(qualified("List" + res.plural + "Request.FiltersEntry")),
+			},
+			protoScalar("page_size", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			protoScalar("page_token", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		)
+		listRequest.NestedType = []*descriptorpb.DescriptorProto{{
+			Name: proto.String("FiltersEntry"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				protoScalar("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				protoScalar("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}}
+		listResponse := message("List"+res.plural+"Response",
+			&descriptorpb.FieldDescriptorProto{
+				Name: proto.String("items"), Number: proto.Int32(1),
+				Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(qualified(res.Name)),
+			},
+			protoScalar("next_page_token", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		)
+		file.MessageType = append(file.MessageType, resource,
+			message("Get"+res.Name+"Request", key),
+			message("Delete"+res.Name+"Request", proto.Clone(key).(*descriptorpb.FieldDescriptorProto)),
+			listRequest, listResponse)
+
+		name := qualified(res.Name)
+		service.Method = append(service.Method,
+			method("Get"+res.Name, qualified("Get"+res.Name+"Request"), name),
+			method("Create"+res.Name, name, name),
+			method("Update"+res.Name, name, name),
+			method("Delete"+res.Name, qualified("Delete"+res.Name+"Request"), ".google.protobuf.Empty"),
+			method("List"+res.plural, qualified("List"+res.plural+"Request"), qualified("List"+res.plural+"Response")))
+	}
+
+	for imp := range imports {
+		file.Dependency = append(file.Dependency, imp)
+	}
+	sort.Strings(file.Dependency)
+	file.Service = []*descriptorpb.ServiceDescriptorProto{service}
+	return file, nil
+}
+
+// protoScalar describes a singular scalar field
+func protoScalar(name string, number int32, t descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   t.Enum(),
+	}
+}
+
+// protoField describes the field holding a column of Go type t, with any import it needs
+func protoField(column string, number int, t reflect.Type) (*descriptorpb.FieldDescriptorProto, string, error) {
+	protoType, typeName, imp, err := protoFieldType(t)
+	if err != nil {
+		return nil, "", err
+	}
+	field := protoScalar(column, int32(number), protoType)
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field, imp, nil
+}
+
+// protoFieldType maps a Go field type to a proto3 type, the message name for message
+// types, and any import it needs
+func protoFieldType(t reflect.Type) (descriptorpb.FieldDescriptorProto_Type, string, string, error) {
+	if t.Kind() == reflect.Ptr {
+		return protoFieldType(t.Elem())
+	}
+	if t == timeType {
+		return descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp", "google/protobuf/timestamp.proto", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING, "", "", nil
+	case reflect.Bool:
+		return descriptorpb.FieldDescriptorProto_TYPE_BOOL, "", "", nil
+	case reflect.Int, reflect.Int64:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT64, "", "", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT32, "", "", nil
+	case reflect.Uint, reflect.Uint64:
+		return descriptorpb.FieldDescriptorProto_TYPE_UINT64, "", "", nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return descriptorpb.FieldDescriptorProto_TYPE_UINT32, "", "", nil
+	case reflect.Float32:
+		return descriptorpb.FieldDescriptorProto_TYPE_FLOAT, "", "", nil
+	case reflect.Float64:
+		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "", "", nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return descriptorpb.FieldDescriptorProto_TYPE_BYTES, "", "", nil
+		}
+	}
+	return 0, "", "", fmt.Errorf("unsupported type %s", t)
+}
+
+// renderProto renders the repository API file as .proto source
+func renderProto(file *descriptorpb.FileDescriptorProto) string {
+	local := func(typeName string) string {
+		if pkg := file.GetPackage(); pkg != "" {
+			if name, ok := strings.CutPrefix(typeName, "."+pkg+"."); ok {
+				return name
+			}
+		}
+		return strings.TrimPrefix(typeName, ".")
+	}
+	scalar := func(field *descriptorpb.FieldDescriptorProto) string {
+		if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+			return local(field.GetTypeName())
+		}
+		return strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+	}
+
+	var out strings.Builder
+	out.WriteString("// Code generated by go-database GenerateProto. DO NOT EDIT.\n\n")
+	out.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&out, "package %s;\n\n", file.GetPackage())
+	for _, imp := range file.Dependency {
+		fmt.Fprintf(&out, "import \"%s\";\n", imp)
+	}
+	out.WriteString("\n")
+
+	for _, message := range file.MessageType {
+		entries := make(map[string]*descriptorpb.DescriptorProto)
+		for _, nested := range message.NestedType {
+			if nested.GetOptions().GetMapEntry() {
+				entries[message.GetName()+"."+nested.GetName()] = nested
+			}
+		}
+		fmt.Fprintf(&out, "message %s {\n", message.GetName())
+		for _, field := range message.Field {
+			fieldType := scalar(field)
+			if entry, ok := entries[local(field.GetTypeName())]; ok {
+				fieldType = fmt.Sprintf("map<%s, %s>", scalar(entry.Field[0]), scalar(entry.Field[1]))
+			} else if field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+				fieldType = "repeated " + fieldType
+			} else if field.GetProto3Optional() {
+				fieldType = "optional " + fieldType
+			}
+			fmt.Fprintf(&out, "  %s %s = %d;\n", fieldType, field.GetName(), field.GetNumber())
+		}
+		out.WriteString("}\n\n")
+	}
+
+	for _, service := range file.Service {
+		fmt.Fprintf(&out, "service %s {\n", service.GetName())
+		for _, method := range service.Method {
+			fmt.Fprintf(&out, "  rpc %s(%s) returns (%s);\n", method.GetName(), local(method.GetInputType()), local(method.GetOutputType()))
+		}
+		out.WriteString("}\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,573 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/timestamppb" // Registers google/protobuf/timestamp.proto
+)
+
+// DefaultRepositoryPageSize is the List page size when a request sets none
+const DefaultRepositoryPageSize = 50
+
+// RepositoryServiceOptions configures the gRPC service serving registered repositories
+type RepositoryServiceOptions struct {
+	// Package and Service name the service, as passed to GenerateProto for client stubs
+	Package string
+	Service string
+	// Authorize is called for every call with its full method name (e.g. "/app.Data/GetUser");
+	// returning an error rejects it with PermissionDenied, or with the error's own status.
+	// It is required: without it every call is rejected.
+	Authorize func(ctx context.Context, method string) error
+	// MaxPageSize caps the List page size (default: 1000)
+	MaxPageSize int
+}
+
+// errInvalidRepositoryRequest marks requests rejected with InvalidArgument
+var errInvalidRepositoryRequest = errors.New("invalid request")
+
+// RegisterRepositoryService registers the service GenerateProto describes on s, serving
+// Get, Create, Update, Delete and List for every registered repository. Messages are
+// decoded dynamically, so no protoc-generated server code is needed; clients use stubs
+// generated from GenerateProto's output with the same package and service names.
+// Register repositories before calling it.
+func RegisterRepositoryService(s grpc.ServiceRegistrar, opts RepositoryServiceOptions) error {
+	if opts.Service == "" {
+		return fmt.Errorf("repository service needs a service name")
+	}
+	if opts.MaxPageSize <= 0 {
+		opts.MaxPageSize = 1000
+	}
+	if opts.Authorize == nil {
+		logWarnf("⚠️  Repository service has no Authorize hook; every call will be rejected")
+	}
+
+	resources, err := repositoryResources()
+	if err != nil {
+		return err
+	}
+	fileProto, err := repositoryProto(opts.Package, opts.Service, resources)
+	if err != nil {
+		return err
+	}
+	file, err := protodesc.NewFile(fileProto, protoregistry.GlobalFiles)
+	if err != nil {
+		return fmt.Errorf("invalid repository service definition: %w", err)
+	}
+	service := file.Services().Get(0)
+
+	desc := grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*interface{})(nil),
+		Metadata:    fileProto.GetName(),
+	}
+	methods := service.Methods()
+	for i, res := range resources {
+		calls := []func(context.Context, *dynamicpb.Message) (interface{}, error){
+			res.get, res.create, res.update, res.delete,
+			func(ctx context.Context, in *dynamicpb.Message) (interface{}, error) {
+				return res.list(ctx, in, opts.MaxPageSize)
+			},
+		}
+		for j, call := range calls {
+			method := methods.Get(i*len(calls) + j)
+			desc.Methods = append(desc.Methods, repositoryMethod(desc.ServiceName, method, opts.Authorize, call))
+		}
+	}
+
+	s.RegisterService(&desc, struct{}{})
+	logf("🛰️  Serving %d repositories over gRPC as %s", len(resources), desc.ServiceName)
+	return nil
+}
+
+// repositoryMethod adapts a repository call to a gRPC method handler
+func repositoryMethod(serviceName string, method protoreflect.MethodDescriptor, authorize func(context.Context, string) error,
+	call func(context.Context, *dynamicpb.Message) (interface{}, error)) grpc.MethodDesc {
+	fullMethod := "/" + serviceName + "/" + string(method.Name())
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if authorize == nil {
+			return nil, status.Error(codes.Unauthenticated, errNoAuthorizer.Error())
+		}
+		if err := authorize(ctx, fullMethod); err != nil {
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		out, err := call(ctx, req.(*dynamicpb.Message))
+		if err != nil {
+			return nil, repositoryStatus(fullMethod, err)
+		}
+		return out, nil
+	}
+
+	return grpc.MethodDesc{
+		MethodName: string(method.Name()),
+		Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := dynamicpb.NewMessage(method.Input())
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: fullMethod}, handler)
+		},
+	}
+}
+
+// repositoryStatus maps an error to its gRPC status, hiding internal failures from clients
+func repositoryStatus(method string, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, errInvalidRepositoryRequest), errors.Is(err, ErrInvalidFilter):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrConstraintUnique), errors.Is(err, ErrConstraintPrimaryKey):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrConstraint):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case IsBusy(err):
+		return status.Error(codes.Unavailable, "database is busy")
+	}
+	logErrorf("❌ Repository call %s failed: %v", method, err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// get serves Get<Name>: the row with the requested primary key
+func (res repositoryResource) get(ctx context.Context, in *dynamicpb.Message) (interface{}, error) {
+	key, err := res.requestKey(in)
+	if err != nil {
+		return nil, err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	row, err := res.load(ctx, NewDBTX(db), key)
+	if err != nil {
+		return nil, err
+	}
+	return res.message(in.Descriptor().ParentFile(), row)
+}
+
+// create serves Create<Name>. A zero integer primary key is left for SQLite to assign.
+func (res repositoryResource) create(ctx context.Context, in *dynamicpb.Message) (interface{}, error) {
+	values, err := res.values(in)
+	if err != nil {
+		return nil, err
+	}
+	key := values[res.pk.Column]
+	assignKey := res.integerKey() && (key == nil || reflect.ValueOf(key).IsZero())
+
+	var columns, placeholders []string
+	var args []interface{}
+	for _, f := range res.writableFields() {
+		if f.PrimaryKey && assignKey {
+			continue
+		}
+		columns = append(columns, quoteIdentifier(f.Column))
+		placeholders = append(placeholders, "?")
+		args = append(args, values[f.Column])
+	}
+	query := fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", quoteIdentifier(res.Table))
+	if len(columns) > 0 {
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdentifier(res.Table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	}
+
+	var row map[string]interface{}
+	err = WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		q := NewTxDBTX(tx)
+		result, err := q.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if assignKey {
+			if key, err = result.LastInsertId(); err != nil {
+				return err
+			}
+		}
+		row, err = res.load(ctx, q, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.message(in.Descriptor().ParentFile(), row)
+}
+
+// update serves Update<Name>, replacing every column of the row with the message's key
+func (res repositoryResource) update(ctx context.Context, in *dynamicpb.Message) (interface{}, error) {
+	values, err := res.values(in)
+	if err != nil {
+		return nil, err
+	}
+	var assignments []string
+	var args []interface{}
+	for _, f := range res.writableFields() {
+		if f.PrimaryKey {
+			continue
+		}
+		assignments = append(assignments, quoteIdentifier(f.Column)+" = ?")
+		args = append(args, values[f.Column])
+	}
+	if len(assignments) == 0 {
+		return nil, fmt.Errorf("%w: %s has no columns to update", errInvalidRepositoryRequest, res.Name)
+	}
+	key := values[res.pk.Column]
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", quoteIdentifier(res.Table), strings.Join(assignments, ", "), quoteIdentifier(res.pk.Column))
+
+	var row map[string]interface{}
+	err = WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		q := NewTxDBTX(tx)
+		result, err := q.ExecContext(ctx, query, append(args, key)...)
+		if err != nil {
+			return err
+		}
+		if rowsAffected(result) == 0 {
+			return sql.ErrNoRows
+		}
+		row, err = res.load(ctx, q, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.message(in.Descriptor().ParentFile(), row)
+}
+
+// delete serves Delete<Name>
+func (res repositoryResource) delete(ctx context.Context, in *dynamicpb.Message) (interface{}, error) {
+	key, err := res.requestKey(in)
+	if err != nil {
+		return nil, err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", quoteIdentifier(res.Table), quoteIdentifier(res.pk.Column))
+	result, err := ExecContextWithRetry(ctx, db, query, key)
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected(result) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// list serves List<Plural>: a page of rows in primary key order matching the filters,
+// which use ParseFilterQuery's syntax with column names (e.g. "age[gte]": "18"). The page
+// token is the offset of the next page.
+func (res repositoryResource) list(ctx context.Context, in *dynamicpb.Message, maxPageSize int) (interface{}, error) {
+	fields := in.Descriptor().Fields()
+	pageSize := int(in.Get(fields.ByName("page_size")).Int())
+	switch {
+	case pageSize < 0:
+		return nil, fmt.Errorf("%w: page_size must not be negative", errInvalidRepositoryRequest)
+	case pageSize == 0:
+		pageSize = DefaultRepositoryPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+	offset := 0
+	if token := in.Get(fields.ByName("page_token")).String(); token != "" {
+		var err error
+		if offset, err = strconv.Atoi(token); err != nil || offset < 0 {
+			return nil, fmt.Errorf("%w: malformed page_token", errInvalidRepositoryRequest)
+		}
+	}
+
+	allowed := make(FilterFields, len(res.fields))
+	for _, f := range res.fields {
+		allowed[f.Column] = f.Column
+	}
+	params := url.Values{}
+	var badFilter error
+	in.Get(fields.ByName("filters")).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		field, _, _ := strings.Cut(k.String(), "[")
+		if _, ok := allowed[field]; !ok {
+			badFilter = fmt.Errorf("%w: field %q cannot be filtered on", ErrInvalidFilter, field)
+			return false
+		}
+		params.Set(k.String(), v.String())
+		return true
+	})
+	if badFilter != nil {
+		return nil, badFilter
+	}
+	filter, err := ParseFilterQuery(params, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := ListQuery{
+		Table:      res.Table,
+		Fields:     allowed,
+		Filter:     filter,
+		Tiebreaker: res.pk.Column,
+		Limit:      pageSize,
+		Offset:     offset,
+	}.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file := in.Descriptor().ParentFile()
+	out := dynamicpb.NewMessage(file.Messages().ByName(protoreflect.Name("List" + res.plural + "Response")))
+	items := out.Mutable(out.Descriptor().Fields().ByName("items")).List()
+	for _, row := range page.Rows {
+		item, err := res.message(file, row)
+		if err != nil {
+			return nil, err
+		}
+		items.Append(protoreflect.ValueOfMessage(item))
+	}
+	if page.HasMore {
+		out.Set(out.Descriptor().Fields().ByName("next_page_token"), protoreflect.ValueOfString(strconv.Itoa(offset+len(page.Rows))))
+	}
+	return out, nil
+}
+
+// load reads the row with the given primary key, or returns sql.ErrNoRows
+func (res repositoryResource) load(ctx context.Context, q *DBTX, key interface{}) (map[string]interface{}, error) {
+	columns := make([]string, len(res.fields))
+	for i, f := range res.fields {
+		columns[i] = quoteIdentifier(f.Column)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(columns, ", "), quoteIdentifier(res.Table), quoteIdentifier(res.pk.Column))
+	rows, err := q.QueryContext(ctx, query, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	found, _, err := scanRowMaps(rows, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return found[0], nil
+}
+
+// writableFields returns the fields stored by Create and Update: all but generated columns
+func (res repositoryResource) writableFields() []modelField {
+	var fields []modelField
+	for _, f := range res.fields {
+		if f.Generated == "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// integerKey reports whether the primary key is an integer SQLite can assign
+func (res repositoryResource) integerKey() bool {
+	t := res.pk.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// requestKey returns the primary key of a Get or Delete request
+func (res repositoryResource) requestKey(in *dynamicpb.Message) (interface{}, error) {
+	fd := in.Descriptor().Fields().ByName(protoreflect.Name(res.pk.Column))
+	return protoColumnValue(fd, in.Get(fd))
+}
+
+// values returns the column values of a resource message. Unset optional fields and
+// timestamps are NULL.
+func (res repositoryResource) values(in *dynamicpb.Message) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(res.fields))
+	for _, f := range res.fields {
+		fd := in.Descriptor().Fields().ByNumber(protoreflect.FieldNumber(f.Proto))
+		if fd.HasPresence() && !in.Has(fd) {
+			values[f.Column] = nil
+			continue
+		}
+		value, err := protoColumnValue(fd, in.Get(fd))
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %s: %v", errInvalidRepositoryRequest, fd.Name(), err)
+		}
+		values[f.Column] = value
+	}
+	return values, nil
+}
+
+// message builds the resource message of a row; NULL columns are left unset
+func (res repositoryResource) message(file protoreflect.FileDescriptor, row map[string]interface{}) (*dynamicpb.Message, error) {
+	out := dynamicpb.NewMessage(file.Messages().ByName(protoreflect.Name(res.Name)))
+	for _, f := range res.fields {
+		value := row[f.Column]
+		if value == nil {
+			continue
+		}
+		fd := out.Descriptor().Fields().ByNumber(protoreflect.FieldNumber(f.Proto))
+		v, err := columnProtoValue(out, fd, value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s of %s: %w", f.Column, res.Table, err)
+		}
+		out.Set(fd, v)
+	}
+	return out, nil
+}
+
+// protoColumnValue converts a field value to the value bound for its column. Timestamps
+// are stored as UTC times.
+func protoColumnValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (interface{}, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return v.String(), nil
+	case protoreflect.BytesKind:
+		return v.Bytes(), nil
+	case protoreflect.BoolKind:
+		return v.Bool(), nil
+	case protoreflect.Int32Kind, protoreflect.Int64Kind:
+		return v.Int(), nil
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		return v.Uint(), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float(), nil
+	case protoreflect.MessageKind:
+		ts := v.Message()
+		fields := ts.Descriptor().Fields()
+		return time.Unix(ts.Get(fields.ByName("seconds")).Int(), ts.Get(fields.ByName("nanos")).Int()).UTC(), nil
+	}
+	return nil, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
+
+// columnProtoValue converts a scanned column value to the value of field fd of msg
+func columnProtoValue(msg *dynamicpb.Message, fd protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if b, ok := value.([]byte); ok {
+			return protoreflect.ValueOfString(string(b)), nil
+		}
+		return protoreflect.ValueOfString(fmt.Sprint(value)), nil
+	case protoreflect.BytesKind:
+		if s, ok := value.(string); ok {
+			return protoreflect.ValueOfBytes([]byte(s)), nil
+		}
+		if b, ok := value.([]byte); ok {
+			return protoreflect.ValueOfBytes(b), nil
+		}
+	case protoreflect.BoolKind:
+		n, err := columnInt(value)
+		return protoreflect.ValueOfBool(n != 0), err
+	case protoreflect.Int32Kind:
+		n, err := columnInt(value)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind:
+		n, err := columnInt(value)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind:
+		n, err := columnInt(value)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind:
+		n, err := columnInt(value)
+		return protoreflect.ValueOfUint64(uint64(n)), err
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		var f float64
+		var err error
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case int64:
+			f = float64(v)
+		default:
+			f, err = strconv.ParseFloat(fmt.Sprint(v), 64)
+		}
+		if fd.Kind() == protoreflect.FloatKind {
+			return protoreflect.ValueOfFloat32(float32(f)), err
+		}
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.MessageKind:
+		t, err := columnTime(value)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		ts := msg.NewField(fd).Message()
+		ts.Set(ts.Descriptor().Fields().ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+		ts.Set(ts.Descriptor().Fields().ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+		return protoreflect.ValueOfMessage(ts), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("cannot convert %T to %s", value, fd.Kind())
+}
+
+// columnInt reads an integer column value
+func columnInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to an integer", value)
+}
+
+// columnTimeLayouts are the text forms of times read from TEXT columns: RFC 3339, SQLite's
+// datetime() output and Go's time.Time.String() written by the driver
+var columnTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// columnTime reads a time column value: a time, Unix seconds or text
+func columnTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(v, 0), nil
+	case []byte:
+		value = string(v)
+	}
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot convert %T to a time", value)
+	}
+	for _, layout := range columnTimeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", text)
+}
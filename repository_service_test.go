@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// startRepositoryService serves the registered repositories in memory and returns a
+// client connection and the service's message descriptors
+func startRepositoryService(t *testing.T, opts RepositoryServiceOptions) (*grpc.ClientConn, protoreflect.FileDescriptor) {
+	server := grpc.NewServer()
+	if err := RegisterRepositoryService(server, opts); err != nil {
+		t.Fatalf("RegisterRepositoryService failed: %v", err)
+	}
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///repositories",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	resources, err := repositoryResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileProto, err := repositoryProto(opts.Package, opts.Service, resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := protodesc.NewFile(fileProto, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, file
+}
+
+// TestRepositoryServiceCRUD verifies each RPC against the database over a gRPC connection
+func TestRepositoryServiceCRUD(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "repository.db"))
+	defer CloseAll()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE categories (id INTEGER PRIMARY KEY, title TEXT NOT NULL UNIQUE, parent_id INTEGER, created_at DATETIME)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+	useRepositories(t, Repository{Table: "categories", Model: protoCategory{}, Name: "Category"})
+
+	var methods []string
+	conn, file := startRepositoryService(t, RepositoryServiceOptions{
+		Package: "app.data",
+		Service: "Data",
+		Authorize: func(ctx context.Context, method string) error {
+			methods = append(methods, method)
+			return nil
+		},
+	})
+	ctx := context.Background()
+	newMessage := func(name string) *dynamicpb.Message {
+		return dynamicpb.NewMessage(file.Messages().ByName(protoreflect.Name(name)))
+	}
+	set := func(m *dynamicpb.Message, field string, v protoreflect.Value) *dynamicpb.Message {
+		m.Set(m.Descriptor().Fields().ByName(protoreflect.Name(field)), v)
+		return m
+	}
+	get := func(m *dynamicpb.Message, field string) protoreflect.Value {
+		return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(field)))
+	}
+
+	stamp := time.Date(2024, 5, 1, 12, 30, 0, 500, time.UTC)
+	create := set(newMessage("Category"), "title", protoreflect.ValueOfString("Books"))
+	set(create, "created_at", protoreflect.ValueOfMessage(timestamppb.New(stamp).ProtoReflect()))
+	created := newMessage("Category")
+	if err := conn.Invoke(ctx, "/app.data.Data/CreateCategory", create, created); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	id := get(created, "id").Int()
+	if id == 0 || get(created, "title").String() != "Books" || created.Has(created.Descriptor().Fields().ByName("parent_id")) {
+		t.Fatalf("Unexpected created category: %v", created)
+	}
+	createdAt := get(created, "created_at").Message()
+	if seconds, nanos := createdAt.Get(createdAt.Descriptor().Fields().ByName("seconds")).Int(), createdAt.Get(createdAt.Descriptor().Fields().ByName("nanos")).Int(); seconds != stamp.Unix() || nanos != 500 {
+		t.Errorf("Expected created_at to round-trip, got %d.%09d", seconds, nanos)
+	}
+	if len(methods) != 1 || methods[0] != "/app.data.Data/CreateCategory" {
+		t.Errorf("Expected Authorize to see the full method name, got %v", methods)
+	}
+
+	duplicate := set(newMessage("Category"), "title", protoreflect.ValueOfString("Books"))
+	if err := conn.Invoke(ctx, "/app.data.Data/CreateCategory", duplicate, newMessage("Category")); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists for a duplicate title, got %v", err)
+	}
+
+	for _, title := range []string{"Comics", "Games"} {
+		child := set(newMessage("Category"), "title", protoreflect.ValueOfString(title))
+		set(child, "parent_id", protoreflect.ValueOfInt64(id))
+		if err := conn.Invoke(ctx, "/app.data.Data/CreateCategory", child, newMessage("Category")); err != nil {
+			t.Fatalf("CreateCategory failed: %v", err)
+		}
+	}
+
+	update := set(newMessage("Category"), "id", protoreflect.ValueOfInt64(id))
+	set(update, "title", protoreflect.ValueOfString("Novels"))
+	updated := newMessage("Category")
+	if err := conn.Invoke(ctx, "/app.data.Data/UpdateCategory", update, updated); err != nil || get(updated, "title").String() != "Novels" {
+		t.Fatalf("UpdateCategory returned %v: %v", updated, err)
+	}
+
+	fetched := newMessage("Category")
+	request := set(newMessage("GetCategoryRequest"), "id", protoreflect.ValueOfInt64(id))
+	if err := conn.Invoke(ctx, "/app.data.Data/GetCategory", request, fetched); err != nil || get(fetched, "title").String() != "Novels" {
+		t.Fatalf("GetCategory returned %v: %v", fetched, err)
+	}
+
+	list := newMessage("ListCategoriesRequest")
+	filters := list.Mutable(list.Descriptor().Fields().ByName("filters")).Map()
+	filters.Set(protoreflect.ValueOfString("parent_id").MapKey(), protoreflect.ValueOfString("1"))
+	set(list, "page_size", protoreflect.ValueOfInt32(1))
+	page := newMessage("ListCategoriesResponse")
+	if err := conn.Invoke(ctx, "/app.data.Data/ListCategories", list, page); err != nil {
+		t.Fatalf("ListCategories failed: %v", err)
+	}
+	items := get(page, "items").List()
+	token := get(page, "next_page_token").String()
+	if items.Len() != 1 || items.Get(0).Message().Get(created.Descriptor().Fields().ByName("title")).String() != "Comics" || token == "" {
+		t.Fatalf("Unexpected first page: %v", page)
+	}
+	set(list, "page_token", protoreflect.ValueOfString(token))
+	page = newMessage("ListCategoriesResponse")
+	if err := conn.Invoke(ctx, "/app.data.Data/ListCategories", list, page); err != nil {
+		t.Fatalf("ListCategories failed: %v", err)
+	}
+	if items := get(page, "items").List(); items.Len() != 1 || get(page, "next_page_token").String() != "" {
+		t.Errorf("Expected a last page with one item, got %v", page)
+	}
+
+	filters.Set(protoreflect.ValueOfString("secret").MapKey(), protoreflect.ValueOfString("x"))
+	if err := conn.Invoke(ctx, "/app.data.Data/ListCategories", list, newMessage("ListCategoriesResponse")); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown filter field, got %v", err)
+	}
+
+	remove := set(newMessage("DeleteCategoryRequest"), "id", protoreflect.ValueOfInt64(id))
+	if err := conn.Invoke(ctx, "/app.data.Data/DeleteCategory", remove, &emptypb.Empty{}); err != nil {
+		t.Fatalf("DeleteCategory failed: %v", err)
+	}
+	if err := conn.Invoke(ctx, "/app.data.Data/GetCategory", request, newMessage("Category")); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound after delete, got %v", err)
+	}
+}
+
+// TestRepositoryServiceRequiresAuthorize verifies a service without an Authorize hook
+// rejects every call
+func TestRepositoryServiceRequiresAuthorize(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "repository.db"))
+	useRepositories(t, Repository{Table: "categories", Model: protoCategory{}, Name: "Category"})
+
+	conn, file := startRepositoryService(t, RepositoryServiceOptions{Package: "app.data", Service: "Data"})
+	request := dynamicpb.NewMessage(file.Messages().ByName("GetCategoryRequest"))
+	err := conn.Invoke(context.Background(), "/app.data.Data/GetCategory", request, dynamicpb.NewMessage(file.Messages().ByName("Category")))
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without an Authorize hook, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type protoCategory struct {
+	ID       int64     `db:"id,pk" proto:"1"`
+	Title    string    `db:"title" proto:"3"`
+	ParentID *int64    `db:"parent_id" proto:"2"`
+	Created  time.Time `db:"created_at" proto:"4"`
+}
+
+// useRepositories replaces the registered repositories for the rest of the test
+func useRepositories(t *testing.T, repos ...Repository) {
+	globalRepositories.mu.Lock()
+	saved := globalRepositories.repositories
+	globalRepositories.repositories = nil
+	globalRepositories.mu.Unlock()
+	t.Cleanup(func() {
+		globalRepositories.mu.Lock()
+		globalRepositories.repositories = saved
+		globalRepositories.mu.Unlock()
+	})
+	for _, repo := range repos {
+		RegisterRepository(repo)
+	}
+}
+
+// TestGenerateProtoUsesTaggedFieldNumbers verifies field numbers come from proto tags
+// rather than field order, and that List RPCs use proper plurals
+func TestGenerateProtoUsesTaggedFieldNumbers(t *testing.T) {
+	useRepositories(t,
+		Repository{Table: "categories", Model: protoCategory{}, Name: "Category"},
+		Repository{Table: "people", Model: protoCategory{}, Name: "Person", Plural: "People"})
+
+	out, err := GenerateProto("app.data", "Data")
+	if err != nil {
+		t.Fatalf("GenerateProto failed: %v", err)
+	}
+	for _, want := range []string{
+		"message Category {\n  int64 id = 1;\n  string title = 3;\n  optional int64 parent_id = 2;\n  google.protobuf.Timestamp created_at = 4;\n}",
+		"message ListCategoriesRequest {\n  map<string, string> filters = 1;",
+		"  repeated Category items = 1;",
+		"  rpc ListCategories(ListCategoriesRequest) returns (ListCategoriesResponse);",
+		"  rpc DeletePerson(DeletePersonRequest) returns (google.protobuf.Empty);",
+		"  rpc ListPeople(ListPeopleRequest) returns (ListPeopleResponse);",
+		"import \"google/protobuf/timestamp.proto\";",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected generated proto to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	for name, plural := range map[string]string{"Address": "Addresses", "Box": "Boxes", "Key": "Keys", "Batch": "Batches", "User": "Users"} {
+		if got := pluralize(name); got != plural {
+			t.Errorf("pluralize(%q) = %q, want %q", name, got, plural)
+		}
+	}
+}
+
+// TestGenerateProtoRequiresFieldNumbers verifies untagged and conflicting field numbers fail
+func TestGenerateProtoRequiresFieldNumbers(t *testing.T) {
+	type untagged struct {
+		ID   int64  `db:"id,pk" proto:"1"`
+		Name string `db:"name"`
+	}
+	type duplicate struct {
+		ID   int64  `db:"id,pk" proto:"1"`
+		Name string `db:"name" proto:"1"`
+	}
+	type reserved struct {
+		ID int64 `db:"id,pk" proto:"19000"`
+	}
+
+	for _, model := range []interface{}{untagged{}, duplicate{}, reserved{}} {
+		useRepositories(t, Repository{Table: "items", Model: model, Name: "Item"})
+		if _, err := GenerateProto("app", "Data"); err == nil {
+			t.Errorf("Expected GenerateProto to reject %T", model)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrResultTooLarge is returned when a query's result set exceeds its row or byte limit
+var ErrResultTooLarge = errors.New("result set too large")
+
+// ResultLimits bounds the result sets of queries run through the pipeline driver
+type ResultLimits struct {
+	MaxRows  int64 // Rows a query may return (0 = unlimited)
+	MaxBytes int64 // Approximate bytes of column values a query may return (0 = unlimited)
+	Truncate bool  // End the result at the limit instead of failing with ErrResultTooLarge
+}
+
+// ResultLimitError reports the limit a result set ran into. It matches ErrResultTooLarge.
+type ResultLimitError struct {
+	Limit ResultLimits
+	Rows  int64  // Rows returned before the limit was hit
+	Bytes int64  // Approximate bytes returned before the limit was hit
+	Query string // Normalized statement
+}
+
+func (e *ResultLimitError) Error() string {
+	limit := fmt.Sprintf("%d rows", e.Limit.MaxRows)
+	if e.Limit.MaxRows <= 0 || (e.Limit.MaxBytes > 0 && e.Bytes > e.Limit.MaxBytes) {
+		limit = fmt.Sprintf("%d bytes", e.Limit.MaxBytes)
+	}
+	return fmt.Sprintf("%v: limit of %s reached after %d rows, %d bytes (query %q)", ErrResultTooLarge, limit, e.Rows, e.Bytes, e.Query)
+}
+
+func (e *ResultLimitError) Is(target error) bool {
+	return target == ErrResultTooLarge
+}
+
+// resultLimitsContextKey is the context key for per-call result limits
+type resultLimitsContextKey struct{}
+
+// WithResultLimits returns a context whose queries use limits instead of the defaults from
+// DATABASE_MAX_RESULT_ROWS and DATABASE_MAX_RESULT_BYTES; pass ResultLimits{} to lift them,
+// e.g. for exports
+func WithResultLimits(ctx context.Context, limits ResultLimits) context.Context {
+	return context.WithValue(ctx, resultLimitsContextKey{}, limits)
+}
+
+// resultLimitsFor returns the result limits for a query made with ctx
+func resultLimitsFor(ctx context.Context) ResultLimits {
+	if limits, ok := ctx.Value(resultLimitsContextKey{}).(ResultLimits); ok {
+		return limits
+	}
+	return ResultLimits{
+		MaxRows:  int64EnvOrZero("DATABASE_MAX_RESULT_ROWS"),
+		MaxBytes: int64EnvOrZero("DATABASE_MAX_RESULT_BYTES"),
+		Truncate: configValue("DATABASE_RESULT_LIMIT_MODE") == "truncate",
+	}
+}
+
+// int64EnvOrZero parses a non-negative integer environment variable, 0 when unset or invalid
+func int64EnvOrZero(name string) int64 {
+	value, err := strconv.ParseInt(configValue(name), 10, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// limitedRows enforces result limits while rows are read
+type limitedRows struct {
+	driver.Rows
+	ctx    context.Context
+	limits ResultLimits
+	query  string
+	rows   int64
+	bytes  int64
+}
+
+// limitRows wraps rows with result limits when any apply
+func limitRows(ctx context.Context, rows driver.Rows, query string) driver.Rows {
+	limits := resultLimitsFor(ctx)
+	if rows == nil || (limits.MaxRows <= 0 && limits.MaxBytes <= 0) {
+		return rows
+	}
+	return &limitedRows{Rows: rows, ctx: ctx, limits: limits, query: query}
+}
+
+func (r *limitedRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	r.rows++
+	for _, value := range dest {
+		r.bytes += valueSize(value)
+	}
+	if (r.limits.MaxRows > 0 && r.rows > r.limits.MaxRows) || (r.limits.MaxBytes > 0 && r.bytes > r.limits.MaxBytes) {
+		err := &ResultLimitError{Limit: r.limits, Rows: r.rows - 1, Bytes: r.bytes, Query: NormalizeStatement(r.query)}
+		if r.limits.Truncate {
+			logfContext(r.ctx, LogLevelInfo, "✂️  Truncated result: %v", err)
+			return io.EOF
+		}
+		return err
+	}
+	return nil
+}
+
+// valueSize approximates the memory a scanned column value takes
+func valueSize(value driver.Value) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	case nil:
+		return 0
+	default:
+		return 8
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestResultLimitsAbortOrTruncateRunawayQueries verifies row and byte limits on the
+// pipeline driver, per-call overrides and truncation
+func TestResultLimitsAbortOrTruncateRunawayQueries(t *testing.T) {
+	t.Setenv("DATABASE_MAX_RESULT_ROWS", "10")
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "limits.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, payload TEXT);
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100)
+		INSERT INTO events SELECT i, printf('%.100c', 'x') FROM n`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+
+	count := func(ctx context.Context) (int, error) {
+		rows, err := db.QueryContext(ctx, "SELECT * FROM events")
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+		n := 0
+		for rows.Next() {
+			n++
+		}
+		return n, rows.Err()
+	}
+
+	n, err := count(context.Background())
+	var limitErr *ResultLimitError
+	if !errors.Is(err, ErrResultTooLarge) || !errors.As(err, &limitErr) || limitErr.Rows != 10 || n != 10 {
+		t.Errorf("Expected the env row limit to abort after 10 rows, got %d rows: %v", n, err)
+	}
+
+	if n, err := count(WithResultLimits(context.Background(), ResultLimits{MaxBytes: 1000, Truncate: true})); err != nil || n >= 10 || n == 0 {
+		t.Errorf("Expected the byte limit to truncate under 10 rows, got %d: %v", n, err)
+	}
+	if n, err := count(WithResultLimits(context.Background(), ResultLimits{})); err != nil || n != 100 {
+		t.Errorf("Expected lifted limits to return every row, got %d: %v", n, err)
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM events").Scan(&total); err != nil || total != 100 {
+		t.Errorf("Expected small results to be unaffected, got %d: %v", total, err)
+	}
+}
@@ -1,10 +1,12 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math/rand"
-	"strings"
 	"time"
 )
 
@@ -16,12 +18,28 @@ const (
 	DefaultJitterPercent    = 0.25 // ±25%
 )
 
+// Clock provides the current time and sleeping for the retry loop
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
 // RetryConfig holds configuration for database retry operations
 type RetryConfig struct {
 	MaxRetryDuration time.Duration
 	BaseDelay        time.Duration
 	MaxDelay         time.Duration
 	JitterPercent    float64
+	Clock            Clock // Time source for backoff (default: real time)
+	// Logger receives the retry loop's messages, overriding the logger on the context and
+	// LogConfig for operations using this config (default: the package's logging)
+	Logger *slog.Logger
 }
 
 // DefaultRetryConfig returns the default retry configuration
@@ -34,99 +52,228 @@ func DefaultRetryConfig() RetryConfig {
 	}
 }
 
+// IsBusy reports whether err is SQLITE_BUSY or one of its extended codes
+func IsBusy(err error) bool {
+	return errors.Is(ClassifyError(err), ErrBusy)
+}
+
+// IsLocked reports whether err is SQLITE_LOCKED (a table lock conflict within this process)
+func IsLocked(err error) bool {
+	return errors.Is(ClassifyError(err), ErrLocked)
+}
+
+// isRetryable reports whether waiting can clear err: SQLITE_BUSY, or a table lock held by
+// another connection sharing the cache. Other SQLITE_LOCKED errors conflict with the
+// failing connection itself and never clear.
+func isRetryable(err error) bool {
+	return IsBusy(err) || errors.Is(ClassifyError(err), ErrLockedSharedCache)
+}
+
 // retryDatabaseOperation executes a database operation with exponential backoff retry and jitter
 func retryDatabaseOperation(operation func() error, config RetryConfig) error {
-	var err error
-	startTime := time.Now()
+	return retryDatabaseOperationContext(context.Background(), operation, config)
+}
+
+// retryDatabaseOperationContext is retryDatabaseOperation logging for the request on ctx.
+// It fails fast with ErrCircuitOpen while the circuit breaker is open.
+func retryDatabaseOperationContext(ctx context.Context, operation func() error, config RetryConfig) (err error) {
+	probe, openErr := breaker.allow()
+	if openErr != nil {
+		return openErr
+	}
+	defer func() { breaker.record(err, probe) }()
+
+	config = adaptRetryConfig(config)
+	if config.Logger != nil {
+		ctx = WithLogger(ctx, config.Logger)
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	startTime := clock.Now()
 	attempt := 0
+	waiting := false
+	defer func() {
+		if waiting {
+			retryWaiters.Add(-1)
+		}
+	}()
 
 	for {
 		err = operation()
+		observeRetryAttempt(isRetryable(err))
 		if err == nil {
 			if attempt > 0 {
-				log.Printf("✅ SQLite operation succeeded after %d retries in %v", attempt, time.Since(startTime))
+				logEvent(ctx, LogLevelInfo, []any{"operation", "retry_succeeded", "attempt", attempt + 1, "elapsed", clock.Now().Sub(startTime)},
+					"✅ SQLite operation succeeded after %d retries in %v", attempt, clock.Now().Sub(startTime))
 			}
 			return nil
 		}
 
-		// Check if it's a SQLite BUSY error
-		if !strings.Contains(err.Error(), "database is locked") && !strings.Contains(err.Error(), "SQLITE_BUSY") {
+		// A stale snapshot only clears when the whole transaction restarts
+		if errors.Is(ClassifyError(err), ErrBusySnapshot) {
+			logEvent(ctx, LogLevelError, []any{"operation", "busy_snapshot", "attempt", attempt + 1, "error", err}, "❌ SQLite snapshot is stale - transaction must restart: %v", err)
+			return err
+		}
+
+		// Check if it's a SQLite BUSY (or shared-cache LOCKED) error
+		if !isRetryable(err) {
 			// Non-retryable error
-			log.Printf("❌ Non-retryable SQLite error: %v", err)
+			logEvent(ctx, LogLevelError, []any{"operation", "non_retryable", "attempt", attempt + 1, "error", err}, "❌ Non-retryable SQLite error: %v", err)
 			return err
 		}
 
 		// Check if we've exceeded max retry duration
-		elapsed := time.Since(startTime)
+		elapsed := clock.Now().Sub(startTime)
 		if elapsed >= config.MaxRetryDuration {
-			log.Printf("❌ SQLite operation failed after %v (max retry duration exceeded)", elapsed)
-			return err
+			logEvent(ctx, LogLevelError, []any{"operation", "retry_exhausted", "attempt", attempt + 1, "elapsed", elapsed, "error", err},
+				"❌ SQLite operation failed after %v (max retry duration exceeded)", elapsed)
+			return lockWaitError(ctx, err, elapsed, attempt+1)
 		}
 
-		// Calculate exponential backoff with jitter
-		// Base delay: baseDelay * 2^attempt, capped at maxDelay
-		multiplier := 1 << uint(attempt)
-		baseDelay := time.Duration(int64(config.BaseDelay) * int64(multiplier))
-		if baseDelay > config.MaxDelay {
-			baseDelay = config.MaxDelay
+		delay := backoffDelay(config, attempt, elapsed, rand.Float64)
+		if delay <= 0 {
+			logEvent(ctx, LogLevelError, []any{"operation", "retry_exhausted", "attempt", attempt + 1, "elapsed", elapsed, "error", err},
+				"❌ SQLite operation failed after %v (no time remaining for retry)", elapsed)
+			return lockWaitError(ctx, err, elapsed, attempt+1)
 		}
 
-		// Add jitter: ±jitterPercent of base delay
-		jitterRange := float64(baseDelay) * config.JitterPercent
-		jitter := time.Duration(rand.Float64()*jitterRange*2 - jitterRange) // ±jitterPercent
-		delay := baseDelay + jitter
-
-		// Ensure delay is positive and doesn't exceed remaining time
-		if delay < 0 {
-			delay = config.BaseDelay
+		if !waiting {
+			waiting = true
+			retryWaiters.Add(1)
 		}
-
-		remaining := config.MaxRetryDuration - elapsed
-		if delay > remaining {
-			delay = remaining
+		attempt++
+		logEvent(ctx, LogLevelInfo, []any{"operation", "retry", "attempt", attempt, "elapsed", elapsed, "delay", delay, "error", err},
+			"🔄 SQLite BUSY - retrying in %v (attempt %d, elapsed %v)", delay, attempt, elapsed)
+		if ctxErr := sleepContext(ctx, clock, delay); ctxErr != nil {
+			return retryAborted(ctx, ctxErr, err, attempt)
 		}
+	}
+}
 
-		if delay <= 0 {
-			log.Printf("❌ SQLite operation failed after %v (no time remaining for retry)", elapsed)
-			return err
-		}
+// sleepContext sleeps for d on clock, returning early with ctx's error when it is
+// cancelled or its deadline passes
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) error {
+	if _, ok := clock.(realClock); !ok || ctx.Done() == nil {
+		clock.Sleep(d)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
-		attempt++
-		log.Printf("🔄 SQLite BUSY - retrying in %v (attempt %d, elapsed %v)", delay, attempt, elapsed)
-		time.Sleep(delay)
+// retryAborted returns ctx's error wrapping the last BUSY error
+func retryAborted(ctx context.Context, ctxErr, lastErr error, attempts int) error {
+	logEvent(ctx, LogLevelError, []any{"operation", "retry_aborted", "attempt", attempts, "error", ctxErr}, "❌ SQLite retry aborted after %d attempts: %v", attempts, ctxErr)
+	return fmt.Errorf("%w (last error: %w)", ctxErr, lastErr)
+}
+
+// backoffDelay returns how long to wait before retry attempt+1: baseDelay * 2^attempt,
+// capped at maxDelay, with ±jitterPercent jitter from random, and never past the
+// remaining retry budget
+func backoffDelay(config RetryConfig, attempt int, elapsed time.Duration, random func() float64) time.Duration {
+	multiplier := 1 << uint(attempt)
+	baseDelay := time.Duration(int64(config.BaseDelay) * int64(multiplier))
+	if baseDelay > config.MaxDelay {
+		baseDelay = config.MaxDelay
 	}
+
+	// Add jitter: ±jitterPercent of base delay
+	jitterRange := float64(baseDelay) * config.JitterPercent
+	jitter := time.Duration(random()*jitterRange*2 - jitterRange) // ±jitterPercent
+	delay := baseDelay + jitter
+
+	// Ensure delay is positive and doesn't exceed remaining time
+	if delay < 0 {
+		delay = config.BaseDelay
+	}
+
+	remaining := config.MaxRetryDuration - elapsed
+	if delay > remaining {
+		delay = remaining
+	}
+	return delay
 }
 
 // ExecWithRetry executes a database Exec operation with retry logic
 func ExecWithRetry(db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	return ExecContextWithRetry(context.Background(), db, query, args...)
+}
+
+// ExecContextWithRetry executes a database Exec operation with retry logic
+// in the priority lane set on ctx (see WithQoS)
+func ExecContextWithRetry(ctx context.Context, db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+	if err := checkHandleWriteLimit(db); err != nil {
+		return nil, err
+	}
+	ctx, leave, err := enterWriteQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
 	var result sql.Result
-	var err error
 
-	retryErr := retryDatabaseOperation(func() error {
-		result, err = db.Exec(query, args...)
+	defer trackWrite(query)()
+
+	recorder, execCtx := startStatement(ctx, "exec", query)
+	retryErr := retryInLane(ctx, func() error {
+		recorder.attempt()
+		result, err = db.ExecContext(execCtx, query, args...)
 		return err
-	}, DefaultRetryConfig())
+	}, retryConfigFor(ctx, query))
+	recorder.finish(rowsAffected(result), retryErr)
+	if retryErr == nil {
+		invalidateRowCacheForWrite(ctx, query)
+		countTableWrite(query, rowsAffected(result))
+	}
 
-	return result, retryErr
+	return result, resultError(retryErr)
 }
 
 // QueryWithRetry executes a database Query operation with retry logic
 func QueryWithRetry(db *sql.DB, query string, args ...interface{}) (*sql.Rows, error) {
+	return QueryContextWithRetry(context.Background(), db, query, args...)
+}
+
+// QueryContextWithRetry executes a database Query operation with retry logic in the
+// priority lane set on ctx, abandoning retries once ctx is cancelled or its deadline passes
+func QueryContextWithRetry(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*sql.Rows, error) {
 	var rows *sql.Rows
 	var err error
 
-	retryErr := retryDatabaseOperation(func() error {
-		rows, err = db.Query(query, args...)
+	recorder, ctx := startStatement(ctx, "query", query)
+	retryErr := retryInLane(ctx, func() error {
+		recorder.attempt()
+		rows, err = db.QueryContext(ctx, query, args...)
 		return err
-	}, DefaultRetryConfig())
+	}, retryConfigFor(ctx, query))
+	recorder.finish(-1, retryErr)
 
-	return rows, retryErr
+	return rows, resultError(retryErr)
 }
 
 // QueryRowWithRetry executes a database QueryRow operation with retry logic
 // Returns a wrapper that will retry the entire QueryRow+Scan operation on SQLITE_BUSY
 func QueryRowWithRetry(db *sql.DB, query string, args ...interface{}) *RetryRow {
+	return QueryRowContextWithRetry(context.Background(), db, query, args...)
+}
+
+// QueryRowContextWithRetry is QueryRowWithRetry whose Scan abandons retries once ctx is
+// cancelled or its deadline passes
+func QueryRowContextWithRetry(ctx context.Context, db *sql.DB, query string, args ...interface{}) *RetryRow {
 	return &RetryRow{
+		ctx:   ctx,
 		db:    db,
 		query: query,
 		args:  args,
@@ -135,6 +282,7 @@ func QueryRowWithRetry(db *sql.DB, query string, args ...interface{}) *RetryRow
 
 // RetryRow wraps sql.Row to provide retry functionality
 type RetryRow struct {
+	ctx   context.Context
 	db    *sql.DB
 	query string
 	args  []interface{}
@@ -144,44 +292,82 @@ type RetryRow struct {
 func (r *RetryRow) Scan(dest ...interface{}) error {
 	var err error
 
-	retryErr := retryDatabaseOperation(func() error {
-		row := r.db.QueryRow(r.query, r.args...)
+	recorder, ctx := startStatement(r.ctx, "query", r.query)
+	retryErr := retryInLane(ctx, func() error {
+		recorder.attempt()
+		row := r.db.QueryRowContext(ctx, r.query, r.args...)
 		err = row.Scan(dest...)
 		return err
-	}, DefaultRetryConfig())
+	}, retryConfigFor(ctx, r.query))
+	recorder.finish(-1, retryErr)
 
-	return retryErr
+	return resultError(retryErr)
 }
 
 // TxExecWithRetry executes a transaction Exec operation with retry logic
 func TxExecWithRetry(tx *sql.Tx, query string, args ...interface{}) (sql.Result, error) {
+	return TxExecContextWithRetry(context.Background(), tx, query, args...)
+}
+
+// TxExecContextWithRetry executes a transaction Exec operation with retry logic,
+// abandoning retries once ctx is cancelled or its deadline passes
+func TxExecContextWithRetry(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (sql.Result, error) {
+	if err := checkWriteGuards(ctx); err != nil {
+		return nil, err
+	}
+
 	var result sql.Result
 	var err error
 
-	retryErr := retryDatabaseOperation(func() error {
-		result, err = tx.Exec(query, args...)
+	defer trackWrite(query)()
+
+	recorder, execCtx := startStatement(ctx, "exec", query)
+	retryErr := retryDatabaseOperationContext(execCtx, func() error {
+		recorder.attempt()
+		result, err = tx.ExecContext(execCtx, query, args...)
 		return err
-	}, DefaultRetryConfig())
+	}, retryConfigFor(ctx, query))
+	recorder.finish(rowsAffected(result), retryErr)
+	if retryErr == nil {
+		invalidateRowCacheForWrite(ctx, query)
+		countTableWrite(query, rowsAffected(result))
+	}
 
-	return result, retryErr
+	return result, resultError(retryErr)
 }
 
 // TxQueryWithRetry executes a transaction Query operation with retry logic
 func TxQueryWithRetry(tx *sql.Tx, query string, args ...interface{}) (*sql.Rows, error) {
+	return TxQueryContextWithRetry(context.Background(), tx, query, args...)
+}
+
+// TxQueryContextWithRetry executes a transaction Query operation with retry logic,
+// abandoning retries once ctx is cancelled or its deadline passes
+func TxQueryContextWithRetry(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (*sql.Rows, error) {
 	var rows *sql.Rows
 	var err error
 
-	retryErr := retryDatabaseOperation(func() error {
-		rows, err = tx.Query(query, args...)
+	recorder, ctx := startStatement(ctx, "query", query)
+	retryErr := retryDatabaseOperationContext(ctx, func() error {
+		recorder.attempt()
+		rows, err = tx.QueryContext(ctx, query, args...)
 		return err
-	}, DefaultRetryConfig())
+	}, retryConfigFor(ctx, query))
+	recorder.finish(-1, retryErr)
 
-	return rows, retryErr
+	return rows, resultError(retryErr)
 }
 
 // TxQueryRowWithRetry executes a transaction QueryRow operation with retry logic
 func TxQueryRowWithRetry(tx *sql.Tx, query string, args ...interface{}) *TxRetryRow {
+	return TxQueryRowContextWithRetry(context.Background(), tx, query, args...)
+}
+
+// TxQueryRowContextWithRetry is TxQueryRowWithRetry whose Scan abandons retries once ctx
+// is cancelled or its deadline passes
+func TxQueryRowContextWithRetry(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) *TxRetryRow {
 	return &TxRetryRow{
+		ctx:   ctx,
 		tx:    tx,
 		query: query,
 		args:  args,
@@ -190,6 +376,7 @@ func TxQueryRowWithRetry(tx *sql.Tx, query string, args ...interface{}) *TxRetry
 
 // TxRetryRow wraps sql.Row for transaction queries to provide retry functionality
 type TxRetryRow struct {
+	ctx   context.Context
 	tx    *sql.Tx
 	query string
 	args  []interface{}
@@ -199,59 +386,64 @@ type TxRetryRow struct {
 func (r *TxRetryRow) Scan(dest ...interface{}) error {
 	var err error
 
-	retryErr := retryDatabaseOperation(func() error {
-		row := r.tx.QueryRow(r.query, r.args...)
+	recorder, ctx := startStatement(r.ctx, "query", r.query)
+	retryErr := retryDatabaseOperationContext(ctx, func() error {
+		recorder.attempt()
+		row := r.tx.QueryRowContext(ctx, r.query, r.args...)
 		err = row.Scan(dest...)
 		return err
-	}, DefaultRetryConfig())
+	}, retryConfigFor(ctx, r.query))
+	recorder.finish(-1, retryErr)
 
-	return retryErr
+	return resultError(retryErr)
 }
 
 // WithTransactionRetry executes a function within a database transaction with retry logic
-// This creates its own transaction and doesn't use the nested WithTransaction to avoid double-retry issues
+// This creates its own transaction and doesn't use the nested WithTransaction to avoid double-retry issues.
+// The transaction restarts when it loses a write conflict (see WithTransactionRetryOptions).
 func WithTransactionRetry(fn func(*sql.Tx) error) error {
-	return retryDatabaseOperation(func() error {
-		// Get fresh database connection
-		db, err := GetDB()
-		if err != nil {
-			return err
-		}
-		defer db.Close()
-
-		// Begin transaction (without nested retry to avoid conflicts)
-		tx, err := db.Begin()
-		if err != nil {
-			return err
-		}
-
-		// Execute the function
-		if err := fn(tx); err != nil {
-			// Rollback on error (simple rollback without retry)
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				log.Printf("❌ Failed to rollback transaction: %v", rollbackErr)
-			}
-			return err
-		}
+	return WithTransactionRetryContext(context.Background(), fn)
+}
 
-		// Commit the transaction (simple commit without retry)
-		return tx.Commit()
-	}, DefaultRetryConfig())
+// WithTransactionRetryContext runs WithTransactionRetry in the priority lane set on ctx,
+// abandoning retries and restarts once ctx is cancelled or its deadline passes
+func WithTransactionRetryContext(ctx context.Context, fn func(*sql.Tx) error) error {
+	return WithTransactionRetryOptions(ctx, TransactionOptions{}, fn)
 }
 
 // Custom retry functions for specific configurations
 
 // ExecWithRetryConfig executes a database Exec operation with custom retry config
 func ExecWithRetryConfig(db *sql.DB, config RetryConfig, query string, args ...interface{}) (sql.Result, error) {
+	if err := checkWriteGuards(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := checkHandleWriteLimit(db); err != nil {
+		return nil, err
+	}
+	ctx, leave, err := enterWriteQueue(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
 	var result sql.Result
-	var err error
 
+	defer trackWrite(query)()
+
+	recorder, ctx := startStatement(ctx, "exec", query)
 	retryErr := retryDatabaseOperation(func() error {
-		result, err = db.Exec(query, args...)
+		recorder.attempt()
+		result, err = db.ExecContext(ctx, query, args...)
 		return err
 	}, config)
+	recorder.finish(rowsAffected(result), retryErr)
+	if retryErr == nil {
+		invalidateRowCacheForWrite(context.Background(), query)
+		countTableWrite(query, rowsAffected(result))
+	}
 
-	return result, retryErr
+	return result, resultError(retryErr)
 }
 
 // QueryWithRetryConfig executes a database Query operation with custom retry config
@@ -259,10 +451,13 @@ func QueryWithRetryConfig(db *sql.DB, config RetryConfig, query string, args ...
 	var rows *sql.Rows
 	var err error
 
+	recorder, ctx := startStatement(context.Background(), "query", query)
 	retryErr := retryDatabaseOperation(func() error {
-		rows, err = db.Query(query, args...)
+		recorder.attempt()
+		rows, err = db.QueryContext(ctx, query, args...)
 		return err
 	}, config)
+	recorder.finish(-1, retryErr)
 
-	return rows, retryErr
+	return rows, resultError(retryErr)
 }
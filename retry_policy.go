@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// StatementKind classifies a SQL statement for retry policy selection
+type StatementKind int
+
+// Statement kinds
+const (
+	StatementRead  StatementKind = iota // SELECT, read-only PRAGMA, EXPLAIN
+	StatementWrite                      // INSERT, UPDATE, DELETE, REPLACE and anything unrecognized
+	StatementDDL                        // CREATE, DROP, ALTER and maintenance statements
+)
+
+// String returns the name of the statement kind
+func (k StatementKind) String() string {
+	switch k {
+	case StatementRead:
+		return "read"
+	case StatementDDL:
+		return "ddl"
+	default:
+		return "write"
+	}
+}
+
+// RetryPolicy selects a retry config per statement kind
+type RetryPolicy struct {
+	Read  RetryConfig
+	Write RetryConfig
+	DDL   RetryConfig
+}
+
+// DefaultRetryPolicy returns a policy that uses DefaultRetryConfig for every statement kind
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Read:  DefaultRetryConfig(),
+		Write: DefaultRetryConfig(),
+		DDL:   DefaultRetryConfig(),
+	}
+}
+
+// retryPolicy is the active package-wide retry policy
+var (
+	retryPolicyMu sync.RWMutex
+	retryPolicy   = DefaultRetryPolicy()
+)
+
+// SetRetryPolicy replaces the package-wide retry policy
+func SetRetryPolicy(policy RetryPolicy) {
+	retryPolicyMu.Lock()
+	defer retryPolicyMu.Unlock()
+	retryPolicy = policy
+}
+
+// retryConfigContextKey is the context key for a per-call retry config override
+type retryConfigContextKey struct{}
+
+// WithRetryConfig returns a context that overrides the retry policy for calls made with it
+func WithRetryConfig(ctx context.Context, config RetryConfig) context.Context {
+	return context.WithValue(ctx, retryConfigContextKey{}, config)
+}
+
+// retryConfigFor returns the retry config for a statement: the per-call override on ctx
+// if set, otherwise the policy entry for the statement's kind
+func retryConfigFor(ctx context.Context, query string) RetryConfig {
+	if config, ok := ctx.Value(retryConfigContextKey{}).(RetryConfig); ok {
+		return config
+	}
+
+	retryPolicyMu.RLock()
+	defer retryPolicyMu.RUnlock()
+
+	switch ClassifyStatement(query) {
+	case StatementRead:
+		return retryPolicy.Read
+	case StatementDDL:
+		return retryPolicy.DDL
+	default:
+		return retryPolicy.Write
+	}
+}
+
+// ClassifyStatement determines the kind of a SQL statement from its leading keyword
+func ClassifyStatement(query string) StatementKind {
+	words := strings.Fields(strings.ToUpper(stripLeadingComments(query)))
+	if len(words) == 0 {
+		return StatementWrite
+	}
+
+	switch strings.TrimRight(words[0], "(;") {
+	case "SELECT", "VALUES", "EXPLAIN":
+		return StatementRead
+	case "PRAGMA":
+		// PRAGMA name = value changes state; PRAGMA name and PRAGMA name(arg) read it
+		if strings.Contains(query, "=") {
+			return StatementWrite
+		}
+		return StatementRead
+	case "WITH":
+		// A common table expression can precede a write
+		for _, word := range words[1:] {
+			switch word {
+			case "INSERT", "UPDATE", "DELETE", "REPLACE":
+				return StatementWrite
+			}
+		}
+		return StatementRead
+	case "CREATE", "DROP", "ALTER", "REINDEX", "VACUUM", "ANALYZE":
+		return StatementDDL
+	default:
+		return StatementWrite
+	}
+}
+
+// stripLeadingComments removes whitespace and SQL comments before the first keyword
+func stripLeadingComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			end := strings.Index(query, "\n")
+			if end < 0 {
+				return ""
+			}
+			query = query[end+1:]
+		case strings.HasPrefix(query, "/*"):
+			end := strings.Index(query, "*/")
+			if end < 0 {
+				return ""
+			}
+			query = query[end+2:]
+		default:
+			return query
+		}
+	}
+}
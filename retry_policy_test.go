@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestClassifyStatement verifies statement kinds used to select retry policies
+func TestClassifyStatement(t *testing.T) {
+	cases := map[string]StatementKind{
+		"SELECT * FROM users":                                      StatementRead,
+		"  -- comment\n/* block */ select 1":                       StatementRead,
+		"WITH recent AS (SELECT 1) SELECT * FROM recent":           StatementRead,
+		"WITH ids AS (SELECT 1) DELETE FROM users WHERE id IN ids": StatementWrite,
+		"INSERT INTO users (id) VALUES (1)":                        StatementWrite,
+		"PRAGMA page_count":                                        StatementRead,
+		"PRAGMA journal_mode = WAL":                                StatementWrite,
+		"CREATE TABLE users (id INTEGER)":                          StatementDDL,
+		"ALTER TABLE users ADD COLUMN name TEXT":                   StatementDDL,
+	}
+	for query, expected := range cases {
+		if kind := ClassifyStatement(query); kind != expected {
+			t.Errorf("ClassifyStatement(%q) = %s, expected %s", query, kind, expected)
+		}
+	}
+}
+
+// TestRetryConfigForSelectsPolicy verifies policy selection by statement kind and per-call override
+func TestRetryConfigForSelectsPolicy(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.Write.MaxRetryDuration = time.Second
+	policy.DDL.MaxRetryDuration = 0
+	SetRetryPolicy(policy)
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	ctx := context.Background()
+	if config := retryConfigFor(ctx, "SELECT 1"); config.MaxRetryDuration != DefaultMaxRetryDuration {
+		t.Errorf("Expected read budget %v, got %v", DefaultMaxRetryDuration, config.MaxRetryDuration)
+	}
+	if config := retryConfigFor(ctx, "UPDATE users SET name = ?"); config.MaxRetryDuration != time.Second {
+		t.Errorf("Expected write budget 1s, got %v", config.MaxRetryDuration)
+	}
+	if config := retryConfigFor(ctx, "DROP TABLE users"); config.MaxRetryDuration != 0 {
+		t.Errorf("Expected no retry for DDL, got %v", config.MaxRetryDuration)
+	}
+
+	override := WithRetryConfig(ctx, RetryConfig{MaxRetryDuration: 5 * time.Second})
+	if config := retryConfigFor(override, "UPDATE users SET name = ?"); config.MaxRetryDuration != 5*time.Second {
+		t.Errorf("Expected per-call override 5s, got %v", config.MaxRetryDuration)
+	}
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// DefaultSimulationTrials is how many operations RetrySimulator simulates by default
+const DefaultSimulationTrials = 10000
+
+// ContentionProfile describes synthetic write contention: how often an operation finds
+// the database locked and how long the lock then stays held
+type ContentionProfile struct {
+	BusyProbability float64       // Chance an operation starts while another writer holds the lock (0-1)
+	MeanLockHold    time.Duration // Mean remaining lock hold, exponentially distributed
+	AttemptDuration time.Duration // Time one attempt takes, successful or not
+}
+
+// RetrySimulator estimates how a RetryConfig behaves under a ContentionProfile by running
+// the retry backoff against a simulated clock, without touching a database. Use it to
+// pick budgets that meet a latency SLO:
+//
+//	sim := database.RetrySimulator{Profile: database.ContentionProfile{BusyProbability: 0.2, MeanLockHold: 200 * time.Millisecond}}
+//	report := sim.Simulate(database.DefaultRetryConfig())
+//	fmt.Println(report.P99, report.GiveUpRate)
+type RetrySimulator struct {
+	Profile ContentionProfile
+	Trials  int   // Default: DefaultSimulationTrials
+	Seed    int64 // Random seed, so reports are reproducible
+}
+
+// RetrySimulation is the outcome of a simulation. Latencies cover every trial, including
+// those that gave up.
+type RetrySimulation struct {
+	Trials       int
+	GaveUp       int
+	GiveUpRate   float64
+	MeanAttempts float64
+	P50          time.Duration
+	P95          time.Duration
+	P99          time.Duration
+	Max          time.Duration
+}
+
+// Simulate runs the trials against config. Adaptive retry is not applied.
+func (s RetrySimulator) Simulate(config RetryConfig) RetrySimulation {
+	trials := s.Trials
+	if trials <= 0 {
+		trials = DefaultSimulationTrials
+	}
+	random := rand.New(rand.NewSource(s.Seed))
+	latencies := make([]time.Duration, trials)
+	report := RetrySimulation{Trials: trials}
+	attempts := 0
+
+	for i := range latencies {
+		var hold time.Duration
+		if random.Float64() < s.Profile.BusyProbability {
+			hold = time.Duration(random.ExpFloat64() * float64(s.Profile.MeanLockHold))
+		}
+		var elapsed time.Duration
+		for attempt := 0; ; attempt++ {
+			attempts++
+			elapsed += s.Profile.AttemptDuration
+			if elapsed >= hold {
+				break
+			}
+			if elapsed >= config.MaxRetryDuration {
+				report.GaveUp++
+				break
+			}
+			delay := backoffDelay(config, attempt, elapsed, random.Float64)
+			if delay <= 0 {
+				report.GaveUp++
+				break
+			}
+			elapsed += delay
+		}
+		latencies[i] = elapsed
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(trials-1))]
+	}
+	report.GiveUpRate = float64(report.GaveUp) / float64(trials)
+	report.MeanAttempts = float64(attempts) / float64(trials)
+	report.P50, report.P95, report.P99 = percentile(0.50), percentile(0.95), percentile(0.99)
+	report.Max = latencies[trials-1]
+	return report
+}
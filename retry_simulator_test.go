@@ -0,0 +1,34 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRetrySimulatorReportsLatencyAndGiveUps verifies the simulation is reproducible and
+// that a tighter budget trades latency for give-ups
+func TestRetrySimulatorReportsLatencyAndGiveUps(t *testing.T) {
+	sim := RetrySimulator{
+		Profile: ContentionProfile{BusyProbability: 0.3, MeanLockHold: 500 * time.Millisecond, AttemptDuration: time.Millisecond},
+		Trials:  2000,
+		Seed:    1,
+	}
+
+	generous := sim.Simulate(DefaultRetryConfig())
+	if generous != sim.Simulate(DefaultRetryConfig()) {
+		t.Error("Expected the same seed to give the same report")
+	}
+	if generous.GaveUp != 0 || generous.P50 != time.Millisecond || generous.P99 <= generous.P50 || generous.MeanAttempts <= 1 {
+		t.Errorf("Unexpected report for the default config: %+v", generous)
+	}
+
+	tight := DefaultRetryConfig()
+	tight.MaxRetryDuration = 100 * time.Millisecond
+	report := sim.Simulate(tight)
+	if report.GiveUpRate < 0.1 || report.GiveUpRate > 0.3 {
+		t.Errorf("Expected roughly a fifth of operations to give up, got %v", report.GiveUpRate)
+	}
+	if report.Max > tight.MaxRetryDuration+time.Millisecond {
+		t.Errorf("Expected no trial to outlast the budget, max %v", report.Max)
+	}
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeClock advances virtual time on Sleep instead of waiting
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+// TestRetryUsesInjectedClock verifies that the retry loop backs off exponentially
+// and gives up after MaxRetryDuration of virtual time without real waiting
+func TestRetryUsesInjectedClock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	config := RetryConfig{
+		MaxRetryDuration: time.Second,
+		BaseDelay:        10 * time.Millisecond,
+		MaxDelay:         200 * time.Millisecond,
+		Clock:            clock,
+	}
+
+	busy := errors.New("database is locked")
+	attempts := 0
+	started := time.Now()
+	err := retryDatabaseOperation(func() error {
+		attempts++
+		return busy
+	}, config)
+
+	if !errors.Is(err, busy) {
+		t.Fatalf("Expected busy error, got %v", err)
+	}
+	if time.Since(started) > 100*time.Millisecond {
+		t.Errorf("Retry loop waited in real time")
+	}
+	if elapsed := clock.now.Sub(time.Unix(0, 0)); elapsed != config.MaxRetryDuration {
+		t.Errorf("Expected %v of virtual time, got %v", config.MaxRetryDuration, elapsed)
+	}
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond, 160 * time.Millisecond, 200 * time.Millisecond}
+	for i, d := range expected {
+		if clock.sleeps[i] != d {
+			t.Errorf("Sleep %d: expected %v, got %v", i, d, clock.sleeps[i])
+		}
+	}
+	if attempts != len(clock.sleeps)+1 {
+		t.Errorf("Expected %d attempts, got %d", len(clock.sleeps)+1, attempts)
+	}
+}
+
+// TestRetryStopsWhenContextIsCancelled verifies the retry loop abandons its backoff as
+// soon as ctx is done and reports both the context error and the last BUSY error
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	config := RetryConfig{MaxRetryDuration: time.Minute, BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Second}
+	busy := errors.New("database is locked")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	attempts := 0
+	err := retryDatabaseOperationContext(ctx, func() error {
+		attempts++
+		return busy
+	}, config)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, busy) {
+		t.Fatalf("Expected the deadline and busy errors, got %v", err)
+	}
+	if waited := time.Since(started); waited > time.Second || attempts != 1 {
+		t.Errorf("Expected one attempt and an early return, got %d attempts after %v", attempts, waited)
+	}
+
+	// An injected clock is checked after each virtual sleep
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	config.Clock = clock
+	ctx, cancel = context.WithCancel(context.Background())
+	attempts = 0
+	err = retryDatabaseOperationContext(ctx, func() error {
+		attempts++
+		if attempts == 3 {
+			cancel()
+		}
+		return busy
+	}, config)
+	if !errors.Is(err, context.Canceled) || attempts != 3 {
+		t.Errorf("Expected cancellation after 3 attempts, got %d: %v", attempts, err)
+	}
+}
+
+// TestContextRetryHelpers verifies the query helpers run in the QoS lane set on ctx and
+// the transaction helpers honor ctx
+func TestContextRetryHelpers(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "retry.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := ExecContextWithRetry(context.Background(), db, "CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A batch query yields to running interactive work until its deadline
+	activeInteractive.Add(1)
+	ctx, cancel := context.WithTimeout(WithQoS(context.Background(), QoSBatch), 20*time.Millisecond)
+	_, err = QueryContextWithRetry(ctx, db, "SELECT id FROM items")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the batch query to yield until its deadline, got %v", err)
+	}
+	var n int
+	if err := QueryRowContextWithRetry(ctx, db, "SELECT COUNT(*) FROM items").Scan(&n); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the batch row query to yield until its deadline, got %v", err)
+	}
+	cancel()
+	activeInteractive.Add(-1)
+
+	err = WithTransactionRetryContext(context.Background(), func(tx *sql.Tx) error {
+		if _, err := TxExecContextWithRetry(context.Background(), tx, "INSERT INTO items (id) VALUES (1)"); err != nil {
+			return err
+		}
+		if err := TxQueryRowContextWithRetry(context.Background(), tx, "SELECT COUNT(*) FROM items").Scan(&n); err != nil || n != 1 {
+			return fmt.Errorf("expected 1 row in the transaction, got %d: %v", n, err)
+		}
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := TxExecContextWithRetry(canceled, tx, "INSERT INTO items (id) VALUES (2)"); !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("expected a canceled exec, got %v", err)
+		}
+		if _, err := TxQueryContextWithRetry(canceled, tx, "SELECT id FROM items"); !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("expected a canceled query, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestContextFunctionsStopRetrying verifies that package functions taking a ctx stop
+// backing off on BUSY once their deadline passes
+func TestContextFunctionsStopRetrying(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locked.db")
+	t.Setenv("DATABASE_FILE", path)
+	defer CloseAll()
+
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer other.Close()
+	other.SetMaxOpenConns(1)
+	if _, err := other.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to set up: %v", err)
+	}
+	// Readers still get through; writers see BUSY until the lock is released
+	if _, err := other.Exec("BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("Failed to lock database: %v", err)
+	}
+	defer other.Exec("ROLLBACK")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := TrackTableChanges(ctx, "items", "id"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to stop retries, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected retries to stop near the deadline, took %v", elapsed)
+	}
+}
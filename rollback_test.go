@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestRollbackOnFailureRestoresPreDeploySchema verifies that a failed batch runs the
+// down files of the migrations it applied and leaves the version clean
+func TestRollbackOnFailureRestoresPreDeploySchema(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DATABASE_FILE", filepath.Join(tempDir, "rollback.db"))
+	dir := writeMigrations(t, filepath.Join(tempDir, "shop"), map[string]string{
+		"001_create_products.up.sql":   "CREATE TABLE products (id INTEGER PRIMARY KEY);",
+		"001_create_products.down.sql": "DROP TABLE products;",
+	})
+
+	globalRegistry.mu.Lock()
+	globalRegistry.sources = []MigrationSource{}
+	globalRegistry.mu.Unlock()
+	defer func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.sources = []MigrationSource{}
+		globalRegistry.mu.Unlock()
+	}()
+
+	RegisterMigrations(MigrationSource{Name: "shop", Directory: dir, RollbackOnFailure: true})
+	if err := UpAll(); err != nil {
+		t.Fatalf("Failed to apply first deploy: %v", err)
+	}
+
+	writeMigrations(t, dir, map[string]string{
+		"002_create_orders.up.sql":   "CREATE TABLE orders (id INTEGER PRIMARY KEY);",
+		"002_create_orders.down.sql": "DROP TABLE orders;",
+		"003_add_index.up.sql":       "CREATE INDEX idx_orders_missing ON orders (missing_column);",
+		"003_add_index.down.sql":     "DROP INDEX idx_orders_missing;",
+	})
+	if err := UpAllContext(context.Background()); err == nil {
+		t.Fatal("Expected the broken migration to fail the deploy")
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('products', 'orders')").Scan(&tables); err != nil {
+		t.Fatalf("Failed to check tables: %v", err)
+	}
+	if tables != 1 {
+		t.Errorf("Expected only products to remain, found %d tables", tables)
+	}
+
+	var version int
+	var dirty bool
+	if err := db.QueryRow("SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty); err != nil {
+		t.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version != 1 || dirty {
+		t.Errorf("Expected clean version 1, got %d (dirty: %t)", version, dirty)
+	}
+}
@@ -0,0 +1,413 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Row cache defaults
+const (
+	DefaultRowCacheEntries      = 10000
+	DefaultRowCacheTTL          = time.Minute
+	DefaultRowCachePollInterval = time.Second
+)
+
+// RowCacheOptions configures the entity row cache
+type RowCacheOptions struct {
+	MaxEntries int           // Rows kept before the least recently used is evicted (default: DefaultRowCacheEntries)
+	TTL        time.Duration // Longest a row is served from the cache (default: DefaultRowCacheTTL)
+	// PollInterval is how often the sync change log is read to invalidate rows of tables
+	// tracked with TrackTableChanges, including writes by other processes (default: DefaultRowCachePollInterval)
+	PollInterval time.Duration
+}
+
+// RowCacheStats reports the row cache's effectiveness
+type RowCacheStats struct {
+	Entries       int     `json:"entries"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRate       float64 `json:"hit_rate"` // Hits / (Hits + Misses), 0 before any lookup
+	Invalidations int64   `json:"invalidations"`
+}
+
+// rowCacheKey identifies a cached row
+type rowCacheKey struct {
+	table string
+	key   string
+}
+
+// rowCacheEntry is a cached row in the LRU list
+type rowCacheEntry struct {
+	key     rowCacheKey
+	row     map[string]interface{}
+	expires time.Time
+}
+
+// rowCache caches rows of registered repositories by primary key
+type rowCache struct {
+	opts RowCacheOptions
+	db   *sql.DB
+
+	mu         sync.Mutex
+	entries    map[rowCacheKey]*list.Element
+	lru        *list.List
+	generation uint64          // Bumped by every invalidation, so racing loads are not cached
+	tracked    map[string]bool // Tables with change log triggers
+	cursor     int64           // Last change log sequence seen
+
+	hits, misses, invalidations atomic.Int64
+}
+
+// activeRowCache is the running row cache, if any
+var activeRowCache atomic.Pointer[rowCache]
+
+// EnableRowCache caches rows read with CachedRow until ctx is cancelled. Rows are
+// invalidated when their table is written through the retry helpers, DBTX or the
+// pipeline driver: precisely via the sync change log for tables tracked with
+// TrackTableChanges, otherwise by dropping the table's cached rows.
+func EnableRowCache(ctx context.Context, opts RowCacheOptions) error {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultRowCacheEntries
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultRowCacheTTL
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultRowCachePollInterval
+	}
+
+	db, err := sql.Open("sqlite", buildDSN(configValue("DATABASE_FILE")))
+	if err != nil {
+		return err
+	}
+	cache := &rowCache{opts: opts, db: db, entries: make(map[rowCacheKey]*list.Element), lru: list.New()}
+	if err := cache.poll(ctx); err != nil {
+		db.Close()
+		return err
+	}
+	activeRowCache.Store(cache)
+	logf("⚡ Row cache enabled (up to %d rows, TTL %v)", opts.MaxEntries, opts.TTL)
+
+	tracker := startSubsystem("row_cache", opts.PollInterval)
+	go func() {
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+		defer tracker.stop()
+		for {
+			select {
+			case <-ctx.Done():
+				activeRowCache.CompareAndSwap(cache, nil)
+				db.Close()
+				return
+			case <-ticker.C:
+				tracker.run(func() error {
+					err := cache.poll(ctx)
+					if err != nil && ctx.Err() == nil {
+						logWarnf("⚠️  Row cache failed to read the change log: %v", err)
+					}
+					return err
+				})
+			}
+		}
+	}()
+	return nil
+}
+
+// GetRowCacheStats returns the row cache's hit rate and size (zero when disabled)
+func GetRowCacheStats() RowCacheStats {
+	cache := activeRowCache.Load()
+	if cache == nil {
+		return RowCacheStats{}
+	}
+	cache.mu.Lock()
+	stats := RowCacheStats{Entries: cache.lru.Len()}
+	cache.mu.Unlock()
+	stats.Hits, stats.Misses, stats.Invalidations = cache.hits.Load(), cache.misses.Load(), cache.invalidations.Load()
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// CachedRow returns the row of a registered repository's table with primary key key,
+// from the row cache when enabled. It returns sql.ErrNoRows when the row does not exist.
+// The returned map is the caller's to modify.
+func CachedRow(ctx context.Context, table string, key interface{}) (map[string]interface{}, error) {
+	pkColumn, err := repositoryPrimaryKey(table)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", quoteIdentifier(table), quoteIdentifier(pkColumn))
+
+	cache := activeRowCache.Load()
+	if cache == nil {
+		db, err := GetDB()
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		return loadRow(ctx, db, query, key)
+	}
+
+	cacheKey := rowCacheKey{table: table, key: fmt.Sprint(key)}
+	row, generation, ok := cache.get(cacheKey)
+	if ok {
+		cache.hits.Add(1)
+		return copyRow(row), nil
+	}
+	cache.misses.Add(1)
+
+	row, err = loadRow(ctx, cache.db, query, key)
+	if err != nil {
+		return nil, err
+	}
+	cache.put(cacheKey, row, generation)
+	return copyRow(row), nil
+}
+
+// InvalidateCachedRow drops a row from the row cache, for writes made outside this
+// package's helpers
+func InvalidateCachedRow(table string, key interface{}) {
+	if cache := activeRowCache.Load(); cache != nil {
+		cache.invalidate(func(k rowCacheKey) bool { return k.table == table && k.key == fmt.Sprint(key) })
+	}
+}
+
+// repositoryPrimaryKey returns the primary key column of the repository backed by table
+func repositoryPrimaryKey(table string) (string, error) {
+	for _, repo := range GetRegisteredRepositories() {
+		if repo.Table != table {
+			continue
+		}
+		fields, err := modelFields(repo.Model)
+		if err != nil {
+			return "", fmt.Errorf("repository %s: %w", repo.Name, err)
+		}
+		for _, f := range fields {
+			if f.PrimaryKey {
+				return f.Column, nil
+			}
+		}
+		return "", fmt.Errorf("repository %s has no primary key field (tag a field with `db:\"column,pk\"`)", repo.Name)
+	}
+	return "", fmt.Errorf("no repository registered for table %s", table)
+}
+
+// loadRow reads one row by primary key
+func loadRow(ctx context.Context, db *sql.DB, query string, key interface{}) (map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := retryDatabaseOperationContext(ctx, func() error {
+		result, err := db.QueryContext(ctx, query, key)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+		rows, _, err = scanRowMaps(result, 1)
+		return err
+	}, retryConfigFor(ctx, query))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return rows[0], nil
+}
+
+// copyRow returns a shallow copy of a cached row, copying []byte values
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		if b, ok := value.([]byte); ok {
+			value = append([]byte(nil), b...)
+		}
+		copied[column] = value
+	}
+	return copied
+}
+
+// get returns a live cached row, or the current generation to load it under
+func (c *rowCache) get(key rowCacheKey) (map[string]interface{}, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*rowCacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(element)
+			return entry.row, c.generation, true
+		}
+		c.lru.Remove(element)
+		delete(c.entries, key)
+	}
+	return nil, c.generation, false
+}
+
+// put caches a row loaded under generation, unless an invalidation happened since
+func (c *rowCache) put(key rowCacheKey, row map[string]interface{}, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		c.lru.Remove(element)
+	}
+	c.entries[key] = c.lru.PushFront(&rowCacheEntry{key: key, row: row, expires: time.Now().Add(c.opts.TTL)})
+	for c.lru.Len() > c.opts.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*rowCacheEntry).key)
+	}
+}
+
+// invalidate drops the cached rows matching match
+func (c *rowCache) invalidate(match func(rowCacheKey) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for key, element := range c.entries {
+		if match(key) {
+			c.lru.Remove(element)
+			delete(c.entries, key)
+			c.invalidations.Add(1)
+		}
+	}
+}
+
+// poll invalidates rows changed in the sync change log since the last poll and
+// refreshes the set of tracked tables
+func (c *rowCache) poll(ctx context.Context) error {
+	tracked := make(map[string]bool)
+	rows, err := c.db.QueryContext(ctx, "SELECT table_name FROM _sync_tables")
+	if err != nil {
+		if isMissingTable(err) {
+			return nil // No table is tracked yet
+		}
+		return err
+	}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tracked[table] = true
+	}
+	rows.Close()
+
+	c.mu.Lock()
+	cursor, first := c.cursor, c.tracked == nil
+	c.tracked = tracked
+	c.mu.Unlock()
+
+	if first {
+		// Rows changed before the cache existed are not cached, so skip their changes
+		if err := c.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM _sync_changes").Scan(&cursor); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.cursor = max(c.cursor, cursor)
+		c.mu.Unlock()
+		return nil
+	}
+
+	rows, err = c.db.QueryContext(ctx, "SELECT seq, table_name, row_key FROM _sync_changes WHERE seq > ? ORDER BY seq", cursor)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	changed := make(map[rowCacheKey]bool)
+	for rows.Next() {
+		var key rowCacheKey
+		if err := rows.Scan(&cursor, &key.table, &key.key); err != nil {
+			return err
+		}
+		changed[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if cursor > c.cursor {
+		c.cursor = cursor
+	}
+	c.mu.Unlock()
+	if len(changed) > 0 {
+		c.invalidate(func(key rowCacheKey) bool { return changed[key] })
+	}
+	return nil
+}
+
+// invalidateRowCacheForWrite invalidates the rows a write statement may have changed
+func invalidateRowCacheForWrite(ctx context.Context, query string) {
+	cache := activeRowCache.Load()
+	if cache == nil {
+		return
+	}
+	switch ClassifyStatement(query) {
+	case StatementRead:
+		return
+	case StatementDDL:
+		cache.invalidate(func(rowCacheKey) bool { return true })
+		return
+	}
+
+	table := writeTargetTable(query)
+	if table == "" {
+		cache.invalidate(func(rowCacheKey) bool { return true })
+		return
+	}
+	cache.mu.Lock()
+	tracked := cache.tracked[table]
+	cache.mu.Unlock()
+	if tracked {
+		// The change log names the exact rows; inside a transaction they appear on the
+		// poll after COMMIT
+		if err := cache.poll(context.WithoutCancel(ctx)); err == nil {
+			return
+		}
+	}
+	cache.invalidate(func(key rowCacheKey) bool { return key.table == table })
+}
+
+// writeTargetTable returns the table written by an INSERT, REPLACE, UPDATE or DELETE
+// statement, or "" when it cannot be determined
+func writeTargetTable(query string) string {
+	words := strings.Fields(stripLeadingComments(query))
+	for i := 0; i < len(words); i++ {
+		switch strings.ToUpper(words[i]) {
+		case "INTO", "FROM":
+			if i+1 < len(words) {
+				return unquoteTable(words[i+1])
+			}
+		case "UPDATE":
+			if i+1 < len(words) && strings.ToUpper(words[i+1]) == "OR" {
+				i += 2
+			}
+			if i+1 < len(words) {
+				return unquoteTable(words[i+1])
+			}
+		case "WITH":
+			return ""
+		}
+	}
+	return ""
+}
+
+// unquoteTable strips quotes, a schema prefix and trailing punctuation from a table name
+func unquoteTable(name string) string {
+	if open := strings.IndexAny(name, "(;"); open >= 0 {
+		name = name[:open]
+	}
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return strings.Trim(name, "\"`[]")
+}
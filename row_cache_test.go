@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRowCacheInvalidatesOnWrites verifies that cached rows are served until written,
+// through the retry helpers or (for tracked tables) by another connection
+func TestRowCacheInvalidatesOnWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	t.Setenv("DATABASE_FILE", path)
+
+	globalRepositories.mu.Lock()
+	saved := globalRepositories.repositories
+	globalRepositories.repositories = nil
+	globalRepositories.mu.Unlock()
+	defer func() {
+		globalRepositories.mu.Lock()
+		globalRepositories.repositories = saved
+		globalRepositories.mu.Unlock()
+	}()
+	type user struct {
+		ID   int64  `db:"id,pk"`
+		Name string `db:"name"`
+	}
+	RegisterRepository(Repository{Table: "users", Model: user{}})
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)", "INSERT INTO users VALUES (1, 'ada')"} {
+		if _, err := ExecWithRetry(db, stmt); err != nil {
+			t.Fatalf("Failed to set up: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := EnableRowCache(ctx, RowCacheOptions{PollInterval: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("Failed to enable row cache: %v", err)
+	}
+
+	name := func() interface{} {
+		t.Helper()
+		row, err := CachedRow(ctx, "users", 1)
+		if err != nil {
+			t.Fatalf("Failed to read cached row: %v", err)
+		}
+		return row["name"]
+	}
+	name()
+	if got := name(); got != "ada" {
+		t.Errorf("Expected ada, got %v", got)
+	}
+	if stats := GetRowCacheStats(); stats.Hits != 1 || stats.Misses != 1 || stats.HitRate != 0.5 {
+		t.Errorf("Expected one hit and one miss, got %+v", stats)
+	}
+
+	if _, err := ExecWithRetry(db, "UPDATE users SET name = 'grace' WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if got := name(); got != "grace" {
+		t.Errorf("Expected the write to invalidate the cached row, got %v", got)
+	}
+
+	if err := TrackTableChanges(ctx, "users", "id"); err != nil {
+		t.Fatalf("Failed to track users: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // Let the poller pick up the tracked table
+	name()
+	if _, err := db.Exec("UPDATE users SET name = 'linus' WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to update outside the helpers: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for name() != "linus" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the change log to invalidate the cached row")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := CachedRow(ctx, "users", 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing row, got %v", err)
+	}
+}
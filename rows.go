@@ -0,0 +1,172 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// scanBuffer holds the destinations one row is scanned into, reused across rows and calls
+type scanBuffer struct {
+	values   []interface{}
+	pointers []interface{}
+}
+
+// scanBuffers pools scan buffers so hot read paths do not allocate them per row
+var scanBuffers = sync.Pool{New: func() interface{} { return &scanBuffer{} }}
+
+// getScanBuffer returns a pooled buffer sized for columns columns
+func getScanBuffer(columns int) *scanBuffer {
+	buf := scanBuffers.Get().(*scanBuffer)
+	if cap(buf.values) < columns {
+		buf.values = make([]interface{}, columns)
+		buf.pointers = make([]interface{}, columns)
+	}
+	buf.values, buf.pointers = buf.values[:columns], buf.pointers[:columns]
+	for i := range buf.values {
+		buf.pointers[i] = &buf.values[i]
+	}
+	return buf
+}
+
+// putScanBuffer clears a buffer's values, so pooled buffers do not pin row data, and
+// returns it to the pool
+func putScanBuffer(buf *scanBuffer) {
+	clear(buf.values)
+	scanBuffers.Put(buf)
+}
+
+// scanRowMaps reads remaining rows into column-name keyed maps, stopping after
+// limit rows when limit is positive
+func scanRowMaps(rows *sql.Rows, limit int) ([]map[string]interface{}, []string, error) {
+	var result []map[string]interface{}
+	if limit > 0 {
+		result = make([]map[string]interface{}, 0, min(limit, 64))
+	}
+	columns, err := eachRowMap(rows, func(row map[string]interface{}) bool {
+		result = append(result, row)
+		return limit <= 0 || len(result) < limit
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, columns, nil
+}
+
+// eachRowMap scans remaining rows into column-name keyed maps and passes each to fn,
+// stopping early when fn returns false
+func eachRowMap(rows *sql.Rows, fn func(row map[string]interface{}) bool) ([]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := getScanBuffer(len(columns))
+	defer putScanBuffer(buf)
+	for rows.Next() {
+		// Scanning into *interface{} copies bytes, so the buffer can be reused
+		if err := rows.Scan(buf.pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = buf.values[i]
+		}
+		if !fn(row) {
+			break
+		}
+	}
+
+	return columns, rows.Err()
+}
+
+// scanPlanKey identifies a struct type scanned from a set of columns
+type scanPlanKey struct {
+	t       reflect.Type
+	columns string
+}
+
+// scanPlans caches the field index of each column per struct type and column set, so
+// reflection over the struct runs once rather than per query
+var scanPlans sync.Map // scanPlanKey -> []int
+
+// ScanStructs reads remaining rows into dest, a pointer to a slice of structs or struct
+// pointers. Columns map to fields by their `db` tag (or snake_case name, as for
+// repository models); columns without a field are skipped.
+func ScanStructs(rows *sql.Rows, dest interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dest must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	elem := slice.Type().Elem()
+	structType, isPtr := elem, elem.Kind() == reflect.Ptr
+	if isPtr {
+		structType = elem.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("dest must be a pointer to a slice of structs, got %T", dest)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	plan, err := scanPlan(structType, columns)
+	if err != nil {
+		return err
+	}
+
+	var discard interface{}
+	pointers := make([]interface{}, len(columns))
+	for rows.Next() {
+		item := reflect.New(structType).Elem()
+		for i, index := range plan {
+			if index < 0 {
+				pointers[i] = &discard
+				continue
+			}
+			pointers[i] = item.Field(index).Addr().Interface()
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, item.Addr()))
+		} else {
+			slice.Set(reflect.Append(slice, item))
+		}
+	}
+	return rows.Err()
+}
+
+// scanPlan returns the cached field index (-1 for none) of each column for a struct type
+func scanPlan(t reflect.Type, columns []string) ([]int, error) {
+	key := scanPlanKey{t: t, columns: strings.Join(columns, "\x00")}
+	if plan, ok := scanPlans.Load(key); ok {
+		return plan.([]int), nil
+	}
+
+	fields, err := modelFields(reflect.New(t).Interface())
+	if err != nil {
+		return nil, err
+	}
+	byColumn := make(map[string]int, len(fields))
+	for _, field := range fields {
+		f, _ := t.FieldByName(field.Name)
+		byColumn[field.Column] = f.Index[0]
+	}
+	plan := make([]int, len(columns))
+	for i, column := range columns {
+		index, ok := byColumn[column]
+		if !ok {
+			index = -1
+		}
+		plan[i] = index
+	}
+	scanPlans.Store(key, plan)
+	return plan, nil
+}
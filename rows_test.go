@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// scanUser is a tagged struct for scanning tests
+type scanUser struct {
+	ID        int64  `db:"id,pk"`
+	Email     string `db:"email"`
+	Avatar    []byte
+	CreatedAt time.Time
+	Note      string `db:"-"`
+}
+
+// openScanTestDB returns a database with a few users
+func openScanTestDB(t testing.TB) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "scan.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, avatar BLOB, created_at DATETIME, extra TEXT);
+		INSERT INTO users VALUES (1, 'a@x', X'01', '2024-01-02 03:04:05', 'e'), (2, 'b@x', X'0203', '2024-02-03 04:05:06', 'e')`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	return db
+}
+
+// TestScanStructsUsesCachedPlans verifies struct scanning by db tag, skipping of unknown
+// columns, pointer slices and reuse of pooled map buffers
+func TestScanStructsUsesCachedPlans(t *testing.T) {
+	db := openScanTestDB(t)
+
+	rows, err := db.Query("SELECT * FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var users []scanUser
+	if err := ScanStructs(rows, &users); err != nil {
+		t.Fatalf("ScanStructs failed: %v", err)
+	}
+	rows.Close()
+	if len(users) != 2 || users[1].Email != "b@x" || !reflect.DeepEqual(users[1].Avatar, []byte{2, 3}) || users[0].CreatedAt.Year() != 2024 {
+		t.Errorf("Unexpected users: %+v", users)
+	}
+	if _, ok := scanPlans.Load(scanPlanKey{t: reflect.TypeOf(scanUser{}), columns: "id\x00email\x00avatar\x00created_at\x00extra"}); !ok {
+		t.Error("Expected the scan plan to be cached")
+	}
+
+	rows, _ = db.Query("SELECT email, id FROM users ORDER BY id")
+	var pointers []*scanUser
+	if err := ScanStructs(rows, &pointers); err != nil || len(pointers) != 2 || pointers[0].ID != 1 || pointers[0].Email != "a@x" {
+		t.Errorf("Unexpected pointer scan %+v: %v", pointers, err)
+	}
+	rows.Close()
+
+	var notStructs []int
+	rows, _ = db.Query("SELECT id FROM users")
+	if err := ScanStructs(rows, &notStructs); err == nil {
+		t.Error("Expected a slice of non-structs to be rejected")
+	}
+	rows.Close()
+
+	// Pooled buffers must not alias values between rows
+	rows, _ = db.Query("SELECT avatar FROM users ORDER BY id")
+	maps, _, err := scanRowMaps(rows, 0)
+	rows.Close()
+	if err != nil || !reflect.DeepEqual(maps[0]["avatar"], []byte{1}) || !reflect.DeepEqual(maps[1]["avatar"], []byte{2, 3}) {
+		t.Errorf("Unexpected row maps %v: %v", maps, err)
+	}
+}
+
+// BenchmarkScanStructs measures struct scanning with a cached plan
+func BenchmarkScanStructs(b *testing.B) {
+	db := openScanTestDB(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT * FROM users")
+		if err != nil {
+			b.Fatal(err)
+		}
+		var users []scanUser
+		if err := ScanStructs(rows, &users); err != nil {
+			b.Fatal(err)
+		}
+		rows.Close()
+	}
+}
+
+// BenchmarkScanRowMaps measures map scanning with pooled buffers
+func BenchmarkScanRowMaps(b *testing.B) {
+	db := openScanTestDB(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT * FROM users")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := scanRowMaps(rows, 0); err != nil {
+			b.Fatal(err)
+		}
+		rows.Close()
+	}
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Saga statuses recorded in the intent journal
+const (
+	SagaRunning      = "running"      // Steps are being executed
+	SagaCompensating = "compensating" // A step failed; completed steps are being undone
+	SagaCompleted    = "completed"
+	SagaCompensated  = "compensated"
+)
+
+// ErrUnknownSaga is returned for a saga name that was never registered
+var ErrUnknownSaga = errors.New("unknown saga")
+
+// sagaSchema creates the intent journal of multi-step operations
+const sagaSchema = `CREATE TABLE IF NOT EXISTS _saga_journal (
+    id         TEXT PRIMARY KEY,
+    saga       TEXT NOT NULL,
+    state      TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    step       INTEGER NOT NULL,
+    error      TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+)`
+
+// SagaStep is one step of a saga. Do performs it (a database write or an external side
+// effect such as an S3 upload or an email) and Compensate undoes it (nil when nothing
+// needs undoing). After a crash the interrupted step runs again, so both must be idempotent.
+type SagaStep struct {
+	Name       string
+	Do         func(ctx context.Context, state json.RawMessage) error
+	Compensate func(ctx context.Context, state json.RawMessage) error
+}
+
+// Saga is a named multi-step operation whose progress is journaled, so it can be resumed
+// or compensated after a crash
+type Saga struct {
+	Name  string
+	Steps []SagaStep
+}
+
+// SagaRecord is a saga's entry in the intent journal
+type SagaRecord struct {
+	ID        string
+	Saga      string
+	State     json.RawMessage
+	Status    string
+	Step      int    // Steps completed while running, or still to undo while compensating
+	Error     string // Failure that triggered compensation
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// sagaRegistry holds the saga definitions that can be started and resumed
+var sagaRegistry = struct {
+	mu    sync.RWMutex
+	sagas map[string]Saga
+}{sagas: make(map[string]Saga)}
+
+// RegisterSaga registers a saga definition. Register every saga before ResumeSagas runs
+// at startup, so interrupted operations can find their steps.
+func RegisterSaga(saga Saga) {
+	sagaRegistry.mu.Lock()
+	defer sagaRegistry.mu.Unlock()
+	logf("🧭 Registering saga: %s (%d steps)", saga.Name, len(saga.Steps))
+	sagaRegistry.sagas[saga.Name] = saga
+}
+
+// StartSaga journals the intent to run a registered saga with state (JSON-encoded) and
+// runs its steps. When a step fails, the completed steps are compensated in reverse order
+// and the step's error is returned. It returns the saga's journal ID.
+func StartSaga(ctx context.Context, name string, state interface{}) (string, error) {
+	saga, ok := registeredSaga(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownSaga, name)
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode saga state: %w", err)
+	}
+	id, err := newCoordinatedID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	record := &SagaRecord{ID: id, Saga: name, State: encoded, Status: SagaRunning, CreatedAt: now, UpdatedAt: now}
+	err = withSagaJournal(ctx, func(db *sql.DB) error {
+		_, err := ExecContextWithRetry(ctx, db,
+			"INSERT INTO _saga_journal (id, saga, state, status, step, created_at, updated_at) VALUES (?, ?, ?, ?, 0, ?, ?)",
+			id, name, string(encoded), SagaRunning, now.UnixMilli(), now.UnixMilli())
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to journal saga %s: %w", name, err)
+	}
+	return id, runSaga(ctx, saga, record)
+}
+
+// ResumeSagas continues every saga left running or compensating by a crash: running
+// sagas re-run their interrupted step and carry on, compensating sagas finish undoing.
+// Call it at startup after registering sagas. It returns the number of sagas resumed.
+func ResumeSagas(ctx context.Context) (int, error) {
+	var records []*SagaRecord
+	err := withSagaJournal(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx,
+			"SELECT id, saga, state, status, step, COALESCE(error, ''), created_at, updated_at FROM _saga_journal WHERE status IN (?, ?) ORDER BY created_at",
+			SagaRunning, SagaCompensating)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			record, err := scanSagaRecord(rows)
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read saga journal: %w", err)
+	}
+
+	var errs []error
+	for _, record := range records {
+		saga, ok := registeredSaga(record.Saga)
+		if !ok {
+			errs = append(errs, fmt.Errorf("saga %s: %w: %s", record.ID, ErrUnknownSaga, record.Saga))
+			continue
+		}
+		logf("♻️  Resuming saga %s (%s) at step %d: %s", record.ID, record.Saga, record.Step, record.Status)
+		if err := runSaga(ctx, saga, record); err != nil {
+			errs = append(errs, fmt.Errorf("saga %s: %w", record.ID, err))
+		}
+	}
+	return len(records), errors.Join(errs...)
+}
+
+// GetSaga returns a saga's journal entry
+func GetSaga(ctx context.Context, id string) (*SagaRecord, error) {
+	var record *SagaRecord
+	err := withSagaJournal(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx,
+			"SELECT id, saga, state, status, step, COALESCE(error, ''), created_at, updated_at FROM _saga_journal WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return sql.ErrNoRows
+		}
+		record, err = scanSagaRecord(rows)
+		return err
+	})
+	return record, err
+}
+
+// runSaga executes or compensates a journaled saga from its recorded position
+func runSaga(ctx context.Context, saga Saga, record *SagaRecord) error {
+	for record.Status == SagaRunning && record.Step < len(saga.Steps) {
+		step := saga.Steps[record.Step]
+		if stepErr := step.Do(ctx, record.State); stepErr != nil {
+			logErrorf("❌ Saga %s step %s failed, compensating: %v", record.ID, step.Name, stepErr)
+			record.Status, record.Error = SagaCompensating, fmt.Sprintf("%s: %v", step.Name, stepErr)
+			if err := updateSaga(ctx, record); err != nil {
+				return err
+			}
+			if err := compensateSaga(ctx, saga, record); err != nil {
+				return fmt.Errorf("saga %s step %s failed: %w (%v; ResumeSagas retries)", saga.Name, step.Name, stepErr, err)
+			}
+			return fmt.Errorf("saga %s step %s failed and was compensated: %w", saga.Name, step.Name, stepErr)
+		}
+		record.Step++
+		if err := updateSaga(ctx, record); err != nil {
+			return err
+		}
+	}
+
+	if record.Status == SagaCompensating {
+		return compensateSaga(ctx, saga, record)
+	}
+	record.Status = SagaCompleted
+	return updateSaga(ctx, record)
+}
+
+// compensateSaga undoes completed steps in reverse order, journaling each one
+func compensateSaga(ctx context.Context, saga Saga, record *SagaRecord) error {
+	for record.Step > 0 {
+		step := saga.Steps[record.Step-1]
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, record.State); err != nil {
+				logErrorf("❌ Saga %s failed to compensate step %s: %v", record.ID, step.Name, err)
+				return fmt.Errorf("failed to compensate step %s: %w", step.Name, err)
+			}
+		}
+		record.Step--
+		if err := updateSaga(ctx, record); err != nil {
+			return err
+		}
+	}
+	record.Status = SagaCompensated
+	return updateSaga(ctx, record)
+}
+
+// updateSaga journals a saga's position and status
+func updateSaga(ctx context.Context, record *SagaRecord) error {
+	record.UpdatedAt = time.Now()
+	err := withSagaJournal(ctx, func(db *sql.DB) error {
+		_, err := ExecContextWithRetry(ctx, db,
+			"UPDATE _saga_journal SET status = ?, step = ?, error = NULLIF(?, ''), updated_at = ? WHERE id = ?",
+			record.Status, record.Step, record.Error, record.UpdatedAt.UnixMilli(), record.ID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to journal saga %s: %w", record.ID, err)
+	}
+	return nil
+}
+
+// withSagaJournal runs fn with a database handle on which the journal exists
+func withSagaJournal(ctx context.Context, fn func(db *sql.DB) error) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := ExecContextWithRetry(ctx, db, sagaSchema); err != nil {
+		return fmt.Errorf("failed to create saga journal: %w", err)
+	}
+	return fn(db)
+}
+
+// registeredSaga returns a registered saga definition
+func registeredSaga(name string) (Saga, bool) {
+	sagaRegistry.mu.RLock()
+	defer sagaRegistry.mu.RUnlock()
+	saga, ok := sagaRegistry.sagas[name]
+	return saga, ok
+}
+
+// scanSagaRecord reads a journal row
+func scanSagaRecord(rows *sql.Rows) (*SagaRecord, error) {
+	var record SagaRecord
+	var state string
+	var createdAt, updatedAt int64
+	if err := rows.Scan(&record.ID, &record.Saga, &state, &record.Status, &record.Step, &record.Error, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	record.State = json.RawMessage(state)
+	record.CreatedAt, record.UpdatedAt = time.UnixMilli(createdAt), time.UnixMilli(updatedAt)
+	return &record, nil
+}
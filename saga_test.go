@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestSagaCompensatesAndResumes verifies that a failing step undoes the completed steps,
+// and that a saga interrupted by a crash is resumed from its journaled step
+func TestSagaCompensatesAndResumes(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "saga.db"))
+	ctx := context.Background()
+
+	var calls []string
+	failEmail := true
+	step := func(name string) SagaStep {
+		return SagaStep{
+			Name: name,
+			Do: func(ctx context.Context, state json.RawMessage) error {
+				if name == "email" && failEmail {
+					return errors.New("smtp unavailable")
+				}
+				calls = append(calls, name)
+				return nil
+			},
+			Compensate: func(ctx context.Context, state json.RawMessage) error {
+				calls = append(calls, "undo "+name)
+				return nil
+			},
+		}
+	}
+	RegisterSaga(Saga{Name: "signup", Steps: []SagaStep{step("insert"), step("upload"), step("email")}})
+
+	id, err := StartSaga(ctx, "signup", map[string]string{"user": "ada"})
+	if err == nil {
+		t.Fatal("Expected the failing step to fail the saga")
+	}
+	want := []string{"insert", "upload", "undo upload", "undo insert"}
+	if len(calls) != len(want) {
+		t.Fatalf("Expected calls %v, got %v", want, calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("Expected calls %v, got %v", want, calls)
+		}
+	}
+	record, err := GetSaga(ctx, id)
+	if err != nil {
+		t.Fatalf("Failed to read saga: %v", err)
+	}
+	if record.Status != SagaCompensated || record.Error == "" {
+		t.Errorf("Expected a compensated saga with its error, got %+v", record)
+	}
+
+	// Simulate a crash after the first step completed
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := ExecWithRetry(db, "INSERT INTO _saga_journal (id, saga, state, status, step, created_at, updated_at) VALUES ('crashed', 'signup', '{}', ?, 1, 0, 0)", SagaRunning); err != nil {
+		t.Fatalf("Failed to journal crashed saga: %v", err)
+	}
+	calls, failEmail = nil, false
+	resumed, err := ResumeSagas(ctx)
+	if err != nil || resumed != 1 {
+		t.Fatalf("Expected one saga resumed, got %d: %v", resumed, err)
+	}
+	if len(calls) != 2 || calls[0] != "upload" || calls[1] != "email" {
+		t.Errorf("Expected the remaining steps to run, got %v", calls)
+	}
+	if record, _ := GetSaga(ctx, "crashed"); record == nil || record.Status != SagaCompleted {
+		t.Errorf("Expected the resumed saga to complete, got %+v", record)
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Row count estimation methods
+const (
+	EstimateFromStats = "sqlite_stat1" // Row count recorded by the last ANALYZE
+	EstimateFromRowID = "max_rowid"    // MAX(rowid), exact unless rows were deleted
+	EstimateFromCount = "count"        // Full COUNT(*) scan (WITHOUT ROWID tables)
+)
+
+// RowCountEstimate is an approximate row count and how it was obtained
+type RowCountEstimate struct {
+	Table  string
+	Count  int64
+	Method string
+}
+
+// EstimateRowCount returns a fast approximate row count for a table, preferring
+// ANALYZE statistics, then MAX(rowid), and only falling back to COUNT(*) for
+// tables without a rowid
+func EstimateRowCount(ctx context.Context, table string) (*RowCountEstimate, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	estimate := &RowCountEstimate{Table: table}
+	err = retryDatabaseOperationContext(ctx, func() error {
+		if count, ok, err := rowCountFromStats(ctx, db, table); err != nil {
+			return err
+		} else if ok {
+			estimate.Count, estimate.Method = count, EstimateFromStats
+			return nil
+		}
+
+		withoutRowID, err := isWithoutRowID(ctx, db, table)
+		if err != nil {
+			return err
+		}
+		if !withoutRowID {
+			var maxRowID sql.NullInt64
+			err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(rowid) FROM %s", quoteIdentifier(table))).Scan(&maxRowID)
+			if err != nil {
+				return err
+			}
+			estimate.Count, estimate.Method = maxRowID.Int64, EstimateFromRowID
+			return nil
+		}
+
+		estimate.Method = EstimateFromCount
+		return db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdentifier(table))).Scan(&estimate.Count)
+	}, DefaultRetryConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate row count for %s: %w", table, err)
+	}
+
+	return estimate, nil
+}
+
+// isWithoutRowID reports whether a table was declared WITHOUT ROWID, from its schema
+func isWithoutRowID(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var withoutRowID bool
+	err := db.QueryRowContext(ctx, "SELECT wr FROM pragma_table_list WHERE name = ? AND type = 'table'", table).Scan(&withoutRowID)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("table not found: %s", table)
+	}
+	return withoutRowID, err
+}
+
+// rowCountFromStats reads the row count recorded by ANALYZE, if available
+func rowCountFromStats(ctx context.Context, db *sql.DB, table string) (int64, bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1')").Scan(&exists)
+	if err != nil || !exists {
+		return 0, false, err
+	}
+
+	var stat string
+	err = db.QueryRowContext(ctx, "SELECT stat FROM sqlite_stat1 WHERE tbl = ? LIMIT 1", table).Scan(&stat)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	// The first field of stat is the approximate number of rows in the table
+	fields := strings.Fields(stat)
+	if len(fields) == 0 {
+		return 0, false, nil
+	}
+	count, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, false, nil
+	}
+	return count, true, nil
+}
+
+// Sample returns up to n rows chosen uniformly at random from a table using
+// reservoir sampling, holding at most n rows in memory
+func Sample(ctx context.Context, table string, n int) ([]map[string]interface{}, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("sample size must be positive, got %d", n)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var reservoir []map[string]interface{}
+	err = retryDatabaseOperationContext(ctx, func() error {
+		reservoir = make([]map[string]interface{}, 0, n)
+
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", quoteIdentifier(table)))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// Algorithm R: keep the first n rows, then replace with probability n/seen
+		seen := 0
+		_, err = eachRowMap(rows, func(row map[string]interface{}) bool {
+			seen++
+			if len(reservoir) < n {
+				reservoir = append(reservoir, row)
+			} else if slot := rand.Intn(seen); slot < n {
+				reservoir[slot] = row
+			}
+			return true
+		})
+		return err
+	}, DefaultRetryConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample %s: %w", table, err)
+	}
+
+	return reservoir, nil
+}
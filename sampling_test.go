@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestEstimateRowCountMethods verifies the estimate method chosen for rowid and
+// WITHOUT ROWID tables
+func TestEstimateRowCountMethods(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "sampling.db"))
+	ctx := context.Background()
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT)",
+		"INSERT INTO events (name) VALUES ('a'), ('b'), ('c')",
+		"CREATE TABLE tags (name TEXT PRIMARY KEY) WITHOUT ROWID",
+		"INSERT INTO tags (name) VALUES ('x'), ('y')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	estimate, err := EstimateRowCount(ctx, "events")
+	if err != nil {
+		t.Fatalf("EstimateRowCount(events): %v", err)
+	}
+	if estimate.Method != EstimateFromRowID || estimate.Count != 3 {
+		t.Fatalf("events estimate = %+v, want 3 from %s", estimate, EstimateFromRowID)
+	}
+
+	estimate, err = EstimateRowCount(ctx, "tags")
+	if err != nil {
+		t.Fatalf("EstimateRowCount(tags): %v", err)
+	}
+	if estimate.Method != EstimateFromCount || estimate.Count != 2 {
+		t.Fatalf("tags estimate = %+v, want 2 from %s", estimate, EstimateFromCount)
+	}
+
+	if _, err := EstimateRowCount(ctx, "missing"); err == nil {
+		t.Fatal("EstimateRowCount of a missing table should fail")
+	}
+}
+
+// TestSample verifies sample sizes and that sampled rows come from the table
+func TestSample(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "sampling.db"))
+	ctx := context.Background()
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE numbers (n INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 100) INSERT INTO numbers SELECT n FROM seq"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := Sample(ctx, "numbers", 10)
+	if err != nil {
+		t.Fatalf("Sample: %v", err)
+	}
+	if len(rows) != 10 {
+		t.Fatalf("Sample returned %d rows, want 10", len(rows))
+	}
+	seen := make(map[int64]bool)
+	for _, row := range rows {
+		n, ok := row["n"].(int64)
+		if !ok || n < 1 || n > 100 || seen[n] {
+			t.Fatalf("unexpected sampled row %v", row)
+		}
+		seen[n] = true
+	}
+
+	rows, err = Sample(ctx, "numbers", 500)
+	if err != nil {
+		t.Fatalf("Sample: %v", err)
+	}
+	if len(rows) != 100 {
+		t.Fatalf("oversized Sample returned %d rows, want all 100", len(rows))
+	}
+
+	if _, err := Sample(ctx, "numbers", 0); err == nil {
+		t.Fatal("Sample with a non-positive size should fail")
+	}
+}
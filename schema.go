@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// ColumnInfo describes a table column
+type ColumnInfo struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	PrimaryKey bool
+	Default    *string
+	Generated  string // "VIRTUAL" or "STORED" for generated columns, empty otherwise
+}
+
+// ForeignKeyInfo describes a single-column foreign key reference
+type ForeignKeyInfo struct {
+	Column    string // Column in the referencing table
+	RefTable  string // Referenced table
+	RefColumn string // Referenced column (empty when referring to the primary key)
+}
+
+// IndexInfo describes an index on a table
+type IndexInfo struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// TableInfo describes a table and its columns
+type TableInfo struct {
+	Name        string
+	Columns     []ColumnInfo
+	ForeignKeys []ForeignKeyInfo
+	Indexes     []IndexInfo // Explicit and UNIQUE constraint indexes (excludes the primary key)
+}
+
+// Column returns the named column and whether it exists
+func (t TableInfo) Column(name string) (ColumnInfo, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ColumnInfo{}, false
+}
+
+// Schema describes the user tables of the database
+type Schema struct {
+	Tables []TableInfo
+}
+
+// Table returns the named table and whether it exists
+func (s *Schema) Table(name string) (TableInfo, bool) {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return TableInfo{}, false
+}
+
+// IntrospectSchema reads the tables, columns and foreign keys of the database,
+// skipping SQLite internal tables
+func IntrospectSchema(ctx context.Context) (*Schema, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	schema := &Schema{}
+	err = retryDatabaseOperationContext(ctx, func() error {
+		schema.Tables = nil
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT name FROM sqlite_master
+			WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+			ORDER BY name`)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			names = append(names, name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			table := TableInfo{Name: name}
+
+			// table_xinfo also lists generated columns: hidden is 2 for VIRTUAL and 3 for STORED
+			colRows, err := db.QueryContext(ctx, `SELECT name, type, "notnull", pk, dflt_value, hidden FROM pragma_table_xinfo(?) WHERE hidden != 1`, name)
+			if err != nil {
+				return err
+			}
+			for colRows.Next() {
+				var c ColumnInfo
+				var pk, hidden int
+				if err := colRows.Scan(&c.Name, &c.Type, &c.NotNull, &pk, &c.Default, &hidden); err != nil {
+					colRows.Close()
+					return err
+				}
+				c.PrimaryKey = pk > 0
+				switch hidden {
+				case 2:
+					c.Generated = "VIRTUAL"
+				case 3:
+					c.Generated = "STORED"
+				}
+				table.Columns = append(table.Columns, c)
+			}
+			colRows.Close()
+
+			fkRows, err := db.QueryContext(ctx, `SELECT "from", "table", "to" FROM pragma_foreign_key_list(?)`, name)
+			if err != nil {
+				return err
+			}
+			for fkRows.Next() {
+				var fk ForeignKeyInfo
+				var to *string
+				if err := fkRows.Scan(&fk.Column, &fk.RefTable, &to); err != nil {
+					fkRows.Close()
+					return err
+				}
+				if to != nil {
+					fk.RefColumn = *to
+				}
+				table.ForeignKeys = append(table.ForeignKeys, fk)
+			}
+			fkRows.Close()
+
+			idxRows, err := db.QueryContext(ctx, `SELECT name, "unique" FROM pragma_index_list(?) WHERE origin != 'pk' ORDER BY name`, name)
+			if err != nil {
+				return err
+			}
+			for idxRows.Next() {
+				var idx IndexInfo
+				if err := idxRows.Scan(&idx.Name, &idx.Unique); err != nil {
+					idxRows.Close()
+					return err
+				}
+				table.Indexes = append(table.Indexes, idx)
+			}
+			idxRows.Close()
+
+			for i, idx := range table.Indexes {
+				infoRows, err := db.QueryContext(ctx, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, idx.Name)
+				if err != nil {
+					return err
+				}
+				for infoRows.Next() {
+					var column *string
+					if err := infoRows.Scan(&column); err != nil {
+						infoRows.Close()
+						return err
+					}
+					if column != nil {
+						table.Indexes[i].Columns = append(table.Indexes[i].Columns, *column)
+					}
+				}
+				infoRows.Close()
+			}
+
+			schema.Tables = append(schema.Tables, table)
+		}
+		return nil
+	}, DefaultRetryConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect schema: %w", err)
+	}
+
+	return schema, nil
+}
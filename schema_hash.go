@@ -0,0 +1,222 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SchemaHashOptions configures SchemaHashWithOptions
+type SchemaHashOptions struct {
+	DataHashes bool     // Also hash every table's rows (reads every table in full)
+	Exclude    []string // Objects to leave out, e.g. tables that differ by design
+}
+
+// SchemaFingerprint is a stable hash of the database schema
+type SchemaFingerprint struct {
+	Hash       string            `json:"hash"`                  // Hash of the normalized schema
+	Objects    int               `json:"objects"`               // Tables, indexes, views and triggers hashed
+	DataHashes map[string]string `json:"data_hashes,omitempty"` // Table -> hash of its rows (with DataHashes)
+}
+
+// SchemaHash returns a stable hash of the normalized schema: every table, index, view and
+// trigger definition with comments, formatting, keyword case and IF NOT EXISTS removed.
+// Databases built by the same migrations hash identically, so it can compare environments,
+// validate replicas and gate deploys on an expected schema. Tables starting with an
+// underscore (this package's bookkeeping, created on first use) are left out.
+func SchemaHash(ctx context.Context) (string, error) {
+	fingerprint, err := SchemaHashWithOptions(ctx, SchemaHashOptions{})
+	if err != nil {
+		return "", err
+	}
+	return fingerprint.Hash, nil
+}
+
+// SchemaHashWithOptions is SchemaHash with exclusions and optional per-table data hashes
+func SchemaHashWithOptions(ctx context.Context, opts SchemaHashOptions) (*SchemaFingerprint, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return schemaFingerprint(ctx, db, opts)
+}
+
+// schemaFingerprint hashes the schema (and optionally data) of db
+func schemaFingerprint(ctx context.Context, db *sql.DB, opts SchemaHashOptions) (*SchemaFingerprint, error) {
+	excluded := make(map[string]bool, len(opts.Exclude))
+	for _, name := range opts.Exclude {
+		excluded[name] = true
+	}
+
+	type object struct{ kind, name, table, sql string }
+	var objects []object
+	err := retryDatabaseOperationContext(ctx, func() error {
+		objects = nil
+		rows, err := db.QueryContext(ctx, `
+			SELECT type, name, tbl_name, sql FROM sqlite_master
+			WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var o object
+			if err := rows.Scan(&o.kind, &o.name, &o.table, &o.sql); err != nil {
+				return err
+			}
+			if excluded[o.name] || excluded[o.table] || strings.HasPrefix(o.table, "_") {
+				continue
+			}
+			objects = append(objects, o)
+		}
+		return rows.Err()
+	}, DefaultRetryConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].kind != objects[j].kind {
+			return objects[i].kind < objects[j].kind
+		}
+		return objects[i].name < objects[j].name
+	})
+	h := sha256.New()
+	for _, o := range objects {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", o.kind, o.name, normalizeSchemaSQL(o.sql))
+	}
+	fingerprint := &SchemaFingerprint{Hash: hex.EncodeToString(h.Sum(nil)), Objects: len(objects)}
+
+	if opts.DataHashes {
+		fingerprint.DataHashes = make(map[string]string)
+		for _, o := range objects {
+			if o.kind != "table" || strings.HasPrefix(normalizeSchemaSQL(o.sql), "CREATE VIRTUAL TABLE") {
+				continue
+			}
+			hash, err := tableDataHash(ctx, db, o.name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash table %s: %w", o.name, err)
+			}
+			fingerprint.DataHashes[o.name] = hash
+		}
+	}
+	return fingerprint, nil
+}
+
+// tableDataHash hashes a table's rows in a content-defined order (sorted by every
+// column), so equal data hashes equally regardless of insertion order or rowids
+func tableDataHash(ctx context.Context, db *sql.DB, table string) (string, error) {
+	var hash string
+	err := retryDatabaseOperationContext(ctx, func() error {
+		var columns int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?)", table).Scan(&columns); err != nil {
+			return err
+		}
+		order := make([]string, columns)
+		for i := range order {
+			order[i] = fmt.Sprint(i + 1)
+		}
+
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s ORDER BY %s", quoteIdentifier(table), strings.Join(order, ", ")))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		names, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+
+		h := sha256.New()
+		values := make([]interface{}, len(names))
+		pointers := make([]interface{}, len(names))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		for rows.Next() {
+			if err := rows.Scan(pointers...); err != nil {
+				return err
+			}
+			row := make(map[string]interface{}, len(names))
+			for i, name := range names {
+				row[name] = values[i]
+			}
+			fmt.Fprintf(h, "%s\n", RowHash(row))
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		hash = hex.EncodeToString(h.Sum(nil))
+		return nil
+	}, DefaultRetryConfig())
+	return hash, err
+}
+
+// normalizeSchemaSQL canonicalizes a schema statement: comments are dropped, whitespace is
+// kept only between adjacent words, keywords and unquoted identifiers are upper-cased and
+// IF NOT EXISTS is removed. Quoted text is kept verbatim.
+func normalizeSchemaSQL(statement string) string {
+	runes := []rune(statement)
+	var tokens []string
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/'); i++ {
+			}
+			i++
+		case r == '\'' || r == '"' || r == '`' || r == '[':
+			end := r
+			if r == '[' {
+				end = ']'
+			}
+			start := i
+			for i++; i < len(runes); i++ {
+				if runes[i] == end {
+					// Doubled quotes escape themselves
+					if end != ']' && i+1 < len(runes) && runes[i+1] == end {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			tokens = append(tokens, string(runes[start:min(i+1, len(runes))]))
+		case isIdentifierRune(r):
+			start := i
+			for i+1 < len(runes) && isIdentifierRune(runes[i+1]) {
+				i++
+			}
+			tokens = append(tokens, strings.ToUpper(string(runes[start:i+1])))
+		default:
+			tokens = append(tokens, string(r))
+		}
+	}
+
+	var b strings.Builder
+	previous := ""
+	for i := 0; i < len(tokens); i++ {
+		if i+2 < len(tokens) && tokens[i] == "IF" && tokens[i+1] == "NOT" && tokens[i+2] == "EXISTS" {
+			i += 2
+			continue
+		}
+		// Separate adjacent words; quoted text and punctuation delimit themselves
+		if previous != "" && isIdentifierRune([]rune(previous)[len([]rune(previous))-1]) && isIdentifierRune([]rune(tokens[i])[0]) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tokens[i])
+		previous = tokens[i]
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestSchemaHashIgnoresFormatting verifies that equivalent schemas hash identically while
+// schema and data differences change the hashes
+func TestSchemaHashIgnoresFormatting(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	hashes := make([]*SchemaFingerprint, 3)
+	schemas := []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT DEFAULT 'x')",
+		"-- users\ncreate table if not exists users (\n    id   integer primary key,\n    name text default 'x'\n)",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT DEFAULT 'y')",
+	}
+	for i, schema := range schemas {
+		t.Setenv("DATABASE_FILE", filepath.Join(dir, "schema"+string(rune('a'+i))+".db"))
+		db, err := GetDB()
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		for _, statement := range []string{schema, "CREATE INDEX users_name ON users (name)", "CREATE TABLE _config (key TEXT)", "INSERT INTO users (name) VALUES ('ada')"} {
+			if _, err := db.Exec(statement); err != nil {
+				t.Fatalf("Failed to run %q: %v", statement, err)
+			}
+		}
+		if i == 1 {
+			// Bookkeeping tables and excluded objects do not count
+			if _, err := db.Exec("DROP TABLE _config"); err != nil {
+				t.Fatalf("Failed to drop table: %v", err)
+			}
+		}
+		db.Close()
+		if hashes[i], err = SchemaHashWithOptions(ctx, SchemaHashOptions{DataHashes: true}); err != nil {
+			t.Fatalf("Failed to hash schema: %v", err)
+		}
+	}
+
+	if hashes[0].Hash != hashes[1].Hash || hashes[0].Objects != 2 {
+		t.Errorf("Expected equivalent schemas to hash identically, got %+v and %+v", hashes[0], hashes[1])
+	}
+	if hashes[0].Hash == hashes[2].Hash {
+		t.Error("Expected a different default to change the hash")
+	}
+	if hashes[0].DataHashes["users"] == "" || hashes[0].DataHashes["users"] != hashes[1].DataHashes["users"] {
+		t.Errorf("Expected equal data to hash equally, got %v and %v", hashes[0].DataHashes, hashes[1].DataHashes)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("UPDATE users SET name = 'grace'"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	changed, err := SchemaHashWithOptions(ctx, SchemaHashOptions{DataHashes: true, Exclude: []string{"users_name"}})
+	if err != nil {
+		t.Fatalf("Failed to hash schema: %v", err)
+	}
+	if changed.Objects != 1 || changed.DataHashes["users"] == hashes[2].DataHashes["users"] {
+		t.Errorf("Expected the index to be excluded and the data hash to change, got %+v", changed)
+	}
+	if hash, err := SchemaHash(ctx); err != nil || hash != hashes[2].Hash {
+		t.Errorf("Expected SchemaHash to match, got %s (%v)", hash, err)
+	}
+}
@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultSessionCookieName is the cookie carrying the session token
+	DefaultSessionCookieName = "session_id"
+	// DefaultSessionTTL is how long a session lives without activity (sliding) or in total
+	DefaultSessionTTL = 24 * time.Hour
+)
+
+// sessionSchema creates the sessions table; ids are SHA-256 hashes of the cookie tokens,
+// so a leaked database file does not leak live sessions
+const sessionSchema = `CREATE TABLE IF NOT EXISTS _sessions (
+    id         TEXT PRIMARY KEY,
+    data       TEXT NOT NULL,
+    expires_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+)`
+
+// sessionExpiryIndex keeps cleanup of expired sessions from scanning the table
+const sessionExpiryIndex = `CREATE INDEX IF NOT EXISTS _sessions_expires_at ON _sessions (expires_at)`
+
+// SessionStoreOptions configures a SessionStore and its cookie
+type SessionStoreOptions struct {
+	CookieName string        // Default: DefaultSessionCookieName
+	TTL        time.Duration // Default: DefaultSessionTTL
+	Sliding    bool          // Extend expiry on activity instead of expiring TTL after Save
+	Path       string        // Cookie path (default: "/")
+	Domain     string
+	Secure     bool
+	SameSite   http.SameSite // Default: http.SameSiteLaxMode
+}
+
+// Session is a request's session. Values are stored as JSON, so numbers read back as
+// float64 and structs as maps.
+type Session struct {
+	ID        string // Cookie token; empty until the session is first saved
+	Values    map[string]interface{}
+	ExpiresAt time.Time
+	IsNew     bool
+
+	updatedAt time.Time
+}
+
+// SessionStore keeps HTTP sessions in the _sessions table, keyed by a random cookie token
+type SessionStore struct {
+	opts SessionStoreOptions
+}
+
+// NewSessionStore returns a session store, filling in option defaults
+func NewSessionStore(opts SessionStoreOptions) *SessionStore {
+	if opts.CookieName == "" {
+		opts.CookieName = DefaultSessionCookieName
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultSessionTTL
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	return &SessionStore{opts: opts}
+}
+
+// Get returns the request's session, or a new empty one when the request has no cookie
+// or its session is unknown or expired. With sliding expiration, activity pushes the
+// expiry out by TTL; the write is skipped unless a tenth of the TTL has passed since the
+// last one, so busy sessions do not write on every request.
+func (s *SessionStore) Get(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(s.opts.CookieName)
+	if err != nil || cookie.Value == "" {
+		return s.newSession(), nil
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var data string
+	var expiresAt, updatedAt int64
+	err = QueryRowWithRetry(db, "SELECT data, expires_at, updated_at FROM _sessions WHERE id = ?",
+		hashSessionToken(cookie.Value)).Scan(&data, &expiresAt, &updatedAt)
+	// The table is created by the first Save
+	if errors.Is(err, sql.ErrNoRows) || isMissingTable(err) {
+		return s.newSession(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	now := time.Now()
+	if now.UnixMilli() >= expiresAt {
+		return s.newSession(), nil
+	}
+
+	session := &Session{ID: cookie.Value, ExpiresAt: time.UnixMilli(expiresAt), updatedAt: time.UnixMilli(updatedAt)}
+	if err := json.Unmarshal([]byte(data), &session.Values); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	if session.Values == nil {
+		session.Values = make(map[string]interface{})
+	}
+
+	if s.opts.Sliding && now.Sub(session.updatedAt) >= s.opts.TTL/10 {
+		session.ExpiresAt, session.updatedAt = now.Add(s.opts.TTL), now
+		if _, err := ExecContextWithRetry(r.Context(), db,
+			"UPDATE _sessions SET expires_at = ?, updated_at = ? WHERE id = ?",
+			session.ExpiresAt.UnixMilli(), now.UnixMilli(), hashSessionToken(session.ID)); err != nil {
+			return nil, fmt.Errorf("failed to extend session: %w", err)
+		}
+	}
+	return session, nil
+}
+
+// Save stores the session, expiring TTL from now, and sets its cookie. A new session gets
+// its token here. Sliding sessions use a browser-session cookie, since the server extends
+// their expiry without re-sending the cookie; other sessions' cookies expire with them.
+func (s *SessionStore) Save(w http.ResponseWriter, r *http.Request, session *Session) error {
+	if session.ID == "" {
+		token, err := newSessionToken()
+		if err != nil {
+			return fmt.Errorf("failed to generate session token: %w", err)
+		}
+		session.ID = token
+	}
+	if session.Values == nil {
+		session.Values = make(map[string]interface{})
+	}
+	data, err := json.Marshal(session.Values)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	ctx := r.Context()
+	now := time.Now()
+	session.ExpiresAt, session.updatedAt = now.Add(s.opts.TTL), now
+	err = WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		for _, ddl := range []string{sessionSchema, sessionExpiryIndex} {
+			if _, err := tx.ExecContext(ctx, ddl); err != nil {
+				return fmt.Errorf("failed to create sessions table: %w", err)
+			}
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO _sessions (id, data, expires_at, updated_at) VALUES (?, ?, ?, ?)",
+			hashSessionToken(session.ID), string(data), session.ExpiresAt.UnixMilli(), now.UnixMilli())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	session.IsNew = false
+
+	cookie := s.cookie(session.ID)
+	if !s.opts.Sliding {
+		cookie.MaxAge = int(s.opts.TTL / time.Second)
+	}
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+// Destroy deletes the session and expires its cookie, e.g. on logout
+func (s *SessionStore) Destroy(w http.ResponseWriter, r *http.Request, session *Session) error {
+	if session.ID != "" {
+		db, err := GetDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		_, err = ExecContextWithRetry(r.Context(), db, "DELETE FROM _sessions WHERE id = ?", hashSessionToken(session.ID))
+		if err != nil && !isMissingTable(err) {
+			return fmt.Errorf("failed to destroy session: %w", err)
+		}
+	}
+
+	cookie := s.cookie("")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+	*session = *s.newSession()
+	return nil
+}
+
+// Cleanup deletes expired sessions and returns how many were removed
+func (s *SessionStore) Cleanup(ctx context.Context) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	result, err := ExecContextWithRetry(ctx, db, "DELETE FROM _sessions WHERE expires_at <= ?", time.Now().UnixMilli())
+	if err != nil {
+		if isMissingTable(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to clean up sessions: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	if removed > 0 {
+		logf("🧹 Removed %d expired sessions", removed)
+	}
+	return removed, nil
+}
+
+// StartCleanup runs Cleanup every interval until ctx is cancelled
+func (s *SessionStore) StartCleanup(ctx context.Context, interval time.Duration) {
+	tracker := startSubsystem("sessions", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer tracker.stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				tracker.run(func() error {
+					_, err := s.Cleanup(ctx)
+					if err != nil && ctx.Err() == nil {
+						logErrorf("❌ Scheduled session cleanup failed: %v", err)
+					}
+					return err
+				})
+			}
+		}
+	}()
+}
+
+// newSession returns an empty, unsaved session
+func (s *SessionStore) newSession() *Session {
+	return &Session{Values: make(map[string]interface{}), IsNew: true}
+}
+
+// cookie returns the session cookie carrying token
+func (s *SessionStore) cookie(token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.opts.CookieName,
+		Value:    token,
+		Path:     s.opts.Path,
+		Domain:   s.opts.Domain,
+		Secure:   s.opts.Secure,
+		HttpOnly: true,
+		SameSite: s.opts.SameSite,
+	}
+}
+
+// newSessionToken returns a random 256-bit cookie token
+func newSessionToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashSessionToken returns the stored id of a cookie token
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSessionStoreLifecycle verifies saving, loading, sliding expiry, destroy and cleanup
+func TestSessionStoreLifecycle(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "sessions.db"))
+	store := NewSessionStore(SessionStoreOptions{TTL: time.Hour, Sliding: true})
+
+	session, err := store.Get(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil || !session.IsNew {
+		t.Fatalf("Expected a new session before any table exists, got %+v: %v", session, err)
+	}
+	session.Values["user"] = "ada"
+	recorder := httptest.NewRecorder()
+	if err := store.Save(recorder, httptest.NewRequest(http.MethodGet, "/", nil), session); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != session.ID || !cookies[0].HttpOnly || cookies[0].MaxAge != 0 {
+		t.Fatalf("Expected an HttpOnly browser-session cookie, got %+v", cookies)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.AddCookie(cookies[0])
+	loaded, err := store.Get(request)
+	if err != nil || loaded.IsNew || loaded.Values["user"] != "ada" {
+		t.Fatalf("Expected the saved session, got %+v: %v", loaded, err)
+	}
+
+	// Age the session so the next request slides its expiry
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	stale := time.Now().Add(-30 * time.Minute)
+	if _, err := db.Exec("UPDATE _sessions SET expires_at = ?, updated_at = ?", stale.Add(time.Hour).UnixMilli(), stale.UnixMilli()); err != nil {
+		t.Fatalf("Failed to age session: %v", err)
+	}
+	if loaded, err = store.Get(request); err != nil || time.Until(loaded.ExpiresAt) < 59*time.Minute {
+		t.Errorf("Expected activity to extend expiry to an hour, got %v (%v)", loaded.ExpiresAt, err)
+	}
+
+	if _, err := db.Exec("UPDATE _sessions SET expires_at = ?", time.Now().Add(-time.Second).UnixMilli()); err != nil {
+		t.Fatalf("Failed to expire session: %v", err)
+	}
+	if expired, err := store.Get(request); err != nil || !expired.IsNew {
+		t.Errorf("Expected an expired session to read as new, got %+v: %v", expired, err)
+	}
+	if removed, err := store.Cleanup(context.Background()); err != nil || removed != 1 {
+		t.Errorf("Expected cleanup to remove 1 session, got %d: %v", removed, err)
+	}
+
+	session.ID = ""
+	if err := store.Save(httptest.NewRecorder(), request, session); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	recorder = httptest.NewRecorder()
+	if err := store.Destroy(recorder, request, session); err != nil || !session.IsNew {
+		t.Fatalf("Failed to destroy session: %v", err)
+	}
+	if cookies := recorder.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("Expected an expired cookie, got %+v", cookies)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM _sessions").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected no stored sessions, got %d: %v", count, err)
+	}
+}
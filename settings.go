@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultSettingPollInterval is how often WatchSetting checks a setting for changes
+const DefaultSettingPollInterval = time.Second
+
+// settingPollInterval is WatchSetting's poll interval (replaced in tests)
+var settingPollInterval = DefaultSettingPollInterval
+
+var (
+	// ErrSettingNotFound is returned for a setting that is neither stored nor defaulted
+	ErrSettingNotFound = errors.New("setting not found")
+	// ErrSettingVersionConflict is returned by CompareAndSetSetting when the setting changed
+	ErrSettingVersionConflict = errors.New("setting was changed concurrently")
+)
+
+// settingsSchema creates the typed settings table
+const settingsSchema = `CREATE TABLE IF NOT EXISTS _settings (
+    key        TEXT PRIMARY KEY,
+    value      TEXT NOT NULL,
+    version    INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+)`
+
+// SettingEntry is a setting's JSON value and version (0 when only the default applies)
+type SettingEntry struct {
+	Key       string
+	Value     json.RawMessage
+	Version   int64
+	UpdatedAt time.Time
+}
+
+// Decode unmarshals the entry's value into v
+func (e SettingEntry) Decode(v interface{}) error {
+	return json.Unmarshal(e.Value, v)
+}
+
+// settingDefaults holds registered default values, which also fix each setting's type
+var settingDefaults = struct {
+	mu     sync.RWMutex
+	values map[string]json.RawMessage
+	types  map[string]reflect.Type
+}{values: make(map[string]json.RawMessage), types: make(map[string]reflect.Type)}
+
+// RegisterSettingDefault sets the value a setting has until one is stored. The default's
+// type becomes the setting's type: SetSetting rejects values that do not decode into it.
+func RegisterSettingDefault(key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode default for %s: %w", key, err)
+	}
+	settingDefaults.mu.Lock()
+	defer settingDefaults.mu.Unlock()
+	settingDefaults.values[key] = encoded
+	settingDefaults.types[key] = reflect.TypeOf(value)
+	return nil
+}
+
+// GetSetting decodes a setting into dest, falling back to its registered default
+func GetSetting(ctx context.Context, key string, dest interface{}) error {
+	entry, err := GetSettingEntry(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := entry.Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetSettingEntry returns a setting's stored entry, or its default with version 0
+func GetSettingEntry(ctx context.Context, key string) (SettingEntry, error) {
+	db, err := GetDB()
+	if err != nil {
+		return SettingEntry{}, err
+	}
+	defer db.Close()
+	return readSettingEntry(ctx, db, key)
+}
+
+// SetSetting stores a setting's value and returns its new version
+func SetSetting(ctx context.Context, key string, value interface{}) (int64, error) {
+	return writeSetting(ctx, key, value, -1)
+}
+
+// CompareAndSetSetting stores a setting's value only if its version is still version (0
+// for a setting never stored), returning ErrSettingVersionConflict otherwise
+func CompareAndSetSetting(ctx context.Context, key string, value interface{}, version int64) (int64, error) {
+	return writeSetting(ctx, key, value, version)
+}
+
+// WatchSetting sends a setting's current entry, then every new version until ctx is
+// cancelled. Changes are detected by polling every DefaultSettingPollInterval, so writes
+// by other processes are seen too. A slow receiver only sees the latest version.
+func WatchSetting(ctx context.Context, key string) <-chan SettingEntry {
+	changes := make(chan SettingEntry, 1)
+	go func() {
+		defer close(changes)
+		db, err := GetDB()
+		if err != nil {
+			logErrorf("❌ Failed to watch setting %s: %v", key, err)
+			return
+		}
+		defer db.Close()
+
+		ticker := time.NewTicker(settingPollInterval)
+		defer ticker.Stop()
+		last := int64(-1)
+		for {
+			entry, err := readSettingEntry(ctx, db, key)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				logWarnf("⚠️  Failed to read setting %s: %v", key, err)
+			case err == nil && entry.Version != last:
+				last = entry.Version
+				// Replace an unread older version rather than blocking the poller
+				select {
+				case <-changes:
+				default:
+				}
+				changes <- entry
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return changes
+}
+
+// readSettingEntry reads a setting, falling back to its default
+func readSettingEntry(ctx context.Context, db *sql.DB, key string) (SettingEntry, error) {
+	entry := SettingEntry{Key: key}
+	var value string
+	var updatedAt int64
+	err := QueryRowContextWithRetry(ctx, db, "SELECT value, version, updated_at FROM _settings WHERE key = ?", key).Scan(&value, &entry.Version, &updatedAt)
+	if err == nil {
+		entry.Value, entry.UpdatedAt = json.RawMessage(value), time.UnixMilli(updatedAt)
+		return entry, nil
+	}
+	// The table is created by the first SetSetting
+	if !errors.Is(err, sql.ErrNoRows) && !isMissingTable(err) {
+		return SettingEntry{}, err
+	}
+
+	settingDefaults.mu.RLock()
+	defer settingDefaults.mu.RUnlock()
+	def, ok := settingDefaults.values[key]
+	if !ok {
+		return SettingEntry{}, fmt.Errorf("%w: %s", ErrSettingNotFound, key)
+	}
+	entry.Value = def
+	return entry, nil
+}
+
+// writeSetting stores a setting, checking its type and, unless expected is -1, its version
+func writeSetting(ctx context.Context, key string, value interface{}, expected int64) (int64, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode setting %s: %w", key, err)
+	}
+	settingDefaults.mu.RLock()
+	typ := settingDefaults.types[key]
+	settingDefaults.mu.RUnlock()
+	if typ != nil {
+		if err := json.Unmarshal(encoded, reflect.New(typ).Interface()); err != nil {
+			return 0, fmt.Errorf("setting %s must be a %v: %w", key, typ, err)
+		}
+	}
+
+	var version int64
+	err = WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, settingsSchema); err != nil {
+			return fmt.Errorf("failed to create settings table: %w", err)
+		}
+		var current int64
+		err := tx.QueryRowContext(ctx, "SELECT version FROM _settings WHERE key = ?", key).Scan(&current)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if expected >= 0 && current != expected {
+			return fmt.Errorf("%w: %s is at version %d, not %d", ErrSettingVersionConflict, key, current, expected)
+		}
+		version = current + 1
+		_, err = tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO _settings (key, value, version, updated_at) VALUES (?, ?, ?, ?)",
+			key, string(encoded), version, time.Now().UnixMilli())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	logf("⚙️  Setting %s set to version %d", key, version)
+	return version, nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSettingDefaultsVersionsAndWatch verifies defaulting, type checks, optimistic
+// versioning and change notifications of typed settings
+func TestSettingDefaultsVersionsAndWatch(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "settings.db"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	settingPollInterval = 10 * time.Millisecond
+	defer func() { settingPollInterval = DefaultSettingPollInterval }()
+
+	type limits struct {
+		MaxUploads int `json:"max_uploads"`
+	}
+	if err := RegisterSettingDefault("limits", limits{MaxUploads: 5}); err != nil {
+		t.Fatalf("Failed to register default: %v", err)
+	}
+
+	var got limits
+	if err := GetSetting(ctx, "limits", &got); err != nil || got.MaxUploads != 5 {
+		t.Fatalf("Expected the default, got %+v: %v", got, err)
+	}
+	if err := GetSetting(ctx, "missing", &got); !errors.Is(err, ErrSettingNotFound) {
+		t.Errorf("Expected ErrSettingNotFound, got %v", err)
+	}
+
+	changes := WatchSetting(ctx, "limits")
+	if entry := <-changes; entry.Version != 0 {
+		t.Errorf("Expected the default first, got version %d", entry.Version)
+	}
+
+	if _, err := SetSetting(ctx, "limits", "unlimited"); err == nil {
+		t.Error("Expected a value of the wrong type to be rejected")
+	}
+	version, err := CompareAndSetSetting(ctx, "limits", limits{MaxUploads: 10}, 0)
+	if err != nil || version != 1 {
+		t.Fatalf("Expected version 1, got %d: %v", version, err)
+	}
+	if _, err := CompareAndSetSetting(ctx, "limits", limits{MaxUploads: 20}, 0); !errors.Is(err, ErrSettingVersionConflict) {
+		t.Errorf("Expected ErrSettingVersionConflict, got %v", err)
+	}
+
+	select {
+	case entry := <-changes:
+		if err := entry.Decode(&got); err != nil || entry.Version != 1 || got.MaxUploads != 10 {
+			t.Errorf("Expected version 1 with 10 uploads, got %+v (%v)", entry, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a change notification")
+	}
+}
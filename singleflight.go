@@ -0,0 +1,43 @@
+package database
+
+import "sync"
+
+// flightGroup collapses concurrent calls with the same key into one execution whose
+// result is shared by every caller
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an execution in progress or completed
+type flightCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// do runs fn once for all concurrent callers of key and reports whether the result
+// was shared with another caller
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.value, call.err, true
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn()
+	return call.value, call.err, false
+}
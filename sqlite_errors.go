@@ -0,0 +1,163 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// SQLite result codes (https://www.sqlite.org/rescode.html)
+const (
+	sqliteError              = 1
+	sqliteBusy               = 5
+	sqliteLocked             = 6
+	sqliteFull               = 13
+	sqliteConstraint         = 19
+	sqliteBusyRecovery       = sqliteBusy | 1<<8
+	sqliteBusySnapshot       = sqliteBusy | 2<<8
+	sqliteLockedSharedCache  = sqliteLocked | 1<<8
+	sqliteConstraintCheck    = sqliteConstraint | 1<<8
+	sqliteConstraintFK       = sqliteConstraint | 3<<8
+	sqliteConstraintNotNull  = sqliteConstraint | 5<<8
+	sqliteConstraintPK       = sqliteConstraint | 6<<8
+	sqliteConstraintUnique   = sqliteConstraint | 8<<8
+	sqliteConstraintRowID    = sqliteConstraint | 10<<8
+	sqliteResultCodeBaseMask = 0xff
+)
+
+// Typed SQLite errors, matched with errors.Is against errors returned by this package's
+// helpers, DBTX and the pipeline driver (or any driver error passed through ClassifyError).
+// Specific errors also match their general class, e.g. ErrConstraintUnique is an ErrConstraint.
+var (
+	ErrBusy                 = errors.New("sqlite: database is busy")
+	ErrBusySnapshot         = errors.New("sqlite: busy snapshot (WAL read transaction is stale)")
+	ErrBusyRecovery         = errors.New("sqlite: busy recovering WAL")
+	ErrLocked               = errors.New("sqlite: table is locked")
+	ErrLockedSharedCache    = errors.New("sqlite: table is locked in shared cache")
+	ErrFull                 = errors.New("sqlite: database or disk is full")
+	ErrConstraint           = errors.New("sqlite: constraint failed")
+	ErrConstraintUnique     = errors.New("sqlite: UNIQUE constraint failed")
+	ErrConstraintPrimaryKey = errors.New("sqlite: PRIMARY KEY constraint failed")
+	ErrConstraintForeignKey = errors.New("sqlite: FOREIGN KEY constraint failed")
+	ErrConstraintNotNull    = errors.New("sqlite: NOT NULL constraint failed")
+	ErrConstraintCheck      = errors.New("sqlite: CHECK constraint failed")
+)
+
+// sqliteCodeErrors maps extended result codes to their typed error
+var sqliteCodeErrors = map[int]error{
+	sqliteBusy:              ErrBusy,
+	sqliteBusyRecovery:      ErrBusyRecovery,
+	sqliteBusySnapshot:      ErrBusySnapshot,
+	sqliteLocked:            ErrLocked,
+	sqliteLockedSharedCache: ErrLockedSharedCache,
+	sqliteFull:              ErrFull,
+	sqliteConstraint:        ErrConstraint,
+	sqliteConstraintUnique:  ErrConstraintUnique,
+	sqliteConstraintPK:      ErrConstraintPrimaryKey,
+	sqliteConstraintRowID:   ErrConstraintPrimaryKey,
+	sqliteConstraintFK:      ErrConstraintForeignKey,
+	sqliteConstraintNotNull: ErrConstraintNotNull,
+	sqliteConstraintCheck:   ErrConstraintCheck,
+}
+
+// SQLiteError is a driver error annotated with its SQLite extended result code
+type SQLiteError struct {
+	Code int // Extended result code; Code & 0xff is the primary code
+	Err  error
+}
+
+func (e *SQLiteError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SQLiteError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the typed error for the extended code and for its primary code
+func (e *SQLiteError) Is(target error) bool {
+	if specific, ok := sqliteCodeErrors[e.Code]; ok && specific == target {
+		return true
+	}
+	if general, ok := sqliteCodeErrors[e.Code&sqliteResultCodeBaseMask]; ok && general == target {
+		return true
+	}
+	return false
+}
+
+// ClassifyError wraps a driver error in *SQLiteError when its SQLite result code can be
+// determined, so it can be matched with errors.Is against the typed errors. It reads the
+// code from modernc.org/sqlite (Code() method) and mattn/go-sqlite3 (ExtendedCode field)
+// errors, falling back to the message text for drivers that expose neither.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *SQLiteError
+	if errors.As(err, &classified) {
+		return err
+	}
+	if code, ok := sqliteErrorCode(err); ok {
+		return &SQLiteError{Code: code, Err: err}
+	}
+	return err
+}
+
+// sqliteErrorCode extracts the extended result code from a driver error
+func sqliteErrorCode(err error) (int, bool) {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if coder, ok := e.(interface{ Code() int }); ok {
+			return coder.Code(), true
+		}
+		v := reflect.ValueOf(e)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Struct {
+			if field := v.FieldByName("ExtendedCode"); field.IsValid() && field.CanInt() {
+				return int(field.Int()), true
+			}
+		}
+	}
+	return sqliteCodeFromMessage(err.Error())
+}
+
+// sqliteCodeFromMessage infers a result code from SQLite's standard error messages
+func sqliteCodeFromMessage(message string) (int, bool) {
+	switch {
+	case strings.Contains(message, "UNIQUE constraint failed"):
+		return sqliteConstraintUnique, true
+	case strings.Contains(message, "PRIMARY KEY constraint failed"):
+		return sqliteConstraintPK, true
+	case strings.Contains(message, "FOREIGN KEY constraint failed"):
+		return sqliteConstraintFK, true
+	case strings.Contains(message, "NOT NULL constraint failed"):
+		return sqliteConstraintNotNull, true
+	case strings.Contains(message, "CHECK constraint failed"):
+		return sqliteConstraintCheck, true
+	case strings.Contains(message, "database is locked"), strings.Contains(message, "SQLITE_BUSY"):
+		return sqliteBusy, true
+	case strings.Contains(message, "database table is locked"):
+		return sqliteLocked, true
+	case strings.Contains(message, "database or disk is full"), strings.Contains(message, "SQLITE_FULL"):
+		return sqliteFull, true
+	}
+	return 0, false
+}
+
+// isMissingTable reports whether err is SQLite's "no such table" error. It has no result
+// code of its own (it is a plain SQLITE_ERROR), so this is the one place the message is matched.
+func isMissingTable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code, ok := sqliteErrorCode(err); ok && code&sqliteResultCodeBaseMask != sqliteError {
+		return false
+	}
+	return strings.Contains(err.Error(), "no such table")
+}
+
+// resultError translates an error returned to callers: typed SQLite errors and quota errors
+func resultError(err error) error {
+	return quotaError(ClassifyError(err))
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mattnError mirrors the shape of mattn/go-sqlite3's Error
+type mattnError struct {
+	Code         int
+	ExtendedCode int
+}
+
+func (e mattnError) Error() string { return "sqlite error" }
+
+// codedError mirrors modernc.org/sqlite's Error, with a message in another language
+type codedError int
+
+func (e codedError) Error() string { return "Datenbank ist gesperrt" }
+func (e codedError) Code() int     { return int(e) }
+
+// TestTypedSQLiteErrors verifies that constraint failures surface as typed errors from the
+// retry helpers and that codes are read from other driver shapes and plain messages
+func TestTypedSQLiteErrors(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "errors.db"))
+	t.Setenv("DATABASE_FOREIGN_KEYS", "true")
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE teams (id INTEGER PRIMARY KEY, slug TEXT UNIQUE NOT NULL CHECK (slug <> ''))",
+		"CREATE TABLE members (team_id INTEGER REFERENCES teams(id))",
+		"INSERT INTO teams (id, slug) VALUES (1, 'core')",
+	} {
+		if _, err := ExecWithRetry(db, stmt); err != nil {
+			t.Fatalf("Failed to set up: %v", err)
+		}
+	}
+
+	cases := []struct {
+		query    string
+		expected error
+	}{
+		{"INSERT INTO teams (id, slug) VALUES (2, 'core')", ErrConstraintUnique},
+		{"INSERT INTO teams (id, slug) VALUES (1, 'other')", ErrConstraintPrimaryKey},
+		{"INSERT INTO teams (id, slug) VALUES (3, NULL)", ErrConstraintNotNull},
+		{"INSERT INTO teams (id, slug) VALUES (4, '')", ErrConstraintCheck},
+		{"INSERT INTO members (team_id) VALUES (99)", ErrConstraintForeignKey},
+	}
+	for _, c := range cases {
+		_, err := ExecWithRetry(db, c.query)
+		if !errors.Is(err, c.expected) || !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s: expected %v, got %v", c.query, c.expected, err)
+		}
+		var sqliteErr *SQLiteError
+		if !errors.As(err, &sqliteErr) || sqliteErr.Code&0xff != 19 {
+			t.Errorf("%s: expected a SQLiteError with a constraint code, got %v", c.query, err)
+		}
+	}
+
+	snapshot := ClassifyError(mattnError{Code: 5, ExtendedCode: 517})
+	if !errors.Is(snapshot, ErrBusySnapshot) || !errors.Is(snapshot, ErrBusy) || errors.Is(snapshot, ErrLockedSharedCache) {
+		t.Errorf("Expected a busy snapshot error, got %#v", snapshot)
+	}
+	if !IsBusy(errors.New("database is locked (5) (SQLITE_BUSY)")) || IsBusy(errors.New("no such table: x")) {
+		t.Error("Expected message fallback to classify busy errors only")
+	}
+}
+
+// TestRetryClassifiesByResultCode verifies retries are decided by result code regardless
+// of the message: shared-cache LOCKED is retried, other LOCKED errors are not
+func TestRetryClassifiesByResultCode(t *testing.T) {
+	config := RetryConfig{MaxRetryDuration: time.Second, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Clock: &fakeClock{}}
+
+	attempts := 0
+	err := retryDatabaseOperation(func() error {
+		attempts++
+		if attempts < 3 {
+			return codedError(sqliteLockedSharedCache)
+		}
+		return nil
+	}, config)
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected shared-cache LOCKED to be retried, got %d attempts: %v", attempts, err)
+	}
+
+	attempts = 0
+	err = retryDatabaseOperation(func() error {
+		attempts++
+		return codedError(sqliteLocked)
+	}, config)
+	if !IsLocked(err) || IsBusy(err) || attempts != 1 {
+		t.Errorf("Expected LOCKED to fail without retrying, got %d attempts: %v", attempts, err)
+	}
+	if !IsBusy(codedError(sqliteBusyRecovery)) {
+		t.Error("Expected a coded BUSY error with a non-English message to be busy")
+	}
+}
+
+// TestIsMissingTable verifies missing tables are recognised from driver errors and
+// wrapped errors, but not from errors with another result code
+func TestIsMissingTable(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "missing.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("DELETE FROM absent")
+	if !isMissingTable(err) || !isMissingTable(fmt.Errorf("cleanup: %w", err)) {
+		t.Errorf("Expected a missing table, got %v", err)
+	}
+	_, err = db.Exec("DELETE FROM")
+	if isMissingTable(err) || isMissingTable(nil) {
+		t.Errorf("Expected a syntax error not to be a missing table, got %v", err)
+	}
+	if isMissingTable(fmt.Errorf("no such table: absent: %w", codedError(sqliteBusy))) {
+		t.Error("Expected a BUSY error not to be a missing table")
+	}
+}
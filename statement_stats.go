@@ -0,0 +1,198 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatementOrder selects how TopStatements ranks statements
+type StatementOrder string
+
+// Statement orderings
+const (
+	ByTotalTime StatementOrder = "total_time" // Cumulative execution time
+	ByCount     StatementOrder = "count"      // Number of executions
+	ByP99       StatementOrder = "p99"        // 99th percentile latency
+)
+
+// DefaultMaxStatements is the number of distinct statements tracked by default
+const DefaultMaxStatements = 1000
+
+// statementLatencySamples is the number of recent latencies kept per statement for percentiles
+const statementLatencySamples = 1024
+
+// StatementStats aggregates executions of one normalized statement
+type StatementStats struct {
+	Fingerprint string        `json:"fingerprint"`
+	Statement   string        `json:"statement"`
+	Calls       int64         `json:"calls"`
+	Errors      int64         `json:"errors"`
+	Retries     int64         `json:"retries"` // Attempts beyond the first, summed over calls
+	Rows        int64         `json:"rows"`    // Rows affected by exec statements
+	TotalTime   time.Duration `json:"total_time_ns"`
+	MeanTime    time.Duration `json:"mean_time_ns"`
+	MaxTime     time.Duration `json:"max_time_ns"`
+	P99         time.Duration `json:"p99_ns"` // Over the most recent executions
+	LastSeen    time.Time     `json:"last_seen"`
+}
+
+// statementEntry accumulates one statement's counters and recent latencies
+type statementEntry struct {
+	stats     StatementStats
+	latencies []time.Duration // Ring buffer of recent latencies
+	next      int
+}
+
+// statementStats is the in-memory statement aggregator
+type statementStats struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*statementEntry
+}
+
+// activeStatementStats is the running aggregator, if any
+var activeStatementStats atomic.Pointer[statementStats]
+
+// EnableStatementStats starts aggregating statements run through the retry helpers, DBTX
+// and the pipeline driver, tracking up to maxStatements distinct normalized statements
+// (default: DefaultMaxStatements). When full, the least executed statement is evicted.
+func EnableStatementStats(maxStatements int) {
+	if maxStatements <= 0 {
+		maxStatements = DefaultMaxStatements
+	}
+	activeStatementStats.Store(&statementStats{max: maxStatements, entries: make(map[string]*statementEntry)})
+	logf("📊 Statement statistics enabled (tracking up to %d statements)", maxStatements)
+}
+
+// DisableStatementStats stops aggregating statements and discards collected statistics
+func DisableStatementStats() {
+	activeStatementStats.Store(nil)
+}
+
+// ResetStatementStats discards collected statistics while keeping aggregation enabled
+func ResetStatementStats() {
+	if stats := activeStatementStats.Load(); stats != nil {
+		stats.mu.Lock()
+		stats.entries = make(map[string]*statementEntry)
+		stats.mu.Unlock()
+	}
+}
+
+// record adds one execution to the aggregate for its fingerprint
+func (s *statementStats) record(fingerprint, statement string, duration time.Duration, rows int64, attempts int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[fingerprint]
+	if !ok {
+		if len(s.entries) >= s.max {
+			s.evictLocked()
+		}
+		entry = &statementEntry{stats: StatementStats{Fingerprint: fingerprint, Statement: statement}}
+		s.entries[fingerprint] = entry
+	}
+
+	entry.stats.Calls++
+	entry.stats.TotalTime += duration
+	entry.stats.LastSeen = time.Now()
+	if duration > entry.stats.MaxTime {
+		entry.stats.MaxTime = duration
+	}
+	if err != nil {
+		entry.stats.Errors++
+	}
+	if attempts > 1 {
+		entry.stats.Retries += int64(attempts - 1)
+	}
+	if rows > 0 {
+		entry.stats.Rows += rows
+	}
+
+	if len(entry.latencies) < statementLatencySamples {
+		entry.latencies = append(entry.latencies, duration)
+	} else {
+		entry.latencies[entry.next] = duration
+		entry.next = (entry.next + 1) % statementLatencySamples
+	}
+}
+
+// evictLocked removes the least executed statement, oldest first on ties
+func (s *statementStats) evictLocked() {
+	var victim *statementEntry
+	for _, entry := range s.entries {
+		if victim == nil || entry.stats.Calls < victim.stats.Calls ||
+			(entry.stats.Calls == victim.stats.Calls && entry.stats.LastSeen.Before(victim.stats.LastSeen)) {
+			victim = entry
+		}
+	}
+	if victim != nil {
+		delete(s.entries, victim.stats.Fingerprint)
+	}
+}
+
+// snapshot returns the entry's statistics with derived fields filled in
+func (e *statementEntry) snapshot() StatementStats {
+	stats := e.stats
+	if stats.Calls > 0 {
+		stats.MeanTime = stats.TotalTime / time.Duration(stats.Calls)
+	}
+	if len(e.latencies) > 0 {
+		sorted := append([]time.Duration(nil), e.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		stats.P99 = sorted[(len(sorted)*99-1)/100]
+	}
+	return stats
+}
+
+// TopStatements returns up to n statements ranked by order (all when n <= 0). It returns
+// nil when statement statistics are not enabled.
+func TopStatements(order StatementOrder, n int) []StatementStats {
+	stats := activeStatementStats.Load()
+	if stats == nil {
+		return nil
+	}
+
+	stats.mu.Lock()
+	result := make([]StatementStats, 0, len(stats.entries))
+	for _, entry := range stats.entries {
+		result = append(result, entry.snapshot())
+	}
+	stats.mu.Unlock()
+
+	key := func(s StatementStats) int64 {
+		switch order {
+		case ByCount:
+			return s.Calls
+		case ByP99:
+			return int64(s.P99)
+		default:
+			return int64(s.TotalTime)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if ki, kj := key(result[i]), key(result[j]); ki != kj {
+			return ki > kj
+		}
+		return result[i].Fingerprint < result[j].Fingerprint
+	})
+
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// parseStatementOrder validates an ordering name
+func parseStatementOrder(value string) (StatementOrder, error) {
+	switch order := StatementOrder(value); order {
+	case "":
+		return ByTotalTime, nil
+	case ByTotalTime, ByCount, ByP99:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unknown statement order %q (use total_time, count or p99)", value)
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTopStatements verifies aggregation by normalized statement, each ordering, eviction,
+// and the admin API and CLI report
+func TestTopStatements(t *testing.T) {
+	EnableStatementStats(2)
+	defer DisableStatementStats()
+	stats := activeStatementStats.Load()
+
+	for i := 0; i < 3; i++ {
+		stats.record(Fingerprint("SELECT 1"), "SELECT ?", time.Millisecond, -1, 1, nil)
+	}
+	stats.record(Fingerprint("UPDATE t SET a = 1"), "UPDATE T SET A = ?", 50*time.Millisecond, 4, 3, nil)
+	stats.record(Fingerprint("UPDATE t SET a = 2"), "UPDATE T SET A = ?", 10*time.Millisecond, 0, 1, errors.New("boom"))
+
+	byCount := TopStatements(ByCount, 0)
+	if len(byCount) != 2 || byCount[0].Statement != "SELECT ?" || byCount[0].Calls != 3 {
+		t.Fatalf("Unexpected count ordering: %+v", byCount)
+	}
+	update := TopStatements(ByTotalTime, 1)[0]
+	if update.Statement != "UPDATE T SET A = ?" || update.Calls != 2 || update.TotalTime != 60*time.Millisecond ||
+		update.Errors != 1 || update.Retries != 2 || update.Rows != 4 || update.P99 != 50*time.Millisecond {
+		t.Errorf("Unexpected update statistics: %+v", update)
+	}
+	if TopStatements(ByP99, 1)[0].Statement != "UPDATE T SET A = ?" {
+		t.Error("Expected the update to have the highest p99")
+	}
+
+	// A third statement evicts the least executed one (the update)
+	stats.record(Fingerprint("DELETE FROM t"), "DELETE FROM T", time.Millisecond, 0, 1, nil)
+	for _, s := range TopStatements(ByCount, 0) {
+		if s.Statement == "UPDATE T SET A = ?" {
+			t.Error("Expected the least executed statement to be evicted")
+		}
+	}
+
+	server := httptest.NewServer(NewAdminHandler(AdminOptions{}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	if err := runCLI(context.Background(), []string{"top-statements", "-url", server.URL, "-by", "count"}, &out); err != nil {
+		t.Fatalf("top-statements failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "3 ") || !strings.HasSuffix(lines[1], "SELECT ?") {
+		t.Errorf("Unexpected report:\n%s", out.String())
+	}
+	if err := runCLI(context.Background(), []string{"top-statements", "-url", server.URL, "-by", "size"}, &out); err == nil {
+		t.Error("Expected an unknown ordering to be rejected")
+	}
+}
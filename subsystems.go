@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SubsystemStatus reports a background component's schedule and most recent run
+type SubsystemStatus struct {
+	Name         string        `json:"name"`
+	Running      bool          `json:"running"`
+	Interval     time.Duration `json:"interval"`
+	LastRun      time.Time     `json:"last_run,omitempty"`
+	NextRun      time.Time     `json:"next_run,omitempty"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	Runs         int64         `json:"runs"`
+	Failures     int64         `json:"failures"`
+}
+
+// HealthReport summarizes database reachability and background subsystems
+type HealthReport struct {
+	Status     string            `json:"status"` // "ok", or "degraded" when the database or a subsystem fails
+	Database   string            `json:"database,omitempty"`
+	Build      *BuildDetails     `json:"build,omitempty"`
+	CheckedAt  time.Time         `json:"checked_at"`
+	Subsystems []SubsystemStatus `json:"subsystems"`
+}
+
+// subsystem records the runs of one background loop
+type subsystem struct {
+	mu     sync.Mutex
+	status SubsystemStatus
+}
+
+// subsystems holds every background loop started in this process, by name
+var subsystems = struct {
+	mu     sync.RWMutex
+	byName map[string]*subsystem
+}{byName: make(map[string]*subsystem)}
+
+// startSubsystem registers a background loop running every interval, replacing a stopped
+// (or restarted) loop of the same name
+func startSubsystem(name string, interval time.Duration) *subsystem {
+	s := &subsystem{status: SubsystemStatus{Name: name, Running: true, Interval: interval, NextRun: time.Now().Add(interval)}}
+	subsystems.mu.Lock()
+	defer subsystems.mu.Unlock()
+	subsystems.byName[name] = s
+	return s
+}
+
+// run times one iteration of the loop and records its outcome
+func (s *subsystem) run(fn func() error) {
+	started := time.Now()
+	err := fn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastRun = started
+	s.status.LastDuration = time.Since(started)
+	s.status.NextRun = started.Add(s.status.Interval)
+	s.status.Runs++
+	s.status.LastError = ""
+	if err != nil {
+		s.status.Failures++
+		s.status.LastError = err.Error()
+	}
+}
+
+// stop marks the loop as no longer running
+func (s *subsystem) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Running = false
+	s.status.NextRun = time.Time{}
+}
+
+// Subsystems returns the status of every background subsystem started in this process
+// (assertion schedules, session cleanup, leader elections, EFS coordination, the disk
+// guard and the row cache), sorted by name
+func Subsystems() []SubsystemStatus {
+	subsystems.mu.RLock()
+	defer subsystems.mu.RUnlock()
+
+	statuses := make([]SubsystemStatus, 0, len(subsystems.byName))
+	for _, s := range subsystems.byName {
+		s.mu.Lock()
+		statuses = append(statuses, s.status)
+		s.mu.Unlock()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Health pings the database and reports it together with BuildInfo and Subsystems. The status is
+// degraded when the ping fails or a running subsystem's last run failed.
+func Health(ctx context.Context) HealthReport {
+	report := HealthReport{Status: "ok", CheckedAt: time.Now(), Subsystems: Subsystems()}
+	if build, err := BuildInfo(); err == nil {
+		report.Build = build
+	}
+
+	var err error
+	if configValue("DATABASE_FILE") == "" {
+		err = errors.New("DATABASE_FILE is not set")
+	} else {
+		var db *sql.DB
+		if db, err = GetDB(); err == nil {
+			err = db.PingContext(ctx)
+			db.Close()
+		}
+	}
+	if err == nil && breaker.open() {
+		err = ErrCircuitOpen
+	}
+	if err != nil {
+		report.Status, report.Database = "degraded", err.Error()
+	}
+	for _, s := range report.Subsystems {
+		if s.Running && s.LastError != "" {
+			report.Status = "degraded"
+		}
+	}
+	return report
+}
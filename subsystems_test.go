@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSubsystemsReportRunsAndHealth verifies run tracking of background loops and its
+// inclusion in Health and the admin API
+func TestSubsystemsReportRunsAndHealth(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "subsystems.db"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	NewSessionStore(SessionStoreOptions{}).StartCleanup(ctx, 10*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	var sessions SubsystemStatus
+	for time.Now().Before(deadline) && sessions.Runs == 0 {
+		time.Sleep(10 * time.Millisecond)
+		for _, s := range Subsystems() {
+			if s.Name == "sessions" {
+				sessions = s
+			}
+		}
+	}
+	if !sessions.Running || sessions.Runs == 0 || sessions.LastRun.IsZero() || !sessions.NextRun.After(sessions.LastRun) {
+		t.Fatalf("Expected session cleanup runs to be tracked, got %+v", sessions)
+	}
+	if report := Health(ctx); report.Status != "ok" {
+		t.Fatalf("Expected a healthy report, got %+v", report)
+	}
+
+	failing := startSubsystem("test_failing", time.Minute)
+	defer func() {
+		subsystems.mu.Lock()
+		delete(subsystems.byName, "test_failing")
+		subsystems.mu.Unlock()
+	}()
+	failing.run(func() error { return errors.New("backup target unreachable") })
+
+	recorder := httptest.NewRecorder()
+	NewAdminHandler(AdminOptions{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var report HealthReport
+	if err := json.NewDecoder(recorder.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode health: %v", err)
+	}
+	if recorder.Code != http.StatusServiceUnavailable || report.Status != "degraded" {
+		t.Errorf("Expected a degraded 503, got %d %+v", recorder.Code, report)
+	}
+	found := false
+	for _, s := range report.Subsystems {
+		if s.Name == "test_failing" && s.Failures == 1 && s.LastError == "backup target unreachable" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the failing subsystem in the report, got %+v", report.Subsystems)
+	}
+
+	failing.stop()
+	if report := Health(ctx); report.Status != "ok" {
+		t.Errorf("Expected a stopped subsystem not to degrade health, got %+v", report)
+	}
+
+	// Let the cleanup loop stop before DATABASE_FILE is restored
+	cancel()
+	for stopped := false; !stopped && time.Now().Before(deadline.Add(time.Second)); time.Sleep(5 * time.Millisecond) {
+		stopped = true
+		for _, s := range Subsystems() {
+			if s.Name == "sessions" && s.Running {
+				stopped = false
+			}
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change operations recorded in the sync change log
+const (
+	ChangeInsert = "INSERT"
+	ChangeUpdate = "UPDATE"
+	ChangeDelete = "DELETE"
+)
+
+// LocalOrigin is the origin recorded for changes made by this database
+const LocalOrigin = "local"
+
+// ErrInvalidChangesetSignature is returned when a changeset fails signature verification
+var ErrInvalidChangesetSignature = errors.New("invalid changeset signature")
+
+// syncSchema creates the change log, tracked table list, peer cursors and apply marker
+var syncSchema = []string{
+	`CREATE TABLE IF NOT EXISTS _sync_changes (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    table_name TEXT NOT NULL,
+    row_key TEXT NOT NULL,
+    op TEXT NOT NULL,
+    data TEXT,
+    changed_at INTEGER NOT NULL,
+    origin TEXT NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS idx_sync_changes_row ON _sync_changes(table_name, row_key, seq)`,
+	`CREATE TABLE IF NOT EXISTS _sync_tables (
+    table_name TEXT PRIMARY KEY,
+    key_column TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS _sync_peers (
+    peer_id TEXT PRIMARY KEY,
+    cursor INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+	`CREATE TABLE IF NOT EXISTS _sync_apply (
+    origin TEXT NOT NULL,
+    changed_at INTEGER
+)`,
+}
+
+// nowMillisSQL evaluates to the current Unix time in milliseconds inside SQLite
+const nowMillisSQL = "CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)"
+
+// blobKey marks a BLOB column value in change data: {"$blob": "<hex>"}, since JSON
+// cannot hold binary values
+const blobKey = "$blob"
+
+// Change is a single row change captured by the sync change log
+type Change struct {
+	Seq       int64           `json:"seq"`
+	Table     string          `json:"table"`
+	Key       string          `json:"key"`
+	Op        string          `json:"op"`
+	Data      json.RawMessage `json:"data,omitempty"` // Row contents after the change (nil for deletes), BLOBs as {"$blob": "<hex>"}
+	ChangedAt int64           `json:"changed_at"`     // Unix milliseconds
+	Origin    string          `json:"origin"`
+}
+
+// Changeset is a signed batch of changes produced for a peer
+type Changeset struct {
+	Origin    string   `json:"origin"`
+	Since     int64    `json:"since"`
+	Until     int64    `json:"until"`
+	Changes   []Change `json:"changes"`
+	Signature string   `json:"signature,omitempty"`
+}
+
+// ConflictResolver decides whether a remote change should overwrite the latest local
+// change to the same row. It is only called when the row has a local change.
+type ConflictResolver func(local, remote Change) bool
+
+// LastWriterWins applies the remote change when it is at least as recent as the local one
+func LastWriterWins(local, remote Change) bool {
+	return remote.ChangedAt >= local.ChangedAt
+}
+
+// TrackTableChanges installs change log triggers on a table so its inserts,
+// updates and deletes are captured for sync. It is safe to call at every startup
+// and re-reads the table's columns so triggers follow schema changes.
+func TrackTableChanges(ctx context.Context, table, keyColumn string) error {
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+
+	return WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range syncSchema {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to create sync schema: %w", err)
+			}
+		}
+
+		columns, err := tableColumns(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			return fmt.Errorf("table does not exist: %s", table)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO _sync_tables (table_name, key_column) VALUES (?, ?)",
+			table, keyColumn); err != nil {
+			return fmt.Errorf("failed to register sync table %s: %w", table, err)
+		}
+
+		for _, t := range changeLogTriggers(table, keyColumn, columns) {
+			if _, err := tx.ExecContext(ctx, t.DropSQL()); err != nil {
+				return fmt.Errorf("failed to drop trigger %s: %w", t.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, t.SQL); err != nil {
+				return fmt.Errorf("failed to create trigger %s: %w", t.Name, err)
+			}
+		}
+
+		logf("🔁 Tracking changes for sync: %s", table)
+		return nil
+	})
+}
+
+// changeLogTriggers builds the triggers that record row changes into _sync_changes
+func changeLogTriggers(table, keyColumn string, columns []string) []Trigger {
+	jsonArgs := func(ref string) string {
+		parts := make([]string, 0, len(columns))
+		for _, column := range columns {
+			value := ref + "." + quoteIdentifier(column)
+			parts = append(parts, fmt.Sprintf("'%s', CASE WHEN typeof(%s) = 'blob' THEN json_object('%s', hex(%s)) ELSE %s END",
+				strings.ReplaceAll(column, "'", "''"), value, blobKey, value, value))
+		}
+		return "json_object(" + strings.Join(parts, ", ") + ")"
+	}
+	origin := fmt.Sprintf("COALESCE((SELECT origin FROM _sync_apply LIMIT 1), '%s')", LocalOrigin)
+	changedAt := fmt.Sprintf("COALESCE((SELECT changed_at FROM _sync_apply LIMIT 1), %s)", nowMillisSQL)
+	key := quoteIdentifier(keyColumn)
+
+	build := func(op, event, ref, data string) Trigger {
+		name := fmt.Sprintf("%s_sync_%s", table, strings.ToLower(op))
+		return Trigger{
+			Name:  name,
+			Table: table,
+			SQL: fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s AFTER %s ON %s
+FOR EACH ROW
+BEGIN
+    INSERT INTO _sync_changes (table_name, row_key, op, data, changed_at, origin)
+    VALUES ('%s', %s.%s, '%s', %s, %s, %s);
+END;`, quoteIdentifier(name), event, quoteIdentifier(table),
+				strings.ReplaceAll(table, "'", "''"), ref, key, op, data, changedAt, origin),
+		}
+	}
+
+	return []Trigger{
+		build(ChangeInsert, "INSERT", "NEW", jsonArgs("NEW")),
+		build(ChangeUpdate, "UPDATE", "NEW", jsonArgs("NEW")),
+		build(ChangeDelete, "DELETE", "OLD", "NULL"),
+	}
+}
+
+// tableColumns returns the column names of a table in declaration order
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// ProduceChangeset returns all changes after the since cursor that did not originate
+// from the given peer, signed with secret (unsigned when secret is empty)
+func ProduceChangeset(ctx context.Context, peerID string, since int64, secret []byte) (*Changeset, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	cs := &Changeset{Origin: LocalOrigin, Since: since, Until: since}
+	err = retryDatabaseOperationContext(ctx, func() error {
+		cs.Changes = nil
+		cs.Until = since
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT seq, table_name, row_key, op, data, changed_at, origin
+			FROM _sync_changes
+			WHERE seq > ? AND origin != ?
+			ORDER BY seq`, since, peerID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c Change
+			var data sql.NullString
+			if err := rows.Scan(&c.Seq, &c.Table, &c.Key, &c.Op, &data, &c.ChangedAt, &c.Origin); err != nil {
+				return err
+			}
+			if data.Valid {
+				c.Data = json.RawMessage(data.String)
+			}
+			cs.Changes = append(cs.Changes, c)
+			cs.Until = c.Seq
+		}
+		return rows.Err()
+	}, DefaultRetryConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to produce changeset: %w", err)
+	}
+
+	if len(secret) > 0 {
+		signature, err := signChangeset(cs, secret)
+		if err != nil {
+			return nil, err
+		}
+		cs.Signature = signature
+	}
+	return cs, nil
+}
+
+// signChangeset computes the HMAC-SHA256 signature of a changeset's contents
+func signChangeset(cs *Changeset, secret []byte) (string, error) {
+	unsigned := *cs
+	unsigned.Signature = ""
+	payload, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode changeset: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ApplyChangeset verifies and applies a remote peer's changeset in a single transaction.
+// Conflicts with local changes are decided by resolver (LastWriterWins when nil).
+// The peer's cursor is advanced to the changeset's Until value on success.
+// Returns the number of changes applied.
+func ApplyChangeset(ctx context.Context, peerID string, cs *Changeset, secret []byte, resolver ConflictResolver) (int, error) {
+	if len(secret) > 0 {
+		expected, err := signChangeset(cs, secret)
+		if err != nil {
+			return 0, err
+		}
+		if !hmac.Equal([]byte(expected), []byte(cs.Signature)) {
+			return 0, ErrInvalidChangesetSignature
+		}
+	}
+	if resolver == nil {
+		resolver = LastWriterWins
+	}
+
+	applied := 0
+	err := WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		applied = 0
+
+		// Mark changes recorded by triggers during apply with the remote change's origin,
+		// keeping the remote timestamp so later conflict checks compare original write times
+		if _, err := tx.ExecContext(ctx, "DELETE FROM _sync_apply"); err != nil {
+			return fmt.Errorf("failed to prepare sync apply: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO _sync_apply (origin) VALUES (?)", peerID); err != nil {
+			return fmt.Errorf("failed to prepare sync apply: %w", err)
+		}
+
+		keyColumns := make(map[string]string)
+		for _, remote := range cs.Changes {
+			keyColumn, ok := keyColumns[remote.Table]
+			if !ok {
+				err := tx.QueryRowContext(ctx, "SELECT key_column FROM _sync_tables WHERE table_name = ?", remote.Table).Scan(&keyColumn)
+				if err == sql.ErrNoRows {
+					return fmt.Errorf("table is not tracked for sync: %s", remote.Table)
+				}
+				if err != nil {
+					return err
+				}
+				keyColumns[remote.Table] = keyColumn
+			}
+
+			var local Change
+			err := tx.QueryRowContext(ctx, `
+				SELECT seq, table_name, row_key, op, changed_at, origin
+				FROM _sync_changes
+				WHERE table_name = ? AND row_key = ?
+				ORDER BY seq DESC LIMIT 1`, remote.Table, remote.Key).
+				Scan(&local.Seq, &local.Table, &local.Key, &local.Op, &local.ChangedAt, &local.Origin)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if err == nil && !resolver(local, remote) {
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, "UPDATE _sync_apply SET origin = ?, changed_at = ?", changeOrigin(peerID, remote), remote.ChangedAt); err != nil {
+				return err
+			}
+			if err := applyChange(ctx, tx, keyColumn, remote); err != nil {
+				return fmt.Errorf("failed to apply change %d to %s: %w", remote.Seq, remote.Table, err)
+			}
+			applied++
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM _sync_apply"); err != nil {
+			return err
+		}
+		return setPeerCursorTx(ctx, tx, peerID, cs.Until)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logf("🔁 Applied %d/%d changes from peer %s", applied, len(cs.Changes), peerID)
+	return applied, nil
+}
+
+// changeOrigin returns the origin to record for a change received from peerID: the
+// database that made it, so that with more than two peers a relayed change is neither
+// echoed back to its origin nor attributed to the peer that relayed it
+func changeOrigin(peerID string, c Change) string {
+	if c.Origin == "" || c.Origin == LocalOrigin {
+		return peerID
+	}
+	return c.Origin
+}
+
+// applyChange writes a single remote change to its table
+func applyChange(ctx context.Context, tx *sql.Tx, keyColumn string, c Change) error {
+	table := quoteIdentifier(c.Table)
+
+	if c.Op == ChangeDelete {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, quoteIdentifier(keyColumn)), c.Key)
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(c.Data))
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return fmt.Errorf("invalid change data: %w", err)
+	}
+
+	columns := make([]string, 0, len(data))
+	for column := range data {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	var updates []string
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+		placeholders[i] = "?"
+		arg, err := jsonValueToArg(data[column])
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", column, err)
+		}
+		args[i] = arg
+		if column != keyColumn {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", quoted[i], quoted[i]))
+		}
+	}
+
+	// Upsert in place: INSERT OR REPLACE deletes the existing row first, which fires
+	// ON DELETE CASCADE and SET NULL on its children when foreign keys are enforced
+	conflict := "DO NOTHING"
+	if len(updates) > 0 {
+		conflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		table, strings.Join(quoted, ", "), strings.Join(placeholders, ", "), quoteIdentifier(keyColumn), conflict)
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// jsonValueToArg converts a decoded JSON value into a SQL argument
+func jsonValueToArg(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		if f, err := v.Float64(); err == nil {
+			return f, nil
+		}
+		return v.String(), nil
+	case map[string]interface{}:
+		if encoded, ok := v[blobKey].(string); ok && len(v) == 1 {
+			return hex.DecodeString(encoded)
+		}
+	}
+	return value, nil
+}
+
+// GetPeerCursor returns the last change sequence received from a peer (0 if unknown)
+func GetPeerCursor(ctx context.Context, peerID string) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var cursor int64
+	err = retryDatabaseOperationContext(ctx, func() error {
+		err := db.QueryRowContext(ctx, "SELECT cursor FROM _sync_peers WHERE peer_id = ?", peerID).Scan(&cursor)
+		if err == sql.ErrNoRows {
+			cursor = 0
+			return nil
+		}
+		return err
+	}, DefaultRetryConfig())
+	return cursor, err
+}
+
+// SetPeerCursor records the last change sequence received from a peer
+func SetPeerCursor(ctx context.Context, peerID string, cursor int64) error {
+	return WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		return setPeerCursorTx(ctx, tx, peerID, cursor)
+	})
+}
+
+// setPeerCursorTx records a peer cursor within an existing transaction
+func setPeerCursorTx(ctx context.Context, tx *sql.Tx, peerID string, cursor int64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO _sync_peers (peer_id, cursor, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(peer_id) DO UPDATE SET cursor = excluded.cursor, updated_at = excluded.updated_at`,
+		peerID, cursor)
+	if err != nil {
+		return fmt.Errorf("failed to set cursor for peer %s: %w", peerID, err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestChangesetRoundTrip verifies that changes captured on one database can be
+// signed, applied to another, and are not echoed back to their origin
+func TestChangesetRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	serverFile := filepath.Join(dir, "server.db")
+	clientFile := filepath.Join(dir, "client.db")
+	secret := []byte("shared-secret")
+
+	for _, file := range []string{serverFile, clientFile} {
+		t.Setenv("DATABASE_FILE", file)
+		if _, err := ExecBatch(ctx, []Statement{{Query: "CREATE TABLE notes (id TEXT PRIMARY KEY, body TEXT, stars INTEGER)"}}); err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+		if err := TrackTableChanges(ctx, "notes", "id"); err != nil {
+			t.Fatalf("TrackTableChanges failed: %v", err)
+		}
+	}
+
+	t.Setenv("DATABASE_FILE", serverFile)
+	if _, err := ExecBatch(ctx, []Statement{
+		{Query: "INSERT INTO notes (id, body, stars) VALUES ('n1', 'hello', 3)"},
+		{Query: "INSERT INTO notes (id, body, stars) VALUES ('n2', 'bye', 1)"},
+		{Query: "DELETE FROM notes WHERE id = 'n2'"},
+	}); err != nil {
+		t.Fatalf("Failed to write server rows: %v", err)
+	}
+
+	cs, err := ProduceChangeset(ctx, "client", 0, secret)
+	if err != nil {
+		t.Fatalf("ProduceChangeset failed: %v", err)
+	}
+	if len(cs.Changes) != 3 || cs.Signature == "" {
+		t.Fatalf("Expected 3 signed changes, got %d (signature %q)", len(cs.Changes), cs.Signature)
+	}
+
+	t.Setenv("DATABASE_FILE", clientFile)
+	tampered := *cs
+	tampered.Until++
+	if _, err := ApplyChangeset(ctx, "server", &tampered, secret, nil); err != ErrInvalidChangesetSignature {
+		t.Fatalf("Expected signature error for tampered changeset, got %v", err)
+	}
+
+	applied, err := ApplyChangeset(ctx, "server", cs, secret, nil)
+	if err != nil {
+		t.Fatalf("ApplyChangeset failed: %v", err)
+	}
+	if applied != 3 {
+		t.Errorf("Expected 3 changes applied, got %d", applied)
+	}
+
+	cursor, err := GetPeerCursor(ctx, "server")
+	if err != nil || cursor != cs.Until {
+		t.Errorf("Expected server cursor %d, got %d (err %v)", cs.Until, cursor, err)
+	}
+
+	var body string
+	var stars int
+	if err := QueryRowWithRetry(mustGetDB(t), "SELECT body, stars FROM notes WHERE id = 'n1'").Scan(&body, &stars); err != nil {
+		t.Fatalf("Expected n1 on client: %v", err)
+	}
+	if body != "hello" || stars != 3 {
+		t.Errorf("Unexpected n1 contents: %q, %d", body, stars)
+	}
+
+	// Changes applied from the server must not be sent back to it
+	echo, err := ProduceChangeset(ctx, "server", 0, secret)
+	if err != nil {
+		t.Fatalf("ProduceChangeset failed: %v", err)
+	}
+	if len(echo.Changes) != 0 {
+		t.Errorf("Expected no changes to echo back to server, got %d", len(echo.Changes))
+	}
+}
+
+// TestApplyChangesetKeepsChildRows verifies that syncing an update to a parent row
+// updates it in place instead of deleting it, so enforced foreign keys do not cascade
+func TestApplyChangesetKeepsChildRows(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	serverFile := filepath.Join(dir, "server.db")
+	clientFile := filepath.Join(dir, "client.db")
+	t.Setenv("DATABASE_FOREIGN_KEYS", "1")
+
+	for _, file := range []string{serverFile, clientFile} {
+		t.Setenv("DATABASE_FILE", file)
+		if _, err := ExecBatch(ctx, []Statement{
+			{Query: "CREATE TABLE boards (id TEXT PRIMARY KEY, title TEXT)"},
+			{Query: "CREATE TABLE cards (id TEXT PRIMARY KEY, board_id TEXT REFERENCES boards(id) ON DELETE CASCADE)"},
+			{Query: "INSERT INTO boards (id, title) VALUES ('b1', 'Roadmap')"},
+			{Query: "INSERT INTO cards (id, board_id) VALUES ('c1', 'b1')"},
+		}); err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+		if err := TrackTableChanges(ctx, "boards", "id"); err != nil {
+			t.Fatalf("TrackTableChanges failed: %v", err)
+		}
+	}
+
+	t.Setenv("DATABASE_FILE", serverFile)
+	if _, err := ExecBatch(ctx, []Statement{{Query: "UPDATE boards SET title = 'Plans' WHERE id = 'b1'"}}); err != nil {
+		t.Fatalf("Failed to update server row: %v", err)
+	}
+	cs, err := ProduceChangeset(ctx, "client", 0, nil)
+	if err != nil {
+		t.Fatalf("ProduceChangeset failed: %v", err)
+	}
+
+	t.Setenv("DATABASE_FILE", clientFile)
+	if _, err := ApplyChangeset(ctx, "server", cs, nil, nil); err != nil {
+		t.Fatalf("ApplyChangeset failed: %v", err)
+	}
+	db := mustGetDB(t)
+	var title string
+	var cards int
+	if err := QueryRowWithRetry(db, "SELECT title, (SELECT COUNT(*) FROM cards WHERE board_id = 'b1') FROM boards WHERE id = 'b1'").Scan(&title, &cards); err != nil {
+		t.Fatalf("Failed to read client rows: %v", err)
+	}
+	if title != "Plans" || cards != 1 {
+		t.Errorf("Expected the board updated with its card kept, got title %q and %d cards", title, cards)
+	}
+}
+
+// mustGetDB opens a database connection that is closed when the test finishes
+func mustGetDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestChangesetRelaysBlobsAndOrigin verifies that BLOB columns survive a sync, and that a
+// change relayed through a third peer keeps its origin so it is not echoed back to it
+func TestChangesetRelaysBlobsAndOrigin(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files := map[string]string{}
+	for _, peer := range []string{"a", "b", "c"} {
+		files[peer] = filepath.Join(dir, peer+".db")
+		t.Setenv("DATABASE_FILE", files[peer])
+		if _, err := ExecBatch(ctx, []Statement{{Query: "CREATE TABLE files (id TEXT PRIMARY KEY, content BLOB)"}}); err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+		if err := TrackTableChanges(ctx, "files", "id"); err != nil {
+			t.Fatalf("TrackTableChanges failed: %v", err)
+		}
+	}
+
+	t.Setenv("DATABASE_FILE", files["a"])
+	if _, err := ExecBatch(ctx, []Statement{{Query: "INSERT INTO files (id, content) VALUES ('f1', ?)", Args: []interface{}{[]byte{0x00, 0xff, 0x10}}}}); err != nil {
+		t.Fatalf("Failed to write a BLOB row: %v", err)
+	}
+	relay := func(from, to string) *Changeset {
+		t.Setenv("DATABASE_FILE", files[from])
+		cs, err := ProduceChangeset(ctx, to, 0, nil)
+		if err != nil {
+			t.Fatalf("ProduceChangeset from %s failed: %v", from, err)
+		}
+		t.Setenv("DATABASE_FILE", files[to])
+		if _, err := ApplyChangeset(ctx, from, cs, nil, nil); err != nil {
+			t.Fatalf("ApplyChangeset from %s to %s failed: %v", from, to, err)
+		}
+		return cs
+	}
+	relay("a", "b")
+	if cs := relay("b", "c"); len(cs.Changes) != 1 || cs.Changes[0].Origin != "a" {
+		t.Fatalf("Expected the relayed change to keep origin a, got %+v", cs.Changes)
+	}
+
+	var content []byte
+	if err := QueryRowWithRetry(mustGetDB(t), "SELECT content FROM files WHERE id = 'f1'").Scan(&content); err != nil || string(content) != "\x00\xff\x10" {
+		t.Errorf("Expected the BLOB to arrive intact, got %x (%v)", content, err)
+	}
+	echo, err := ProduceChangeset(ctx, "a", 0, nil)
+	if err != nil {
+		t.Fatalf("ProduceChangeset failed: %v", err)
+	}
+	if len(echo.Changes) != 0 {
+		t.Errorf("Expected no changes to be echoed back to their origin, got %d", len(echo.Changes))
+	}
+}
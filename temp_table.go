@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// WithTempTable creates a uniquely named TEMP table with the given column definitions
+// (e.g. "id INTEGER PRIMARY KEY, score REAL"), loads rows into it in one transaction, and
+// runs fn on the connection that owns it, typically joining against it:
+//
+//	WithTempTable(ctx, "id INTEGER PRIMARY KEY", ids, func(conn *sql.Conn, table string) error {
+//	    rows, err := conn.QueryContext(ctx, "SELECT u.* FROM users u JOIN "+table+" t ON t.id = u.id")
+//	    ...
+//	})
+//
+// Temp tables are only visible on the connection that created them, so fn must use conn.
+// The table is dropped when fn returns, even if it fails or ctx is cancelled.
+func WithTempTable(ctx context.Context, columns string, rows [][]interface{}, fn func(conn *sql.Conn, table string) error) error {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	table := "tmp_" + hex.EncodeToString(buf)
+	qualified := "temp." + quoteIdentifier(table)
+
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TEMP TABLE %s (%s)", quoteIdentifier(table), columns)); err != nil {
+		return fmt.Errorf("failed to create temp table: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "DROP TABLE IF EXISTS "+qualified); err != nil {
+			logfContext(ctx, LogLevelWarn, "⚠️  Failed to drop temp table %s: %v", table, err)
+		}
+	}()
+
+	if len(rows) > 0 {
+		if err := loadTempTable(ctx, conn, qualified, rows); err != nil {
+			return err
+		}
+	}
+	return fn(conn, qualified)
+}
+
+// loadTempTable inserts rows into a temp table with one prepared statement in a transaction
+func loadTempTable(ctx context.Context, conn *sql.Conn, table string, rows [][]interface{}) error {
+	width := len(rows[0])
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)", table, strings.TrimSuffix(strings.Repeat("?, ", width), ", ")))
+	if err != nil {
+		return fmt.Errorf("failed to load temp table: %w", err)
+	}
+	defer stmt.Close()
+	for i, row := range rows {
+		if len(row) != width {
+			return fmt.Errorf("temp table row %d has %d values, want %d", i, len(row), width)
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("failed to load temp table row %d: %w", i, err)
+		}
+	}
+	return tx.Commit()
+}
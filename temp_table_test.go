@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestWithTempTableLoadsAndJoins verifies loading, joining and error handling of temp tables
+func TestWithTempTableLoadsAndJoins(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "temp.db"))
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		INSERT INTO users VALUES (1, 'ada'), (2, 'grace'), (3, 'linus')`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	ctx := context.Background()
+
+	var names []string
+	var created string
+	err = WithTempTable(ctx, "id INTEGER PRIMARY KEY, rank INTEGER", [][]interface{}{{3, 1}, {1, 2}, {9, 3}}, func(conn *sql.Conn, table string) error {
+		created = table
+		rows, err := conn.QueryContext(ctx, "SELECT u.name FROM users u JOIN "+table+" t ON t.id = u.id ORDER BY t.rank")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+		return rows.Err()
+	})
+	if err != nil || strings.Join(names, ",") != "linus,ada" {
+		t.Errorf("Expected linus,ada, got %v: %v", names, err)
+	}
+	if !strings.HasPrefix(created, "temp.") {
+		t.Errorf("Expected a temp-qualified table name, got %s", created)
+	}
+
+	failure := errors.New("callback failed")
+	err = WithTempTable(ctx, "id INTEGER", nil, func(*sql.Conn, string) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+	if err := WithTempTable(ctx, "id INTEGER", [][]interface{}{{1}, {1, 2}}, func(*sql.Conn, string) error { return nil }); err == nil {
+		t.Error("Expected ragged rows to be rejected")
+	}
+}
@@ -4,20 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
+// sqlConn is the statement interface shared by *sql.DB, *sql.Tx and *sql.Conn
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // isTracingEnabled checks if Datadog tracing should be enabled
 func isTracingEnabled() bool {
-	return os.Getenv("DD_API_KEY_SECRET_ARN") != ""
+	return configValue("DD_API_KEY_SECRET_ARN") != ""
 }
 
 // getServiceName returns the service name for database spans
 func getServiceName() string {
-	if svc := os.Getenv("DD_SERVICE"); svc != "" {
+	if svc := configValue("DD_SERVICE"); svc != "" {
 		return svc + "-sqlite"
 	}
 	return "sqlite-db"
@@ -25,17 +31,12 @@ func getServiceName() string {
 
 // getDatabasePath returns the database file path from environment
 func getDatabasePath() string {
-	return os.Getenv("DATABASE_FILE")
+	return configValue("DATABASE_FILE")
 }
 
-// QueryContext executes a query with optional Datadog tracing
-// Use this instead of db.QueryContext() when you want automatic tracing
-func QueryContext(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*sql.Rows, error) {
-	if !isTracingEnabled() {
-		return db.QueryContext(ctx, query, args...)
-	}
-
-	span, ctx := tracer.StartSpanFromContext(ctx, "sqlite.query",
+// startSQLSpan starts a Datadog span for a SQL statement
+func startSQLSpan(ctx context.Context, operation, query string, args interface{}) (tracer.Span, context.Context) {
+	return tracer.StartSpanFromContext(ctx, operation,
 		tracer.SpanType(ext.SpanTypeSQL),
 		tracer.ServiceName(getServiceName()),
 		tracer.ResourceName(query),
@@ -43,6 +44,21 @@ func QueryContext(ctx context.Context, db *sql.DB, query string, args ...interfa
 		tracer.Tag(ext.DBInstance, getDatabasePath()),
 		tracer.Tag("db.statement.params", fmt.Sprintf("%v", args)), // Raw parameters for debugging
 	)
+}
+
+// QueryContext executes a query with optional Datadog tracing
+// Use this instead of db.QueryContext() when you want automatic tracing
+func QueryContext(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*sql.Rows, error) {
+	return tracedQueryContext(ctx, db, query, args...)
+}
+
+// tracedQueryContext implements QueryContext for any connection, transaction or handle
+func tracedQueryContext(ctx context.Context, db sqlConn, query string, args ...interface{}) (*sql.Rows, error) {
+	if !isTracingEnabled() {
+		return db.QueryContext(ctx, query, args...)
+	}
+
+	span, ctx := startSQLSpan(ctx, "sqlite.query", query, args)
 	defer span.Finish()
 
 	rows, err := db.QueryContext(ctx, query, args...)
@@ -56,18 +72,16 @@ func QueryContext(ctx context.Context, db *sql.DB, query string, args ...interfa
 // QueryRowContext executes a query that returns a single row with optional Datadog tracing
 // Use this instead of db.QueryRowContext() when you want automatic tracing
 func QueryRowContext(ctx context.Context, db *sql.DB, query string, args ...interface{}) *sql.Row {
+	return tracedQueryRowContext(ctx, db, query, args...)
+}
+
+// tracedQueryRowContext implements QueryRowContext for any connection, transaction or handle
+func tracedQueryRowContext(ctx context.Context, db sqlConn, query string, args ...interface{}) *sql.Row {
 	if !isTracingEnabled() {
 		return db.QueryRowContext(ctx, query, args...)
 	}
 
-	span, ctx := tracer.StartSpanFromContext(ctx, "sqlite.query",
-		tracer.SpanType(ext.SpanTypeSQL),
-		tracer.ServiceName(getServiceName()),
-		tracer.ResourceName(query),
-		tracer.Tag(ext.DBType, "sqlite"),
-		tracer.Tag(ext.DBInstance, getDatabasePath()),
-		tracer.Tag("db.statement.params", fmt.Sprintf("%v", args)), // Raw parameters for debugging
-	)
+	span, ctx := startSQLSpan(ctx, "sqlite.query", query, args)
 	defer span.Finish()
 
 	return db.QueryRowContext(ctx, query, args...)
@@ -76,18 +90,16 @@ func QueryRowContext(ctx context.Context, db *sql.DB, query string, args ...inte
 // ExecContext executes a query without returning rows with optional Datadog tracing
 // Use this instead of db.ExecContext() when you want automatic tracing
 func ExecContext(ctx context.Context, db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	return tracedExecContext(ctx, db, query, args...)
+}
+
+// tracedExecContext implements ExecContext for any connection, transaction or handle
+func tracedExecContext(ctx context.Context, db sqlConn, query string, args ...interface{}) (sql.Result, error) {
 	if !isTracingEnabled() {
 		return db.ExecContext(ctx, query, args...)
 	}
 
-	span, ctx := tracer.StartSpanFromContext(ctx, "sqlite.exec",
-		tracer.SpanType(ext.SpanTypeSQL),
-		tracer.ServiceName(getServiceName()),
-		tracer.ResourceName(query),
-		tracer.Tag(ext.DBType, "sqlite"),
-		tracer.Tag(ext.DBInstance, getDatabasePath()),
-		tracer.Tag("db.statement.params", fmt.Sprintf("%v", args)), // Raw parameters for debugging
-	)
+	span, ctx := startSQLSpan(ctx, "sqlite.exec", query, args)
 	defer span.Finish()
 
 	result, err := db.ExecContext(ctx, query, args...)
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// DefaultTransactionAttempts is how many times a transaction runs before a conflict is returned
+const DefaultTransactionAttempts = 10
+
+// ErrTransactionConflict is returned when a transaction keeps losing write conflicts
+// after all restart attempts; it wraps the last conflict error
+var ErrTransactionConflict = errors.New("transaction conflict persisted after restarts")
+
+// TransactionOptions configures WithTransactionRetryOptions
+type TransactionOptions struct {
+	MaxAttempts int   // Runs of the transaction before giving up (default: DefaultTransactionAttempts)
+	Clock       Clock // Time source for restart backoff (default: real time)
+}
+
+// IsSerializationFailure reports whether err means a transaction lost a write conflict
+// and must be restarted from the beginning: a stale WAL snapshot (SQLITE_BUSY_SNAPSHOT),
+// or BUSY raised inside the transaction or on COMMIT. Retrying the failed statement in
+// place cannot succeed, because the transaction's reads are no longer current.
+func IsSerializationFailure(err error) bool {
+	if errors.Is(ClassifyError(err), ErrBusySnapshot) {
+		return true
+	}
+	// A statement that already exhausted its own retry budget is lock starvation, not a conflict
+	var lockErr *LockWaitError
+	return IsBusy(err) && !errors.As(err, &lockErr)
+}
+
+// WithTransactionRetryOptions runs fn in a transaction, restarting the whole transaction
+// (with backoff) when it fails with a serialization failure, up to opts.MaxAttempts runs.
+// fn may run several times and must not have side effects outside the transaction.
+func WithTransactionRetryOptions(ctx context.Context, opts TransactionOptions, fn func(*sql.Tx) error) error {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultTransactionAttempts
+	}
+
+	leaveLane, err := enterLane(ctx)
+	if err != nil {
+		return err
+	}
+	defer leaveLane()
+
+	config := adaptRetryConfig(laneRetryConfig(ctx, DefaultRetryConfig()))
+	clock := opts.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	for attempt := 1; ; attempt++ {
+		err := runTransactionOnce(ctx, config, fn)
+		if err == nil {
+			if attempt > 1 {
+				logEvent(ctx, LogLevelInfo, []any{"operation", "transaction_committed", "attempt", attempt}, "✅ Transaction committed after %d restarts", attempt-1)
+			}
+			return nil
+		}
+		if !IsSerializationFailure(err) {
+			return resultError(err)
+		}
+		if attempt >= opts.MaxAttempts {
+			logEvent(ctx, LogLevelError, []any{"operation", "transaction_conflict", "attempt", attempt, "error", err}, "❌ Transaction conflict persisted after %d attempts: %v", attempt, err)
+			return fmt.Errorf("%w (%d attempts): %w", ErrTransactionConflict, attempt, resultError(err))
+		}
+
+		delay := transactionBackoff(config, attempt)
+		logEvent(ctx, LogLevelInfo, []any{"operation", "transaction_restart", "attempt", attempt, "delay", delay, "error", err},
+			"🔁 Transaction conflict - restarting in %v (attempt %d/%d): %v", delay, attempt, opts.MaxAttempts, err)
+		if err := sleepContext(ctx, clock, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// runTransactionOnce runs fn in a fresh transaction and commits it. BEGIN is retried on
+// BUSY; failures from fn and COMMIT are returned for the caller to classify. Only BEGIN
+// waits for the write queue's turn: holding it for the whole transaction would stall any
+// writer fn starts on another context until the queue's MaxWait.
+func runTransactionOnce(ctx context.Context, config RetryConfig, fn func(*sql.Tx) error) error {
+	// Get fresh database connection
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Pin one connection so a failed COMMIT can be cleaned up on the connection that ran it
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var tx *sql.Tx
+	err = func() error {
+		queueCtx, leave, err := enterWriteQueue(ctx)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return retryDatabaseOperationContext(queueCtx, func() error {
+			var err error
+			tx, err = conn.BeginTx(ctx, nil)
+			return err
+		}, config)
+	}()
+	if err != nil {
+		return err
+	}
+	done, _ := trackLockHolder("transaction", "")
+	defer done()
+
+	if err := fn(tx); err != nil {
+		// Rollback on error (simple rollback without retry)
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logEvent(ctx, LogLevelError, []any{"operation", "transaction_rollback_failed", "error", rollbackErr}, "❌ Failed to rollback transaction: %v", rollbackErr)
+		}
+		return err
+	}
+
+	// A failed COMMIT leaves SQLite's transaction open on the connection, which would
+	// otherwise return to the shared pool mid-transaction: roll it back, or discard the
+	// connection, before the restart
+	if err := tx.Commit(); err != nil {
+		if _, rollbackErr := conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK"); rollbackErr != nil {
+			discardConn(conn)
+		}
+		return err
+	}
+	return nil
+}
+
+// transactionBackoff returns the jittered exponential delay before restart number attempt
+func transactionBackoff(config RetryConfig, attempt int) time.Duration {
+	delay := config.BaseDelay << uint(attempt-1)
+	if delay > config.MaxDelay || delay <= 0 {
+		delay = config.MaxDelay
+	}
+	jitterRange := float64(delay) * config.JitterPercent
+	return delay + time.Duration(rand.Float64()*jitterRange*2-jitterRange)
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestTransactionRestartsOnStaleSnapshot verifies that a transaction whose WAL snapshot
+// goes stale is restarted as a whole, and that restarts are bounded
+func TestTransactionRestartsOnStaleSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	t.Setenv("DATABASE_FILE", path)
+
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer other.Close()
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "CREATE TABLE balances (id INTEGER PRIMARY KEY, amount INTEGER)", "INSERT INTO balances VALUES (1, 100)"} {
+		if _, err := other.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up: %v", err)
+		}
+	}
+
+	// Read, let another connection commit, then write: the write hits SQLITE_BUSY_SNAPSHOT
+	conflict := func(tx *sql.Tx) error {
+		var amount int
+		if err := tx.QueryRow("SELECT amount FROM balances WHERE id = 1").Scan(&amount); err != nil {
+			return err
+		}
+		if _, err := other.Exec("UPDATE balances SET amount = amount + 1 WHERE id = 1"); err != nil {
+			return err
+		}
+		_, err := TxExecWithRetry(tx, "UPDATE balances SET amount = ? WHERE id = 1", amount+10)
+		return err
+	}
+
+	attempts := 0
+	err = WithTransactionRetryOptions(context.Background(), TransactionOptions{MaxAttempts: 3}, func(tx *sql.Tx) error {
+		attempts++
+		if attempts == 1 {
+			return conflict(tx)
+		}
+		var amount int
+		if err := tx.QueryRow("SELECT amount FROM balances WHERE id = 1").Scan(&amount); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE balances SET amount = ? WHERE id = 1", amount+10)
+		return err
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Expected success on the second attempt, got %v after %d attempts", err, attempts)
+	}
+	var amount int
+	if err := other.QueryRow("SELECT amount FROM balances WHERE id = 1").Scan(&amount); err != nil || amount != 111 {
+		t.Errorf("Expected the restarted transaction to see the concurrent update (111), got %d (%v)", amount, err)
+	}
+
+	attempts = 0
+	err = WithTransactionRetryOptions(context.Background(), TransactionOptions{MaxAttempts: 2}, func(tx *sql.Tx) error {
+		attempts++
+		return conflict(tx)
+	})
+	if !errors.Is(err, ErrTransactionConflict) || !errors.Is(err, ErrBusySnapshot) || attempts != 2 {
+		t.Errorf("Expected a bounded conflict wrapping ErrBusySnapshot, got %v after %d attempts", err, attempts)
+	}
+}
+
+// TestWithTransactionRunsOnce verifies that WithTransaction does not restart a transaction
+// that loses a write conflict, and that restart backoff sleeps on the injected clock
+func TestWithTransactionRunsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "once.db")
+	t.Setenv("DATABASE_FILE", path)
+	defer CloseAll()
+
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer other.Close()
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "CREATE TABLE balances (id INTEGER PRIMARY KEY, amount INTEGER)", "INSERT INTO balances VALUES (1, 100)"} {
+		if _, err := other.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up: %v", err)
+		}
+	}
+	conflict := func(tx *sql.Tx) error {
+		var amount int
+		if err := tx.QueryRow("SELECT amount FROM balances WHERE id = 1").Scan(&amount); err != nil {
+			return err
+		}
+		if _, err := other.Exec("UPDATE balances SET amount = amount + 1 WHERE id = 1"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE balances SET amount = ? WHERE id = 1", amount+10)
+		return err
+	}
+
+	attempts := 0
+	err = WithTransaction(func(tx *sql.Tx) error {
+		attempts++
+		return conflict(tx)
+	})
+	if !errors.Is(err, ErrBusySnapshot) || attempts != 1 {
+		t.Errorf("Expected one run returning ErrBusySnapshot, got %v after %d attempts", err, attempts)
+	}
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	attempts = 0
+	err = WithTransactionRetryOptions(context.Background(), TransactionOptions{MaxAttempts: 3, Clock: clock}, func(tx *sql.Tx) error {
+		attempts++
+		return conflict(tx)
+	})
+	if !errors.Is(err, ErrTransactionConflict) || attempts != 3 {
+		t.Errorf("Expected a conflict after 3 attempts, got %v after %d attempts", err, attempts)
+	}
+	if len(clock.sleeps) != 2 {
+		t.Errorf("Expected 2 restart delays on the injected clock, got %v", clock.sleeps)
+	}
+}
+
+// TestTransactionReleasesWriteQueueAfterBegin verifies that a transaction does not hold
+// the write queue while fn runs, so writers fn starts on other contexts are not stalled
+func TestTransactionReleasesWriteQueueAfterBegin(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "queue.db"))
+	defer CloseAll()
+	EnableWriteQueue(WriteQueueOptions{MaxWait: 50 * time.Millisecond})
+	defer DisableWriteQueue()
+
+	err := WithTransactionRetryContext(context.Background(), func(tx *sql.Tx) error {
+		if stats := GetWriteQueueStats(); stats.Held {
+			t.Errorf("Expected the write queue to be free while the transaction runs, got %+v", stats)
+		}
+		_, leave, err := enterWriteQueue(context.Background())
+		if err != nil {
+			return err
+		}
+		leave()
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected a nested writer to take its turn, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Trigger is a named trigger definition that can be applied idempotently
+// or rendered into migration files
+type Trigger struct {
+	Name  string // Trigger name (e.g., "users_updated_at_touch")
+	Table string // Table the trigger is attached to
+	SQL   string // Full CREATE TRIGGER statement
+}
+
+// DropSQL returns the statement that removes the trigger
+func (t Trigger) DropSQL() string {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s;", quoteIdentifier(t.Name))
+}
+
+// CounterTriggerSpec describes a denormalized counter kept on a parent table
+// (e.g., boards.card_count maintained from cards.board_id)
+type CounterTriggerSpec struct {
+	ChildTable    string // Table whose rows are counted (e.g., "cards")
+	ForeignKey    string // Column in the child table referencing the parent (e.g., "board_id")
+	ParentTable   string // Table holding the counter (e.g., "boards")
+	ParentKey     string // Key column in the parent table (default: "id")
+	CounterColumn string // Counter column in the parent table (e.g., "card_count")
+}
+
+// UpdatedAtTrigger returns a trigger that sets column to CURRENT_TIMESTAMP whenever
+// a row is updated without explicitly changing it. The table must have a rowid.
+func UpdatedAtTrigger(table, column string) Trigger {
+	name := fmt.Sprintf("%s_%s_touch", table, column)
+	t, c := quoteIdentifier(table), quoteIdentifier(column)
+
+	return Trigger{
+		Name:  name,
+		Table: table,
+		SQL: fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE ON %s
+FOR EACH ROW WHEN NEW.%s IS OLD.%s
+BEGIN
+    UPDATE %s SET %s = CURRENT_TIMESTAMP WHERE rowid = NEW.rowid;
+END;`, quoteIdentifier(name), t, c, c, t, c),
+	}
+}
+
+// CounterTriggers returns the insert, delete and re-parent triggers that keep
+// a denormalized counter column in sync with the child table
+func CounterTriggers(spec CounterTriggerSpec) []Trigger {
+	parentKey := spec.ParentKey
+	if parentKey == "" {
+		parentKey = "id"
+	}
+
+	base := fmt.Sprintf("%s_%s_%s", spec.ChildTable, spec.ParentTable, spec.CounterColumn)
+	child, fk := quoteIdentifier(spec.ChildTable), quoteIdentifier(spec.ForeignKey)
+	parent, pk := quoteIdentifier(spec.ParentTable), quoteIdentifier(parentKey)
+	counter := quoteIdentifier(spec.CounterColumn)
+
+	adjust := func(delta, ref string) string {
+		return fmt.Sprintf("UPDATE %s SET %s = %s %s 1 WHERE %s = %s.%s;", parent, counter, counter, delta, pk, ref, fk)
+	}
+
+	return []Trigger{
+		{
+			Name:  base + "_insert",
+			Table: spec.ChildTable,
+			SQL: fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s\nFOR EACH ROW\nBEGIN\n    %s\nEND;",
+				quoteIdentifier(base+"_insert"), child, adjust("+", "NEW")),
+		},
+		{
+			Name:  base + "_delete",
+			Table: spec.ChildTable,
+			SQL: fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s\nFOR EACH ROW\nBEGIN\n    %s\nEND;",
+				quoteIdentifier(base+"_delete"), child, adjust("-", "OLD")),
+		},
+		{
+			Name:  base + "_reparent",
+			Table: spec.ChildTable,
+			SQL: fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE OF %s ON %s\nFOR EACH ROW WHEN NEW.%s IS NOT OLD.%s\nBEGIN\n    %s\n    %s\nEND;",
+				quoteIdentifier(base+"_reparent"), fk, child, fk, fk, adjust("-", "OLD"), adjust("+", "NEW")),
+		},
+	}
+}
+
+// TriggerMigrationSQL renders triggers as the contents of .up.sql and .down.sql migration files
+func TriggerMigrationSQL(triggers ...Trigger) (up string, down string) {
+	var upParts, downParts []string
+	for _, t := range triggers {
+		upParts = append(upParts, t.SQL)
+	}
+	// Drop in reverse order so down migrations mirror up migrations
+	for i := len(triggers) - 1; i >= 0; i-- {
+		downParts = append(downParts, triggers[i].DropSQL())
+	}
+	return strings.Join(upParts, "\n\n") + "\n", strings.Join(downParts, "\n") + "\n"
+}
+
+// ApplyTriggers drops and recreates the given triggers in a single transaction,
+// so startup code can apply them idempotently even when a definition changes
+func ApplyTriggers(ctx context.Context, triggers ...Trigger) error {
+	return WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		for _, t := range triggers {
+			if _, err := tx.ExecContext(ctx, t.DropSQL()); err != nil {
+				return fmt.Errorf("failed to drop trigger %s: %w", t.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, t.SQL); err != nil {
+				return fmt.Errorf("failed to create trigger %s: %w", t.Name, err)
+			}
+			logf("⚡ Applied trigger: %s on %s", t.Name, t.Table)
+		}
+		return nil
+	})
+}
+
+// quoteIdentifier quotes a SQLite identifier, escaping embedded double quotes
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestCounterTriggersMaintainCount verifies that generated counter triggers keep
+// the parent counter in sync across insert, re-parent and delete
+func TestCounterTriggersMaintainCount(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "triggers.db"))
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	defer db.Close()
+
+	schema := []string{
+		"CREATE TABLE boards (id TEXT PRIMARY KEY, card_count INTEGER NOT NULL DEFAULT 0, updated_at DATETIME)",
+		"CREATE TABLE cards (id TEXT PRIMARY KEY, board_id TEXT)",
+		"INSERT INTO boards (id) VALUES ('a'), ('b')",
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", stmt, err)
+		}
+	}
+
+	triggers := CounterTriggers(CounterTriggerSpec{
+		ChildTable:    "cards",
+		ForeignKey:    "board_id",
+		ParentTable:   "boards",
+		CounterColumn: "card_count",
+	})
+	triggers = append(triggers, UpdatedAtTrigger("boards", "updated_at"))
+
+	// Applying twice must be idempotent
+	for i := 0; i < 2; i++ {
+		if err := ApplyTriggers(context.Background(), triggers...); err != nil {
+			t.Fatalf("ApplyTriggers failed: %v", err)
+		}
+	}
+
+	steps := []string{
+		"INSERT INTO cards (id, board_id) VALUES ('c1', 'a'), ('c2', 'a'), ('c3', 'b')",
+		"UPDATE cards SET board_id = 'b' WHERE id = 'c2'",
+		"DELETE FROM cards WHERE id = 'c3'",
+	}
+	for _, stmt := range steps {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to execute %q: %v", stmt, err)
+		}
+	}
+
+	expected := map[string]int{"a": 1, "b": 1}
+	for id, want := range expected {
+		var got int
+		var updatedAt *string
+		if err := db.QueryRow("SELECT card_count, updated_at FROM boards WHERE id = ?", id).Scan(&got, &updatedAt); err != nil {
+			t.Fatalf("Failed to read board %s: %v", id, err)
+		}
+		if got != want {
+			t.Errorf("Expected board %s card_count %d, got %d", id, want, got)
+		}
+		if updatedAt == nil {
+			t.Errorf("Expected board %s updated_at to be touched", id)
+		}
+	}
+
+	up, down := TriggerMigrationSQL(triggers...)
+	if up == "" || down == "" {
+		t.Error("Expected non-empty migration SQL")
+	}
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ViewDefinition represents a view managed by the package
+type ViewDefinition struct {
+	Name         string // View or table name
+	Query        string // SELECT statement defining the view
+	Materialized bool   // Stored as a table and rebuilt by RefreshMaterialized
+}
+
+// viewRegistry manages all registered views
+type viewRegistry struct {
+	mu    sync.RWMutex
+	views []ViewDefinition
+}
+
+// Global view registry instance
+var globalViews = &viewRegistry{
+	views: make([]ViewDefinition, 0),
+}
+
+// RegisterView registers a view that is (re)created at startup by ApplyViews
+func RegisterView(name, query string) {
+	registerView(ViewDefinition{Name: name, Query: query})
+}
+
+// RegisterMaterializedView registers a table-backed view whose contents are
+// rebuilt on demand by RefreshMaterialized
+func RegisterMaterializedView(name, query string) {
+	registerView(ViewDefinition{Name: name, Query: query, Materialized: true})
+}
+
+// registerView adds or replaces a view definition in the global view registry
+func registerView(view ViewDefinition) {
+	globalViews.mu.Lock()
+	defer globalViews.mu.Unlock()
+
+	logf("👁️  Registering view: %s", view.Name)
+	for i, existing := range globalViews.views {
+		if existing.Name == view.Name {
+			globalViews.views[i] = view
+			return
+		}
+	}
+	globalViews.views = append(globalViews.views, view)
+}
+
+// GetRegisteredViews returns all registered views
+func GetRegisteredViews() []ViewDefinition {
+	globalViews.mu.RLock()
+	defer globalViews.mu.RUnlock()
+
+	// Return a copy to prevent external modification
+	views := make([]ViewDefinition, len(globalViews.views))
+	copy(views, globalViews.views)
+	return views
+}
+
+// lookupView returns the registered view with the given name
+func lookupView(name string) (ViewDefinition, bool) {
+	for _, view := range GetRegisteredViews() {
+		if view.Name == name {
+			return view, true
+		}
+	}
+	return ViewDefinition{}, false
+}
+
+// materializedViewsSchema records the query each materialized view was last built from,
+// so ApplyViews can tell when a registered query changed
+const materializedViewsSchema = `CREATE TABLE IF NOT EXISTS _materialized_views (
+    name  TEXT PRIMARY KEY,
+    query TEXT NOT NULL
+)`
+
+// ApplyViews recreates all registered views and builds any missing materialized views.
+// Existing materialized views keep their contents until RefreshMaterialized is called,
+// unless their registered query differs from the one they were built from: those are
+// rebuilt from the new query.
+func ApplyViews(ctx context.Context) error {
+	views := GetRegisteredViews()
+	if len(views) == 0 {
+		return nil
+	}
+
+	return WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, materializedViewsSchema); err != nil {
+			return fmt.Errorf("failed to create materialized view definitions: %w", err)
+		}
+		for _, view := range views {
+			name := quoteIdentifier(view.Name)
+
+			if view.Materialized {
+				var built sql.NullString
+				err := tx.QueryRowContext(ctx,
+					"SELECT (SELECT query FROM _materialized_views WHERE name = ?) FROM sqlite_master WHERE type = 'table' AND name = ?",
+					view.Name, view.Name).Scan(&built)
+				switch {
+				case errors.Is(err, sql.ErrNoRows):
+					// Not built yet
+				case err != nil:
+					return fmt.Errorf("failed to check materialized view %s: %w", view.Name, err)
+				case built.Valid && built.String == view.Query:
+					continue
+				}
+				if err := buildMaterialized(ctx, tx, view); err != nil {
+					return err
+				}
+				if errors.Is(err, sql.ErrNoRows) {
+					logf("✅ Created materialized view: %s", view.Name)
+				} else {
+					logf("🔄 Rebuilt materialized view %s for its changed query", view.Name)
+				}
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP VIEW IF EXISTS %s", name)); err != nil {
+				return fmt.Errorf("failed to drop view %s: %w", view.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE VIEW %s AS %s", name, view.Query)); err != nil {
+				return fmt.Errorf("failed to create view %s: %w", view.Name, err)
+			}
+			logf("✅ Applied view: %s", view.Name)
+		}
+		return nil
+	})
+}
+
+// RefreshMaterialized rebuilds a materialized view by creating a new table from its
+// query and swapping it in place of the old one within a single transaction
+func RefreshMaterialized(ctx context.Context, name string) error {
+	view, ok := lookupView(name)
+	if !ok || !view.Materialized {
+		return fmt.Errorf("materialized view not registered: %s", name)
+	}
+
+	return WithTransactionRetryContext(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, materializedViewsSchema); err != nil {
+			return fmt.Errorf("failed to create materialized view definitions: %w", err)
+		}
+		if err := buildMaterialized(ctx, tx, view); err != nil {
+			return err
+		}
+		logf("🔄 Refreshed materialized view: %s", view.Name)
+		return nil
+	})
+}
+
+// buildMaterialized (re)builds a materialized view's table from its query through a
+// staging table, and records the query it was built from
+func buildMaterialized(ctx context.Context, tx *sql.Tx, view ViewDefinition) error {
+	target := quoteIdentifier(view.Name)
+	staging := quoteIdentifier(view.Name + "__refresh")
+	statements := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", staging),
+		fmt.Sprintf("CREATE TABLE %s AS %s", staging, view.Query),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", target),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", staging, target),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to build materialized view %s: %w", view.Name, err)
+		}
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO _materialized_views (name, query) VALUES (?, ?) ON CONFLICT (name) DO UPDATE SET query = excluded.query",
+		view.Name, view.Query)
+	if err != nil {
+		return fmt.Errorf("failed to record materialized view %s: %w", view.Name, err)
+	}
+	return nil
+}
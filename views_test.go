@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// useViews swaps in an empty view registry for the duration of a test
+func useViews(t *testing.T) {
+	globalViews.mu.Lock()
+	saved := globalViews.views
+	globalViews.views = nil
+	globalViews.mu.Unlock()
+	t.Cleanup(func() {
+		globalViews.mu.Lock()
+		globalViews.views = saved
+		globalViews.mu.Unlock()
+	})
+}
+
+// countRows returns the number of rows in a table
+func countRows(t *testing.T, table string) int {
+	t.Helper()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + quoteIdentifier(table)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// execView runs a statement against the test database
+func execView(t *testing.T, query string) error {
+	t.Helper()
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(query)
+	return err
+}
+
+// TestApplyViewsRebuildsChangedMaterializedViews verifies materialized views keep their
+// contents across ApplyViews until their registered query changes
+func TestApplyViewsRebuildsChangedMaterializedViews(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "views.db"))
+	useViews(t)
+	ctx := context.Background()
+
+	if err := execView(t, "CREATE TABLE items (id INTEGER PRIMARY KEY, price INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execView(t, "INSERT INTO items (price) VALUES (5), (50), (500)"); err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterView("cheap_items", "SELECT * FROM items WHERE price < 10")
+	RegisterMaterializedView("priced_items", "SELECT * FROM items WHERE price > 10")
+	if err := ApplyViews(ctx); err != nil {
+		t.Fatalf("ApplyViews: %v", err)
+	}
+	if got := countRows(t, "cheap_items"); got != 1 {
+		t.Fatalf("cheap_items rows = %d, want 1", got)
+	}
+	if got := countRows(t, "priced_items"); got != 2 {
+		t.Fatalf("priced_items rows = %d, want 2", got)
+	}
+
+	// Unchanged queries keep the stored contents
+	if err := execView(t, "INSERT INTO items (price) VALUES (5000)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyViews(ctx); err != nil {
+		t.Fatalf("ApplyViews: %v", err)
+	}
+	if got := countRows(t, "priced_items"); got != 2 {
+		t.Fatalf("unchanged priced_items rows = %d, want 2", got)
+	}
+
+	// A changed query rebuilds the table
+	RegisterMaterializedView("priced_items", "SELECT * FROM items WHERE price > 100")
+	if err := ApplyViews(ctx); err != nil {
+		t.Fatalf("ApplyViews: %v", err)
+	}
+	if got := countRows(t, "priced_items"); got != 2 {
+		t.Fatalf("rebuilt priced_items rows = %d, want 2 (500, 5000)", got)
+	}
+
+	if err := RefreshMaterialized(ctx, "priced_items"); err != nil {
+		t.Fatalf("RefreshMaterialized: %v", err)
+	}
+	if err := RefreshMaterialized(ctx, "cheap_items"); err == nil {
+		t.Fatal("RefreshMaterialized on a plain view should fail")
+	}
+}
+
+// TestApplyViewsHonorsContext verifies a canceled context stops ApplyViews
+func TestApplyViewsHonorsContext(t *testing.T) {
+	t.Setenv("DATABASE_FILE", filepath.Join(t.TempDir(), "views.db"))
+	useViews(t)
+	RegisterView("one", "SELECT 1 AS n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ApplyViews(ctx); err == nil {
+		t.Fatal("ApplyViews with a canceled context should fail")
+	}
+	if err := RefreshMaterialized(ctx, "missing"); err == nil {
+		t.Fatal("RefreshMaterialized of an unregistered view should fail")
+	}
+}
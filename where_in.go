@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultWhereInChunkSize is how many values WhereIn binds per statement, well under the
+// 999 variables SQLite builds before 3.32 allow
+const DefaultWhereInChunkSize = 500
+
+// WhereInPlaceholder marks where WhereIn puts its IN condition in a query
+const WhereInPlaceholder = "{in}"
+
+// WhereIn runs query with WhereInPlaceholder replaced by "column IN (values)" and returns
+// the rows, however many values there are:
+//
+//	rows, err := WhereIn(ctx, db, "SELECT * FROM users WHERE {in} AND active = ?", "id", ids, true)
+//
+// Up to DefaultWhereInChunkSize values are bound directly. Longer lists of numbers and
+// strings are passed as one JSON array read with json_each, so ORDER BY and LIMIT still
+// apply to the whole result. Other values (blobs, times) are split into chunks whose
+// results are concatenated, with ORDER BY and LIMIT applying per chunk. No values match
+// no rows. args fill the query's other placeholders in order.
+func WhereIn(ctx context.Context, db *sql.DB, query, column string, values []interface{}, args ...interface{}) ([]map[string]interface{}, error) {
+	if strings.Count(query, WhereInPlaceholder) != 1 {
+		return nil, fmt.Errorf("query must contain %s exactly once", WhereInPlaceholder)
+	}
+	if len(values) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+	position := placeholdersBefore(query[:strings.Index(query, WhereInPlaceholder)])
+	if position > len(args) {
+		return nil, fmt.Errorf("query has %d placeholders before %s but only %d args", position, WhereInPlaceholder, len(args))
+	}
+	run := func(condition string, inArgs []interface{}) ([]map[string]interface{}, error) {
+		statement := strings.Replace(query, WhereInPlaceholder, condition, 1)
+		bound := make([]interface{}, 0, len(args)+len(inArgs))
+		bound = append(append(append(bound, args[:position]...), inArgs...), args[position:]...)
+		var result []map[string]interface{}
+		err := retryDatabaseOperationContext(ctx, func() error {
+			rows, err := db.QueryContext(ctx, statement, bound...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			result, _, err = scanRowMaps(rows, 0)
+			return err
+		}, retryConfigFor(ctx, statement))
+		return result, resultError(err)
+	}
+	quoted := quoteIdentifier(column)
+
+	if len(values) > DefaultWhereInChunkSize && jsonEncodable(values) {
+		if details, err := BuildInfo(); err == nil && details.Features["JSON1"] {
+			encoded, err := json.Marshal(values)
+			if err != nil {
+				return nil, err
+			}
+			return run(quoted+" IN (SELECT value FROM json_each(?))", []interface{}{string(encoded)})
+		}
+	}
+
+	result := []map[string]interface{}{}
+	for start := 0; start < len(values); start += DefaultWhereInChunkSize {
+		chunk := values[start:min(start+DefaultWhereInChunkSize, len(values))]
+		rows, err := run(quoted+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")+")", chunk)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rows...)
+	}
+	return result, nil
+}
+
+// jsonEncodable reports whether every value survives a round trip through json_each with
+// its SQLite type intact
+func jsonEncodable(values []interface{}) bool {
+	for _, value := range values {
+		switch value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// placeholdersBefore counts the ? placeholders in a query fragment outside string literals,
+// quoted identifiers and comments
+func placeholdersBefore(fragment string) int {
+	count := 0
+	for i := 0; i < len(fragment); i++ {
+		switch c := fragment[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			if end := strings.IndexByte(fragment[i+1:], c); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(fragment)
+			}
+		case c == '-' && strings.HasPrefix(fragment[i:], "--"):
+			if end := strings.IndexByte(fragment[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(fragment)
+			}
+		case c == '/' && strings.HasPrefix(fragment[i:], "/*"):
+			if end := strings.Index(fragment[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(fragment)
+			}
+		case c == '?':
+			count++
+		}
+	}
+	return count
+}
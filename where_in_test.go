@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestWhereInHandlesLargeValueLists verifies direct, JSON and chunked IN conditions and
+// the placement of surrounding arguments
+func TestWhereInHandlesLargeValueLists(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "wherein.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, kind TEXT, tag BLOB);
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5000)
+		INSERT INTO items SELECT i, CASE WHEN i % 2 = 0 THEN 'even' ELSE 'odd' END, CAST(i AS BLOB) FROM n`); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	ctx := context.Background()
+
+	rows, err := WhereIn(ctx, db, "SELECT id FROM items WHERE kind = ? AND {in} AND id > ? ORDER BY id", "id", []interface{}{1, 2, 3, 4}, "even", 2)
+	if err != nil || len(rows) != 1 || rows[0]["id"] != int64(4) {
+		t.Errorf("Expected only id 4, got %v: %v", rows, err)
+	}
+
+	// Far more values than SQLite allows variables in one statement
+	ids := make([]interface{}, 40000)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	rows, err = WhereIn(ctx, db, "SELECT id FROM items WHERE {in} AND kind = ? ORDER BY id DESC LIMIT 3", "id", ids, "odd")
+	if err != nil || len(rows) != 3 || rows[0]["id"] != int64(4999) {
+		t.Errorf("Expected one ordered result over every value, got %v: %v", rows, err)
+	}
+
+	tags := make([]interface{}, 1200)
+	for i := range tags {
+		tags[i] = []byte(string(rune('0' + i%10)))
+	}
+	if rows, err = WhereIn(ctx, db, "SELECT id FROM items WHERE {in}", "tag", tags); err != nil || len(rows) != 3*9 {
+		t.Errorf("Expected 9 matches from each of 3 chunks, got %d: %v", len(rows), err)
+	}
+
+	if rows, err = WhereIn(ctx, db, "SELECT id FROM items WHERE {in}", "id", nil); err != nil || len(rows) != 0 {
+		t.Errorf("Expected no values to match nothing, got %v: %v", rows, err)
+	}
+	if _, err := WhereIn(ctx, db, "SELECT id FROM items WHERE id IN (?)", "id", ids[:1]); err == nil {
+		t.Error("Expected a query without the placeholder to be rejected")
+	}
+	if placeholdersBefore(`SELECT '?' , "a?" -- ?
+		/* ? */ ?`) != 1 {
+		t.Error("Expected placeholders in literals and comments to be ignored")
+	}
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrWriteQueueFull is returned when a writer arrives while the write queue is at capacity
+	ErrWriteQueueFull = errors.New("database write queue is full")
+	// ErrWriteQueueTimeout is returned when a writer waits longer than the queue's MaxWait
+	ErrWriteQueueTimeout = errors.New("timed out waiting in database write queue")
+)
+
+// WriteQueueOptions configures the writer queue
+type WriteQueueOptions struct {
+	MaxWaiters int           // Writers allowed to wait at once; more are rejected (default: 1000)
+	MaxWait    time.Duration // Longest a writer waits for its turn (default: DefaultMaxRetryDuration)
+}
+
+// WriteQueueStats reports the writer queue's state
+type WriteQueueStats struct {
+	Held       bool // A writer currently holds the queue
+	Waiting    int  // Writers waiting for their turn
+	Batch      int  // Of Waiting, writers in the batch lane
+	MaxWaiters int
+	Rejected   int64 // Writers turned away because the queue was full
+	TimedOut   int64 // Writers that gave up after MaxWait
+}
+
+// writeQueue hands the in-process writer slot to waiting writers, interactive ones
+// first, each lane in arrival order
+type writeQueue struct {
+	opts     WriteQueueOptions
+	mu       sync.Mutex
+	held     bool
+	waiters  []*writeWaiter // Arrival order
+	rejected int64
+	timedOut int64
+}
+
+// writeWaiter is a writer waiting for the slot
+type writeWaiter struct {
+	turn  chan struct{}
+	batch bool
+	since time.Time
+}
+
+// activeWriteQueue is the enabled queue, if any
+var activeWriteQueue atomic.Pointer[writeQueue]
+
+// writeQueueHeldKey marks a context whose writer already holds the queue
+type writeQueueHeldKey struct{}
+
+// EnableWriteQueue serializes writes made through the package write helpers, DBTX and the
+// pipeline driver, so under heavy contention blocked writers take turns instead of racing
+// each other's retries. Waiting interactive writers go first, in arrival order; batch
+// writers (see WithQoS) follow, except that one waiting BatchMaxYield or longer goes next
+// so batch work is not starved. SQLite BUSY from other processes is still retried while a
+// writer holds its turn.
+func EnableWriteQueue(opts WriteQueueOptions) {
+	if opts.MaxWaiters <= 0 {
+		opts.MaxWaiters = 1000
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = DefaultMaxRetryDuration
+	}
+	activeWriteQueue.Store(&writeQueue{opts: opts})
+	logf("🚦 Write queue enabled (max %d waiters)", opts.MaxWaiters)
+}
+
+// DisableWriteQueue stops queueing new writers; writers already queued finish normally
+func DisableWriteQueue() {
+	activeWriteQueue.Store(nil)
+}
+
+// GetWriteQueueStats returns the queue state, or zero stats when the queue is disabled
+func GetWriteQueueStats() WriteQueueStats {
+	q := activeWriteQueue.Load()
+	if q == nil {
+		return WriteQueueStats{}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := WriteQueueStats{Held: q.held, Waiting: len(q.waiters), MaxWaiters: q.opts.MaxWaiters, Rejected: q.rejected, TimedOut: q.timedOut}
+	for _, w := range q.waiters {
+		if w.batch {
+			stats.Batch++
+		}
+	}
+	return stats
+}
+
+// enterWriteQueue waits for the writer's turn when the queue is enabled. The returned
+// context marks the turn as held so nested pipeline layers do not queue again, and the
+// returned function must be called when the write completes. Batch writers yield to
+// interactive work before taking their turn rather than while holding it.
+func enterWriteQueue(ctx context.Context) (context.Context, func(), error) {
+	q := activeWriteQueue.Load()
+	if q == nil || ctx.Value(writeQueueHeldKey{}) != nil {
+		return ctx, func() {}, nil
+	}
+	if QoSFromContext(ctx) == QoSBatch {
+		if err := yieldToInteractive(ctx); err != nil {
+			return ctx, nil, err
+		}
+	}
+	if err := q.acquire(ctx); err != nil {
+		return ctx, nil, err
+	}
+	return context.WithValue(ctx, writeQueueHeldKey{}, true), q.release, nil
+}
+
+// acquire takes the writer slot, waiting behind earlier writers
+func (q *writeQueue) acquire(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.held && len(q.waiters) == 0 {
+		q.held = true
+		q.mu.Unlock()
+		return nil
+	}
+	if len(q.waiters) >= q.opts.MaxWaiters {
+		q.rejected++
+		q.mu.Unlock()
+		return ErrWriteQueueFull
+	}
+	turn := make(chan struct{})
+	q.waiters = append(q.waiters, &writeWaiter{turn: turn, batch: QoSFromContext(ctx) == QoSBatch, since: time.Now()})
+	q.mu.Unlock()
+
+	timer := time.NewTimer(q.opts.MaxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-turn:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+		err = ErrWriteQueueTimeout
+	}
+
+	q.mu.Lock()
+	for i, waiter := range q.waiters {
+		if waiter.turn == turn {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			if err == ErrWriteQueueTimeout {
+				q.timedOut++
+			}
+			q.mu.Unlock()
+			return err
+		}
+	}
+	q.mu.Unlock()
+
+	// The turn was handed to us while giving up; pass it on
+	q.release()
+	return err
+}
+
+// release hands the writer slot to the next writer: the longest waiting interactive
+// writer, unless a batch writer has waited BatchMaxYield or longer
+func (q *writeQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.waiters) == 0 {
+		q.held = false
+		return
+	}
+	next := 0
+	if first := q.waiters[0]; first.batch && time.Since(first.since) < BatchMaxYield {
+		for i, w := range q.waiters {
+			if !w.batch {
+				next = i
+				break
+			}
+		}
+	}
+	turn := q.waiters[next].turn
+	q.waiters = append(q.waiters[:next], q.waiters[next+1:]...)
+	close(turn)
+}
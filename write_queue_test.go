@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWriteQueueFIFO verifies that waiting writers get their turn in arrival order, that
+// the queue length is bounded, and that waits time out
+func TestWriteQueueFIFO(t *testing.T) {
+	EnableWriteQueue(WriteQueueOptions{MaxWaiters: 3, MaxWait: time.Second})
+	defer DisableWriteQueue()
+
+	_, leave, err := enterWriteQueue(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to take the writer slot: %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, release, err := enterWriteQueue(context.Background())
+			if err != nil {
+				t.Errorf("Writer %d failed to queue: %v", i, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			release()
+		}()
+		for GetWriteQueueStats().Waiting != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if _, _, err := enterWriteQueue(context.Background()); !errors.Is(err, ErrWriteQueueFull) {
+		t.Errorf("Expected ErrWriteQueueFull, got %v", err)
+	}
+
+	leave()
+	wg.Wait()
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Errorf("Expected writers in arrival order, got %v", order)
+	}
+
+	EnableWriteQueue(WriteQueueOptions{MaxWait: 20 * time.Millisecond})
+	_, leave, _ = enterWriteQueue(context.Background())
+	if _, _, err := enterWriteQueue(context.Background()); !errors.Is(err, ErrWriteQueueTimeout) {
+		t.Errorf("Expected ErrWriteQueueTimeout, got %v", err)
+	}
+	leave()
+	if stats := GetWriteQueueStats(); stats.Held || stats.TimedOut != 1 {
+		t.Errorf("Expected a released queue with one timeout, got %+v", stats)
+	}
+}
+
+// TestWriteQueueFavorsInteractive verifies that waiting interactive writers go before
+// earlier batch writers, that a batch writer waiting BatchMaxYield goes next, and that a
+// batch writer holding the slot does not yield to interactive work
+func TestWriteQueueFavorsInteractive(t *testing.T) {
+	EnableWriteQueue(WriteQueueOptions{MaxWait: 5 * time.Second})
+	defer DisableWriteQueue()
+	batch := WithQoS(context.Background(), QoSBatch)
+
+	_, leave, err := enterWriteQueue(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to take the writer slot: %v", err)
+	}
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	queue := func(name string, ctx context.Context) {
+		wg.Add(1)
+		waiting := GetWriteQueueStats().Waiting
+		go func() {
+			defer wg.Done()
+			_, release, err := enterWriteQueue(ctx)
+			if err != nil {
+				t.Errorf("Writer %s failed to queue: %v", name, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		}()
+		for GetWriteQueueStats().Waiting != waiting+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	queue("batch", batch)
+	queue("interactive", context.Background())
+	if stats := GetWriteQueueStats(); stats.Batch != 1 {
+		t.Errorf("Expected one waiting batch writer, got %+v", stats)
+	}
+	leave()
+	wg.Wait()
+	if len(order) != 2 || order[0] != "interactive" {
+		t.Errorf("Expected the interactive writer first, got %v", order)
+	}
+
+	order = nil
+	_, leave, _ = enterWriteQueue(context.Background())
+	queue("starved", batch)
+	queue("interactive", context.Background())
+	q := activeWriteQueue.Load()
+	q.mu.Lock()
+	q.waiters[0].since = time.Now().Add(-BatchMaxYield)
+	q.mu.Unlock()
+	leave()
+	wg.Wait()
+	if len(order) != 2 || order[0] != "starved" {
+		t.Errorf("Expected a batch writer waiting BatchMaxYield to go first, got %v", order)
+	}
+
+	held, leave, err := enterWriteQueue(batch)
+	if err != nil {
+		t.Fatalf("Failed to take the writer slot: %v", err)
+	}
+	defer leave()
+	activeInteractive.Add(1)
+	defer activeInteractive.Add(-1)
+	started := time.Now()
+	if exit, err := enterLane(held); err != nil {
+		t.Fatalf("Failed to enter lane: %v", err)
+	} else {
+		exit()
+	}
+	if waited := time.Since(started); waited > BatchMaxYield/2 {
+		t.Errorf("Expected a batch writer holding the slot not to yield, waited %v", waited)
+	}
+}
+
+// TestWriteQueueNestedLayers verifies that helpers over the pipeline driver and
+// transactions on it do not queue twice
+func TestWriteQueueNestedLayers(t *testing.T) {
+	EnableWriteQueue(WriteQueueOptions{MaxWait: time.Second})
+	defer DisableWriteQueue()
+
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := ExecWithRetry(db, "CREATE TABLE jobs (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := tx.Exec("INSERT INTO jobs DEFAULT VALUES"); err != nil {
+			t.Fatalf("Failed to insert in transaction: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if _, err := NewDBTX(db).ExecContext(context.Background(), "DELETE FROM jobs"); err != nil {
+		t.Fatalf("Failed to delete through DBTX: %v", err)
+	}
+	if stats := GetWriteQueueStats(); stats.Held {
+		t.Errorf("Expected the queue to be free, got %+v", stats)
+	}
+}